@@ -0,0 +1,196 @@
+// Package alert pages the on-call through PagerDuty (Events API v2) or
+// Opsgenie when cycle publication breaks. Failure classes map to severities
+// so a broken merkle file pages while a failed chat notification does not.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityLow      Severity = "low"
+)
+
+// Class is what failed.
+type Class string
+
+const (
+	ClassValidation   Class = "validation"
+	ClassSync         Class = "sync"
+	ClassPublish      Class = "publish"
+	ClassMonitor      Class = "monitor"
+	ClassNotification Class = "notification"
+	// ClassEmergency is an operator-declared incident, e.g. an emergency
+	// pause of the distributors.
+	ClassEmergency Class = "emergency"
+)
+
+var classSeverity = map[Class]Severity{
+	ClassValidation:   SeverityHigh,
+	ClassSync:         SeverityHigh,
+	ClassPublish:      SeverityHigh,
+	ClassMonitor:      SeverityCritical,
+	ClassNotification: SeverityLow,
+	ClassEmergency:    SeverityCritical,
+}
+
+// SeverityFor returns the severity for a class; unknown classes are high.
+func SeverityFor(c Class) Severity {
+	if s, ok := classSeverity[c]; ok {
+		return s
+	}
+	return SeverityHigh
+}
+
+type Alert struct {
+	Summary string
+	Source  string
+	Class   Class
+	// DedupKey groups repeats of the same failure into one incident.
+	DedupKey string
+	Details  map[string]string
+}
+
+// JobSend is the queue kind of an alert that could not be sent; its payload
+// is the Alert.
+const JobSend = "alert.send"
+
+type Sender interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// FromEnv builds senders from PAGERDUTY_ROUTING_KEY and OPSGENIE_API_KEY.
+// It returns nil when neither is set.
+func FromEnv() Sender {
+	var out multi
+	if k := os.Getenv("PAGERDUTY_ROUTING_KEY"); k != "" {
+		out = append(out, &PagerDuty{RoutingKey: k})
+	}
+	if k := os.Getenv("OPSGENIE_API_KEY"); k != "" {
+		out = append(out, &Opsgenie{APIKey: k, URL: os.Getenv("OPSGENIE_API_URL")})
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+type multi []Sender
+
+func (m multi) Send(ctx context.Context, a Alert) error {
+	errs := make([]error, 0)
+	for _, s := range m {
+		if err := s.Send(ctx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+func post(ctx context.Context, url string, header http.Header, body any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("alert %s: %s: %s", url, resp.Status, bytes.TrimSpace(rb))
+	}
+	return nil
+}
+
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+type PagerDuty struct {
+	RoutingKey string
+}
+
+var pagerDutySeverity = map[Severity]string{
+	SeverityCritical: "critical",
+	SeverityHigh:     "error",
+	SeverityLow:      "warning",
+}
+
+func (p *PagerDuty) Send(ctx context.Context, a Alert) error {
+	event := map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"payload": map[string]any{
+			"summary":        a.Summary,
+			"source":         a.Source,
+			"severity":       pagerDutySeverity[SeverityFor(a.Class)],
+			"class":          string(a.Class),
+			"component":      "fairflow-reward",
+			"custom_details": a.Details,
+		},
+	}
+	if a.DedupKey != "" {
+		event["dedup_key"] = a.DedupKey
+	}
+	return post(ctx, pagerDutyURL, nil, event)
+}
+
+type Opsgenie struct {
+	APIKey string
+	// URL defaults to the US instance; EU accounts use api.eu.opsgenie.com.
+	URL string
+}
+
+var opsgeniePriority = map[Severity]string{
+	SeverityCritical: "P1",
+	SeverityHigh:     "P2",
+	SeverityLow:      "P4",
+}
+
+func (o *Opsgenie) Send(ctx context.Context, a Alert) error {
+	url := o.URL
+	if url == "" {
+		url = "https://api.opsgenie.com"
+	}
+	body := map[string]any{
+		"message":  truncate(a.Summary, 130),
+		"priority": opsgeniePriority[SeverityFor(a.Class)],
+		"source":   a.Source,
+		"tags":     []string{"fairflow", string(a.Class)},
+		"details":  a.Details,
+	}
+	if a.DedupKey != "" {
+		body["alias"] = a.DedupKey
+	}
+	return post(ctx, url+"/v2/alerts", http.Header{"Authorization": {"GenieKey " + o.APIKey}}, body)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
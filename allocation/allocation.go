@@ -0,0 +1,293 @@
+// Package allocation turns per-position points into integer token amounts.
+// Each reward type picks a strategy in config/allocation.json so tokenomics
+// changes show up as reviewable config and golden-file diffs.
+package allocation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+const DefaultConfigPath = "config/allocation.json"
+
+// Strategy splits budget across recipients. The result has one amount per
+// input, and amounts never sum to more than budget; the remainder is dust.
+type Strategy interface {
+	Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error)
+}
+
+// Spec is the per-type configuration of a strategy.
+type Spec struct {
+	Strategy string `json:"strategy"`
+	// CapBps caps any single recipient at this share of the budget
+	// (capped-pro-rata).
+	CapBps int64 `json:"capBps,omitempty"`
+	// Tiers for the tiered strategy; a recipient falls in the highest tier
+	// whose MinPoints it reaches.
+	Tiers []Tier `json:"tiers,omitempty"`
+	// Amount paid to every eligible recipient, in base units (fixed).
+	Amount string `json:"amount,omitempty"`
+	// Vesting, when set, moves part of every allocation to the vester.
+	Vesting *Vesting `json:"vesting,omitempty"`
+	// HashScheme is the merkle hash scheme the type's distributor verifies
+	// (standard, keccak or sha256); empty is standard.
+	HashScheme string `json:"hashScheme,omitempty"`
+	// Rounding is how exact shares become base units: down (the default),
+	// nearest or largest-remainder.
+	Rounding string `json:"rounding,omitempty"`
+	// MinPayout maps token to the smallest amount, in base units, worth a
+	// leaf; "*" covers tokens not listed. Smaller amounts become dust.
+	MinPayout map[string]string `json:"minPayout,omitempty"`
+	// Treasury, when set, is the treasury's position as
+	// <erc721Addr>:<erc721Id>; each token's dust joins its leaf rather than
+	// staying in the distributor. The distributor pays positions, not
+	// addresses, so it must be in the snapshot.
+	Treasury string `json:"treasury,omitempty"`
+}
+
+type Tier struct {
+	MinPoints string `json:"minPoints"`
+	Weight    string `json:"weight"`
+}
+
+// Config maps reward type to its strategy spec.
+type Config map[string]Spec
+
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse allocation config: %w", err)
+	}
+	for t, s := range c {
+		if _, err := New(s); err != nil {
+			return nil, fmt.Errorf("allocation config %s: %w", t, err)
+		}
+		if s.Vesting != nil {
+			if err := s.Vesting.Validate(); err != nil {
+				return nil, fmt.Errorf("allocation config %s: %w", t, err)
+			}
+		}
+		if _, err := s.dustPolicy(); err != nil {
+			return nil, fmt.Errorf("allocation config %s: %w", t, err)
+		}
+	}
+	return c, nil
+}
+
+// For returns the strategy for rewardType, defaulting to pro-rata.
+func (c Config) For(rewardType string) (Strategy, error) {
+	s, ok := c[rewardType]
+	if !ok {
+		return ProRata{}, nil
+	}
+	return New(s)
+}
+
+// New builds the strategy of s, rounding its shares with s.Rounding.
+func New(s Spec) (Strategy, error) {
+	base, err := newStrategy(s)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Rounding {
+	case "", RoundDown:
+		return base, nil
+	case RoundNearest, RoundLargestRemainder:
+		return Rounded{sharer: base.(sharer), Mode: s.Rounding}, nil
+	default:
+		return nil, fmt.Errorf("unknown rounding %q (down, nearest or largest-remainder)", s.Rounding)
+	}
+}
+
+func newStrategy(s Spec) (Strategy, error) {
+	switch s.Strategy {
+	case "", "pro-rata":
+		return ProRata{}, nil
+	case "capped-pro-rata":
+		if s.CapBps <= 0 || s.CapBps > 10_000 {
+			return nil, fmt.Errorf("capped-pro-rata: capBps must be in (0, 10000], got %d", s.CapBps)
+		}
+		return CappedProRata{CapBps: s.CapBps}, nil
+	case "tiered":
+		return newTiered(s.Tiers)
+	case "fixed":
+		a, ok := new(big.Int).SetString(s.Amount, 10)
+		if !ok || a.Sign() <= 0 {
+			return nil, fmt.Errorf("fixed: invalid amount %q", s.Amount)
+		}
+		return Fixed{Amount: a}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", s.Strategy)
+	}
+}
+
+// ProRata splits the budget proportionally to points, rounding down.
+type ProRata struct{}
+
+func (p ProRata) Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error) {
+	return floorAll(p.shares(points, budget))
+}
+
+func (ProRata) shares(points []*big.Rat, budget *big.Int) ([]*big.Rat, error) {
+	return weighted(points, new(big.Rat).SetInt(budget))
+}
+
+// CappedProRata is pro-rata with no recipient above CapBps of the budget;
+// the excess is redistributed pro-rata among the uncapped recipients.
+type CappedProRata struct {
+	CapBps int64
+}
+
+func (s CappedProRata) Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error) {
+	return floorAll(s.shares(points, budget))
+}
+
+func (s CappedProRata) shares(points []*big.Rat, budget *big.Int) ([]*big.Rat, error) {
+	capAmt := new(big.Rat).SetFrac(new(big.Int).Mul(budget, big.NewInt(s.CapBps)), big.NewInt(10_000))
+	shares := make([]*big.Rat, len(points))
+	capped := make([]bool, len(points))
+	remaining := new(big.Rat).SetInt(budget)
+	for {
+		total := new(big.Rat)
+		for i, p := range points {
+			if !capped[i] {
+				total.Add(total, p)
+			}
+		}
+		if total.Sign() == 0 {
+			break
+		}
+		changed := false
+		for i, p := range points {
+			if capped[i] {
+				continue
+			}
+			sh := new(big.Rat).Mul(remaining, p)
+			sh.Quo(sh, total)
+			shares[i] = sh
+			if sh.Cmp(capAmt) > 0 {
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		for i := range points {
+			if !capped[i] && shares[i].Cmp(capAmt) > 0 {
+				capped[i] = true
+				shares[i] = capAmt
+				remaining.Sub(remaining, capAmt)
+			}
+		}
+	}
+	for i := range shares {
+		if shares[i] == nil {
+			shares[i] = new(big.Rat)
+		}
+	}
+	return shares, nil
+}
+
+// Tiered ignores points beyond placing each recipient in a tier and splits
+// the budget by tier weight.
+type Tiered struct {
+	tiers []tier
+}
+
+type tier struct {
+	min    *big.Rat
+	weight *big.Rat
+}
+
+func newTiered(ts []Tier) (Strategy, error) {
+	if len(ts) == 0 {
+		return nil, errors.New("tiered: no tiers")
+	}
+	out := Tiered{tiers: make([]tier, len(ts))}
+	for i, t := range ts {
+		m, ok1 := new(big.Rat).SetString(t.MinPoints)
+		w, ok2 := new(big.Rat).SetString(t.Weight)
+		if !ok1 || !ok2 || w.Sign() < 0 {
+			return nil, fmt.Errorf("tiered: invalid tier %d", i)
+		}
+		out.tiers[i] = tier{min: m, weight: w}
+	}
+	sort.Slice(out.tiers, func(i, j int) bool { return out.tiers[i].min.Cmp(out.tiers[j].min) < 0 })
+	return out, nil
+}
+
+func (s Tiered) Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error) {
+	return floorAll(s.shares(points, budget))
+}
+
+func (s Tiered) shares(points []*big.Rat, budget *big.Int) ([]*big.Rat, error) {
+	weights := make([]*big.Rat, len(points))
+	for i, p := range points {
+		weights[i] = new(big.Rat)
+		if p.Sign() == 0 {
+			continue
+		}
+		for _, t := range s.tiers {
+			if p.Cmp(t.min) >= 0 {
+				weights[i] = t.weight
+			}
+		}
+	}
+	return weighted(weights, new(big.Rat).SetInt(budget))
+}
+
+// Fixed pays Amount to every recipient with non-zero points.
+type Fixed struct {
+	Amount *big.Int
+}
+
+func (s Fixed) Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error) {
+	return floorAll(s.shares(points, budget))
+}
+
+func (s Fixed) shares(points []*big.Rat, budget *big.Int) ([]*big.Rat, error) {
+	out := make([]*big.Rat, len(points))
+	total := new(big.Int)
+	for i, p := range points {
+		out[i] = new(big.Rat)
+		if p.Sign() > 0 {
+			out[i].SetInt(s.Amount)
+			total.Add(total, s.Amount)
+		}
+	}
+	if total.Cmp(budget) > 0 {
+		return nil, fmt.Errorf("fixed: %s needed exceeds budget %s", total, budget)
+	}
+	return out, nil
+}
+
+func weighted(weights []*big.Rat, budget *big.Rat) ([]*big.Rat, error) {
+	total := new(big.Rat)
+	for _, w := range weights {
+		total.Add(total, w)
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("total points is zero")
+	}
+	out := make([]*big.Rat, len(weights))
+	for i, w := range weights {
+		sh := new(big.Rat).Mul(budget, w)
+		out[i] = sh.Quo(sh, total)
+	}
+	return out, nil
+}
+
+func floor(r *big.Rat) *big.Int {
+	if r == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
@@ -0,0 +1,141 @@
+package allocation
+
+import (
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/snapshot"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+type goldenCase struct {
+	Spec   Spec     `json:"spec"`
+	Budget string   `json:"budget"`
+	Points []string `json:"points"`
+}
+
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), ".json")
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c goldenCase
+			if err := json.Unmarshal(b, &c); err != nil {
+				t.Fatal(err)
+			}
+			s, err := New(c.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			budget, _ := new(big.Int).SetString(c.Budget, 10)
+			points := make([]*big.Rat, len(c.Points))
+			for i, p := range c.Points {
+				points[i], _ = new(big.Rat).SetString(p)
+			}
+			amounts, err := s.Allocate(points, budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total := new(big.Int)
+			got := make([]string, len(amounts))
+			for i, a := range amounts {
+				total.Add(total, a)
+				got[i] = a.String()
+			}
+			if total.Cmp(budget) > 0 {
+				t.Fatalf("allocated %s exceeds budget %s", total, budget)
+			}
+			out, _ := json.MarshalIndent(got, "", "  ")
+			out = append(out, '\n')
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, out, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create)", err)
+			}
+			if string(want) != string(out) {
+				t.Errorf("allocation changed:\n got: %s\nwant: %s", out, want)
+			}
+		})
+	}
+}
+
+func TestBuildVesting(t *testing.T) {
+	in := Input{
+		Entries: []snapshot.Entry{
+			{ERC721Addr: "0x00000000000000000000000000000000000000a1", ERC721ID: "1", Points: big.NewRat(1, 1)},
+			{ERC721Addr: "0x00000000000000000000000000000000000000a1", ERC721ID: "2", Points: big.NewRat(3, 1)},
+		},
+		Budgets: Budgets{"0x00000000000000000000000000000000000000b1": big.NewInt(1000)},
+		Start:   100,
+		End:     200,
+	}
+	res, err := Build(Spec{Strategy: "pro-rata", Vesting: &Vesting{ImmediateBps: 2500, Duration: 50}}, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 250 and 750 split a quarter immediate, rounding towards the vester.
+	if got := res.File.TotalAmounts["0x00000000000000000000000000000000000000b1"]; got != "249" {
+		t.Errorf("immediate total %s, want 62 + 187", got)
+	}
+	if got := res.Vested["0x00000000000000000000000000000000000000b1"]; got.Cmp(big.NewInt(751)) != 0 || len(res.Grants) != 2 {
+		t.Errorf("vested %s in %d grants, want 188 + 563", got, len(res.Grants))
+	}
+	// All vested would leave the merkle file without leaves.
+	for _, bps := range []int64{0, -1, 10_001} {
+		if _, err := Build(Spec{Strategy: "pro-rata", Vesting: &Vesting{ImmediateBps: bps, Duration: 50}}, in); err == nil || !strings.Contains(err.Error(), "immediateBps") {
+			t.Errorf("immediateBps %d: got %v", bps, err)
+		}
+	}
+}
+
+// Dust only reaches the treasury through a position the distributor pays.
+func TestBuildTreasury(t *testing.T) {
+	const token = "0x00000000000000000000000000000000000000b1"
+	in := Input{
+		Entries: []snapshot.Entry{
+			{ERC721Addr: "0x00000000000000000000000000000000000000a1", ERC721ID: "1", Points: big.NewRat(1, 1)},
+			{ERC721Addr: "0x00000000000000000000000000000000000000a1", ERC721ID: "2", Points: big.NewRat(1, 1)},
+			{ERC721Addr: "0x00000000000000000000000000000000000000a1", ERC721ID: "3", Points: big.NewRat(1, 1)},
+		},
+		Budgets: Budgets{token: big.NewInt(1000)},
+		Start:   100,
+		End:     200,
+	}
+	res, err := Build(Spec{Strategy: "pro-rata", Treasury: "0x00000000000000000000000000000000000000A1:3"}, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.File.TotalAmounts[token]; got != "1000" {
+		t.Errorf("total %s, want the whole budget", got)
+	}
+	for _, ud := range res.File.UserDatas {
+		if ud.Leaf.ERC721ID == "3" && ud.Leaf.Amounts[0] != "334" {
+			t.Errorf("treasury position got %s, want 333 + 1 dust", ud.Leaf.Amounts[0])
+		}
+	}
+	for _, tr := range []string{"0x00000000000000000000000000000000000000a1:9", "0x00000000000000000000000000000000000000a1", "0xa1:1"} {
+		if _, err := Build(Spec{Strategy: "pro-rata", Treasury: tr}, in); err == nil || !strings.Contains(err.Error(), "treasury") {
+			t.Errorf("treasury %s: got %v", tr, err)
+		}
+	}
+}
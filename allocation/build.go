@@ -0,0 +1,211 @@
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/snapshot"
+)
+
+// Budgets is a repeatable <token>=<base units> flag.
+type Budgets map[string]*big.Int
+
+func (b Budgets) String() string {
+	parts := make([]string, 0, len(b))
+	for t, a := range b {
+		parts = append(parts, t+"="+a.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (b Budgets) Set(s string) error {
+	tok, amt, ok := strings.Cut(s, "=")
+	if !ok || !evm.IsAddress(tok) {
+		return fmt.Errorf("budget must be <token>=<base units>, got %q", s)
+	}
+	a, err := cycle.ParseAmount(amt)
+	if err != nil {
+		return err
+	}
+	b[strings.ToLower(tok)] = a
+	return nil
+}
+
+// LoadSpec returns the configured spec for rewardType, with the strategy
+// replaced by override when set. A missing config file means pro-rata.
+func LoadSpec(path, rewardType, override string) (Spec, error) {
+	cfg, err := LoadConfig(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		cfg = Config{}
+	case err != nil:
+		return Spec{}, err
+	}
+	spec := cfg[rewardType]
+	if override != "" {
+		spec.Strategy = override
+	}
+	return spec, nil
+}
+
+// Input is everything a merkle file is generated from.
+type Input struct {
+	Entries []snapshot.Entry
+	// Budgets maps lower-case token address to budget in base units.
+	Budgets  Budgets
+	Start    int64
+	End      int64
+	Metadata string
+	Salt     string
+}
+
+// Result is a generated merkle file plus the per-token bookkeeping callers
+// report.
+type Result struct {
+	File      *cycle.File
+	Grants    []Grant
+	Tokens    []string
+	Allocated map[string]*big.Int
+	Vested    map[string]*big.Int
+	// Dust is what of each budget no recipient was paid: rounding
+	// remainders and amounts under the type's minPayout.
+	Dust map[string]*big.Int
+	// BelowMin counts the recipients of each token dropped by minPayout.
+	BelowMin map[string]int
+	// Treasury is where the dust was paid, if the type redirects it.
+	Treasury string
+}
+
+// Build allocates every budget across in.Entries with spec, applies the
+// vesting split and returns the rebuilt merkle file. It is deterministic, so
+// build-merkle and reproduce produce byte-identical files from the same input.
+func Build(spec Spec, in Input) (*Result, error) {
+	alloc, err := New(spec)
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := spec.dustPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if spec.Vesting != nil {
+		if err := spec.Vesting.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	points := make([]*big.Rat, len(in.Entries))
+	for i, e := range in.Entries {
+		points[i] = e.Points
+	}
+	amounts := make([]map[string]*big.Int, len(in.Entries))
+	for i := range amounts {
+		amounts[i] = make(map[string]*big.Int)
+	}
+	r := &Result{Allocated: make(map[string]*big.Int), Vested: make(map[string]*big.Int), Dust: make(map[string]*big.Int), BelowMin: make(map[string]int)}
+	for t := range in.Budgets {
+		r.Tokens = append(r.Tokens, t)
+	}
+	sort.Strings(r.Tokens)
+	for _, t := range r.Tokens {
+		shares, err := alloc.Allocate(points, in.Budgets[t])
+		if err != nil {
+			return nil, fmt.Errorf("allocate %s: %w", t, err)
+		}
+		allocated := new(big.Int)
+		least := minPayout(thresholds, t)
+		for i, a := range shares {
+			if a.Sign() <= 0 {
+				continue
+			}
+			if least != nil && a.Cmp(least) < 0 {
+				r.BelowMin[t]++
+				continue
+			}
+			amounts[i][t] = a
+			allocated.Add(allocated, a)
+		}
+		if allocated.Cmp(in.Budgets[t]) > 0 {
+			return nil, fmt.Errorf("allocation for %s exceeds budget: %s > %s", t, allocated, in.Budgets[t])
+		}
+		r.Allocated[t] = allocated
+		r.Dust[t] = new(big.Int).Sub(in.Budgets[t], allocated)
+	}
+
+	if v := spec.Vesting; v != nil {
+		vstart := v.Start
+		if vstart == 0 {
+			vstart = in.Start
+		}
+		for i, e := range in.Entries {
+			for t, a := range amounts[i] {
+				imm, vested := v.Split(a)
+				if imm.Sign() > 0 {
+					amounts[i][t] = imm
+				} else {
+					delete(amounts[i], t)
+				}
+				if vested.Sign() == 0 {
+					continue
+				}
+				r.Grants = append(r.Grants, Grant{ERC721Addr: e.ERC721Addr, ERC721ID: e.ERC721ID, Token: t, Amount: vested, Start: vstart, Cliff: v.Cliff, Duration: v.Duration})
+				if r.Vested[t] == nil {
+					r.Vested[t] = new(big.Int)
+				}
+				r.Vested[t].Add(r.Vested[t], vested)
+			}
+		}
+	}
+
+	f := &cycle.File{
+		StartTimestamp: fmt.Sprint(in.Start),
+		EndTimestamp:   fmt.Sprint(in.End),
+		Metadata:       in.Metadata,
+		Salt:           in.Salt,
+		HashScheme:     spec.HashScheme,
+	}
+	// The treasury's dust joins its position's leaf, which only a position
+	// in the snapshot can claim.
+	if spec.Treasury != "" {
+		i := slices.IndexFunc(in.Entries, func(e snapshot.Entry) bool {
+			return (cycle.Leaf{ERC721Addr: e.ERC721Addr, ERC721ID: e.ERC721ID}).Key() == strings.ToLower(spec.Treasury)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("treasury position %s is not in the snapshot", spec.Treasury)
+		}
+		r.Treasury = spec.Treasury
+		for t, a := range r.Dust {
+			if a.Sign() > 0 {
+				amounts[i][t] = new(big.Int).Add(a, zeroIfNil(amounts[i][t]))
+			}
+		}
+	}
+	for i, e := range in.Entries {
+		if len(amounts[i]) == 0 {
+			continue
+		}
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(e.ERC721Addr, e.ERC721ID, amounts[i])})
+	}
+	if len(f.UserDatas) == 0 {
+		return nil, errors.New("no position received a non-zero amount")
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		return nil, err
+	}
+	r.File = f
+	return r, nil
+}
+
+func zeroIfNil(a *big.Int) *big.Int {
+	if a == nil {
+		return new(big.Int)
+	}
+	return a
+}
@@ -0,0 +1,145 @@
+package allocation
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// Rounding modes turn a strategy's exact shares into base units. None pays
+// out more than the budget; what is left over is dust.
+const (
+	// RoundDown floors every share.
+	RoundDown = "down"
+	// RoundNearest rounds half up, then takes units back from the shares
+	// closest to a half until the total fits the budget.
+	RoundNearest = "nearest"
+	// RoundLargestRemainder floors every share, then gives the units the
+	// floors left over one each to the largest fractions.
+	RoundLargestRemainder = "largest-remainder"
+)
+
+// sharer is a strategy that can report its shares before rounding.
+type sharer interface {
+	shares(points []*big.Rat, budget *big.Int) ([]*big.Rat, error)
+}
+
+// Rounded is a strategy whose shares are rounded with Mode instead of down.
+type Rounded struct {
+	sharer
+	Mode string
+}
+
+func (r Rounded) Allocate(points []*big.Rat, budget *big.Int) ([]*big.Int, error) {
+	shares, err := r.shares(points, budget)
+	if err != nil {
+		return nil, err
+	}
+	if r.Mode == RoundNearest {
+		return nearest(shares, budget), nil
+	}
+	return largestRemainder(shares, budget), nil
+}
+
+func floorAll(shares []*big.Rat, err error) ([]*big.Int, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*big.Int, len(shares))
+	for i, sh := range shares {
+		out[i] = floor(sh)
+	}
+	return out, nil
+}
+
+// frac is the fractional part of r, which is not negative.
+func frac(r *big.Rat) *big.Rat {
+	return new(big.Rat).Sub(r, new(big.Rat).SetInt(floor(r)))
+}
+
+func nearest(shares []*big.Rat, budget *big.Int) []*big.Int {
+	half := big.NewRat(1, 2)
+	out := make([]*big.Int, len(shares))
+	total := new(big.Int)
+	up := make([]int, 0)
+	for i, sh := range shares {
+		out[i] = floor(new(big.Rat).Add(sh, half))
+		total.Add(total, out[i])
+		if out[i].Cmp(floor(sh)) > 0 {
+			up = append(up, i)
+		}
+	}
+	// Undo the round-ups that were closest to a half first.
+	sort.SliceStable(up, func(a, b int) bool { return frac(shares[up[a]]).Cmp(frac(shares[up[b]])) < 0 })
+	for _, i := range up {
+		if total.Cmp(budget) <= 0 {
+			break
+		}
+		out[i].Sub(out[i], big.NewInt(1))
+		total.Sub(total, big.NewInt(1))
+	}
+	return out
+}
+
+func largestRemainder(shares []*big.Rat, budget *big.Int) []*big.Int {
+	out := make([]*big.Int, len(shares))
+	exact := new(big.Rat)
+	total := new(big.Int)
+	order := make([]int, 0, len(shares))
+	for i, sh := range shares {
+		out[i] = floor(sh)
+		total.Add(total, out[i])
+		exact.Add(exact, sh)
+		if frac(sh).Sign() > 0 {
+			order = append(order, i)
+		}
+	}
+	left := floor(exact)
+	if left.Cmp(budget) > 0 {
+		left.Set(budget)
+	}
+	left.Sub(left, total)
+	sort.SliceStable(order, func(a, b int) bool { return frac(shares[order[a]]).Cmp(frac(shares[order[b]])) > 0 })
+	for _, i := range order {
+		if left.Sign() <= 0 {
+			break
+		}
+		out[i].Add(out[i], big.NewInt(1))
+		left.Sub(left, big.NewInt(1))
+	}
+	return out
+}
+
+// dustPolicy parses MinPayout and checks Treasury.
+func (s Spec) dustPolicy() (map[string]*big.Int, error) {
+	if s.Treasury != "" {
+		addr, id, ok := strings.Cut(s.Treasury, ":")
+		if _, err := cycle.ParseAmount(id); !ok || err != nil || !evm.IsAddress(addr) {
+			return nil, fmt.Errorf("invalid treasury %q: want <erc721Addr>:<erc721Id>", s.Treasury)
+		}
+	}
+	out := make(map[string]*big.Int, len(s.MinPayout))
+	for t, v := range s.MinPayout {
+		if t != "*" && !evm.IsAddress(t) {
+			return nil, fmt.Errorf("minPayout: invalid token %q", t)
+		}
+		a, ok := new(big.Int).SetString(v, 10)
+		if !ok || a.Sign() < 0 {
+			return nil, fmt.Errorf("minPayout %s: invalid amount %q", t, v)
+		}
+		out[strings.ToLower(t)] = a
+	}
+	return out, nil
+}
+
+// minPayout is token's threshold in thresholds, or nil.
+func minPayout(thresholds map[string]*big.Int, token string) *big.Int {
+	if m, ok := thresholds[token]; ok {
+		return m
+	}
+	return thresholds["*"]
+}
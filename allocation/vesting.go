@@ -0,0 +1,90 @@
+package allocation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// Vesting splits each allocation into an immediately claimable part, paid
+// through the merkle file, and a part streamed by the vester contract.
+type Vesting struct {
+	ImmediateBps int64 `json:"immediateBps"`
+	// Start is the unix time vesting begins; 0 means the cycle's start.
+	Start    int64 `json:"start,omitempty"`
+	Cliff    int64 `json:"cliffSeconds,omitempty"`
+	Duration int64 `json:"durationSeconds"`
+}
+
+func (v *Vesting) Validate() error {
+	// With nothing immediate the merkle file would have no leaves, which no
+	// tool downstream can load.
+	if v.ImmediateBps < 1 || v.ImmediateBps > 10_000 {
+		return fmt.Errorf("vesting: immediateBps must be in [1, 10000], got %d", v.ImmediateBps)
+	}
+	if v.Duration <= 0 {
+		return errors.New("vesting: durationSeconds must be positive")
+	}
+	if v.Cliff < 0 || v.Cliff > v.Duration {
+		return errors.New("vesting: cliffSeconds must be within durationSeconds")
+	}
+	return nil
+}
+
+// Split returns the immediate and vested parts of amount. Rounding favours
+// the vested side so the two always add up to amount.
+func (v *Vesting) Split(amount *big.Int) (immediate, vested *big.Int) {
+	immediate = new(big.Int).Mul(amount, big.NewInt(v.ImmediateBps))
+	immediate.Quo(immediate, big.NewInt(10_000))
+	return immediate, new(big.Int).Sub(amount, immediate)
+}
+
+// Grant is one row of the vester export.
+type Grant struct {
+	ERC721Addr string
+	ERC721ID   string
+	Token      string
+	Amount     *big.Int
+	Start      int64
+	Cliff      int64
+	Duration   int64
+}
+
+// WriteSchedule writes grants as CSV in a stable order.
+func WriteSchedule(w io.Writer, grants []Grant) error {
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].ERC721Addr != grants[j].ERC721Addr {
+			return grants[i].ERC721Addr < grants[j].ERC721Addr
+		}
+		if grants[i].ERC721ID != grants[j].ERC721ID {
+			return lessNumeric(grants[i].ERC721ID, grants[j].ERC721ID)
+		}
+		return grants[i].Token < grants[j].Token
+	})
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"erc721Addr", "erc721Id", "token", "amount", "start", "cliffSeconds", "durationSeconds"}); err != nil {
+		return err
+	}
+	for _, g := range grants {
+		rec := []string{
+			g.ERC721Addr, g.ERC721ID, g.Token, g.Amount.String(),
+			strconv.FormatInt(g.Start, 10), strconv.FormatInt(g.Cliff, 10), strconv.FormatInt(g.Duration, 10),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func lessNumeric(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
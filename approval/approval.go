@@ -0,0 +1,279 @@
+// Package approval implements the two-person rule for releases: one operator
+// signs a proposal pinning the exact cycle files, a different operator
+// countersigns it, and only then may publish and the values bump run.
+// Signatures are ed25519 over the proposal body.
+package approval
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+const DefaultConfigPath = "config/approvers.json"
+
+// Config lists who may sign and how many distinct signers a release needs.
+type Config struct {
+	// Required is the number of distinct signers, proposer included. Below 2
+	// the workflow is off.
+	Required int `json:"required"`
+	// Approvers maps operator name to base64 ed25519 public key.
+	Approvers map[string]string `json:"approvers"`
+}
+
+// LoadConfig reads the approvers file. A missing DefaultConfigPath disables
+// approval; any other path that is missing is an error, so a mistyped
+// --approvers cannot switch the two-person rule off.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) && filepath.Clean(path) == filepath.Clean(DefaultConfigPath) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse approvers: %w", err)
+	}
+	for name, k := range c.Approvers {
+		if _, err := decodePublic(k); err != nil {
+			return nil, fmt.Errorf("approvers: %s: %w", name, err)
+		}
+	}
+	if c.Required > len(c.Approvers) {
+		return nil, fmt.Errorf("approvers: %d signatures required but only %d approvers configured", c.Required, len(c.Approvers))
+	}
+	return &c, nil
+}
+
+func (c *Config) Enabled() bool { return c.Required >= 2 }
+
+type Body struct {
+	Cycle int `json:"cycle"`
+	// Files maps file name to sha256.
+	Files     map[string]string `json:"files"`
+	Proposer  string            `json:"proposer"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+type Signature struct {
+	Signer    string    `json:"signer"`
+	Signature string    `json:"signature"`
+	SignedAt  time.Time `json:"signedAt"`
+}
+
+type Proposal struct {
+	Body       Body        `json:"proposal"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// New pins every published file in dir: merkle files, shards and the
+// manifest.
+func New(dir, proposer string) (*Proposal, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no merkle files in %s", dir)
+	}
+	files, err := digests(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Proposal{Body: Body{Cycle: entries[0].Cycle, Files: files, Proposer: proposer, CreatedAt: time.Now().UTC()}}, nil
+}
+
+func digests(dir string) (map[string]string, error) {
+	paths, err := cycle.ArtifactPaths(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(paths))
+	for _, p := range paths {
+		d, err := verify.Digest(p)
+		if err != nil {
+			return nil, err
+		}
+		out[filepath.Base(p)] = d
+	}
+	return out, nil
+}
+
+// payload is the signed message; json.Marshal sorts map keys, so it is
+// canonical.
+func (p *Proposal) payload() []byte {
+	b, _ := json.Marshal(p.Body)
+	return append([]byte("fairflow-release-proposal:v1\n"), b...)
+}
+
+// Sign adds signer's signature, replacing an earlier one by the same signer.
+func (p *Proposal) Sign(signer string, key ed25519.PrivateKey) {
+	sig := Signature{Signer: signer, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, p.payload())), SignedAt: time.Now().UTC()}
+	for i, s := range p.Signatures {
+		if s.Signer == signer {
+			p.Signatures[i] = sig
+			return
+		}
+	}
+	p.Signatures = append(p.Signatures, sig)
+}
+
+// Signers returns the distinct configured approvers with a valid signature.
+func (p *Proposal) Signers(c *Config) []string {
+	seen := make(map[string]bool)
+	for _, s := range p.Signatures {
+		pub, err := decodePublic(c.Approvers[s.Signer])
+		if err != nil {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Signature)
+		if err != nil || !ed25519.Verify(pub, p.payload(), sig) {
+			continue
+		}
+		seen[s.Signer] = true
+	}
+	out := make([]string, 0, len(seen))
+	for s := range seen {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Check confirms the proposal covers exactly the files in dir, is signed by
+// its proposer, and has the required number of distinct signers.
+func (p *Proposal) Check(c *Config, dir string) error {
+	if err := p.VerifyFiles(dir); err != nil {
+		return err
+	}
+	if !p.SignedByProposer(c) {
+		return fmt.Errorf("approval: proposal is not signed by its proposer %s", p.Body.Proposer)
+	}
+	signers := p.Signers(c)
+	if len(signers) < c.Required {
+		return fmt.Errorf("approval: %d of %d required signatures (%s)", len(signers), c.Required, strings.Join(signers, ", "))
+	}
+	return nil
+}
+
+func (p *Proposal) SignedByProposer(c *Config) bool {
+	for _, s := range p.Signers(c) {
+		if s == p.Body.Proposer {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyFiles confirms dir holds exactly the pinned files and contents.
+func (p *Proposal) VerifyFiles(dir string) error {
+	files, err := digests(dir)
+	if err != nil {
+		return err
+	}
+	for name, d := range files {
+		if p.Body.Files[name] != d {
+			return fmt.Errorf("approval: %s changed since the proposal was signed", name)
+		}
+	}
+	for name := range p.Body.Files {
+		if _, ok := files[name]; !ok {
+			return fmt.Errorf("approval: %s is in the proposal but missing from %s", name, dir)
+		}
+	}
+	return nil
+}
+
+func Load(path string) (*Proposal, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Proposal
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parse proposal: %w", err)
+	}
+	return &p, nil
+}
+
+func (p *Proposal) Write(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// DefaultProposalPath is where publish --propose writes for cycle n.
+func DefaultProposalPath(n int) string {
+	return fmt.Sprintf("release-proposal-%d.json", n)
+}
+
+// LoadKey reads a PKCS#8 PEM ed25519 private key.
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("approval: key is not PEM")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("approval: %w", err)
+	}
+	ek, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("approval: key is not ed25519")
+	}
+	return ek, nil
+}
+
+// GenerateKey writes a new private key to path and returns the base64
+// public key for the approvers file.
+func GenerateKey(path string) (string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+func decodePublic(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key is %d bytes, want %d", len(b), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
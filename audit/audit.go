@@ -0,0 +1,58 @@
+// Package audit is the append-only trail, kept in the state DB, of changes
+// an operator had to confirm: who allowed what, and when.
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+const bucket = "audit"
+
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Actor is FAIRFLOW_ACTOR, GITHUB_ACTOR or USER, whichever is set first.
+	Actor string `json:"actor"`
+	// Action names what happened, e.g. "attachment.changed".
+	Action string `json:"action"`
+	// Subject is what it happened to, e.g. a merkle file name.
+	Subject string `json:"subject"`
+	Detail  string `json:"detail"`
+}
+
+// Record appends an entry to db; the caller saves it.
+func Record(db *state.DB, action, subject, detail string) (Entry, error) {
+	e := Entry{Time: time.Now().UTC(), Actor: actor(), Action: action, Subject: subject, Detail: detail}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	// Keys sort by time; the suffix keeps two entries in one instant apart.
+	return e, db.Put(bucket, e.Time.Format("20060102T150405.000000000Z")+"-"+hex.EncodeToString(b), e)
+}
+
+// List returns the trail, oldest first.
+func List(db *state.DB) ([]Entry, error) {
+	out := make([]Entry, 0)
+	for _, k := range db.Keys(bucket) {
+		var e Entry
+		if _, err := db.Get(bucket, k, &e); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+func actor() string {
+	for _, k := range []string{"FAIRFLOW_ACTOR", "GITHUB_ACTOR", "USER"} {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return "unknown"
+}
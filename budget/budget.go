@@ -0,0 +1,156 @@
+// Package budget checks that a logical reward program paid out on several
+// chains adds up to its total budget. Token amounts are normalised by
+// decimals so 6- and 18-decimal deployments of the same asset compare.
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+const DefaultPath = "config/budgets.json"
+
+type Config struct {
+	Programs []Program `json:"programs"`
+}
+
+type Program struct {
+	Name string `json:"name"`
+	// Budget is the per-cycle total across all members, in whole tokens
+	// (e.g. "14000" or "2500.5").
+	Budget       string   `json:"budget"`
+	ToleranceBps int64    `json:"toleranceBps"`
+	Members      []Member `json:"members"`
+}
+
+type Member struct {
+	ChainID    string `json:"chain"`
+	RewardType string `json:"type"`
+	Token      string `json:"token"`
+	Decimals   int    `json:"decimals"`
+}
+
+// UnmarshalJSON requires decimals, which normalise the member's amounts: read
+// as 0, an 18-decimal token would count 10^18 times over.
+func (m *Member) UnmarshalJSON(b []byte) error {
+	type member Member
+	var v struct {
+		member
+		Decimals *int `json:"decimals"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Decimals == nil {
+		return fmt.Errorf("member %s %s: missing decimals", v.ChainID, v.RewardType)
+	}
+	if err := evm.CheckDecimals(*v.Decimals); err != nil {
+		return fmt.Errorf("member %s %s: %w", v.ChainID, v.RewardType, err)
+	}
+	*m = Member(v.member)
+	m.Decimals = *v.Decimals
+	return nil
+}
+
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse budgets: %w", err)
+	}
+	for i, p := range c.Programs {
+		if p.Name == "" {
+			return nil, fmt.Errorf("budgets: program %d has no name", i)
+		}
+		if _, ok := new(big.Rat).SetString(p.Budget); !ok {
+			return nil, fmt.Errorf("budgets: program %s: invalid budget %q", p.Name, p.Budget)
+		}
+		if len(p.Members) == 0 {
+			return nil, fmt.Errorf("budgets: program %s has no members", p.Name)
+		}
+		for _, m := range p.Members {
+			if !evm.IsAddress(m.Token) {
+				return nil, fmt.Errorf("budgets: program %s: invalid token %q", p.Name, m.Token)
+			}
+		}
+	}
+	return &c, nil
+}
+
+type MemberResult struct {
+	Member
+	File    string
+	Missing bool
+	Amount  *big.Rat
+}
+
+type Result struct {
+	Program  Program
+	Members  []MemberResult
+	Total    *big.Rat
+	Budget   *big.Rat
+	DiffBps  *big.Rat
+	OK       bool
+	Problems []string
+}
+
+// Check evaluates every program against the files in one cycle directory.
+func Check(c *Config, dir string, cycleNum int) ([]Result, error) {
+	out := make([]Result, 0, len(c.Programs))
+	for _, p := range c.Programs {
+		budget, _ := new(big.Rat).SetString(p.Budget)
+		r := Result{Program: p, Total: new(big.Rat), Budget: budget, OK: true}
+		for _, m := range p.Members {
+			name := cycle.Name{ChainID: m.ChainID, RewardType: strings.ToUpper(m.RewardType), Cycle: cycleNum}
+			if err := name.Validate(); err != nil {
+				return nil, fmt.Errorf("budgets: program %s: %w", p.Name, err)
+			}
+			mr := MemberResult{Member: m, File: name.String(), Amount: new(big.Rat)}
+			f, err := cycle.Load(filepath.Join(dir, name.String()))
+			if os.IsNotExist(err) {
+				mr.Missing = true
+				r.OK = false
+				r.Problems = append(r.Problems, fmt.Sprintf("%s missing", name))
+				r.Members = append(r.Members, mr)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			totals, err := f.SumAmounts()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if a, ok := totals[strings.ToLower(m.Token)]; ok {
+				mr.Amount.SetFrac(a, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(m.Decimals)), nil))
+			} else {
+				r.OK = false
+				r.Problems = append(r.Problems, fmt.Sprintf("%s pays nothing in %s", name, m.Token))
+			}
+			r.Total.Add(r.Total, mr.Amount)
+			r.Members = append(r.Members, mr)
+		}
+		if budget.Sign() > 0 {
+			diff := new(big.Rat).Sub(r.Total, budget)
+			diff.Abs(diff)
+			r.DiffBps = diff.Mul(diff, big.NewRat(10_000, 1)).Quo(diff, budget)
+			if r.DiffBps.Cmp(big.NewRat(p.ToleranceBps, 1)) > 0 {
+				r.OK = false
+				r.Problems = append(r.Problems, fmt.Sprintf("total %s differs from budget %s by %s bps (tolerance %d)",
+					r.Total.FloatString(6), budget.FloatString(6), r.DiffBps.FloatString(2), p.ToleranceBps))
+			}
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
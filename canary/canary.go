@@ -0,0 +1,171 @@
+// Package canary checks the team's own positions, which are paid in every
+// cycle, against the distributors: after a release each canary's leaf and
+// proof from the new file must verify on-chain against the distributor's
+// current root. That one eth_call exercises the whole path (file, published
+// root, contract), so it doubles as a continuous end-to-end health signal.
+//
+// The canaries are listed in config/canaries.json:
+//
+//	[
+//	  {"label": "ops-1", "position": "0xabc...:0"},
+//	  {"label": "ops-base", "position": "0xdef...", "chains": ["8453"]}
+//	]
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+const DefaultPath = "config/canaries.json"
+
+type Canary struct {
+	Label string `json:"label"`
+	// Position is erc721Addr:erc721Id; a bare address is id 0.
+	Position string `json:"position"`
+	// Chains limits the canary to these chain IDs; empty is every chain.
+	Chains []string `json:"chains,omitempty"`
+}
+
+// On reports whether c is expected in files of chainID.
+func (c Canary) On(chainID string) bool {
+	return len(c.Chains) == 0 || slices.Contains(c.Chains, chainID)
+}
+
+// Load reads the canary list; a missing file is none.
+func Load(path string) ([]Canary, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cs []Canary
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, c := range cs {
+		addr, id, ok := strings.Cut(c.Position, ":")
+		if !ok {
+			id = "0"
+		}
+		if !evm.IsAddress(addr) {
+			return nil, fmt.Errorf("%s: canary %q: invalid position %q", path, c.Label, c.Position)
+		}
+		cs[i].Position = strings.ToLower(addr) + ":" + id
+		if c.Label == "" {
+			cs[i].Label = cs[i].Position
+		}
+	}
+	return cs, nil
+}
+
+type Result struct {
+	Label    string `json:"label"`
+	Position string `json:"position"`
+	File     string `json:"file"`
+	OK       bool   `json:"ok"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+func (r Result) String() string {
+	if r.OK {
+		return fmt.Sprintf("%s %s (%s): verified on-chain", r.File, r.Label, r.Position)
+	}
+	return fmt.Sprintf("%s %s (%s): %s", r.File, r.Label, r.Position, r.Problem)
+}
+
+// Verifier calls the distributors of the chains in Registry.
+type Verifier struct {
+	Registry chains.Registry
+	// Sig overrides the signature of the ABI's verifyProof when set.
+	Sig     string
+	clients map[string]*evm.Client
+}
+
+func (v *Verifier) client(chainID string, ch chains.Chain) (*evm.Client, error) {
+	if c, ok := v.clients[chainID]; ok {
+		return c, nil
+	}
+	if len(ch.Endpoints()) == 0 {
+		return nil, fmt.Errorf("chain %s has no rpc configured", chainID)
+	}
+	if v.clients == nil {
+		v.clients = make(map[string]*evm.Client)
+	}
+	c := evm.NewClient(ch.Endpoints()...)
+	v.clients[chainID] = c
+	return c, nil
+}
+
+// CheckCycle verifies every canary on the chain of each merkle file in the
+// cycle directory dir. A canary missing from a file fails too: canaries are
+// paid in every cycle.
+func (v *Verifier) CheckCycle(ctx context.Context, dir string, canaries []Canary) ([]Result, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Result, 0)
+	for _, e := range entries {
+		for _, c := range canaries {
+			if !c.On(e.ChainID) {
+				continue
+			}
+			r := Result{Label: c.Label, Position: c.Position, File: e.String()}
+			if err := v.verify(ctx, dir, e.Name, c.Position); err != nil {
+				r.Problem = err.Error()
+			} else {
+				r.OK = true
+			}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (v *Verifier) verify(ctx context.Context, dir string, n cycle.Name, key string) error {
+	ud, _, err := cycle.FindProof(dir, n, key)
+	if err != nil {
+		return err
+	}
+	ch, err := v.Registry.Get(n.ChainID)
+	if err != nil {
+		return err
+	}
+	dist, ok := ch.Distributor(n.RewardType)
+	if !ok {
+		return fmt.Errorf("chain %s has no distributor for %s", n.ChainID, n.RewardType)
+	}
+	abi, err := distributor.ForChain(ch)
+	if err == nil {
+		abi, err = abi.Override("verifyProof", v.Sig)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := v.client(n.ChainID, ch)
+	if err != nil {
+		return err
+	}
+	// The latest block, not a finalized one: a release's root update is
+	// checked minutes after it lands.
+	valid, err := abi.At(dist, c).VerifyProof(ctx, "latest", ud)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("proof does not verify against distributor %s (is its root still the previous cycle's?)", dist)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package chains loads the chain registry (config/chains.json) describing
+// per-chain RPC endpoints and distributor contracts.
+package chains
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+const DefaultPath = "config/chains.json"
+
+type Chain struct {
+	Name string `json:"name"`
+	RPC  string `json:"rpc"`
+	// RPCs are fallback endpoints tried when RPC fails or rate-limits.
+	RPCs []string `json:"rpcs,omitempty"`
+	// WS is a WebSocket endpoint serving eth_subscribe, for the claim monitor
+	// of serve --watch; without one it polls.
+	WS string `json:"ws,omitempty"`
+	// Distributors maps reward type to distributor address; "*" applies to
+	// every type without its own entry.
+	Distributors map[string]string `json:"distributors"`
+	// ABI names the distributors' verified ABI in the distributor package's
+	// bundle (see vendor-abi); empty calls them through its unverified
+	// bindings.
+	ABI      string `json:"abi,omitempty"`
+	Treasury string `json:"treasury,omitempty"`
+	// Tokens holds display metadata keyed by lowercased token address.
+	Tokens   map[string]Token `json:"tokens,omitempty"`
+	Explorer Explorer         `json:"explorer"`
+}
+
+// Explorer holds block explorer URL templates with {address}, {tx} and
+// {block} placeholders. Base alone is enough for Etherscan and Blockscout,
+// which both serve /address/, /tx/ and /block/ under it.
+type Explorer struct {
+	Base    string `json:"base,omitempty"`
+	Address string `json:"address,omitempty"`
+	Tx      string `json:"tx,omitempty"`
+	Block   string `json:"block,omitempty"`
+}
+
+type Token struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// UnmarshalJSON requires decimals: a token listed without them would read
+// as 0 and show every amount in base units as whole tokens.
+func (t *Token) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Symbol   string `json:"symbol"`
+		Decimals *int   `json:"decimals"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Decimals == nil {
+		return fmt.Errorf("token %s: missing decimals", v.Symbol)
+	}
+	if err := evm.CheckDecimals(*v.Decimals); err != nil {
+		return fmt.Errorf("token %s: %w", v.Symbol, err)
+	}
+	*t = Token{Symbol: v.Symbol, Decimals: *v.Decimals}
+	return nil
+}
+
+type Registry map[string]Chain
+
+func Load(path string) (Registry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Registry
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("parse chain registry: %w", err)
+	}
+	for id, c := range r {
+		toks := make(map[string]Token, len(c.Tokens))
+		for a, t := range c.Tokens {
+			toks[strings.ToLower(a)] = t
+		}
+		c.Tokens = toks
+		r[id] = c
+	}
+	return r, nil
+}
+
+// Write saves the registry to path, replacing it.
+func (r Registry) Write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tmpdir.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func (r Registry) Get(chainID string) (Chain, error) {
+	c, ok := r[chainID]
+	if !ok {
+		return Chain{}, fmt.Errorf("chain %s not in registry", chainID)
+	}
+	return c, nil
+}
+
+func (c Chain) Distributor(rewardType string) (string, bool) {
+	if a, ok := c.Distributors[rewardType]; ok && a != "" {
+		return a, true
+	}
+	a, ok := c.Distributors["*"]
+	return a, ok && a != ""
+}
+
+// Endpoints returns RPC followed by the fallbacks, without blanks or
+// duplicates.
+func (c Chain) Endpoints() []string {
+	out := make([]string, 0, 1+len(c.RPCs))
+	seen := make(map[string]bool)
+	for _, u := range append([]string{c.RPC}, c.RPCs...) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// AddressURL links an account or contract on the chain's explorer, or
+// returns "" if none is configured.
+func (c Chain) AddressURL(addr string) string {
+	return c.Explorer.expand(c.Explorer.Address, "/address/{address}", "{address}", strings.ToLower(addr))
+}
+
+func (c Chain) TxURL(hash string) string {
+	return c.Explorer.expand(c.Explorer.Tx, "/tx/{tx}", "{tx}", hash)
+}
+
+func (c Chain) BlockURL(n uint64) string {
+	return c.Explorer.expand(c.Explorer.Block, "/block/{block}", "{block}", strconv.FormatUint(n, 10))
+}
+
+func (e Explorer) expand(tmpl, path, placeholder, value string) string {
+	if tmpl == "" {
+		if e.Base == "" {
+			return ""
+		}
+		tmpl = strings.TrimRight(e.Base, "/") + path
+	}
+	return strings.ReplaceAll(tmpl, placeholder, value)
+}
+
+func (c Chain) Token(addr string) (Token, bool) {
+	t, ok := c.Tokens[strings.ToLower(addr)]
+	return t, ok
+}
+
+// IDs returns chain IDs in numeric order.
+func (r Registry) IDs() []string {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseUint(ids[i], 10, 64)
+		b, _ := strconv.ParseUint(ids[j], 10, 64)
+		return a < b
+	})
+	return ids
+}
@@ -0,0 +1,343 @@
+// Package claimrate measures how fast recipients claim each cycle from the
+// indexer's Claimed events: for every chain, the share of the cycle's
+// positions and of each token's total claimed at fixed times after the
+// release. A claim counts towards the cycle whose root was live when it was
+// made, from its release until the next one's.
+package claimrate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// Checkpoints are the times after a release the curves are sampled at.
+var Checkpoints = []time.Duration{time.Hour, 6 * time.Hour, Day, 3 * Day, Week, 2 * Week, 30 * Day}
+
+// Point is the share claimed by a checkpoint.
+type Point struct {
+	After    string  `json:"after"`
+	Fraction float64 `json:"fraction"`
+
+	d time.Duration
+}
+
+// Curve is a cycle's claim rate over time, up to the end of its window.
+type Curve []Point
+
+// At is the share claimed within d of the release; false when the window
+// closed before d or d is not a checkpoint.
+func (c Curve) At(d time.Duration) (float64, bool) {
+	for _, p := range c {
+		if p.d == d {
+			return p.Fraction, true
+		}
+	}
+	return 0, false
+}
+
+type Token struct {
+	Token string `json:"token"`
+	Total string `json:"total"`
+	// Claimed is what was claimed in the cycle's window, Fraction its share
+	// of Total.
+	Claimed  string  `json:"claimed"`
+	Fraction float64 `json:"fraction"`
+	Curve    Curve   `json:"curve"`
+}
+
+type Chain struct {
+	ChainID   string `json:"chainId"`
+	Positions int    `json:"positions"`
+	// Claimers are the cycle's positions that claimed in its window; the
+	// curve is their share of Positions.
+	Claimers int     `json:"claimers"`
+	Curve    Curve   `json:"curve"`
+	Tokens   []Token `json:"tokens"`
+}
+
+type Cycle struct {
+	Cycle     int       `json:"cycle"`
+	Published time.Time `json:"published"`
+	// Until is the next cycle's release, or the report's time for the
+	// latest cycle.
+	Until  time.Time `json:"until"`
+	Chains []Chain   `json:"chains"`
+}
+
+// Comparison is a chain's average claim rate across the cycles whose
+// window reached each mark.
+type Comparison struct {
+	ChainID   string  `json:"chainId"`
+	Cycles    int     `json:"cycles"`
+	Within24h float64 `json:"within24h"`
+	Within7d  float64 `json:"within7d"`
+}
+
+type Report struct {
+	Generated time.Time    `json:"generated"`
+	Cycles    []Cycle      `json:"cycles"`
+	Chains    []Comparison `json:"chains"`
+}
+
+// Build measures cycle n of root for each n, given when each cycle was
+// released and the Claimed events sorted by time. A cycle not yet released
+// at now is skipped.
+func Build(root string, ns []int, release func(int) (time.Time, error), events []claims.Event, now time.Time) (*Report, error) {
+	r := &Report{Generated: now.UTC(), Cycles: make([]Cycle, 0, len(ns))}
+	for _, n := range ns {
+		pub, err := release(n)
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", n, err)
+		}
+		if pub.After(now) {
+			continue
+		}
+		until, err := release(n + 1)
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", n+1, err)
+		}
+		if until.After(now) {
+			until = now
+		}
+		c, err := measure(filepath.Join(root, cycle.DirName(n)), pub, until, events)
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", n, err)
+		}
+		c.Cycle = n
+		r.Cycles = append(r.Cycles, *c)
+	}
+	r.Chains = compare(r.Cycles)
+	return r, nil
+}
+
+// chainData is what a cycle paid out on one chain.
+type chainData struct {
+	positions map[string]bool
+	totals    map[string]*big.Int
+}
+
+func measure(dir string, pub, until time.Time, events []claims.Event) (*Cycle, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]*chainData)
+	for _, e := range entries {
+		f, err := cycle.Load(e.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.String(), err)
+		}
+		totals, err := f.SumAmounts()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.String(), err)
+		}
+		d := data[e.ChainID]
+		if d == nil {
+			d = &chainData{positions: make(map[string]bool), totals: make(map[string]*big.Int)}
+			data[e.ChainID] = d
+		}
+		for _, ud := range f.UserDatas {
+			d.positions[ud.Leaf.Key()] = true
+		}
+		for t, a := range totals {
+			if d.totals[t] == nil {
+				d.totals[t] = new(big.Int)
+			}
+			d.totals[t].Add(d.totals[t], a)
+		}
+	}
+
+	c := &Cycle{Published: pub.UTC(), Until: until.UTC()}
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareIDs)
+	for _, id := range ids {
+		c.Chains = append(c.Chains, measureChain(id, data[id], pub, until, events))
+	}
+	return c, nil
+}
+
+func measureChain(id string, d *chainData, pub, until time.Time, events []claims.Event) Chain {
+	first := make(map[string]time.Duration)
+	claimed := make(map[string][]claimAt)
+	for _, e := range events {
+		if e.ChainID != id || e.Time.Before(pub) || !e.Time.Before(until) || !d.positions[e.Key()] {
+			continue
+		}
+		after := e.Time.Sub(pub)
+		if _, ok := first[e.Key()]; !ok {
+			first[e.Key()] = after
+		}
+		if d.totals[e.Token] != nil {
+			claimed[e.Token] = append(claimed[e.Token], claimAt{after, e.Amount})
+		}
+	}
+
+	window := until.Sub(pub)
+	ch := Chain{ChainID: id, Positions: len(d.positions), Claimers: len(first), Curve: Curve{}}
+	for _, cp := range Checkpoints {
+		if cp > window {
+			break
+		}
+		n := 0
+		for _, at := range first {
+			if at < cp {
+				n++
+			}
+		}
+		ch.Curve = append(ch.Curve, point(cp, big.NewInt(int64(n)), big.NewInt(int64(ch.Positions))))
+	}
+
+	toks := make([]string, 0, len(d.totals))
+	for t := range d.totals {
+		toks = append(toks, t)
+	}
+	slices.Sort(toks)
+	for _, t := range toks {
+		total := d.totals[t]
+		tok := Token{Token: t, Total: total.String(), Curve: Curve{}}
+		sum := new(big.Int)
+		i := 0
+		for _, cp := range Checkpoints {
+			if cp > window {
+				break
+			}
+			for ; i < len(claimed[t]) && claimed[t][i].after < cp; i++ {
+				sum.Add(sum, claimed[t][i].amount)
+			}
+			tok.Curve = append(tok.Curve, point(cp, sum, total))
+		}
+		for ; i < len(claimed[t]); i++ {
+			sum.Add(sum, claimed[t][i].amount)
+		}
+		tok.Claimed = sum.String()
+		tok.Fraction = point(window, sum, total).Fraction
+		ch.Tokens = append(ch.Tokens, tok)
+	}
+	return ch
+}
+
+type claimAt struct {
+	after  time.Duration
+	amount *big.Int
+}
+
+func point(d time.Duration, part, whole *big.Int) Point {
+	p := Point{After: Label(d), d: d}
+	if whole.Sign() > 0 {
+		p.Fraction, _ = new(big.Rat).SetFrac(part, whole).Float64()
+	}
+	return p
+}
+
+// Label names a checkpoint: hours up to two days, then days.
+func Label(d time.Duration) string {
+	if d < 2*Day || d%Day != 0 {
+		return strconv.Itoa(int(d.Hours())) + "h"
+	}
+	return strconv.Itoa(int(d/Day)) + "d"
+}
+
+func compare(cs []Cycle) []Comparison {
+	type acc struct {
+		cycles          int
+		sum24, sum7     float64
+		count24, count7 int
+	}
+	by := make(map[string]*acc)
+	for _, c := range cs {
+		for _, ch := range c.Chains {
+			a := by[ch.ChainID]
+			if a == nil {
+				a = &acc{}
+				by[ch.ChainID] = a
+			}
+			a.cycles++
+			if f, ok := ch.Curve.At(Day); ok {
+				a.sum24 += f
+				a.count24++
+			}
+			if f, ok := ch.Curve.At(Week); ok {
+				a.sum7 += f
+				a.count7++
+			}
+		}
+	}
+	out := make([]Comparison, 0, len(by))
+	for id, a := range by {
+		cmp := Comparison{ChainID: id, Cycles: a.cycles}
+		if a.count24 > 0 {
+			cmp.Within24h = a.sum24 / float64(a.count24)
+		}
+		if a.count7 > 0 {
+			cmp.Within7d = a.sum7 / float64(a.count7)
+		}
+		out = append(out, cmp)
+	}
+	slices.SortFunc(out, func(a, b Comparison) int { return compareIDs(a.ChainID, b.ChainID) })
+	return out
+}
+
+// compareIDs orders chain IDs numerically.
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func WriteJSON(path string, r *Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// WriteCSV writes every curve as cycle,chain,series,after,fraction rows,
+// where series is "positions" or a token address, for plotting.
+func WriteCSV(path string, r *Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"cycle", "chain", "series", "after", "fraction"})
+	row := func(c Cycle, chain, series string, curve Curve) {
+		for _, p := range curve {
+			w.Write([]string{strconv.Itoa(c.Cycle), chain, series, p.After, strconv.FormatFloat(p.Fraction, 'f', 6, 64)})
+		}
+	}
+	for _, c := range r.Cycles {
+		for _, ch := range c.Chains {
+			row(c, ch.ChainID, "positions", ch.Curve)
+			for _, t := range ch.Tokens {
+				row(c, ch.ChainID, t.Token, t.Curve)
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,94 @@
+// Package claims reports how much of each leaf has already been claimed from
+// a distributor, either from an exported JSON snapshot or from the chain,
+// and reads the indexer's export of Claimed events for claim analytics.
+package claims
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+)
+
+type Source interface {
+	// Claimed returns the claimed amount per token of l, keyed by lowercased
+	// token address. Tokens never claimed may be absent.
+	Claimed(ctx context.Context, l cycle.Leaf) (map[string]*big.Int, error)
+}
+
+// FileSource reads {"<erc721Addr>:<erc721Id>": {"<token>": "<amount>"}}.
+type FileSource map[string]map[string]*big.Int
+
+func LoadFile(path string) (FileSource, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse claimed file: %w", err)
+	}
+	out := make(FileSource, len(raw))
+	for key, toks := range raw {
+		m := make(map[string]*big.Int, len(toks))
+		for t, s := range toks {
+			a, err := cycle.ParseAmount(s)
+			if err != nil {
+				return nil, fmt.Errorf("claimed %s %s: %w", key, t, err)
+			}
+			m[strings.ToLower(t)] = a
+		}
+		out[strings.ToLower(key)] = m
+	}
+	return out, nil
+}
+
+func (s FileSource) Claimed(_ context.Context, l cycle.Leaf) (map[string]*big.Int, error) {
+	return s[l.Key()], nil
+}
+
+// ChainSource reads the claimed amounts from the distributor at Block.
+type ChainSource struct {
+	Contract *distributor.Contract
+	Block    string
+}
+
+func (s *ChainSource) Claimed(ctx context.Context, l cycle.Leaf) (map[string]*big.Int, error) {
+	vals, err := s.Contract.ClaimedAmounts(ctx, s.Block, l)
+	if err != nil {
+		return nil, fmt.Errorf("claimed %s: %w", l.Key(), err)
+	}
+	out := make(map[string]*big.Int, len(vals))
+	for i, t := range l.Tokens {
+		out[strings.ToLower(t)] = vals[i]
+	}
+	return out, nil
+}
+
+// Unclaimed returns amount-claimed per token, dropping tokens fully claimed.
+// Claims above the leaf amount are reported as an error.
+func Unclaimed(l cycle.Leaf, claimed map[string]*big.Int) (map[string]*big.Int, error) {
+	amounts, err := l.AmountsByToken()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*big.Int)
+	for t, a := range amounts {
+		rest := new(big.Int).Set(a)
+		if c, ok := claimed[t]; ok {
+			rest.Sub(rest, c)
+		}
+		if rest.Sign() < 0 {
+			return nil, fmt.Errorf("leaf %s: claimed %s of %s exceeds amount %s", l.Key(), claimed[t], t, a)
+		}
+		if rest.Sign() > 0 {
+			out[t] = rest
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package claims
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+// Event is one token of a distributor's Claimed event, as the indexer
+// exports them.
+type Event struct {
+	ChainID     string    `json:"chainId"`
+	Distributor string    `json:"distributor"`
+	Block       uint64    `json:"blockNumber"`
+	Time        time.Time `json:"timestamp"`
+	Tx          string    `json:"txHash"`
+	ERC721Addr  string    `json:"erc721Addr"`
+	ERC721ID    string    `json:"erc721Id"`
+	Token       string    `json:"token"`
+	Amount      *big.Int  `json:"-"`
+}
+
+// Key is the claiming position, as cycle.Leaf.Key.
+func (e Event) Key() string {
+	return strings.ToLower(e.ERC721Addr) + ":" + e.ERC721ID
+}
+
+// LoadEvents reads an export of Claimed events, one JSON object per line
+// with the amount in base units as a string, sorted by time.
+func LoadEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := make([]Event, 0)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		b := sc.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+		var raw struct {
+			Event
+			Amount string `json:"amount"`
+		}
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		e := raw.Event
+		if e.Amount, err = cycle.ParseAmount(raw.Amount); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if e.Time.IsZero() {
+			return nil, fmt.Errorf("%s:%d: missing timestamp", path, line)
+		}
+		e.Token = strings.ToLower(e.Token)
+		out = append(out, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
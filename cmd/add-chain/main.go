@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/mapping"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/profile"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// add-chain onboards a network in one step: it adds the chain (RPCs,
+// distributors, tokens, explorer) to the chain registry, maps its Notion
+// Chain option and any new reward types in notion_mappings.json and, with
+// --database-id, adds the missing select options to the Notion database.
+//
+//	add-chain --chain-id 8453 --name Base --rpc https://mainnet.base.org \
+//	  --distributor 0x... --token 0x833589fcd6edb6e08f4c7c32d4f71b54bda02913 \
+//	  --type "Liquidity Mining=LM"
+//
+// Run from a terminal without the required flags it asks for them. Before
+// writing anything it checks that every RPC serves the chain ID, that the
+// distributors are contracts, and reads symbol and decimals of tokens given
+// without them. --dry-run lists the Notion options it would add; with
+// --notion-offline it reads the database's schema from the metadata cache
+// alone.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		chainsPath    = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		mappingPath   = flag.String("mapping", mapping.DefaultPath, "Notion mapping JSON")
+		chainID       = flag.String("chain-id", "", "chain ID")
+		name          = flag.String("name", "", "chain name in the registry")
+		notionName    = flag.String("notion-name", "", "option name in the Notion Chain select (default --name)")
+		rpc           = flag.String("rpc", "", "primary RPC URL")
+		rpcs          = flag.String("rpcs", "", "comma-separated fallback RPC URLs")
+		distributor   = flag.String("distributor", "", "distributor address for every reward type")
+		distributors  = flag.String("distributors", "", "per-type distributors, TYPE=0x... comma-separated")
+		treasury      = flag.String("treasury", "", "treasury address funding the distributors")
+		explorer      = flag.String("explorer", "", "block explorer base URL (Etherscan or Blockscout style)")
+		update        = flag.Bool("update", false, "merge into a chain already in the registry")
+		skipChecks    = flag.Bool("skip-checks", false, "do not validate RPCs, distributors and tokens on-chain")
+		dryRun        = flag.Bool("dry-run", false, "print the changes instead of writing them")
+		databaseID    = flag.String("database-id", "", "cycle database whose Chain and Type options to extend (empty = leave Notion alone)")
+		notionToken   = flag.String("notion-token", os.Getenv("NOTION_TOKEN"), "Notion token (or env NOTION_TOKEN)")
+		notionOAuth   = flag.String("notion-oauth", notion.DefaultOAuthConfigPath, "Notion OAuth client config, used when no token is set (see notion-auth)")
+		notionVersion = flag.String("notion-version", notion.APIVersion, "Notion API version for Notion-Version header")
+		propChain     = flag.String("prop-chain", "Chain", "Select property name")
+		propType      = flag.String("prop-type", "Type", "Multi-select property name")
+		timeout       = flag.Duration("timeout", 15*time.Second, "timeout for each on-chain check")
+	)
+	var tokens, types multiFlag
+	flag.Var(&tokens, "token", "reward token 0xADDR or 0xADDR=SYMBOL:DECIMALS (read on-chain when omitted); repeatable")
+	flag.Var(&types, "type", "reward type as \"Notion name=CODE\", e.g. \"Liquidity Mining=LM\"; repeatable")
+	metaCache := notion.AddCacheFlags(flag.CommandLine, true)
+	prof := profile.AddFlags(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "add-chain"); err != nil {
+		fatal(err)
+	}
+	if _, err := prof.Apply(flag.CommandLine, "add-chain"); err != nil {
+		fatal(err)
+	}
+
+	if interactive() {
+		in := bufio.NewReader(os.Stdin)
+		ask(in, chainID, "Chain ID")
+		ask(in, name, "Chain name")
+		ask(in, rpc, "RPC URL")
+		if *distributor == "" && *distributors == "" {
+			ask(in, distributor, "Distributor address (all reward types)")
+		}
+		if len(tokens) == 0 {
+			for {
+				var t string
+				ask(in, &t, "Reward token 0xADDR[=SYMBOL:DECIMALS] (empty to finish)")
+				if t == "" {
+					break
+				}
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	if *chainID == "" || *name == "" || *rpc == "" {
+		fatal(errors.New("missing --chain-id, --name or --rpc"))
+	}
+	if _, err := strconv.ParseUint(*chainID, 10, 64); err != nil {
+		fatal(fmt.Errorf("invalid --chain-id %q", *chainID))
+	}
+	if *notionName == "" {
+		*notionName = *name
+	}
+
+	reg, err := chains.Load(*chainsPath)
+	if os.IsNotExist(err) {
+		reg, err = make(chains.Registry), nil
+	}
+	if err != nil {
+		fatal(err)
+	}
+	ch, exists := reg[*chainID]
+	if exists && !*update {
+		fatal(fmt.Errorf("chain %s is already in %s (use --update to merge)", *chainID, *chainsPath))
+	}
+	ch.Name, ch.RPC = *name, *rpc
+	for _, u := range splitList(*rpcs) {
+		if u != *rpc && !slices.Contains(ch.RPCs, u) {
+			ch.RPCs = append(ch.RPCs, u)
+		}
+	}
+	if ch.Distributors == nil {
+		ch.Distributors = make(map[string]string)
+	}
+	if *distributor != "" {
+		ch.Distributors["*"] = *distributor
+	}
+	for _, kv := range splitList(*distributors) {
+		typ, addr, ok := strings.Cut(kv, "=")
+		if !ok {
+			fatal(fmt.Errorf("invalid --distributors entry %q (want TYPE=0x...)", kv))
+		}
+		ch.Distributors[strings.ToUpper(strings.TrimSpace(typ))] = strings.TrimSpace(addr)
+	}
+	if len(ch.Distributors) == 0 {
+		fatal(errors.New("missing --distributor or --distributors"))
+	}
+	for typ, addr := range ch.Distributors {
+		if !evm.IsAddress(addr) {
+			fatal(fmt.Errorf("distributor %s: %q is not an address", typ, addr))
+		}
+	}
+	if *treasury != "" {
+		if !evm.IsAddress(*treasury) {
+			fatal(fmt.Errorf("treasury %q is not an address", *treasury))
+		}
+		ch.Treasury = *treasury
+	}
+	if *explorer != "" {
+		ch.Explorer.Base = *explorer
+	}
+
+	var cli *evm.Client
+	if !*skipChecks {
+		for _, u := range ch.Endpoints() {
+			if err := checkRPC(u, *chainID, *timeout); err != nil {
+				fatal(fmt.Errorf("rpc %s: %w", u, err))
+			}
+			fmt.Printf("rpc %s serves chain %s\n", u, *chainID)
+		}
+		cli = evm.NewClient(ch.Endpoints()...)
+		for _, typ := range slices.Sorted(maps.Keys(ch.Distributors)) {
+			if err := checkContract(cli, ch.Distributors[typ], *timeout); err != nil {
+				fatal(fmt.Errorf("distributor %s %s: %w", typ, ch.Distributors[typ], err))
+			}
+			fmt.Printf("distributor %s %s is a contract\n", typ, ch.Distributors[typ])
+		}
+	}
+	if ch.Tokens == nil {
+		ch.Tokens = make(map[string]chains.Token)
+	}
+	for _, spec := range tokens {
+		addr, tok, err := resolveToken(cli, spec, *timeout)
+		if err != nil {
+			fatal(err)
+		}
+		ch.Tokens[addr] = tok
+		fmt.Printf("token %s %s (%d decimals)\n", addr, tok.Symbol, tok.Decimals)
+	}
+	reg[*chainID] = ch
+
+	m, err := mapping.Load(*mappingPath)
+	if errors.Is(err, os.ErrNotExist) {
+		m, err = mapping.New(), nil
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if id, ok := m.Chains[*notionName]; ok && id != *chainID {
+		fatal(fmt.Errorf("Notion chain %q already maps to chain %s", *notionName, id))
+	}
+	m.Chains[*notionName] = *chainID
+	typeNames := make([]string, 0, len(types))
+	for _, kv := range types {
+		n, code, ok := strings.Cut(kv, "=")
+		n, code = strings.TrimSpace(n), strings.ToUpper(strings.TrimSpace(code))
+		if !ok || n == "" || code == "" {
+			fatal(fmt.Errorf("invalid --type %q (want \"Notion name=CODE\")", kv))
+		}
+		if c, ok := m.Types[n]; ok && c != code {
+			fatal(fmt.Errorf("Notion type %q already maps to %s", n, c))
+		}
+		m.Types[n] = code
+		typeNames = append(typeNames, n)
+	}
+	if problems := m.Validate(); len(problems) > 0 {
+		fatal(fmt.Errorf("%s: %w", *mappingPath, errors.Join(problems...)))
+	}
+
+	ctx := context.Background()
+	client := func() (*notion.Client, error) {
+		token := ""
+		if !metaCache.Offline() {
+			var err error
+			if token, err = notion.ResolveToken(ctx, *notionToken, *notionOAuth); err != nil {
+				return nil, err
+			}
+		}
+		nc := notion.NewClient(token, *notionVersion)
+		nc.SetCache(metaCache.Cache())
+		return nc, nil
+	}
+	if *dryRun {
+		entry, _ := json.MarshalIndent(map[string]chains.Chain{*chainID: ch}, "", "  ")
+		fmt.Printf("would write %s:\n%s\n", *chainsPath, entry)
+		fmt.Printf("would map Notion chain %q -> %s", *notionName, *chainID)
+		for _, n := range typeNames {
+			fmt.Printf(", type %q -> %s", n, m.Types[n])
+		}
+		fmt.Printf(" in %s\n", *mappingPath)
+		if *databaseID != "" {
+			if err := previewOptions(ctx, client, *databaseID, map[string][]string{*propChain: {*notionName}, *propType: typeNames}); err != nil {
+				fmt.Printf("would add missing %s/%s options to Notion database %s (cannot read its schema: %v)\n", *propChain, *propType, *databaseID, err)
+			}
+		}
+		return
+	}
+	if metaCache.Offline() {
+		fatal(errors.New("--notion-offline only works with --dry-run"))
+	}
+	if err := reg.Write(*chainsPath); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Wrote chain %s to %s\n", *chainID, *chainsPath)
+	if err := m.Write(*mappingPath); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Wrote %s\n", *mappingPath)
+
+	if *databaseID == "" {
+		return
+	}
+	nc, err := client()
+	if err != nil {
+		fatal(err)
+	}
+	src, err := nc.ResolveSource(ctx, *databaseID)
+	if err != nil {
+		fatal(err)
+	}
+	for prop, names := range map[string][]string{*propChain: {*notionName}, *propType: typeNames} {
+		if len(names) == 0 {
+			continue
+		}
+		added, err := nc.AddSelectOptions(ctx, src, prop, names...)
+		if err != nil {
+			fatal(err)
+		}
+		for _, n := range added {
+			fmt.Printf("Added Notion %s option %q\n", prop, n)
+		}
+	}
+}
+
+// previewOptions prints the select options the run would add to the
+// database, from its schema as cached when the cache holds it.
+func previewOptions(ctx context.Context, client func() (*notion.Client, error), databaseID string, options map[string][]string) error {
+	nc, err := client()
+	if err != nil {
+		return err
+	}
+	src, err := nc.ResolveSource(ctx, databaseID)
+	if err != nil {
+		return err
+	}
+	adds := 0
+	for _, prop := range slices.Sorted(maps.Keys(options)) {
+		if len(options[prop]) == 0 {
+			continue
+		}
+		missing, err := nc.MissingSelectOptions(ctx, src, prop, options[prop]...)
+		if err != nil {
+			return err
+		}
+		for _, n := range missing {
+			fmt.Printf("would add Notion %s option %q\n", prop, n)
+		}
+		adds += len(missing)
+	}
+	if adds == 0 {
+		fmt.Printf("Notion database %s already has every option\n", databaseID)
+	}
+	return nil
+}
+
+func checkRPC(url, chainID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	id, err := evm.NewClient(url).ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if id.String() != chainID {
+		return fmt.Errorf("serves chain %s, not %s", id, chainID)
+	}
+	return nil
+}
+
+func checkContract(cli *evm.Client, addr string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	code, err := cli.CodeAt(ctx, addr, "latest")
+	if err != nil {
+		return err
+	}
+	if len(code) == 0 {
+		return errors.New("no contract code at that address")
+	}
+	return nil
+}
+
+// resolveToken parses 0xADDR[=SYMBOL:DECIMALS], reading what is missing from
+// the chain (cli is nil with --skip-checks).
+func resolveToken(cli *evm.Client, spec string, timeout time.Duration) (string, chains.Token, error) {
+	addr, meta, _ := strings.Cut(spec, "=")
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if !evm.IsAddress(addr) {
+		return "", chains.Token{}, fmt.Errorf("token %q is not an address", addr)
+	}
+	var tok chains.Token
+	decimals := -1
+	if meta != "" {
+		sym, d, ok := strings.Cut(meta, ":")
+		tok.Symbol = strings.TrimSpace(sym)
+		if ok {
+			n, err := strconv.Atoi(d)
+			if err != nil || evm.CheckDecimals(n) != nil {
+				return "", tok, fmt.Errorf("token %s: invalid decimals %q", addr, d)
+			}
+			decimals = n
+		}
+	}
+	if tok.Symbol != "" && decimals >= 0 {
+		tok.Decimals = decimals
+		return addr, tok, nil
+	}
+	if cli == nil {
+		return "", tok, fmt.Errorf("token %s: give SYMBOL:DECIMALS with --skip-checks", addr)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if decimals < 0 {
+		d, err := cli.Decimals(ctx, addr)
+		if err != nil {
+			return "", tok, fmt.Errorf("token %s: decimals(): %w", addr, err)
+		}
+		decimals = d
+	}
+	if tok.Symbol == "" {
+		s, err := cli.Symbol(ctx, addr)
+		if err != nil {
+			return "", tok, fmt.Errorf("token %s: %w", addr, err)
+		}
+		tok.Symbol = s
+	}
+	tok.Decimals = decimals
+	return addr, tok, nil
+}
+
+// interactive reports whether stdin is a terminal to prompt on.
+func interactive() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ask prompts for v unless it is already set; end of input answers "".
+func ask(in *bufio.Reader, v *string, prompt string) {
+	if *v != "" {
+		return
+	}
+	fmt.Printf("%s: ", prompt)
+	line, _ := in.ReadString('\n')
+	*v = strings.TrimSpace(line)
+}
+
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+type multiFlag []string
+
+func (m *multiFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/alert"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// alert-on-failure runs a pipeline step and pages the on-call when it fails:
+//
+//	alert-on-failure --class validation -- verify --all
+//
+// Output passes through unchanged and the step's exit code is preserved.
+// PAGERDUTY_ROUTING_KEY and/or OPSGENIE_API_KEY select where alerts go.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		class     = flag.String("class", string(alert.ClassPublish), "failure class: validation, sync, publish, monitor or notification")
+		source    = flag.String("source", hostname(), "alert source (host or pipeline name)")
+		dedup     = flag.String("dedup-key", "", "incident dedup key (default: class + command)")
+		statePath = flag.String("state", state.DefaultPath, "state DB whose retry queue keeps alerts that fail to send")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "alert-on-failure"); err != nil {
+		fatal(err)
+	}
+	if flag.NArg() == 0 {
+		fatal(errors.New("usage: alert-on-failure [--class c] -- <command> [args...]"))
+	}
+
+	var tail tailBuffer
+	cmd := exec.Command(flag.Arg(0), flag.Args()[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &tail)
+	err := cmd.Run()
+	if err == nil {
+		return
+	}
+	code := 1
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	}
+
+	sender := alert.FromEnv()
+	if sender == nil {
+		fmt.Fprintln(os.Stderr, "alert-on-failure: no PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY set; not paging")
+		tmpdir.Exit(code)
+	}
+	command := strings.Join(flag.Args(), " ")
+	a := alert.Alert{
+		Summary:  fmt.Sprintf("fairflow %s failure: %s", *class, command),
+		Source:   *source,
+		Class:    alert.Class(*class),
+		DedupKey: *dedup,
+		Details:  map[string]string{"command": command, "exit": err.Error(), "stderr": tail.String()},
+	}
+	if a.DedupKey == "" {
+		a.DedupKey = "fairflow:" + *class + ":" + flag.Arg(0)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := sender.Send(ctx, a); err != nil {
+		fmt.Fprintln(os.Stderr, "alert-on-failure: sending alert:", err)
+		if err := queueAlert(*statePath, a, err); err != nil {
+			fmt.Fprintln(os.Stderr, "alert-on-failure: queueing alert:", err)
+		}
+	}
+	tmpdir.Exit(code)
+}
+
+func queueAlert(path string, a alert.Alert, cause error) error {
+	db, err := state.Open(path)
+	if err != nil {
+		return err
+	}
+	job, err := queue.New(db).Enqueue(alert.JobSend, a, cause)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "alert-on-failure: queued as job %s; retry with flush-queue\n", job.ID)
+	return nil
+}
+
+// tailBuffer keeps the last few KB of stderr for the alert body.
+type tailBuffer struct{ bytes.Buffer }
+
+const tailMax = 4096
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.Buffer.Write(p)
+	if over := t.Len() - tailMax; over > 0 {
+		t.Next(over)
+	}
+	return len(p), nil
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
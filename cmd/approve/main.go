@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/approval"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// approve countersigns a release proposal written by publish --propose.
+//
+//	approve --keygen ~/.fairflow/approver.pem     # once per operator
+//	approve --proposal release-proposal-21.json --cycle-dir cycle-21 --signer bob --key ~/.fairflow/approver.pem
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		proposalPath = flag.String("proposal", "", "release proposal to countersign")
+		cycleDir     = flag.String("cycle-dir", "", "cycle-N directory the proposal covers")
+		signer       = flag.String("signer", os.Getenv("FAIRFLOW_SIGNER"), "your name in the approvers file (or env FAIRFLOW_SIGNER)")
+		keyPath      = flag.String("key", os.Getenv("FAIRFLOW_SIGNING_KEY"), "your ed25519 private key PEM (or env FAIRFLOW_SIGNING_KEY)")
+		approvers    = flag.String("approvers", approval.DefaultConfigPath, "approvers JSON")
+		keygen       = flag.String("keygen", "", "write a new private key to this path, print its public key and exit")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "approve"); err != nil {
+		fatal(err)
+	}
+
+	if *keygen != "" {
+		pub, err := approval.GenerateKey(*keygen)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Wrote %s. Add this public key to %s:\n%s\n", *keygen, *approvers, pub)
+		return
+	}
+	if *proposalPath == "" || *cycleDir == "" || *signer == "" || *keyPath == "" {
+		fatal(errors.New("missing --proposal, --cycle-dir, --signer or --key"))
+	}
+	cfg, err := approval.LoadConfig(*approvers)
+	if err != nil {
+		fatal(err)
+	}
+	p, err := approval.Load(*proposalPath)
+	if err != nil {
+		fatal(err)
+	}
+	if *signer == p.Body.Proposer {
+		fatal(fmt.Errorf("%s proposed this release; a different operator must approve it", *signer))
+	}
+	if !p.SignedByProposer(cfg) {
+		fatal(fmt.Errorf("proposal is not validly signed by its proposer %s", p.Body.Proposer))
+	}
+	// Only approve what is actually on disk.
+	if err := p.VerifyFiles(*cycleDir); err != nil {
+		fatal(err)
+	}
+	key, err := approval.LoadKey(*keyPath)
+	if err != nil {
+		fatal(err)
+	}
+	p.Sign(*signer, key)
+	signed := false
+	for _, s := range p.Signers(cfg) {
+		signed = signed || s == *signer
+	}
+	if !signed {
+		fatal(fmt.Errorf("key does not match %s's public key in %s", *signer, *approvers))
+	}
+	if err := p.Write(*proposalPath); err != nil {
+		fatal(err)
+	}
+	runsummary.Output(*proposalPath)
+	fmt.Printf("Approved cycle %d (%d/%d signatures: %v)\n", p.Body.Cycle, len(p.Signers(cfg)), cfg.Required, p.Signers(cfg))
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
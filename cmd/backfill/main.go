@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// backfill brings cycle directories that predate notion-sync (cycles 1-15)
+// up to what the tooling expects of every cycle: a manifest recording each
+// merkle file's sha256, size and root, a stats.json, and an
+// entry in the changelog, so verify --all and the history cover the whole
+// archive.
+//
+//	backfill --to 15 --dry-run
+//
+// Manifest entries, stats and changelog entries that already exist are left
+// alone, so it is safe to rerun. A changelog entry is dated by the commit
+// that added the directory, or the files' modification time outside git.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		root      = flag.String("root", ".", "repo root containing cycle-N directories")
+		from      = flag.Int("from", 1, "first cycle to backfill")
+		to        = flag.Int("to", 0, "last cycle to backfill (0 = all)")
+		changelog = flag.String("changelog", summary.DefaultChangelog, "history to add missing cycles to; .json for JSON (empty = off)")
+		dryRun    = flag.Bool("dry-run", false, "report what would be written without writing it")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "backfill"); err != nil {
+		fatal(err)
+	}
+
+	cycles, err := cycle.Cycles(*root)
+	if err != nil {
+		fatal(err)
+	}
+	failed := 0
+	for _, n := range cycles {
+		if n < *from || (*to > 0 && n > *to) {
+			continue
+		}
+		dir := filepath.Join(*root, cycle.DirName(n))
+		done, err := backfill(dir, n, *changelog, *dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			failed++
+			continue
+		}
+		if len(done) == 0 {
+			done = []string{"nothing to do"}
+		}
+		fmt.Printf("%s: %s\n", dir, strings.Join(done, ", "))
+	}
+	if failed > 0 {
+		fatal(fmt.Errorf("%d cycle(s) could not be backfilled", failed))
+	}
+}
+
+// backfill fills in cycle n's directory and returns what it wrote.
+func backfill(dir string, n int, changelog string, dryRun bool) ([]string, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	m, err := cycle.LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]string, 0)
+	added := 0
+	for _, e := range entries {
+		if e.Cycle != n {
+			return nil, fmt.Errorf("%s is named for cycle %d", e.String(), e.Cycle)
+		}
+		if m.Files[e.String()].SHA256 != "" {
+			continue
+		}
+		entry, err := describe(e.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.String(), err)
+		}
+		m.Files[e.String()] = entry
+		added++
+	}
+	if added > 0 {
+		if !dryRun {
+			if err := m.Write(dir); err != nil {
+				return nil, err
+			}
+		}
+		done = append(done, fmt.Sprintf("%d manifest entries", added))
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, cycle.StatsName)); os.IsNotExist(err) {
+		s, err := summary.BuildStats(dir, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		if !dryRun {
+			if err := summary.WriteStats(dir, s); err != nil {
+				return nil, err
+			}
+		}
+		done = append(done, cycle.StatsName)
+	}
+
+	if changelog != "" {
+		has, err := summary.ChangelogHas(changelog, n)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			s, err := summary.Build(dir)
+			if err != nil {
+				return nil, err
+			}
+			if !dryRun {
+				if _, err := summary.AppendChangelog(changelog, s.ChangelogEntry(firstCommitted(dir, entries), "")); err != nil {
+					return nil, err
+				}
+				runsummary.Output(changelog)
+			}
+			done = append(done, "changelog entry")
+		}
+	}
+	return done, nil
+}
+
+// describe is the manifest entry of the merkle file at path. Its root is
+// the stored tree's, which must agree with the root field; verify checks
+// the rest of the tree.
+func describe(path string) (cycle.ManifestEntry, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return cycle.ManifestEntry{}, err
+	}
+	digest, err := verify.Digest(path)
+	if err != nil {
+		return cycle.ManifestEntry{}, err
+	}
+	f, err := cycle.Load(path)
+	if err != nil {
+		return cycle.ManifestEntry{}, err
+	}
+	root := f.Root
+	if len(f.Tree) > 0 {
+		root = f.Tree[0]
+	}
+	if root == "" || !strings.EqualFold(root, f.Root) {
+		return cycle.ManifestEntry{}, fmt.Errorf("root %q does not match tree[0] %q", f.Root, root)
+	}
+	return cycle.ManifestEntry{SHA256: digest, Size: fi.Size(), Root: root, Backfilled: true}, nil
+}
+
+// firstCommitted is when dir was first committed, or the newest
+// modification time of its merkle files when git cannot say.
+func firstCommitted(dir string, entries []cycle.Entry) time.Time {
+	out, err := exec.Command("git", "log", "--diff-filter=A", "--format=%aI", "--", dir).Output()
+	if lines := strings.Fields(string(out)); err == nil && len(lines) > 0 {
+		if t, err := time.Parse(time.RFC3339, lines[len(lines)-1]); err == nil {
+			return t
+		}
+	}
+	var t time.Time
+	for _, e := range entries {
+		if fi, err := os.Stat(e.Path); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// bench generates synthetic cycles of each size and times the paths that
+// grow with them: parsing the file, running the verify rules and serving a
+// proof for every leaf. A stage over its --budget, in time per recipient,
+// fails the run, so a regression shows up wherever bench runs.
+//
+//	bench --sizes 100000 --budget parse=20us,verify=90us,proofs=8us
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		sizes       = flag.String("sizes", "100000,1000000,5000000", "comma-separated recipient counts")
+		outDir      = flag.String("out-dir", "bench-fixtures", "directory for generated fixtures")
+		tokens      = flag.Int("tokens", 2, "tokens per leaf")
+		seed        = flag.Int64("seed", 1, "generator seed")
+		parallelism = flag.Int("parallelism", 0, "goroutines for hashing (0 = one per CPU)")
+		budgetFlag  = flag.String("budget", defaultBudget, "per-recipient time limits as stage=duration for parse, verify and proofs (empty = report only)")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "bench"); err != nil {
+		fatal(err)
+	}
+	par.SetWorkers(*parallelism)
+
+	ns, err := parseSizes(*sizes)
+	if err != nil {
+		fatal(err)
+	}
+	budget, err := parseBudget(*budgetFlag)
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fatal(err)
+	}
+	over := make([]string, 0)
+	for _, n := range ns {
+		start := time.Now()
+		f, err := fixtures.Generate(fixtures.Options{Recipients: n, Tokens: *tokens, Seed: *seed})
+		if err != nil {
+			fatal(err)
+		}
+		out := filepath.Join(*outDir, cycle.Name{ChainID: "1", RewardType: "BENCH", Cycle: n}.String())
+		if err := cycle.Write(out, f); err != nil {
+			fatal(fmt.Errorf("write %s: %w", out, err))
+		}
+		st, err := os.Stat(out)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("%s: %d recipients, %.1f MB, root %s (%s)\n", out, n, float64(st.Size())/1e6, f.Root, time.Since(start).Round(time.Millisecond))
+		for _, st := range stages {
+			took, err := st.run(out)
+			if err != nil {
+				fatal(fmt.Errorf("%s %s: %w", st.name, out, err))
+			}
+			per := took / time.Duration(n)
+			line := fmt.Sprintf("  %-7s %10s  %8s/recipient", st.name, took.Round(time.Millisecond), per)
+			if limit, ok := budget[st.name]; ok {
+				line += fmt.Sprintf("  (budget %s)", limit)
+				if per > limit {
+					over = append(over, fmt.Sprintf("%s at %d recipients: %s/recipient, budget %s", st.name, n, per, limit))
+					line += "  OVER"
+				}
+			}
+			fmt.Println(line)
+		}
+	}
+	if len(over) > 0 {
+		fatal(fmt.Errorf("over budget: %s", strings.Join(over, "; ")))
+	}
+}
+
+// defaultBudget is about three times what 100k recipients took when it was
+// set; tighten it along with speedups.
+const defaultBudget = "parse=20us,verify=90us,proofs=8us"
+
+type stage struct {
+	name string
+	run  func(path string) (time.Duration, error)
+}
+
+var stages = []stage{
+	{"parse", func(path string) (time.Duration, error) {
+		start := time.Now()
+		_, err := cycle.LoadMapped(path)
+		return time.Since(start), err
+	}},
+	{"verify", func(path string) (time.Duration, error) {
+		f, err := cycle.LoadMapped(path)
+		if err != nil {
+			return 0, err
+		}
+		start := time.Now()
+		fs := verify.Run(verify.NewTarget(path, f), verify.Options{})
+		took := time.Since(start)
+		if verify.HasErrors(fs) {
+			return 0, fmt.Errorf("fixture failed verification: %s", fs[0])
+		}
+		return took, nil
+	}},
+	{"proofs", func(path string) (time.Duration, error) {
+		f, err := cycle.LoadMapped(path)
+		if err != nil {
+			return 0, err
+		}
+		start := time.Now()
+		tr, err := f.ParseTree()
+		if err != nil {
+			return 0, err
+		}
+		for _, l := range tr.Leaves() {
+			if _, err := tr.Proof(l); err != nil {
+				return 0, err
+			}
+		}
+		return time.Since(start), nil
+	}},
+}
+
+// parseBudget reads stage=duration pairs, e.g. "parse=5us,verify=60us".
+func parseBudget(s string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(part, "=")
+		if !ok || !slices.ContainsFunc(stages, func(st stage) bool { return st.name == name }) {
+			return nil, fmt.Errorf("invalid budget %q (want parse, verify or proofs=duration)", part)
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid budget %q: want a positive duration", part)
+		}
+		out[name] = d
+	}
+	return out, nil
+}
+
+func parseSizes(s string) ([]int, error) {
+	out := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid size %q", part)
+		}
+		out = append(out, n)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no sizes given")
+	}
+	return out, nil
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// bloom writes a <name>.bloom.json sidecar next to every merkle file of a
+// cycle, so the front-end can rule out ineligible positions without a proof
+// lookup. Run it after the files are final: verify flags stale sidecars.
+//
+//	bloom --cycle-dir cycle-21
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir = flag.String("cycle-dir", "", "path to the cycle-N directory")
+		fpRate   = flag.Float64("fp-rate", cycle.DefaultBloomFPRate, "false-positive rate to size each filter for")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "bloom"); err != nil {
+		fatal(err)
+	}
+	if *cycleDir == "" {
+		fatal(errors.New("missing --cycle-dir"))
+	}
+	entries, err := cycle.ScanDir(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(entries) == 0 {
+		fatal(fmt.Errorf("no merkle files in %s", *cycleDir))
+	}
+	for _, e := range entries {
+		f, err := cycle.Load(e.Path)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", e.Path, err))
+		}
+		b, err := cycle.NewBloom(e.Name, f, *fpRate)
+		if err != nil {
+			fatal(err)
+		}
+		if err := b.Write(*cycleDir, e.Name); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Wrote %s: %d positions, %d bytes, k=%d\n", filepath.Join(*cycleDir, e.BloomName()), b.Count, len(b.Bits), b.K)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
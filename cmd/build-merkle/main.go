@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/allocation"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+	"github.com/KyberNetwork/fairflow-reward/snapshot"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+const zeroSalt = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+func main() {
+	defer tmpdir.Cleanup()
+	budgets := make(allocation.Budgets)
+	var (
+		snapshotSrc   = flag.String("snapshot", "", "snapshot service URL, CSV endpoint or local file with points per position")
+		format        = flag.String("snapshot-format", string(snapshot.FormatAuto), "snapshot format: auto, json or csv")
+		snapshotToken = flag.String("snapshot-token", os.Getenv("SNAPSHOT_TOKEN"), "bearer token for the snapshot service (or env SNAPSHOT_TOKEN)")
+		rewardType    = flag.String("type", "", "reward type (e.g. LM); selects the allocation strategy from --allocation-config")
+		allocConfig   = flag.String("allocation-config", allocation.DefaultConfigPath, "per-type allocation strategies JSON")
+		strategy      = flag.String("allocation", "", "override the configured strategy (pro-rata, capped-pro-rata, tiered, fixed)")
+		outPath       = flag.String("out", "", "merkle file to write")
+		start         = flag.Int64("start", 0, "startTimestamp (unix seconds)")
+		end           = flag.Int64("end", 0, "endTimestamp (unix seconds)")
+		metadata      = flag.String("metadata", "", "metadata string stored in the file")
+		salt          = flag.String("salt", zeroSalt, "salt stored in the file")
+		vestingOut    = flag.String("vesting-out", "", "vester schedule CSV to write when the type's config has a vesting split")
+		parallelism   = flag.Int("parallelism", 0, "goroutines for hashing (0 = one per CPU)")
+		hashScheme    = flag.String("hash-scheme", "", "override the type's merkle hash scheme: standard, keccak or sha256")
+		reference     = flag.String("reference", "", "merkle file the target distributor already accepted (e.g. last cycle's); its tree must rebuild with this tool's leaf encoding")
+		unverified    = flag.Bool("unverified-encoding", false, "write without --reference, for a distributor whose leaf encoding is known to match (test deployments)")
+	)
+	flag.Var(budgets, "budget", "token budget as <token>=<base units>; repeat per token")
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "build-merkle"); err != nil {
+		fatal(err)
+	}
+	par.SetWorkers(*parallelism)
+
+	if *snapshotSrc == "" || *outPath == "" {
+		fatal(errors.New("missing --snapshot or --out"))
+	}
+	if len(budgets) == 0 {
+		fatal(errors.New("missing --budget"))
+	}
+	if *start == 0 || *end <= *start {
+		fatal(errors.New("--start and --end must be set with end after start"))
+	}
+	switch {
+	case *reference != "":
+		ref, err := cycle.Load(*reference)
+		if err != nil {
+			fatal(err)
+		}
+		if err := cycle.CheckLeafEncoding(ref); err != nil {
+			fatal(fmt.Errorf("%s: %w; the distributor could not verify the file built here", *reference, err))
+		}
+	case !*unverified:
+		fatal(errors.New("missing --reference: nothing shows the distributor hashes leaves as this tool does (--unverified-encoding to build anyway)"))
+	}
+	spec, err := allocation.LoadSpec(*allocConfig, strings.ToUpper(*rewardType), *strategy)
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := allocation.New(spec); err != nil {
+		fatal(err)
+	}
+	if *hashScheme != "" {
+		spec.HashScheme = *hashScheme
+	}
+	if _, err := merkle.SchemeByName(spec.HashScheme); err != nil {
+		fatal(err)
+	}
+	if spec.Vesting != nil && *vestingOut == "" {
+		fatal(fmt.Errorf("type %s has a vesting split; set --vesting-out", strings.ToUpper(*rewardType)))
+	}
+
+	ctx := context.Background()
+	entries, err := snapshot.Load(ctx, *snapshotSrc, snapshot.Options{Format: snapshot.Format(*format), Token: *snapshotToken, Timeout: 2 * time.Minute})
+	if err != nil {
+		fatal(fmt.Errorf("load snapshot: %w", err))
+	}
+	entries = snapshot.Merge(entries)
+	if len(entries) == 0 {
+		fatal(errors.New("snapshot has no entries"))
+	}
+	res, err := allocation.Build(spec, allocation.Input{Entries: entries, Budgets: budgets, Start: *start, End: *end, Metadata: *metadata, Salt: *salt})
+	if err != nil {
+		fatal(err)
+	}
+	for _, t := range res.Tokens {
+		fmt.Printf("%s: allocated %s of %s (dust %s)\n", t, res.Allocated[t], budgets[t], res.Dust[t])
+		if n := res.BelowMin[t]; n > 0 {
+			fmt.Printf("%s: %d recipients below minPayout, paid to dust\n", t, n)
+		}
+		if res.Treasury != "" && res.Dust[t].Sign() > 0 {
+			fmt.Printf("%s: dust %s paid to treasury %s\n", t, res.Dust[t], res.Treasury)
+		}
+	}
+	for _, t := range res.Tokens {
+		if vt := res.Vested[t]; vt != nil {
+			fmt.Printf("%s: %s routed to vesting (%d bps immediate)\n", t, vt, spec.Vesting.ImmediateBps)
+		}
+	}
+	f := res.File
+	if err := cycle.Write(*outPath, f); err != nil {
+		fatal(fmt.Errorf("write %s: %w", *outPath, err))
+	}
+	fmt.Printf("Wrote %s: %d recipients, root %s\n", *outPath, len(f.UserDatas), f.Root)
+
+	if spec.Vesting != nil {
+		vf, err := os.Create(*vestingOut)
+		if err != nil {
+			fatal(err)
+		}
+		if err := allocation.WriteSchedule(vf, res.Grants); err != nil {
+			vf.Close()
+			fatal(fmt.Errorf("write %s: %w", *vestingOut, err))
+		}
+		if err := vf.Close(); err != nil {
+			fatal(err)
+		}
+		runsummary.Output(*vestingOut)
+		fmt.Printf("Wrote %s: %d vesting grants\n", *vestingOut, len(res.Grants))
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
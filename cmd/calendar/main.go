@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	_ "time/tzdata" // --schedule-tz zones without a system zoneinfo
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// calendar answers which cycle the release schedule has out at a time, and
+// when the next one is due; with --cycle, when that cycle is released.
+// A program's cadence supplies the schedule:
+//
+//	calendar --program partner
+//	calendar --schedule "@every 14d" --schedule-anchor 1@2026-01-05T09:00 --at 2026-10-15
+//
+// --number prints only the cycle number, for scripts:
+//
+//	notion-sync --cycle "$(calendar --program partner --number)" ...
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		sched       = flag.String("schedule", os.Getenv("RELEASE_SCHEDULE"), "release schedule, e.g. \"0 14 * * 4/2\" or \"@every 14d\" (or env RELEASE_SCHEDULE)")
+		schedTZ     = flag.String("schedule-tz", os.Getenv("RELEASE_TZ"), "time zone of --schedule: IANA name or offset like UTC+7; default UTC (or env RELEASE_TZ)")
+		schedAnchor = flag.String("schedule-anchor", os.Getenv("RELEASE_ANCHOR"), "a known release as <cycle>@<date>[T<hh:mm>] (or env RELEASE_ANCHOR)")
+		at          = flag.String("at", "", "date (2006-01-02, in --schedule-tz) or RFC 3339 time to answer for (default now)")
+		cycleNum    = flag.Int("cycle", 0, "print when this cycle is released instead")
+		number      = flag.Bool("number", false, "print only the cycle number")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "calendar"); err != nil {
+		fatal(err)
+	}
+	releases, err := schedule.FromSpec(*sched, *schedTZ, *schedAnchor)
+	if err != nil {
+		fatal(err)
+	}
+	if releases == nil {
+		fatal(errors.New("missing --schedule (or a --program with a cadence)"))
+	}
+	loc := releases.Location()
+	const layout = "2006-01-02 15:04 MST"
+
+	if *cycleNum > 0 {
+		t, err := releases.Release(*cycleNum)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("cycle %d: %s\n", *cycleNum, t.In(loc).Format(layout))
+		return
+	}
+
+	now := time.Now()
+	if *at != "" {
+		if now, err = time.Parse(time.RFC3339, *at); err != nil {
+			if now, err = time.ParseInLocation("2006-01-02", *at, loc); err != nil {
+				fatal(fmt.Errorf("--at: want a date or RFC 3339 time, got %q", *at))
+			}
+			// A whole day: whatever is released by its end.
+			now = now.AddDate(0, 0, 1).Add(-time.Minute)
+		}
+	}
+	n, err := releases.Expected(now)
+	if err != nil {
+		fatal(err)
+	}
+	if *number {
+		fmt.Println(n)
+		return
+	}
+	if released, err := releases.Release(n); err == nil {
+		fmt.Printf("cycle %d: released %s\n", n, released.In(loc).Format(layout))
+	}
+	if next := releases.Next(now); !next.IsZero() {
+		fmt.Printf("cycle %d: due %s\n", n+1, next.In(loc).Format(layout))
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
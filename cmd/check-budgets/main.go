@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/budget"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir    = flag.String("cycle-dir", "", "path to cycle-N directory")
+		budgetsPath = flag.String("budgets", budget.DefaultPath, "program budgets JSON grouping chain/type files per program")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "check-budgets"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *cycleDir == "" {
+		fatal(errors.New("missing --cycle-dir"))
+	}
+
+	cfg, err := budget.Load(*budgetsPath)
+	if err != nil {
+		fatal(err)
+	}
+	entries, err := cycle.ScanDir(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(entries) == 0 {
+		fatal(fmt.Errorf("no merkle files found in %s", *cycleDir))
+	}
+	cycleNum := entries[0].Cycle
+	for _, e := range entries {
+		if e.Cycle != cycleNum {
+			fatal(fmt.Errorf("multiple cycle numbers found in %s", *cycleDir))
+		}
+	}
+
+	results, err := budget.Check(cfg, *cycleDir, cycleNum)
+	if err != nil {
+		fatal(err)
+	}
+	failed := 0
+	for _, r := range results {
+		status := "OK"
+		if !r.OK {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("[%s] %s: total %s / budget %s\n", status, r.Program.Name, r.Total.FloatString(6), r.Budget.FloatString(6))
+		for _, m := range r.Members {
+			if m.Missing {
+				fmt.Printf("  %-20s missing\n", m.File)
+				continue
+			}
+			fmt.Printf("  %-20s %s\n", m.File, m.Amount.FloatString(6))
+		}
+		for _, p := range r.Problems {
+			fmt.Printf("  ! %s\n", p)
+		}
+	}
+	if failed > 0 {
+		fatal(fmt.Errorf("%d of %d programs failed the cross-chain budget check", failed, len(results)))
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	_ "time/tzdata" // --schedule-tz zones without a system zoneinfo
+
+	"github.com/KyberNetwork/fairflow-reward/claimrate"
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// claim-rates reports how fast each cycle was claimed, from the indexer's
+// export of Claimed events: per chain, the share of positions and of each
+// token's total claimed 1h to 30d after the release, and each chain's
+// average within 24h and 7d across the cycles.
+//
+//	claim-rates --events claimed.jsonl --from 18 --schedule "0 14 * * 4/2" --schedule-anchor 21@2026-10-08 --csv curves.csv
+//
+// Releases come from the schedule; a claim counts towards the cycle that was
+// live when it was made.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		root        = flag.String("root", ".", "directory holding the cycle-N directories")
+		eventsPath  = flag.String("events", "", "Claimed events exported by the indexer, one JSON object per line")
+		from        = flag.Int("from", 0, "first cycle to report (default the first)")
+		to          = flag.Int("to", 0, "last cycle to report (default the latest)")
+		sched       = flag.String("schedule", os.Getenv("RELEASE_SCHEDULE"), "cron-like release schedule, e.g. \"0 14 * * 4/2\" (or env RELEASE_SCHEDULE)")
+		schedTZ     = flag.String("schedule-tz", os.Getenv("RELEASE_TZ"), "time zone of --schedule: IANA name or offset like UTC+7; default UTC (or env RELEASE_TZ)")
+		schedAnchor = flag.String("schedule-anchor", os.Getenv("RELEASE_ANCHOR"), "a known release as <cycle>@<date>[T<hh:mm>] (or env RELEASE_ANCHOR)")
+		jsonOut     = flag.String("json-out", "", "write the report as JSON")
+		csvOut      = flag.String("csv", "", "write every curve as cycle,chain,series,after,fraction rows")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "claim-rates"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *eventsPath == "" {
+		fatal(errors.New("missing --events"))
+	}
+	releases, err := schedule.FromSpec(*sched, *schedTZ, *schedAnchor)
+	if err != nil {
+		fatal(err)
+	}
+	if releases == nil {
+		fatal(errors.New("missing --schedule: release times come from the schedule and its anchor"))
+	}
+	events, err := claims.LoadEvents(*eventsPath)
+	if err != nil {
+		fatal(err)
+	}
+	all, err := cycle.Cycles(*root)
+	if err != nil {
+		fatal(err)
+	}
+	ns := make([]int, 0, len(all))
+	for _, n := range all {
+		if n >= *from && (*to == 0 || n <= *to) {
+			ns = append(ns, n)
+		}
+	}
+	if len(ns) == 0 {
+		fatal(fmt.Errorf("no cycles to report in %s", *root))
+	}
+
+	rep, err := claimrate.Build(*root, ns, releases.Release, events, time.Now())
+	if err != nil {
+		fatal(err)
+	}
+	report(rep)
+
+	if *jsonOut != "" {
+		if err := claimrate.WriteJSON(*jsonOut, rep); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *jsonOut, err))
+		}
+		runsummary.Output(*jsonOut)
+	}
+	if *csvOut != "" {
+		if err := claimrate.WriteCSV(*csvOut, rep); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *csvOut, err))
+		}
+		runsummary.Output(*csvOut)
+	}
+}
+
+func report(rep *claimrate.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"cycle", "chain", "series", "claimed"}
+	for _, cp := range claimrate.Checkpoints {
+		header = append(header, claimrate.Label(cp))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, c := range rep.Cycles {
+		for _, ch := range c.Chains {
+			fmt.Fprintf(w, "%d\t%s\tpositions\t%d/%d\t%s\n", c.Cycle, ch.ChainID, ch.Claimers, ch.Positions, percents(ch.Curve))
+			for _, t := range ch.Tokens {
+				fmt.Fprintf(w, "\t\t%s\t%s\t%s\n", short(t.Token), fmt.Sprintf("%.1f%%", 100*t.Fraction), percents(t.Curve))
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "chain\tcycles\tpositions within 24h\twithin 7d")
+	for _, cmp := range rep.Chains {
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%.1f%%\n", cmp.ChainID, cmp.Cycles, 100*cmp.Within24h, 100*cmp.Within7d)
+	}
+	w.Flush()
+}
+
+// percents renders a curve one column per checkpoint, "-" past the end of
+// its window.
+func percents(c claimrate.Curve) string {
+	cols := make([]string, len(claimrate.Checkpoints))
+	for i, cp := range claimrate.Checkpoints {
+		cols[i] = "-"
+		if f, ok := c.At(cp); ok {
+			cols[i] = fmt.Sprintf("%.1f%%", 100*f)
+		}
+	}
+	return strings.Join(cols, "\t")
+}
+
+func short(addr string) string {
+	if len(addr) <= 12 {
+		return addr
+	}
+	return addr[:6] + "…" + addr[len(addr)-4:]
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	tmpdir.Exit(1)
+}
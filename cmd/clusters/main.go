@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/sybil"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// clusters flags recipients of a new cycle that are likely one entity, for
+// the anti-sybil team.
+//
+//	clusters --cycle-dir cycle-21 --transfers transfers.csv --holders holders.csv
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir  = flag.String("cycle-dir", "", "path to the cycle-N directory to analyse")
+		root      = flag.String("root", "", "repo root with earlier cycles, to tell fresh positions (default: parent of --cycle-dir)")
+		transfers = flag.String("transfers", "", "CSV with from,to columns; a recipient's first sender is its funder")
+		holders   = flag.String("holders", "", "CSV with position,holder columns, to match transfers by holder address")
+		minSize   = flag.Int("min-cluster", 3, "smallest group reported")
+		jsonOut   = flag.String("json-out", "", "also write the clusters as JSON")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "clusters"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *cycleDir == "" {
+		fatal(errors.New("missing --cycle-dir"))
+	}
+	if *root == "" {
+		*root = filepath.Dir(filepath.Clean(*cycleDir))
+	}
+	entries, err := cycle.ScanDir(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(entries) == 0 {
+		fatal(fmt.Errorf("no merkle files in %s", *cycleDir))
+	}
+	seen, err := sybil.Seen(*root, entries[0].Cycle)
+	if err != nil {
+		fatal(err)
+	}
+	rs, err := sybil.Recipients(*cycleDir, seen)
+	if err != nil {
+		fatal(err)
+	}
+	var funders, holderMap map[string]string
+	if *transfers != "" {
+		if funders, err = sybil.LoadTransfers(*transfers); err != nil {
+			fatal(err)
+		}
+	}
+	if *holders != "" {
+		if holderMap, err = sybil.LoadHolders(*holders); err != nil {
+			fatal(err)
+		}
+	}
+
+	fresh := 0
+	for _, r := range rs {
+		if r.Fresh {
+			fresh++
+		}
+	}
+	clusters := sybil.Analyze(rs, funders, holderMap, *minSize)
+	fmt.Printf("cycle %d: %d positions, %d fresh, %d clusters\n", entries[0].Cycle, len(rs), fresh, len(clusters))
+	for _, c := range clusters {
+		fmt.Printf("[%s] %d positions: %s\n", c.Reason, len(c.Positions), c.Key)
+		for _, p := range c.Positions {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+
+	if *jsonOut != "" {
+		b, err := json.MarshalIndent(clusters, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(*jsonOut, append(b, '\n'), 0o644); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *jsonOut, err))
+		}
+		runsummary.Output(*jsonOut)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+const zeroSalt = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+// convert translates merkle files between our cycle format and the formats
+// partner teams and older distributors use:
+//
+//	cycle     our <chain>_<type>_<cycle>.json
+//	oz        OpenZeppelin StandardMerkleTree.dump() JSON; the tree is kept
+//	uniswap   merkle-distributor claims JSON for one --token; cycle leaves
+//	          map to it only when they are per account (erc721Id 0)
+//
+// The input format is detected unless --from is given.
+//
+//	convert --in partner.json --out cycle-21/56_LM_21.json \
+//	    --start 1759305600 --end 1760515200
+//	convert --to uniswap --in cycle-21/1_LM_21.json --out cycle-21/1_LM_21.uniswap.json
+//
+// Every input is checked against its own root before it is converted.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		from     = flag.String("from", "", "input format: cycle, oz or uniswap (default: detected)")
+		to       = flag.String("to", "cycle", "output format: cycle, oz or uniswap")
+		inPath   = flag.String("in", "", "file to convert")
+		outPath  = flag.String("out", "", "file to write")
+		token    = flag.String("token", "", "token of uniswap claims; defaults to the claims' own token or, to uniswap, the file's only token")
+		start    = flag.Int64("start", 0, "startTimestamp of a cycle file converted from another format (unix seconds)")
+		end      = flag.Int64("end", 0, "endTimestamp of a cycle file converted from another format (unix seconds)")
+		metadata = flag.String("metadata", "", "metadata of a cycle file converted from another format")
+		salt     = flag.String("salt", zeroSalt, "salt of a cycle file converted from another format")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "convert"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *inPath == "" || *outPath == "" {
+		fatal(errors.New("missing --in or --out"))
+	}
+	if *from == "" {
+		b, err := os.ReadFile(*inPath)
+		if err != nil {
+			fatal(err)
+		}
+		detected, err := cycle.DetectFormat(b)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", *inPath, err))
+		}
+		*from = string(detected)
+		if detected == cycle.FormatV1 || detected == cycle.FormatV2 {
+			*from = "cycle"
+		}
+	}
+	if *from == *to {
+		fatal(fmt.Errorf("--from and --to are both %s", *from))
+	}
+
+	var (
+		f   *cycle.File
+		err error
+	)
+	switch *from {
+	case "cycle":
+		if f, err = cycle.Load(*inPath); err == nil {
+			_, err = f.ParseTree()
+		}
+	case "oz":
+		var d *cycle.OZDump
+		if d, err = cycle.LoadOZDump(*inPath); err == nil {
+			f, err = cycle.FromOZDump(d)
+		}
+	case "uniswap":
+		var c *cycle.UniswapClaims
+		if c, err = cycle.LoadUniswapClaims(*inPath); err == nil {
+			f, err = cycle.FromUniswapClaims(c, *token)
+		}
+	default:
+		fatal(fmt.Errorf("unknown --from %q", *from))
+	}
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", *inPath, err))
+	}
+
+	switch *to {
+	case "cycle":
+		if *start == 0 || *end <= *start {
+			fatal(errors.New("--start and --end must be set with end after start"))
+		}
+		f.StartTimestamp = fmt.Sprint(*start)
+		f.EndTimestamp = fmt.Sprint(*end)
+		f.Metadata = *metadata
+		f.Salt = *salt
+		err = cycle.Write(*outPath, f)
+	case "oz":
+		var d *cycle.OZDump
+		if d, err = f.OZDump(); err == nil {
+			err = cycle.WriteOZDump(*outPath, d)
+		}
+	case "uniswap":
+		var c *cycle.UniswapClaims
+		if c, err = f.UniswapClaims(*token); err == nil {
+			err = cycle.WriteUniswapClaims(*outPath, c)
+			f.Root = c.MerkleRoot
+		}
+	default:
+		fatal(fmt.Errorf("unknown --to %q", *to))
+	}
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", *outPath, err))
+	}
+	runsummary.Output(*outPath)
+	fmt.Printf("Wrote %s: %d recipients, root %s\n", *outPath, len(f.UserDatas), f.Root)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
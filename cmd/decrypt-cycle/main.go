@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/embargo"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// decrypt-cycle lifts the embargo on a cycle synced with notion-sync
+// --encrypt-key, restoring the plaintext merkle files for publish.
+//
+//	decrypt-cycle --keygen ~/.fairflow/cycle.key   # once; share with operators
+//	decrypt-cycle --cycle-dir cycle-21 --key ~/.fairflow/cycle.key
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir = flag.String("cycle-dir", "", "path to cycle-N directory")
+		keyPath  = flag.String("key", os.Getenv("FAIRFLOW_CYCLE_KEY"), "embargo key file (or env FAIRFLOW_CYCLE_KEY)")
+		keygen   = flag.String("keygen", "", "write a new embargo key to this path and exit")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "decrypt-cycle"); err != nil {
+		fatal(err)
+	}
+
+	if *keygen != "" {
+		if err := embargo.GenerateKey(*keygen); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Wrote %s. Keep it out of the repo.\n", *keygen)
+		return
+	}
+	if *cycleDir == "" || *keyPath == "" {
+		fatal(errors.New("missing --cycle-dir or --key"))
+	}
+	key, err := embargo.LoadKey(*keyPath)
+	if err != nil {
+		fatal(err)
+	}
+	sealed, err := embargo.Pending(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(sealed) == 0 {
+		fmt.Printf("No encrypted files in %s\n", *cycleDir)
+		return
+	}
+	manifest, err := cycle.LoadManifest(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	for _, p := range sealed {
+		out, err := key.Open(p)
+		if err != nil {
+			fatal(err)
+		}
+		name := filepath.Base(out)
+		want := manifest.Files[name].SHA256
+		if want == "" {
+			continue
+		}
+		got, err := verify.Digest(out)
+		if err != nil {
+			fatal(err)
+		}
+		if got != want {
+			fatal(fmt.Errorf("%s: sha256 %s does not match the manifest (%s)", name, got, want))
+		}
+	}
+	fmt.Printf("Decrypted %d files in %s\n", len(sealed), *cycleDir)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
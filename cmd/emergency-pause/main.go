@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/alert"
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/maintenance"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/safe"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/webhook"
+)
+
+// emergency-pause is the incident runbook as one command:
+//
+//	emergency-pause --reason "root mismatch on 137" --values values.yaml
+//
+// It pauses the distributors of --chain (every chain by default), sets
+// reward-service's maintenance key in --values, then pages the on-call and
+// notifies the webhooks subscribed to distributor.paused. By default the
+// pause is proposed as emergency-pause-<chain>.json Safe batches for the
+// treasury signers; with --pauser it is sent at once with `cast send` from
+// the pauser key in PAUSER_PRIVATE_KEY.
+//
+// Every step runs even when an earlier one fails, so a broken RPC does not
+// keep the site up or the on-call asleep; the command fails if any did.
+// Undo with update-kyber-applications --end-maintenance and an unpause.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		reason     = flag.String("reason", "", "why the distributors are paused, for signers, alerts and webhooks")
+		chainsPath = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		only       = flag.String("chain", "", "comma-separated chain IDs to pause (default every chain in the registry)")
+		pauseSig   = flag.String("pause-sig", "pause()", "distributor function that pauses claims")
+		pauser     = flag.Bool("pauser", false, "send the pause from the pauser key (env PAUSER_PRIVATE_KEY) instead of proposing Safe batches")
+		outDir     = flag.String("out-dir", ".", "directory for emergency-pause-<chain>.json Safe batches")
+		valuesPath = flag.String("values", "", "values.yaml or ConfigMap holding reward-service's maintenance key (skipped when empty)")
+		maintKey   = flag.String("maintenance-key", maintenance.DefaultKey, "name of the maintenance key")
+		statePath  = flag.String("state", state.DefaultPath, "state DB of webhook subscriptions and the alert retry queue")
+		source     = flag.String("source", hostname(), "alert source (host or operator)")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "emergency-pause"); err != nil {
+		fatal(err)
+	}
+	if *reason == "" {
+		fatal(errors.New("missing --reason"))
+	}
+	key := os.Getenv("PAUSER_PRIVATE_KEY")
+	if *pauser && key == "" {
+		fatal(errors.New("--pauser needs PAUSER_PRIVATE_KEY"))
+	}
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+	ids := reg.IDs()
+	if *only != "" {
+		ids = strings.Split(*only, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	errs := make([]error, 0)
+	paused := make(map[string][]string)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		ch, err := reg.Get(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addrs := distributors(ch)
+		if len(addrs) == 0 {
+			runsummary.Warnf("chain %s has no distributors; nothing to pause", id)
+			continue
+		}
+		if *pauser {
+			err = send(ctx, ch, addrs, *pauseSig, key)
+		} else {
+			err = propose(id, ch, addrs, *pauseSig, *reason, *outDir)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chain %s: %w", id, err))
+			continue
+		}
+		paused[id] = addrs
+	}
+
+	if *valuesPath != "" {
+		changed, err := maintenance.Set(*valuesPath, *maintKey, true)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("maintenance: %w", err))
+		case changed:
+			runsummary.Output(*valuesPath)
+			fmt.Printf("%s: set %s to true.\n", *valuesPath, *maintKey)
+		default:
+			fmt.Printf("%s: %s already true.\n", *valuesPath, *maintKey)
+		}
+	} else {
+		runsummary.Warnf("no --values; reward-service keeps serving")
+	}
+
+	mode := "proposed to the treasury Safe"
+	if *pauser {
+		mode = "sent from the pauser key"
+	}
+	if err := notify(ctx, *statePath, *source, *reason, mode, paused); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		fatal(err)
+	}
+}
+
+// distributors are ch's distinct distributor addresses, sorted.
+func distributors(ch chains.Chain) []string {
+	out := make([]string, 0, len(ch.Distributors))
+	for _, a := range ch.Distributors {
+		if a != "" && !slices.Contains(out, strings.ToLower(a)) {
+			out = append(out, strings.ToLower(a))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// propose writes a Safe batch calling sig on each of addrs from ch's
+// treasury.
+func propose(id string, ch chains.Chain, addrs []string, sig, reason, dir string) error {
+	if ch.Treasury == "" {
+		return errors.New("no treasury Safe configured")
+	}
+	b := safe.NewBatch(id, "Emergency pause", "Pause claims on every distributor: "+reason, ch.Treasury)
+	for _, a := range addrs {
+		b.Add(a, nil, evm.Calldata(sig))
+	}
+	out := filepath.Join(dir, fmt.Sprintf("emergency-pause-%s.json", id))
+	if err := b.Write(out); err != nil {
+		return fmt.Errorf("write %s: %w", out, err)
+	}
+	runsummary.Output(out)
+	fmt.Printf("Chain %s: wrote %s (%d distributor(s)) for Safe %s; import it in the Transaction Builder and collect signatures.\n", id, out, len(addrs), ch.Treasury)
+	return nil
+}
+
+// send calls sig on each of addrs with cast, signing with key. The key goes
+// to cast through ETH_PRIVATE_KEY, not argv, where any local user could read
+// it from the process list.
+func send(ctx context.Context, ch chains.Chain, addrs []string, sig, key string) error {
+	if ch.RPC == "" {
+		return errors.New("no RPC configured")
+	}
+	for _, a := range addrs {
+		cmd := exec.CommandContext(ctx, "cast", "send", "--rpc-url", ch.RPC, a, sig)
+		cmd.Env = append(os.Environ(), "ETH_PRIVATE_KEY="+key)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("cast send %s %s: %w", a, sig, err)
+		}
+		fmt.Printf("Paused %s on %s%s\n", a, ch.Name, link(ch.AddressURL(a)))
+	}
+	return nil
+}
+
+type pausedEvent struct {
+	Reason       string              `json:"reason"`
+	Mode         string              `json:"mode"`
+	Distributors map[string][]string `json:"distributors"`
+}
+
+// notify pages the on-call and delivers distributor.paused to webhook
+// subscribers; an alert that fails to send is queued for flush-queue. The
+// page goes out before the state DB is opened, so a broken DB cannot stop
+// it.
+func notify(ctx context.Context, statePath, source, reason, mode string, paused map[string][]string) error {
+	ids := make([]string, 0, len(paused))
+	for id := range paused {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	errs := make([]error, 0)
+	a := alert.Alert{
+		Summary:  "fairflow emergency pause: " + reason,
+		Source:   source,
+		Class:    alert.ClassEmergency,
+		DedupKey: "fairflow:emergency-pause",
+		Details:  map[string]string{"reason": reason, "mode": mode, "chains": strings.Join(ids, ",")},
+	}
+	var sendErr error
+	if sender := alert.FromEnv(); sender != nil {
+		if sendErr = sender.Send(ctx, a); sendErr == nil {
+			fmt.Println("Paged the on-call.")
+		}
+	} else {
+		runsummary.Warnf("no PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY set; not paging")
+	}
+
+	db, err := state.Open(statePath)
+	if err != nil {
+		return errors.Join(sendErr, err)
+	}
+	if sendErr != nil {
+		job, qerr := queue.New(db).Enqueue(alert.JobSend, a, sendErr)
+		if qerr == nil {
+			fmt.Fprintf(os.Stderr, "emergency-pause: alert queued as job %s; retry with flush-queue\n", job.ID)
+		}
+		errs = append(errs, fmt.Errorf("sending alert: %w", errors.Join(sendErr, qerr)))
+	}
+	ev := webhook.NewEvent(webhook.EventPaused, pausedEvent{Reason: reason, Mode: mode, Distributors: paused})
+	if err := webhook.NewRegistry(db).Notify(ctx, ev); err != nil {
+		errs = append(errs, fmt.Errorf("webhooks: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// link formats an optional explorer URL as a suffix.
+func link(u string) string {
+	if u == "" {
+		return ""
+	}
+	return "  " + u
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	tmpdir.Exit(1)
+}
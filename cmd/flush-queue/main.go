@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/jobs"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// flush-queue retries side effects that failed after their command's main
+// work succeeded: alerts, Notion writebacks and webhook deliveries.
+//
+//	flush-queue                # retry jobs whose backoff has passed
+//	flush-queue --force        # retry every job now
+//	flush-queue --list
+//	flush-queue --drop <id>
+//
+// serve retries the same queue on its own; run this against a state DB that
+// no serve process has open, since the DB has a single writer.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		statePath = flag.String("state", state.DefaultPath, "state DB holding the queue")
+		list      = flag.Bool("list", false, "list queued jobs and exit")
+		drop      = flag.String("drop", "", "remove the job with this ID without retrying it")
+		force     = flag.Bool("force", false, "retry every job, ignoring backoff")
+		timeout   = flag.Duration("timeout", 5*time.Minute, "give up on the flush after this long")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "flush-queue"); err != nil {
+		fatal(err)
+	}
+	db, err := state.Open(*statePath)
+	if err != nil {
+		fatal(err)
+	}
+	q := queue.New(db)
+
+	switch {
+	case *list:
+		js, err := q.List()
+		if err != nil {
+			fatal(err)
+		}
+		for _, j := range js {
+			fmt.Printf("%s  %-20s attempts=%d next=%s  %s\n", j.ID, j.Kind, j.Attempts, j.NextAttempt.Format(time.RFC3339), j.LastError)
+		}
+		fmt.Printf("%d queued\n", len(js))
+		return
+	case *drop != "":
+		ok, err := q.Drop(*drop)
+		if err != nil {
+			fatal(err)
+		}
+		if !ok {
+			fatal(fmt.Errorf("no job %q", *drop))
+		}
+		fmt.Printf("Dropped %s\n", *drop)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	res, err := q.Flush(ctx, jobs.Handlers(db), *force)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("%d done, %d failed, %d waiting for backoff, %d without credentials\n", res.Done, res.Failed, res.Waiting, res.Unhandled)
+	if res.Failed > 0 {
+		tmpdir.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+	"github.com/KyberNetwork/fairflow-reward/safe"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/tenderly"
+)
+
+type topUp struct {
+	Line     reconcile.Line
+	Amount   *big.Int
+	Symbol   string
+	Decimals int
+}
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		reconPath  = flag.String("reconcile", "", "JSON written by reconcile --json-out")
+		chainsPath = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		outDir     = flag.String("out-dir", ".", "directory for funding-<chain>.json Safe batches")
+		bufferBps  = flag.Int64("buffer-bps", 0, "extra margin on top of each shortfall, in basis points")
+		name       = flag.String("name", "Distributor top-up", "batch name shown to signers")
+		simulate   = flag.Bool("simulate", false, "simulate each transaction from the Safe on Tenderly and add the links to the batch description")
+		tenderlyCf = flag.String("tenderly", tenderly.DefaultConfigPath, "Tenderly account/project JSON (access key there or env TENDERLY_ACCESS_KEY)")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "funding"); err != nil {
+		fatal(err)
+	}
+
+	if *reconPath == "" {
+		fatal(errors.New("missing --reconcile"))
+	}
+	if *bufferBps < 0 {
+		fatal(errors.New("--buffer-bps must not be negative"))
+	}
+	rep, err := reconcile.LoadJSON(*reconPath)
+	if err != nil {
+		fatal(err)
+	}
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+
+	var sim *tenderly.Config
+	if *simulate {
+		if sim, err = tenderly.LoadConfig(*tenderlyCf); err != nil {
+			fatal(fmt.Errorf("load tenderly config: %w", err))
+		}
+		if !sim.Enabled() {
+			fatal(fmt.Errorf("--simulate needs an account and project in %s and an access key", *tenderlyCf))
+		}
+	}
+
+	ctx := context.Background()
+	byChain := make(map[string][]topUp)
+	for _, l := range rep.Underfunded() {
+		short, ok := new(big.Int).SetString(l.Shortfall, 10)
+		if !ok {
+			fatal(fmt.Errorf("invalid shortfall %q for %s/%s", l.Shortfall, l.ChainID, l.Token))
+		}
+		amount := new(big.Int).Mul(short, big.NewInt(10_000+*bufferBps))
+		// round up so the buffer never leaves the distributor a wei short
+		amount.Add(amount, big.NewInt(9_999))
+		amount.Quo(amount, big.NewInt(10_000))
+
+		ch, err := reg.Get(l.ChainID)
+		if err != nil {
+			fatal(err)
+		}
+		symbol, decimals, err := tokenInfo(ctx, ch, l.Token)
+		if err != nil {
+			fatal(fmt.Errorf("chain %s token %s: %w", l.ChainID, l.Token, err))
+		}
+		byChain[l.ChainID] = append(byChain[l.ChainID], topUp{Line: l, Amount: amount, Symbol: symbol, Decimals: decimals})
+	}
+
+	if len(byChain) == 0 {
+		fmt.Println("All distributors are funded; no transactions generated.")
+		return
+	}
+
+	ids := make([]string, 0, len(byChain))
+	for id := range byChain {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		ch := reg[id]
+		if ch.Treasury == "" {
+			fatal(fmt.Errorf("chain %s has no treasury Safe configured", id))
+		}
+		at := rep.Block
+		if n, ok := rep.Blocks[id]; ok {
+			at = fmt.Sprint(n)
+		}
+		desc := fmt.Sprintf("Top up %d distributor balance(s) on %s to cover unclaimed rewards at block %s", len(byChain[id]), chainLabel(id, ch), at)
+		batch := safe.NewBatch(id, *name, desc, ch.Treasury)
+
+		fmt.Printf("Chain %s — from Safe %s%s\n", chainLabel(id, ch), ch.Treasury, link(ch.AddressURL(ch.Treasury)))
+		for _, t := range byChain[id] {
+			if evm.IsNative(t.Line.Token) {
+				batch.Add(t.Line.Distributor, t.Amount, nil)
+			} else {
+				data, err := evm.TransferCalldata(t.Line.Distributor, t.Amount)
+				if err != nil {
+					fatal(err)
+				}
+				batch.Add(t.Line.Token, nil, data)
+			}
+			amount, err := evm.FormatUnits(t.Amount, t.Decimals)
+			if err != nil {
+				fatal(fmt.Errorf("chain %s token %s: %w", id, t.Line.Token, err))
+			}
+			fmt.Printf("  transfer %s %s (%s base units) to %s%s\n", amount, t.Symbol, t.Amount, t.Line.Distributor, link(ch.AddressURL(t.Line.Distributor)))
+			fmt.Printf("    balance %s, unclaimed %s, shortfall %s\n",
+				formatBase(t.Line.Balance, t.Decimals), formatBase(t.Line.Unclaimed, t.Decimals), formatBase(t.Line.Shortfall, t.Decimals))
+		}
+		if sim != nil {
+			if err := simulateBatch(ctx, sim, batch); err != nil {
+				fatal(fmt.Errorf("chain %s: %w", id, err))
+			}
+		}
+
+		out := filepath.Join(*outDir, fmt.Sprintf("funding-%s.json", id))
+		if err := batch.Write(out); err != nil {
+			fatal(fmt.Errorf("write %s: %w", out, err))
+		}
+		runsummary.Output(out)
+		fmt.Printf("  wrote %s\n", out)
+	}
+}
+
+// simulateBatch simulates each of b's transactions from its Safe and
+// appends the results to its description, for signers to check before
+// executing. A revert, or an ERC20 transfer emitting no Transfer, is a
+// warning rather than an error: the batch is still written so it can be
+// inspected.
+func simulateBatch(ctx context.Context, sim *tenderly.Config, b *safe.Batch) error {
+	lines := make([]string, 0, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		r, err := sim.Simulate(ctx, tenderly.Tx{ChainID: b.ChainID, From: b.Meta.CreatedFromSafeAddress, To: tx.To, Value: tx.Value, Data: tx.Data})
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		fmt.Printf("  transaction %d: %s\n", i+1, r.Summary())
+		switch {
+		case !r.Success:
+			runsummary.Warnf("chain %s transaction %d reverts in simulation: %s", b.ChainID, i+1, r.URL)
+		case tx.Data != "0x" && !r.Emitted("Transfer"):
+			runsummary.Warnf("chain %s transaction %d emits no Transfer in simulation: %s", b.ChainID, i+1, r.URL)
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, r.Summary()))
+	}
+	b.Meta.Description += "\n\n" + strings.Join(lines, "\n")
+	return nil
+}
+
+// link formats an optional explorer URL as a suffix.
+func link(u string) string {
+	if u == "" {
+		return ""
+	}
+	return "  " + u
+}
+
+func tokenInfo(ctx context.Context, ch chains.Chain, token string) (string, int, error) {
+	if t, ok := ch.Token(token); ok {
+		return t.Symbol, t.Decimals, nil
+	}
+	if evm.IsNative(token) {
+		return "native", 18, nil
+	}
+	if len(ch.Endpoints()) == 0 {
+		return "", 0, errors.New("token not in registry and no rpc to read decimals")
+	}
+	d, err := evm.NewClient(ch.Endpoints()...).Decimals(ctx, token)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, d, nil
+}
+
+// formatBase renders a base-unit string in whole tokens, or leaves it as is
+// if it cannot.
+func formatBase(s string, decimals int) string {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return s
+	}
+	out, err := evm.FormatUnits(n, decimals)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+func chainLabel(id string, ch chains.Chain) string {
+	if ch.Name == "" {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", ch.Name, id)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// gen-fixtures writes small synthetic cycle directories for reward-service
+// and front-end integration tests: cycle-N directories of merkle files with
+// valid roots and proofs, their manifest and stats.json. The same flags
+// always produce the same bytes, so consumers can regenerate fixtures
+// instead of keeping hand-made copies.
+//
+//	gen-fixtures --out-dir testdata --cycles 20-22 --chains 1,56 --types LM,EG --recipients 8
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		outDir     = flag.String("out-dir", "fixtures-out", "directory to write the cycle-N directories into")
+		cycles     = flag.String("cycles", "1", "cycle numbers: a list and/or ranges, e.g. 20-22,25")
+		chainList  = flag.String("chains", "56", "comma-separated chain IDs")
+		typeList   = flag.String("types", "LM", "comma-separated reward types")
+		recipients = flag.Int("recipients", 10, "recipients per merkle file")
+		tokens     = flag.Int("tokens", 2, "tokens per leaf")
+		seed       = flag.Int64("seed", 1, "generator seed")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "gen-fixtures"); err != nil {
+		fatal(err)
+	}
+
+	ns, err := parseCycles(*cycles)
+	if err != nil {
+		fatal(err)
+	}
+	chainIDs, types := split(*chainList), split(*typeList)
+	for _, id := range chainIDs {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			fatal(fmt.Errorf("--chains: invalid chain ID %q", id))
+		}
+	}
+	for i, t := range types {
+		types[i] = strings.ToUpper(t)
+	}
+	for _, n := range ns {
+		paths, err := fixtures.WriteCycle(*outDir, fixtures.CycleOptions{
+			Cycle:      n,
+			Chains:     chainIDs,
+			Types:      types,
+			Recipients: *recipients,
+			Tokens:     *tokens,
+			Seed:       *seed,
+		})
+		if err != nil {
+			fatal(err)
+		}
+		for _, p := range paths {
+			runsummary.Output(p)
+		}
+		fmt.Printf("cycle %d: %d files\n", n, len(paths))
+	}
+}
+
+// parseCycles reads "20-22,25" as 20, 21, 22, 25.
+func parseCycles(s string) ([]int, error) {
+	out := make([]int, 0)
+	for _, part := range split(s) {
+		lo, hi, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(lo)
+		b := a
+		if err == nil && isRange {
+			b, err = strconv.Atoi(hi)
+		}
+		if err != nil || a <= 0 || b < a {
+			return nil, fmt.Errorf("--cycles: invalid %q", part)
+		}
+		for n := a; n <= b; n++ {
+			out = append(out, n)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("--cycles: no cycles")
+	}
+	return out, nil
+}
+
+func split(s string) []string {
+	out := make([]string, 0)
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/mapping"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// mapping checks and formats Notion mapping files.
+//
+//	mapping lint [FILE...]   report schema problems and non-canonical
+//	                         formatting; exits 1 if there are any
+//	mapping fmt [FILE...]    rewrite valid files in canonical form
+//
+// Without files both act on config/notion_mappings.json, or the --program's
+// mapping.
+func main() {
+	defer tmpdir.Cleanup()
+	if len(os.Args) < 2 || (os.Args[1] != "lint" && os.Args[1] != "fmt") {
+		fatal(errors.New("usage: mapping lint|fmt [FILE...]"))
+	}
+	fs := flag.NewFlagSet("mapping "+os.Args[1], flag.ExitOnError)
+	runsummary.Register(fs)
+	prog := tenant.AddFlags(fs)
+	fs.Parse(os.Args[2:])
+	active, err := prog.Apply(fs, "mapping")
+	if err != nil {
+		fatal(err)
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{mapping.DefaultPath}
+		if active != nil && active.Mapping != "" {
+			files = []string{active.Mapping}
+		}
+	}
+	failed := false
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			fatal(err)
+		}
+		if os.Args[1] == "fmt" {
+			// Unknown and repeated keys would be dropped silently, so only
+			// a valid file is rewritten.
+			m, problems := mapping.Parse(b)
+			if len(problems) > 0 {
+				fatal(fmt.Errorf("%s: %w", path, errors.Join(problems...)))
+			}
+			if bytes.Equal(b, m.Format()) {
+				continue
+			}
+			if err := m.Write(path); err != nil {
+				fatal(err)
+			}
+			fmt.Println(path)
+			continue
+		}
+		for _, p := range mapping.Lint(b) {
+			fmt.Printf("%s: %v\n", path, p)
+			failed = true
+		}
+	}
+	if failed {
+		tmpdir.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/merge"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// merge combines the merkle files two or more teams deliver for the same
+// chain, type and cycle into the one canonical file: amounts owed to the
+// same position are summed per token and the tree is rebuilt. The inputs
+// must cover the same period with the same hash scheme, salt and metadata,
+// unless --salt or --metadata set the merged file's.
+//
+//	merge --out cycle-21/56_LM_21.json team-a/56_LM_21.json team-b/lm.json
+//
+// The merge report lists each input's recipients, totals and root beside
+// the merged file's; --report writes it as JSON too.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		outPath    = flag.String("out", "", "merged merkle file to write, named <chain>_<type>_<cycle>.json")
+		metadata   = flag.String("metadata", "", "metadata of the merged file (default the inputs', which must agree)")
+		salt       = flag.String("salt", "", "salt of the merged file (default the inputs', which must agree)")
+		reportPath = flag.String("report", "", "also write the merge report as JSON to this file")
+		dryRun     = flag.Bool("dry-run", false, "print the merge report without writing the merged file")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "merge"); err != nil {
+		fatal(err)
+	}
+
+	if *outPath == "" {
+		fatal(errors.New("missing --out"))
+	}
+	name, ok := cycle.ParseName(filepath.Base(*outPath))
+	if !ok {
+		fatal(fmt.Errorf("%s is not named like a merkle file", *outPath))
+	}
+	inputs := make([]merge.Input, 0, flag.NArg())
+	for _, p := range flag.Args() {
+		// Upstream files may be named anything; those named for a file
+		// must be named for this one.
+		if n, ok := cycle.ParseName(filepath.Base(p)); ok && n != name {
+			fatal(fmt.Errorf("%s is for %s, not %s", p, n, name))
+		}
+		f, err := cycle.Load(p)
+		if err != nil {
+			fatal(fmt.Errorf("load %s: %w", p, err))
+		}
+		inputs = append(inputs, merge.Input{Name: p, File: f})
+	}
+
+	f, report, err := merge.Merge(inputs, merge.Options{Metadata: *metadata, Salt: *salt})
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Print(report)
+	if *reportPath != "" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(*reportPath, append(b, '\n'), 0o644); err != nil {
+			fatal(fmt.Errorf("write merge report: %w", err))
+		}
+		runsummary.Output(*reportPath)
+	}
+	if *dryRun {
+		fmt.Printf("Would write %s\n", *outPath)
+		return
+	}
+	if err := cycle.Write(*outPath, f); err != nil {
+		fatal(fmt.Errorf("write %s: %w", *outPath, err))
+	}
+	fmt.Printf("Wrote %s: %d recipients, root %s\n", *outPath, len(f.UserDatas), f.Root)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
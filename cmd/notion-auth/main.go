@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// notion-auth connects the tools to a workspace through the OAuth client in
+// config/notion_oauth.json, for workspaces that do not issue us an internal
+// integration token:
+//
+//	notion-auth login                 # prints the URL to approve the integration at
+//	notion-auth login --code CODE     # caches the token for the redirect's code
+//	notion-auth refresh               # refreshes the cached token now
+//	notion-auth status                # shows the cached token's workspace and expiry
+//
+// notion-sync, notion-release and add-chain then use the cached token, and
+// refresh it, whenever no --notion-token is given.
+func main() {
+	defer tmpdir.Cleanup()
+	if len(os.Args) < 2 {
+		fatal(errors.New("usage: notion-auth login|refresh|status [flags]"))
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet("notion-auth "+cmd, flag.ExitOnError)
+	runsummary.Register(fs)
+	prog := tenant.AddFlags(fs)
+	var (
+		configPath = fs.String("config", notion.DefaultOAuthConfigPath, "Notion OAuth client config")
+		code       = fs.String("code", "", "authorization code from the redirect (login)")
+	)
+	_ = fs.Parse(os.Args[2:])
+	if _, err := prog.Apply(fs, "notion-auth"); err != nil {
+		fatal(err)
+	}
+
+	c, err := notion.LoadOAuthConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	if c == nil {
+		fatal(fmt.Errorf("no OAuth client configured in %s", *configPath))
+	}
+	ctx := context.Background()
+
+	switch cmd {
+	case "login":
+		if *code == "" {
+			b := make([]byte, 8)
+			_, _ = rand.Read(b)
+			fmt.Printf("Approve the integration at:\n\n  %s\n\nthen run notion-auth login --code with the code Notion redirects to %s with.\n", c.AuthorizeURL(hex.EncodeToString(b)), c.RedirectURI)
+			return
+		}
+		t, err := c.Exchange(ctx, *code)
+		if err != nil {
+			fatal(err)
+		}
+		if err := notion.SaveToken(c.TokenCache, t); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Connected workspace %s (%s); token cached in %s\n", t.WorkspaceName, t.WorkspaceID, c.TokenCache)
+
+	case "refresh":
+		t, err := notion.LoadToken(c.TokenCache)
+		if err != nil {
+			fatal(err)
+		}
+		if t, err = c.Refresh(ctx, t); err != nil {
+			fatal(err)
+		}
+		if err := notion.SaveToken(c.TokenCache, t); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Refreshed token for workspace %s\n", t.WorkspaceName)
+
+	case "status":
+		t, err := notion.LoadToken(c.TokenCache)
+		if err != nil {
+			fatal(err)
+		}
+		expiry := "never"
+		if !t.ExpiresAt.IsZero() {
+			expiry = t.ExpiresAt.Format("2006-01-02 15:04 MST")
+		}
+		fmt.Printf("Workspace %s (%s), bot %s, expires %s, refreshable %t\n", t.WorkspaceName, t.WorkspaceID, t.BotID, expiry, t.RefreshToken != "")
+
+	default:
+		fatal(fmt.Errorf("unknown subcommand %q (login, refresh or status)", cmd))
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/policy"
+	"github.com/KyberNetwork/fairflow-reward/profile"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// notion-release records a published cycle as a row in the "Cycle Releases"
+// Notion database. --dry-run prints the row and checks it against the
+// database's schema; add --notion-offline to read the schema from the
+// metadata cache alone, without a token or network.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir      = flag.String("cycle-dir", "", "published cycle-N directory")
+		databaseID    = flag.String("database-id", os.Getenv("NOTION_RELEASES_DB"), "Cycle Releases database ID (or env NOTION_RELEASES_DB)")
+		prURL         = flag.String("pr-url", "", "pull request that published the cycle")
+		notionToken   = flag.String("notion-token", os.Getenv("NOTION_TOKEN"), "Notion token (or env NOTION_TOKEN)")
+		notionOAuth   = flag.String("notion-oauth", notion.DefaultOAuthConfigPath, "Notion OAuth client config, used when no token is set (see notion-auth)")
+		notionVersion = flag.String("notion-version", notion.APIVersion, "Notion API version for Notion-Version header")
+		dryRun        = flag.Bool("dry-run", false, "print the row instead of creating it")
+		statePath     = flag.String("state", state.DefaultPath, "state DB whose retry queue keeps the row if creating it fails")
+
+		propTitle     = flag.String("prop-title", "Name", "Title property name")
+		propCycle     = flag.String("prop-cycle", "Cycle", "Number property for the cycle number")
+		propTotals    = flag.String("prop-totals", "Totals", "Text property for per-chain totals")
+		propRoots     = flag.String("prop-roots", "Roots", "Text property for merkle roots")
+		propPR        = flag.String("prop-pr", "PR", "URL property for the pull request")
+		propPublished = flag.String("prop-published", "Published", "Date property for the publish timestamp")
+	)
+	metaCache := notion.AddCacheFlags(flag.CommandLine, true)
+	prof := profile.AddFlags(flag.CommandLine)
+	gate := policy.AddFlags(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "notion-release"); err != nil {
+		fatal(err)
+	}
+	active, err := prof.Apply(flag.CommandLine, "notion-release")
+	if err != nil {
+		fatal(err)
+	}
+
+	if *cycleDir == "" || *databaseID == "" {
+		fatal(errors.New("missing --cycle-dir or --database-id"))
+	}
+	s, err := summary.Build(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	props := map[string]any{
+		*propTitle:     notion.TitleValue(fmt.Sprintf("Cycle %d", s.Cycle)),
+		*propCycle:     notion.NumberValue(float64(s.Cycle)),
+		*propTotals:    notion.RichTextValue(s.TotalsText()),
+		*propRoots:     notion.RichTextValue(s.RootsText()),
+		*propPR:        notion.URLValue(*prURL),
+		*propPublished: notion.DateValue(time.Now()),
+	}
+	ctx := context.Background()
+	client := func() (*notion.Client, error) {
+		token := ""
+		if !metaCache.Offline() {
+			var err error
+			if token, err = notion.ResolveToken(ctx, *notionToken, *notionOAuth); err != nil {
+				return nil, err
+			}
+		}
+		cli := notion.NewClient(token, *notionVersion)
+		cli.SetCache(metaCache.Cache())
+		return cli, nil
+	}
+	if *dryRun {
+		fmt.Printf("Cycle %d\n\nTotals:\n%s\n\nRoots:\n%s\n\nPR: %s\n", s.Cycle, s.TotalsText(), s.RootsText(), *prURL)
+		if err := checkSchema(ctx, client, *databaseID, props); err != nil {
+			fmt.Printf("\nThe row does not fit database %s: %v\n", *databaseID, err)
+		} else {
+			fmt.Printf("\nThe row fits the schema of database %s\n", *databaseID)
+		}
+		return
+	}
+	if metaCache.Offline() {
+		fatal(errors.New("--notion-offline only works with --dry-run"))
+	}
+	if err := gate.Check(policy.OpWriteback, active.ID(), s.Cycle); err != nil {
+		fatal(err)
+	}
+	cli, err := client()
+	if err != nil {
+		fatal(err)
+	}
+	src, err := cli.ResolveSource(ctx, *databaseID)
+	if err != nil {
+		fatal(err)
+	}
+	page, err := cli.CreatePage(ctx, src, props)
+	if err != nil {
+		// The cycle is already published; keep the row for flush-queue
+		// rather than failing the pipeline over the writeback.
+		sdb, serr := state.Open(*statePath)
+		if serr != nil {
+			fatal(errors.Join(err, serr))
+		}
+		job, qerr := queue.New(sdb).Enqueue(notion.JobCreatePage, notion.CreatePageJob{DataSourceID: src.ID, Legacy: src.Legacy, Properties: props, Version: *notionVersion}, err)
+		if qerr != nil {
+			fatal(errors.Join(err, qerr))
+		}
+		runsummary.Warnf("recording cycle %d failed: %v\nqueued as job %s; retry with flush-queue", s.Cycle, err, job.ID)
+		return
+	}
+	fmt.Printf("Recorded cycle %d release as Notion page %s\n", s.Cycle, page.Link())
+}
+
+// checkSchema checks the row against the database's schema, as cached when
+// the cache holds it.
+func checkSchema(ctx context.Context, client func() (*notion.Client, error), databaseID string, props map[string]any) error {
+	cli, err := client()
+	if err != nil {
+		return err
+	}
+	src, err := cli.ResolveSource(ctx, databaseID)
+	if err != nil {
+		return err
+	}
+	ds, err := cli.RetrieveDataSource(ctx, src)
+	if err != nil {
+		return err
+	}
+	return notion.CheckProperties(ds, props)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
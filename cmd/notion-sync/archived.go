@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	cyclefile "github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+)
+
+// archivedSinceSync finds the files of an earlier sync, in manifest, whose
+// rows the query no longer returned because they were archived since. The
+// files are kept and their entries flagged; the names are returned.
+func archivedSinceSync(ctx context.Context, cli *notion.Client, manifest *cyclefile.Manifest, items []downloadItem) ([]string, error) {
+	queried := make(map[string]bool, len(items))
+	for _, item := range items {
+		queried[item.PageID] = true
+	}
+	files := make([]string, 0, len(manifest.Files))
+	for f := range manifest.Files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	out := make([]string, 0)
+	for _, f := range files {
+		e := manifest.Files[f]
+		if e.PageID == "" || queried[e.PageID] {
+			continue
+		}
+		if e.Archived {
+			out = append(out, f)
+			continue
+		}
+		_, err := cli.RetrievePage(ctx, e.PageID)
+		if notion.IsArchived(err) {
+			out = append(out, skipArchived(manifest, f, err))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// skipArchived reports file's row as archived and flags the file's entry,
+// if an earlier sync left one. It returns file.
+func skipArchived(manifest *cyclefile.Manifest, file string, err error) string {
+	if e, ok := manifest.Files[file]; ok {
+		e.Archived = true
+		manifest.Files[file] = e
+		runsummary.Warnf("%s: %v; keeping the file from the last sync", file, err)
+	} else {
+		runsummary.Warnf("%s: %v; not synced", file, err)
+	}
+	return file
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/audit"
+	"github.com/KyberNetwork/fairflow-reward/download"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+const attachmentBucket = "attachments"
+
+// attachment identifies the file attached to a Notion row when it was first
+// synced, keyed by page ID in the state DB.
+type attachment struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+func (a attachment) String() string {
+	return fmt.Sprintf("%s (%d bytes, sha256 %s)", a.Name, a.Size, a.SHA256)
+}
+
+// trackAttachment records the attachment of item just downloaded as outName.
+// A different attachment than before on a row whose old file was already
+// verified is refused unless confirm is set; every change goes into the
+// audit trail.
+func trackAttachment(db *state.DB, item downloadItem, outName string, res download.Result, confirm bool) error {
+	cur := attachment{Name: item.FileName, Size: res.Size, SHA256: res.SHA256, FirstSeen: time.Now().UTC()}
+	var prev attachment
+	ok, err := db.Get(attachmentBucket, item.PageID, &prev)
+	if err != nil {
+		return err
+	}
+	if ok && prev.Name == cur.Name && prev.Size == cur.Size && prev.SHA256 == cur.SHA256 {
+		return nil
+	}
+	if ok {
+		if verify.NewCache(db).Verified(prev.SHA256) && !confirm {
+			return fmt.Errorf("%s: Notion row %s now has %s, not the already verified %s first seen %s; rerun with --confirm-attachment-change to accept it",
+				outName, item.PageURL, cur, prev, prev.FirstSeen.Format(time.RFC3339))
+		}
+		detail := fmt.Sprintf("%s: %s -> %s", item.PageURL, prev, cur)
+		if _, err := audit.Record(db, "attachment.changed", outName, detail); err != nil {
+			return err
+		}
+	}
+	return db.Put(attachmentBucket, item.PageID, cur)
+}
@@ -1,242 +1,174 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // --schedule-tz zones without a system zoneinfo
+
+	cyclefile "github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/download"
+	"github.com/KyberNetwork/fairflow-reward/embargo"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/mapping"
+	"github.com/KyberNetwork/fairflow-reward/metrics"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/policy"
+	"github.com/KyberNetwork/fairflow-reward/profile"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/storage"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/tracing"
 )
 
-const (
-	notionBaseURL    = "https://api.notion.com/v1"
-	notionAPIVersion = "2025-09-03"
-)
-
-type Mapping struct {
-	Chains map[string]string `json:"chains"`
-	Types  map[string]string `json:"types"`
-}
-
-// --- Notion: database -> data_sources (pick first) ---
-type RetrieveDatabaseResp struct {
-	DataSources []struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	} `json:"data_sources"`
-}
-
-type QueryResp struct {
-	Results    []Page `json:"results"`
-	HasMore    bool   `json:"has_more"`
-	NextCursor string `json:"next_cursor"`
-}
-
-type Page struct {
-	ID         string                 `json:"id"`
-	Properties map[string]PropertyVal `json:"properties"`
-}
-
-type PropertyVal struct {
-	Type string `json:"type"`
-
-	Title json.RawMessage `json:"title"`
-
-	Select *struct {
-		Name string `json:"name"`
-	} `json:"select"`
-
-	MultiSelect []struct {
-		Name string `json:"name"`
-	} `json:"multi_select"`
-
-	Status *struct {
-		Name string `json:"name"`
-	} `json:"status"`
-
-	Files []NotionFile `json:"files"`
-}
-
-type RichText struct {
-	PlainText string `json:"plain_text"`
-	Text      struct {
-		Content string `json:"content"`
-	} `json:"text"`
-}
-
-type NotionFile struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-	File *struct {
-		URL        string `json:"url"`
-		ExpiryTime string `json:"expiry_time"`
-	} `json:"file"`
-	External *struct {
-		URL string `json:"url"`
-	} `json:"external"`
-}
-
-type Client struct {
-	http          *http.Client
-	token         string
-	notionVersion string
-}
-
-func NewClient(token, version string) *Client {
-	return &Client{
-		http:          &http.Client{Timeout: 60 * time.Second},
-		token:         token,
-		notionVersion: version,
-	}
-}
-
-func (c *Client) do(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Notion-Version", c.notionVersion)
-	req.Header.Set("Accept", "application/json")
-	return c.http.Do(req)
-}
-
-func (c *Client) RetrieveDatabase(ctx context.Context, databaseID string) (RetrieveDatabaseResp, error) {
-	var out RetrieveDatabaseResp
-	req, err := http.NewRequestWithContext(ctx, "GET", notionBaseURL+"/databases/"+databaseID, nil)
-	if err != nil {
-		return out, err
-	}
-	resp, err := c.do(req)
-	if err != nil {
-		return out, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		b, _ := io.ReadAll(resp.Body)
-		return out, fmt.Errorf("retrieve database failed: %s: %s", resp.Status, string(b))
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return out, err
-	}
-	return out, nil
-}
-
-func (c *Client) QueryDataSource(ctx context.Context, dataSourceID string, body any) (QueryResp, error) {
-	var out QueryResp
-	b, err := json.Marshal(body)
-	if err != nil {
-		return out, err
-	}
-	req, err := http.NewRequestWithContext(ctx, "POST", notionBaseURL+"/data_sources/"+dataSourceID+"/query", bytes.NewReader(b))
-	if err != nil {
-		return out, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.do(req)
-	if err != nil {
-		return out, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		rb, _ := io.ReadAll(resp.Body)
-		return out, fmt.Errorf("query data source failed: %s: %s", resp.Status, string(rb))
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return out, err
-	}
-	return out, nil
-}
-
-func titleText(p PropertyVal) string {
-	if len(p.Title) == 0 {
-		return ""
-	}
-	var obj struct {
-		Title   []RichText `json:"title"`
-		Results []RichText `json:"results"`
-	}
-	if err := json.Unmarshal(p.Title, &obj); err == nil && (len(obj.Title) > 0 || len(obj.Results) > 0) {
-		return joinPlainText(append(obj.Title, obj.Results...))
-	}
-
-	var arr []RichText
-	if err := json.Unmarshal(p.Title, &arr); err == nil && len(arr) > 0 {
-		return joinPlainText(arr)
-	}
-	return ""
-}
-
-func joinPlainText(items []RichText) string {
-	parts := make([]string, 0, len(items))
-	for _, t := range items {
-		if t.PlainText != "" {
-			parts = append(parts, t.PlainText)
-		}
-	}
-	return strings.Join(parts, "")
-}
-
 type downloadItem struct {
 	ChainID    string
 	RewardType string
 	PageID     string
+	PageURL    string
+	Owner      string
 	SourceURL  string
+	FileName   string
+	Edited     time.Time
 }
 
+// editSlack allows for Notion rounding last_edited_time to the minute: the
+// edit that attaches a file can read as a little after its upload.
+const editSlack = time.Minute
+
 func main() {
+	defer tmpdir.Cleanup()
 	var (
 		databaseID    = flag.String("database-id", "", "Notion database ID")
 		cycle         = flag.Int("cycle", 0, "Cycle number to fetch (e.g. 20)")
 		outDir        = flag.String("out-dir", ".", "Repo root output directory")
-		mappingPath   = flag.String("mapping", "config/notion_mappings.json", "JSON mapping file")
+		mappingPath   = flag.String("mapping", mapping.DefaultPath, "JSON mapping file")
 		notionToken   = flag.String("notion-token", os.Getenv("NOTION_TOKEN"), "Notion token (or env NOTION_TOKEN)")
-		notionVersion = flag.String("notion-version", notionAPIVersion, "Notion API version for Notion-Version header")
+		notionOAuth   = flag.String("notion-oauth", notion.DefaultOAuthConfigPath, "Notion OAuth client config, used when no token is set (see notion-auth)")
+		notionVersion = flag.String("notion-version", notion.APIVersion, "Notion API version for Notion-Version header")
 		allowExisting = flag.Bool("allow-existing", false, "allow existing cycle directory (re-download and overwrite files)")
 
 		propTitle = flag.String("prop-title", "Task name", "Title property name")
+		propCycle = flag.String("prop-cycle", "", "Number property holding each row's cycle, matched instead of --title-pattern (default: match the title)")
+		titlePat  = flag.String("title-pattern", notion.DefaultTitlePattern, "title of a cycle row: a template over {{.Cycle}}, {{.Chain}} and {{.Type}}, e.g. \"C{{.Cycle}} — {{.Chain}}\", or regexp:<expr> with those named groups; a chain or type it names must agree with the row's selects")
 		propChain = flag.String("prop-chain", "Chain", "Select property name")
 		propType  = flag.String("prop-type", "Type", "Multi-select property name")
 		propFile  = flag.String("prop-file", "Merkle file", "Files property name")
+		propOwner = flag.String("prop-owner", "", "People property naming each file's owner, recorded in the cycle manifest (default none)")
+
+		propStatus    = flag.String("prop-status", "Status", "Status property name")
+		statusDone    = flag.String("status-done", "", "only sync pages in one of these comma-separated statuses, e.g. \"Done,Approved\" (default any)")
+		statusExclude = flag.String("status-exclude", "", "skip pages in any of these comma-separated statuses, e.g. \"Superseded\"")
 
 		pageSize = flag.Int("page-size", 100, "Notion query page_size")
+		sortBy   = flag.String("sort", "created_time", "comma-separated query sorts, each a property or created_time/last_edited_time with an optional :asc or :desc")
+
+		retries         = flag.Int("download-retries", 3, "resume an interrupted download this many times")
+		noManifestCheck = flag.Bool("no-manifest-check", false, "accept files whose sha256 differs from the cycle manifest")
+		freezeAt        = flag.String("freeze-at", os.Getenv("NOTION_FREEZE_AT"), "RFC 3339 time after which no cycle row should be edited (or env NOTION_FREEZE_AT)")
+		strict          = flag.Bool("strict", false, "fail, rather than warn, when a row was edited after its file was uploaded or after --freeze-at")
+		statePath       = flag.String("state", state.DefaultPath, "state DB remembering each row's attachment and the audit trail")
+		confirmChange   = flag.Bool("confirm-attachment-change", false, "accept a different attachment on a row whose earlier file was already verified")
+
+		encryptKey = flag.String("encrypt-key", os.Getenv("FAIRFLOW_CYCLE_KEY"), "embargo: encrypt merkle files with this key file until decrypt-cycle runs (or env FAIRFLOW_CYCLE_KEY)")
+
+		sched       = flag.String("schedule", os.Getenv("RELEASE_SCHEDULE"), "release schedule --cycle is checked against, e.g. \"0 14 * * 4/2\" or \"@every 14d\" (or env RELEASE_SCHEDULE)")
+		schedTZ     = flag.String("schedule-tz", os.Getenv("RELEASE_TZ"), "time zone of --schedule: IANA name or offset like UTC+7; default UTC (or env RELEASE_TZ)")
+		schedAnchor = flag.String("schedule-anchor", os.Getenv("RELEASE_ANCHOR"), "a known release as <cycle>@<date>[T<hh:mm>] (or env RELEASE_ANCHOR)")
+		offSchedule = flag.Bool("off-schedule", false, "an off-schedule hotfix: warn, rather than fail, when --cycle is not the one --schedule has due")
 	)
+	storageConfig := flag.String("storage-config", storage.DefaultConfigPath, "named storage destinations JSON")
+	var mirrors multiFlag
+	flag.Var(&mirrors, "mirror", "also upload every file to this destination name or storage URL (file://, s3://, gs://, azblob://, sftp://, webdav://, git+ssh://...); repeatable")
+	metaCache := notion.AddCacheFlags(flag.CommandLine, false)
+	prof := profile.AddFlags(flag.CommandLine)
+	gate := policy.AddFlags(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
 	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "notion-sync"); err != nil {
+		fatal(err)
+	}
+	active, err := prof.Apply(flag.CommandLine, "notion-sync")
+	if err != nil {
+		fatal(err)
+	}
 
 	if *databaseID == "" || *cycle == 0 {
 		fatal(errors.New("missing --database-id or --cycle"))
 	}
-	if *notionToken == "" {
-		fatal(errors.New("missing Notion token (set NOTION_TOKEN or --notion-token)"))
+	sorts, err := notion.ParseSorts(*sortBy)
+	if err != nil {
+		fatal(fmt.Errorf("--sort: %w", err))
 	}
-
-	var m Mapping
-	mb, err := os.ReadFile(*mappingPath)
+	title, err := notion.ParseTitlePattern(*titlePat)
+	if err != nil {
+		fatal(fmt.Errorf("--title-pattern: %w", err))
+	}
+	if err := gate.Check(policy.OpSync, active.ID(), *cycle); err != nil {
+		fatal(err)
+	}
+	releases, err := schedule.FromSpec(*sched, *schedTZ, *schedAnchor)
 	if err != nil {
-		fatal(fmt.Errorf("read mapping: %w", err))
+		fatal(err)
+	}
+	if err := checkCalendar(releases, *cycle, time.Now()); err != nil {
+		if !*offSchedule {
+			fatal(fmt.Errorf("%w (--off-schedule for a hotfix)", err))
+		}
+		runsummary.Warnf("off-schedule sync: %v", err)
 	}
-	if err := json.Unmarshal(mb, &m); err != nil {
-		fatal(fmt.Errorf("parse mapping json: %w", err))
+	token, err := notion.ResolveToken(context.Background(), *notionToken, *notionOAuth)
+	if err != nil {
+		fatal(err)
 	}
 
-	ctx := context.Background()
-	cli := NewClient(*notionToken, *notionVersion)
+	var freeze time.Time
+	if *freezeAt != "" {
+		if freeze, err = time.Parse(time.RFC3339, *freezeAt); err != nil {
+			fatal(fmt.Errorf("--freeze-at: %w", err))
+		}
+	}
 
-	// Get first data source ID from database (new data model: database -> data_sources)
-	db, err := cli.RetrieveDatabase(ctx, *databaseID)
+	var sealKey embargo.Key
+	if *encryptKey != "" {
+		if sealKey, err = embargo.LoadKey(*encryptKey); err != nil {
+			fatal(err)
+		}
+	}
+
+	m, err := mapping.Load(*mappingPath)
 	if err != nil {
 		fatal(err)
 	}
-	if len(db.DataSources) == 0 {
-		fatal(errors.New("database has no data_sources"))
+
+	tracing.Init("fairflow-notion-sync")
+	defer tracing.Flush()
+	metrics.Init("fairflow-notion-sync")
+	metrics.SetLabel("cycle", strconv.Itoa(*cycle))
+	defer metrics.Push(nil)
+	ctx, span := tracing.Start(context.Background(), "notion-sync", "cycle", *cycle)
+	defer span.End(nil)
+	cli := notion.NewClient(token, *notionVersion)
+	cli.SetCache(metaCache.Cache())
+
+	// Rows live in the database's first data source, or in the database
+	// itself on workspaces without data sources.
+	src, err := cli.ResolveSource(ctx, *databaseID)
+	if err != nil {
+		fatal(err)
 	}
-	dataSourceID := db.DataSources[0].ID
 
 	cycleStr := fmt.Sprintf("Cycle %d", *cycle)
-	targetDir := filepath.Join(*outDir, fmt.Sprintf("cycle-%d", *cycle))
+	targetDir := filepath.Join(*outDir, cyclefile.DirName(*cycle))
 
 	if fi, err := os.Stat(targetDir); err == nil && fi.IsDir() {
 		entries, _ := os.ReadDir(targetDir)
@@ -245,104 +177,126 @@ func main() {
 		}
 	}
 
+	filter := notion.And{
+		notion.CycleFilter(*propTitle, *propCycle, title, *cycle),
+		notion.FilesNotEmpty(*propFile),
+	}
+	filter = append(filter, notion.StatusConditions(*propStatus, splitList(*statusDone), splitList(*statusExclude))...)
 	body := map[string]any{
 		"page_size": *pageSize,
-		"filter": map[string]any{
-			"and": []any{
-				map[string]any{
-					"property": *propTitle,
-					"title": map[string]any{
-						"contains": cycleStr,
-					},
-				},
-				map[string]any{
-					"property": *propFile,
-					"files": map[string]any{
-						"is_not_empty": true,
-					},
-				},
-			},
-		},
-	}
-
-	seen := make(map[string]struct{})
+		"filter":    filter,
+	}
+	// Sorted server-side, rows come in the same order on every run.
+	if len(sorts) > 0 {
+		body["sorts"] = sorts
+	}
+
+	// seen maps chain:type to the page that has it.
+	seen := make(map[string]string)
 	seenChains := make(map[string]struct{})
 	items := make([]downloadItem, 0)
 
-	for {
-		qr, err := cli.QueryDataSource(ctx, dataSourceID, body)
-		if err != nil {
-			fatal(err)
-		}
-
-		for _, page := range qr.Results {
-			titleProp, ok := page.Properties[*propTitle]
-			if !ok || titleProp.Type != "title" {
-				fatal(fmt.Errorf("page %s: missing/invalid title property %q", page.ID, *propTitle))
+	err = cli.QueryPages(ctx, src, body, func(results []notion.Page) error {
+		for _, page := range results {
+			ok, err := notion.InCycle(page, *propTitle, *propCycle, title, *cycle)
+			if err != nil {
+				fatal(err)
 			}
-			if !strings.Contains(titleText(titleProp), cycleStr) {
+			if !ok {
 				continue
 			}
 
-			chainProp, ok := page.Properties[*propChain]
-			if !ok || chainProp.Select == nil || chainProp.Select.Name == "" {
-				fatal(fmt.Errorf("page %s: missing chain select %q", page.ID, *propChain))
+			chainName, err := notion.GetSelect(page, *propChain)
+			if err != nil {
+				fatal(err)
 			}
-			chainID, ok := m.Chains[chainProp.Select.Name]
-			if !ok {
-				fatal(fmt.Errorf("page %s: chain %q not found in mapping", page.ID, chainProp.Select.Name))
+			if chainName == "" {
+				fatal(fmt.Errorf("page %s: no chain selected in %q", page.Link(), *propChain))
+			}
+			chainID, err := m.Chain(chainName)
+			if err != nil {
+				fatal(fmt.Errorf("page %s: %w", page.Link(), err))
 			}
 
-			typeProp, ok := page.Properties[*propType]
-			if !ok || typeProp.Type != "multi_select" {
-				fatal(fmt.Errorf("page %s: missing type multi_select %q", page.ID, *propType))
+			types, err := notion.GetMultiSelect(page, *propType)
+			if err != nil {
+				fatal(err)
 			}
-			if len(typeProp.MultiSelect) != 1 {
-				fatal(fmt.Errorf("page %s: expected exactly 1 Type, got %d", page.ID, len(typeProp.MultiSelect)))
+			if len(types) != 1 {
+				fatal(fmt.Errorf("page %s: expected exactly 1 Type, got %d", page.Link(), len(types)))
 			}
-			typeName := typeProp.MultiSelect[0].Name
-			rewardType, ok := m.Types[typeName]
-			if !ok {
-				fatal(fmt.Errorf("page %s: type %q not found in mapping", page.ID, typeName))
+			rewardType, err := m.Type(types[0])
+			if err != nil {
+				fatal(fmt.Errorf("page %s: %w", page.Link(), err))
+			}
+			if err := checkTitle(page, *propTitle, title, chainName, types); err != nil {
+				fatal(err)
 			}
 
-			fileProp, ok := page.Properties[*propFile]
-			if !ok || fileProp.Type != "files" {
-				fatal(fmt.Errorf("page %s: missing files property %q", page.ID, *propFile))
+			files, err := notion.GetFiles(page, *propFile)
+			if err != nil {
+				fatal(err)
 			}
-			if len(fileProp.Files) != 1 {
-				fatal(fmt.Errorf("page %s: expected exactly 1 merkle file, got %d", page.ID, len(fileProp.Files)))
+			if len(files) != 1 {
+				fatal(fmt.Errorf("page %s: expected exactly 1 merkle file, got %d", page.Link(), len(files)))
 			}
-			f := fileProp.Files[0]
-			url, err := fileURL(f)
+			f := files[0]
+			url, err := notion.FileURL(f)
 			if err != nil {
-				fatal(fmt.Errorf("page %s: %w", page.ID, err))
+				fatal(fmt.Errorf("page %s: %w", page.Link(), err))
+			}
+
+			var owner string
+			if *propOwner != "" {
+				people, err := notion.GetPeople(page, *propOwner)
+				if err != nil {
+					fatal(err)
+				}
+				owner = notion.PeopleText(people)
 			}
 
 			key := chainID + ":" + rewardType
-			if _, exists := seen[key]; exists {
-				fatal(fmt.Errorf("page %s: duplicate chain/type %s", page.ID, key))
+			if first, exists := seen[key]; exists {
+				fatal(fmt.Errorf("page %s: duplicate chain/type %s, already on %s", page.Link(), key, first))
 			}
-			seen[key] = struct{}{}
+			seen[key] = page.Link()
 			seenChains[chainID] = struct{}{}
 
 			items = append(items, downloadItem{
 				ChainID:    chainID,
 				RewardType: rewardType,
 				PageID:     page.ID,
+				PageURL:    page.Link(),
+				Owner:      owner,
+				Edited:     page.LastEditedTime,
 				SourceURL:  url,
+				FileName:   f.Name,
 			})
 		}
-
-		if !qr.HasMore || qr.NextCursor == "" {
-			break
-		}
-		body["start_cursor"] = qr.NextCursor
+		return nil
+	})
+	if err != nil {
+		fatal(err)
 	}
 
-	if len(items) == 0 {
+	manifest, err := cyclefile.LoadManifest(targetDir)
+	if err != nil {
+		fatal(err)
+	}
+	// Rows archived since the last sync drop out of the query; their
+	// files stay, flagged, rather than failing the rows that are there.
+	archived, err := archivedSinceSync(ctx, cli, manifest, items)
+	if err != nil {
+		fatal(err)
+	}
+	if len(items) == 0 && len(archived) == 0 {
 		fatal(fmt.Errorf("no matching Notion rows found for %s", cycleStr))
 	}
+	for _, file := range archived {
+		if n, ok := cyclefile.ParseName(file); ok {
+			seenChains[n.ChainID] = struct{}{}
+		}
+	}
 	for name, id := range m.Chains {
 		if _, ok := seenChains[id]; !ok {
 			fatal(fmt.Errorf("no merkle files found for chain %q (id %s) in %s", name, id, cycleStr))
@@ -353,65 +307,196 @@ func main() {
 		fatal(err)
 	}
 
+	// Open mirrors before downloading so a bad URL or missing credentials
+	// fail the run up front.
+	dests, err := storage.LoadConfig(*storageConfig)
+	if err != nil {
+		fatal(err)
+	}
+	backends := make([]storage.Backend, 0, len(mirrors))
+	for _, u := range mirrors {
+		b, err := dests.Open(u)
+		if err != nil {
+			fatal(err)
+		}
+		backends = append(backends, b)
+	}
+
+	sdb, err := state.Open(*statePath)
+	if err != nil {
+		fatal(err)
+	}
+	downloaded := 0
 	for _, item := range items {
-		outName := fmt.Sprintf("%s_%s_%d.json", item.ChainID, item.RewardType, *cycle)
+		name := cyclefile.Name{ChainID: item.ChainID, RewardType: item.RewardType, Cycle: *cycle}
+		if err := name.Validate(); err != nil {
+			fatal(fmt.Errorf("%s: %w", item.PageURL, err))
+		}
+		outName := name.String()
 		outPath := filepath.Join(targetDir, outName)
 
-		if err := downloadToFile(ctx, cli.http, item.SourceURL, outPath); err != nil {
-			fatal(fmt.Errorf("download %s: %w", outName, err))
+		// A row archived after the query is skipped, not downloaded: its
+		// file is no longer what Notion says the cycle holds.
+		if _, err := cli.RetrievePage(ctx, item.PageID); notion.IsArchived(err) {
+			archived = append(archived, skipArchived(manifest, outName, err))
+			continue
+		} else if err != nil {
+			fatal(err)
+		}
+		opt := download.Options{Retries: *retries}
+		if !*noManifestCheck {
+			opt.SHA256 = manifest.Files[outName].SHA256
+		}
+		res, err := download.ToFile(ctx, cli.HTTPClient(), item.SourceURL, outPath, opt)
+		if err != nil {
+			if _, aerr := cli.RetrievePage(ctx, item.PageID); notion.IsArchived(aerr) {
+				archived = append(archived, skipArchived(manifest, outName, aerr))
+				continue
+			}
+			if opt.SHA256 != "" {
+				err = fmt.Errorf("%w (the Notion attachment changed since the last sync; rerun with --no-manifest-check to accept it)", err)
+			}
+			fatal(fmt.Errorf("download %s from %s: %w", outName, item.PageURL, err))
+		}
+		if res.Size == 0 {
+			fatal(fmt.Errorf("downloaded file is empty: %s (attached to %s)", outPath, item.PageURL))
+		}
+		metrics.Add(metrics.Files, 1)
+		metrics.Add(metrics.Bytes, res.Size)
+		if res.Resumed {
+			fmt.Printf("Resumed %s (%d bytes, sha256 %s)\n", outName, res.Size, res.SHA256)
+		}
+		// A row edited after its file went up may have had the attachment
+		// swapped, or its chain or type changed, after review.
+		late := make([]string, 0, 2)
+		if !res.LastModified.IsZero() && item.Edited.After(res.LastModified.Add(editSlack)) {
+			late = append(late, fmt.Sprintf("after its file was uploaded at %s", res.LastModified.UTC().Format(time.RFC3339)))
+		}
+		if !freeze.IsZero() && item.Edited.After(freeze) {
+			late = append(late, fmt.Sprintf("after the %s freeze", freeze.UTC().Format(time.RFC3339)))
+		}
+		for _, l := range late {
+			err := fmt.Errorf("%s: Notion row %s was edited at %s, %s", outName, item.PageURL, item.Edited.UTC().Format(time.RFC3339), l)
+			if *strict {
+				os.Remove(outPath)
+				fatal(err)
+			}
+			runsummary.Warnf("%v", err)
+		}
+		if err := trackAttachment(sdb, item, outName, res, *confirmChange); err != nil {
+			os.Remove(outPath)
+			fatal(err)
+		}
+		manifest.Files[outName] = cyclefile.ManifestEntry{
+			SHA256: res.SHA256, Size: res.Size, PageID: item.PageID, Owner: item.Owner,
+			Edited: item.Edited, Uploaded: res.LastModified,
+		}
+		// The manifest keeps the plaintext digest, which decrypt-cycle checks.
+		if sealKey != nil {
+			if err := sealKey.Seal(outPath); err != nil {
+				fatal(fmt.Errorf("encrypt %s: %w", outName, err))
+			}
+			outName, outPath = outName+embargo.Ext, outPath+embargo.Ext
 		}
+		for _, b := range backends {
+			if err := storage.PutFile(ctx, b, path.Join(cyclefile.DirName(*cycle), outName), outPath); err != nil {
+				fatal(err)
+			}
+		}
+		downloaded++
+	}
+	if err := manifest.Write(targetDir); err != nil {
+		fatal(err)
+	}
+	if err := sdb.Save(); err != nil {
+		fatal(err)
+	}
+	for _, b := range backends {
+		key := path.Join(cyclefile.DirName(*cycle), cyclefile.ManifestName)
+		if err := storage.PutFile(ctx, b, key, filepath.Join(targetDir, cyclefile.ManifestName)); err != nil {
+			fatal(err)
+		}
+		if err := b.Close(); err != nil {
+			fatal(fmt.Errorf("%s: %w", b, err))
+		}
+		fmt.Printf("Mirrored %d files to %s\n", downloaded+1, b)
+	}
 
-		if st, err := os.Stat(outPath); err != nil || st.Size() == 0 {
-			fatal(fmt.Errorf("downloaded file is empty: %s", outPath))
+	fmt.Printf("Downloaded %d files into %s\n", downloaded, targetDir)
+	if len(archived) > 0 {
+		err := fmt.Errorf("%d Notion rows were archived, so %s are missing or from an earlier sync", len(archived), strings.Join(archived, ", "))
+		if *strict {
+			fatal(err)
 		}
+		runsummary.Warnf("%v", err)
+	}
+	if sealKey != nil {
+		fmt.Printf("Files are encrypted; run decrypt-cycle --cycle-dir %s once the cycle is announced\n", targetDir)
 	}
+}
 
-	fmt.Printf("Downloaded %d files into %s\n", len(items), targetDir)
+type multiFlag []string
+
+func (m *multiFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
 }
 
-func fileURL(f NotionFile) (string, error) {
-	if f.Type == "file" && f.File != nil && f.File.URL != "" {
-		return f.File.URL, nil
-	}
-	if f.Type == "external" && f.External != nil && f.External.URL != "" {
-		return f.External.URL, nil
-	}
-	if f.File != nil && f.File.URL != "" {
-		return f.File.URL, nil
-	}
-	if f.External != nil && f.External.URL != "" {
-		return f.External.URL, nil
+func fatal(err error) {
+	runsummary.Fail(err)
+	tracing.Flush()
+	metrics.Push(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
+
+// splitList splits a comma-separated flag value, dropping blanks.
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
 	}
-	return "", fmt.Errorf("file entry %q has no downloadable URL", f.Name)
+	return out
 }
 
-func downloadToFile(ctx context.Context, client *http.Client, urlStr, outPath string) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
-	if err != nil {
-		return err
+// checkCalendar reports a cycle the schedule does not have due at now: the
+// latest one released, while it may still be hotfixed, or the next one, which
+// is synced ahead of its release. Without a schedule any cycle is fine.
+func checkCalendar(releases *schedule.Schedule, n int, now time.Time) error {
+	if releases == nil {
+		return nil
 	}
-	resp, err := client.Do(req)
+	due, err := releases.Expected(now)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("download failed: %s: %s", resp.Status, string(b))
+	if n == due || n == due+1 {
+		return nil
 	}
-	tmp := outPath + ".tmp"
-	f, err := os.Create(tmp)
+	next := releases.Next(now).In(releases.Location()).Format("2006-01-02 15:04")
+	return fmt.Errorf("cycle %d does not match the release calendar: cycle %d is the latest released and cycle %d is due %s", n, due, due+1, next)
+}
+
+// checkTitle cross-checks what page's title says through the title pattern —
+// its cycle, chain and type — against the row's cycle and selects, so a row
+// copied from another chain and only half edited does not sync under the
+// wrong one. A title the pattern does not match, possible with --prop-cycle,
+// is not checked.
+func checkTitle(page notion.Page, titleProp string, pattern *notion.TitlePattern, chain string, types []string) error {
+	t, err := notion.GetTitle(page, titleProp)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		return err
+	f, ok := pattern.Match(t)
+	if !ok {
+		return nil
 	}
-	return os.Rename(tmp, outPath)
-}
-
-func fatal(err error) {
-	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
-	os.Exit(1)
+	if err := f.CheckSelects(chain, types); err != nil {
+		return fmt.Errorf("page %s: %q: %w", page.Link(), t, err)
+	}
+	return nil
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/outreach"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// outreach exports the positions of a merkle file with the most left
+// unclaimed, for the community team's reminders: each position's holder,
+// when the holder last sent a transaction, its ENS name and any label from
+// --labels (exchange deposit addresses among them).
+//
+//	outreach --file cycle-21/56_LM_21.json --top 100 --out outreach-56-LM-21.csv
+//
+// Finding the last transaction bisects the holder's nonce over history, so
+// the chain's RPC must serve old state (an archive node).
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		filePath      = flag.String("file", "", "merkle file (e.g. cycle-21/56_LM_21.json)")
+		chainsPath    = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		claimedFile   = flag.String("claimed-file", "", "claimed amounts JSON exported from the distributor (default: read on-chain)")
+		claimedSig    = flag.String("claimed-sig", "", "distributor view returning claimed amounts (default: the ABI's getClaimedAmounts)")
+		block         = flag.String("block", evm.DefaultBlockTag, "block tag for on-chain reads: finalized, safe, latest or a number")
+		confirmations = flag.Uint64("confirmations", 0, "read this many blocks behind --block")
+		top           = flag.Int("top", 50, "number of positions to export")
+		minShareBps   = flag.Int64("min-share-bps", 1, "skip positions leaving less than this share of every token's total unclaimed, in basis points")
+		labelsPath    = flag.String("labels", outreach.DefaultLabelsPath, "known addresses CSV with address, label and category columns")
+		ens           = flag.Bool("ens", true, "look up holders' ENS names through the registry's chain 1")
+		out           = flag.String("out", "outreach.csv", "CSV to write")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "outreach"); err != nil {
+		fatal(err)
+	}
+	if *filePath == "" {
+		fatal(errors.New("missing --file"))
+	}
+	n, ok := cycle.ParseName(filepath.Base(*filePath))
+	if !ok {
+		fatal(fmt.Errorf("cannot tell the chain of %s from its name", *filePath))
+	}
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+	ch, err := reg.Get(n.ChainID)
+	if err != nil {
+		fatal(err)
+	}
+	if len(ch.Endpoints()) == 0 {
+		fatal(fmt.Errorf("chain %s has no rpc configured", n.ChainID))
+	}
+	labels, err := outreach.LoadLabels(*labelsPath)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx := context.Background()
+	c := evm.NewClient(ch.Endpoints()...)
+	head, err := c.PinBlock(ctx, *block, *confirmations)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("reading at block %d (%s)\n", head, *block)
+	var src claims.Source
+	if *claimedFile != "" {
+		if src, err = claims.LoadFile(*claimedFile); err != nil {
+			fatal(fmt.Errorf("read claimed file: %w", err))
+		}
+	} else {
+		dist, ok := ch.Distributor(n.RewardType)
+		if !ok {
+			fatal(fmt.Errorf("chain %s has no distributor for %s", n.ChainID, n.RewardType))
+		}
+		abi, err := distributor.ForChain(ch)
+		if err == nil {
+			abi, err = abi.Override("getClaimedAmounts", *claimedSig)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		src = &claims.ChainSource{Contract: abi.At(dist, c), Block: evm.BlockParam(head)}
+	}
+
+	f, err := cycle.Load(*filePath)
+	if err != nil {
+		fatal(err)
+	}
+	totals, err := f.SumAmounts()
+	if err != nil {
+		fatal(err)
+	}
+	type candidate struct {
+		leaf      cycle.Leaf
+		unclaimed map[string]*big.Int
+		shareBps  int64
+	}
+	cands := make([]candidate, 0)
+	for _, ud := range f.UserDatas {
+		claimed, err := src.Claimed(ctx, ud.Leaf)
+		if err != nil {
+			fatal(err)
+		}
+		rest, err := claims.Unclaimed(ud.Leaf, claimed)
+		if err != nil {
+			fatal(err)
+		}
+		cd := candidate{leaf: ud.Leaf, unclaimed: rest}
+		for t, a := range rest {
+			if tot := totals[t]; tot.Sign() > 0 {
+				bps := new(big.Int).Mul(a, big.NewInt(10_000))
+				cd.shareBps = max(cd.shareBps, bps.Quo(bps, tot).Int64())
+			}
+		}
+		if len(rest) > 0 && cd.shareBps >= *minShareBps {
+			cands = append(cands, cd)
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].shareBps > cands[j].shareBps })
+	cands = cands[:min(*top, len(cands))]
+
+	en := &outreach.Enricher{Client: c, Block: head, Labels: labels}
+	if *ens {
+		if mainnet, err := reg.Get("1"); err == nil && len(mainnet.Endpoints()) > 0 {
+			en.ENS = evm.NewClient(mainnet.Endpoints()...)
+		} else {
+			runsummary.Warnf("chain 1 has no rpc in the registry; skipping ENS names")
+		}
+	}
+	tokens := make(map[string]chains.Token)
+	rows := make([]outreach.Row, 0, len(cands))
+	for i, cd := range cands {
+		id, err := cycle.ParseAmount(cd.leaf.ERC721ID)
+		if err != nil {
+			fatal(err)
+		}
+		holder, err := c.OwnerOf(ctx, cd.leaf.ERC721Addr, id, evm.BlockParam(head))
+		if err != nil {
+			fatal(fmt.Errorf("position %s: ownerOf: %w", cd.leaf.Key(), err))
+		}
+		r := outreach.Row{ChainID: n.ChainID, Position: cd.leaf.Key(), Holder: holder, ShareBps: cd.shareBps}
+		toks := make([]string, 0, len(cd.unclaimed))
+		for t := range cd.unclaimed {
+			toks = append(toks, t)
+		}
+		sort.Strings(toks)
+		for _, t := range toks {
+			info, ok := tokens[t]
+			if !ok {
+				if info, err = tokenInfo(ctx, c, ch, t); err != nil {
+					fatal(fmt.Errorf("token %s: %w", t, err))
+				}
+				tokens[t] = info
+			}
+			amount, err := evm.FormatUnits(cd.unclaimed[t], info.Decimals)
+			if err != nil {
+				fatal(fmt.Errorf("token %s: %w", t, err))
+			}
+			r.Unclaimed = append(r.Unclaimed, amount+" "+info.Symbol)
+		}
+		if err := en.Enrich(ctx, &r); err != nil {
+			runsummary.Warnf("position %s: %v", r.Position, err)
+		}
+		rows = append(rows, r)
+		fmt.Printf("%3d. %s  %d.%02d%%  holder %s%s%s\n", i+1, r.Position, r.ShareBps/100, r.ShareBps%100, holder, suffix(r.ENS), suffix(r.Label.Name))
+	}
+
+	if err := outreach.WriteCSV(*out, rows, time.Now()); err != nil {
+		fatal(fmt.Errorf("write %s: %w", *out, err))
+	}
+	runsummary.Output(*out)
+	fmt.Printf("wrote %s: %d positions\n", *out, len(rows))
+}
+
+// tokenInfo is t's registry metadata, else its symbol and decimals read
+// on-chain.
+func tokenInfo(ctx context.Context, c *evm.Client, ch chains.Chain, t string) (chains.Token, error) {
+	if info, ok := ch.Token(t); ok {
+		return info, nil
+	}
+	if evm.IsNative(t) {
+		return chains.Token{Symbol: "native", Decimals: 18}, nil
+	}
+	d, err := c.Decimals(ctx, t)
+	if err != nil {
+		return chains.Token{}, err
+	}
+	sym, err := c.Symbol(ctx, t)
+	if err != nil {
+		sym = t
+	}
+	return chains.Token{Symbol: strings.TrimSpace(sym), Decimals: d}, nil
+}
+
+func suffix(s string) string {
+	if s == "" {
+		return ""
+	}
+	return " (" + s + ")"
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err)
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/correction"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// patch-cycle corrects a published merkle file after the fact. It reads a
+// corrections CSV (see package correction) and writes the delta as the next
+// supplementary merkle file beside it, 56_LM_21.patch-1.json, then
+// .patch-2.json and so on. Each patch's metadata records the file it
+// corrects, the patch before it and its own rows, so the chain of
+// corrections travels with the published files:
+//
+//	patch-cycle --file cycle-21/56_LM_21.json --corrections fixes.csv
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		filePath    = flag.String("file", "", "published merkle file to correct")
+		corrections = flag.String("corrections", "", "corrections CSV: recipient,new_recipient,token,amount,reason")
+		out         = flag.String("out", "", "patch file to write (default the next <file>.patch-N.json beside --file)")
+		dryRun      = flag.Bool("dry-run", false, "print the patch's recipients and totals without writing it")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "patch-cycle"); err != nil {
+		fatal(err)
+	}
+
+	if *filePath == "" || *corrections == "" {
+		fatal(errors.New("missing --file or --corrections"))
+	}
+	name, ok := cycle.ParseName(filepath.Base(*filePath))
+	if !ok {
+		fatal(fmt.Errorf("%s is not named like a merkle file", *filePath))
+	}
+	base, err := cycle.Load(*filePath)
+	if err != nil {
+		fatal(fmt.Errorf("load %s: %w", *filePath, err))
+	}
+	cs, err := correction.LoadCSV(*corrections)
+	if err != nil {
+		fatal(err)
+	}
+
+	dir := filepath.Dir(*filePath)
+	patches, err := cycle.Patches(dir, name)
+	if err != nil {
+		fatal(err)
+	}
+	chain := correction.Chain{Base: name.String(), BaseRoot: base.Root, Patch: len(patches) + 1}
+	prior := make([]correction.Correction, 0)
+	for _, p := range patches {
+		f, err := cycle.Load(p)
+		if err != nil {
+			fatal(fmt.Errorf("load %s: %w", p, err))
+		}
+		c, err := correction.ParseChain(f)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", p, err))
+		}
+		if c.BaseRoot != base.Root {
+			fatal(fmt.Errorf("%s corrects root %s, but %s has root %s", p, c.BaseRoot, name, base.Root))
+		}
+		prior = append(prior, c.Corrections...)
+		chain.Previous, chain.PrevRoot = filepath.Base(p), f.Root
+	}
+
+	patch, err := correction.Apply(base, prior, cs, chain)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", *corrections, err))
+	}
+	totals, err := patch.SumAmounts()
+	if err != nil {
+		fatal(err)
+	}
+	path := *out
+	if path == "" {
+		path = filepath.Join(dir, name.PatchName(chain.Patch))
+	}
+	if *dryRun {
+		fmt.Printf("Would write %s: %d corrections, %d recipients, root %s\n", path, len(cs), len(patch.UserDatas), patch.Root)
+	} else {
+		if err := cycle.Write(path, patch); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Wrote %s: %d corrections, %d recipients, root %s\n", path, len(cs), len(patch.UserDatas), patch.Root)
+	}
+	for t, a := range totals {
+		fmt.Printf("  %s %s\n", t, a)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
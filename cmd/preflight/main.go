@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/alert"
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/hosting"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/preflight"
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/webhook"
+)
+
+// preflight runs the day before cycle N is released and prints a
+// red/yellow/green checklist: every Notion row of the cycle is in a
+// --status-done status, no distributor in the reconcile report is short,
+// every chain in the registry has a healthy RPC endpoint, and values.yaml
+// still points at cycle N-1 so the release's URL swap matches.
+//
+//	preflight --cycle 22 --database-id ... --reconcile recon.json --values values.yaml --post
+//
+// With --onchain it also reads every chain's distributors: cycle N-1's root
+// is the one stored, its claims read back, and the balance covers what it
+// still owes plus cycle N. The chains are checked at the same time, each
+// under --chain-timeout, and the checklist ends with a chain × check matrix
+// of pass/warn/fail and durations, so one slow RPC only delays its own row.
+//
+// With --post the checklist goes to webhooks subscribed to
+// preflight.checked, and a red one also pages through PAGERDUTY_ROUTING_KEY
+// or OPSGENIE_API_KEY. It exits 1 when anything is red. With --offline the
+// Notion, RPC and on-chain checks are yellow and listed as skipped, and
+// nothing is posted.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleNum    = flag.Int("cycle", 0, "cycle about to be released")
+		root        = flag.String("root", ".", "repo root holding the cycle-N directories")
+		databaseID  = flag.String("database-id", os.Getenv("NOTION_DATABASE_ID"), "Notion database ID (or env NOTION_DATABASE_ID); the Notion check is skipped without it")
+		notionToken = flag.String("notion-token", os.Getenv("NOTION_TOKEN"), "Notion token (or env NOTION_TOKEN)")
+		notionOAuth = flag.String("notion-oauth", notion.DefaultOAuthConfigPath, "Notion OAuth client config, used when no token is set (see notion-auth)")
+		propTitle   = flag.String("prop-title", "Task name", "Title property name")
+		propCycle   = flag.String("prop-cycle", "", "Number property holding each row's cycle, matched instead of --title-pattern (default: match the title)")
+		titlePat    = flag.String("title-pattern", notion.DefaultTitlePattern, "title of a cycle row, as notion-sync's --title-pattern")
+		propStatus  = flag.String("prop-status", "Status", "Status property name")
+		statusDone  = flag.String("status-done", "Done", "comma-separated statuses a row is ready in")
+		reconPath   = flag.String("reconcile", "", "JSON written by reconcile --json-out; funding is yellow without it")
+		chainsPath  = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		timeout     = flag.Duration("timeout", 10*time.Second, "per-endpoint RPC probe timeout")
+		onchain     = flag.Bool("onchain", false, "also check each chain's distributor code, roots, claims and funding")
+		block       = flag.String("block", evm.DefaultBlockTag, "block tag for on-chain reads: finalized, safe, latest or a number")
+		parallel    = flag.Int("parallel", 0, "chains checked at a time (default all)")
+		chainTO     = flag.Duration("chain-timeout", 5*time.Minute, "give up on a chain's remaining checks after this long")
+		valuesPath  = flag.String("values", "", "path to core/reward-service/api/public/values.yaml; the check is skipped without it")
+		hostConfig  = flag.String("hosting", hosting.DefaultConfigPath, "per-chain/type URL templates of the values.yaml URLs (default raw GitHub)")
+		maintKey    = flag.String("maintenance-key", "maintenance", "name of the values.yaml maintenance key")
+		post        = flag.Bool("post", false, "send the checklist to webhooks and page on red")
+		statePath   = flag.String("state", state.DefaultPath, "state DB holding the webhooks and their retry queue")
+		jsonOut     = flag.Bool("json", false, "print the checklist as JSON")
+	)
+	metaCache := notion.AddCacheFlags(flag.CommandLine, false)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "preflight"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *cycleNum < 2 {
+		fatal(errors.New("missing --cycle"))
+	}
+	ctx := context.Background()
+	list := &preflight.Checklist{Cycle: *cycleNum}
+
+	if *databaseID != "" && offline.Enabled() {
+		list.Add(skipped("Notion"))
+	} else if *databaseID != "" {
+		list.Add(notionCheck(ctx, *databaseID, *notionToken, *notionOAuth, metaCache.Cache(), *cycleNum, *propTitle, *titlePat, *propCycle, *propStatus, *statusDone))
+	}
+
+	var rep *reconcile.Report
+	if *reconPath != "" {
+		var err error
+		if rep, err = reconcile.LoadJSON(*reconPath); err != nil {
+			fatal(err)
+		}
+	}
+	list.Add(preflight.Funding(rep))
+
+	columns := [][2]string{{"rpc", "RPC"}}
+	if *onchain {
+		columns = append(columns, [2]string{"root", "Distributor roots"}, [2]string{"claims", "Claims"}, [2]string{"funding", "On-chain funding"})
+	}
+	if offline.Enabled() {
+		for _, c := range columns {
+			list.Add(skipped(c[1]))
+		}
+	} else {
+		reg, err := chains.Load(*chainsPath)
+		if err != nil {
+			fatal(fmt.Errorf("load chain registry: %w", err))
+		}
+		files := &onchainFiles{n: *cycleNum}
+		if *onchain {
+			if files, err = loadOnchainFiles(*root, *cycleNum); err != nil {
+				fatal(err)
+			}
+		}
+		list.Matrix = preflight.RunMatrix(ctx, reg.IDs(), chainChecks(reg, files, *timeout, *block, *onchain), *parallel, *chainTO)
+		for _, c := range columns {
+			list.Add(list.Matrix.Column(c[0], c[1]))
+		}
+	}
+
+	if *valuesPath != "" {
+		vb, err := os.ReadFile(*valuesPath)
+		if err != nil {
+			fatal(err)
+		}
+		host, err := hosting.LoadConfig(*hostConfig)
+		if err != nil {
+			fatal(err)
+		}
+		urls, err := cycleURLs(*root, *cycleNum-1, host)
+		if err != nil {
+			fatal(err)
+		}
+		list.Add(preflight.Values(string(vb), *cycleNum, urls, *maintKey))
+	}
+
+	if *jsonOut {
+		b, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		fmt.Print(list)
+	}
+
+	if *post && !offline.Skip("posting the checklist (--post)") {
+		if err := postChecklist(ctx, *statePath, list); err != nil {
+			fmt.Fprintln(os.Stderr, "preflight: posting checklist:", err)
+		}
+	}
+	if list.Status() == preflight.Red {
+		tmpdir.Exit(1)
+	}
+}
+
+func notionCheck(ctx context.Context, databaseID, token, oauth string, cache *notion.Cache, n int, propTitle, titlePat, propCycle, propStatus, statusDone string) preflight.Check {
+	ck := preflight.Check{Name: "Notion"}
+	fail := func(err error) preflight.Check {
+		ck.Status, ck.Detail = preflight.Red, err.Error()
+		return ck
+	}
+	pattern, err := notion.ParseTitlePattern(titlePat)
+	if err != nil {
+		return fail(fmt.Errorf("--title-pattern: %w", err))
+	}
+	token, err = notion.ResolveToken(ctx, token, oauth)
+	if err != nil {
+		return fail(err)
+	}
+	cli := notion.NewClient(token, notion.APIVersion)
+	cli.SetCache(cache)
+	src, err := cli.ResolveSource(ctx, databaseID)
+	if err != nil {
+		return fail(err)
+	}
+	cycleStr := fmt.Sprintf("Cycle %d", n)
+	body := map[string]any{
+		"page_size": 100,
+		"filter":    notion.CycleFilter(propTitle, propCycle, pattern, n),
+	}
+	done := strings.Split(statusDone, ",")
+	for i := range done {
+		done[i] = strings.TrimSpace(done[i])
+	}
+	rows := 0
+	err = cli.QueryPages(ctx, src, body, func(results []notion.Page) error {
+		for _, page := range results {
+			ok, err := notion.InCycle(page, propTitle, propCycle, pattern, n)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			title, err := notion.GetTitle(page, propTitle)
+			if err != nil {
+				return err
+			}
+			rows++
+			status, err := notion.GetStatus(page, propStatus)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(done, status) {
+				if status == "" {
+					status = "no status"
+				}
+				ck.Items = append(ck.Items, fmt.Sprintf("%s (%s) %s", title, status, page.Link()))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	switch {
+	case rows == 0:
+		ck.Status, ck.Detail = preflight.Red, "no rows for "+cycleStr
+	case len(ck.Items) > 0:
+		ck.Status, ck.Detail = preflight.Yellow, fmt.Sprintf("%d of %d rows not %s", len(ck.Items), rows, statusDone)
+	default:
+		ck.Status, ck.Detail = preflight.Green, fmt.Sprintf("all %d rows %s", rows, statusDone)
+	}
+	return ck
+}
+
+func probe(ctx context.Context, url, chainID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c := evm.NewClient(url)
+	id, err := c.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if id.String() != chainID {
+		return fmt.Errorf("serves chain %s", id)
+	}
+	_, err = c.BlockNumber(ctx)
+	return err
+}
+
+// skipped is the entry of a check an offline run does not make.
+func skipped(name string) preflight.Check {
+	offline.Skip("preflight " + name + " check")
+	return preflight.Check{Name: name, Status: preflight.Yellow, Detail: "skipped offline"}
+}
+
+// cycleURLs are the values.yaml URLs of cycle n's files, as
+// update-kyber-applications writes them.
+func cycleURLs(root string, n int, host *hosting.Config) ([]string, error) {
+	entries, err := cycle.ScanDir(filepath.Join(root, cycle.DirName(n)))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		urls, err := host.URLs(root, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, urls...)
+	}
+	return out, nil
+}
+
+func postChecklist(ctx context.Context, statePath string, list *preflight.Checklist) error {
+	db, err := state.Open(statePath)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	if err := webhook.NewRegistry(db).Notify(ctx, webhook.NewEvent(webhook.EventPreflight, list)); err != nil {
+		errs = append(errs, err)
+	}
+	if list.Status() == preflight.Red {
+		if sender := alert.FromEnv(); sender != nil {
+			a := alert.Alert{
+				Summary:  fmt.Sprintf("fairflow cycle %d preflight is red", list.Cycle),
+				Source:   "preflight",
+				Class:    alert.ClassPublish,
+				DedupKey: fmt.Sprintf("fairflow:preflight:%d", list.Cycle),
+				Details:  map[string]string{"checklist": list.String()},
+			}
+			if err := sender.Send(ctx, a); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/preflight"
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+)
+
+// onchainFiles are the merkle files the on-chain checks look at: those of
+// cycle n-1, live now, and those of cycle n, about to be.
+type onchainFiles struct {
+	n              int
+	released, next []cycle.Entry
+}
+
+func loadOnchainFiles(root string, n int) (*onchainFiles, error) {
+	f := &onchainFiles{n: n}
+	var err error
+	if f.released, err = cycle.ScanDir(filepath.Join(root, cycle.DirName(n-1))); err != nil {
+		return nil, err
+	}
+	// Cycle n may not be synced yet; funding then only covers cycle n-1.
+	if f.next, err = cycle.ScanDir(filepath.Join(root, cycle.DirName(n))); err != nil {
+		f.next = nil
+	}
+	return f, nil
+}
+
+// chainRun is what the checks of one chain share: the endpoints the rpc
+// check found working and what the claims check read for the funding check.
+type chainRun struct {
+	id           string
+	ch           chains.Chain
+	files        *onchainFiles
+	probeTimeout time.Duration
+	blockTag     string
+
+	healthy []string
+	client  *evm.Client
+	block   string
+	// unclaimed is distributor -> token -> what cycle n-1 still owes; nil
+	// when the claims check failed.
+	unclaimed map[string]map[string]*big.Int
+	loaded    map[string]*cycle.File
+}
+
+// chainChecks are the matrix columns: rpc always, and with onchain the
+// distributor code, root, claims and funding of every chain.
+func chainChecks(reg chains.Registry, files *onchainFiles, probeTimeout time.Duration, blockTag string, onchain bool) preflight.ChainChecks {
+	return func(id string) []preflight.ChainCheck {
+		r := &chainRun{id: id, ch: reg[id], files: files, probeTimeout: probeTimeout, blockTag: blockTag, loaded: make(map[string]*cycle.File)}
+		out := []preflight.ChainCheck{{Name: "rpc", Run: r.rpc}}
+		if onchain {
+			out = append(out,
+				preflight.ChainCheck{Name: "abi", Run: r.abi},
+				preflight.ChainCheck{Name: "root", Run: r.root},
+				preflight.ChainCheck{Name: "claims", Run: r.claims},
+				preflight.ChainCheck{Name: "funding", Run: r.funding})
+		}
+		return out
+	}
+}
+
+// rpc is red when the chain has no endpoint serving it, yellow when only
+// some of its endpoints fail.
+func (r *chainRun) rpc(ctx context.Context) (preflight.Status, string, error) {
+	urls := r.ch.Endpoints()
+	if len(urls) == 0 {
+		return preflight.Red, "no rpc configured", nil
+	}
+	healthy := make([]string, 0, len(urls))
+	failed := make([]string, 0)
+	for _, u := range urls {
+		if err := probe(ctx, u, r.id, r.probeTimeout); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", u, err))
+		} else {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		return preflight.Red, "no healthy endpoint: " + strings.Join(failed, "; "), nil
+	}
+	r.healthy = healthy
+	if len(failed) > 0 {
+		return preflight.Yellow, fmt.Sprintf("%d of %d endpoints failing: %s", len(failed), len(urls), strings.Join(failed, "; ")), nil
+	}
+	return preflight.Green, fmt.Sprintf("%d endpoints healthy", len(urls)), nil
+}
+
+// reader is a client over the endpoints the rpc check found healthy, pinned
+// on first use to one block so every read of the chain agrees.
+func (r *chainRun) reader(ctx context.Context) (*evm.Client, error) {
+	if r.client != nil {
+		return r.client, nil
+	}
+	if len(r.healthy) == 0 {
+		return nil, errors.New("no healthy rpc")
+	}
+	c := evm.NewClient(r.healthy...)
+	n, err := c.PinBlock(ctx, r.blockTag, 0)
+	if err != nil {
+		return nil, err
+	}
+	r.client, r.block = c, evm.BlockParam(n)
+	return c, nil
+}
+
+// abi checks the code of each distributor dispatches every function the
+// chain's ABI binds.
+func (r *chainRun) abi(ctx context.Context) (preflight.Status, string, error) {
+	files := r.entries(slices.Concat(r.files.released, r.files.next))
+	if len(files) == 0 {
+		return preflight.Green, "no files on this chain", nil
+	}
+	c, err := r.reader(ctx)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	abi, err := distributor.ForChain(r.ch)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	seen := make(map[string]bool)
+	bad := make([]string, 0)
+	for _, e := range files {
+		dist, err := r.distributor(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		if seen[dist] {
+			continue
+		}
+		seen[dist] = true
+		if err := abi.At(dist, c).CheckCode(ctx, r.block); err != nil {
+			bad = append(bad, err.Error())
+		}
+	}
+	if len(bad) > 0 {
+		return preflight.Red, strings.Join(bad, "; "), nil
+	}
+	return preflight.Green, fmt.Sprintf("%d distributor(s) dispatch ABI %s", len(seen), abi.Name), nil
+}
+
+// root checks each distributor stores the root of its cycle n-1 file, or
+// already that of cycle n.
+func (r *chainRun) root(ctx context.Context) (preflight.Status, string, error) {
+	released := r.entries(r.files.released)
+	if len(released) == 0 {
+		return preflight.Green, "no files on this chain", nil
+	}
+	c, err := r.reader(ctx)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	abi, err := distributor.ForChain(r.ch)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	next := make(map[string]cycle.Entry)
+	for _, e := range r.entries(r.files.next) {
+		next[e.RewardType] = e
+	}
+	bad := make([]string, 0)
+	for _, e := range released {
+		dist, err := r.distributor(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		onChain, err := abi.At(dist, c).MerkleRoot(ctx, r.block)
+		if err != nil {
+			return preflight.Red, "", fmt.Errorf("%s: %w", e, err)
+		}
+		want, err := r.expectedRoot(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		if strings.EqualFold(onChain, want) {
+			continue
+		}
+		if ne, ok := next[e.RewardType]; ok {
+			if nw, err := r.expectedRoot(ne); err == nil && strings.EqualFold(onChain, nw) {
+				continue
+			}
+		}
+		bad = append(bad, fmt.Sprintf("%s: distributor %s stores %s, want %s", e, dist, onChain, want))
+	}
+	if len(bad) > 0 {
+		return preflight.Red, strings.Join(bad, "; "), nil
+	}
+	return preflight.Green, fmt.Sprintf("%d distributor root(s) match", len(released)), nil
+}
+
+// claims reads back what every position of cycle n-1 has claimed; a read
+// that fails or a claim above a leaf's amount is red.
+func (r *chainRun) claims(ctx context.Context) (preflight.Status, string, error) {
+	released := r.entries(r.files.released)
+	if len(released) == 0 {
+		r.unclaimed = map[string]map[string]*big.Int{}
+		return preflight.Green, "no files on this chain", nil
+	}
+	c, err := r.reader(ctx)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	abi, err := distributor.ForChain(r.ch)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	unclaimed := make(map[string]map[string]*big.Int)
+	positions, skipped := 0, 0
+	for _, e := range released {
+		f, err := r.load(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		if f.Format == cycle.FormatUniswap {
+			skipped++
+			continue
+		}
+		dist, err := r.distributor(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		src := &claims.ChainSource{Contract: abi.At(dist, c), Block: r.block}
+		rest, err := reconcile.FileUnclaimed(ctx, f, src)
+		if err != nil {
+			return preflight.Red, "", fmt.Errorf("%s: %w", e, err)
+		}
+		addAmounts(unclaimed, dist, rest)
+		positions += len(f.UserDatas)
+	}
+	r.unclaimed = unclaimed
+	detail := fmt.Sprintf("%d position(s) read back", positions)
+	if skipped > 0 {
+		detail += fmt.Sprintf("; %d merkle-distributor file(s) not checked", skipped)
+	}
+	return preflight.Green, detail, nil
+}
+
+// funding checks each distributor holds what cycle n-1 still owes plus all
+// of cycle n. Earlier cycles' leftovers are reconcile's to report.
+func (r *chainRun) funding(ctx context.Context) (preflight.Status, string, error) {
+	if r.unclaimed == nil {
+		return preflight.Red, "", errors.New("claims were not read")
+	}
+	need := make(map[string]map[string]*big.Int)
+	for dist, toks := range r.unclaimed {
+		addAmounts(need, dist, toks)
+	}
+	for _, e := range r.entries(r.files.next) {
+		f, err := r.load(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		dist, err := r.distributor(e)
+		if err != nil {
+			return preflight.Red, "", err
+		}
+		sums, err := f.SumAmounts()
+		if err != nil {
+			return preflight.Red, "", fmt.Errorf("%s: %w", e, err)
+		}
+		addAmounts(need, dist, sums)
+	}
+	if len(need) == 0 {
+		return preflight.Green, "nothing owed on this chain", nil
+	}
+	c, err := r.reader(ctx)
+	if err != nil {
+		return preflight.Red, "", err
+	}
+	short := make([]string, 0)
+	pairs := 0
+	for _, dist := range sortedKeys(need) {
+		for _, t := range sortedKeys(need[dist]) {
+			pairs++
+			bal, err := c.TokenBalance(ctx, t, dist, r.block)
+			if err != nil {
+				return preflight.Red, "", fmt.Errorf("balance of %s at %s: %w", t, dist, err)
+			}
+			if s := new(big.Int).Sub(need[dist][t], bal); s.Sign() > 0 {
+				short = append(short, fmt.Sprintf("distributor %s token %s short %s", dist, t, s))
+			}
+		}
+	}
+	if len(short) > 0 {
+		return preflight.Red, strings.Join(short, "; "), nil
+	}
+	return preflight.Green, fmt.Sprintf("%d distributor balance(s) cover cycles %d and %d", pairs, r.files.n-1, r.files.n), nil
+}
+
+func (r *chainRun) entries(all []cycle.Entry) []cycle.Entry {
+	out := make([]cycle.Entry, 0)
+	for _, e := range all {
+		if e.ChainID == r.id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (r *chainRun) distributor(e cycle.Entry) (string, error) {
+	dist, ok := r.ch.Distributor(e.RewardType)
+	if !ok {
+		return "", fmt.Errorf("no distributor configured for chain %s type %s", e.ChainID, e.RewardType)
+	}
+	return strings.ToLower(dist), nil
+}
+
+func (r *chainRun) load(e cycle.Entry) (*cycle.File, error) {
+	if f, ok := r.loaded[e.Path]; ok {
+		return f, nil
+	}
+	f, err := cycle.Load(e.Path)
+	if err != nil {
+		return nil, err
+	}
+	r.loaded[e.Path] = f
+	return f, nil
+}
+
+// expectedRoot is the root a distributor stores for e: its master root when
+// it is two-level sharded, else its own.
+func (r *chainRun) expectedRoot(e cycle.Entry) (string, error) {
+	f, err := r.load(e)
+	if err != nil {
+		return "", err
+	}
+	want := f.Root
+	if f.Format == cycle.FormatUniswap {
+		want = f.Claims.MerkleRoot
+	}
+	idx, err := cycle.LoadShardIndex(filepath.Dir(e.Path), e.Name)
+	if err != nil {
+		return "", err
+	}
+	if idx != nil && idx.TwoLevel() {
+		want = idx.MasterRoot
+	}
+	return want, nil
+}
+
+func addAmounts(into map[string]map[string]*big.Int, dist string, amounts map[string]*big.Int) {
+	if into[dist] == nil {
+		into[dist] = make(map[string]*big.Int)
+	}
+	for t, a := range amounts {
+		if prev, ok := into[dist][t]; ok {
+			prev.Add(prev, a)
+		} else {
+			into[dist][t] = new(big.Int).Set(a)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
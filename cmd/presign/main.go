@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/storage"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// presign prints time-limited URLs for cycle files hosted in a private
+// bucket, one per key argument (e.g. cycle-12/56_LM_12.json).
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		dest          = flag.String("dest", "", "destination name or storage URL hosting the cycles")
+		storageConfig = flag.String("storage-config", storage.DefaultConfigPath, "named storage destinations JSON")
+		ttl           = flag.Duration("ttl", time.Hour, "URL validity")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "presign"); err != nil {
+		fatal(err)
+	}
+
+	if *dest == "" || flag.NArg() == 0 {
+		fatal(errors.New("usage: presign --dest <name|url> [--ttl 1h] cycle-N/<file> ..."))
+	}
+	cfg, err := storage.LoadConfig(*storageConfig)
+	if err != nil {
+		fatal(err)
+	}
+	b, err := cfg.Open(*dest)
+	if err != nil {
+		fatal(err)
+	}
+	defer b.Close()
+	for _, key := range flag.Args() {
+		if !cycle.IsArtifactKey(key) {
+			fatal(fmt.Errorf("%q is not a cycle-N/<file> key", key))
+		}
+		u, err := storage.Presign(context.Background(), b, key, *ttl)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("%s\t%s\n", key, u)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
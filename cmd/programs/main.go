@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/programs"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// programs writes programs.json, the claim UI's discovery document: every
+// chain/type/cycle reward-service's values.yaml currently serves, with its
+// URLs, roots, token totals and claim deadline. Run it after
+// update-kyber-applications; serve --values answers the same document at
+// /programs.json.
+//
+//	programs --values core/reward-service/api/public/values.yaml --out programs.json
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		valuesPath = flag.String("values", "", "path to core/reward-service/api/public/values.yaml")
+		root       = flag.String("root", ".", "repo root containing cycle-N directories")
+		out        = flag.String("out", programs.FileName, "file to write")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "programs"); err != nil {
+		fatal(err)
+	}
+	if *valuesPath == "" {
+		fatal(errors.New("missing --values"))
+	}
+	vb, err := os.ReadFile(*valuesPath)
+	if err != nil {
+		fatal(err)
+	}
+	doc, err := programs.Build(vb, *root)
+	if err != nil {
+		fatal(err)
+	}
+	if len(doc.Programs) == 0 {
+		fatal(fmt.Errorf("no merkle URLs found in %s", *valuesPath))
+	}
+	if err := programs.Write(*out, doc); err != nil {
+		fatal(err)
+	}
+	runsummary.Output(*out)
+	fmt.Printf("Wrote %s: %d programs\n", *out, len(doc.Programs))
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
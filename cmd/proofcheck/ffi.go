@@ -0,0 +1,23 @@
+//go:build cgo
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/KyberNetwork/fairflow-reward/proofcheck"
+)
+
+// fairflow_verify_proof takes a NUL-terminated /proof response and returns
+// the JSON result, which the caller releases with fairflow_free.
+//
+//export fairflow_verify_proof
+func fairflow_verify_proof(req *C.char) *C.char {
+	return C.CString(string(proofcheck.VerifyJSON([]byte(C.GoString(req)))))
+}
+
+//export fairflow_free
+func fairflow_free(p *C.char) { C.free(unsafe.Pointer(p)) }
@@ -0,0 +1,47 @@
+//go:build !(js && wasm)
+
+// proofcheck verifies a claim proof from a /proof response, with the code
+// the merkle files are built with, for the claim front-end and wallets:
+//
+//	GOOS=js GOARCH=wasm go build -o proofcheck.wasm ./cmd/proofcheck
+//	go build -buildmode=c-shared -o libproofcheck.so ./cmd/proofcheck
+//
+// The WebAssembly module sets a global fairflowVerifyProof(json) (see
+// wasm.go); the shared library exports fairflow_verify_proof and
+// fairflow_free (see ffi.go). Both take a /proof response as JSON and
+// return {"valid": bool, "unsupported": bool, "error": string}; unsupported
+// means the proof could not be judged, not that it is wrong. Built as a
+// plain binary it reads the response on stdin and exits 1 unless the proof
+// verifies, 2 when it could not be judged:
+//
+//	curl -s 'localhost:8080/proof?cycle=21&file=56_LM_21.json&position=0xabc:7' | proofcheck
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/proofcheck"
+)
+
+func main() {
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+		os.Exit(1)
+	}
+	out := proofcheck.VerifyJSON(b)
+	fmt.Println(string(out))
+	var res proofcheck.Result
+	if json.Unmarshal(out, &res) != nil {
+		os.Exit(1)
+	}
+	switch {
+	case res.Unsupported:
+		os.Exit(2)
+	case !res.Valid:
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/KyberNetwork/fairflow-reward/proofcheck"
+)
+
+// main exposes fairflowVerifyProof(json string) string to JavaScript, then
+// blocks so the function stays callable.
+func main() {
+	js.Global().Set("fairflowVerifyProof", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return `{"valid":false,"error":"fairflowVerifyProof takes one JSON string"}`
+		}
+		return string(proofcheck.VerifyJSON([]byte(args[0].String())))
+	}))
+	select {}
+}
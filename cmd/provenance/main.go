@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/provenance"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// provenance checks that no published merkle file, shard or patch was
+// rewritten after its cycle went out: the blob at HEAD, and in the working
+// tree, must be the one of the commit that first added the file.
+//
+//	provenance --all
+//	provenance --cycle-dir cycle-21
+//
+// Each file's first publication is pinned in the state DB, so a later run
+// also catches a rewrite of that commit itself. It needs the full history,
+// not a shallow clone, and exits 1 on any finding.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		root      = flag.String("root", ".", "repo root containing cycle-N directories")
+		cycleDir  = flag.String("cycle-dir", "", "check one cycle-N directory under --root")
+		all       = flag.Bool("all", false, "check every cycle-N directory under --root")
+		statePath = flag.String("state", state.DefaultPath, "state DB pinning each file's first publication")
+		noPin     = flag.Bool("no-pin", false, "compare with the history only, not with pinned publications")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "provenance"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+
+	dirs := make([]string, 0)
+	switch {
+	case *all:
+		cycles, err := cycle.Cycles(*root)
+		if err != nil {
+			fatal(err)
+		}
+		for _, n := range cycles {
+			dirs = append(dirs, cycle.DirName(n))
+		}
+	case *cycleDir != "":
+		dirs = append(dirs, filepath.Base(*cycleDir))
+	default:
+		fatal(errors.New("missing --cycle-dir or --all"))
+	}
+
+	repo := provenance.Repo{Root: *root}
+	if err := repo.CheckHistory(); err != nil {
+		fatal(err)
+	}
+	var db *state.DB
+	if !*noPin {
+		var err error
+		if db, err = state.Open(*statePath); err != nil {
+			fatal(err)
+		}
+	}
+
+	checked, bad := 0, 0
+	for _, d := range dirs {
+		paths, err := published(*root, d)
+		if err != nil {
+			fatal(err)
+		}
+		for _, p := range paths {
+			res, err := repo.Check(p, db)
+			if err != nil {
+				fatal(fmt.Errorf("%s: %w", p, err))
+			}
+			checked++
+			problems := res.Problems()
+			if len(problems) > 0 {
+				bad++
+			}
+			for _, msg := range problems {
+				fmt.Printf("%s: %s\n", res.Path, msg)
+			}
+		}
+	}
+	if db != nil {
+		if err := db.Save(); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Printf("Checked %d files: %d rewritten or uncommitted\n", checked, bad)
+	if bad > 0 {
+		tmpdir.Exit(1)
+	}
+}
+
+// published lists, relative to root, the files of cycle directory dir that
+// claims are made from: merkle files, their shards and patches. Manifests,
+// stats and other derived sidecars are regenerated and not checked.
+func published(root, dir string) ([]string, error) {
+	abs := filepath.Join(root, dir)
+	entries, err := cycle.ScanDir(abs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, filepath.Join(dir, e.String()))
+		patches, err := cycle.Patches(abs, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patches {
+			out = append(out, filepath.Join(dir, filepath.Base(p)))
+		}
+		idx, err := cycle.LoadShardIndex(abs, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		if idx == nil {
+			continue
+		}
+		for _, s := range idx.Shards {
+			out = append(out, filepath.Join(dir, s.File))
+		}
+	}
+	return out, nil
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
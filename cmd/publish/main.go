@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/approval"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/embargo"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/metrics"
+	"github.com/KyberNetwork/fairflow-reward/policy"
+	"github.com/KyberNetwork/fairflow-reward/profile"
+	"github.com/KyberNetwork/fairflow-reward/releasegate"
+	"github.com/KyberNetwork/fairflow-reward/storage"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+// publish uploads a cycle directory to one or more output destinations
+// (partner SFTP drops, WebDAV shares, buckets) from config/storage.json.
+//
+// With --templates it also renders the release's PR title and body and the
+// commit message for git destinations from Go templates in that directory
+// (pr-title.tmpl, pr-body.tmpl, commit.tmpl; each optional), executed with
+// the cycle summary, its diff against the previous cycle and its Notion
+// links. --locale picks the language: templates in the locale's
+// subdirectory (vi/pr-body.tmpl) win, and the tr function and the default
+// templates use its message catalog. --message-out writes the rendered
+// messages for the PR step.
+//
+// Before proposing or uploading, the cycle must pass the release gates of
+// --gates (see package releasegate); --gate-approvals grants the exceptions
+// they ask for.
+//
+// Each publish appends the cycle's number, date, chains, totals and PR link
+// to --changelog, so the repo keeps its own release history.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir      = flag.String("cycle-dir", "", "cycle-N directory to publish")
+		to            = flag.String("to", "", "comma-separated destination names or storage URLs")
+		storageConfig = flag.String("storage-config", storage.DefaultConfigPath, "named storage destinations JSON")
+		dryRun        = flag.Bool("dry-run", false, "print what would be uploaded")
+
+		propose      = flag.Bool("propose", false, "write and sign a release proposal for a second operator to approve, then stop")
+		proposalPath = flag.String("proposal", "", "release proposal (default release-proposal-<cycle>.json)")
+		approvers    = flag.String("approvers", approval.DefaultConfigPath, "approvers JSON; two-person approval is on when it requires 2+ signatures")
+		signer       = flag.String("signer", os.Getenv("FAIRFLOW_SIGNER"), "your name in the approvers file, for --propose (or env FAIRFLOW_SIGNER)")
+		keyPath      = flag.String("key", os.Getenv("FAIRFLOW_SIGNING_KEY"), "your ed25519 private key PEM, for --propose (or env FAIRFLOW_SIGNING_KEY)")
+
+		templates  = flag.String("templates", os.Getenv("FAIRFLOW_TEMPLATES"), "directory of pr-title.tmpl, pr-body.tmpl and commit.tmpl Go templates (or env FAIRFLOW_TEMPLATES)")
+		messageOut = flag.String("message-out", "", "directory to write the rendered pr-title.txt, pr-body.md and commit.txt to")
+		localeName = flag.String("locale", os.Getenv("FAIRFLOW_LOCALE"), "language of the rendered messages: en, vi or zh (or env FAIRFLOW_LOCALE; default en)")
+		changelog  = flag.String("changelog", summary.DefaultChangelog, "history to append the published cycle to; .json for JSON (empty = off)")
+		prURL      = flag.String("pr-url", "", "pull request publishing the cycle, for the changelog")
+
+		gatesPath     = flag.String("gates", releasegate.DefaultPath, "release gate policies JSON, checked before proposing or uploading (missing = none)")
+		gateApprovals = flag.String("gate-approvals", "", "comma-separated exceptions the release gates look for, e.g. new-chain:137")
+	)
+	prof := profile.AddFlags(flag.CommandLine)
+	gate := policy.AddFlags(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "publish"); err != nil {
+		fatal(err)
+	}
+	active, err := prof.Apply(flag.CommandLine, "publish")
+	if err != nil {
+		fatal(err)
+	}
+
+	if *cycleDir == "" || (*to == "" && !*propose && *messageOut == "") {
+		fatal(errors.New("missing --cycle-dir or --to"))
+	}
+	if err := embargo.CheckOpen(*cycleDir); err != nil {
+		fatal(err)
+	}
+	dirName := filepath.Base(filepath.Clean(*cycleDir))
+	entries, err := cycle.ScanDir(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(entries) == 0 {
+		fatal(fmt.Errorf("no merkle files in %s", *cycleDir))
+	}
+	cycleNum := entries[0].Cycle
+	if *proposalPath == "" {
+		*proposalPath = approval.DefaultProposalPath(cycleNum)
+	}
+	acfg, err := approval.LoadConfig(*approvers)
+	if err != nil {
+		fatal(err)
+	}
+	if *to != "" || *propose {
+		if err := checkGates(*gatesPath, *cycleDir, cycleNum, *gateApprovals); err != nil {
+			if !*dryRun {
+				fatal(err)
+			}
+			fmt.Printf("would stop: %v\n", err)
+		}
+	}
+	if *propose {
+		writeProposal(acfg, *cycleDir, *proposalPath, *signer, *keyPath)
+		return
+	}
+	if !*dryRun {
+		if err := gate.Check(policy.OpPublish, active.ID(), cycleNum); err != nil {
+			fatal(err)
+		}
+		if acfg.Enabled() {
+			p, err := approval.Load(*proposalPath)
+			if err != nil {
+				fatal(fmt.Errorf("two-person approval is required: %w (run publish --propose, then approve)", err))
+			}
+			if err := p.Check(acfg, *cycleDir); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	files, err := cycle.ArtifactPaths(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	var commitMsg string
+	if *templates != "" || *messageOut != "" {
+		locale, err := summary.ParseLocale(*localeName)
+		if err != nil {
+			fatal(err)
+		}
+		msgs, err := renderMessages(*cycleDir, *templates, locale)
+		if err != nil {
+			fatal(err)
+		}
+		commitMsg = msgs[2].text
+		if *messageOut != "" {
+			if err := writeMessages(*messageOut, msgs); err != nil {
+				fatal(err)
+			}
+		} else if *dryRun {
+			for _, m := range msgs {
+				fmt.Printf("--- %s\n%s\n", m.out, m.text)
+			}
+		}
+	}
+
+	cfg, err := storage.LoadConfig(*storageConfig)
+	if err != nil {
+		fatal(err)
+	}
+	tracing.Init("fairflow-publish")
+	defer tracing.Flush()
+	metrics.Init("fairflow-publish")
+	metrics.SetLabel("cycle", strconv.Itoa(cycleNum))
+	defer metrics.Push(nil)
+	ctx, span := tracing.Start(context.Background(), "publish", "cycle_dir", *cycleDir)
+	defer span.End(nil)
+	for _, dest := range strings.Split(*to, ",") {
+		dest = strings.TrimSpace(dest)
+		if dest == "" {
+			continue
+		}
+		b, err := cfg.Open(dest)
+		if err != nil {
+			fatal(err)
+		}
+		if c, ok := b.(storage.Committer); ok && commitMsg != "" {
+			c.SetCommitMessage(commitMsg)
+		}
+		for _, p := range files {
+			key := path.Join(dirName, filepath.Base(p))
+			if *dryRun {
+				fmt.Printf("would upload %s -> %s %s\n", p, b, key)
+				continue
+			}
+			if err := storage.PutFile(ctx, b, key, p); err != nil {
+				fatal(err)
+			}
+			metrics.Add(metrics.Files, 1)
+			if fi, err := os.Stat(p); err == nil {
+				metrics.Add(metrics.Bytes, fi.Size())
+			}
+		}
+		if err := b.Close(); err != nil {
+			fatal(fmt.Errorf("%s: %w", b, err))
+		}
+		if !*dryRun {
+			fmt.Printf("Published %d files to %s\n", len(files), b)
+		}
+	}
+	if *changelog != "" && *to != "" {
+		if *dryRun {
+			fmt.Printf("would add cycle %d to %s\n", cycleNum, *changelog)
+			return
+		}
+		s, err := summary.Build(*cycleDir)
+		if err != nil {
+			fatal(err)
+		}
+		changed, err := summary.AppendChangelog(*changelog, s.ChangelogEntry(time.Now(), *prURL))
+		if err != nil {
+			fatal(fmt.Errorf("changelog: %w", err))
+		}
+		if changed {
+			runsummary.Output(*changelog)
+			fmt.Printf("Added cycle %d to %s\n", cycleNum, *changelog)
+		}
+	}
+}
+
+type message struct {
+	tmpl, out, fallback string
+	text                string
+}
+
+// renderMessages renders the PR title, PR body and commit message, in that
+// order, in locale. A template is read from dir/<locale>, else dir; one
+// missing from both (or every one, without dir) falls back to the default.
+// The commit message has none, leaving the backend's own.
+func renderMessages(cycleDir, dir string, locale summary.Locale) ([]message, error) {
+	msgs := []message{
+		{tmpl: "pr-title.tmpl", out: "pr-title.txt", fallback: summary.DefaultPRTitle},
+		{tmpl: "pr-body.tmpl", out: "pr-body.md", fallback: summary.DefaultPRBody},
+		{tmpl: "commit.tmpl", out: "commit.txt"},
+	}
+	rel, err := summary.NewRelease(cycleDir)
+	if err != nil {
+		return nil, err
+	}
+	rel.Locale = locale
+	for i, m := range msgs {
+		text := m.fallback
+		if dir != "" {
+		lookup:
+			for _, p := range []string{filepath.Join(dir, string(locale), m.tmpl), filepath.Join(dir, m.tmpl)} {
+				b, err := os.ReadFile(p)
+				switch {
+				case err == nil:
+					text = string(b)
+					break lookup
+				case !os.IsNotExist(err):
+					return nil, err
+				}
+			}
+		}
+		if text == "" {
+			continue
+		}
+		out, err := rel.Render(m.tmpl, text)
+		if err != nil {
+			return nil, err
+		}
+		msgs[i].text = strings.TrimSpace(out)
+	}
+	return msgs, nil
+}
+
+func writeMessages(dir string, msgs []message) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if m.text == "" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, m.out), []byte(m.text+"\n"), 0o644); err != nil {
+			return err
+		}
+		runsummary.Output(filepath.Join(dir, m.out))
+		fmt.Printf("Wrote %s\n", filepath.Join(dir, m.out))
+	}
+	return nil
+}
+
+// checkGates evaluates the release gates against the cycle in dir and
+// prints each verdict; any failure is an error.
+func checkGates(path, dir string, n int, approvals string) error {
+	cfg, err := releasegate.Load(path)
+	if err != nil || len(cfg.Gates) == 0 {
+		return err
+	}
+	cur, err := summary.Build(dir)
+	if err != nil {
+		return err
+	}
+	history, err := cfg.LoadHistory(dir, n)
+	if err != nil {
+		return fmt.Errorf("release gates: %w", err)
+	}
+	appr := make([]string, 0)
+	for _, a := range strings.Split(approvals, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			appr = append(appr, a)
+		}
+	}
+	failed := make([]string, 0)
+	for _, r := range cfg.Evaluate(cur, history, appr) {
+		if r.OK {
+			fmt.Printf("gate %s: ok\n", r.Gate)
+			continue
+		}
+		fmt.Printf("gate %s: FAILED: %s\n", r.Gate, r.Problem)
+		failed = append(failed, r.Gate)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("release gates failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func writeProposal(cfg *approval.Config, dir, path, signer, keyPath string) {
+	if signer == "" || keyPath == "" {
+		fatal(errors.New("--propose needs --signer and --key"))
+	}
+	key, err := approval.LoadKey(keyPath)
+	if err != nil {
+		fatal(err)
+	}
+	p, err := approval.New(dir, signer)
+	if err != nil {
+		fatal(err)
+	}
+	p.Sign(signer, key)
+	if !p.SignedByProposer(cfg) {
+		fatal(fmt.Errorf("key does not match %s's public key in the approvers file", signer))
+	}
+	if err := p.Write(path); err != nil {
+		fatal(err)
+	}
+	runsummary.Output(path)
+	fmt.Printf("Wrote %s for cycle %d. A second operator must now run:\n  approve --proposal %s --cycle-dir %s\n", path, p.Body.Cycle, path, dir)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	tracing.Flush()
+	metrics.Push(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
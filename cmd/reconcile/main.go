@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		root             = flag.String("root", ".", "repo root containing cycle-N directories")
+		chainsPath       = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		cyclesFlag       = flag.String("cycles", "", "comma-separated cycle numbers to look for each distributor's current file in (default: every cycle-N directory)")
+		chainFilter      = flag.String("chain", "", "only reconcile this chain ID")
+		claimedDir       = flag.String("claimed-dir", "", "directory of <chain>_<type>_<cycle>.json claimed snapshots used instead of on-chain reads; a distributor's newest snapshotted file is taken as its current one")
+		claimedSig       = flag.String("claimed-sig", "", "distributor view returning claimed amounts (default: the chain's distributor ABI's getClaimedAmounts)")
+		block            = flag.String("block", evm.DefaultBlockTag, "block tag for on-chain reads: finalized, safe, latest or a number")
+		confirmations    = flag.Uint64("confirmations", 0, "read this many blocks behind --block")
+		jsonOut          = flag.String("json-out", "", "write the reconciliation result as JSON")
+		allowUnderfunded = flag.Bool("allow-underfunded", false, "exit 0 even if a distributor is underfunded")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "reconcile"); err != nil {
+		fatal(err)
+	}
+
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+	cycles, err := parseCycles(*cyclesFlag, *root)
+	if err != nil {
+		fatal(err)
+	}
+	if len(cycles) == 0 {
+		fatal(fmt.Errorf("no cycle directories found under %s", *root))
+	}
+
+	ctx := context.Background()
+	// Each chain is pinned to one block on first use, so all of its reads
+	// agree with each other and do not flap near the head.
+	clients := make(map[string]*evm.Client)
+	blocks := make(map[string]uint64)
+	client := func(chainID string) (*evm.Client, string, error) {
+		if c, ok := clients[chainID]; ok {
+			return c, evm.BlockParam(blocks[chainID]), nil
+		}
+		ch, err := reg.Get(chainID)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(ch.Endpoints()) == 0 {
+			return nil, "", fmt.Errorf("chain %s has no rpc configured", chainID)
+		}
+		c := evm.NewClient(ch.Endpoints()...)
+		n, err := c.PinBlock(ctx, *block, *confirmations)
+		if err != nil {
+			return nil, "", fmt.Errorf("chain %s: %w", chainID, err)
+		}
+		clients[chainID], blocks[chainID] = c, n
+		return c, evm.BlockParam(n), nil
+	}
+
+	// A distributor's claims are cumulative against its one root, so only
+	// the file for that root is reconciled; group each distributor's files
+	// across the cycles to find it.
+	groups := make(map[string][]cycle.Entry)
+	keys := make([]string, 0)
+	for _, n := range cycles {
+		entries, err := cycle.ScanDir(filepath.Join(*root, cycle.DirName(n)))
+		if err != nil {
+			fatal(err)
+		}
+		for _, e := range entries {
+			if *chainFilter != "" && e.ChainID != *chainFilter {
+				continue
+			}
+			k := e.ChainID + "_" + e.RewardType
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], e)
+		}
+	}
+	sort.Strings(keys)
+
+	accum := reconcile.NewAccumulator()
+	for _, k := range keys {
+		entries := groups[k]
+		first := entries[0]
+		ch, err := reg.Get(first.ChainID)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", first.Path, err))
+		}
+		dist, ok := ch.Distributor(first.RewardType)
+		if !ok {
+			fatal(fmt.Errorf("%s: no distributor configured for chain %s type %s", first.Path, first.ChainID, first.RewardType))
+		}
+		files := make([]*cycle.File, len(entries))
+		for i, e := range entries {
+			if files[i], err = cycle.Load(e.Path); err != nil {
+				fatal(fmt.Errorf("load %s: %w", e.Path, err))
+			}
+		}
+		i, src, err := current(ctx, *claimedDir, entries, files, func() (*evm.Client, string, error) { return client(first.ChainID) }, ch, dist, *claimedSig)
+		if err != nil {
+			fatal(fmt.Errorf("chain %s type %s: %w", first.ChainID, first.RewardType, err))
+		}
+		e, f := entries[i], files[i]
+		totals, err := f.SumAmounts()
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", e.Path, err))
+		}
+		unclaimed, err := reconcile.FileUnclaimed(ctx, f, src)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", e.Path, err))
+		}
+		accum.Add(e.ChainID, dist, e, f.Root, totals, unclaimed)
+	}
+
+	balance := func(ctx context.Context, chainID, token, holder string) (*big.Int, error) {
+		c, b, err := client(chainID)
+		if err != nil {
+			return nil, err
+		}
+		return c.TokenBalance(ctx, token, holder, b)
+	}
+	treasury := func(chainID string) string {
+		return reg[chainID].Treasury
+	}
+	rep, err := accum.Finish(ctx, *block, balance, treasury)
+	if err != nil {
+		fatal(err)
+	}
+	rep.Blocks = blocks
+	for _, id := range sortedChains(blocks) {
+		fmt.Printf("chain %s read at block %d (%s", id, blocks[id], *block)
+		if *confirmations > 0 {
+			fmt.Printf(" - %d", *confirmations)
+		}
+		fmt.Println(")" + link(reg[id].BlockURL(blocks[id])))
+	}
+	for i, l := range rep.Lines {
+		ch := reg[l.ChainID]
+		links := reconcile.Links{Distributor: ch.AddressURL(l.Distributor), Token: ch.AddressURL(l.Token)}
+		if l.Treasury != "" {
+			links.Treasury = ch.AddressURL(l.Treasury)
+		}
+		if links != (reconcile.Links{}) {
+			rep.Lines[i].Links = &links
+		}
+	}
+
+	for _, l := range rep.Lines {
+		status := "OK"
+		if l.Underfunded() {
+			status = "UNDERFUNDED"
+		}
+		fmt.Printf("[%s] chain %s distributor %s token %s\n", status, l.ChainID, l.Distributor, l.Token)
+		if l.Links != nil {
+			fmt.Printf("  distributor: %s\n  token:       %s\n", l.Links.Distributor, l.Links.Token)
+		}
+		fmt.Printf("  balance:   %s\n", l.Balance)
+		fmt.Printf("  unclaimed: %s\n", l.Unclaimed)
+		if l.Underfunded() {
+			fmt.Printf("  shortfall: %s\n", l.Shortfall)
+		}
+		if l.Treasury != "" {
+			fmt.Printf("  treasury %s balance: %s\n", l.Treasury, l.TreasuryBal)
+			if l.Links != nil && l.Links.Treasury != "" {
+				fmt.Printf("  treasury:    %s\n", l.Links.Treasury)
+			}
+		}
+		for _, c := range l.Cycles {
+			fmt.Printf("    cycle %d %s (root %s): unclaimed %s of %s\n", c.Cycle, c.File, c.Root, c.Unclaimed, c.Total)
+		}
+	}
+
+	if *jsonOut != "" {
+		if err := reconcile.WriteJSON(*jsonOut, rep); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *jsonOut, err))
+		}
+		runsummary.Output(*jsonOut)
+	}
+	if under := rep.Underfunded(); len(under) > 0 && !*allowUnderfunded {
+		fatal(fmt.Errorf("%d distributor/token pairs are underfunded", len(under)))
+	}
+}
+
+// current picks the distributor's current file among entries, in cycle
+// order, and where its claims are read from: the newest file with a snapshot
+// in dir, or else the file whose root the distributor reports on-chain.
+func current(ctx context.Context, dir string, entries []cycle.Entry, files []*cycle.File, client func() (*evm.Client, string, error), ch chains.Chain, dist, sig string) (int, claims.Source, error) {
+	if dir != "" {
+		for i := len(entries) - 1; i >= 0; i-- {
+			p := filepath.Join(dir, entries[i].Name.String())
+			if _, err := os.Stat(p); err != nil {
+				continue
+			}
+			s, err := claims.LoadFile(p)
+			if err != nil {
+				return 0, nil, fmt.Errorf("load claimed snapshot %s: %w", p, err)
+			}
+			return i, s, nil
+		}
+	}
+	abi, err := distributor.ForChain(ch)
+	if err == nil {
+		abi, err = abi.Override("getClaimedAmounts", sig)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	c, block, err := client()
+	if err != nil {
+		return 0, nil, err
+	}
+	contract := abi.At(dist, c)
+	root, err := contract.MerkleRoot(ctx, block)
+	if err != nil {
+		return 0, nil, fmt.Errorf("distributor %s: %w", dist, err)
+	}
+	i, err := reconcile.Current(files, root)
+	if err != nil {
+		return 0, nil, fmt.Errorf("distributor %s: %w", dist, err)
+	}
+	return i, &claims.ChainSource{Contract: contract, Block: block}, nil
+}
+
+// link formats an optional explorer URL as a suffix.
+func link(u string) string {
+	if u == "" {
+		return ""
+	}
+	return "  " + u
+}
+
+func sortedChains(m map[string]uint64) []string {
+	out := make([]string, 0, len(m))
+	for id := range m {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func parseCycles(s, root string) ([]int, error) {
+	if s == "" {
+		return cycle.Cycles(root)
+	}
+	out := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, errors.New("invalid --cycles value " + strconv.Quote(part))
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
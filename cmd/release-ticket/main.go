@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/tracker"
+)
+
+const ticketBucket = "tickets"
+
+// release-ticket tracks a cycle release in Linear or Jira:
+//
+//	release-ticket open  --cycle 21              # when a sync is triggered
+//	release-ticket close --cycle-dir cycle-21    # after publish
+//
+// The issue for each cycle is remembered in the state DB, so open is
+// idempotent and close finds the right issue.
+func main() {
+	defer tmpdir.Cleanup()
+	if len(os.Args) < 2 {
+		fatal(errors.New("usage: release-ticket open|close [flags]"))
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet("release-ticket "+cmd, flag.ExitOnError)
+	runsummary.Register(fs)
+	prog := tenant.AddFlags(fs)
+	var (
+		kind       = fs.String("tracker", "linear", "linear or jira")
+		statePath  = fs.String("state", state.DefaultPath, "state DB")
+		cycleNum   = fs.Int("cycle", 0, "cycle number (open)")
+		cycleDir   = fs.String("cycle-dir", "", "published cycle-N directory (close)")
+		doneState  = fs.String("done-state", "Done", "workflow state to move the issue to on close")
+		localeName = fs.String("locale", os.Getenv("FAIRFLOW_LOCALE"), "language of the close report: en, vi or zh (or env FAIRFLOW_LOCALE; default en)")
+		linearKey  = fs.String("linear-api-key", os.Getenv("LINEAR_API_KEY"), "Linear API key (or env LINEAR_API_KEY)")
+		linearTeam = fs.String("linear-team", os.Getenv("LINEAR_TEAM_ID"), "Linear team ID (or env LINEAR_TEAM_ID)")
+		jiraURL    = fs.String("jira-url", os.Getenv("JIRA_BASE_URL"), "Jira site URL (or env JIRA_BASE_URL)")
+		jiraEmail  = fs.String("jira-email", os.Getenv("JIRA_EMAIL"), "Jira account email (or env JIRA_EMAIL)")
+		jiraToken  = fs.String("jira-token", os.Getenv("JIRA_API_TOKEN"), "Jira API token (or env JIRA_API_TOKEN)")
+		jiraProj   = fs.String("jira-project", os.Getenv("JIRA_PROJECT"), "Jira project key (or env JIRA_PROJECT)")
+	)
+	_ = fs.Parse(os.Args[2:])
+	if _, err := prog.Apply(fs, "release-ticket"); err != nil {
+		fatal(err)
+	}
+
+	var t tracker.Tracker
+	switch *kind {
+	case "linear":
+		if *linearKey == "" || *linearTeam == "" {
+			fatal(errors.New("linear needs --linear-api-key and --linear-team"))
+		}
+		t = &tracker.Linear{APIKey: *linearKey, TeamID: *linearTeam}
+	case "jira":
+		if *jiraURL == "" || *jiraEmail == "" || *jiraToken == "" || *jiraProj == "" {
+			fatal(errors.New("jira needs --jira-url, --jira-email, --jira-token and --jira-project"))
+		}
+		t = &tracker.Jira{BaseURL: *jiraURL, Email: *jiraEmail, Token: *jiraToken, Project: *jiraProj}
+	default:
+		fatal(fmt.Errorf("unknown --tracker %q", *kind))
+	}
+	db, err := state.Open(*statePath)
+	if err != nil {
+		fatal(err)
+	}
+	ctx := context.Background()
+
+	switch cmd {
+	case "open":
+		if *cycleNum == 0 {
+			fatal(errors.New("missing --cycle"))
+		}
+		key := strconv.Itoa(*cycleNum)
+		var issue tracker.Issue
+		if ok, err := db.Get(ticketBucket, key, &issue); err != nil {
+			fatal(err)
+		} else if ok {
+			fmt.Printf("Cycle %d already tracked by %s %s\n", *cycleNum, issue.Key, issue.URL)
+			return
+		}
+		issue, err = t.Create(ctx, fmt.Sprintf("Fairflow cycle %d release", *cycleNum),
+			fmt.Sprintf("Sync, verify and publish reward cycle %d.", *cycleNum))
+		if err != nil {
+			fatal(err)
+		}
+		issue.Cycle = *cycleNum
+		if err := db.Put(ticketBucket, key, issue); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Opened %s %s\n", issue.Key, issue.URL)
+
+	case "close":
+		if *cycleDir == "" {
+			fatal(errors.New("missing --cycle-dir"))
+		}
+		s, err := summary.Build(*cycleDir)
+		if err != nil {
+			fatal(err)
+		}
+		var issue tracker.Issue
+		ok, err := db.Get(ticketBucket, strconv.Itoa(s.Cycle), &issue)
+		if err != nil {
+			fatal(err)
+		}
+		if !ok {
+			fatal(fmt.Errorf("no %s issue recorded for cycle %d (run release-ticket open first)", t.Name(), s.Cycle))
+		}
+		locale, err := summary.ParseLocale(*localeName)
+		if err != nil {
+			fatal(err)
+		}
+		report := fmt.Sprintf("%s\n\n%s:\n%s\n\n%s:\n%s",
+			locale.T("published", s.Cycle), locale.T("totals.units"), s.TotalsText(), locale.T("roots"), s.RootsText())
+		if err := t.Comment(ctx, issue, report); err != nil {
+			fatal(err)
+		}
+		if err := t.Transition(ctx, issue, *doneState); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Closed %s\n", issue.Key)
+
+	default:
+		fatal(fmt.Errorf("unknown subcommand %q (open or close)", cmd))
+	}
+	if err := db.Save(); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/allocation"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/snapshot"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// reproduce regenerates a merkle file from the raw points snapshot and the
+// allocation config, independently of upstream, and diffs it against the file
+// attached in Notion (as downloaded by notion-sync). Timestamps, metadata and
+// salt are taken from that file; everything else must be recomputed.
+//
+//	reproduce --against cycle-21/56_LM_21.json --snapshot points.csv --budget 0xtoken=1000000
+func main() {
+	defer tmpdir.Cleanup()
+	budgets := make(allocation.Budgets)
+	var (
+		against       = flag.String("against", "", "upstream merkle file to check (e.g. cycle-21/56_LM_21.json)")
+		snapshotSrc   = flag.String("snapshot", "", "snapshot service URL, CSV endpoint or local file with points per position")
+		format        = flag.String("snapshot-format", string(snapshot.FormatAuto), "snapshot format: auto, json or csv")
+		snapshotToken = flag.String("snapshot-token", os.Getenv("SNAPSHOT_TOKEN"), "bearer token for the snapshot service (or env SNAPSHOT_TOKEN)")
+		rewardType    = flag.String("type", "", "reward type (default from the --against file name)")
+		allocConfig   = flag.String("allocation-config", allocation.DefaultConfigPath, "per-type allocation strategies JSON")
+		strategy      = flag.String("allocation", "", "override the configured strategy (pro-rata, capped-pro-rata, tiered, fixed)")
+		outPath       = flag.String("out", "", "also write the regenerated merkle file here")
+		maxDiffs      = flag.Int("max-diffs", 20, "print at most this many differing positions")
+		parallelism   = flag.Int("parallelism", 0, "goroutines for hashing (0 = one per CPU)")
+	)
+	flag.Var(budgets, "budget", "token budget as <token>=<base units>; repeat per token")
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "reproduce"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	par.SetWorkers(*parallelism)
+
+	if *against == "" || *snapshotSrc == "" {
+		fatal(errors.New("missing --against or --snapshot"))
+	}
+	if len(budgets) == 0 {
+		fatal(errors.New("missing --budget"))
+	}
+	if *rewardType == "" {
+		n, ok := cycle.ParseName(filepath.Base(*against))
+		if !ok {
+			fatal(fmt.Errorf("cannot tell the reward type of %s; set --type", *against))
+		}
+		*rewardType = n.RewardType
+	}
+	spec, err := allocation.LoadSpec(*allocConfig, strings.ToUpper(*rewardType), *strategy)
+	if err != nil {
+		fatal(err)
+	}
+	upstream, err := cycle.Load(*against)
+	if err != nil {
+		fatal(err)
+	}
+	start, err := strconv.ParseInt(upstream.StartTimestamp, 10, 64)
+	if err != nil {
+		fatal(fmt.Errorf("%s: startTimestamp: %w", *against, err))
+	}
+	end, err := strconv.ParseInt(upstream.EndTimestamp, 10, 64)
+	if err != nil {
+		fatal(fmt.Errorf("%s: endTimestamp: %w", *against, err))
+	}
+
+	entries, err := snapshot.Load(context.Background(), *snapshotSrc, snapshot.Options{Format: snapshot.Format(*format), Token: *snapshotToken, Timeout: 2 * time.Minute})
+	if err != nil {
+		fatal(fmt.Errorf("load snapshot: %w", err))
+	}
+	entries = snapshot.Merge(entries)
+	if len(entries) == 0 {
+		fatal(errors.New("snapshot has no entries"))
+	}
+	res, err := allocation.Build(spec, allocation.Input{Entries: entries, Budgets: budgets, Start: start, End: end, Metadata: upstream.Metadata, Salt: upstream.Salt})
+	if err != nil {
+		fatal(err)
+	}
+	// Roots only compare when upstream's tree uses our leaf encoding; when it
+	// does not, the amounts still do.
+	encErr := cycle.CheckLeafEncoding(upstream)
+	if *outPath != "" {
+		if encErr != nil {
+			fatal(fmt.Errorf("not writing %s: %s: %w", *outPath, *against, encErr))
+		}
+		if err := cycle.Write(*outPath, res.File); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *outPath, err))
+		}
+	}
+
+	if encErr == nil && strings.EqualFold(res.File.Root, upstream.Root) {
+		fmt.Printf("Reproduced %s: %d recipients, root %s\n", *against, len(upstream.UserDatas), upstream.Root)
+		return
+	}
+	diffs, err := diffLeaves(upstream, res.File)
+	if err != nil {
+		fatal(err)
+	}
+	if encErr != nil {
+		fmt.Printf("UNVERIFIED %s: root not compared: %v\n", *against, encErr)
+	} else {
+		fmt.Printf("MISMATCH %s\n  upstream root     %s\n  regenerated root  %s\n", *against, upstream.Root, res.File.Root)
+	}
+	for _, t := range sortedKeys(upstream.TotalAmounts, res.File.TotalAmounts) {
+		if upstream.TotalAmounts[t] != res.File.TotalAmounts[t] {
+			fmt.Printf("  total %s: upstream %s, regenerated %s\n", t, orNone(upstream.TotalAmounts[t]), orNone(res.File.TotalAmounts[t]))
+		}
+	}
+	fmt.Printf("  %d positions differ\n", len(diffs))
+	for i, d := range diffs {
+		if i == *maxDiffs {
+			fmt.Printf("  ... %d more\n", len(diffs)-i)
+			break
+		}
+		fmt.Println("  " + d)
+	}
+	tmpdir.Exit(1)
+}
+
+// diffLeaves describes every position whose amounts differ, sorted by key.
+func diffLeaves(upstream, regen *cycle.File) ([]string, error) {
+	up, err := byKey(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("upstream: %w", err)
+	}
+	re, err := byKey(regen)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for _, k := range sortedKeys(up, re) {
+		u, r := up[k], re[k]
+		if u == r {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s: upstream %s, regenerated %s", k, orNone(u), orNone(r)))
+	}
+	return out, nil
+}
+
+// byKey maps position key to its amounts rendered as token=amount pairs.
+func byKey(f *cycle.File) (map[string]string, error) {
+	out := make(map[string]string, len(f.UserDatas))
+	for _, ud := range f.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]string, 0, len(am))
+		for t, a := range am {
+			parts = append(parts, t+"="+a.String())
+		}
+		sort.Strings(parts)
+		out[ud.Leaf.Key()] = strings.Join(parts, ",")
+	}
+	return out, nil
+}
+
+func sortedKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	out := make([]string, 0, len(seen))
+	for k := range seen {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
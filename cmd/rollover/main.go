@@ -0,0 +1,299 @@
+// rollover merges cycle N into cycle N+1.
+//
+// The distributor pays a leaf's amount minus what the position has claimed
+// so far, cumulatively across cycles, against its single root. So a merged
+// leaf holds the position's whole cycle N amount plus its cycle N+1 rewards,
+// claimed part included: the distributor subtracts the claims itself, and
+// what stays payable is the carried-over remainder plus the new rewards.
+// Cycle N must be the file for the distributor's current root, the one the
+// claims were made against.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+type carryOver struct {
+	Key     string            `json:"key"`
+	Leaf    cycle.Leaf        `json:"leaf"`
+	Claimed map[string]string `json:"claimed"`
+}
+
+type tokenRecon struct {
+	Prev    *big.Int
+	Claimed *big.Int
+	Carried *big.Int
+	Next    *big.Int
+	Merged  *big.Int
+	Payable *big.Int
+}
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		prevPath      = flag.String("prev", "", "cycle N merkle file, the one for the distributor's current root")
+		nextPath      = flag.String("next", "", "cycle N+1 merkle file of that cycle's rewards alone, to merge cycle N into")
+		outPath       = flag.String("out", "", "merged cycle N+1 merkle file to write")
+		carryPath     = flag.String("carry-out", "", "optional JSON file for the carry-over recipient list")
+		reportPath    = flag.String("report", "", "reconciliation report path (default stdout)")
+		claimedFile   = flag.String("claimed-file", "", "claimed amounts JSON exported from the distributor")
+		rpcURL        = flag.String("rpc", "", "RPC URL to read claimed amounts on-chain")
+		distAddr      = flag.String("distributor", "", "distributor contract address")
+		abiName       = flag.String("abi", distributor.UnverifiedABI, "distributor ABI: a name in the distributor package's bundle, a JSON file, or unverified for the bound calls alone")
+		claimedSig    = flag.String("claimed-sig", "", "distributor view returning claimed amounts (default: the ABI's getClaimedAmounts)")
+		block         = flag.String("block", evm.DefaultBlockTag, "block tag for on-chain reads: finalized, safe, latest or a number")
+		confirmations = flag.Uint64("confirmations", 0, "read this many blocks behind --block")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "rollover"); err != nil {
+		fatal(err)
+	}
+
+	if *prevPath == "" || *nextPath == "" || *outPath == "" {
+		fatal(errors.New("missing --prev, --next or --out"))
+	}
+	ctx := context.Background()
+	var src claims.Source
+	var claimedAt, onChainRoot string
+	switch {
+	case *claimedFile != "":
+		fs, err := claims.LoadFile(*claimedFile)
+		if err != nil {
+			fatal(fmt.Errorf("read claimed file: %w", err))
+		}
+		src, claimedAt = fs, *claimedFile
+	case *rpcURL != "" && *distAddr != "":
+		abi, err := distributor.Load(*abiName)
+		if err == nil {
+			abi, err = abi.Override("getClaimedAmounts", *claimedSig)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		c := evm.NewClient(*rpcURL)
+		n, err := c.PinBlock(ctx, *block, *confirmations)
+		if err != nil {
+			fatal(err)
+		}
+		contract := abi.At(*distAddr, c)
+		root, err := contract.MerkleRoot(ctx, evm.BlockParam(n))
+		if err != nil {
+			fatal(err)
+		}
+		onChainRoot = root
+		src = &claims.ChainSource{Contract: contract, Block: evm.BlockParam(n)}
+		claimedAt = fmt.Sprintf("block %d (%s)", n, *block)
+	default:
+		fatal(errors.New("missing --claimed-file or --rpc/--distributor"))
+	}
+
+	prev, err := cycle.Load(*prevPath)
+	if err != nil {
+		fatal(fmt.Errorf("load %s: %w", *prevPath, err))
+	}
+	if onChainRoot != "" && !strings.EqualFold(onChainRoot, prev.Root) {
+		fatal(fmt.Errorf("%s has root %s but the distributor pays out against %s; its claims do not apply to this file", *prevPath, prev.Root, onChainRoot))
+	}
+	next, err := cycle.Load(*nextPath)
+	if err != nil {
+		fatal(fmt.Errorf("load %s: %w", *nextPath, err))
+	}
+	if err := cycle.CheckLeafEncoding(next); err != nil {
+		fatal(fmt.Errorf("%s: %w", *nextPath, err))
+	}
+
+	recon := make(map[string]*tokenRecon)
+	tok := func(t string) *tokenRecon {
+		r, ok := recon[t]
+		if !ok {
+			r = &tokenRecon{Prev: new(big.Int), Claimed: new(big.Int), Carried: new(big.Int), Next: new(big.Int), Merged: new(big.Int), Payable: new(big.Int)}
+			recon[t] = r
+		}
+		return r
+	}
+
+	carry := make([]carryOver, 0)
+	prevByKey := make(map[string]map[string]*big.Int, len(prev.UserDatas))
+	prevLeaves := make([]cycle.Leaf, 0, len(prev.UserDatas))
+	for _, ud := range prev.UserDatas {
+		amounts, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			fatal(err)
+		}
+		claimed, err := src.Claimed(ctx, ud.Leaf)
+		if err != nil {
+			fatal(err)
+		}
+		rest, err := claims.Unclaimed(ud.Leaf, claimed)
+		if err != nil {
+			fatal(err)
+		}
+		for t, a := range amounts {
+			r := tok(t)
+			r.Prev.Add(r.Prev, a)
+			if c, ok := claimed[t]; ok {
+				r.Claimed.Add(r.Claimed, c)
+			}
+		}
+		key := ud.Leaf.Key()
+		if _, ok := prevByKey[key]; ok {
+			fatal(fmt.Errorf("%s: position %s appears twice", *prevPath, key))
+		}
+		prevByKey[key] = amounts
+		prevLeaves = append(prevLeaves, ud.Leaf)
+		if len(rest) == 0 {
+			continue
+		}
+		cl := make(map[string]string, len(claimed))
+		for t, c := range claimed {
+			cl[t] = c.String()
+		}
+		carry = append(carry, carryOver{Key: key, Leaf: cycle.NewLeaf(ud.Leaf.ERC721Addr, ud.Leaf.ERC721ID, rest), Claimed: cl})
+		for t, a := range rest {
+			r := tok(t)
+			r.Carried.Add(r.Carried, a)
+		}
+	}
+
+	nextTotals, err := next.SumAmounts()
+	if err != nil {
+		fatal(err)
+	}
+	for t, a := range nextTotals {
+		tok(t).Next.Set(a)
+	}
+
+	// Every cycle N position keeps its whole amount, fully claimed ones
+	// included, or its next leaf would fall below what it has claimed.
+	merged := *next
+	merged.UserDatas = make([]cycle.UserData, 0, len(next.UserDatas)+len(prevLeaves))
+	mergedInto := 0
+	for _, ud := range next.UserDatas {
+		key := ud.Leaf.Key()
+		prevAmounts, ok := prevByKey[key]
+		if !ok {
+			merged.UserDatas = append(merged.UserDatas, cycle.UserData{Leaf: ud.Leaf})
+			continue
+		}
+		amounts, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			fatal(err)
+		}
+		for t, a := range prevAmounts {
+			if cur, ok := amounts[t]; ok {
+				amounts[t] = new(big.Int).Add(cur, a)
+			} else {
+				amounts[t] = a
+			}
+		}
+		merged.UserDatas = append(merged.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(ud.Leaf.ERC721Addr, ud.Leaf.ERC721ID, amounts)})
+		delete(prevByKey, key)
+		mergedInto++
+	}
+	added := 0
+	for _, l := range prevLeaves {
+		if _, ok := prevByKey[l.Key()]; !ok {
+			continue
+		}
+		merged.UserDatas = append(merged.UserDatas, cycle.UserData{Leaf: l})
+		added++
+	}
+
+	if err := cycle.Rebuild(&merged); err != nil {
+		fatal(fmt.Errorf("rebuild merged tree: %w", err))
+	}
+	mergedTotals, err := merged.SumAmounts()
+	if err != nil {
+		fatal(err)
+	}
+	for t, a := range mergedTotals {
+		r := tok(t)
+		r.Merged.Set(a)
+		r.Payable.Sub(a, r.Claimed)
+	}
+
+	if err := cycle.Write(*outPath, &merged); err != nil {
+		fatal(fmt.Errorf("write merged file: %w", err))
+	}
+	if *carryPath != "" {
+		b, err := json.MarshalIndent(carry, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(*carryPath, b, 0o644); err != nil {
+			fatal(fmt.Errorf("write carry-over list: %w", err))
+		}
+		runsummary.Output(*carryPath)
+	}
+
+	var w io.Writer = os.Stdout
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		runsummary.Output(*reportPath)
+		w = f
+	}
+	ok := writeReport(w, recon, claimedAt, len(carry), mergedInto, added, &merged)
+	if !ok {
+		fatal(errors.New("reconciliation mismatch: merged totals differ from cycle N + cycle N+1, or what stays payable from cycle N+1 + carried"))
+	}
+}
+
+func writeReport(w io.Writer, recon map[string]*tokenRecon, claimedAt string, carried, mergedInto, added int, merged *cycle.File) bool {
+	fmt.Fprintf(w, "Rollover reconciliation\n")
+	fmt.Fprintf(w, "  claims read from: %s\n", claimedAt)
+	fmt.Fprintf(w, "  positions with an unclaimed remainder: %d\n", carried)
+	fmt.Fprintf(w, "  cycle N positions: merged into cycle N+1: %d, kept as is: %d\n", mergedInto, added)
+	fmt.Fprintf(w, "  merged recipients: %d, root: %s\n\n", len(merged.UserDatas), merged.Root)
+
+	tokens := make([]string, 0, len(recon))
+	for t := range recon {
+		tokens = append(tokens, t)
+	}
+	sort.Strings(tokens)
+
+	ok := true
+	for _, t := range tokens {
+		r := recon[t]
+		status := "OK"
+		if new(big.Int).Add(r.Prev, r.Next).Cmp(r.Merged) != 0 || new(big.Int).Add(r.Next, r.Carried).Cmp(r.Payable) != 0 {
+			status = "MISMATCH"
+			ok = false
+		}
+		fmt.Fprintf(w, "%s [%s]\n", t, status)
+		fmt.Fprintf(w, "  cycle N total:     %s\n", r.Prev)
+		fmt.Fprintf(w, "  claimed:           %s\n", r.Claimed)
+		fmt.Fprintf(w, "  carried over:      %s\n", r.Carried)
+		fmt.Fprintf(w, "  cycle N+1 total:   %s\n", r.Next)
+		fmt.Fprintf(w, "  merged total:      %s\n", r.Merged)
+		fmt.Fprintf(w, "  payable after:     %s\n", r.Payable)
+	}
+	return ok
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// rpc diagnoses the RPC endpoints in the chain registry.
+//
+//	rpc status [--chain 56] [--samples 3] [--max-lag 20]
+//
+// status probes every endpoint separately, checks it serves the right chain
+// and is near the head, and prints them best first in the order the evm
+// client would try them. It exits 1 if a chain has no healthy endpoint.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		chainsPath  = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		chainFilter = flag.String("chain", "", "only check this chain ID")
+		samples     = flag.Int("samples", 3, "eth_blockNumber calls per endpoint for latency")
+		maxLag      = flag.Uint64("max-lag", 20, "blocks behind the best endpoint before one counts as stale")
+		timeout     = flag.Duration("timeout", 10*time.Second, "per-endpoint probe timeout")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	if len(os.Args) < 2 || os.Args[1] != "status" {
+		fatal(errors.New("usage: rpc status [--chain ID] [--samples N] [--max-lag N]"))
+	}
+	flag.CommandLine.Parse(os.Args[2:])
+	if _, err := prog.Apply(flag.CommandLine, "rpc"); err != nil {
+		fatal(err)
+	}
+
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+	unhealthy := 0
+	for _, id := range reg.IDs() {
+		if *chainFilter != "" && id != *chainFilter {
+			continue
+		}
+		ch := reg[id]
+		fmt.Printf("chain %s (%s)\n", id, ch.Name)
+		probes := make([]probe, 0)
+		var head uint64
+		for _, u := range ch.Endpoints() {
+			p := probeEndpoint(u, id, *samples, *timeout)
+			head = max(head, p.block)
+			probes = append(probes, p)
+		}
+		sort.SliceStable(probes, func(i, j int) bool {
+			if (probes[i].err == nil) != (probes[j].err == nil) {
+				return probes[i].err == nil
+			}
+			return probes[i].health.Score < probes[j].health.Score
+		})
+		healthy := 0
+		for _, p := range probes {
+			status := "OK"
+			switch {
+			case p.err != nil:
+				status = "FAIL"
+			case head-p.block > *maxLag:
+				status = "STALE"
+			default:
+				healthy++
+			}
+			h := p.health
+			if p.err != nil {
+				fmt.Printf("  %-5s %s  %v\n", status, h.URL, p.err)
+				continue
+			}
+			fmt.Printf("  %-5s %s  block %d (lag %d)  %s  %d/%d errors  score %.0f\n", status, h.URL, p.block, head-p.block, h.Latency.Round(time.Millisecond), h.Errors, h.Calls, h.Score)
+		}
+		if len(probes) == 0 {
+			fmt.Println("  no rpc configured")
+		}
+		if healthy == 0 {
+			unhealthy++
+		}
+	}
+	if unhealthy > 0 {
+		fatal(fmt.Errorf("%d chain(s) have no healthy rpc endpoint", unhealthy))
+	}
+}
+
+type probe struct {
+	block  uint64
+	health evm.Health
+	err    error
+}
+
+func probeEndpoint(url, chainID string, samples int, timeout time.Duration) probe {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := evm.NewClient(url)
+	var p probe
+	id, err := c.ChainID(ctx)
+	switch {
+	case err != nil:
+		p.err = err
+	case id.String() != chainID:
+		p.err = fmt.Errorf("serves chain %s", id)
+	}
+	for i := 0; i < samples && p.err == nil; i++ {
+		p.block, p.err = c.BlockNumber(ctx)
+	}
+	p.health = c.Health()[0]
+	return p
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,23 @@
+//go:build audit
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+)
+
+// ops is empty in the audit build: /presign, webhooks, --watch and the
+// retry queue are not compiled in, so the binary handed to outside auditors
+// can only read the repo and the chains. Build it with
+//
+//	go build -tags audit -o audit-serve ./cmd/serve
+type ops struct{}
+
+func addOps(*flag.FlagSet) *ops { return &ops{} }
+
+func (o *ops) start(s *server, mux *http.ServeMux) error {
+	log.Printf("audit build: read and verify endpoints only")
+	return nil
+}
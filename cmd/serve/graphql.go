@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/graphql"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+)
+
+// The schema served at /graphql:
+//
+//	type Query {
+//	  cycles: [Cycle]
+//	  chainTotals(cycle: Int): [ChainTotal]               # default: latest cycle
+//	  rewards(address: String!, cycle: Int): [Reward]     # position key or NFT contract
+//	  claimStatus(position: String!, cycle: Int): [ClaimStatus]
+//	}
+//	type Cycle { cycle: Int, files: [File], chainTotals: [ChainTotal] }
+//	type File { name: String, chainId: String, type: String, root: String, recipients: Int, totals: [TokenAmount] }
+//	type ChainTotal { chainId: String, token: String, amount: String }
+//	type TokenAmount { token: String, amount: String }
+//	type Reward { cycle: Int, file: String, chainId: String, type: String, position: String,
+//	              amounts: [TokenAmount], root: String, proof: [String] }
+//	type ClaimStatus { cycle: Int, file: String, chainId: String, type: String, position: String,
+//	                   tokens: [TokenClaim] }
+//	type TokenClaim { token: String, amount: String, claimed: String, unclaimed: String }
+//
+// Reward.root and proof are what the distributor checks: for a two-level
+// sharded file, the master root and the stitched proof.
+
+type gqlCycle struct {
+	Cycle       int             `json:"cycle"`
+	Files       []gqlFile       `json:"files"`
+	ChainTotals []gqlChainTotal `json:"chainTotals"`
+}
+
+type gqlFile struct {
+	Name       string           `json:"name"`
+	ChainID    string           `json:"chainId"`
+	RewardType string           `json:"type"`
+	Root       string           `json:"root"`
+	Recipients int              `json:"recipients"`
+	Totals     []gqlTokenAmount `json:"totals"`
+}
+
+type gqlChainTotal struct {
+	ChainID string `json:"chainId"`
+	Token   string `json:"token"`
+	Amount  string `json:"amount"`
+}
+
+type gqlTokenAmount struct {
+	Token  string `json:"token"`
+	Amount string `json:"amount"`
+}
+
+type gqlReward struct {
+	Cycle      int              `json:"cycle"`
+	File       string           `json:"file"`
+	ChainID    string           `json:"chainId"`
+	RewardType string           `json:"type"`
+	Position   string           `json:"position"`
+	Amounts    []gqlTokenAmount `json:"amounts"`
+
+	dir  string
+	name cycle.Name
+}
+
+type gqlClaimStatus struct {
+	Cycle      int             `json:"cycle"`
+	File       string          `json:"file"`
+	ChainID    string          `json:"chainId"`
+	RewardType string          `json:"type"`
+	Position   string          `json:"position"`
+	Tokens     []gqlTokenClaim `json:"tokens"`
+}
+
+type gqlTokenClaim struct {
+	Token     string `json:"token"`
+	Amount    string `json:"amount"`
+	Claimed   string `json:"claimed"`
+	Unclaimed string `json:"unclaimed"`
+}
+
+// claimReader answers claimStatus and the claim-rate watch from
+// --claimed-dir snapshots or, failing those, the distributors in the chain
+// registry.
+type claimReader struct {
+	chainsPath string
+	claimedDir string
+	sig        string
+
+	mu      sync.Mutex
+	reg     chains.Registry
+	clients map[string]*evm.Client
+}
+
+func (s *server) schema() *graphql.Schema {
+	tokenAmount := &graphql.Object{Name: "TokenAmount", Fields: map[string]*graphql.Field{"token": {}, "amount": {}}}
+	chainTotal := &graphql.Object{Name: "ChainTotal", Fields: map[string]*graphql.Field{"chainId": {}, "token": {}, "amount": {}}}
+	file := &graphql.Object{Name: "File", Fields: map[string]*graphql.Field{
+		"name": {}, "chainId": {}, "type": {}, "root": {}, "recipients": {},
+		"totals": {Type: tokenAmount},
+	}}
+	cycleType := &graphql.Object{Name: "Cycle", Fields: map[string]*graphql.Field{
+		"cycle":       {},
+		"files":       {Type: file},
+		"chainTotals": {Type: chainTotal},
+	}}
+	reward := &graphql.Object{Name: "Reward", Fields: map[string]*graphql.Field{
+		"cycle": {}, "file": {}, "chainId": {}, "type": {}, "position": {},
+		"amounts": {Type: tokenAmount},
+		"root":    {Resolve: func(p graphql.Params) (any, error) { _, root, err := p.Source.(gqlReward).proof(); return root, err }},
+		"proof":   {Resolve: func(p graphql.Params) (any, error) { proof, _, err := p.Source.(gqlReward).proof(); return proof, err }},
+	}}
+	tokenClaim := &graphql.Object{Name: "TokenClaim", Fields: map[string]*graphql.Field{"token": {}, "amount": {}, "claimed": {}, "unclaimed": {}}}
+	claimStatus := &graphql.Object{Name: "ClaimStatus", Fields: map[string]*graphql.Field{
+		"cycle": {}, "file": {}, "chainId": {}, "type": {}, "position": {},
+		"tokens": {Type: tokenClaim},
+	}}
+	return &graphql.Schema{Query: &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"cycles": {Type: cycleType, Resolve: func(p graphql.Params) (any, error) { return s.gqlCycles() }},
+		"chainTotals": {Type: chainTotal, Resolve: func(p graphql.Params) (any, error) {
+			c, err := s.cycleArg(p)
+			if err != nil {
+				return nil, err
+			}
+			sum, err := summary.Build(filepath.Join(s.root, cycle.DirName(c)))
+			if err != nil {
+				return nil, err
+			}
+			return chainTotals(sum), nil
+		}},
+		"rewards": {Type: reward, Resolve: func(p graphql.Params) (any, error) {
+			addr, err := p.String("address")
+			if err != nil {
+				return nil, err
+			}
+			c, ok, err := p.Int("cycle")
+			if err != nil {
+				return nil, err
+			}
+			cycles := []int{c}
+			if !ok {
+				if cycles, err = cycle.Cycles(s.root); err != nil {
+					return nil, err
+				}
+			}
+			return s.rewards(addr, cycles)
+		}},
+		"claimStatus": {Type: claimStatus, Resolve: func(p graphql.Params) (any, error) {
+			pos, err := p.String("position")
+			if err != nil {
+				return nil, err
+			}
+			if !strings.Contains(pos, ":") {
+				return nil, fmt.Errorf("position %q is not <erc721Addr>:<id>", pos)
+			}
+			c, err := s.cycleArg(p)
+			if err != nil {
+				return nil, err
+			}
+			rs, err := s.rewards(pos, []int{c})
+			if err != nil {
+				return nil, err
+			}
+			out := make([]gqlClaimStatus, 0, len(rs))
+			for _, r := range rs {
+				st, err := s.claims.status(p.Context, r)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, st)
+			}
+			return out, nil
+		}},
+	}}}
+}
+
+// handleGraphQL serves POST {"query", "operationName", "variables"} and
+// GET ?query=.
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				httpError(w, http.StatusBadRequest, fmt.Errorf("variables: %w", err))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		httpError(w, http.StatusMethodNotAllowed, errors.New("use GET or POST"))
+		return
+	}
+	if req.Query == "" {
+		httpError(w, http.StatusBadRequest, errors.New("missing query"))
+		return
+	}
+	writeJSON(w, s.gql.Do(r.Context(), req))
+}
+
+// cycleArg returns the "cycle" argument, defaulting to the latest cycle.
+func (s *server) cycleArg(p graphql.Params) (int, error) {
+	c, ok, err := p.Int("cycle")
+	if err != nil || ok {
+		return c, err
+	}
+	cycles, err := cycle.Cycles(s.root)
+	if err != nil {
+		return 0, err
+	}
+	if len(cycles) == 0 {
+		return 0, fmt.Errorf("no cycle directories under %s", s.root)
+	}
+	return cycles[len(cycles)-1], nil
+}
+
+func (s *server) gqlCycles() ([]gqlCycle, error) {
+	cycles, err := cycle.Cycles(s.root)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]gqlCycle, 0, len(cycles))
+	for _, n := range cycles {
+		dir := filepath.Join(s.root, cycle.DirName(n))
+		entries, err := cycle.ScanDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			// Still embargoed, or not built yet.
+			continue
+		}
+		sum, err := summary.Build(dir)
+		if err != nil {
+			return nil, err
+		}
+		c := gqlCycle{Cycle: n, ChainTotals: chainTotals(sum)}
+		for _, f := range sum.Files {
+			c.Files = append(c.Files, gqlFile{
+				Name:       f.Name,
+				ChainID:    f.ChainID,
+				RewardType: f.RewardType,
+				Root:       f.Root,
+				Recipients: f.Recipients,
+				Totals:     tokenAmounts(f.Totals),
+			})
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// rewards returns the leaves of addr in the given cycles: the one position
+// when addr is "<erc721Addr>:<id>", else every position of the NFT contract
+// addr. A position lookup skips files whose Bloom sidecar rules it out.
+func (s *server) rewards(addr string, cycles []int) ([]gqlReward, error) {
+	addr = strings.ToLower(addr)
+	nft, _, isPosition := strings.Cut(addr, ":")
+	if !evm.IsAddress(nft) {
+		return nil, fmt.Errorf("%q is neither an address nor a position", addr)
+	}
+	out := make([]gqlReward, 0)
+	for _, c := range cycles {
+		dir := filepath.Join(s.root, cycle.DirName(c))
+		entries, err := cycle.ScanDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if isPosition {
+				b, err := cycle.LoadBloom(dir, e.Name)
+				if err != nil {
+					return nil, err
+				}
+				if b != nil && !b.Has(addr) {
+					continue
+				}
+			}
+			f, err := cycle.Load(e.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.String(), err)
+			}
+			for _, ud := range f.UserDatas {
+				key := ud.Leaf.Key()
+				if (isPosition && key != addr) || (!isPosition && strings.ToLower(ud.Leaf.ERC721Addr) != addr) {
+					continue
+				}
+				am, err := ud.Leaf.AmountsByToken()
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", e.String(), err)
+				}
+				r := gqlReward{Cycle: c, File: e.String(), ChainID: e.ChainID, RewardType: e.RewardType, Position: key, dir: dir, name: e.Name}
+				for _, t := range sortedKeys(am) {
+					r.Amounts = append(r.Amounts, gqlTokenAmount{Token: t, Amount: am[t].String()})
+				}
+				out = append(out, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r gqlReward) proof() ([]string, string, error) {
+	ud, root, err := cycle.FindProof(r.dir, r.name, r.Position)
+	return ud.Proof, root, err
+}
+
+func (cr *claimReader) status(ctx context.Context, r gqlReward) (gqlClaimStatus, error) {
+	st := gqlClaimStatus{Cycle: r.Cycle, File: r.File, ChainID: r.ChainID, RewardType: r.RewardType, Position: r.Position}
+	ud, _, err := cycle.FindProof(r.dir, r.name, r.Position)
+	if err != nil {
+		return st, err
+	}
+	src, err := cr.source(r.name)
+	if err != nil {
+		return st, err
+	}
+	claimed, err := src.Claimed(ctx, ud.Leaf)
+	if err != nil {
+		return st, err
+	}
+	amounts, err := ud.Leaf.AmountsByToken()
+	if err != nil {
+		return st, err
+	}
+	rest, err := claims.Unclaimed(ud.Leaf, claimed)
+	if err != nil {
+		return st, err
+	}
+	for _, t := range sortedKeys(amounts) {
+		tc := gqlTokenClaim{Token: t, Amount: amounts[t].String(), Claimed: "0", Unclaimed: "0"}
+		if c := claimed[t]; c != nil {
+			tc.Claimed = c.String()
+		}
+		if u := rest[t]; u != nil {
+			tc.Unclaimed = u.String()
+		}
+		st.Tokens = append(st.Tokens, tc)
+	}
+	return st, nil
+}
+
+func (cr *claimReader) source(n cycle.Name) (claims.Source, error) {
+	if cr.claimedDir != "" {
+		p := filepath.Join(cr.claimedDir, n.String())
+		if _, err := os.Stat(p); err == nil {
+			s, err := claims.LoadFile(p)
+			if err != nil {
+				return nil, fmt.Errorf("load claimed snapshot %s: %w", p, err)
+			}
+			return s, nil
+		}
+	}
+	reg, err := cr.registry()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := reg.Get(n.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	dist, ok := ch.Distributor(n.RewardType)
+	if !ok {
+		return nil, fmt.Errorf("no distributor configured for chain %s type %s", n.ChainID, n.RewardType)
+	}
+	abi, err := distributor.ForChain(ch)
+	if err == nil {
+		abi, err = abi.Override("getClaimedAmounts", cr.sig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := cr.client(n.ChainID, ch)
+	if err != nil {
+		return nil, err
+	}
+	return &claims.ChainSource{Contract: abi.At(dist, c), Block: evm.DefaultBlockTag}, nil
+}
+
+// registry loads the chain registry on first use.
+func (cr *claimReader) registry() (chains.Registry, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.reg == nil {
+		reg, err := chains.Load(cr.chainsPath)
+		if err != nil {
+			return nil, fmt.Errorf("load chain registry: %w", err)
+		}
+		cr.reg, cr.clients = reg, make(map[string]*evm.Client)
+	}
+	return cr.reg, nil
+}
+
+// client returns the shared RPC client of chain ch.
+func (cr *claimReader) client(chainID string, ch chains.Chain) (*evm.Client, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if c, ok := cr.clients[chainID]; ok {
+		return c, nil
+	}
+	if len(ch.Endpoints()) == 0 {
+		return nil, fmt.Errorf("chain %s has no rpc configured", chainID)
+	}
+	c := evm.NewClient(ch.Endpoints()...)
+	cr.clients[chainID] = c
+	return c, nil
+}
+
+func chainTotals(sum *summary.Cycle) []gqlChainTotal {
+	out := make([]gqlChainTotal, 0)
+	for _, c := range sum.Chains() {
+		for _, t := range tokenAmounts(sum.ChainTotals[c]) {
+			out = append(out, gqlChainTotal{ChainID: c, Token: t.Token, Amount: t.Amount})
+		}
+	}
+	return out
+}
+
+func tokenAmounts(m map[string]string) []gqlTokenAmount {
+	out := make([]gqlTokenAmount, 0, len(m))
+	for _, t := range sortedKeys(m) {
+		out = append(out, gqlTokenAmount{Token: t, Amount: m[t]})
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/graphql"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/programs"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// serve is the long-running HTTP mode: it lists the cycles in the repo,
+// answers claim proofs and Bloom eligibility checks, pages through
+// recipients, serves the same data over GraphQL at /graphql and, when --dest
+// is set, mints pre-signed URLs for them. With --watch it also notifies
+// registered webhooks of new cycles, on-chain root changes and claim-rate
+// thresholds. With --schedule, cycles and roots are only polled during the
+// --release-window after each scheduled release, and a cycle the schedule
+// expects that has not appeared when its window closes fires cycle.overdue.
+// Claim rates are summed every --claim-check, or on a chain with a "ws"
+// endpoint as soon as transfers out of its distributors arrive over
+// eth_subscribe. Every poll also verifies the --canaries' proofs in the latest cycle
+// on-chain, firing canary.failed when one stops verifying.
+// Webhook deliveries that fail are queued in the state DB and
+// retried every --retry-queue, along with anything already queued there.
+// With --values it answers /programs.json, the claim UI's list of the
+// programs that values.yaml serves. GET /verify?cycle=N re-runs the verify
+// rules over a cycle's files and GET /onchain?cycle=N compares their roots
+// and totals with what the distributors store and hold.
+//
+// The audit build is the same server without /presign, webhooks, --watch or
+// the retry queue compiled in, for handing to outside auditors:
+//
+//	go build -tags audit -o audit-serve ./cmd/serve
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		addr       = flag.String("addr", ":8080", "listen address")
+		root       = flag.String("root", ".", "repo root containing cycle-N directories")
+		valuesPath = flag.String("values", "", "reward-service values.yaml to list active programs from at /programs.json")
+		chainsPath = flag.String("chains", chains.DefaultPath, "chain registry JSON, for GraphQL claimStatus and /onchain")
+		claimedDir = flag.String("claimed-dir", "", "directory of <chain>_<type>_<cycle>.json claimed snapshots used instead of on-chain reads")
+		claimedSig = flag.String("claimed-sig", "", "distributor view returning claimed amounts (default: the chain's distributor ABI's getClaimedAmounts)")
+		rootSig    = flag.String("root-sig", "", "distributor view returning its merkle root, for /onchain and root.changed (default: the chain's distributor ABI's merkleRoot; off = no root.changed)")
+	)
+	o := addOps(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "serve"); err != nil {
+		fatal(err)
+	}
+
+	s := &server{
+		root:    *root,
+		values:  *valuesPath,
+		rootSig: *rootSig,
+		claims:  &claimReader{chainsPath: *chainsPath, claimedDir: *claimedDir, sig: *claimedSig},
+	}
+	s.gql = s.schema()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, "ok") })
+	mux.HandleFunc("/cycles", s.handleCycles)
+	mux.HandleFunc("/programs.json", s.handlePrograms)
+	mux.HandleFunc("/proof", s.handleProof)
+	mux.HandleFunc("/bloom", s.handleBloom)
+	mux.HandleFunc("/graphql", s.handleGraphQL)
+	mux.HandleFunc("GET /v1/{chain}/{type}/{cycle}/recipients", s.handleRecipients)
+	mux.HandleFunc("GET /verify", s.handleVerify)
+	mux.HandleFunc("GET /onchain", s.handleOnchain)
+	if err := o.start(s, mux); err != nil {
+		fatal(err)
+	}
+	log.Printf("listening on %s", *addr)
+	fatal(http.ListenAndServe(*addr, mux))
+}
+
+type server struct {
+	root    string
+	values  string
+	rootSig string
+	gql     *graphql.Schema
+	claims  *claimReader
+
+	recipients recipientIndexes
+}
+
+type cycleInfo struct {
+	Cycle int      `json:"cycle"`
+	Files []string `json:"files"`
+}
+
+func (s *server) handleCycles(w http.ResponseWriter, r *http.Request) {
+	cycles, err := cycle.Cycles(s.root)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]cycleInfo, 0, len(cycles))
+	for _, n := range cycles {
+		entries, err := cycle.ScanDir(filepath.Join(s.root, cycle.DirName(n)))
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+		ci := cycleInfo{Cycle: n, Files: make([]string, 0, len(entries))}
+		for _, e := range entries {
+			ci.Files = append(ci.Files, e.String())
+		}
+		out = append(out, ci)
+	}
+	writeJSON(w, out)
+}
+
+// handlePrograms builds programs.json from --values on every request, so it
+// follows update-kyber-applications without a restart.
+func (s *server) handlePrograms(w http.ResponseWriter, r *http.Request) {
+	if s.values == "" {
+		httpError(w, http.StatusNotFound, errors.New("no values.yaml configured"))
+		return
+	}
+	vb, err := os.ReadFile(s.values)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	doc, err := programs.Build(vb, s.root)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, doc)
+}
+
+// merkleFile resolves the cycle and file query parameters to a merkle file
+// on disk, writing the error response itself when it cannot.
+func (s *server) merkleFile(w http.ResponseWriter, r *http.Request) (dir string, n cycle.Name, ok bool) {
+	q := r.URL.Query()
+	c, err := strconv.Atoi(q.Get("cycle"))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("invalid cycle %q", q.Get("cycle")))
+		return "", cycle.Name{}, false
+	}
+	n, ok = cycle.ParseName(q.Get("file"))
+	if !ok || n.Cycle != c {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("%q is not a merkle file of cycle %d", q.Get("file"), c))
+		return "", cycle.Name{}, false
+	}
+	dir = filepath.Join(s.root, cycle.DirName(c))
+	if _, err := os.Stat(filepath.Join(dir, n.String())); err != nil {
+		httpError(w, http.StatusNotFound, fmt.Errorf("no %s in cycle %d", n, c))
+		return "", cycle.Name{}, false
+	}
+	return dir, n, true
+}
+
+// handleProof answers GET /proof?cycle=21&file=56_LM_21.json&position=addr:id
+// with the position's leaf and a proof against the root the distributor
+// stores; for a two-level sharded file that is the master root. A Bloom
+// sidecar miss answers "not eligible" without reading the file. With
+// &account=addr instead of a position it answers from the file's
+// merkle-distributor claims sidecar, or from the file itself when it is in
+// that format. With &calldata=1 it adds the claim call to send to the
+// distributor, encoded with the chain's distributor ABI.
+func (s *server) handleProof(w http.ResponseWriter, r *http.Request) {
+	dir, n, ok := s.merkleFile(w, r)
+	if !ok {
+		return
+	}
+	if acct := r.URL.Query().Get("account"); acct != "" {
+		s.uniswapProof(w, dir, n, acct)
+		return
+	}
+	pos := r.URL.Query().Get("position")
+	b, err := cycle.LoadBloom(dir, n)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if b != nil && !b.Has(pos) {
+		httpError(w, http.StatusNotFound, fmt.Errorf("position %s is not in %s", pos, n))
+		return
+	}
+	ud, root, err := cycle.FindProof(dir, n, pos)
+	if err != nil {
+		httpError(w, http.StatusNotFound, err)
+		return
+	}
+	out := map[string]any{"root": root, "leaf": ud.Leaf, "proof": ud.Proof}
+	if r.URL.Query().Get("calldata") != "" {
+		dist, data, err := s.claimCall(n, ud)
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+		out["distributor"], out["calldata"] = dist, "0x"+hex.EncodeToString(data)
+	}
+	writeJSON(w, out)
+}
+
+// claimCall is the distributor of n and the calldata claiming ud from it.
+func (s *server) claimCall(n cycle.Name, ud cycle.UserData) (string, []byte, error) {
+	reg, err := s.claims.registry()
+	if err != nil {
+		return "", nil, err
+	}
+	ch, err := reg.Get(n.ChainID)
+	if err != nil {
+		return "", nil, err
+	}
+	dist, ok := ch.Distributor(n.RewardType)
+	if !ok {
+		return "", nil, fmt.Errorf("no distributor configured for chain %s type %s", n.ChainID, n.RewardType)
+	}
+	abi, err := distributor.ForChain(ch)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := abi.ClaimCalldata(ud)
+	return dist, data, err
+}
+
+func (s *server) uniswapProof(w http.ResponseWriter, dir string, n cycle.Name, account string) {
+	c, err := cycle.LoadUniswapClaims(filepath.Join(dir, n.UniswapName()))
+	if os.IsNotExist(err) {
+		// A file still in the claims format answers for itself.
+		if f, ferr := cycle.Load(filepath.Join(dir, n.String())); ferr == nil && f.Claims != nil {
+			c, err = f.Claims, nil
+		} else {
+			httpError(w, http.StatusNotFound, fmt.Errorf("%s has no uniswap claims sidecar", n))
+			return
+		}
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for a, cl := range c.Claims {
+		if strings.EqualFold(a, account) {
+			writeJSON(w, map[string]any{"merkleRoot": c.MerkleRoot, "account": a, "index": cl.Index, "amount": cl.Amount, "proof": cl.Proof})
+			return
+		}
+	}
+	httpError(w, http.StatusNotFound, fmt.Errorf("account %s has no claim in %s", account, n.UniswapName()))
+}
+
+// handleBloom serves the Bloom sidecar of GET /bloom?cycle=21&file=56_LM_21.json
+// for clients to check eligibility locally; with &position=addr:id it answers
+// the check itself.
+func (s *server) handleBloom(w http.ResponseWriter, r *http.Request) {
+	dir, n, ok := s.merkleFile(w, r)
+	if !ok {
+		return
+	}
+	b, err := cycle.LoadBloom(dir, n)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if b == nil {
+		httpError(w, http.StatusNotFound, fmt.Errorf("%s has no Bloom sidecar", n))
+		return
+	}
+	if pos := r.URL.Query().Get("position"); pos != "" {
+		writeJSON(w, map[string]any{"position": pos, "mayBeIncluded": b.Has(pos)})
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(dir, n.BloomName()))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func httpError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,189 @@
+//go:build !audit
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // --schedule-tz zones without a system zoneinfo
+
+	"github.com/KyberNetwork/fairflow-reward/canary"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/jobs"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/storage"
+	"github.com/KyberNetwork/fairflow-reward/webhook"
+)
+
+// ops are the operator side of serve: /presign, webhook registration,
+// --watch and the retry queue, which write state, send notifications and
+// run queued Notion writebacks. The audit build leaves all of it out (see
+// audit.go).
+type ops struct {
+	dest          *string
+	storageConfig *string
+	token         *string
+	maxTTL        *time.Duration
+	statePath     *string
+	watch         *time.Duration
+	claimLevels   *string
+	claimEvery    *time.Duration
+	sched         *string
+	schedTZ       *string
+	schedAnchor   *string
+	window        *time.Duration
+	retryEvery    *time.Duration
+	canaries      *string
+	verifySig     *string
+
+	backend storage.Backend
+	hooks   *webhook.Registry
+}
+
+func addOps(fs *flag.FlagSet) *ops {
+	return &ops{
+		dest:          fs.String("dest", "", "destination name or storage URL for /presign"),
+		storageConfig: fs.String("storage-config", storage.DefaultConfigPath, "named storage destinations JSON"),
+		token:         fs.String("token", os.Getenv("SERVE_TOKEN"), "bearer token required by /presign and /webhooks (or env SERVE_TOKEN)"),
+		maxTTL:        fs.Duration("max-ttl", time.Hour, "longest validity /presign will grant"),
+		statePath:     fs.String("state", state.DefaultPath, "state DB holding webhook registrations and what --watch has seen"),
+		watch:         fs.Duration("watch", 0, "poll for webhook events this often (0 = off)"),
+		claimLevels:   fs.String("claim-thresholds", "", "comma-separated claimed percentages that fire claim-rate.crossed (e.g. 50,90)"),
+		claimEvery:    fs.Duration("claim-check", time.Hour, "how often --watch sums claims for claim-rate.crossed on chains without a live \"ws\" subscription, or paying the native currency"),
+		sched:         fs.String("schedule", os.Getenv("RELEASE_SCHEDULE"), "cron-like release schedule for --watch, e.g. \"0 14 * * 4/2\" (4/2 = every other Thursday; or env RELEASE_SCHEDULE)"),
+		schedTZ:       fs.String("schedule-tz", os.Getenv("RELEASE_TZ"), "time zone of --schedule: IANA name or offset like UTC+7; default UTC (or env RELEASE_TZ)"),
+		schedAnchor:   fs.String("schedule-anchor", os.Getenv("RELEASE_ANCHOR"), "a known release as <cycle>@<date>[T<hh:mm>], e.g. 21@2026-10-08; needed for week steps and the expected cycle (or env RELEASE_ANCHOR)"),
+		window:        fs.Duration("release-window", 6*time.Hour, "how long after each scheduled release --watch polls for it"),
+		retryEvery:    fs.Duration("retry-queue", time.Minute, "retry queued side effects (alerts, Notion writebacks, webhooks) this often (0 = off)"),
+		canaries:      fs.String("canaries", canary.DefaultPath, "canary positions JSON whose proofs --watch verifies on-chain, for canary.failed (missing = off)"),
+		verifySig:     fs.String("verify-sig", "", "distributor view verifying a leaf and proof, for the canaries (default: the chain's distributor ABI's verifyProof)"),
+	}
+}
+
+// start registers the operator endpoints on mux and starts the background
+// work.
+func (o *ops) start(s *server, mux *http.ServeMux) error {
+	thresholds, err := parseThresholds(*o.claimLevels)
+	if err != nil {
+		return err
+	}
+	releases, err := schedule.FromSpec(*o.sched, *o.schedTZ, *o.schedAnchor)
+	if err != nil {
+		return err
+	}
+	db, err := state.Open(*o.statePath)
+	if err != nil {
+		return err
+	}
+	cs, err := canary.Load(*o.canaries)
+	if err != nil {
+		return err
+	}
+	o.hooks = webhook.NewRegistry(db)
+	if *o.dest != "" {
+		if *o.token == "" {
+			return errors.New("--dest requires --token: /presign must not be public")
+		}
+		cfg, err := storage.LoadConfig(*o.storageConfig)
+		if err != nil {
+			return err
+		}
+		if o.backend, err = cfg.Open(*o.dest); err != nil {
+			return err
+		}
+	}
+
+	mux.HandleFunc("/webhooks", o.handleWebhooks)
+	mux.HandleFunc("DELETE /webhooks/{id}", o.handleWebhookDelete)
+	if *o.watch > 0 {
+		w := &watcher{s: s, db: db, hooks: o.hooks, rootSig: s.rootSig, thresholds: thresholds, claimEvery: *o.claimEvery, sched: releases, window: *o.window, canaries: cs, verifier: &canary.Verifier{Sig: *o.verifySig}}
+		if releases != nil {
+			if n, err := releases.Expected(time.Now()); err == nil {
+				log.Printf("watch: expecting cycle %d, next release %s", n, releases.Next(time.Now()).Format(time.RFC3339))
+			}
+		}
+		go w.run(context.Background(), *o.watch)
+	}
+	if *o.retryEvery > 0 {
+		go retryQueue(context.Background(), db, *o.retryEvery)
+	}
+	mux.HandleFunc("/presign", o.handlePresign)
+	return nil
+}
+
+func (o *ops) handlePresign(w http.ResponseWriter, r *http.Request) {
+	if o.backend == nil {
+		httpError(w, http.StatusNotFound, errors.New("presigning is not configured"))
+		return
+	}
+	if !o.authorized(w, r) {
+		return
+	}
+	key := r.URL.Query().Get("key")
+	if !cycle.IsArtifactKey(key) {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("%q is not a cycle-N/<file> key", key))
+		return
+	}
+	ttl := *o.maxTTL
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			httpError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", v))
+			return
+		}
+		if d < ttl {
+			ttl = d
+		}
+	}
+	u, err := storage.Presign(r.Context(), o.backend, key, ttl)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, map[string]any{"key": key, "url": u, "expiresAt": time.Now().UTC().Add(ttl)})
+}
+
+func retryQueue(ctx context.Context, db *state.DB, every time.Duration) {
+	q := queue.New(db)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		res, err := q.Flush(ctx, jobs.Handlers(db), false)
+		if err != nil {
+			log.Printf("retry queue: %v", err)
+		}
+		if res.Done+res.Failed > 0 {
+			log.Printf("retry queue: %d done, %d failed", res.Done, res.Failed)
+		}
+	}
+}
+
+// authorized checks the bearer token, answering the request itself when it
+// is wrong or when no --token is configured.
+func (o *ops) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if *o.token == "" {
+		httpError(w, http.StatusNotFound, errors.New("this endpoint needs --token"))
+		return false
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(*o.token)) != 1 {
+		httpError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return false
+	}
+	return true
+}
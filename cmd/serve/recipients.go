@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// recipientIndex is a merkle file's leaves sorted by position key, kept in
+// memory until the file changes on disk.
+type recipientIndex struct {
+	modTime time.Time
+	size    int64
+	leaves  []indexedLeaf
+}
+
+type indexedLeaf struct {
+	key     string
+	leaf    cycle.Leaf
+	amounts map[string]*big.Int
+}
+
+type recipientIndexes struct {
+	mu    sync.Mutex
+	files map[string]*recipientIndex
+}
+
+func (ri *recipientIndexes) get(path string) (*recipientIndex, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if idx, ok := ri.files[path]; ok && idx.modTime.Equal(st.ModTime()) && idx.size == st.Size() {
+		return idx, nil
+	}
+	f, err := cycle.LoadMapped(path)
+	if err != nil {
+		return nil, err
+	}
+	idx := &recipientIndex{modTime: st.ModTime(), size: st.Size(), leaves: make([]indexedLeaf, 0, len(f.UserDatas))}
+	for _, ud := range f.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			return nil, err
+		}
+		idx.leaves = append(idx.leaves, indexedLeaf{key: ud.Leaf.Key(), leaf: ud.Leaf, amounts: am})
+	}
+	sort.Slice(idx.leaves, func(i, j int) bool { return idx.leaves[i].key < idx.leaves[j].key })
+	if ri.files == nil {
+		ri.files = make(map[string]*recipientIndex)
+	}
+	ri.files[path] = idx
+	return idx, nil
+}
+
+type recipientPage struct {
+	Recipients []cycle.Leaf `json:"recipients"`
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// handleRecipients serves GET /v1/{chain}/{type}/{cycle}/recipients, a page
+// of a merkle file's leaves in position-key order:
+//
+//	?limit=     page size, default 100, at most 1000
+//	?cursor=    nextCursor of the previous page
+//	?min_amount=&token=
+//	            only leaves with at least min_amount of token (base units);
+//	            without token, of any token
+func (s *server) handleRecipients(w http.ResponseWriter, r *http.Request) {
+	c, err := strconv.Atoi(r.PathValue("cycle"))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("invalid cycle %q", r.PathValue("cycle")))
+		return
+	}
+	file := fmt.Sprintf("%s_%s_%d.json", r.PathValue("chain"), r.PathValue("type"), c)
+	n, ok := cycle.ParseName(file)
+	if !ok {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("%q is not a merkle file", file))
+		return
+	}
+	q := r.URL.Query()
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxPageSize {
+			httpError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+	}
+	var after string
+	if v := q.Get("cursor"); v != "" {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, errors.New("invalid cursor"))
+			return
+		}
+		after = string(b)
+	}
+	var minAmount *big.Int
+	if v := q.Get("min_amount"); v != "" {
+		if minAmount, err = cycle.ParseAmount(v); err != nil {
+			httpError(w, http.StatusBadRequest, fmt.Errorf("min_amount: %w", err))
+			return
+		}
+	}
+	token := strings.ToLower(q.Get("token"))
+
+	idx, err := s.recipients.get(filepath.Join(s.root, cycle.DirName(c), n.String()))
+	if os.IsNotExist(err) {
+		httpError(w, http.StatusNotFound, fmt.Errorf("no %s in cycle %d", n, c))
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	page := recipientPage{Recipients: make([]cycle.Leaf, 0, limit)}
+	i := sort.Search(len(idx.leaves), func(i int) bool { return idx.leaves[i].key > after })
+	for last := ""; i < len(idx.leaves); i++ {
+		l := idx.leaves[i]
+		if minAmount != nil && !atLeast(l.amounts, token, minAmount) {
+			continue
+		}
+		if len(page.Recipients) == limit {
+			page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		page.Recipients = append(page.Recipients, l.leaf)
+		last = l.key
+	}
+	writeJSON(w, page)
+}
+
+func atLeast(amounts map[string]*big.Int, token string, floor *big.Int) bool {
+	if token != "" {
+		a, ok := amounts[token]
+		return ok && a.Cmp(floor) >= 0
+	}
+	for _, a := range amounts {
+		if a.Cmp(floor) >= 0 {
+			return true
+		}
+	}
+	return false
+}
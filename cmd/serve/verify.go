@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// fileCheck is one merkle file of GET /verify.
+type fileCheck struct {
+	File string `json:"file"`
+	Root string `json:"root"`
+	// MasterRoot is set for a two-level sharded file: it is what the
+	// distributor stores.
+	MasterRoot string            `json:"masterRoot,omitempty"`
+	Recipients int               `json:"recipients"`
+	Totals     map[string]string `json:"totals"`
+	Findings   []verify.Finding  `json:"findings"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// handleVerify answers GET /verify?cycle=21 by running the verify rules
+// over every merkle file of the cycle, recomputing trees, roots, shards and
+// totals from the files themselves, the same as cmd/verify with its default
+// rules.
+func (s *server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	dir, entries, ok := s.cycleFiles(w, r)
+	if !ok {
+		return
+	}
+	out := make([]fileCheck, 0, len(entries))
+	allOK := true
+	for _, e := range entries {
+		fc := fileCheck{File: e.String(), Findings: []verify.Finding{}}
+		if err := verifyFile(dir, e, &fc); err != nil {
+			fc.Error = err.Error()
+		}
+		if fc.Error != "" || verify.HasErrors(fc.Findings) {
+			allOK = false
+		}
+		out = append(out, fc)
+	}
+	writeJSON(w, map[string]any{"cycle": entries[0].Cycle, "ok": allOK, "files": out})
+}
+
+func verifyFile(dir string, e cycle.Entry, fc *fileCheck) error {
+	f, err := cycle.Load(e.Path)
+	if err != nil {
+		return err
+	}
+	if f.Format == cycle.FormatUniswap {
+		fc.Root = f.Claims.MerkleRoot
+		fc.Recipients = len(f.Claims.Claims)
+		fc.Findings = append(fc.Findings, verify.RunUniswap(e.Path, f.Claims, nil, verify.Options{})...)
+		return nil
+	}
+	fc.Root, fc.Recipients = f.Root, len(f.UserDatas)
+	fc.Findings = append(fc.Findings, verify.Run(verify.NewTarget(e.Path, f), verify.Options{})...)
+	sums, err := f.SumAmounts()
+	if err != nil {
+		return err
+	}
+	fc.Totals = make(map[string]string, len(sums))
+	for t, a := range sums {
+		fc.Totals[t] = a.String()
+	}
+	idx, err := cycle.LoadShardIndex(dir, e.Name)
+	if err != nil || idx == nil {
+		return err
+	}
+	fc.MasterRoot = idx.MasterRoot
+	return cycle.VerifyShards(dir, e.Name)
+}
+
+// chainCheck is one merkle file of GET /onchain.
+type chainCheck struct {
+	File        string `json:"file"`
+	Distributor string `json:"distributor,omitempty"`
+	// Expected is the root the distributor should store for the file: its
+	// master root when it is two-level sharded, else its own.
+	Expected string                  `json:"expected,omitempty"`
+	OnChain  string                  `json:"onChain,omitempty"`
+	Match    bool                    `json:"match"`
+	Tokens   map[string]tokenBalance `json:"tokens,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// tokenBalance compares a file's total of one token with what its
+// distributor holds. Covered only says the balance is at least the total:
+// unclaimed amounts of earlier cycles sit in the same distributor.
+type tokenBalance struct {
+	Total   string `json:"total"`
+	Balance string `json:"balance"`
+	Covered bool   `json:"covered"`
+}
+
+// handleOnchain answers GET /onchain?cycle=21 with, for every merkle file of
+// the cycle, the root its distributor stores now against the one the file
+// says it should, and each token's file total against the distributor's
+// balance. Reads go to the chain registry's RPCs at the latest block.
+func (s *server) handleOnchain(w http.ResponseWriter, r *http.Request) {
+	dir, entries, ok := s.cycleFiles(w, r)
+	if !ok {
+		return
+	}
+	reg, err := s.claims.registry()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]chainCheck, 0, len(entries))
+	allOK := true
+	for _, e := range entries {
+		cc := chainCheck{File: e.String()}
+		if err := s.checkOnchain(r.Context(), reg, dir, e, &cc); err != nil {
+			cc.Error = err.Error()
+		}
+		if !cc.Match {
+			allOK = false
+		}
+		for _, t := range cc.Tokens {
+			allOK = allOK && t.Covered
+		}
+		out = append(out, cc)
+	}
+	writeJSON(w, map[string]any{"cycle": entries[0].Cycle, "ok": allOK, "files": out})
+}
+
+func (s *server) checkOnchain(ctx context.Context, reg chains.Registry, dir string, e cycle.Entry, cc *chainCheck) error {
+	ch, err := reg.Get(e.ChainID)
+	if err != nil {
+		return err
+	}
+	dist, ok := ch.Distributor(e.RewardType)
+	if !ok {
+		return fmt.Errorf("no distributor configured for chain %s type %s", e.ChainID, e.RewardType)
+	}
+	cc.Distributor = dist
+	f, err := cycle.Load(e.Path)
+	if err != nil {
+		return err
+	}
+	cc.Expected = f.Root
+	if f.Format == cycle.FormatUniswap {
+		cc.Expected = f.Claims.MerkleRoot
+	}
+	idx, err := cycle.LoadShardIndex(dir, e.Name)
+	if err != nil {
+		return err
+	}
+	if idx != nil && idx.TwoLevel() {
+		cc.Expected = idx.MasterRoot
+	}
+	if cc.OnChain, err = s.readRoot(ctx, e.ChainID, ch, dist); err != nil {
+		return err
+	}
+	cc.Match = strings.EqualFold(cc.OnChain, cc.Expected)
+
+	sums, err := f.SumAmounts()
+	if err != nil {
+		return err
+	}
+	c, err := s.claims.client(e.ChainID, ch)
+	if err != nil {
+		return err
+	}
+	cc.Tokens = make(map[string]tokenBalance, len(sums))
+	for t, total := range sums {
+		bal, err := c.TokenBalance(ctx, t, dist, evm.DefaultBlockTag)
+		if err != nil {
+			return fmt.Errorf("balance of %s: %w", t, err)
+		}
+		cc.Tokens[t] = tokenBalance{Total: total.String(), Balance: bal.String(), Covered: bal.Cmp(total) >= 0}
+	}
+	return nil
+}
+
+// rootsOff is the --root-sig turning root.changed off; /onchain then reads
+// roots through the default view.
+const rootsOff = "off"
+
+// readRoot reads the merkle root dist stores, through --root-sig or the
+// chain's distributor ABI.
+func (s *server) readRoot(ctx context.Context, chainID string, ch chains.Chain, dist string) (string, error) {
+	c, err := s.claims.client(chainID, ch)
+	if err != nil {
+		return "", err
+	}
+	sig := s.rootSig
+	if sig == rootsOff {
+		sig = ""
+	}
+	abi, err := distributor.ForChain(ch)
+	if err == nil {
+		abi, err = abi.Override("merkleRoot", sig)
+	}
+	if err != nil {
+		return "", err
+	}
+	return abi.At(dist, c).MerkleRoot(ctx, evm.DefaultBlockTag)
+}
+
+// cycleFiles resolves the cycle query parameter to its directory and merkle
+// files, writing the error response itself when it cannot.
+func (s *server) cycleFiles(w http.ResponseWriter, r *http.Request) (string, []cycle.Entry, bool) {
+	q := r.URL.Query().Get("cycle")
+	c, err := strconv.Atoi(q)
+	if err != nil || c <= 0 {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("invalid cycle %q", q))
+		return "", nil, false
+	}
+	dir := filepath.Join(s.root, cycle.DirName(c))
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		httpError(w, http.StatusNotFound, fmt.Errorf("cycle %d: %w", c, err))
+		return "", nil, false
+	}
+	if len(entries) == 0 {
+		httpError(w, http.StatusNotFound, errors.New("no merkle files in "+cycle.DirName(c)))
+		return "", nil, false
+	}
+	return dir, entries, true
+}
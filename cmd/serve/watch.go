@@ -0,0 +1,620 @@
+//go:build !audit
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/canary"
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/webhook"
+)
+
+const watchBucket = "watch"
+
+// watcher polls for the events webhooks subscribe to. What it has seen is
+// kept in the state DB, so a restart does not replay events; the first poll
+// of anything only records a baseline.
+type watcher struct {
+	s          *server
+	db         *state.DB
+	hooks      *webhook.Registry
+	rootSig    string
+	thresholds []int // percent, ascending
+	claimEvery time.Duration
+	// With sched set, cycles and roots are only polled for window after
+	// each scheduled release; claim rates keep their own pace.
+	sched  *schedule.Schedule
+	window time.Duration
+	// canaries are verified on-chain in the latest cycle every poll.
+	canaries []canary.Canary
+	verifier *canary.Verifier
+}
+
+func (w *watcher) run(ctx context.Context, every time.Duration) {
+	if len(w.thresholds) > 0 {
+		go w.watchClaims(ctx, every)
+	}
+	for {
+		now := time.Now()
+		checks := []error{w.canaryChecks(ctx)}
+		if w.sched == nil || w.inWindow(now) {
+			checks = append(checks, w.cycles(ctx), w.roots(ctx))
+		}
+		if w.sched != nil {
+			checks = append(checks, w.overdue(ctx, now))
+		}
+		for _, err := range checks {
+			if err != nil {
+				log.Printf("watch: %v", err)
+			}
+		}
+		if err := w.db.Save(); err != nil {
+			log.Printf("watch: save state: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.wait(time.Now(), every)):
+		}
+	}
+}
+
+// inWindow reports whether t falls within window of the latest scheduled
+// release.
+func (w *watcher) inWindow(t time.Time) bool {
+	last := w.sched.Prev(t)
+	return !last.IsZero() && t.Before(last.Add(w.window))
+}
+
+// wait is how long to sleep before the next poll: every inside the release
+// window, otherwise until the window opens.
+func (w *watcher) wait(now time.Time, every time.Duration) time.Duration {
+	if w.sched == nil || w.inWindow(now) {
+		return every
+	}
+	if next := w.sched.Next(now); !next.IsZero() {
+		return next.Sub(now)
+	}
+	return every
+}
+
+type cycleOverdue struct {
+	Cycle    int       `json:"cycle"`
+	Due      time.Time `json:"due"`
+	WindowTo time.Time `json:"windowEnd"`
+}
+
+// overdue fires cycle.overdue once the release window of the cycle the
+// schedule expects has closed without its merkle files appearing.
+func (w *watcher) overdue(ctx context.Context, now time.Time) error {
+	due := w.sched.Prev(now)
+	if due.IsZero() || now.Before(due.Add(w.window)) {
+		return nil
+	}
+	n, err := w.sched.Expected(now)
+	if err != nil {
+		return nil // without an anchor there is no expected cycle
+	}
+	key := "overdue/" + strconv.Itoa(n)
+	if ok, err := w.db.Get(watchBucket, key, new(bool)); err != nil || ok {
+		return err
+	}
+	entries, err := cycle.ScanDir(filepath.Join(w.s.root, cycle.DirName(n)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Printf("watch: cycle %d was due %s and is still not loaded", n, due.Format(time.RFC3339))
+		w.notify(ctx, webhook.EventCycleOverdue, cycleOverdue{Cycle: n, Due: due, WindowTo: due.Add(w.window)})
+	}
+	return w.db.Put(watchBucket, key, true)
+}
+
+func (w *watcher) notify(ctx context.Context, typ string, data any) {
+	if err := w.hooks.Notify(ctx, webhook.NewEvent(typ, data)); err != nil {
+		log.Printf("watch: %s: %v", typ, err)
+	}
+}
+
+// cycles fires cycle.loaded for every cycle whose merkle files appeared since
+// the last poll; embargoed cycles fire once they are decrypted.
+func (w *watcher) cycles(ctx context.Context) error {
+	var seen []int
+	baseline, err := w.db.Get(watchBucket, "cycles", &seen)
+	if err != nil {
+		return err
+	}
+	cycles, err := cycle.Cycles(w.s.root)
+	if err != nil {
+		return err
+	}
+	loaded := make([]int, 0, len(cycles))
+	for _, n := range cycles {
+		dir := filepath.Join(w.s.root, cycle.DirName(n))
+		entries, err := cycle.ScanDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		loaded = append(loaded, n)
+		if !baseline || slices.Contains(seen, n) {
+			continue
+		}
+		sum, err := summary.Build(dir)
+		if err != nil {
+			return err
+		}
+		w.notify(ctx, webhook.EventCycleLoaded, sum)
+	}
+	return w.db.Put(watchBucket, "cycles", loaded)
+}
+
+type rootChange struct {
+	ChainID     string `json:"chainId"`
+	Distributor string `json:"distributor"`
+	Previous    string `json:"previous"`
+	Root        string `json:"root"`
+	// Cycle is the cycle whose file (or two-level master) root this is, if
+	// any.
+	Cycle int    `json:"cycle,omitempty"`
+	File  string `json:"file,omitempty"`
+}
+
+// roots fires root.changed when a distributor in the chain registry reports
+// a root other than the one seen last poll.
+func (w *watcher) roots(ctx context.Context) error {
+	if w.rootSig == rootsOff {
+		return nil
+	}
+	reg, err := w.s.claims.registry()
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for _, id := range reg.IDs() {
+		ch := reg[id]
+		for _, dist := range distinct(ch.Distributors) {
+			root, err := w.s.readRoot(ctx, id, ch, dist)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("chain %s distributor %s: %w", id, dist, err))
+				continue
+			}
+			key := "root/" + id + "/" + strings.ToLower(dist)
+			var prev string
+			ok, err := w.db.Get(watchBucket, key, &prev)
+			if err != nil {
+				return err
+			}
+			if ok && prev != root {
+				ev := rootChange{ChainID: id, Distributor: dist, Previous: prev, Root: root}
+				ev.Cycle, ev.File = w.findRoot(id, root)
+				w.notify(ctx, webhook.EventRootChanged, ev)
+			}
+			if err := w.db.Put(watchBucket, key, root); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findRoot returns the newest cycle file on chainID with root, directly or
+// as the master root of its shards.
+func (w *watcher) findRoot(chainID, root string) (int, string) {
+	cycles, err := cycle.Cycles(w.s.root)
+	if err != nil {
+		return 0, ""
+	}
+	for i := len(cycles) - 1; i >= 0; i-- {
+		dir := filepath.Join(w.s.root, cycle.DirName(cycles[i]))
+		entries, err := cycle.ScanDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.ChainID != chainID {
+				continue
+			}
+			if idx, err := cycle.LoadShardIndex(dir, e.Name); err == nil && idx != nil && strings.EqualFold(idx.MasterRoot, root) {
+				return e.Cycle, e.String()
+			}
+			if f, err := cycle.Load(e.Path); err == nil && strings.EqualFold(f.Root, root) {
+				return e.Cycle, e.String()
+			}
+		}
+	}
+	return 0, ""
+}
+
+type canaryFailed struct {
+	Cycle int `json:"cycle"`
+	canary.Result
+}
+
+// canaryChecks verifies the canaries in the latest cycle's files and fires
+// canary.failed when one that verified, or was not checked before, fails.
+func (w *watcher) canaryChecks(ctx context.Context) error {
+	if len(w.canaries) == 0 {
+		return nil
+	}
+	if w.verifier.Registry == nil {
+		reg, err := w.s.claims.registry()
+		if err != nil {
+			return err
+		}
+		w.verifier.Registry = reg
+	}
+	cycles, err := cycle.Cycles(w.s.root)
+	if err != nil || len(cycles) == 0 {
+		return err
+	}
+	latest := cycles[len(cycles)-1]
+	results, err := w.verifier.CheckCycle(ctx, filepath.Join(w.s.root, cycle.DirName(latest)), w.canaries)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		key := "canary/" + strconv.Itoa(latest) + "/" + r.File + "/" + r.Position
+		var wasOK bool
+		seen, err := w.db.Get(watchBucket, key, &wasOK)
+		if err != nil {
+			return err
+		}
+		if !r.OK && (!seen || wasOK) {
+			log.Printf("watch: canary %s", r)
+			w.notify(ctx, webhook.EventCanaryFailed, canaryFailed{Cycle: latest, Result: r})
+		}
+		if err := w.db.Put(watchBucket, key, r.OK); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type claimRateCrossed struct {
+	Cycle     int    `json:"cycle"`
+	File      string `json:"file"`
+	Token     string `json:"token"`
+	Threshold int    `json:"thresholdPercent"`
+	Claimed   string `json:"claimed"`
+	Total     string `json:"total"`
+}
+
+// claimFeed tracks the chains whose transfers out of their distributors
+// arrive over eth_subscribe.
+type claimFeed struct {
+	mu    sync.Mutex
+	live  map[string]bool // chain ID -> subscribed
+	dirty map[string]bool // chain ID -> transfers since its claims were summed
+	wake  chan struct{}
+}
+
+// mark records transfers on chainID, or with live false that its
+// subscription is down.
+func (f *claimFeed) mark(chainID string, live bool) {
+	f.mu.Lock()
+	f.live[chainID], f.dirty[chainID] = live, live
+	f.mu.Unlock()
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (f *claimFeed) state(chainID string) (live, dirty bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.live[chainID], f.dirty[chainID]
+}
+
+// clear forgets chainID's transfers, before its claims are summed.
+func (f *claimFeed) clear(chainID string) {
+	f.mu.Lock()
+	f.dirty[chainID] = false
+	f.mu.Unlock()
+}
+
+// claimWatch is what watchClaims remembers between sums.
+type claimWatch struct {
+	feed   *claimFeed
+	last   map[string]time.Time // chain ID -> last sum
+	summed map[string]bool      // files summed at least once
+	native map[string]bool      // files paying the native currency
+}
+
+// watchClaims fires claim-rate.crossed for the latest cycle's files. A
+// chain with a "ws" endpoint subscribes to ERC-20 transfers out of its
+// distributors and has its claims summed once some arrive, at most every
+// poll interval; the native currency sends no Transfer, so files paying it
+// are still summed every claimEvery. Chains without a subscription, or
+// whose subscription is down, are summed every claimEvery.
+func (w *watcher) watchClaims(ctx context.Context, every time.Duration) {
+	cw := &claimWatch{
+		feed:   &claimFeed{live: make(map[string]bool), dirty: make(map[string]bool), wake: make(chan struct{}, 1)},
+		last:   make(map[string]time.Time),
+		summed: make(map[string]bool),
+		native: make(map[string]bool),
+	}
+	if reg, err := w.s.claims.registry(); err != nil {
+		log.Printf("watch: %v; polling claims", err)
+	} else {
+		for _, id := range reg.IDs() {
+			if ch := reg[id]; ch.WS != "" && len(ch.Distributors) > 0 {
+				go w.subscribeClaims(ctx, id, ch, cw.feed)
+			}
+		}
+	}
+	for {
+		next, err := w.claimRates(ctx, cw, every)
+		if err != nil {
+			log.Printf("watch: %v", err)
+		}
+		if err := w.db.Save(); err != nil {
+			log.Printf("watch: save state: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-cw.feed.wake:
+		case <-time.After(next):
+		}
+	}
+}
+
+// subscribeClaims keeps a subscription to transfers out of ch's
+// distributors up, resubscribing a minute after it drops.
+func (w *watcher) subscribeClaims(ctx context.Context, chainID string, ch chains.Chain, feed *claimFeed) {
+	filter, err := evm.TransfersFrom(distinct(ch.Distributors)...)
+	if err != nil {
+		log.Printf("watch: chain %s: %v; polling claims", chainID, err)
+		return
+	}
+	for {
+		sub, err := evm.Subscribe(ctx, ch.WS, "logs", filter)
+		if err == nil {
+			// Transfers may have been missed while it was down.
+			feed.mark(chainID, true)
+			for {
+				if _, err = sub.Next(); err != nil {
+					break
+				}
+				feed.mark(chainID, true)
+			}
+			sub.Close()
+		}
+		feed.mark(chainID, false)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("watch: chain %s: claim subscription: %v; polling claims until it is back", chainID, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// claimRates sums the claims of the latest cycle's files on every chain
+// that is due, and returns how long until the next one is. Summing claims
+// reads every leaf.
+func (w *watcher) claimRates(ctx context.Context, cw *claimWatch, every time.Duration) (time.Duration, error) {
+	cycles, err := cycle.Cycles(w.s.root)
+	if err != nil || len(cycles) == 0 {
+		return w.claimEvery, err
+	}
+	latest := cycles[len(cycles)-1]
+	entries, err := cycle.ScanDir(filepath.Join(w.s.root, cycle.DirName(latest)))
+	if err != nil {
+		return w.claimEvery, err
+	}
+	byChain := make(map[string][]cycle.Entry)
+	for _, e := range entries {
+		byChain[e.ChainID] = append(byChain[e.ChainID], e)
+	}
+	now := time.Now()
+	next := w.claimEvery
+	errs := make([]error, 0)
+	for id, es := range byChain {
+		live, dirty := cw.feed.state(id)
+		since := now.Sub(cw.last[id])
+		poll := !live || slices.ContainsFunc(es, func(e cycle.Entry) bool { return cw.native[e.String()] })
+		switch {
+		case slices.ContainsFunc(es, func(e cycle.Entry) bool { return !cw.summed[e.String()] }):
+		case poll && since >= w.claimEvery:
+		case dirty && since >= every:
+		default:
+			if poll {
+				next = min(next, w.claimEvery-since)
+			}
+			if dirty {
+				next = min(next, every-since)
+			}
+			continue
+		}
+		cw.feed.clear(id)
+		cw.last[id] = now
+		for _, e := range es {
+			native, err := w.claimRate(ctx, e)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", e.String(), err))
+				continue
+			}
+			cw.summed[e.String()], cw.native[e.String()] = true, native
+		}
+	}
+	return next, errors.Join(errs...)
+}
+
+// claimRate sums the claims of e and fires claim-rate.crossed for its
+// tokens; native reports whether one of them is the native currency.
+func (w *watcher) claimRate(ctx context.Context, e cycle.Entry) (native bool, err error) {
+	f, err := cycle.Load(e.Path)
+	if err != nil {
+		return false, err
+	}
+	totals, err := f.SumAmounts()
+	if err != nil {
+		return false, err
+	}
+	for t := range totals {
+		native = native || evm.IsNative(t)
+	}
+	src, err := w.s.claims.source(e.Name)
+	if err != nil {
+		return native, err
+	}
+	claimed := make(map[string]*big.Int)
+	for _, ud := range f.UserDatas {
+		c, err := src.Claimed(ctx, ud.Leaf)
+		if err != nil {
+			return native, err
+		}
+		for t, a := range c {
+			if claimed[t] == nil {
+				claimed[t] = new(big.Int)
+			}
+			claimed[t].Add(claimed[t], a)
+		}
+	}
+	for t, total := range totals {
+		if total.Sign() == 0 {
+			continue
+		}
+		if claimed[t] == nil {
+			claimed[t] = new(big.Int)
+		}
+		pct := int(new(big.Int).Quo(new(big.Int).Mul(claimed[t], big.NewInt(100)), total).Int64())
+		key := "claims/" + e.String() + "/" + t
+		var last int
+		baseline, err := w.db.Get(watchBucket, key, &last)
+		if err != nil {
+			return native, err
+		}
+		crossed := 0
+		for _, th := range w.thresholds {
+			if pct >= th {
+				crossed = th
+			}
+		}
+		if baseline && crossed > last {
+			w.notify(ctx, webhook.EventClaimRate, claimRateCrossed{
+				Cycle: e.Cycle, File: e.String(), Token: t, Threshold: crossed,
+				Claimed: claimed[t].String(), Total: total.String(),
+			})
+		}
+		if err := w.db.Put(watchBucket, key, crossed); err != nil {
+			return native, err
+		}
+	}
+	return native, nil
+}
+
+// distinct returns the distributor addresses of m without repeats.
+func distinct(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for _, a := range m {
+		if !slices.ContainsFunc(out, func(b string) bool { return strings.EqualFold(a, b) }) {
+			out = append(out, a)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func parseThresholds(s string) ([]int, error) {
+	out := make([]int, 0)
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(p, "%"))
+		if err != nil || n <= 0 || n > 100 {
+			return nil, fmt.Errorf("invalid claim threshold %q (want a percent in 1-100)", p)
+		}
+		out = append(out, n)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+type hookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// handleWebhooks serves GET /webhooks (list) and POST /webhooks
+// {"url", "events", "secret"} (register), both behind --token.
+func (o *ops) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !o.authorized(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		hooks, err := o.hooks.List()
+		if err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for i := range hooks {
+			hooks[i].Secret = ""
+		}
+		writeJSON(w, hooks)
+	case http.MethodPost:
+		var req hookRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+		h, err := o.hooks.Add(req.URL, req.Events, req.Secret)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.Secret = ""
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, h)
+	default:
+		httpError(w, http.StatusMethodNotAllowed, errors.New("use GET or POST"))
+	}
+}
+
+// handleWebhookDelete serves DELETE /webhooks/{id}.
+func (o *ops) handleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	if !o.authorized(w, r) {
+		return
+	}
+	ok, err := o.hooks.Remove(r.PathValue("id"))
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		httpError(w, http.StatusNotFound, fmt.Errorf("no webhook %q", r.PathValue("id")))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// shard splits an oversized merkle file into N shards next to it, each a
+// standalone merkle file with its own root, plus a <name>.shards.json index.
+// update-kyber-applications then emits one URL per shard.
+//
+// With --two-level the index also carries a master root over the shard roots
+// for the distributor to store instead, and each shard's proof into it.
+//
+//	shard --file cycle-21/56_LM_21.json --shards 8 --two-level
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		filePath    = flag.String("file", "", "merkle file to split (e.g. cycle-21/56_LM_21.json)")
+		count       = flag.Int("shards", 0, "number of shards")
+		twoLevel    = flag.Bool("two-level", false, "build a master root over the shard roots")
+		parallelism = flag.Int("parallelism", 0, "goroutines for hashing (0 = one per CPU)")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "shard"); err != nil {
+		fatal(err)
+	}
+	par.SetWorkers(*parallelism)
+	if *filePath == "" || *count == 0 {
+		fatal(errors.New("missing --file or --shards"))
+	}
+	dir := filepath.Dir(*filePath)
+	n, ok := cycle.ParseName(filepath.Base(*filePath))
+	if !ok {
+		fatal(fmt.Errorf("%s is not a <chain>_<type>_<cycle>.json merkle file", *filePath))
+	}
+	f, err := cycle.Load(*filePath)
+	if err != nil {
+		fatal(err)
+	}
+	if old, err := cycle.LoadShardIndex(dir, n); err != nil {
+		fatal(err)
+	} else if old != nil {
+		// A re-shard with another count must not leave stale shards behind.
+		for _, s := range old.Shards {
+			if err := os.Remove(filepath.Join(dir, s.File)); err != nil && !os.IsNotExist(err) {
+				fatal(err)
+			}
+		}
+	}
+	shards, err := cycle.Split(f, *count)
+	if err != nil {
+		fatal(err)
+	}
+	idx := cycle.NewShardIndex(n, f.Root, shards)
+	if *twoLevel {
+		if err := idx.BuildMaster(); err != nil {
+			fatal(err)
+		}
+	}
+	for i, s := range shards {
+		p := filepath.Join(dir, idx.Shards[i].File)
+		if err := cycle.Write(p, s); err != nil {
+			fatal(fmt.Errorf("write %s: %w", p, err))
+		}
+		fmt.Printf("Wrote %s: %d recipients, root %s\n", p, len(s.UserDatas), s.Root)
+	}
+	if err := idx.Write(dir, n); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Wrote %s\n", filepath.Join(dir, n.ShardIndexName()))
+	if idx.TwoLevel() {
+		fmt.Printf("Master root (set this on the distributor): %s\n", idx.MasterRoot)
+	}
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/alert"
+	"github.com/KyberNetwork/fairflow-reward/canary"
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/sample"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/smoke"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// smoke checks, after a release, that the deployed reward-service serves the
+// new cycle: for a sample of positions of every merkle file in --cycle-dir
+// it asks the service for the position's reward and compares root, amounts
+// and proof with the file's.
+//
+//	smoke --cycle-dir cycle-21 --endpoint 'https://reward.example.com/api/v1/proof?chainId={chain}&type={type}&cycle={cycle}&position={position}'
+//
+// The service is expected to answer like serve's /proof. The same positions
+// are sampled on every run for a cycle unless --seed changes; --summary-file
+// records the seed and positions so a reviewer can check the same ones. The canaries
+// of --canaries are checked too, and on-chain: each one's proof must verify
+// against its distributor's current root. Any finding exits 1 and, with
+// --alert, pages.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir   = flag.String("cycle-dir", "", "released cycle-N directory")
+		endpoint   = flag.String("endpoint", os.Getenv("REWARD_SERVICE_ENDPOINT"), "reward-service proof URL template with {chain}, {type}, {cycle}, {file}, {position}, {addr} and {id} (or env REWARD_SERVICE_ENDPOINT)")
+		sampleSize = flag.Int("sample", 10, "positions to check per merkle file")
+		seed       = flag.Uint64("seed", 0, "sampling seed (0 = the cycle number)")
+		timeout    = flag.Duration("timeout", 10*time.Second, "per-request timeout")
+		sendAlert  = flag.Bool("alert", false, "page through PAGERDUTY_ROUTING_KEY / OPSGENIE_API_KEY on a mismatch")
+		jsonOut    = flag.Bool("json", false, "print the findings as JSON")
+		canaries   = flag.String("canaries", canary.DefaultPath, "canary positions JSON whose proofs are verified on-chain (missing = none)")
+		chainsPath = flag.String("chains", chains.DefaultPath, "chain registry JSON, for the canaries' RPCs and distributors")
+		verifySig  = flag.String("verify-sig", "", "distributor view verifying a leaf and proof (default: the chain's distributor ABI's verifyProof)")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "smoke"); err != nil {
+		fatal(err)
+	}
+	if *cycleDir == "" || *endpoint == "" {
+		fatal(errors.New("missing --cycle-dir or --endpoint"))
+	}
+	entries, err := cycle.ScanDir(*cycleDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(entries) == 0 {
+		fatal(fmt.Errorf("no merkle files in %s", *cycleDir))
+	}
+
+	ctx := context.Background()
+	client := &smoke.Client{Endpoint: *endpoint, HTTP: &http.Client{Timeout: *timeout}}
+	findings := make([]smoke.Finding, 0)
+	checked := 0
+	for _, e := range entries {
+		f, err := cycle.Load(e.Path)
+		if err != nil {
+			fatal(err)
+		}
+		s := *seed
+		if s == 0 {
+			s = uint64(e.Cycle)
+		}
+		keys := smoke.Sample(f, *sampleSize, s)
+		runsummary.Sampled(e.Path, sample.Scheme, s, keys)
+		found, err := smoke.Check(ctx, client, filepath.Dir(e.Path), e.Name, keys)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", e.Name, err))
+		}
+		checked += len(keys)
+		findings = append(findings, found...)
+	}
+
+	cs, err := canary.Load(*canaries)
+	if err != nil {
+		fatal(err)
+	}
+	if len(cs) > 0 {
+		reg, err := chains.Load(*chainsPath)
+		if err != nil {
+			fatal(fmt.Errorf("load chain registry: %w", err))
+		}
+		v := &canary.Verifier{Registry: reg, Sig: *verifySig}
+		results, err := v.CheckCycle(ctx, *cycleDir, cs)
+		if err != nil {
+			fatal(err)
+		}
+		for _, r := range results {
+			if !r.OK {
+				findings = append(findings, smoke.Finding{File: r.File, Position: r.Position, Problem: "canary " + r.Label + ": " + r.Problem})
+			}
+		}
+		checked += len(results)
+	}
+
+	if *jsonOut {
+		b, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+		fmt.Printf("Checked %d positions in %d files: %d findings\n", checked, len(entries), len(findings))
+	}
+	if len(findings) == 0 {
+		return
+	}
+	if *sendAlert {
+		if err := page(ctx, entries[0].Cycle, findings); err != nil {
+			fmt.Fprintln(os.Stderr, "smoke: sending alert:", err)
+		}
+	}
+	tmpdir.Exit(1)
+}
+
+func page(ctx context.Context, n int, findings []smoke.Finding) error {
+	sender := alert.FromEnv()
+	if sender == nil {
+		return errors.New("no PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY set")
+	}
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = f.String()
+	}
+	return sender.Send(ctx, alert.Alert{
+		Summary:  fmt.Sprintf("reward-service is not serving fairflow cycle %d correctly (%d findings)", n, len(findings)),
+		Source:   "smoke",
+		Class:    alert.ClassPublish,
+		DedupKey: fmt.Sprintf("fairflow:smoke:%d", n),
+		Details:  map[string]string{"findings": strings.Join(lines, "\n")},
+	})
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	_ "time/tzdata" // --schedule-tz zones without a system zoneinfo
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+// stats writes cycle-N/stats.json, the recipient counts, totals and
+// per-token distribution of each merkle file, for reward-service and the
+// front-end to display without fetching the files. Commit it with them;
+// verify flags one the files no longer match.
+//
+//	stats --cycle-dir cycle-21 --schedule "0 14 * * 4/2" --schedule-tz UTC+7 --schedule-anchor 21@2026-10-08
+//
+// With a schedule the claim deadline is the release of cycle N+--claim-cycles,
+// when update-kyber-applications drops cycle N's URLs from values.yaml.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir    = flag.String("cycle-dir", "", "path to the cycle-N directory")
+		sched       = flag.String("schedule", os.Getenv("RELEASE_SCHEDULE"), "cron-like release schedule, e.g. \"0 14 * * 4/2\" (or env RELEASE_SCHEDULE)")
+		schedTZ     = flag.String("schedule-tz", os.Getenv("RELEASE_TZ"), "time zone of --schedule: IANA name or offset like UTC+7; default UTC (or env RELEASE_TZ)")
+		schedAnchor = flag.String("schedule-anchor", os.Getenv("RELEASE_ANCHOR"), "a known release as <cycle>@<date>[T<hh:mm>] (or env RELEASE_ANCHOR)")
+		claimCycles = flag.Int("claim-cycles", 2, "releases a cycle stays claimable for")
+		deadlineArg = flag.String("claim-deadline", "", "RFC 3339 claim deadline, instead of deriving it from --schedule")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "stats"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *cycleDir == "" {
+		fatal(errors.New("missing --cycle-dir"))
+	}
+
+	var deadline time.Time
+	if *deadlineArg != "" {
+		var err error
+		if deadline, err = time.Parse(time.RFC3339, *deadlineArg); err != nil {
+			fatal(fmt.Errorf("--claim-deadline: %w", err))
+		}
+	}
+	releases, err := schedule.FromSpec(*sched, *schedTZ, *schedAnchor)
+	if err != nil {
+		fatal(err)
+	}
+
+	s, err := summary.BuildStats(*cycleDir, time.Time{})
+	if err != nil {
+		fatal(err)
+	}
+	if deadline.IsZero() && releases != nil {
+		if deadline, err = releases.Release(s.Cycle + *claimCycles); err != nil {
+			fatal(err)
+		}
+	}
+	if !deadline.IsZero() {
+		s.ClaimDeadline = deadline.UTC()
+	}
+	if err := summary.WriteStats(*cycleDir, s); err != nil {
+		fatal(err)
+	}
+	due := "no claim deadline"
+	if !deadline.IsZero() {
+		due = "claimable until " + deadline.Format(time.RFC3339)
+	}
+	fmt.Printf("Wrote %s: %d files, %d recipients, %s\n", filepath.Join(*cycleDir, cycle.StatsName), len(s.Files), s.Recipients, due)
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
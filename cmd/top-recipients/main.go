@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/safe"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+type recipient struct {
+	Position string            `json:"position"`
+	Amounts  map[string]string `json:"amounts"`
+	// ShareBps is the largest share of any token's total, in basis points.
+	ShareBps int64      `json:"shareBps"`
+	Holder   string     `json:"holder,omitempty"`
+	Safe     *safe.Info `json:"safe,omitempty"`
+}
+
+// top-recipients lists the largest positions of a merkle file for compliance
+// review. With --owners it also reads who holds each position NFT and, when
+// the holder is a Safe, its owners and threshold.
+//
+//	top-recipients --file cycle-21/56_LM_21.json --top 20 --owners
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		filePath      = flag.String("file", "", "merkle file (e.g. cycle-21/56_LM_21.json)")
+		top           = flag.Int("top", 20, "number of recipients to list")
+		owners        = flag.Bool("owners", false, "read each position's holder on-chain and expand Safe owners/threshold")
+		chainsPath    = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		block         = flag.String("block", evm.DefaultBlockTag, "block tag for on-chain reads: finalized, safe, latest or a number")
+		confirmations = flag.Uint64("confirmations", 0, "read this many blocks behind --block")
+		jsonOut       = flag.String("json-out", "", "also write the list as JSON")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "top-recipients"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	if *filePath == "" {
+		fatal(errors.New("missing --file"))
+	}
+
+	f, err := cycle.Load(*filePath)
+	if err != nil {
+		fatal(err)
+	}
+	totals, err := f.SumAmounts()
+	if err != nil {
+		fatal(err)
+	}
+	list := make([]recipient, 0, len(f.UserDatas))
+	for _, ud := range f.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			fatal(err)
+		}
+		r := recipient{Position: ud.Leaf.Key(), Amounts: make(map[string]string, len(am))}
+		for t, a := range am {
+			r.Amounts[t] = a.String()
+			if tot := totals[t]; tot.Sign() > 0 {
+				bps := new(big.Int).Mul(a, big.NewInt(10_000))
+				r.ShareBps = max(r.ShareBps, bps.Quo(bps, tot).Int64())
+			}
+		}
+		list = append(list, r)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ShareBps > list[j].ShareBps })
+	list = list[:min(*top, len(list))]
+
+	var ch chains.Chain
+	if *owners && !offline.Skip("holders and Safe owners of the positions (--owners)") {
+		n, ok := cycle.ParseName(filepath.Base(*filePath))
+		if !ok {
+			fatal(fmt.Errorf("cannot tell the chain of %s from its name", *filePath))
+		}
+		reg, err := chains.Load(*chainsPath)
+		if err != nil {
+			fatal(fmt.Errorf("load chain registry: %w", err))
+		}
+		if ch, err = reg.Get(n.ChainID); err != nil {
+			fatal(err)
+		}
+		if len(ch.Endpoints()) == 0 {
+			fatal(fmt.Errorf("chain %s has no rpc configured", n.ChainID))
+		}
+		if err := resolveOwners(context.Background(), evm.NewClient(ch.Endpoints()...), list, *block, *confirmations); err != nil {
+			fatal(err)
+		}
+	}
+
+	for i, r := range list {
+		fmt.Printf("%3d. %s  %d.%02d%%  %s\n", i+1, r.Position, r.ShareBps/100, r.ShareBps%100, amounts(r.Amounts))
+		if r.Holder == "" {
+			continue
+		}
+		if r.Safe == nil {
+			fmt.Printf("     holder %s%s\n", r.Holder, link(ch.AddressURL(r.Holder)))
+			continue
+		}
+		fmt.Printf("     holder %s is a Safe, %d of %d%s\n", r.Holder, r.Safe.Threshold, len(r.Safe.Owners), link(ch.AddressURL(r.Holder)))
+		for _, o := range r.Safe.Owners {
+			fmt.Printf("       owner %s%s\n", o, link(ch.AddressURL(o)))
+		}
+	}
+
+	if *jsonOut != "" {
+		b, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(*jsonOut, append(b, '\n'), 0o644); err != nil {
+			fatal(fmt.Errorf("write %s: %w", *jsonOut, err))
+		}
+		runsummary.Output(*jsonOut)
+	}
+}
+
+// resolveOwners fills Holder and Safe for every recipient, reading at one
+// pinned block.
+func resolveOwners(ctx context.Context, c *evm.Client, list []recipient, tag string, confirmations uint64) error {
+	n, err := c.PinBlock(ctx, tag, confirmations)
+	if err != nil {
+		return err
+	}
+	block := evm.BlockParam(n)
+	fmt.Printf("holders read at block %d (%s)\n", n, tag)
+	for i := range list {
+		nft, idStr, _ := strings.Cut(list[i].Position, ":")
+		id, err := cycle.ParseAmount(idStr)
+		if err != nil {
+			return err
+		}
+		holder, err := c.OwnerOf(ctx, nft, id, block)
+		if err != nil {
+			return fmt.Errorf("position %s: ownerOf: %w", list[i].Position, err)
+		}
+		list[i].Holder = holder
+		if list[i].Safe, err = safe.Inspect(ctx, c, holder, block); err != nil {
+			return fmt.Errorf("position %s: %w", list[i].Position, err)
+		}
+	}
+	return nil
+}
+
+func amounts(m map[string]string) string {
+	parts := make([]string, 0, len(m))
+	for t, a := range m {
+		parts = append(parts, t+"="+a)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// link formats an optional explorer URL as a suffix.
+func link(u string) string {
+	if u == "" {
+		return ""
+	}
+	return "  " + u
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
@@ -4,9 +4,19 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"regexp"
-	"strconv"
+	"path/filepath"
 	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/approval"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/embargo"
+	"github.com/KyberNetwork/fairflow-reward/hosting"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/maintenance"
+	"github.com/KyberNetwork/fairflow-reward/policy"
+	"github.com/KyberNetwork/fairflow-reward/profile"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
 )
 
 type pair struct {
@@ -14,93 +24,229 @@ type pair struct {
 	RewardType string
 }
 
+// update-kyber-applications points reward-service's values.yaml at a new
+// cycle: each merkle URL of the previous cycle becomes the new cycle's, and
+// the one before becomes the previous. Files of --cycle-dir that are not
+// merkle files are skipped with a warning, or fail the run with --strict;
+// --scan-config excludes the ones that belong there.
+//
+// --rollback undoes that bump for --cycle-dir's cycle N: N's URLs go back to
+// N-1's and N-1's to N-2's. It is the policy's rollback operation and needs
+// no approval, which was given for the release it takes back.
+//
+// With --maintenance it first sets a `maintenance: true` key (in
+// --maintenance-file, a ConfigMap or values file, default --values) so
+// reward-service stops serving while the URL set rolls out; once the rollout
+// is verified, --end-maintenance sets it back to false and changes nothing
+// else.
 func main() {
+	defer tmpdir.Cleanup()
 	var (
 		valuesPath = flag.String("values", "", "path to core/reward-service/api/public/values.yaml")
 		cycleDir   = flag.String("cycle-dir", "", "path to cycle-N directory")
-		rawPrefix  = flag.String("raw-prefix", "https://raw.githubusercontent.com/KyberNetwork/fairflow-reward/refs/heads/main", "raw github prefix")
+		hostConfig = flag.String("hosting", hosting.DefaultConfigPath, "per-chain/type URL templates of the merkle files (default raw GitHub)")
+		proposal   = flag.String("proposal", "", "approved release proposal (default release-proposal-<cycle>.json)")
+		approvers  = flag.String("approvers", approval.DefaultConfigPath, "approvers JSON; two-person approval is on when it requires 2+ signatures")
+		maint      = flag.Bool("maintenance", false, "set the maintenance key to true before swapping URLs")
+		endMaint   = flag.Bool("end-maintenance", false, "only set the maintenance key back to false, after the rollout is verified")
+		maintFile  = flag.String("maintenance-file", "", "values or ConfigMap YAML holding the maintenance key (default --values)")
+		maintKey   = flag.String("maintenance-key", maintenance.DefaultKey, "name of the maintenance key")
+		scanConfig = flag.String("scan-config", cycle.DefaultScanConfigPath, "include/exclude patterns for the files of --cycle-dir")
+		strict     = flag.Bool("strict", false, "fail on files in --cycle-dir that are not merkle files or their sidecars (also strict in --scan-config)")
+		rollback   = flag.Bool("rollback", false, "point values.yaml back from --cycle-dir's cycle to the one before")
 	)
+	prof := profile.AddFlags(flag.CommandLine)
+	gate := policy.AddFlags(flag.CommandLine)
+	prog := tenant.AddFlags(flag.CommandLine)
 	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "update-kyber-applications"); err != nil {
+		die(err)
+	}
+	active, err := prof.Apply(flag.CommandLine, "update-kyber-applications")
+	if err != nil {
+		die(err)
+	}
+	if *maintFile == "" {
+		*maintFile = *valuesPath
+	}
+	if *endMaint {
+		if *maintFile == "" {
+			die(fmt.Errorf("missing --values or --maintenance-file"))
+		}
+		if err := setMaintenance(*maintFile, *maintKey, false); err != nil {
+			die(err)
+		}
+		return
+	}
 	if *valuesPath == "" || *cycleDir == "" {
 		die(fmt.Errorf("missing --values or --cycle-dir"))
 	}
 
-	pairs := make(map[pair]struct{})
-	cycleNum := 0
-	re := regexp.MustCompile(`^([0-9]+)_([A-Za-z]+)_([0-9]+)\.json$`)
-	entries, err := os.ReadDir(*cycleDir)
+	if err := embargo.CheckOpen(*cycleDir); err != nil {
+		die(err)
+	}
+
+	scan, err := cycle.LoadScanConfig(*scanConfig)
 	if err != nil {
 		die(err)
 	}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		m := re.FindStringSubmatch(name)
-		if len(m) == 0 {
-			continue
-		}
-		chainID := m[1]
-		rewardType := strings.ToUpper(m[2])
-		cn, err := strconv.Atoi(m[3])
-		if err != nil {
-			die(fmt.Errorf("invalid cycle in filename %q: %w", name, err))
-		}
+	scan.Strict = scan.Strict || *strict
+	entries, unknown, err := scan.Scan(*cycleDir)
+	if err != nil {
+		die(err)
+	}
+	for _, name := range unknown {
+		runsummary.Warnf("skipping %s: not a merkle file (--strict to fail)", name)
+	}
+	pairs := make(map[pair]struct{})
+	cycleNum := 0
+	for _, e := range entries {
 		if cycleNum == 0 {
-			cycleNum = cn
-		} else if cycleNum != cn {
+			cycleNum = e.Cycle
+		} else if cycleNum != e.Cycle {
 			die(fmt.Errorf("multiple cycle numbers found in %s", *cycleDir))
 		}
-		pairs[pair{ChainID: chainID, RewardType: rewardType}] = struct{}{}
+		pairs[pair{ChainID: e.ChainID, RewardType: e.RewardType}] = struct{}{}
 	}
 	if cycleNum == 0 || len(pairs) == 0 {
 		die(fmt.Errorf("no matching merkle files found in %s", *cycleDir))
 	}
-	if cycleNum < 2 {
+	if cycleNum < 2 || *rollback && cycleNum < 3 {
 		die(fmt.Errorf("cycle too small: %d", cycleNum))
 	}
+	op := policy.OpPublish
+	if *rollback {
+		op = policy.OpRollback
+	}
+	if err := gate.Check(op, active.ID(), cycleNum); err != nil {
+		die(err)
+	}
+	acfg, err := approval.LoadConfig(*approvers)
+	if err != nil {
+		die(err)
+	}
+	if acfg.Enabled() && !*rollback {
+		if *proposal == "" {
+			*proposal = approval.DefaultProposalPath(cycleNum)
+		}
+		p, err := approval.Load(*proposal)
+		if err != nil {
+			die(fmt.Errorf("two-person approval is required: %w", err))
+		}
+		if err := p.Check(acfg, *cycleDir); err != nil {
+			die(err)
+		}
+	}
+
+	if *maint {
+		if err := setMaintenance(*maintFile, *maintKey, true); err != nil {
+			die(err)
+		}
+	}
 
 	vb, err := os.ReadFile(*valuesPath)
 	if err != nil {
 		die(err)
 	}
 	orig := string(vb)
-	updated := orig
-	changed := false
 
 	newC := cycleNum
 	prevC := newC - 1
 	oldC := newC - 2
 
-	// replace exact URL substrings.
-	for p := range pairs {
-		prevURL := fmt.Sprintf("%s/cycle-%d/%s_%s_%d.json", *rawPrefix, prevC, p.ChainID, p.RewardType, prevC)
-		newURL := fmt.Sprintf("%s/cycle-%d/%s_%s_%d.json", *rawPrefix, newC, p.ChainID, p.RewardType, newC)
-		oldURL := fmt.Sprintf("%s/cycle-%d/%s_%s_%d.json", *rawPrefix, oldC, p.ChainID, p.RewardType, oldC)
+	// A file split by the shard command is served as one URL per shard, so a
+	// values line naming it is repeated once per shard; this suits URL lists.
+	host, err := hosting.LoadConfig(*hostConfig)
+	if err != nil {
+		die(err)
+	}
+	repoRoot := filepath.Dir(filepath.Clean(*cycleDir))
+	urls := func(c int, p pair) []string {
+		out, err := host.URLs(repoRoot, cycle.Name{ChainID: p.ChainID, RewardType: p.RewardType, Cycle: c})
+		if err != nil {
+			die(err)
+		}
+		return out
+	}
+	// rewrite maps a line naming from onto one line per URL in to, and drops
+	// lines naming the other shards of from, which the first one replaces.
+	rewrite := func(lines []string, from, to []string) []string {
+		out := make([]string, 0, len(lines))
+		for _, l := range lines {
+			switch {
+			case strings.Contains(l, from[0]):
+				for _, u := range to {
+					out = append(out, strings.ReplaceAll(l, from[0], u))
+				}
+			case containsAny(l, from[1:]):
+			default:
+				out = append(out, l)
+			}
+		}
+		return out
+	}
 
-		if strings.Contains(updated, prevURL) {
-			updated = strings.ReplaceAll(updated, prevURL, newURL)
-			changed = true
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(orig, "\n") {
+		lines := []string{line}
+		for p := range pairs {
+			prevURLs := urls(prevC, p)
+			if *rollback {
+				lines = rewrite(lines, prevURLs, urls(oldC, p))
+				lines = rewrite(lines, urls(newC, p), prevURLs)
+			} else {
+				lines = rewrite(lines, prevURLs, urls(newC, p))
+				lines = rewrite(lines, urls(oldC, p), prevURLs)
+			}
 		}
-		if strings.Contains(updated, oldURL) {
-			updated = strings.ReplaceAll(updated, oldURL, prevURL)
-			changed = true
+		for _, l := range lines {
+			b.WriteString(l)
 		}
 	}
+	updated := b.String()
+	changed := updated != orig
 
 	if !changed {
 		fmt.Println("No changes made to values.yaml (nothing matched).")
+		if *maint {
+			fmt.Println("Maintenance is still on; run with --end-maintenance to turn it off.")
+		}
 		return
 	}
 
 	if err := os.WriteFile(*valuesPath, []byte(updated), 0o644); err != nil {
 		die(err)
 	}
+	runsummary.Output(*valuesPath)
 	fmt.Println("Updated values.yaml via URL string replacement only.")
 }
 
+// setMaintenance sets key to on in the YAML file at path.
+func setMaintenance(path, key string, on bool) error {
+	changed, err := maintenance.Set(path, key, on)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Printf("%s: %s already %t.\n", path, key, on)
+		return nil
+	}
+	runsummary.Output(path)
+	fmt.Printf("%s: set %s to %t.\n", path, key, on)
+	return nil
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
 func die(err error) {
+	runsummary.Fail(err)
 	fmt.Fprintln(os.Stderr, "ERROR:", err)
-	os.Exit(1)
+	tmpdir.Exit(1)
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// vendor-abi exports the verified ABI of a chain's distributors from an
+// Etherscan-compatible explorer API into the distributor package's bundle
+// and names it as the chain's "abi" in the registry:
+//
+//	vendor-abi --chain 56 --api-key $ETHERSCAN_API_KEY
+//
+// A proxy is followed to its implementation. Every distributor of the chain
+// must have the same functions, and the ABI must declare the functions the
+// distributor package calls; otherwise nothing is written. The bundle is
+// embedded, so commands pick the ABI up once rebuilt.
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		chainsPath = flag.String("chains", chains.DefaultPath, "chain registry JSON")
+		chainID    = flag.String("chain", "", "chain ID whose distributors' ABI to vendor")
+		name       = flag.String("name", "", "ABI name in the bundle (default <chain>-distributor)")
+		dir        = flag.String("dir", "distributor/abi", "the distributor package's ABI directory")
+		api        = flag.String("api", "https://api.etherscan.io/v2/api", "Etherscan-compatible API serving module=contract&action=getsourcecode")
+		apiKey     = flag.String("api-key", os.Getenv("ETHERSCAN_API_KEY"), "explorer API key (or env ETHERSCAN_API_KEY)")
+		timeout    = flag.Duration("timeout", 30*time.Second, "timeout for each explorer request")
+		dryRun     = flag.Bool("dry-run", false, "check the ABI without writing it or the registry")
+	)
+	flag.Parse()
+	if *chainID == "" {
+		fatal(errors.New("missing --chain"))
+	}
+	if *name == "" {
+		*name = *chainID + "-distributor"
+	}
+	reg, err := chains.Load(*chainsPath)
+	if err != nil {
+		fatal(fmt.Errorf("load chain registry: %w", err))
+	}
+	ch, err := reg.Get(*chainID)
+	if err != nil {
+		fatal(err)
+	}
+	addrs := make([]string, 0, len(ch.Distributors))
+	for _, a := range ch.Distributors {
+		if a = strings.ToLower(a); !slices.Contains(addrs, a) {
+			addrs = append(addrs, a)
+		}
+	}
+	slices.Sort(addrs)
+	if len(addrs) == 0 {
+		fatal(fmt.Errorf("chain %s has no distributors", *chainID))
+	}
+
+	ex := &explorer{api: *api, key: *apiKey, chainID: *chainID, client: &http.Client{Timeout: *timeout}}
+	var raw []byte
+	var sigs []string
+	for _, a := range addrs {
+		b, err := ex.abi(context.Background(), a)
+		if err != nil {
+			fatal(fmt.Errorf("distributor %s: %w", a, err))
+		}
+		abi, err := distributor.Parse(*name, b)
+		if err != nil {
+			fatal(fmt.Errorf("distributor %s: %w", a, err))
+		}
+		s := functionSigs(abi)
+		if sigs == nil {
+			raw, sigs = b, s
+		} else if !slices.Equal(s, sigs) {
+			fatal(fmt.Errorf("distributors %s and %s of chain %s declare different functions; they cannot share one abi", addrs[0], a, *chainID))
+		}
+		fmt.Printf("distributor %s: verified ABI declares %d function(s), the bound ones included\n", a, len(s))
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		fatal(fmt.Errorf("explorer ABI: %w", err))
+	}
+	out.WriteByte('\n')
+	path := filepath.Join(*dir, *name+".json")
+	if *dryRun {
+		fmt.Printf("would write %s and set chain %s's abi to %s\n", path, *chainID, *name)
+		return
+	}
+	if err := tmpdir.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		fatal(err)
+	}
+	runsummary.Output(path)
+	ch.ABI = *name
+	reg[*chainID] = ch
+	if err := reg.Write(*chainsPath); err != nil {
+		fatal(err)
+	}
+	runsummary.Output(*chainsPath)
+	fmt.Printf("Wrote %s; chain %s now uses abi %s\n", path, *chainID, *name)
+}
+
+// explorer reads verified contracts from an Etherscan-compatible API.
+type explorer struct {
+	api, key, chainID string
+	client            *http.Client
+}
+
+type source struct {
+	ABI            string `json:"ABI"`
+	ContractName   string `json:"ContractName"`
+	Proxy          string `json:"Proxy"`
+	Implementation string `json:"Implementation"`
+}
+
+// abi returns the verified ABI of addr, or of its implementation if addr
+// is a proxy.
+func (e *explorer) abi(ctx context.Context, addr string) ([]byte, error) {
+	s, err := e.source(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if impl := s.Implementation; s.Proxy == "1" && impl != "" {
+		if s, err = e.source(ctx, impl); err != nil {
+			return nil, fmt.Errorf("implementation %s: %w", impl, err)
+		}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(s.ABI), "[") {
+		return nil, fmt.Errorf("no verified ABI: %s", s.ABI)
+	}
+	return []byte(s.ABI), nil
+}
+
+func (e *explorer) source(ctx context.Context, addr string) (*source, error) {
+	q := url.Values{"chainid": {e.chainID}, "module": {"contract"}, "action": {"getsourcecode"}, "address": {addr}}
+	if e.key != "" {
+		q.Set("apikey", e.key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.api+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("explorer API: %s", resp.Status)
+	}
+	var body struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("explorer API: %w", err)
+	}
+	var res []source
+	if body.Status != "1" || json.Unmarshal(body.Result, &res) != nil || len(res) == 0 {
+		return nil, fmt.Errorf("explorer API: %s: %s", body.Message, body.Result)
+	}
+	return &res[0], nil
+}
+
+// functionSigs lists the signatures a declares, sorted.
+func functionSigs(a *distributor.ABI) []string {
+	out := make([]string, 0, len(a.Functions))
+	for _, f := range a.Functions {
+		out = append(out, f.Sig())
+	}
+	slices.Sort(out)
+	return out
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
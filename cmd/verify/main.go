@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/offline"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/metrics"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+func main() {
+	defer tmpdir.Cleanup()
+	var (
+		cycleDir    = flag.String("cycle-dir", "", "verify every merkle file in this cycle-N directory")
+		all         = flag.Bool("all", false, "verify every cycle-N directory under --root (verify-all)")
+		root        = flag.String("root", ".", "repo root for --all")
+		disable     = flag.String("disable", "", "comma-separated rule IDs to skip")
+		jsonOut     = flag.Bool("json", false, "print findings as JSON")
+		sarifOut    = flag.String("sarif", "", "also write findings as SARIF to this file, for PR annotations")
+		list        = flag.Bool("list-rules", false, "list active rules and exit")
+		parallelism = flag.Int("parallelism", 0, "goroutines for hashing (0 = one per CPU)")
+		statePath   = flag.String("state", state.DefaultPath, "state DB holding cached verification results")
+		noCache     = flag.Bool("no-cache", false, "re-verify every file, ignoring and not updating the cache")
+		strict      = flag.Bool("strict", false, "every warning is an error and unknown JSON fields are rejected (the default, or env VERIFY_MODE)")
+		permissive  = flag.Bool("permissive", false, "report every finding as a warning, for ad-hoc analysis")
+		configPath  = flag.String("config", verify.DefaultConfigPath, "per-mode rule overrides JSON")
+	)
+	prog := tenant.AddFlags(flag.CommandLine)
+	off := offline.AddFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := prog.Apply(flag.CommandLine, "verify"); err != nil {
+		fatal(err)
+	}
+	off.Apply()
+	par.SetWorkers(*parallelism)
+	tracing.Init("fairflow-verify")
+	ctx, span := tracing.Start(context.Background(), "verify")
+	defer tracing.Flush()
+	metrics.Init("fairflow-verify")
+	defer metrics.Push(nil)
+
+	disabled, err := verify.ParseRuleList(*disable)
+	if err != nil {
+		fatal(err)
+	}
+	mode := verify.ModeStrict
+	switch {
+	case *strict && *permissive:
+		fatal(errors.New("--strict and --permissive are exclusive"))
+	case *permissive:
+		mode = verify.ModePermissive
+	case !*strict && os.Getenv("VERIFY_MODE") != "":
+		if mode, err = verify.ParseMode(os.Getenv("VERIFY_MODE")); err != nil {
+			fatal(err)
+		}
+	}
+	cfg, err := verify.LoadConfig(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+	opt, err := cfg.Options(mode, disabled)
+	if err != nil {
+		fatal(err)
+	}
+	if *list {
+		fmt.Printf("Mode: %s\n", mode)
+		for _, r := range append(verify.AllRules(), verify.DirRules()...) {
+			status := "enabled"
+			if opt.Disabled[r.ID()] {
+				status = "disabled"
+			}
+			fmt.Printf("%-18s %-8s %-8s %s\n", r.ID(), opt.Severity(r.ID(), r.Severity()), status, r.Description())
+		}
+		return
+	}
+
+	paths := flag.Args()
+	dirs := make([]string, 0)
+	if *cycleDir != "" {
+		dirs = append(dirs, *cycleDir)
+	}
+	if *all {
+		cycles, err := cycle.Cycles(*root)
+		if err != nil {
+			fatal(err)
+		}
+		for _, n := range cycles {
+			dirs = append(dirs, filepath.Join(*root, cycle.DirName(n)))
+		}
+	}
+	findings := make([]verify.Finding, 0)
+	// checkDir runs directory rule id unless it is disabled.
+	checkDir := func(id, file string, check func() error) {
+		if opt.Disabled[id] {
+			return
+		}
+		if err := check(); err != nil {
+			findings = append(findings, opt.DirFinding(id, file, err.Error()))
+		}
+	}
+	for _, d := range dirs {
+		entries, err := cycle.ScanDir(d)
+		if err != nil {
+			fatal(err)
+		}
+		m, err := cycle.LoadManifest(d)
+		if err != nil {
+			fatal(err)
+		}
+		for _, e := range entries {
+			paths = append(paths, e.Path)
+			if want := m.Files[e.String()].SHA256; want != "" {
+				checkDir("manifest", e.Path, func() error {
+					if got, err := verify.Digest(e.Path); err != nil || got != want {
+						return fmt.Errorf("sha256 %s differs from %s in %s", got, want, cycle.ManifestName)
+					}
+					return nil
+				})
+			}
+			if u := filepath.Join(d, e.Name.UniswapName()); fileExists(u) {
+				paths = append(paths, u)
+			}
+			patches, err := cycle.Patches(d, e.Name)
+			if err != nil {
+				fatal(err)
+			}
+			paths = append(paths, patches...)
+			checkDir("shards", e.Path, func() error { return cycle.VerifyShards(d, e.Name) })
+			checkDir("bloom", e.Path, func() error { return cycle.CheckBloom(d, e.Name) })
+		}
+		checkDir("stats", filepath.Join(d, cycle.StatsName), func() error { return summary.CheckStats(d) })
+	}
+	if len(paths) == 0 {
+		fatal(errors.New("no files to verify (pass --cycle-dir, --all or file paths)"))
+	}
+	var (
+		db    *state.DB
+		cache *verify.Cache
+	)
+	if !*noCache {
+		if db, err = state.Open(*statePath); err != nil {
+			fatal(err)
+		}
+		cache = verify.NewCache(db)
+	}
+	hits := 0
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil {
+			metrics.Add(metrics.Bytes, fi.Size())
+		}
+		var digest string
+		if cache != nil {
+			if digest, err = verify.Digest(p); err != nil {
+				fatal(err)
+			}
+			if fs, ok := cache.Lookup(digest, filepath.Base(p), opt); ok {
+				findings = append(findings, fs...)
+				hits++
+				continue
+			}
+		}
+		fctx, fspan := tracing.Start(ctx, "verify.file", "file", filepath.Base(p))
+		// The rules run while p is mapped, so known-fields reads the mapped
+		// JSON rather than a second copy of the file.
+		var fs []verify.Finding
+		_, lspan := tracing.Start(fctx, "load")
+		err := cycle.MapFile(p, func(b []byte) error {
+			raw := b
+			if opt.Disabled["known-fields"] {
+				raw = nil
+			}
+			if cycle.IsUniswapPath(p) {
+				c, err := cycle.ParseUniswapClaims(b)
+				lspan.End(err)
+				if err != nil {
+					return err
+				}
+				fs = verify.RunUniswap(p, c, raw, opt)
+				return nil
+			}
+			f, err := cycle.Parse(b)
+			lspan.End(err)
+			if err != nil {
+				return err
+			}
+			if f.Format == cycle.FormatUniswap {
+				fs = verify.RunUniswap(p, f.Claims, raw, opt)
+				return nil
+			}
+			_, tspan := tracing.Start(fctx, "parse", "recipients", len(f.UserDatas))
+			target := verify.NewTarget(p, f)
+			target.Raw = raw
+			tspan.End(target.TreeErr)
+			fs = verify.RunContext(fctx, target, opt)
+			return nil
+		})
+		if err != nil {
+			fatal(fmt.Errorf("load %s: %w", p, err))
+		}
+		fspan.SetAttrs("findings", len(fs))
+		fspan.End(nil)
+		findings = append(findings, fs...)
+		if cache != nil {
+			if err := cache.Store(digest, opt, fs); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if db != nil {
+		if err := db.Save(); err != nil {
+			fatal(err)
+		}
+	}
+	if err := linkNotion(findings, paths); err != nil {
+		fatal(err)
+	}
+
+	if *sarifOut != "" {
+		if err := verify.WriteSARIF(*sarifOut, findings, paths); err != nil {
+			fatal(err)
+		}
+		runsummary.Output(*sarifOut)
+	}
+	if *jsonOut {
+		b, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+		fmt.Printf("Verified %d files (%d cached): %d findings\n", len(paths), hits, len(findings))
+	}
+	span.SetAttrs("files", len(paths), "cached", hits, "findings", len(findings))
+	metrics.Add(metrics.Files, int64(len(paths)))
+	for _, f := range findings {
+		if f.Severity == verify.SeverityError {
+			metrics.Add(metrics.Failures, 1)
+		}
+	}
+	if verify.HasErrors(findings) {
+		span.End(errors.New("verification failed"))
+		tracing.Flush()
+		runsummary.Fail(errors.New("verification failed"))
+		metrics.Push(errors.New("verification failed"))
+		tmpdir.Exit(1)
+	}
+	span.End(nil)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func fatal(err error) {
+	runsummary.Fail(err)
+	tracing.Flush()
+	metrics.Push(err)
+	fmt.Fprintln(os.Stderr, "ERROR:", err.Error())
+	tmpdir.Exit(1)
+}
+
+// linkNotion points each finding at the Notion row its file was synced from,
+// and its owner, as recorded in the manifest of the file's cycle directory.
+func linkNotion(findings []verify.Finding, paths []string) error {
+	dirs := make(map[string]string, len(paths))
+	for _, p := range paths {
+		dirs[filepath.Base(p)] = filepath.Dir(p)
+	}
+	manifests := make(map[string]*cycle.Manifest)
+	for i := range findings {
+		name := filepath.Base(findings[i].File)
+		dir, ok := dirs[name]
+		if !ok {
+			continue
+		}
+		m, ok := manifests[dir]
+		if !ok {
+			var err error
+			if m, err = cycle.LoadManifest(dir); err != nil {
+				return fmt.Errorf("%s: %w", dir, err)
+			}
+			manifests[dir] = m
+		}
+		findings[i].Notion = m.Files[name].NotionURL()
+		findings[i].Owner = m.Files[name].Owner
+	}
+	return nil
+}
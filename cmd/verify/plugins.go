@@ -0,0 +1,6 @@
+package main
+
+// Additional lint rules are compiled in by blank-importing their packages
+// here; each package registers its rules with verify.Register from init.
+//
+//	import _ "example.com/team/verifyrules/multisig"
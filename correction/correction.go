@@ -0,0 +1,279 @@
+// Package correction turns post-hoc fixes to a published merkle file — a
+// reward reported to the wrong wallet, an amount that came out short — into
+// a supplementary merkle file paying the difference, since a published root
+// cannot change. The fixes come as CSV:
+//
+//	recipient,new_recipient,token,amount,reason
+//	0xold...,0xnew...,,,wrong wallet reported       # moves all of 0xold's rewards
+//	0xold...:7,0xnew...,0xtoken...,100,partial move # moves 100 of one token
+//	0xacct...,,0xtoken...,250,missed volume         # pays 250 more
+//
+// A recipient is an erc721Addr, with :erc721Id when it is not 0.
+package correction
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+type Correction struct {
+	Line int `json:"line"`
+	// From is the recipient key (erc721Addr:erc721Id) the row is about.
+	From string `json:"from"`
+	// To is where From's rewards go instead; empty for an adjustment.
+	To string `json:"to,omitempty"`
+	// Token is empty to move all of From's tokens.
+	Token string `json:"token,omitempty"`
+	// Amount is base units, empty to move all of From's amount of Token.
+	Amount string `json:"amount,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (c Correction) amount() *big.Int {
+	if c.Amount == "" {
+		return nil
+	}
+	a, _ := new(big.Int).SetString(c.Amount, 10)
+	return a
+}
+
+// LoadCSV reads corrections from path. Only the recipient column is
+// required in the header.
+func LoadCSV(path string) ([]Correction, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	cr := csv.NewReader(fh)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: read header: %w", path, err)
+	}
+	idx := make(map[string]int, len(header))
+	for j, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = j
+	}
+	if _, ok := idx["recipient"]; !ok {
+		return nil, fmt.Errorf("%s: missing %q column", path, "recipient")
+	}
+	out := make([]Correction, 0)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(col string) string {
+			if j, ok := idx[col]; ok && j < len(rec) {
+				return strings.TrimSpace(rec[j])
+			}
+			return ""
+		}
+		c, err := parse(line, get)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		out = append(out, c)
+	}
+}
+
+func parse(line int, get func(string) string) (Correction, error) {
+	c := Correction{Line: line, Reason: get("reason")}
+	var err error
+	if c.From, err = recipientKey(get("recipient")); err != nil {
+		return c, err
+	}
+	if to := get("new_recipient"); to != "" {
+		if c.To, err = recipientKey(to); err != nil {
+			return c, err
+		}
+		if c.To == c.From {
+			return c, errors.New("new_recipient is the recipient")
+		}
+	}
+	if t := get("token"); t != "" {
+		if !evm.IsAddress(t) {
+			return c, fmt.Errorf("invalid token %q", t)
+		}
+		c.Token = strings.ToLower(t)
+	}
+	if a := get("amount"); a != "" {
+		amount, ok := new(big.Int).SetString(a, 10)
+		if !ok || amount.Sign() <= 0 {
+			return c, fmt.Errorf("amount %q: want a positive integer of base units (a published amount cannot be taken back)", a)
+		}
+		c.Amount = amount.String()
+		if c.Token == "" {
+			return c, errors.New("an amount needs a token")
+		}
+	}
+	if c.To == "" && c.Amount == "" {
+		return c, errors.New("need new_recipient, or token and amount")
+	}
+	return c, nil
+}
+
+// recipientKey reads "addr" or "addr:id" as a cycle.Leaf key.
+func recipientKey(s string) (string, error) {
+	addr, id, ok := strings.Cut(s, ":")
+	if !ok {
+		id = "0"
+	}
+	if !evm.IsAddress(addr) {
+		return "", fmt.Errorf("invalid recipient %q", s)
+	}
+	if _, ok := new(big.Int).SetString(id, 10); !ok {
+		return "", fmt.Errorf("invalid erc721Id in recipient %q", s)
+	}
+	return strings.ToLower(addr) + ":" + id, nil
+}
+
+// Chain is the correction history of a merkle file, kept as the metadata of
+// each patch: what it corrects, the patch before it, and its own rows.
+type Chain struct {
+	Base        string       `json:"base"`
+	BaseRoot    string       `json:"baseRoot"`
+	Patch       int          `json:"patch"`
+	Previous    string       `json:"previous,omitempty"`
+	PrevRoot    string       `json:"previousRoot,omitempty"`
+	Corrections []Correction `json:"corrections"`
+}
+
+// ParseChain reads the Chain a patch file carries in its metadata.
+func ParseChain(f *cycle.File) (*Chain, error) {
+	var c Chain
+	if err := json.Unmarshal([]byte(f.Metadata), &c); err != nil || c.Base == "" {
+		return nil, errors.New("metadata is not a correction chain")
+	}
+	return &c, nil
+}
+
+// Apply builds the supplementary file for cs against base: a redirect pays
+// the new recipient what base paid the old one (all of it, or Amount of
+// Token), an adjustment pays the recipient Amount more. Redirects cannot
+// move more than base paid, less what the prior patches' rows moved. The
+// file keeps base's timestamps, salt and scheme; its metadata is chain with
+// cs filled in.
+func Apply(base *cycle.File, prior, cs []Correction, chain Chain) (*cycle.File, error) {
+	if len(cs) == 0 {
+		return nil, errors.New("no corrections")
+	}
+	if err := cycle.CheckLeafEncoding(base); err != nil {
+		return nil, err
+	}
+	paid := make(map[string]map[string]*big.Int, len(base.UserDatas))
+	for _, ud := range base.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			return nil, err
+		}
+		paid[ud.Leaf.Key()] = am
+	}
+	delta := make(map[string]map[string]*big.Int)
+	add := func(key, token string, a *big.Int) {
+		if delta[key] == nil {
+			delta[key] = make(map[string]*big.Int)
+		}
+		if delta[key][token] == nil {
+			delta[key][token] = new(big.Int)
+		}
+		delta[key][token].Add(delta[key][token], a)
+	}
+	// Prior rows are replayed only to use up what they moved.
+	for i, c := range append(append([]Correction(nil), prior...), cs...) {
+		pay := add
+		if i < len(prior) {
+			pay = func(string, string, *big.Int) {}
+		}
+		if c.To == "" {
+			pay(c.From, c.Token, c.amount())
+			continue
+		}
+		left, ok := paid[c.From]
+		if !ok {
+			return nil, fmt.Errorf("line %d: %s is not paid in %s", c.Line, c.From, chain.Base)
+		}
+		moved := false
+		for _, t := range sortedTokens(left) {
+			if c.Token != "" && t != c.Token {
+				continue
+			}
+			a := left[t]
+			if want := c.amount(); want != nil {
+				if want.Cmp(a) > 0 {
+					return nil, fmt.Errorf("line %d: moves %s of %s but only %s is left to move from %s", c.Line, want, t, a, c.From)
+				}
+				a = want
+			}
+			if a.Sign() == 0 {
+				continue
+			}
+			pay(c.To, t, a)
+			left[t] = new(big.Int).Sub(left[t], a)
+			moved = true
+		}
+		if !moved {
+			return nil, fmt.Errorf("line %d: nothing of %s left to move from %s", c.Line, tokenLabel(c.Token), c.From)
+		}
+	}
+
+	keys := make([]string, 0, len(delta))
+	for k := range delta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	chain.Corrections = cs
+	meta, err := json.Marshal(chain)
+	if err != nil {
+		return nil, err
+	}
+	f := &cycle.File{
+		StartTimestamp: base.StartTimestamp,
+		EndTimestamp:   base.EndTimestamp,
+		Metadata:       string(meta),
+		Salt:           base.Salt,
+		HashScheme:     base.HashScheme,
+		UserDatas:      make([]cycle.UserData, 0, len(keys)),
+	}
+	for _, k := range keys {
+		addr, id, _ := strings.Cut(k, ":")
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(addr, id, delta[k])})
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func sortedTokens(m map[string]*big.Int) []string {
+	out := make([]string, 0, len(m))
+	for t := range m {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func tokenLabel(t string) string {
+	if t == "" {
+		return "any token"
+	}
+	return t
+}
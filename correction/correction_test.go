@@ -0,0 +1,181 @@
+package correction_test
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/correction"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+const (
+	nft    = "0x00000000000000000000000000000000000000a1"
+	newNFT = "0x00000000000000000000000000000000000000a2"
+	tokA   = "0x00000000000000000000000000000000000000b1"
+	tokB   = "0x00000000000000000000000000000000000000b2"
+)
+
+// base pays nft:1 100 A and 40 B, and nft:2 50 A.
+func base(t *testing.T) *cycle.File {
+	t.Helper()
+	f := &cycle.File{StartTimestamp: "100", EndTimestamp: "200", Salt: "0x" + strings.Repeat("0", 64)}
+	for _, l := range []cycle.Leaf{
+		cycle.NewLeaf(nft, "1", map[string]*big.Int{tokA: big.NewInt(100), tokB: big.NewInt(40)}),
+		cycle.NewLeaf(nft, "2", map[string]*big.Int{tokA: big.NewInt(50)}),
+	} {
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: l})
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func load(t *testing.T, rows ...string) ([]correction.Correction, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "corrections.csv")
+	src := "recipient,new_recipient,token,amount,reason\n" + strings.Join(rows, "\n") + "\n"
+	if err := os.WriteFile(p, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return correction.LoadCSV(p)
+}
+
+// paid flattens f to "key token amount" lines, sorted by key and token.
+func paid(t *testing.T, f *cycle.File) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	for _, ud := range f.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for tok, a := range am {
+			out[ud.Leaf.Key()+" "+tok] = a.String()
+		}
+	}
+	return out
+}
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name  string
+		prior []string
+		rows  []string
+		want  map[string]string
+		err   string
+	}{
+		{
+			name: "move everything",
+			rows: []string{nft + ":1," + newNFT + ",,,wrong wallet"},
+			want: map[string]string{newNFT + ":0 " + tokA: "100", newNFT + ":0 " + tokB: "40"},
+		},
+		{
+			name: "partial move and adjustment sum on one position",
+			rows: []string{
+				nft + ":1," + newNFT + "," + tokA + ",30,partial",
+				newNFT + ",," + tokA + ",5,missed volume",
+				nft + ":2,," + tokB + ",7,missed volume",
+			},
+			want: map[string]string{newNFT + ":0 " + tokA: "35", nft + ":2 " + tokB: "7"},
+		},
+		{
+			name: "two moves use up one position",
+			rows: []string{
+				nft + ":2," + newNFT + "," + tokA + ",30,",
+				nft + ":2," + newNFT + "," + tokA + ",20,",
+			},
+			want: map[string]string{newNFT + ":0 " + tokA: "50"},
+		},
+		{
+			name: "more than is left",
+			rows: []string{nft + ":2," + newNFT + "," + tokA + ",30,", nft + ":2," + newNFT + "," + tokA + ",21,"},
+			err:  "only 20 is left",
+		},
+		{
+			name:  "prior patches moved it already",
+			prior: []string{nft + ":2," + newNFT + ",,,"},
+			rows:  []string{nft + ":2," + newNFT + "," + tokA + ",1,"},
+			err:   "only 0 is left",
+		},
+		{
+			name: "token the recipient was not paid",
+			rows: []string{nft + ":2," + newNFT + "," + tokB + ",,"},
+			err:  "nothing of " + tokB,
+		},
+		{
+			name: "recipient not in the file",
+			rows: []string{nft + ":9," + newNFT + ",,,"},
+			err:  "is not paid",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prior, err := load(t, c.prior...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c.prior) == 0 {
+				prior = nil
+			}
+			cs, err := load(t, c.rows...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := correction.Apply(base(t), prior, cs, correction.Chain{Base: "56_LM_3.json", Patch: 1})
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("got %v, want %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := paid(t, f)
+			if len(got) != len(c.want) {
+				t.Errorf("paid %v, want %v", got, c.want)
+			}
+			for k, v := range c.want {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+			totals, err := f.SumAmounts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for tok, a := range totals {
+				if f.TotalAmounts[tok] != a.String() {
+					t.Errorf("total %s = %s, leaves sum to %s", tok, f.TotalAmounts[tok], a)
+				}
+			}
+			chain, err := correction.ParseChain(f)
+			if err != nil || len(chain.Corrections) != len(cs) {
+				t.Errorf("metadata chain %+v, %v", chain, err)
+			}
+		})
+	}
+}
+
+// A published amount cannot be taken back, so a correction only adds.
+func TestLoadCSVRejects(t *testing.T) {
+	for row, want := range map[string]string{
+		nft + ",," + tokA + ",0,zero":       "positive integer",
+		nft + ",," + tokA + ",-5,negative":  "positive integer",
+		nft + ",," + tokA + ",1.5,fraction": "positive integer",
+		nft + ",,,10,amount without token":  "needs a token",
+		nft + ",,,,nothing to do":           "need new_recipient",
+		nft + "," + nft + ":0,,,to itself":  "is the recipient",
+		nft + ":x," + newNFT + ",,,bad id":  "invalid erc721Id",
+		nft + ",," + "0xb1,10,bad token":    "invalid token",
+	} {
+		_, err := load(t, row)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", row, err, want)
+		}
+	}
+}
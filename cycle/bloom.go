@@ -0,0 +1,141 @@
+package cycle
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// BloomScheme names how a Bloom sidecar is probed, so the front-end can
+// answer "not eligible" without fetching the file: with h = keccak256 of the
+// lowercased "<erc721Addr>:<id>" and h1, h2 the big-endian uint64s in h[0:8]
+// and h[8:16], a position sets bits (h1 + i*h2) mod M for i in [0, K), bit 0
+// being the most significant bit of Bits[0].
+const BloomScheme = "keccak256(position) double-hash"
+
+// DefaultBloomFPRate is the false-positive rate sidecars are sized for.
+const DefaultBloomFPRate = 0.01
+
+// Bloom is the filter written next to a merkle file as <name>.bloom.json.
+// A miss means the position has no leaf; a hit still needs a proof lookup.
+type Bloom struct {
+	Source string `json:"source"`
+	Root   string `json:"root"`
+	Scheme string `json:"scheme"`
+	Count  int    `json:"count"`
+	M      uint64 `json:"m"`
+	K      int    `json:"k"`
+	// Bits is base64 in JSON.
+	Bits []byte `json:"bits"`
+}
+
+// BloomName is "56_LM_21.bloom.json" for 56_LM_21.json.
+func (n Name) BloomName() string {
+	return strings.TrimSuffix(n.String(), ".json") + ".bloom.json"
+}
+
+// NewBloom builds the filter over every position of f, the file named n,
+// sized for fpRate.
+func NewBloom(n Name, f *File, fpRate float64) (*Bloom, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("false-positive rate %v is not in (0, 1)", fpRate)
+	}
+	count := max(len(f.UserDatas), 1)
+	m := uint64(math.Ceil(-float64(count) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) &^ 7
+	b := &Bloom{
+		Source: n.String(),
+		Root:   f.Root,
+		Scheme: BloomScheme,
+		Count:  len(f.UserDatas),
+		M:      m,
+		K:      max(1, int(math.Round(float64(m)/float64(count)*math.Ln2))),
+		Bits:   make([]byte, m/8),
+	}
+	for _, ud := range f.UserDatas {
+		b.add(ud.Leaf.Key())
+	}
+	return b, nil
+}
+
+func bloomHashes(key string) (h1, h2 uint64) {
+	h := keccak.Sum256([]byte(strings.ToLower(key)))
+	return binary.BigEndian.Uint64(h[0:8]), binary.BigEndian.Uint64(h[8:16])
+}
+
+func (b *Bloom) add(key string) {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < b.K; i++ {
+		bit := (h1 + uint64(i)*h2) % b.M
+		b.Bits[bit/8] |= 0x80 >> (bit % 8)
+	}
+}
+
+// Has reports whether position key may have a leaf.
+func (b *Bloom) Has(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < b.K; i++ {
+		bit := (h1 + uint64(i)*h2) % b.M
+		if b.Bits[bit/8]&(0x80>>(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadBloom reads the sidecar for n in dir; it returns nil if there is none.
+func LoadBloom(dir string, n Name) (*Bloom, error) {
+	b, err := os.ReadFile(filepath.Join(dir, n.BloomName()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bf Bloom
+	if err := json.Unmarshal(b, &bf); err != nil {
+		return nil, fmt.Errorf("%s: %w", n.BloomName(), err)
+	}
+	if bf.M == 0 || uint64(len(bf.Bits))*8 != bf.M {
+		return nil, fmt.Errorf("%s: %d bytes of bits for m=%d", n.BloomName(), len(bf.Bits), bf.M)
+	}
+	return &bf, nil
+}
+
+// CheckBloom reports a sidecar for the file named n in dir that no longer
+// matches it, e.g. left behind when the file was rebuilt.
+func CheckBloom(dir string, n Name) error {
+	b, err := LoadBloom(dir, n)
+	if err != nil || b == nil {
+		return err
+	}
+	f, err := Load(filepath.Join(dir, n.String()))
+	if err != nil {
+		return err
+	}
+	if b.Root != f.Root {
+		return fmt.Errorf("%s: built for root %s, file root is %s", n.BloomName(), b.Root, f.Root)
+	}
+	for _, ud := range f.UserDatas {
+		if !b.Has(ud.Leaf.Key()) {
+			return fmt.Errorf("%s: position %s is missing", n.BloomName(), ud.Leaf.Key())
+		}
+	}
+	return nil
+}
+
+func (b *Bloom) Write(dir string, n Name) error {
+	out, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, n.BloomName())
+	return tmpdir.WriteFile(path, append(out, '\n'), 0o644)
+}
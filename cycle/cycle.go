@@ -0,0 +1,207 @@
+// Package cycle models the per-chain, per-type merkle files published into
+// cycle-N directories (e.g. cycle-12/56_LM_12.json).
+package cycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+type File struct {
+	StartTimestamp string            `json:"startTimestamp"`
+	EndTimestamp   string            `json:"endTimestamp"`
+	Metadata       string            `json:"metadata"`
+	Salt           string            `json:"salt"`
+	UserDatas      []UserData        `json:"userDatas"`
+	Tree           []string          `json:"tree"`
+	Root           string            `json:"root"`
+	TotalAmounts   map[string]string `json:"totalAmounts"`
+	// HashScheme names the merkle.Scheme of the tree; empty is the
+	// StandardMerkleTree every distributor so far uses.
+	HashScheme string `json:"hashScheme,omitempty"`
+
+	// Format is the layout the file was parsed from; Write always writes ours.
+	Format Format `json:"-"`
+	// Claims is the source of a FormatUniswap file.
+	Claims *UniswapClaims `json:"-"`
+}
+
+// Scheme returns the merkle hash scheme the file's tree is built with.
+func (f *File) Scheme() (merkle.Scheme, error) { return merkle.SchemeByName(f.HashScheme) }
+
+type UserData struct {
+	Leaf  Leaf     `json:"leaf"`
+	Proof []string `json:"proof"`
+}
+
+type Leaf struct {
+	ERC721Addr string   `json:"erc721Addr"`
+	ERC721ID   string   `json:"erc721Id"`
+	Tokens     []string `json:"tokens"`
+	Amounts    []string `json:"amounts"`
+}
+
+// Key identifies a recipient position across cycles.
+func (l Leaf) Key() string {
+	return strings.ToLower(l.ERC721Addr) + ":" + l.ERC721ID
+}
+
+func Load(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse reads a merkle file in any Format.
+func Parse(b []byte) (*File, error) {
+	format, err := DetectFormat(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse cycle file: %w", err)
+	}
+	switch format {
+	case FormatOZ:
+		var d OZDump
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("parse oz dump: %w", err)
+		}
+		f, err := FromOZDump(&d)
+		if err != nil {
+			return nil, err
+		}
+		f.Format = FormatOZ
+		return f, nil
+	case FormatUniswap:
+		var c UniswapClaims
+		if err := json.Unmarshal(b, &c); err != nil {
+			return nil, fmt.Errorf("parse uniswap claims: %w", err)
+		}
+		return c.File()
+	}
+	var f File
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse cycle file: %w", err)
+	}
+	f.Format = FormatV1
+	if f.HashScheme != "" {
+		f.Format = FormatV2
+	}
+	return &f, nil
+}
+
+func Marshal(f *File) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func Write(path string, f *File) error {
+	b, err := Marshal(f)
+	if err != nil {
+		return err
+	}
+	return tmpdir.WriteFile(path, b, 0o644)
+}
+
+// AmountsByToken returns the leaf amounts keyed by lowercased token address.
+func (l Leaf) AmountsByToken() (map[string]*big.Int, error) {
+	if len(l.Tokens) != len(l.Amounts) {
+		return nil, fmt.Errorf("leaf %s: %d tokens but %d amounts", l.Key(), len(l.Tokens), len(l.Amounts))
+	}
+	out := make(map[string]*big.Int, len(l.Tokens))
+	for i, t := range l.Tokens {
+		a, err := ParseAmount(l.Amounts[i])
+		if err != nil {
+			return nil, fmt.Errorf("leaf %s: %w", l.Key(), err)
+		}
+		t = strings.ToLower(t)
+		if prev, ok := out[t]; ok {
+			a.Add(a, prev)
+		}
+		out[t] = a
+	}
+	return out, nil
+}
+
+func ParseAmount(s string) (*big.Int, error) {
+	a, ok := new(big.Int).SetString(s, 10)
+	if !ok || a.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	if a.BitLen() > 256 {
+		return nil, fmt.Errorf("amount %q does not fit uint256", s)
+	}
+	return a, nil
+}
+
+// SumAmounts totals every leaf per token.
+func (f *File) SumAmounts() (map[string]*big.Int, error) {
+	out := make(map[string]*big.Int)
+	for _, ud := range f.UserDatas {
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			return nil, err
+		}
+		for t, a := range am {
+			if prev, ok := out[t]; ok {
+				prev.Add(prev, a)
+			} else {
+				out[t] = new(big.Int).Set(a)
+			}
+		}
+	}
+	return out, nil
+}
+
+var nameRe = regexp.MustCompile(`^([0-9]+)_([A-Za-z]+)_([0-9]+)\.json$`)
+
+type Name struct {
+	ChainID    string
+	RewardType string
+	Cycle      int
+}
+
+func (n Name) String() string {
+	return fmt.Sprintf("%s_%s_%d.json", n.ChainID, n.RewardType, n.Cycle)
+}
+
+func ParseName(name string) (Name, bool) {
+	m := nameRe.FindStringSubmatch(name)
+	if len(m) == 0 {
+		return Name{}, false
+	}
+	c, err := strconv.Atoi(m[3])
+	if err != nil {
+		return Name{}, false
+	}
+	return Name{ChainID: m[1], RewardType: strings.ToUpper(m[2]), Cycle: c}, true
+}
+
+// Validate checks that n is a merkle file name ParseName would accept, so
+// a chain ID or reward type taken from Notion or a config file cannot put a
+// path separator or ".." into the paths built from it.
+func (n Name) Validate() error {
+	if _, ok := ParseName(n.String()); !ok || n.Cycle <= 0 {
+		return fmt.Errorf("invalid merkle file name: chain %q, type %q, cycle %d", n.ChainID, n.RewardType, n.Cycle)
+	}
+	return nil
+}
+
+func DirName(cycle int) string {
+	return fmt.Sprintf("cycle-%d", cycle)
+}
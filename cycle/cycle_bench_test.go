@@ -0,0 +1,50 @@
+package cycle_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+)
+
+func BenchmarkParse(b *testing.B) {
+	for _, n := range fixtures.BenchSizes() {
+		b.Run(fmt.Sprintf("recipients=%d", n), func(b *testing.B) {
+			f, err := fixtures.Cached(n)
+			if err != nil {
+				b.Fatal(err)
+			}
+			data, err := cycle.Marshal(f)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := cycle.Parse(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRebuild(b *testing.B) {
+	for _, n := range fixtures.BenchSizes() {
+		b.Run(fmt.Sprintf("recipients=%d", n), func(b *testing.B) {
+			f, err := fixtures.Cached(n)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cp := *f
+				cp.UserDatas = append([]cycle.UserData(nil), f.UserDatas...)
+				if err := cycle.Rebuild(&cp); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
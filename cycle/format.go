@@ -0,0 +1,117 @@
+package cycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Format is the on-disk layout a merkle file was parsed from. Parse detects
+// it and normalizes every format into a File, so a cycle directory can mix
+// them while distributors migrate.
+type Format string
+
+const (
+	// FormatV1 is our file with a StandardMerkleTree and no hashScheme.
+	FormatV1 Format = "v1"
+	// FormatV2 is our file naming its merkle.Scheme in hashScheme.
+	FormatV2 Format = "v2"
+	// FormatOZ is a StandardMerkleTree dump; see OZDump.
+	FormatOZ Format = "oz"
+	// FormatUniswap is a merkle-distributor claims file; see UniswapClaims.
+	FormatUniswap Format = "uniswap"
+)
+
+// DetectFormat sniffs which format b is in from its top-level keys. The
+// first key usually decides; only an unfamiliar first key costs a full scan.
+func DetectFormat(b []byte) (Format, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", errors.New("merkle file is not a JSON object")
+	}
+	if tok, err := dec.Token(); err == nil {
+		if f, ok := formatOfKey(tok); ok {
+			return f, nil
+		}
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return "", err
+	}
+	for _, k := range []string{"userDatas", "leafEncoding", "values", "claims", "merkleRoot"} {
+		if _, ok := keys[k]; ok {
+			f, _ := formatOfKey(k)
+			return f, nil
+		}
+	}
+	return "", errors.New("merkle file is in no known format")
+}
+
+func formatOfKey(tok json.Token) (Format, bool) {
+	switch tok {
+	case "format", "leafEncoding", "values":
+		return FormatOZ, true
+	case "merkleRoot", "tokenTotal", "claims":
+		return FormatUniswap, true
+	case "startTimestamp", "endTimestamp", "metadata", "salt", "userDatas", "root", "totalAmounts", "hashScheme":
+		// Parse tells v2 from v1 once hashScheme is read.
+		return FormatV1, true
+	}
+	return "", false
+}
+
+// CheckFields reports the first JSON field of b, in whichever format it is,
+// that the format does not define. Parse ignores such fields.
+func CheckFields(b []byte) error {
+	format, err := DetectFormat(b)
+	if err != nil {
+		return err
+	}
+	var v any
+	switch format {
+	case FormatOZ:
+		v = &OZDump{}
+	case FormatUniswap:
+		v = &UniswapClaims{}
+	default:
+		v = &File{}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%s file: %w", format, err)
+	}
+	return nil
+}
+
+// File normalizes a claims file. Its leaves are per account (erc721Id 0)
+// paying Token, with the claims' own proofs and merkleRoot; it has no flat
+// tree, and Claims keeps the source for the checks that need indices.
+func (c *UniswapClaims) File() (*File, error) {
+	if c.Token == "" {
+		return nil, errors.New("uniswap claims name no token; add \"token\" to the file")
+	}
+	accounts := make([]string, 0, len(c.Claims))
+	for a := range c.Claims {
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return c.Claims[accounts[i]].Index < c.Claims[accounts[j]].Index })
+	f := &File{Root: c.MerkleRoot, Format: FormatUniswap, Claims: c, UserDatas: make([]UserData, 0, len(accounts))}
+	total := new(big.Int)
+	for _, a := range accounts {
+		cl := c.Claims[a]
+		amount, err := ParseUniswapAmount(cl.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: %w", a, err)
+		}
+		total.Add(total, amount)
+		l := NewLeaf(a, "0", map[string]*big.Int{c.Token: amount})
+		f.UserDatas = append(f.UserDatas, UserData{Leaf: l, Proof: cl.Proof})
+	}
+	f.TotalAmounts = map[string]string{strings.ToLower(c.Token): total.String()}
+	return f, nil
+}
@@ -0,0 +1,99 @@
+package cycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+)
+
+// ManifestName is the per-cycle-directory record of what was synced.
+const ManifestName = "manifest.json"
+
+// StatsName is the per-cycle-directory figures the stats command writes for
+// reward-service and the front-end to display.
+const StatsName = "stats.json"
+
+type ManifestEntry struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	PageID string `json:"pageId,omitempty"`
+	// Owner is who the Notion row assigns the file to, when notion-sync
+	// ran with --prop-owner.
+	Owner string `json:"owner,omitempty"`
+	// Edited is the Notion row's last_edited_time and Uploaded when its
+	// attachment was stored, as of the sync.
+	Edited   time.Time `json:"edited,omitzero"`
+	Uploaded time.Time `json:"uploaded,omitzero"`
+	// Root is the file's merkle root, as backfill recorded it.
+	Root string `json:"root,omitempty"`
+	// Backfilled marks an entry backfill wrote for a file that predates
+	// notion-sync, so it has no Notion row.
+	Backfilled bool `json:"backfilled,omitempty"`
+	// Archived marks a file whose Notion row was archived after it was
+	// synced: the file is the one from that sync, and nothing in Notion
+	// backs it any more.
+	Archived bool `json:"archived,omitempty"`
+}
+
+// NotionURL links the Notion row the file was synced from, or is "".
+func (e ManifestEntry) NotionURL() string {
+	if e.PageID == "" {
+		return ""
+	}
+	return notion.PageURL(e.PageID)
+}
+
+// Label names file in errors and reports, with its Notion row and owner when
+// the manifest has them: "56_LM_21.json (https://www.notion.so/…, owner Ann)".
+func (m *Manifest) Label(file string) string {
+	e := m.Files[file]
+	extra := make([]string, 0, 2)
+	if u := e.NotionURL(); u != "" {
+		extra = append(extra, u)
+	}
+	if e.Owner != "" {
+		extra = append(extra, "owner "+e.Owner)
+	}
+	if len(extra) == 0 {
+		return file
+	}
+	return file + " (" + strings.Join(extra, ", ") + ")"
+}
+
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads dir/manifest.json; a missing manifest is empty.
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]ManifestEntry)}
+	b, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestName, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+	return m, nil
+}
+
+func (m *Manifest) Write(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, ManifestName)
+	return tmpdir.WriteFile(path, append(b, '\n'), 0o644)
+}
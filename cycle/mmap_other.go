@@ -0,0 +1,19 @@
+//go:build !unix
+
+package cycle
+
+import "os"
+
+// LoadMapped falls back to a plain read where mmap is unavailable.
+func LoadMapped(path string) (*File, error) {
+	return Load(path)
+}
+
+// MapFile falls back to calling fn with a plain read of path.
+func MapFile(path string, fn func(b []byte) error) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return fn(b)
+}
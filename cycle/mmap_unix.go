@@ -0,0 +1,51 @@
+//go:build unix
+
+package cycle
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LoadMapped parses path straight from a read-only memory mapping, so the
+// raw JSON never has to be copied onto the heap. Decoded values do not
+// reference the mapping, which is released before returning.
+func LoadMapped(path string) (*File, error) {
+	var f *File
+	err := MapFile(path, func(b []byte) (err error) {
+		f, err = Parse(b)
+		return err
+	})
+	return f, err
+}
+
+// MapFile calls fn with the contents of path, mapped read-only. b is only
+// valid until fn returns: anything fn keeps must be copied out of it.
+func MapFile(path string, fn func(b []byte) error) error {
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	st, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	if st.Size() == 0 {
+		return fmt.Errorf("%s: empty file", path)
+	}
+	if int64(int(st.Size())) != st.Size() {
+		return fmt.Errorf("%s: too large to map", path)
+	}
+	data, err := syscall.Mmap(int(fh.Fd()), 0, int(st.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	}
+	defer syscall.Munmap(data)
+	return fn(data)
+}
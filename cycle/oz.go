@@ -0,0 +1,250 @@
+package cycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+// OZFormat is the dump format @openzeppelin/merkle-tree writes from
+// StandardMerkleTree.dump().
+const OZFormat = "standard-v1"
+
+// OZLeafEncoding is the only leaf encoding a cycle file can hold.
+var OZLeafEncoding = []string{"address", "uint256", "address[]", "uint256[]"}
+
+// OZDump is a StandardMerkleTree dump: the flat tree and each leaf's values
+// with the tree index of its hash.
+type OZDump struct {
+	Format       string    `json:"format"`
+	LeafEncoding []string  `json:"leafEncoding"`
+	Tree         []string  `json:"tree"`
+	Values       []OZValue `json:"values"`
+}
+
+type OZValue struct {
+	Value     []json.RawMessage `json:"value"`
+	TreeIndex int               `json:"treeIndex"`
+}
+
+func LoadOZDump(path string) (*OZDump, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d OZDump
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+func WriteOZDump(path string, d *OZDump) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// FromOZDump converts a dump into a cycle file, keeping the dump's tree
+// layout. Every value must hash to the node at its treeIndex, unless none
+// does: then the tree was hashed with another leaf encoding (see
+// CheckLeafEncoding) and treeIndex alone places each value, as it does when
+// StandardMerkleTree loads the dump. The timestamps, metadata and salt are
+// not part of a dump and are left for the caller.
+func FromOZDump(d *OZDump) (*File, error) {
+	if d.Format != OZFormat {
+		return nil, fmt.Errorf("oz dump: unsupported format %q (want %s)", d.Format, OZFormat)
+	}
+	if strings.Join(d.LeafEncoding, ",") != strings.Join(OZLeafEncoding, ",") {
+		return nil, fmt.Errorf("oz dump: leaf encoding %v is not the cycle leaf %v", d.LeafEncoding, OZLeafEncoding)
+	}
+	f := &File{Tree: d.Tree, UserDatas: make([]UserData, len(d.Values))}
+	t, err := f.ParseTree()
+	if err != nil {
+		return nil, fmt.Errorf("oz dump: %w", err)
+	}
+	if len(d.Values) != t.LeafCount() {
+		return nil, fmt.Errorf("oz dump: %d values for %d leaves", len(d.Values), t.LeafCount())
+	}
+	nodes := t.Nodes()
+	seen := make(map[int]bool, len(d.Values))
+	hashed := make([]bool, len(d.Values))
+	matched := 0
+	for i, v := range d.Values {
+		l, err := ozLeaf(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("oz dump: values[%d]: %w", i, err)
+		}
+		if v.TreeIndex < len(nodes)/2 || v.TreeIndex >= len(nodes) {
+			return nil, fmt.Errorf("oz dump: values[%d]: tree[%d] is not a leaf", i, v.TreeIndex)
+		}
+		if seen[v.TreeIndex] {
+			return nil, fmt.Errorf("oz dump: values[%d] repeats tree[%d]", i, v.TreeIndex)
+		}
+		seen[v.TreeIndex] = true
+		h, err := LeafHash(l)
+		if err != nil {
+			return nil, fmt.Errorf("oz dump: values[%d]: %w", i, err)
+		}
+		if hashed[i] = nodes[v.TreeIndex] == h; hashed[i] {
+			matched++
+		}
+		p, err := t.Proof(nodes[v.TreeIndex])
+		if err != nil {
+			return nil, err
+		}
+		f.UserDatas[i] = UserData{Leaf: l, Proof: hexHashes(p)}
+	}
+	if matched > 0 && matched < len(d.Values) {
+		for i, ok := range hashed {
+			if !ok {
+				return nil, fmt.Errorf("oz dump: values[%d] does not hash to tree[%d]", i, d.Values[i].TreeIndex)
+			}
+		}
+	}
+	f.Root = t.Root().Hex()
+	totals, err := f.SumAmounts()
+	if err != nil {
+		return nil, err
+	}
+	f.TotalAmounts = make(map[string]string, len(totals))
+	for tok, a := range totals {
+		f.TotalAmounts[tok] = a.String()
+	}
+	return f, nil
+}
+
+func ozLeaf(v []json.RawMessage) (Leaf, error) {
+	if len(v) != len(OZLeafEncoding) {
+		return Leaf{}, fmt.Errorf("want %d values, got %d", len(OZLeafEncoding), len(v))
+	}
+	var (
+		l   Leaf
+		err error
+	)
+	if l.ERC721Addr, err = ozString(v[0]); err != nil {
+		return l, err
+	}
+	if l.ERC721ID, err = ozString(v[1]); err != nil {
+		return l, err
+	}
+	var tokens, amounts []json.RawMessage
+	if err := json.Unmarshal(v[2], &tokens); err != nil {
+		return l, fmt.Errorf("tokens: %w", err)
+	}
+	if err := json.Unmarshal(v[3], &amounts); err != nil {
+		return l, fmt.Errorf("amounts: %w", err)
+	}
+	for _, t := range tokens {
+		s, err := ozString(t)
+		if err != nil {
+			return l, err
+		}
+		l.Tokens = append(l.Tokens, strings.ToLower(s))
+	}
+	for _, a := range amounts {
+		s, err := ozString(a)
+		if err != nil {
+			return l, err
+		}
+		l.Amounts = append(l.Amounts, s)
+	}
+	l.ERC721Addr = strings.ToLower(l.ERC721Addr)
+	return l, nil
+}
+
+// ozString reads a value the JS library may have written as a string or, for
+// small integers, a bare number.
+func ozString(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var n json.Number
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&n); err != nil {
+		return "", fmt.Errorf("invalid value %s", raw)
+	}
+	return n.String(), nil
+}
+
+// OZDump converts the file into a StandardMerkleTree dump with the same tree.
+// A file whose leaves do not hash with LeafHash (see CheckLeafEncoding) is
+// placed in the tree by its proofs instead; StandardMerkleTree then loads its
+// tree and serves its proofs, but its validate() and verify() reject it.
+func (f *File) OZDump() (*OZDump, error) {
+	scheme, err := f.Scheme()
+	if err != nil {
+		return nil, err
+	}
+	if scheme.Name != merkle.Standard.Name {
+		return nil, fmt.Errorf("oz dump: the %s hash scheme cannot be loaded by StandardMerkleTree", scheme.Name)
+	}
+	t, err := f.ParseTree()
+	if err != nil {
+		return nil, err
+	}
+	encErr := CheckLeafEncoding(f)
+	byProof := errors.Is(encErr, ErrLeafEncoding)
+	if encErr != nil && !byProof {
+		return nil, fmt.Errorf("oz dump: %w", encErr)
+	}
+	nodes := t.Nodes()
+	index := make(map[merkle.Hash]int, t.LeafCount())
+	for i := len(nodes) / 2; i < len(nodes); i++ {
+		index[nodes[i]] = i
+	}
+	seen := make(map[int]string, len(f.UserDatas))
+	d := &OZDump{Format: OZFormat, LeafEncoding: OZLeafEncoding, Tree: f.Tree, Values: make([]OZValue, len(f.UserDatas))}
+	for i, ud := range f.UserDatas {
+		var h merkle.Hash
+		if byProof {
+			p, err := ParseProof(ud.Proof)
+			if err != nil {
+				return nil, fmt.Errorf("leaf %s: %w", ud.Leaf.Key(), err)
+			}
+			leaf, ok := t.LeafForProof(p)
+			if !ok || !scheme.Verify(t.Root(), leaf, p) {
+				return nil, fmt.Errorf("leaf %s: its proof does not lead from a leaf of the tree", ud.Leaf.Key())
+			}
+			h = leaf
+		} else if h, err = LeafHash(ud.Leaf); err != nil {
+			return nil, err
+		}
+		ti, ok := index[h]
+		if !ok {
+			return nil, fmt.Errorf("leaf %s is not in the tree", ud.Leaf.Key())
+		}
+		if prev, dup := seen[ti]; dup {
+			return nil, fmt.Errorf("leaf %s is at tree[%d] with %s", ud.Leaf.Key(), ti, prev)
+		}
+		seen[ti] = ud.Leaf.Key()
+		v := []any{ud.Leaf.ERC721Addr, ud.Leaf.ERC721ID, nonNil(ud.Leaf.Tokens), nonNil(ud.Leaf.Amounts)}
+		d.Values[i].TreeIndex = ti
+		d.Values[i].Value = make([]json.RawMessage, len(v))
+		for j, x := range v {
+			if d.Values[i].Value[j], err = json.Marshal(x); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d, nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package cycle_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+)
+
+func ozRoundTrip(t *testing.T, f *cycle.File) *cycle.File {
+	t.Helper()
+	d, err := f.OZDump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back cycle.OZDump
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	g, err := cycle.FromOZDump(&back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestOZRoundTrip(t *testing.T) {
+	files := map[string]*cycle.File{}
+	for _, p := range []string{"../56_LM_12.json", "../56_EG_12.json"} {
+		f, err := cycle.Load(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[p] = f
+	}
+	fx, err := fixtures.Generate(fixtures.Options{Recipients: 30, Tokens: 2, Seed: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["fixture"] = fx
+
+	for name, f := range files {
+		g := ozRoundTrip(t, f)
+		if g.Root != f.Root || !reflect.DeepEqual(g.Tree, f.Tree) || !reflect.DeepEqual(g.TotalAmounts, f.TotalAmounts) {
+			t.Errorf("%s: root, tree or totals changed", name)
+		}
+		if !reflect.DeepEqual(g.UserDatas, f.UserDatas) {
+			t.Errorf("%s: userDatas changed", name)
+		}
+	}
+}
+
+func TestFromOZDumpTampered(t *testing.T) {
+	f, err := fixtures.Generate(fixtures.Options{Recipients: 10, Seed: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := f.OZDump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Values[4].Value[3] = json.RawMessage(`["1"]`)
+	if _, err := cycle.FromOZDump(d); err == nil || !strings.Contains(err.Error(), "values[4]") {
+		t.Errorf("tampered value: got %v", err)
+	}
+}
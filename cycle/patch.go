@@ -0,0 +1,45 @@
+package cycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchName is "56_LM_21.patch-2.json" for the second supplementary file
+// patch-cycle made for 56_LM_21.json. A patch is a merkle file of its own,
+// published beside the one it corrects.
+func (n Name) PatchName(i int) string {
+	return fmt.Sprintf("%s.patch-%d.json", strings.TrimSuffix(n.String(), ".json"), i)
+}
+
+var patchRe = regexp.MustCompile(`\.patch-([0-9]+)\.json$`)
+
+// Patches lists the patch files of n in dir, in order.
+func Patches(dir string, n Name) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	nums := make(map[string]int)
+	out := make([]string, 0)
+	for _, e := range entries {
+		m := patchRe.FindStringSubmatch(e.Name())
+		if m == nil || e.Name() != n.PatchName(atoi(m[1])) {
+			continue
+		}
+		nums[e.Name()] = atoi(m[1])
+		out = append(out, filepath.Join(dir, e.Name()))
+	}
+	sort.Slice(out, func(i, j int) bool { return nums[filepath.Base(out[i])] < nums[filepath.Base(out[j])] })
+	return out, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
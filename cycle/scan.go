@@ -0,0 +1,160 @@
+package cycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var dirRe = regexp.MustCompile(`^cycle-([0-9]+)$`)
+
+type Entry struct {
+	Name
+	Path string
+}
+
+// ScanDir lists the merkle files in a single cycle-N directory.
+func ScanDir(dir string) ([]Entry, error) {
+	out, _, err := ScanConfig{}.Scan(dir)
+	return out, err
+}
+
+// DefaultScanConfigPath is where update-kyber-applications looks for its
+// ScanConfig.
+const DefaultScanConfigPath = "config/scan.json"
+
+// ScanConfig narrows which files of a cycle directory a scan looks at.
+// Patterns are filepath.Match globs against the file name, e.g. "README*".
+type ScanConfig struct {
+	// Include, when set, is the only files looked at.
+	Include []string `json:"include,omitempty"`
+	// Exclude is files never looked at; it wins over Include.
+	Exclude []string `json:"exclude,omitempty"`
+	// Strict makes any file looked at that is neither a merkle file nor
+	// one of the tooling's own files an error, so a typo such as
+	// 8453_LM_23.json.json is not silently left out.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// LoadScanConfig reads a ScanConfig; a missing file is the zero config,
+// which looks at everything and is not strict.
+func LoadScanConfig(path string) (ScanConfig, error) {
+	var c ScanConfig
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, p := range append(append([]string(nil), c.Include...), c.Exclude...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return c, fmt.Errorf("%s: pattern %q: %w", path, p, err)
+		}
+	}
+	return c, nil
+}
+
+func (c ScanConfig) looksAt(file string) bool {
+	for _, p := range c.Exclude {
+		if ok, _ := filepath.Match(p, file); ok {
+			return false
+		}
+	}
+	if len(c.Include) == 0 {
+		return true
+	}
+	for _, p := range c.Include {
+		if ok, _ := filepath.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Known reports whether file is a name the tooling gives files in a cycle
+// directory: a merkle file, one of its sidecars, shards or patches, the
+// manifest or the stats.
+func Known(file string) bool {
+	if file == ManifestName || file == StatsName {
+		return true
+	}
+	_, ok := Owner(file)
+	return ok
+}
+
+// Scan lists the merkle files of dir that c looks at, and the files it
+// looks at that are not Known. In strict mode any such file is an error.
+func (c ScanConfig) Scan(dir string) (entries []Entry, unknown []string, err error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries = make([]Entry, 0, len(des))
+	for _, e := range des {
+		if e.IsDir() || !c.looksAt(e.Name()) {
+			continue
+		}
+		n, ok := ParseName(e.Name())
+		if ok {
+			entries = append(entries, Entry{Name: n, Path: filepath.Join(dir, e.Name())})
+		} else if !Known(e.Name()) {
+			unknown = append(unknown, e.Name())
+		}
+	}
+	if c.Strict && len(unknown) > 0 {
+		return nil, unknown, fmt.Errorf("%s: unknown files %s (fix their names, or exclude them in the scan config)", dir, strings.Join(unknown, ", "))
+	}
+	return entries, unknown, nil
+}
+
+// Cycles lists the cycle numbers that have a cycle-N directory under root.
+func Cycles(root string) ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int, 0)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m := dirRe.FindStringSubmatch(e.Name())
+		if len(m) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		out = append(out, n)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// IsArtifactKey reports whether key is "cycle-N/<file>" for a merkle file,
+// shard, shard index, Bloom sidecar, manifest or stats of cycle N, the only
+// objects published per cycle.
+func IsArtifactKey(key string) bool {
+	dir, file, ok := strings.Cut(key, "/")
+	if !ok || !dirRe.MatchString(dir) {
+		return false
+	}
+	if file == ManifestName || file == StatsName {
+		return true
+	}
+	if n, ok := parseSidecar(file); ok {
+		return DirName(n.Cycle) == dir
+	}
+	n, ok := ParseName(file)
+	return ok && DirName(n.Cycle) == dir
+}
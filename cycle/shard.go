@@ -0,0 +1,383 @@
+package cycle
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+// ShardScheme names how positions map to shards, so clients can find theirs
+// without the index: the first two bytes of keccak256("<erc721Addr>:<id>")
+// (address lowercased), as a big-endian uint16, split into equal ranges.
+const ShardScheme = "keccak256(position)[0:2]"
+
+// MaxShards bounds the shard count to the two-byte prefix space.
+const MaxShards = 1 << 16
+
+// ShardIndex is written next to a sharded merkle file as <name>.shards.json.
+//
+// In a two-level index the shard roots are themselves the leaves of a master
+// tree, and the distributor stores only MasterRoot. Hashing is sorted-pair
+// throughout, so a leaf's shard proof followed by its shard's MasterProof is
+// an ordinary proof against MasterRoot.
+type ShardIndex struct {
+	Source string `json:"source"`
+	Root   string `json:"root"`
+	Scheme string `json:"scheme"`
+	// HashScheme is the source file's merkle hash scheme, also used for the
+	// master tree.
+	HashScheme string   `json:"hashScheme,omitempty"`
+	MasterRoot string   `json:"masterRoot,omitempty"`
+	MasterTree []string `json:"masterTree,omitempty"`
+	Shards     []Shard  `json:"shards"`
+}
+
+type Shard struct {
+	File       string `json:"file"`
+	Root       string `json:"root"`
+	Recipients int    `json:"recipients"`
+	// Prefixes is the inclusive range of position-hash prefixes in the shard.
+	Prefixes     [2]uint16         `json:"prefixes"`
+	TotalAmounts map[string]string `json:"totalAmounts"`
+	MasterProof  []string          `json:"masterProof,omitempty"`
+}
+
+// ShardIndexName is "56_LM_21.shards.json" for 56_LM_21.json.
+func (n Name) ShardIndexName() string {
+	return strings.TrimSuffix(n.String(), ".json") + ".shards.json"
+}
+
+// ShardName is "56_LM_21.shard-3-of-8.json" for shard 3 of 8.
+func (n Name) ShardName(i, count int) string {
+	return fmt.Sprintf("%s.shard-%d-of-%d.json", strings.TrimSuffix(n.String(), ".json"), i, count)
+}
+
+// ShardOf returns the shard of count holding position key.
+func ShardOf(key string, count int) int {
+	h := keccak.Sum256([]byte(strings.ToLower(key)))
+	return int(binary.BigEndian.Uint16(h[:2])) * count / MaxShards
+}
+
+func shardPrefixes(i, count int) [2]uint16 {
+	lo := (i*MaxShards + count - 1) / count
+	hi := ((i+1)*MaxShards+count-1)/count - 1
+	return [2]uint16{uint16(lo), uint16(hi)}
+}
+
+// Split distributes f's leaves over count shards by ShardOf and rebuilds
+// each shard as a standalone merkle file with its own root. An empty shard is
+// an error: the caller should pick fewer shards.
+func Split(f *File, count int) ([]*File, error) {
+	if count < 2 || count > MaxShards {
+		return nil, fmt.Errorf("shard count must be between 2 and %d", MaxShards)
+	}
+	if len(f.UserDatas) < count {
+		return nil, fmt.Errorf("%d recipients cannot fill %d shards", len(f.UserDatas), count)
+	}
+	if err := CheckLeafEncoding(f); err != nil {
+		return nil, err
+	}
+	out := make([]*File, count)
+	for i := range out {
+		out[i] = &File{StartTimestamp: f.StartTimestamp, EndTimestamp: f.EndTimestamp, Metadata: f.Metadata, Salt: f.Salt, HashScheme: f.HashScheme}
+	}
+	for _, ud := range f.UserDatas {
+		s := out[ShardOf(ud.Leaf.Key(), count)]
+		s.UserDatas = append(s.UserDatas, UserData{Leaf: ud.Leaf})
+	}
+	for i, s := range out {
+		if len(s.UserDatas) == 0 {
+			return nil, fmt.Errorf("shard %d of %d is empty; use fewer shards", i, count)
+		}
+		if err := Rebuild(s); err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// NewShardIndex describes shards split from the file named n with root.
+func NewShardIndex(n Name, root string, shards []*File) *ShardIndex {
+	idx := &ShardIndex{Source: n.String(), Root: root, Scheme: ShardScheme}
+	if len(shards) > 0 {
+		idx.HashScheme = shards[0].HashScheme
+	}
+	for i, s := range shards {
+		idx.Shards = append(idx.Shards, Shard{
+			File:         n.ShardName(i, len(shards)),
+			Root:         s.Root,
+			Recipients:   len(s.UserDatas),
+			Prefixes:     shardPrefixes(i, len(shards)),
+			TotalAmounts: s.TotalAmounts,
+		})
+	}
+	return idx
+}
+
+// BuildMaster makes idx two-level: it builds the master tree over the shard
+// roots and records each shard's proof into it.
+func (idx *ShardIndex) BuildMaster() error {
+	scheme, err := merkle.SchemeByName(idx.HashScheme)
+	if err != nil {
+		return err
+	}
+	roots := make([]merkle.Hash, len(idx.Shards))
+	for i, s := range idx.Shards {
+		h, err := merkle.ParseHash(s.Root)
+		if err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+		roots[i] = h
+	}
+	t, err := scheme.Build(roots)
+	if err != nil {
+		return err
+	}
+	for i := range idx.Shards {
+		p, err := t.Proof(roots[i])
+		if err != nil {
+			return err
+		}
+		idx.Shards[i].MasterProof = hexHashes(p)
+	}
+	idx.MasterTree = hexHashes(t.Nodes())
+	idx.MasterRoot = t.Root().Hex()
+	return nil
+}
+
+// TwoLevel reports whether idx has a master root.
+func (idx *ShardIndex) TwoLevel() bool { return idx.MasterRoot != "" }
+
+// Proof turns a proof within shard i into one against the root the
+// distributor stores: the master root for a two-level index, else the shard
+// root itself.
+func (idx *ShardIndex) Proof(i int, shardProof []string) []string {
+	if !idx.TwoLevel() {
+		return shardProof
+	}
+	return append(append([]string(nil), shardProof...), idx.Shards[i].MasterProof...)
+}
+
+// Lookup returns the shard holding position key.
+func (idx *ShardIndex) Lookup(key string) int {
+	return ShardOf(key, len(idx.Shards))
+}
+
+// FindProof returns position key's leaf in the file named n in dir with a
+// proof against root. For a sharded file only the position's shard is read;
+// in a two-level index the proof is stitched up to the master root.
+func FindProof(dir string, n Name, key string) (ud UserData, root string, err error) {
+	idx, err := LoadShardIndex(dir, n)
+	if err != nil {
+		return UserData{}, "", err
+	}
+	file, i := n.String(), 0
+	if idx != nil {
+		i = idx.Lookup(key)
+		file = idx.Shards[i].File
+	}
+	f, err := Load(filepath.Join(dir, file))
+	if err != nil {
+		return UserData{}, "", err
+	}
+	for _, u := range f.UserDatas {
+		if !strings.EqualFold(u.Leaf.Key(), key) {
+			continue
+		}
+		if idx == nil {
+			return u, f.Root, nil
+		}
+		u.Proof = idx.Proof(i, u.Proof)
+		if idx.TwoLevel() {
+			return u, idx.MasterRoot, nil
+		}
+		return u, f.Root, nil
+	}
+	return UserData{}, "", fmt.Errorf("position %s is not in %s", key, file)
+}
+
+// VerifyShards checks the shards of the file named n in dir against their
+// index and the source file: every shard's tree and root, that each position
+// sits in its ShardOf shard, that the shards together hold exactly the source
+// leaves, and for a two-level index the master tree and every master proof.
+func VerifyShards(dir string, n Name) error {
+	idx, err := LoadShardIndex(dir, n)
+	if err != nil || idx == nil {
+		return err
+	}
+	src, err := Load(filepath.Join(dir, n.String()))
+	if err != nil {
+		return err
+	}
+	if src.Root != idx.Root {
+		return fmt.Errorf("%s: index root %s does not match the source file root %s", n.ShardIndexName(), idx.Root, src.Root)
+	}
+	want := make(map[merkle.Hash]bool, len(src.UserDatas))
+	for _, ud := range src.UserDatas {
+		h, err := LeafHash(ud.Leaf)
+		if err != nil {
+			return err
+		}
+		want[h] = true
+	}
+	roots := make([]merkle.Hash, len(idx.Shards))
+	got := 0
+	for i, sh := range idx.Shards {
+		f, err := Load(filepath.Join(dir, sh.File))
+		if err != nil {
+			return err
+		}
+		t, err := f.ParseTree()
+		if err != nil {
+			return fmt.Errorf("%s: %w", sh.File, err)
+		}
+		if t.Root().Hex() != f.Root || f.Root != sh.Root {
+			return fmt.Errorf("%s: root %s does not match its tree or the index", sh.File, f.Root)
+		}
+		roots[i] = t.Root()
+		for _, ud := range f.UserDatas {
+			if s := idx.Lookup(ud.Leaf.Key()); s != i {
+				return fmt.Errorf("%s: position %s belongs in shard %d", sh.File, ud.Leaf.Key(), s)
+			}
+			h, err := LeafHash(ud.Leaf)
+			if err != nil {
+				return err
+			}
+			if !want[h] {
+				return fmt.Errorf("%s: leaf %s is not in %s", sh.File, ud.Leaf.Key(), n)
+			}
+			got++
+		}
+	}
+	if got != len(want) {
+		return fmt.Errorf("shards hold %d leaves, %s has %d", got, n, len(want))
+	}
+	if !idx.TwoLevel() {
+		return nil
+	}
+	scheme, err := merkle.SchemeByName(idx.HashScheme)
+	if err != nil {
+		return err
+	}
+	master, err := scheme.Build(roots)
+	if err != nil {
+		return err
+	}
+	if master.Root().Hex() != idx.MasterRoot {
+		return fmt.Errorf("%s: master root %s, recomputed %s", n.ShardIndexName(), idx.MasterRoot, master.Root().Hex())
+	}
+	for i, sh := range idx.Shards {
+		p, err := ParseProof(sh.MasterProof)
+		if err != nil {
+			return fmt.Errorf("shard %d master proof: %w", i, err)
+		}
+		if !scheme.Verify(master.Root(), roots[i], p) {
+			return fmt.Errorf("shard %d: master proof does not verify", i)
+		}
+	}
+	return nil
+}
+
+// LoadShardIndex reads the index for n in dir; it returns nil if n is not
+// sharded.
+func LoadShardIndex(dir string, n Name) (*ShardIndex, error) {
+	b, err := os.ReadFile(filepath.Join(dir, n.ShardIndexName()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var idx ShardIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("%s: %w", n.ShardIndexName(), err)
+	}
+	// Shard files are joined to dir, removed and published; one that is
+	// not a shard of n, e.g. "../x", is refused.
+	for _, s := range idx.Shards {
+		if owner, ok := parseSidecar(s.File); !ok || owner != n || !strings.Contains(s.File, ".shard-") {
+			return nil, fmt.Errorf("%s: %q is not a shard of %s", n.ShardIndexName(), s.File, n)
+		}
+	}
+	return &idx, nil
+}
+
+var sidecarRe = regexp.MustCompile(`^([0-9]+_[A-Za-z]+_[0-9]+)\.(shards|shard-[0-9]+-of-[0-9]+|bloom|uniswap|patch-[0-9]+)\.json$`)
+
+// parseSidecar returns the merkle file name a shard, shard index, Bloom,
+// uniswap claims or patch sidecar belongs to.
+func parseSidecar(name string) (Name, bool) {
+	m := sidecarRe.FindStringSubmatch(name)
+	if len(m) == 0 {
+		return Name{}, false
+	}
+	return ParseName(m[1] + ".json")
+}
+
+// Owner returns the merkle file name file is, or is a sidecar, shard or
+// patch of.
+func Owner(file string) (Name, bool) {
+	if n, ok := ParseName(file); ok {
+		return n, true
+	}
+	return parseSidecar(file)
+}
+
+// ArtifactPaths lists everything published for the cycle directory dir: the
+// merkle files, their Bloom, uniswap claims and patch sidecars, shard indexes
+// and shards, and the manifest and stats if present.
+func ArtifactPaths(dir string) ([]string, error) {
+	entries, err := ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(entries)+1)
+	for _, e := range entries {
+		out = append(out, e.Path)
+		if _, err := os.Stat(filepath.Join(dir, e.BloomName())); err == nil {
+			out = append(out, filepath.Join(dir, e.BloomName()))
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.UniswapName())); err == nil {
+			out = append(out, filepath.Join(dir, e.UniswapName()))
+		}
+		patches, err := Patches(dir, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, patches...)
+		idx, err := LoadShardIndex(dir, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		if idx == nil {
+			continue
+		}
+		out = append(out, filepath.Join(dir, e.ShardIndexName()))
+		for _, s := range idx.Shards {
+			out = append(out, filepath.Join(dir, s.File))
+		}
+	}
+	for _, name := range []string{ManifestName, StatsName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			out = append(out, filepath.Join(dir, name))
+		}
+	}
+	return out, nil
+}
+
+func (idx *ShardIndex) Write(dir string, n Name) error {
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, n.ShardIndexName())
+	return tmpdir.WriteFile(path, append(b, '\n'), 0o644)
+}
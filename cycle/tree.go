@@ -0,0 +1,232 @@
+package cycle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+// ErrLeafEncoding is returned by CheckLeafEncoding for a file none of whose
+// leaves hash into its own tree: its tree was built with a leaf encoding
+// SchemeLeafHash does not implement, so a tree rebuilt here from its leaves
+// would not verify on the distributor the file was made for. The published
+// cycle files (56_LM_12.json, 56_EG_12.json) are such files.
+var ErrLeafEncoding = errors.New("leaves do not hash into the file's tree with this tool's leaf encoding")
+
+// LeafHash is the StandardMerkleTree leaf for
+// (address erc721Addr, uint256 erc721Id, address[] tokens, uint256[] amounts).
+// Trees of the published cycle files do not use this encoding; see
+// CheckLeafEncoding.
+func LeafHash(l Leaf) (merkle.Hash, error) { return SchemeLeafHash(merkle.Standard, l) }
+
+// SchemeLeafHash hashes the leaf's ABI encoding with s.
+func SchemeLeafHash(s merkle.Scheme, l Leaf) (merkle.Hash, error) {
+	addr, err := evm.EncodeAddress(l.ERC721Addr)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("leaf %s: %w", l.Key(), err)
+	}
+	id, err := ParseAmount(l.ERC721ID)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("leaf %s: invalid erc721Id: %w", l.Key(), err)
+	}
+	tokens, err := evm.AddressArray(l.Tokens)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("leaf %s: %w", l.Key(), err)
+	}
+	amounts := make([]*big.Int, len(l.Amounts))
+	for i, s := range l.Amounts {
+		if amounts[i], err = ParseAmount(s); err != nil {
+			return merkle.Hash{}, fmt.Errorf("leaf %s: %w", l.Key(), err)
+		}
+	}
+	idw, err := evm.EncodeUint(id)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("leaf %s: invalid erc721Id: %w", l.Key(), err)
+	}
+	amountsArg, err := evm.UintArray(amounts)
+	if err != nil {
+		return merkle.Hash{}, fmt.Errorf("leaf %s: %w", l.Key(), err)
+	}
+	enc := evm.Encode(evm.Static(addr), evm.Static(idw), tokens, amountsArg)
+	return s.LeafHash(enc), nil
+}
+
+// CheckLeafEncoding checks that every leaf of f, hashed with SchemeLeafHash,
+// verifies against its root with its proof. Whatever rebuilds a tree from an
+// existing file checks that file first, so it never writes a root the
+// distributor that accepted the file cannot verify. A file without a tree,
+// or a merkle-distributor one, has nothing to check.
+func CheckLeafEncoding(f *File) error {
+	if f.Format == FormatUniswap || len(f.Tree) == 0 {
+		return nil
+	}
+	scheme, err := f.Scheme()
+	if err != nil {
+		return err
+	}
+	bad := make([]bool, len(f.UserDatas))
+	par.Range(len(bad), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			bad[i] = VerifyProof(scheme, f.Root, f.UserDatas[i]) != nil
+		}
+	})
+	n, first := 0, ""
+	for i, b := range bad {
+		if b {
+			if n == 0 {
+				first = f.UserDatas[i].Leaf.Key()
+			}
+			n++
+		}
+	}
+	switch {
+	case n == 0:
+		return nil
+	case n == len(bad):
+		return fmt.Errorf("%w (none of its %d leaves verify)", ErrLeafEncoding, n)
+	}
+	return fmt.Errorf("%d of %d leaves do not verify against the root (first: %s)", n, len(bad), first)
+}
+
+// Rebuild recomputes tree, root, proofs and totals from the leaves, hashing
+// with the file's scheme. It cannot tell whether the distributor hashes
+// leaves the same way; check an existing file with CheckLeafEncoding first.
+func Rebuild(f *File) error {
+	scheme, err := f.Scheme()
+	if err != nil {
+		return err
+	}
+	hashes := make([]merkle.Hash, len(f.UserDatas))
+	err = par.RangeErr(len(hashes), func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			h, err := SchemeLeafHash(scheme, f.UserDatas[i].Leaf)
+			if err != nil {
+				return err
+			}
+			hashes[i] = h
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	t, err := scheme.Build(hashes)
+	if err != nil {
+		return err
+	}
+	err = par.RangeErr(len(hashes), func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			p, err := t.Proof(hashes[i])
+			if err != nil {
+				return err
+			}
+			f.UserDatas[i].Proof = hexHashes(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	f.Tree = hexHashes(t.Nodes())
+	f.Root = t.Root().Hex()
+
+	totals, err := f.SumAmounts()
+	if err != nil {
+		return err
+	}
+	f.TotalAmounts = make(map[string]string, len(totals))
+	for tok, a := range totals {
+		f.TotalAmounts[tok] = a.String()
+	}
+	return nil
+}
+
+// NewLeaf builds a leaf with tokens in a stable order.
+func NewLeaf(erc721Addr, erc721ID string, amounts map[string]*big.Int) Leaf {
+	tokens := make([]string, 0, len(amounts))
+	for t := range amounts {
+		tokens = append(tokens, strings.ToLower(t))
+	}
+	sort.Strings(tokens)
+	l := Leaf{ERC721Addr: strings.ToLower(erc721Addr), ERC721ID: erc721ID}
+	for _, t := range tokens {
+		l.Tokens = append(l.Tokens, t)
+		l.Amounts = append(l.Amounts, amounts[t].String())
+	}
+	return l
+}
+
+func hexHashes(hs []merkle.Hash) []string {
+	out := make([]string, len(hs))
+	par.Range(len(hs), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			out[i] = hs[i].Hex()
+		}
+	})
+	return out
+}
+
+// ParseTree loads and checks the tree stored in the file.
+func (f *File) ParseTree() (*merkle.Tree, error) {
+	if f.Format == FormatUniswap {
+		return nil, errors.New("a uniswap claims file has no flat tree")
+	}
+	scheme, err := f.Scheme()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]merkle.Hash, len(f.Tree))
+	err = par.RangeErr(len(nodes), func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			h, err := merkle.ParseHash(f.Tree[i])
+			if err != nil {
+				return fmt.Errorf("tree[%d]: %w", i, err)
+			}
+			nodes[i] = h
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scheme.FromNodes(nodes)
+}
+
+func ParseProof(p []string) ([]merkle.Hash, error) {
+	out := make([]merkle.Hash, len(p))
+	for i, s := range p {
+		h, err := merkle.ParseHash(s)
+		if err != nil {
+			return nil, fmt.Errorf("proof[%d]: %w", i, err)
+		}
+		out[i] = h
+	}
+	return out, nil
+}
+
+// VerifyProof checks ud's proof against root, hashing the leaf with scheme.
+// For a two-level sharded file root is the master root.
+func VerifyProof(scheme merkle.Scheme, root string, ud UserData) error {
+	r, err := merkle.ParseHash(root)
+	if err != nil {
+		return fmt.Errorf("root: %w", err)
+	}
+	leaf, err := SchemeLeafHash(scheme, ud.Leaf)
+	if err != nil {
+		return err
+	}
+	proof, err := ParseProof(ud.Proof)
+	if err != nil {
+		return err
+	}
+	if !scheme.Verify(r, leaf, proof) {
+		return fmt.Errorf("proof of %s does not verify against root %s", ud.Leaf.Key(), root)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cycle_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+)
+
+// The published cycle files were not built with LeafHash: none of their
+// leaves hash into their trees, so rebuilding them here gives roots and
+// proofs the distributor rejects. Until its leaf encoding is implemented,
+// CheckLeafEncoding must catch them; once it is, this becomes a check that
+// Rebuild reproduces both files byte for byte.
+func TestCheckLeafEncodingRepoFiles(t *testing.T) {
+	for _, p := range []string{"../56_LM_12.json", "../56_EG_12.json"} {
+		f, err := cycle.Load(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cycle.CheckLeafEncoding(f); !errors.Is(err, cycle.ErrLeafEncoding) {
+			t.Errorf("%s: CheckLeafEncoding = %v, want ErrLeafEncoding", p, err)
+		}
+		root := f.Root
+		if err := cycle.Rebuild(f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Root == root {
+			t.Errorf("%s: Rebuild reproduced root %s; make this test check the whole file", p, root)
+		}
+	}
+}
+
+func TestCheckLeafEncodingTampered(t *testing.T) {
+	f, err := fixtures.Generate(fixtures.Options{Recipients: 40, Tokens: 2, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cycle.CheckLeafEncoding(f); err != nil {
+		t.Fatalf("rebuilt file: %v", err)
+	}
+	f.UserDatas[7].Leaf.Amounts[0] = "99999999999999999999999"
+	err = cycle.CheckLeafEncoding(f)
+	if err == nil || errors.Is(err, cycle.ErrLeafEncoding) || !strings.Contains(err.Error(), "1 of 40") {
+		t.Errorf("one tampered leaf: got %v", err)
+	}
+}
+
+func TestParseAmountBounds(t *testing.T) {
+	max := "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+	if _, err := cycle.ParseAmount(max); err != nil {
+		t.Errorf("ParseAmount(2^256-1): %v", err)
+	}
+	for _, s := range []string{"115792089237316195423570985008687907853269984665640564039457584007913129639936", "-1", "1e3"} {
+		if _, err := cycle.ParseAmount(s); err == nil {
+			t.Errorf("ParseAmount(%s) passed", s)
+		}
+	}
+	if _, err := cycle.ParseUniswapAmount("0x1" + strings.Repeat("0", 64)); err == nil {
+		t.Error("ParseUniswapAmount took 2^256")
+	}
+}
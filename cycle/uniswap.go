@@ -0,0 +1,288 @@
+package cycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+// UniswapClaims is the claims JSON of Uniswap's merkle-distributor, which the
+// older single-token distributors consume. A leaf is
+// keccak256(abi.encodePacked(uint256 index, address account, uint256 amount))
+// and pairs are sorted keccak256, but the tree is built level by level with an
+// odd node carried up, so it is not the StandardMerkleTree layout.
+type UniswapClaims struct {
+	MerkleRoot string `json:"merkleRoot"`
+	TokenTotal string `json:"tokenTotal"`
+	// Token is not part of merkle-distributor's output; we add it so the
+	// file can be read as a cycle file.
+	Token  string                  `json:"token,omitempty"`
+	Claims map[string]UniswapClaim `json:"claims"`
+}
+
+type UniswapClaim struct {
+	Index  uint64   `json:"index"`
+	Amount string   `json:"amount"`
+	Proof  []string `json:"proof"`
+}
+
+// UniswapName is "56_LM_21.uniswap.json" for 56_LM_21.json.
+func (n Name) UniswapName() string {
+	return strings.TrimSuffix(n.String(), ".json") + ".uniswap.json"
+}
+
+// IsUniswapPath reports whether path is named like a claims file.
+func IsUniswapPath(path string) bool { return strings.HasSuffix(path, ".uniswap.json") }
+
+func LoadUniswapClaims(path string) (*UniswapClaims, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseUniswapClaims(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseUniswapClaims reads a claims file's JSON.
+func ParseUniswapClaims(b []byte) (*UniswapClaims, error) {
+	var c UniswapClaims
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse uniswap claims: %w", err)
+	}
+	if c.Claims == nil {
+		return nil, errors.New("no claims object")
+	}
+	return &c, nil
+}
+
+func WriteUniswapClaims(path string, c *UniswapClaims) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// UniswapLeafHash is the leaf of one claim.
+func UniswapLeafHash(index uint64, account string, amount *big.Int) (merkle.Hash, error) {
+	a, err := evm.ParseAddress(account)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	amt, err := evm.EncodeUint(amount)
+	if err != nil {
+		return merkle.Hash{}, err
+	}
+	enc := append(evm.EncodeInt(int(index)), a...)
+	return merkle.Keccak.LeafHash(append(enc, amt...)), nil
+}
+
+// ParseUniswapAmount reads the hex amounts merkle-distributor writes, or
+// decimal ones.
+func ParseUniswapAmount(s string) (*big.Int, error) {
+	a, ok := new(big.Int), false
+	if h, isHex := strings.CutPrefix(s, "0x"); isHex {
+		_, ok = a.SetString(h, 16)
+	} else {
+		_, ok = a.SetString(s, 10)
+	}
+	if !ok || a.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	if a.BitLen() > 256 {
+		return nil, fmt.Errorf("amount %q does not fit uint256", s)
+	}
+	return a, nil
+}
+
+// NewUniswapClaims builds the claims file for amounts keyed by account the
+// way merkle-distributor's parse-balance-map does: indices follow the sorted
+// checksummed addresses.
+func NewUniswapClaims(amounts map[string]*big.Int) (*UniswapClaims, error) {
+	if len(amounts) == 0 {
+		return nil, errors.New("uniswap claims: no accounts")
+	}
+	accounts := make([]string, 0, len(amounts))
+	byChecksum := make(map[string]*big.Int, len(amounts))
+	for acct, a := range amounts {
+		cs, err := evm.ChecksumAddress(acct)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := byChecksum[cs]; dup {
+			return nil, fmt.Errorf("uniswap claims: account %s appears twice", cs)
+		}
+		if a.Sign() <= 0 {
+			return nil, fmt.Errorf("uniswap claims: account %s has no amount", cs)
+		}
+		byChecksum[cs] = a
+		accounts = append(accounts, cs)
+	}
+	sort.Strings(accounts)
+	leaves := make([]merkle.Hash, len(accounts))
+	total := new(big.Int)
+	for i, acct := range accounts {
+		h, err := UniswapLeafHash(uint64(i), acct, byChecksum[acct])
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = h
+		total.Add(total, byChecksum[acct])
+	}
+	layers := uniswapLayers(leaves)
+	c := &UniswapClaims{
+		MerkleRoot: layers[len(layers)-1][0].Hex(),
+		TokenTotal: "0x" + total.Text(16),
+		Claims:     make(map[string]UniswapClaim, len(accounts)),
+	}
+	for i, acct := range accounts {
+		c.Claims[acct] = UniswapClaim{Index: uint64(i), Amount: "0x" + byChecksum[acct].Text(16), Proof: hexHashes(uniswapProof(layers, leaves[i]))}
+	}
+	return c, nil
+}
+
+// uniswapLayers sorts the leaves and hashes them up level by level; the last
+// node of an odd level is carried up unhashed.
+func uniswapLayers(leaves []merkle.Hash) [][]merkle.Hash {
+	level := append([]merkle.Hash(nil), leaves...)
+	sort.Slice(level, func(i, j int) bool { return bytes.Compare(level[i][:], level[j][:]) < 0 })
+	layers := [][]merkle.Hash{level}
+	for len(level) > 1 {
+		next := make([]merkle.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkle.Keccak.HashPair(level[i], level[i+1]))
+		}
+		layers = append(layers, next)
+		level = next
+	}
+	return layers
+}
+
+func uniswapProof(layers [][]merkle.Hash, leaf merkle.Hash) []merkle.Hash {
+	i := sort.Search(len(layers[0]), func(i int) bool { return bytes.Compare(layers[0][i][:], leaf[:]) >= 0 })
+	proof := make([]merkle.Hash, 0, len(layers))
+	for _, level := range layers[:len(layers)-1] {
+		if j := i ^ 1; j < len(level) {
+			proof = append(proof, level[j])
+		}
+		i /= 2
+	}
+	return proof
+}
+
+// UniswapClaims converts account-level leaves into the claims file of one
+// token: every leaf must have erc721Id 0, its erc721Addr being the account,
+// and pay only token. With token empty the file's only token is used.
+func (f *File) UniswapClaims(token string) (*UniswapClaims, error) {
+	if token == "" {
+		if len(f.TotalAmounts) != 1 {
+			return nil, fmt.Errorf("uniswap claims: the file pays %d tokens; choose one", len(f.TotalAmounts))
+		}
+		for t := range f.TotalAmounts {
+			token = t
+		}
+	}
+	token = strings.ToLower(token)
+	amounts := make(map[string]*big.Int, len(f.UserDatas))
+	for _, ud := range f.UserDatas {
+		if ud.Leaf.ERC721ID != "0" {
+			return nil, fmt.Errorf("leaf %s: uniswap claims are per account and need erc721Id 0", ud.Leaf.Key())
+		}
+		am, err := ud.Leaf.AmountsByToken()
+		if err != nil {
+			return nil, err
+		}
+		for t := range am {
+			if t != token {
+				return nil, fmt.Errorf("leaf %s: pays %s, not %s", ud.Leaf.Key(), t, token)
+			}
+		}
+		acct := strings.ToLower(ud.Leaf.ERC721Addr)
+		if _, dup := amounts[acct]; dup {
+			return nil, fmt.Errorf("leaf %s: account appears twice", ud.Leaf.Key())
+		}
+		amounts[acct] = am[token]
+	}
+	c, err := NewUniswapClaims(amounts)
+	if err != nil {
+		return nil, err
+	}
+	c.Token = token
+	return c, nil
+}
+
+// VerifyClaim checks one claim's proof against the merkle root.
+func (c *UniswapClaims) VerifyClaim(account string, cl UniswapClaim) error {
+	root, err := merkle.ParseHash(c.MerkleRoot)
+	if err != nil {
+		return fmt.Errorf("merkleRoot: %w", err)
+	}
+	amount, err := ParseUniswapAmount(cl.Amount)
+	if err != nil {
+		return err
+	}
+	leaf, err := UniswapLeafHash(cl.Index, account, amount)
+	if err != nil {
+		return err
+	}
+	proof, err := ParseProof(cl.Proof)
+	if err != nil {
+		return err
+	}
+	if !merkle.Keccak.Verify(root, leaf, proof) {
+		return errors.New("proof does not verify against merkleRoot")
+	}
+	return nil
+}
+
+// FromUniswapClaims converts a claims file, whose every proof must verify,
+// into account-level leaves (erc721Id 0) paying token, by default the file's
+// own, in a new StandardMerkleTree. The timestamps, metadata and salt are
+// left for the caller.
+func FromUniswapClaims(c *UniswapClaims, token string) (*File, error) {
+	if token == "" {
+		token = c.Token
+	}
+	if token == "" {
+		return nil, errors.New("uniswap claims name no token; pass one")
+	}
+	if !evm.IsAddress(token) {
+		return nil, fmt.Errorf("uniswap claims: invalid token %q", token)
+	}
+	accounts := make([]string, 0, len(c.Claims))
+	for acct := range c.Claims {
+		accounts = append(accounts, acct)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return c.Claims[accounts[i]].Index < c.Claims[accounts[j]].Index })
+	f := &File{UserDatas: make([]UserData, 0, len(accounts))}
+	for _, acct := range accounts {
+		cl := c.Claims[acct]
+		if err := c.VerifyClaim(acct, cl); err != nil {
+			return nil, fmt.Errorf("claim %s: %w", acct, err)
+		}
+		amount, _ := ParseUniswapAmount(cl.Amount)
+		f.UserDatas = append(f.UserDatas, UserData{Leaf: NewLeaf(acct, "0", map[string]*big.Int{token: amount})})
+	}
+	if err := Rebuild(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,244 @@
+// Package distributor holds the ABIs of the distributor contracts and binds
+// them for the Go code that calls distributors: calldata generation,
+// claimed-amount reads, proof checks and root monitoring.
+//
+// abi/ holds only ABIs exported from a deployment's verified source, which
+// vendor-abi fetches from the chain's block explorer; scripts beside the
+// repo read them from here rather than keeping copies. A chain's
+// distributors use the ABI named by its "abi" in the chain registry. A name
+// is a file of the bundle without .json; a path to a JSON file works too.
+// Every ABI must declare the bound functions as this package calls them.
+//
+// A chain without an "abi" is called through Unverified: the bound
+// functions alone, as this repo called distributors before any ABI was
+// vendored, not an export of any deployment. Until its ABI is vendored,
+// Contract.CheckCode (preflight --onchain's abi check) is what confirms a
+// deployment dispatches those functions.
+package distributor
+
+import (
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/chains"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// UnverifiedABI is the name Load gives Unverified.
+const UnverifiedABI = "unverified"
+
+//go:embed abi
+var bundle embed.FS
+
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Function struct {
+	Name            string  `json:"name"`
+	Inputs          []Param `json:"inputs"`
+	Outputs         []Param `json:"outputs"`
+	StateMutability string  `json:"stateMutability"`
+}
+
+// Sig is the canonical signature the selector is hashed from, e.g.
+// "merkleRoot()".
+func (f Function) Sig() string {
+	types := make([]string, len(f.Inputs))
+	for i, p := range f.Inputs {
+		types[i] = p.Type
+	}
+	return f.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// ABI is a contract's functions by name.
+type ABI struct {
+	Name      string
+	Functions map[string]Function
+}
+
+// Bundled lists the names of the vendored ABIs.
+func Bundled() []string {
+	entries, _ := bundle.ReadDir("abi")
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Unverified is an ABI of the bound functions alone, for a chain whose
+// distributors' ABI is not vendored yet.
+func Unverified() *ABI {
+	a := &ABI{Name: UnverifiedABI, Functions: make(map[string]Function, len(bindings))}
+	for _, b := range bindings {
+		a.Functions[b.Name] = b
+	}
+	return a
+}
+
+// Load reads the vendored ABI name, or the ABI JSON file at name when it is
+// a path; UnverifiedABI is Unverified.
+func Load(name string) (*ABI, error) {
+	if name == UnverifiedABI {
+		return Unverified(), nil
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if strings.HasSuffix(name, ".json") {
+		b, err = os.ReadFile(name)
+	} else {
+		b, err = bundle.ReadFile(path.Join("abi", name+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("no distributor ABI %q (bundled: %s)", name, strings.Join(Bundled(), ", "))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(name, b)
+}
+
+// Parse reads ABI JSON b as name and checks it declares the bindings.
+func Parse(name string, b []byte) (*ABI, error) {
+	var entries []struct {
+		Type string `json:"type"`
+		Function
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("parse ABI %s: %w", name, err)
+	}
+	a := &ABI{Name: name, Functions: make(map[string]Function)}
+	for _, e := range entries {
+		if e.Type == "function" {
+			a.Functions[e.Name] = e.Function
+		}
+	}
+	if err := a.checkBindings(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ForChain loads the ABI of ch's distributors, Unverified if it names none.
+func ForChain(ch chains.Chain) (*ABI, error) {
+	if ch.ABI == "" {
+		return Unverified(), nil
+	}
+	return Load(ch.ABI)
+}
+
+func (a *ABI) Function(name string) (Function, error) {
+	f, ok := a.Functions[name]
+	if !ok {
+		return Function{}, fmt.Errorf("distributor ABI %s has no function %s", a.Name, name)
+	}
+	return f, nil
+}
+
+// Override returns a copy of a whose function name is called as sig
+// instead, for a distributor whose view was renamed or takes its arguments
+// in another form; the outputs are name's. An empty sig is a itself.
+func (a *ABI) Override(name, sig string) (*ABI, error) {
+	if sig == "" {
+		return a, nil
+	}
+	f, err := a.Function(name)
+	if err != nil {
+		return nil, err
+	}
+	lp, rp := strings.Index(sig, "("), strings.LastIndex(sig, ")")
+	if lp <= 0 || rp != len(sig)-1 {
+		return nil, fmt.Errorf("invalid function signature %q", sig)
+	}
+	f.Name = sig[:lp]
+	f.Inputs = nil
+	if args := sig[lp+1 : rp]; args != "" {
+		for _, t := range strings.Split(args, ",") {
+			f.Inputs = append(f.Inputs, Param{Type: strings.TrimSpace(t)})
+		}
+	}
+	out := &ABI{Name: a.Name, Functions: maps.Clone(a.Functions)}
+	out.Functions[name] = f
+	return out, nil
+}
+
+// Pack encodes a call of function name. Each argument must suit its input
+// type: a hex string for address and bytes32, a *big.Int for uint256, and
+// slices of those for the array types.
+func (a *ABI) Pack(name string, args ...any) ([]byte, error) {
+	f, err := a.Function(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(f.Inputs) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", f.Sig(), len(f.Inputs), len(args))
+	}
+	enc := make([]evm.Arg, len(args))
+	for i, in := range f.Inputs {
+		if enc[i], err = pack(in.Type, args[i]); err != nil {
+			return nil, fmt.Errorf("%s argument %d: %w", f.Sig(), i+1, err)
+		}
+	}
+	return evm.Calldata(f.Sig(), enc...), nil
+}
+
+func pack(typ string, v any) (evm.Arg, error) {
+	switch x := v.(type) {
+	case string:
+		switch typ {
+		case "address":
+			w, err := evm.EncodeAddress(x)
+			return evm.Static(w), err
+		case "bytes32":
+			b, err := hex.DecodeString(strings.TrimPrefix(x, "0x"))
+			if err != nil || len(b) != 32 {
+				return evm.Arg{}, fmt.Errorf("invalid bytes32 %q", x)
+			}
+			return evm.Static(b), nil
+		}
+	case *big.Int:
+		if typ == "uint256" {
+			w, err := evm.EncodeUint(x)
+			return evm.Static(w), err
+		}
+	case []string:
+		switch typ {
+		case "address[]":
+			return evm.AddressArray(x)
+		case "bytes32[]":
+			return evm.Bytes32Array(x)
+		}
+	case []*big.Int:
+		if typ == "uint256[]" {
+			return evm.UintArray(x)
+		}
+	}
+	return evm.Arg{}, fmt.Errorf("cannot pack %T as %s", v, typ)
+}
+
+// output checks that function name returns typ first.
+func (a *ABI) output(name, typ string) error {
+	f, err := a.Function(name)
+	if err != nil {
+		return err
+	}
+	if len(f.Outputs) == 0 || f.Outputs[0].Type != typ {
+		return fmt.Errorf("%s in ABI %s does not return %s", name, a.Name, typ)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package distributor_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/distributor"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+func TestBundledMatchBindings(t *testing.T) {
+	for _, name := range distributor.Bundled() {
+		if _, err := distributor.Load(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadRejectsMismatchedABI(t *testing.T) {
+	a, err := distributor.Load(distributor.UnverifiedABI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]map[string]any, 0)
+	for _, f := range a.Functions {
+		if f.Name == "getClaimedAmounts" {
+			f.Inputs = f.Inputs[:2]
+		}
+		entries = append(entries, map[string]any{"type": "function", "name": f.Name, "inputs": f.Inputs, "outputs": f.Outputs})
+	}
+	b, _ := json.Marshal(entries)
+	p := filepath.Join(t.TempDir(), "changed.json")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := distributor.Load(p); err == nil || !strings.Contains(err.Error(), "getClaimedAmounts(address,uint256)") {
+		t.Errorf("got %v", err)
+	}
+}
+
+// codeServer answers eth_getCode with code.
+func codeServer(t *testing.T, code []byte) *evm.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0x" + hex.EncodeToString(code)})
+	}))
+	t.Cleanup(srv.Close)
+	return evm.NewClient(srv.URL)
+}
+
+func TestCheckCode(t *testing.T) {
+	a, err := distributor.Load(distributor.UnverifiedABI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatch := func(names ...string) []byte {
+		code := []byte{0x60, 0x80}
+		for _, n := range names {
+			code = append(code, 0x80, 0x63)
+			code = append(code, evm.Selector(a.Functions[n].Sig())...)
+			code = append(code, 0x14)
+		}
+		return code
+	}
+	const addr = "0x00000000000000000000000000000000000000d1"
+	for name, c := range map[string]struct {
+		code []byte
+		want string
+	}{
+		"all":     {dispatch("merkleRoot", "getClaimedAmounts", "verifyProof", "claim"), ""},
+		"missing": {dispatch("merkleRoot", "getClaimedAmounts", "claim"), "does not dispatch verifyProof("},
+		"none":    {dispatch(), "dispatches none"},
+	} {
+		err := a.At(addr, codeServer(t, c.code)).CheckCode(context.Background(), "latest")
+		if c.want == "" && err != nil || c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("%s: got %v, want %q", name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// bindings are the functions this package calls, with the inputs it packs
+// and the output it decodes; an ABI must declare each of them so.
+var bindings = []Function{
+	{Name: "merkleRoot", Outputs: params("bytes32")},
+	{Name: "getClaimedAmounts", Inputs: params("address", "uint256", "address[]"), Outputs: params("uint256[]")},
+	{Name: "verifyProof", Inputs: params("address", "uint256", "address[]", "uint256[]", "bytes32[]"), Outputs: params("bool")},
+	{Name: "claim", Inputs: params("address", "uint256", "address[]", "uint256[]", "bytes32[]")},
+}
+
+func params(types ...string) []Param {
+	out := make([]Param, len(types))
+	for i, t := range types {
+		out[i] = Param{Type: t}
+	}
+	return out
+}
+
+// checkBindings reports every binding a does not declare as this package
+// calls it.
+func (a *ABI) checkBindings() error {
+	bad := make([]string, 0)
+	for _, b := range bindings {
+		f, ok := a.Functions[b.Name]
+		switch {
+		case !ok:
+			bad = append(bad, "no "+b.Sig())
+		case f.Sig() != b.Sig():
+			bad = append(bad, fmt.Sprintf("%s declared, %s bound", f.Sig(), b.Sig()))
+		case len(b.Outputs) > 0 && (len(f.Outputs) == 0 || f.Outputs[0].Type != b.Outputs[0].Type):
+			bad = append(bad, fmt.Sprintf("%s does not return %s", b.Name, b.Outputs[0].Type))
+		}
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("distributor ABI %s does not match its bindings: %s", a.Name, strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+// CheckCode reports the bound functions whose selectors the code deployed
+// at c.Address does not dispatch, that is, has no PUSH4 of. It does not
+// follow a proxy to its implementation: code that dispatches none of them
+// may be one.
+func (c *Contract) CheckCode(ctx context.Context, block string) error {
+	code, err := c.Client.CodeAt(ctx, c.Address, block)
+	if err != nil {
+		return fmt.Errorf("eth_getCode %s: %w", c.Address, err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("%s has no code", c.Address)
+	}
+	missing := make([]string, 0)
+	for _, b := range bindings {
+		f, err := c.ABI.Function(b.Name)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(code, append([]byte{0x63}, evm.Selector(f.Sig())...)) {
+			missing = append(missing, f.Sig())
+		}
+	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case len(bindings):
+		return fmt.Errorf("code at %s dispatches none of ABI %s's functions (a proxy, or another contract)", c.Address, c.ABI.Name)
+	}
+	return fmt.Errorf("code at %s does not dispatch %s of ABI %s", c.Address, strings.Join(missing, ", "), c.ABI.Name)
+}
+
+// Contract is a distributor deployment bound to its ABI.
+type Contract struct {
+	ABI     *ABI
+	Address string
+	Client  *evm.Client
+}
+
+func (c *Contract) call(ctx context.Context, block, name, returns string, args ...any) ([]byte, error) {
+	if err := c.ABI.output(name, returns); err != nil {
+		return nil, err
+	}
+	data, err := c.ABI.Pack(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Call(ctx, c.Address, data, block)
+	if err != nil {
+		f, _ := c.ABI.Function(name)
+		return nil, fmt.Errorf("eth_call %s: %w", f.Sig(), err)
+	}
+	return res, nil
+}
+
+// MerkleRoot is the root the distributor pays out against.
+func (c *Contract) MerkleRoot(ctx context.Context, block string) (string, error) {
+	res, err := c.call(ctx, block, "merkleRoot", "bytes32")
+	if err != nil {
+		return "", err
+	}
+	if len(res) < 32 {
+		return "", fmt.Errorf("merkleRoot returned %d bytes", len(res))
+	}
+	return "0x" + hex.EncodeToString(res[:32]), nil
+}
+
+// ClaimedAmounts is how much of each of l's tokens its position has
+// claimed, in l's token order.
+func (c *Contract) ClaimedAmounts(ctx context.Context, block string, l cycle.Leaf) ([]*big.Int, error) {
+	id, err := cycle.ParseAmount(l.ERC721ID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.call(ctx, block, "getClaimedAmounts", "uint256[]", l.ERC721Addr, id, l.Tokens)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := evm.DecodeUintArray(res)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != len(l.Tokens) {
+		return nil, fmt.Errorf("expected %d amounts, got %d", len(l.Tokens), len(vals))
+	}
+	return vals, nil
+}
+
+// VerifyProof reports whether ud's leaf and proof verify against the
+// distributor's root.
+func (c *Contract) VerifyProof(ctx context.Context, block string, ud cycle.UserData) (bool, error) {
+	args, err := leafArgs(ud)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.call(ctx, block, "verifyProof", "bool", args...)
+	if err != nil {
+		return false, err
+	}
+	valid, err := evm.DecodeUint(res, 0)
+	if err != nil {
+		return false, err
+	}
+	return valid.Sign() != 0, nil
+}
+
+// ClaimCalldata is the calldata claiming ud's leaf with its proof, for a
+// wallet or a simulation to send.
+func (a *ABI) ClaimCalldata(ud cycle.UserData) ([]byte, error) {
+	args, err := leafArgs(ud)
+	if err != nil {
+		return nil, err
+	}
+	return a.Pack("claim", args...)
+}
+
+// leafArgs are the (erc721Addr, erc721Id, tokens, amounts, proof) that
+// claim and verifyProof take.
+func leafArgs(ud cycle.UserData) ([]any, error) {
+	l := ud.Leaf
+	id, err := cycle.ParseAmount(l.ERC721ID)
+	if err != nil {
+		return nil, err
+	}
+	amounts := make([]*big.Int, len(l.Amounts))
+	for i, s := range l.Amounts {
+		if amounts[i], err = cycle.ParseAmount(s); err != nil {
+			return nil, err
+		}
+	}
+	return []any{l.ERC721Addr, id, l.Tokens, amounts, ud.Proof}, nil
+}
+
+// At binds a to the distributor at address, called through c.
+func (a *ABI) At(address string, c *evm.Client) *Contract {
+	return &Contract{ABI: a, Address: address, Client: c}
+}
@@ -0,0 +1,232 @@
+// Package download fetches artifacts to disk through a partial file that
+// survives failures, so an interrupted transfer resumes with a Range request
+// instead of starting from zero. Partial files live in the user's cache
+// directory, never beside the target where a directory scan would see them.
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+type Options struct {
+	// Retries is how many times a failed transfer is resumed before giving up.
+	Retries int
+	// Backoff is the pause before the first retry; it doubles per attempt.
+	Backoff time.Duration
+	// SHA256, when set, is the expected hex digest of the complete file.
+	SHA256 string
+}
+
+type Result struct {
+	SHA256  string
+	Size    int64
+	Resumed bool
+	// LastModified is when the server says the object last changed, for a
+	// blob store its upload time; zero if it did not say.
+	LastModified time.Time
+}
+
+// validator is kept next to the partial file so a resumed request only
+// appends if the remote object is unchanged (If-Range).
+type validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func (v validator) ifRange() string {
+	if v.ETag != "" && !strings.HasPrefix(v.ETag, "W/") {
+		return v.ETag
+	}
+	return v.LastModified
+}
+
+// ToFile downloads url to outPath. The partial file is kept on failure; a
+// hash mismatch discards it, since resuming would only reproduce the error.
+func ToFile(ctx context.Context, client *http.Client, url, outPath string, opt Options) (res Result, err error) {
+	ctx, span := tracing.Start(ctx, "download", "file", filepath.Base(outPath))
+	defer func() {
+		span.SetAttrs("bytes", res.Size, "resumed", res.Resumed)
+		span.End(err)
+	}()
+	tmp, err := partialPath(outPath)
+	if err != nil {
+		return res, err
+	}
+	backoff := opt.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		var resumed bool
+		resumed, err = fetch(ctx, client, url, tmp)
+		res.Resumed = res.Resumed || resumed
+		if err == nil || attempt >= opt.Retries || ctx.Err() != nil {
+			break
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		return res, err
+	}
+
+	res.SHA256, res.Size, err = digest(tmp)
+	if err != nil {
+		return res, err
+	}
+	if opt.SHA256 != "" && !strings.EqualFold(opt.SHA256, res.SHA256) {
+		clean(tmp)
+		return res, fmt.Errorf("sha256 %s does not match expected %s", res.SHA256, opt.SHA256)
+	}
+	var v validator
+	if b, err := os.ReadFile(tmp + ".json"); err == nil && json.Unmarshal(b, &v) == nil {
+		res.LastModified, _ = http.ParseTime(v.LastModified)
+	}
+	os.Remove(tmp + ".json")
+	return res, tmpdir.Rename(tmp, outPath)
+}
+
+// partialPath is where the download to outPath is staged, the same for
+// every run so a later one resumes it.
+func partialPath(outPath string) (string, error) {
+	abs, err := filepath.Abs(outPath)
+	if err != nil {
+		return "", err
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, "fairflow-reward", "partial")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+"-"+filepath.Base(outPath)), nil
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func fetch(ctx context.Context, client *http.Client, url, tmp string) (bool, error) {
+	var offset int64
+	if st, err := os.Stat(tmp); err == nil {
+		offset = st.Size()
+	}
+	var v validator
+	if b, err := os.ReadFile(tmp + ".json"); err == nil {
+		_ = json.Unmarshal(b, &v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, &permanentError{err}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		if ir := v.ifRange(); ir != "" {
+			req.Header.Set("If-Range", ir)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	resumed := false
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart(resp) == offset:
+		flags = os.O_WRONLY | os.O_APPEND
+		resumed = true
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is no good for this object; start over next attempt.
+		clean(tmp)
+		return false, fmt.Errorf("download failed: %s", resp.Status)
+	case resp.StatusCode/100 != 2:
+		b, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("download failed: %s: %s", resp.Status, string(b))
+		if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+			return false, &permanentError{err}
+		}
+		return false, err
+	case resp.StatusCode == http.StatusPartialContent:
+		// A range we did not ask for; refetch in full.
+		clean(tmp)
+		return false, errors.New("download failed: unexpected partial content")
+	}
+
+	if !resumed {
+		v = validator{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+		if b, err := json.Marshal(v); err == nil {
+			_ = os.WriteFile(tmp+".json", b, 0o644)
+		}
+	}
+	f, err := os.OpenFile(tmp, flags, 0o644)
+	if err != nil {
+		return resumed, &permanentError{err}
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return resumed, err
+	}
+	return resumed, f.Close()
+}
+
+// rangeStart parses the first byte offset of "Content-Range: bytes a-b/n".
+func rangeStart(resp *http.Response) int64 {
+	cr := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	start, _, ok := strings.Cut(cr, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func digest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func clean(tmp string) {
+	os.Remove(tmp)
+	os.Remove(tmp + ".json")
+}
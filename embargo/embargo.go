@@ -0,0 +1,157 @@
+// Package embargo keeps cycle files encrypted at rest until the cycle is
+// announced, so the repo (and any mirrors) can hold them without leaking
+// reward amounts. notion-sync seals each merkle file to <name>.enc and
+// decrypt-cycle opens them again at publish time.
+//
+// Files are AES-256-GCM with a random nonce; the plaintext file name is the
+// additional data, so an encrypted file cannot be renamed to stand in for
+// another chain or reward type.
+package embargo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// Ext is appended to the name of a sealed file.
+const Ext = ".enc"
+
+const magic = "fairflow-embargo:v1\n"
+
+// Key is a 256-bit AES key.
+type Key []byte
+
+// LoadKey reads a key file holding the base64 key.
+func LoadKey(path string) (Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("embargo: key: %w", err)
+	}
+	if len(k) != 32 {
+		return nil, fmt.Errorf("embargo: key is %d bytes, want 32", len(k))
+	}
+	return Key(k), nil
+}
+
+// GenerateKey writes a new key file to path; it never overwrites one.
+func GenerateKey(path string) error {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, base64.StdEncoding.EncodeToString(k)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (k Key) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts path to path+Ext and removes the plaintext.
+func (k Key) Seal(path string) error {
+	pt, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	g, err := k.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, g.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out := append([]byte(magic), nonce...)
+	out = g.Seal(out, nonce, pt, []byte(filepath.Base(path)))
+	if err := writeAtomic(path+Ext, out); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Open decrypts a sealed file to its plaintext name and removes the sealed
+// copy. It returns the plaintext path.
+func (k Key) Open(sealed string) (string, error) {
+	path, ok := strings.CutSuffix(sealed, Ext)
+	if !ok {
+		return "", fmt.Errorf("embargo: %s does not end in %s", sealed, Ext)
+	}
+	b, err := os.ReadFile(sealed)
+	if err != nil {
+		return "", err
+	}
+	g, err := k.aead()
+	if err != nil {
+		return "", err
+	}
+	rest, ok := strings.CutPrefix(string(b), magic)
+	if !ok || len(rest) < g.NonceSize() {
+		return "", fmt.Errorf("embargo: %s is not a sealed cycle file", sealed)
+	}
+	nonce, ct := []byte(rest[:g.NonceSize()]), []byte(rest[g.NonceSize():])
+	pt, err := g.Open(nil, nonce, ct, []byte(filepath.Base(path)))
+	if err != nil {
+		return "", fmt.Errorf("embargo: %s: wrong key or corrupted file", sealed)
+	}
+	if err := writeAtomic(path, pt); err != nil {
+		return "", err
+	}
+	return path, os.Remove(sealed)
+}
+
+// Pending lists the sealed files in dir, sorted.
+func Pending(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), Ext) {
+			out = append(out, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// CheckOpen fails if dir still holds sealed files; commands that publish a
+// cycle call it so an embargoed cycle is never released half-decrypted.
+func CheckOpen(dir string) error {
+	sealed, err := Pending(dir)
+	if err != nil {
+		return err
+	}
+	if len(sealed) > 0 {
+		return fmt.Errorf("embargo: %s has %d encrypted files; run decrypt-cycle first", dir, len(sealed))
+	}
+	return nil
+}
+
+func writeAtomic(path string, b []byte) error {
+	return tmpdir.WriteFile(path, b, 0o644)
+}
@@ -0,0 +1,230 @@
+package evm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+const wordSize = 32
+
+// Selector returns the 4-byte function selector for a canonical signature
+// such as "balanceOf(address)".
+func Selector(sig string) []byte {
+	h := keccak.Sum256([]byte(sig))
+	return h[:4]
+}
+
+func ParseAddress(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil || len(b) != 20 {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return b, nil
+}
+
+func IsAddress(s string) bool {
+	_, err := ParseAddress(s)
+	return err == nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed-case form of a valid address.
+func ChecksumAddress(s string) (string, error) {
+	b, err := ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	lower := hex.EncodeToString(b)
+	h := keccak.Sum256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := h[i/2] >> 4
+		if i%2 == 1 {
+			nibble = h[i/2] & 0xf
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out), nil
+}
+
+// Word left-pads b to one ABI word; b longer than a word is an error.
+func Word(b []byte) ([]byte, error) {
+	if len(b) > wordSize {
+		return nil, fmt.Errorf("%d bytes do not fit an ABI word", len(b))
+	}
+	w := make([]byte, wordSize)
+	copy(w[wordSize-len(b):], b)
+	return w, nil
+}
+
+func EncodeAddress(s string) ([]byte, error) {
+	b, err := ParseAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	return Word(b)
+}
+
+// EncodeUint encodes n as a uint256, which n must fit.
+func EncodeUint(n *big.Int) ([]byte, error) {
+	if n.Sign() < 0 || n.BitLen() > 256 {
+		return nil, fmt.Errorf("%s does not fit uint256", n)
+	}
+	return Word(n.Bytes())
+}
+
+// EncodeInt encodes a length or an offset, which is never negative.
+func EncodeInt(n int) []byte {
+	w := make([]byte, wordSize)
+	binary.BigEndian.PutUint64(w[wordSize-8:], uint64(n))
+	return w
+}
+
+// Arg is one ABI-encoded argument; dynamic arguments are placed in the tail.
+type Arg struct {
+	Head    []byte
+	Dynamic bool
+}
+
+func Static(w []byte) Arg { return Arg{Head: w} }
+
+func AddressArray(addrs []string) (Arg, error) {
+	out := EncodeInt(len(addrs))
+	for _, a := range addrs {
+		w, err := EncodeAddress(a)
+		if err != nil {
+			return Arg{}, err
+		}
+		out = append(out, w...)
+	}
+	return Arg{Head: out, Dynamic: true}, nil
+}
+
+func UintArray(vals []*big.Int) (Arg, error) {
+	out := EncodeInt(len(vals))
+	for _, v := range vals {
+		w, err := EncodeUint(v)
+		if err != nil {
+			return Arg{}, err
+		}
+		out = append(out, w...)
+	}
+	return Arg{Head: out, Dynamic: true}, nil
+}
+
+// Bytes32Array encodes hex words, such as a merkle proof, as bytes32[].
+func Bytes32Array(words []string) (Arg, error) {
+	out := EncodeInt(len(words))
+	for _, w := range words {
+		b, err := hex.DecodeString(strings.TrimPrefix(w, "0x"))
+		if err != nil || len(b) != wordSize {
+			return Arg{}, fmt.Errorf("invalid bytes32 %q", w)
+		}
+		out = append(out, b...)
+	}
+	return Arg{Head: out, Dynamic: true}, nil
+}
+
+// Encode lays out args as abi.encode would.
+func Encode(args ...Arg) []byte {
+	head := make([]byte, 0, len(args)*wordSize)
+	var tail []byte
+	for _, a := range args {
+		if !a.Dynamic {
+			head = append(head, a.Head...)
+			continue
+		}
+		head = append(head, EncodeInt(len(args)*wordSize+len(tail))...)
+		tail = append(tail, a.Head...)
+	}
+	return append(head, tail...)
+}
+
+// Calldata prefixes the encoded args with the selector for sig.
+func Calldata(sig string, args ...Arg) []byte {
+	return append(Selector(sig), Encode(args...)...)
+}
+
+func DecodeUint(b []byte, index int) (*big.Int, error) {
+	start := index * wordSize
+	if len(b) < start+wordSize {
+		return nil, fmt.Errorf("abi: return data too short (%d bytes) for word %d", len(b), index)
+	}
+	return new(big.Int).SetBytes(b[start : start+wordSize]), nil
+}
+
+func DecodeAddress(b []byte, index int) (string, error) {
+	start := index * wordSize
+	if len(b) < start+wordSize {
+		return "", fmt.Errorf("abi: return data too short (%d bytes) for word %d", len(b), index)
+	}
+	return "0x" + hex.EncodeToString(b[start+12:start+wordSize]), nil
+}
+
+// DecodeString decodes a single dynamic string return value.
+func DecodeString(b []byte) (string, error) {
+	off, err := DecodeUint(b, 0)
+	if err != nil {
+		return "", err
+	}
+	if !off.IsInt64() || off.Int64()+wordSize > int64(len(b)) {
+		return "", fmt.Errorf("abi: invalid string offset %s", off)
+	}
+	n, err := DecodeUint(b[off.Int64():], 0)
+	if err != nil {
+		return "", err
+	}
+	start := off.Int64() + wordSize
+	if !n.IsInt64() || start+n.Int64() > int64(len(b)) {
+		return "", fmt.Errorf("abi: invalid string length %s", n)
+	}
+	return string(b[start : start+n.Int64()]), nil
+}
+
+// DecodeUintArray decodes a single dynamic uint256[] return value.
+func DecodeUintArray(b []byte) ([]*big.Int, error) {
+	off, err := DecodeUint(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !off.IsInt64() || off.Int64()%wordSize != 0 {
+		return nil, fmt.Errorf("abi: invalid array offset %s", off)
+	}
+	base := int(off.Int64()) / wordSize
+	n, err := DecodeUint(b, base)
+	if err != nil {
+		return nil, err
+	}
+	if !n.IsInt64() || n.Int64() > int64(len(b)/wordSize) {
+		return nil, fmt.Errorf("abi: invalid array length %s", n)
+	}
+	out := make([]*big.Int, n.Int64())
+	for i := range out {
+		if out[i], err = DecodeUint(b, base+1+i); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// DecodeAddressArray decodes a single dynamic address[] return value.
+func DecodeAddressArray(b []byte) ([]string, error) {
+	words, err := DecodeUintArray(b)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(words))
+	for i, w := range words {
+		if w.BitLen() > 160 {
+			return nil, fmt.Errorf("abi: word %d is not an address", i)
+		}
+		out[i] = "0x" + hex.EncodeToString(w.FillBytes(make([]byte, 20)))
+	}
+	return out, nil
+}
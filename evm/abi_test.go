@@ -0,0 +1,28 @@
+package evm_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// Values past uint256 must be refused, not sliced into a word.
+func TestEncodeUintBounds(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	w, err := evm.EncodeUint(max)
+	if err != nil || len(w) != 32 || new(big.Int).SetBytes(w).Cmp(max) != 0 {
+		t.Errorf("EncodeUint(2^256-1) = %x, %v", w, err)
+	}
+	for _, n := range []*big.Int{new(big.Int).Add(max, big.NewInt(1)), big.NewInt(-1)} {
+		if _, err := evm.EncodeUint(n); err == nil {
+			t.Errorf("EncodeUint(%s) passed", n)
+		}
+	}
+	if _, err := evm.Word(make([]byte, 33)); err == nil {
+		t.Error("Word took 33 bytes")
+	}
+	if _, err := evm.UintArray([]*big.Int{big.NewInt(1), new(big.Int).Lsh(max, 1)}); err == nil {
+		t.Error("UintArray took an element past uint256")
+	}
+}
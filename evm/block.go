@@ -0,0 +1,112 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBlockTag is where monitoring reads by default: results there do not
+// flap as the chain head reorgs.
+const DefaultBlockTag = "finalized"
+
+// PinBlock resolves tag ("latest", "safe", "finalized", "pending" or a block
+// number in decimal or 0x hex) to a concrete block number, confirmations
+// blocks behind it. Reading every call at the returned number keeps a
+// multi-call scan on one consistent block.
+func (c *Client) PinBlock(ctx context.Context, tag string, confirmations uint64) (uint64, error) {
+	if tag == "" {
+		tag = "latest"
+	}
+	n, err := c.resolveTag(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	if confirmations > n {
+		return 0, fmt.Errorf("block %s is %d, fewer than %d confirmations", tag, n, confirmations)
+	}
+	return n - confirmations, nil
+}
+
+func (c *Client) resolveTag(ctx context.Context, tag string) (uint64, error) {
+	switch tag {
+	case "latest", "safe", "finalized", "pending":
+	default:
+		if strings.HasPrefix(tag, "0x") {
+			return strconv.ParseUint(tag[2:], 16, 64)
+		}
+		n, err := strconv.ParseUint(tag, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid block tag %q", tag)
+		}
+		return n, nil
+	}
+	if tag == "latest" {
+		return c.BlockNumber(ctx)
+	}
+	var res *struct {
+		Number string `json:"number"`
+	}
+	if err := c.call(ctx, "eth_getBlockByNumber", &res, tag, false); err != nil {
+		return 0, fmt.Errorf("%w (does the RPC support the %q tag? try --block latest --confirmations N)", err, tag)
+	}
+	if res == nil {
+		return 0, fmt.Errorf("eth_getBlockByNumber: no %s block", tag)
+	}
+	n, err := parseQuantity(res.Number)
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+// BlockTime is when block n was produced.
+func (c *Client) BlockTime(ctx context.Context, n uint64) (time.Time, error) {
+	var res *struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := c.call(ctx, "eth_getBlockByNumber", &res, BlockParam(n), false); err != nil {
+		return time.Time{}, err
+	}
+	if res == nil {
+		return time.Time{}, fmt.Errorf("eth_getBlockByNumber: no block %d", n)
+	}
+	ts, err := parseQuantity(res.Timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ts.Int64(), 0).UTC(), nil
+}
+
+// LastSent finds the block of the last transaction addr sent at or before
+// head by bisecting its nonce, which needs an archive node for old blocks.
+// ok is false when addr never sent one. Only an EOA's nonce counts what it
+// sent; a contract's counts the contracts it created.
+func (c *Client) LastSent(ctx context.Context, addr string, head uint64) (block uint64, ok bool, err error) {
+	nonce, err := c.NonceAt(ctx, addr, BlockParam(head))
+	if err != nil || nonce == 0 {
+		return 0, false, err
+	}
+	// The nonce reaches its head value at the block of the last transaction.
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		n, err := c.NonceAt(ctx, addr, BlockParam(mid))
+		if err != nil {
+			return 0, false, err
+		}
+		if n < nonce {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, true, nil
+}
+
+// BlockParam formats n as a JSON-RPC block parameter for Call and friends.
+func BlockParam(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
@@ -0,0 +1,199 @@
+// Package evm is a minimal JSON-RPC client and ABI helper set for the
+// read-only on-chain checks the reward tools perform.
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client sends each request to the healthiest of its endpoints and fails
+// over to the next one on transport errors, HTTP errors and rate limiting.
+type Client struct {
+	http      *http.Client
+	endpoints []*endpoint
+	id        atomic.Int64
+}
+
+// NewClient returns a client for one chain; extra URLs are fallbacks.
+func NewClient(rpcURLs ...string) *Client {
+	c := &Client{http: &http.Client{Timeout: 30 * time.Second}}
+	for _, u := range rpcURLs {
+		if u != "" {
+			c.endpoints = append(c.endpoints, &endpoint{url: u})
+		}
+	}
+	return c
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *Client) call(ctx context.Context, method string, out any, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	b, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.id.Add(1), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if len(c.endpoints) == 0 {
+		return fmt.Errorf("%s: no rpc endpoint configured", method)
+	}
+	var errs []error
+	for _, ep := range c.ranked() {
+		start := time.Now()
+		res, err := c.post(ctx, ep.url, method, b)
+		ep.record(time.Since(start), err)
+		if err == nil {
+			return json.Unmarshal(res, out)
+		}
+		var f *failover
+		if !errors.As(err, &f) || ctx.Err() != nil || len(c.endpoints) == 1 {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", redact(ep.url), f.err))
+	}
+	return fmt.Errorf("%s: every endpoint failed: %w", method, errors.Join(errs...))
+}
+
+// failover marks an error another endpoint may not have.
+type failover struct{ err error }
+
+func (f *failover) Error() string { return f.err.Error() }
+func (f *failover) Unwrap() error { return f.err }
+
+func (c *Client) post(ctx context.Context, url, method string, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &failover{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return nil, &failover{fmt.Errorf("%s failed: %s: %s", method, resp.Status, string(rb))}
+	}
+	var rr rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return nil, &failover{fmt.Errorf("%s: decode response: %w", method, err)}
+	}
+	if rr.Error != nil {
+		err := fmt.Errorf("%s: rpc error %d: %s", method, rr.Error.Code, rr.Error.Message)
+		if rateLimited(rr.Error.Code, rr.Error.Message) {
+			return nil, &failover{err}
+		}
+		return nil, err
+	}
+	return rr.Result, nil
+}
+
+// Call performs eth_call against to with calldata at the given block tag.
+func (c *Client) Call(ctx context.Context, to string, data []byte, block string) ([]byte, error) {
+	if block == "" {
+		block = "latest"
+	}
+	var res string
+	msg := map[string]string{"to": to, "data": "0x" + hex.EncodeToString(data)}
+	if err := c.call(ctx, "eth_call", &res, msg, block); err != nil {
+		return nil, err
+	}
+	return decodeHex(res)
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var res string
+	if err := c.call(ctx, "eth_blockNumber", &res); err != nil {
+		return 0, err
+	}
+	n, err := parseQuantity(res)
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var res string
+	if err := c.call(ctx, "eth_chainId", &res); err != nil {
+		return nil, err
+	}
+	return parseQuantity(res)
+}
+
+func (c *Client) BalanceAt(ctx context.Context, addr, block string) (*big.Int, error) {
+	if block == "" {
+		block = "latest"
+	}
+	var res string
+	if err := c.call(ctx, "eth_getBalance", &res, addr, block); err != nil {
+		return nil, err
+	}
+	return parseQuantity(res)
+}
+
+// NonceAt is how many transactions addr had sent by block.
+func (c *Client) NonceAt(ctx context.Context, addr, block string) (uint64, error) {
+	if block == "" {
+		block = "latest"
+	}
+	var res string
+	if err := c.call(ctx, "eth_getTransactionCount", &res, addr, block); err != nil {
+		return 0, err
+	}
+	n, err := parseQuantity(res)
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64(), nil
+}
+
+// CodeAt returns the contract code at addr; it is empty for an EOA.
+func (c *Client) CodeAt(ctx context.Context, addr, block string) ([]byte, error) {
+	if block == "" {
+		block = "latest"
+	}
+	var res string
+	if err := c.call(ctx, "eth_getCode", &res, addr, block); err != nil {
+		return nil, err
+	}
+	return decodeHex(res)
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+func parseQuantity(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,146 @@
+package evm
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An endpoint that fails is skipped for cooldown (doubling per consecutive
+// failure up to maxCooldown) unless every endpoint is cooling down.
+const (
+	cooldown    = 5 * time.Second
+	maxCooldown = 5 * time.Minute
+)
+
+type endpoint struct {
+	url string
+
+	mu       sync.Mutex
+	calls    int
+	errors   int
+	latency  time.Duration // moving average of successful calls
+	failures int           // consecutive
+	until    time.Time
+	lastErr  string
+}
+
+func (e *endpoint) record(d time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	var f *failover
+	if err != nil && errors.As(err, &f) {
+		e.errors++
+		e.failures++
+		e.lastErr = f.err.Error()
+		back := cooldown << min(e.failures-1, 10)
+		e.until = time.Now().Add(min(back, maxCooldown))
+		return
+	}
+	// A deterministic RPC error (e.g. a revert) still shows the endpoint
+	// is up.
+	e.failures = 0
+	e.until = time.Time{}
+	if e.latency == 0 {
+		e.latency = d
+	} else {
+		e.latency = (e.latency*4 + d) / 5
+	}
+}
+
+// score orders endpoints: lower is better. It is the average latency
+// inflated by the error rate, with unknown endpoints scored as fast.
+func (e *endpoint) score() float64 {
+	ok := float64(e.calls-e.errors+1) / float64(e.calls+1)
+	return float64(e.latency.Milliseconds()+1) / ok
+}
+
+func (e *endpoint) cooling(now time.Time) bool { return now.Before(e.until) }
+
+// ranked returns the endpoints best first, those cooling down last.
+func (c *Client) ranked() []*endpoint {
+	type ranked struct {
+		ep      *endpoint
+		cooling bool
+		score   float64
+	}
+	now := time.Now()
+	rs := make([]ranked, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		ep.mu.Lock()
+		rs[i] = ranked{ep, ep.cooling(now), ep.score()}
+		ep.mu.Unlock()
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		if rs[i].cooling != rs[j].cooling {
+			return !rs[i].cooling
+		}
+		return rs[i].score < rs[j].score
+	})
+	out := make([]*endpoint, len(rs))
+	for i, r := range rs {
+		out[i] = r.ep
+	}
+	return out
+}
+
+// Health is an endpoint's record for this client's lifetime.
+type Health struct {
+	// URL is redacted to scheme and host, since RPC URLs often embed keys.
+	URL         string
+	Calls       int
+	Errors      int
+	Latency     time.Duration
+	CoolingDown bool
+	LastError   string
+	Score       float64
+}
+
+// Health reports every endpoint, best first.
+func (c *Client) Health() []Health {
+	now := time.Now()
+	out := make([]Health, 0, len(c.endpoints))
+	for _, ep := range c.ranked() {
+		ep.mu.Lock()
+		out = append(out, Health{
+			URL:         redact(ep.url),
+			Calls:       ep.calls,
+			Errors:      ep.errors,
+			Latency:     ep.latency,
+			CoolingDown: ep.cooling(now),
+			LastError:   ep.lastErr,
+			Score:       ep.score(),
+		})
+		ep.mu.Unlock()
+	}
+	return out
+}
+
+// rateLimited reports JSON-RPC errors that mean "try elsewhere": limit
+// exceeded (-32005), provider-specific capacity codes, and plain-text
+// throttling messages.
+func rateLimited(code int, msg string) bool {
+	switch code {
+	case -32005, -32090, 429:
+		return true
+	}
+	msg = strings.ToLower(msg)
+	for _, s := range []string{"rate limit", "too many requests", "capacity", "limit exceeded"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "rpc"
+	}
+	return u.Scheme + "://" + u.Host
+}
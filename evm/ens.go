@@ -0,0 +1,75 @@
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// ENSRegistry is the ENS registry on Ethereum mainnet.
+const ENSRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// Namehash is the ENS node of name, whose labels must already be
+// normalized (lowercase ASCII is).
+func Namehash(name string) [32]byte {
+	var node [32]byte
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		l := keccak.Sum256([]byte(labels[i]))
+		node = keccak.Sum256(append(node[:], l[:]...))
+	}
+	return node
+}
+
+// ENSName is addr's primary ENS name, read through c on Ethereum mainnet at
+// block: its reverse record, kept only when the name resolves back to addr
+// as ENS clients require. It is empty when addr has no such name.
+func (c *Client) ENSName(ctx context.Context, addr, block string) (string, error) {
+	hexAddr := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X"))
+	rev := Namehash(hexAddr + ".addr.reverse")
+	resolver, err := c.ensResolver(ctx, rev, block)
+	if err != nil || resolver == "" {
+		return "", err
+	}
+	res, err := c.Call(ctx, resolver, Calldata("name(bytes32)", Static(rev[:])), block)
+	if err != nil || len(res) == 0 {
+		return "", err
+	}
+	name, err := DecodeString(res)
+	if err != nil || name == "" {
+		return "", err
+	}
+
+	node := Namehash(name)
+	fwd, err := c.ensResolver(ctx, node, block)
+	if err != nil || fwd == "" {
+		return "", err
+	}
+	res, err = c.Call(ctx, fwd, Calldata("addr(bytes32)", Static(node[:])), block)
+	if err != nil {
+		return "", err
+	}
+	got, err := DecodeAddress(res, 0)
+	if err != nil || got != "0x"+hexAddr {
+		return "", err
+	}
+	return name, nil
+}
+
+// ensResolver is node's resolver in the registry; empty when it has none.
+func (c *Client) ensResolver(ctx context.Context, node [32]byte, block string) (string, error) {
+	res, err := c.Call(ctx, ENSRegistry, Calldata("resolver(bytes32)", Static(node[:])), block)
+	if err != nil {
+		return "", err
+	}
+	r, err := DecodeAddress(res, 0)
+	if err != nil || r == "0x"+hex.EncodeToString(make([]byte, 20)) {
+		return "", err
+	}
+	return r, nil
+}
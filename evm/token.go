@@ -0,0 +1,115 @@
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// NativeToken is the placeholder address the cycle files use for the chain's
+// native currency.
+const NativeToken = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+
+func IsNative(token string) bool {
+	return strings.EqualFold(token, NativeToken)
+}
+
+// TransfersFrom is the log filter, for eth_subscribe or eth_getLogs, of
+// ERC-20 Transfer events out of any of from, whatever the token.
+func TransfersFrom(from ...string) (map[string]any, error) {
+	h := keccak.Sum256([]byte("Transfer(address,address,uint256)"))
+	senders := make([]string, len(from))
+	for i, a := range from {
+		w, err := EncodeAddress(a)
+		if err != nil {
+			return nil, err
+		}
+		senders[i] = "0x" + hex.EncodeToString(w)
+	}
+	return map[string]any{"topics": []any{"0x" + hex.EncodeToString(h[:]), senders}}, nil
+}
+
+// TokenBalance returns holder's balance of an ERC-20 token or, for
+// NativeToken, of the native currency.
+func (c *Client) TokenBalance(ctx context.Context, token, holder, block string) (*big.Int, error) {
+	if IsNative(token) {
+		return c.BalanceAt(ctx, holder, block)
+	}
+	h, err := EncodeAddress(holder)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Call(ctx, token, Calldata("balanceOf(address)", Static(h)), block)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeUint(res, 0)
+}
+
+func (c *Client) Decimals(ctx context.Context, token string) (int, error) {
+	if IsNative(token) {
+		return 18, nil
+	}
+	res, err := c.Call(ctx, token, Selector("decimals()"), "latest")
+	if err != nil {
+		return 0, err
+	}
+	d, err := DecodeUint(res, 0)
+	if err != nil {
+		return 0, err
+	}
+	if !d.IsInt64() || d.Int64() > MaxDecimals {
+		return 0, fmt.Errorf("token %s: implausible decimals %s", token, d)
+	}
+	return int(d.Int64()), nil
+}
+
+// Symbol reads an ERC-20 symbol(), as a string or, for older tokens, a
+// NUL-padded bytes32.
+func (c *Client) Symbol(ctx context.Context, token string) (string, error) {
+	if IsNative(token) {
+		return "", fmt.Errorf("token %s: the native currency has no symbol() to read", token)
+	}
+	res, err := c.Call(ctx, token, Selector("symbol()"), "latest")
+	if err != nil {
+		return "", err
+	}
+	if len(res) == 32 {
+		return strings.TrimRight(string(res), "\x00"), nil
+	}
+	sym, err := DecodeString(res)
+	if err != nil {
+		return "", fmt.Errorf("token %s: malformed symbol(): %w", token, err)
+	}
+	return sym, nil
+}
+
+// OwnerOf returns the current holder of an ERC-721 token.
+func (c *Client) OwnerOf(ctx context.Context, nft string, id *big.Int, block string) (string, error) {
+	w, err := EncodeUint(id)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.Call(ctx, nft, Calldata("ownerOf(uint256)", Static(w)), block)
+	if err != nil {
+		return "", err
+	}
+	return DecodeAddress(res, 0)
+}
+
+// TransferCalldata encodes ERC-20 transfer(to, amount).
+func TransferCalldata(to string, amount *big.Int) ([]byte, error) {
+	w, err := EncodeAddress(to)
+	if err != nil {
+		return nil, err
+	}
+	a, err := EncodeUint(amount)
+	if err != nil {
+		return nil, err
+	}
+	return Calldata("transfer(address,uint256)", Static(w), Static(a)), nil
+}
@@ -0,0 +1,61 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MaxDecimals is the most decimals a uint256 amount can have a digit for.
+const MaxDecimals = 77
+
+// CheckDecimals reports decimals outside [0, MaxDecimals], such as the -1 a
+// caller uses for "not known".
+func CheckDecimals(decimals int) error {
+	if decimals < 0 || decimals > MaxDecimals {
+		return fmt.Errorf("invalid decimals %d", decimals)
+	}
+	return nil
+}
+
+// FormatUnits renders amount with the given decimals, e.g. 1500000 with 6
+// decimals is "1.5". Trailing zeros are dropped. Decimals CheckDecimals
+// refuses are an error rather than base units printed as whole tokens.
+func FormatUnits(amount *big.Int, decimals int) (string, error) {
+	if err := CheckDecimals(decimals); err != nil {
+		return "", err
+	}
+	neg := amount.Sign() < 0
+	s := new(big.Int).Abs(amount).String()
+	if decimals > 0 {
+		if len(s) <= decimals {
+			s = strings.Repeat("0", decimals-len(s)+1) + s
+		}
+		whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+		s = whole
+		if frac != "" {
+			s += "." + frac
+		}
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s, nil
+}
+
+// ParseUnits is the inverse of FormatUnits; it rejects more fractional
+// digits than decimals allows rather than rounding.
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	if err := CheckDecimals(decimals); err != nil {
+		return nil, err
+	}
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("%q has more than %d decimals", s, decimals)
+	}
+	n, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
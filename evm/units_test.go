@@ -0,0 +1,49 @@
+package evm_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+func TestUnits(t *testing.T) {
+	cases := []struct {
+		base     string
+		decimals int
+		text     string
+	}{
+		{"1500000", 6, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"-2500", 3, "-2.5"},
+		{"42", 0, "42"},
+		{"1000000000000000000", 18, "1"},
+	}
+	for _, c := range cases {
+		n, _ := new(big.Int).SetString(c.base, 10)
+		if got, err := evm.FormatUnits(n, c.decimals); err != nil || got != c.text {
+			t.Errorf("FormatUnits(%s, %d) = %s, %v, want %s", c.base, c.decimals, got, err, c.text)
+		}
+		if got, err := evm.ParseUnits(c.text, c.decimals); err != nil || got.Cmp(n) != 0 {
+			t.Errorf("ParseUnits(%s, %d) = %v, %v, want %s", c.text, c.decimals, got, err, c.base)
+		}
+	}
+	if _, err := evm.ParseUnits("1.234", 2); err == nil {
+		t.Error("ParseUnits rounded")
+	}
+}
+
+// Unknown decimals must not pass as 0, which prints base units as tokens.
+func TestUnitsUnknownDecimals(t *testing.T) {
+	for _, d := range []int{-1, evm.MaxDecimals + 1} {
+		if evm.CheckDecimals(d) == nil {
+			t.Errorf("CheckDecimals(%d) passed", d)
+		}
+		if _, err := evm.ParseUnits("1", d); err == nil {
+			t.Errorf("ParseUnits with %d decimals passed", d)
+		}
+		if _, err := evm.FormatUnits(big.NewInt(1), d); err == nil {
+			t.Errorf("FormatUnits with %d decimals passed", d)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package evm
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A Subscription is one eth_subscribe over its own WebSocket connection,
+// which is all the reward tools need of the protocol: text messages, ping,
+// pong and close, without extensions.
+type Subscription struct {
+	conn net.Conn
+	br   *bufio.Reader
+	id   string
+	wmu  sync.Mutex // frames are written by Next (pongs) and the pinger
+	stop func() bool
+	done chan struct{}
+	once sync.Once
+}
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// wsPing is how often an idle connection is pinged; wsIdle is how long
+	// Next waits for any frame, pongs included, before giving up on it.
+	wsPing       = 30 * time.Second
+	wsIdle       = 90 * time.Second
+	wsMaxMessage = 16 << 20
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Subscribe dials the WebSocket endpoint rawURL (ws:// or wss://) and calls
+// eth_subscribe with params, e.g. "logs" and a filter. The connection closes
+// when ctx ends or on Close.
+func Subscribe(ctx context.Context, rawURL string, params ...any) (*Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "ws":
+		if port == "" {
+			port = "80"
+		}
+	case "wss":
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return nil, fmt.Errorf("%s: not a ws:// or wss:// endpoint", redact(rawURL))
+	}
+	d := net.Dialer{Timeout: 30 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+	s := &Subscription{conn: conn, br: bufio.NewReader(conn), done: make(chan struct{})}
+	s.stop = context.AfterFunc(ctx, func() { conn.Close() })
+	if err := s.handshake(u); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %w", redact(rawURL), err)
+	}
+	if err := s.subscribe(params); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %w", redact(rawURL), err)
+	}
+	go s.ping()
+	return s, nil
+}
+
+func (s *Subscription) handshake(u *url.URL) error {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{
+		"Upgrade":               {"websocket"},
+		"Connection":            {"Upgrade"},
+		"Sec-Websocket-Key":     {key},
+		"Sec-Websocket-Version": {"13"},
+	}}
+	s.conn.SetDeadline(time.Now().Add(30 * time.Second))
+	defer s.conn.SetDeadline(time.Time{})
+	if err := req.Write(s.conn); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(s.br, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("websocket upgrade: %s", resp.Status)
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-Websocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return errors.New("websocket upgrade: bad Sec-WebSocket-Accept")
+	}
+	return nil
+}
+
+// subscribe sends eth_subscribe and waits for the subscription ID.
+func (s *Subscription) subscribe(params []any) error {
+	b, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: "eth_subscribe", Params: params})
+	if err != nil {
+		return err
+	}
+	if err := s.write(opText, b); err != nil {
+		return err
+	}
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return err
+		}
+		var rr struct {
+			ID json.RawMessage `json:"id"`
+			rpcResponse
+		}
+		if err := json.Unmarshal(msg, &rr); err != nil || string(rr.ID) != "1" {
+			continue
+		}
+		if rr.Error != nil {
+			return fmt.Errorf("eth_subscribe: rpc error %d: %s", rr.Error.Code, rr.Error.Message)
+		}
+		return json.Unmarshal(rr.Result, &s.id)
+	}
+}
+
+// Next blocks for the next notification of the subscription and returns its
+// result.
+func (s *Subscription) Next() (json.RawMessage, error) {
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		var n struct {
+			Method string `json:"method"`
+			Params struct {
+				Subscription string          `json:"subscription"`
+				Result       json.RawMessage `json:"result"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(msg, &n) == nil && n.Method == "eth_subscription" && n.Params.Subscription == s.id {
+			return n.Params.Result, nil
+		}
+	}
+}
+
+// Close ends the subscription by closing its connection.
+func (s *Subscription) Close() error {
+	s.once.Do(func() {
+		s.stop()
+		close(s.done)
+		s.write(opClose, nil)
+	})
+	return s.conn.Close()
+}
+
+func (s *Subscription) ping() {
+	t := time.NewTicker(wsPing)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			if s.write(opPing, nil) != nil {
+				return
+			}
+		}
+	}
+}
+
+// read returns the next text or binary message, answering pings and
+// reporting a close as an error.
+func (s *Subscription) read() ([]byte, error) {
+	var msg []byte
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(wsIdle))
+		var h [2]byte
+		if _, err := io.ReadFull(s.br, h[:]); err != nil {
+			return nil, err
+		}
+		fin, op := h[0]&0x80 != 0, h[0]&0x0f
+		n := uint64(h[1] & 0x7f)
+		switch n {
+		case 126:
+			var b [2]byte
+			if _, err := io.ReadFull(s.br, b[:]); err != nil {
+				return nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			if _, err := io.ReadFull(s.br, b[:]); err != nil {
+				return nil, err
+			}
+			n = binary.BigEndian.Uint64(b[:])
+		}
+		var mask [4]byte
+		if h[1]&0x80 != 0 {
+			if _, err := io.ReadFull(s.br, mask[:]); err != nil {
+				return nil, err
+			}
+		}
+		if n > wsMaxMessage || uint64(len(msg))+n > wsMaxMessage {
+			return nil, fmt.Errorf("websocket message over %d bytes", wsMaxMessage)
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(s.br, payload); err != nil {
+			return nil, err
+		}
+		if h[1]&0x80 != 0 {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		switch op {
+		case opPing:
+			if err := s.write(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := 1005
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			return nil, fmt.Errorf("websocket closed by the server (%d)", code)
+		case opText, opBinary, opContinuation:
+			msg = append(msg, payload...)
+		default:
+			return nil, fmt.Errorf("websocket opcode %#x", op)
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// write sends one masked frame, as a client must.
+func (s *Subscription) write(op byte, payload []byte) error {
+	frame := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	_, err := s.conn.Write(frame)
+	return err
+}
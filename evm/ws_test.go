@@ -0,0 +1,154 @@
+package evm_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// wsServer upgrades one connection and hands it to serve.
+func wsServer(t *testing.T, serve func(c net.Conn, br *bufio.Reader)) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: "+base64.StdEncoding.EncodeToString(sum[:])+"\r\n\r\n")
+		serve(c, brw.Reader)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// readFrame reads one client frame, which must be masked.
+func readFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	var h [2]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	if h[1]&0x80 == 0 {
+		t.Fatal("client frame not masked")
+	}
+	n := int(h[1] & 0x7f)
+	if n == 126 {
+		var b [2]byte
+		io.ReadFull(br, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	}
+	var mask [4]byte
+	io.ReadFull(br, mask[:])
+	p := make([]byte, n)
+	io.ReadFull(br, p)
+	for i := range p {
+		p[i] ^= mask[i%4]
+	}
+	return h[0] & 0x0f, p
+}
+
+// writeFrames writes msg as an unmasked text message in frames of at most
+// size bytes, with a ping between the first two.
+func writeFrames(c net.Conn, msg string, size int) {
+	for i := 0; i < len(msg); i += size {
+		end := min(i+size, len(msg))
+		op := byte(0x1)
+		if i > 0 {
+			op = 0
+		}
+		if end == len(msg) {
+			op |= 0x80
+		}
+		c.Write(append([]byte{op, byte(end - i)}, msg[i:end]...))
+		if i == 0 {
+			c.Write([]byte{0x89, 2, 'h', 'i'})
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	pong := make(chan string, 1)
+	url := wsServer(t, func(c net.Conn, br *bufio.Reader) {
+		op, p := readFrame(t, br)
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		if err := json.Unmarshal(p, &req); op != 1 || err != nil || req.Method != "eth_subscribe" || req.Params[0] != "logs" {
+			t.Errorf("subscribe request %d %s", op, p)
+			return
+		}
+		writeFrames(c, `{"jsonrpc":"2.0","id":1,"result":"0xabc"}`, 100)
+		writeFrames(c, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xother","result":1}}`, 100)
+		writeFrames(c, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xabc","result":{"blockNumber":"0x10"}}}`, 20)
+		if op, p := readFrame(t, br); op == 0xa {
+			pong <- string(p)
+		}
+		readFrame(t, br)
+	})
+	sub, err := evm.Subscribe(context.Background(), url, "logs", map[string]any{"address": "0x01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	res, err := sub.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != `{"blockNumber":"0x10"}` {
+		t.Errorf("notification %s", res)
+	}
+	if p := <-pong; p != "hi" {
+		t.Errorf("pong %q", p)
+	}
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	url := wsServer(t, func(c net.Conn, br *bufio.Reader) {
+		readFrame(t, br)
+		writeFrames(c, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"subscriptions not supported"}}`, 120)
+	})
+	if _, err := evm.Subscribe(context.Background(), url, "logs"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestSubscribeClosed(t *testing.T) {
+	url := wsServer(t, func(c net.Conn, br *bufio.Reader) {
+		readFrame(t, br)
+		writeFrames(c, `{"jsonrpc":"2.0","id":1,"result":"0xabc"}`, 100)
+		c.Write([]byte{0x88, 2, 0x03, 0xe9})
+	})
+	sub, err := evm.Subscribe(context.Background(), url, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if _, err := sub.Next(); err == nil || !strings.Contains(err.Error(), "1001") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestTransfersFrom(t *testing.T) {
+	f, err := evm.TransfersFrom("0x00000000000000000000000000000000000000D1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(f)
+	want := `{"topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",["0x00000000000000000000000000000000000000000000000000000000000000d1"]]}`
+	if string(b) != want {
+		t.Errorf("got %s", b)
+	}
+}
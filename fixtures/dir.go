@@ -0,0 +1,106 @@
+package fixtures
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// DefaultPeriod is how long a generated cycle lasts.
+const DefaultPeriod = 14 * 24 * time.Hour
+
+// CycleOptions describes a generated cycle directory: one merkle file per
+// chain and reward type.
+type CycleOptions struct {
+	Cycle      int
+	Chains     []string
+	Types      []string
+	Recipients int
+	Tokens     int
+	Seed       int64
+	// Period spaces the cycles' timestamps; zero is DefaultPeriod.
+	Period time.Duration
+}
+
+// WriteCycle writes root/cycle-N as a published cycle has it: its merkle
+// files, manifest and stats.json. Each file's content depends only on the
+// options, its chain and type, and the cycle, so a regenerated directory
+// is identical. It returns the paths written.
+func WriteCycle(root string, opt CycleOptions) ([]string, error) {
+	if opt.Cycle <= 0 {
+		return nil, fmt.Errorf("fixtures: cycle must be positive, got %d", opt.Cycle)
+	}
+	if len(opt.Chains) == 0 || len(opt.Types) == 0 {
+		return nil, fmt.Errorf("fixtures: cycle %d needs at least one chain and type", opt.Cycle)
+	}
+	if opt.Period <= 0 {
+		opt.Period = DefaultPeriod
+	}
+	dir := filepath.Join(root, cycle.DirName(opt.Cycle))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	start := epoch + int64(opt.Cycle-1)*int64(opt.Period/time.Second)
+	m := &cycle.Manifest{Files: make(map[string]cycle.ManifestEntry)}
+	written := make([]string, 0, len(opt.Chains)*len(opt.Types)+2)
+	for _, chain := range opt.Chains {
+		for _, typ := range opt.Types {
+			n := cycle.Name{ChainID: chain, RewardType: typ, Cycle: opt.Cycle}
+			if err := n.Validate(); err != nil {
+				return nil, fmt.Errorf("fixtures: %w", err)
+			}
+			f, err := Generate(Options{
+				Recipients: opt.Recipients,
+				Tokens:     opt.Tokens,
+				Seed:       fileSeed(opt.Seed, n),
+				Start:      start,
+				End:        start + int64(opt.Period/time.Second),
+				Metadata:   fmt.Sprintf("fixture cycle %d %s %s", opt.Cycle, chain, typ),
+			})
+			if err != nil {
+				return nil, err
+			}
+			path := filepath.Join(dir, n.String())
+			if err := cycle.Write(path, f); err != nil {
+				return nil, err
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			digest, err := verify.Digest(path)
+			if err != nil {
+				return nil, err
+			}
+			m.Files[n.String()] = cycle.ManifestEntry{SHA256: digest, Size: fi.Size(), Root: f.Root}
+			written = append(written, path)
+		}
+	}
+	if err := m.Write(dir); err != nil {
+		return nil, err
+	}
+	s, err := summary.BuildStats(dir, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if err := summary.WriteStats(dir, s); err != nil {
+		return nil, err
+	}
+	written = append(written, filepath.Join(dir, cycle.ManifestName), filepath.Join(dir, cycle.StatsName))
+	sort.Strings(written)
+	return written, nil
+}
+
+// fileSeed derives a file's generator seed from the run's.
+func fileSeed(seed int64, n cycle.Name) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%s", seed, n)
+	return int64(h.Sum64() >> 1)
+}
@@ -0,0 +1,359 @@
+// Package graphql executes read-only GraphQL queries against a schema of Go
+// resolvers. It implements the part of the language clients send in
+// practice — named operations, variables, aliases, arguments and fragments —
+// without introspection, directives or mutations; argument types are checked
+// by the resolvers rather than a type system.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+type Schema struct {
+	Query *Object
+}
+
+// Object is a GraphQL object type. Scalar fields have a nil Type; object and
+// list-of-object fields name the element type.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+type Field struct {
+	Type *Object
+	// Resolve returns the field's value; a slice resolves to a list. When
+	// nil, the field is read from the parent: a map key or the struct field
+	// with that JSON name.
+	Resolve func(p Params) (any, error)
+}
+
+type Params struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+// String returns the string argument name, or "" when absent.
+func (p Params) String(name string) (string, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("argument %s: expected a string, got %v", name, v)
+	}
+}
+
+// Int returns the integer argument name and whether it was given. JSON
+// variables arrive as float64 and are accepted when whole.
+func (p Params) Int(name string) (int, bool, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return 0, false, nil
+	case int64:
+		return int(v), true, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("argument %s: expected an integer, got %v", name, p.Args[name])
+}
+
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type Response struct {
+	Data   any     `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string `json:"message"`
+	// Path is the response path of a failed field, with list indexes.
+	Path []any `json:"path,omitempty"`
+}
+
+// Do runs req. A document that does not parse or select unknown fields
+// yields no data; resolver errors null their field and are listed alongside
+// the rest of the result.
+func (s *Schema) Do(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	e := &executor{ctx: ctx, doc: doc, vars: make(map[string]any)}
+	for name, def := range op.vars {
+		if v, ok := req.Variables[name]; ok {
+			e.vars[name] = v
+		} else {
+			e.vars[name], _ = e.resolveValue(def)
+		}
+	}
+	data, err := e.object(s.Query, nil, op.sel, nil)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("document has %d operations; set operationName", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no operation named %q", name)
+}
+
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	vars   map[string]any
+	errors []Error
+}
+
+// object resolves sel against source of type t. Its error is a query error
+// that aborts execution; resolver errors are recorded in e.errors instead.
+func (e *executor) object(t *Object, source any, sel []selection, path []any) (*orderedMap, error) {
+	fields, err := e.collect(sel, nil, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	out := &orderedMap{}
+	for _, s := range fields {
+		key := s.key()
+		fpath := append(append([]any(nil), path...), key)
+		if s.name == "__typename" {
+			out.set(key, t.Name)
+			continue
+		}
+		f, ok := t.Fields[s.name]
+		if !ok {
+			return nil, fmt.Errorf("type %s has no field %q", t.Name, s.name)
+		}
+		if f.Type == nil && s.sel != nil {
+			return nil, fmt.Errorf("field %s.%s is a scalar and takes no selection", t.Name, s.name)
+		}
+		if f.Type != nil && s.sel == nil {
+			return nil, fmt.Errorf("field %s.%s needs a selection of %s fields", t.Name, s.name, f.Type.Name)
+		}
+		args := make(map[string]any, len(s.args))
+		for k, v := range s.args {
+			if args[k], err = e.resolveValue(v); err != nil {
+				return nil, err
+			}
+		}
+		var v any
+		if f.Resolve != nil {
+			v, err = f.Resolve(Params{Context: e.ctx, Source: source, Args: args})
+		} else {
+			v, err = defaultResolve(source, s.name)
+		}
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Path: fpath})
+			out.set(key, nil)
+			continue
+		}
+		if f.Type == nil {
+			out.set(key, v)
+			continue
+		}
+		done, err := e.complete(f.Type, v, s.sel, fpath)
+		if err != nil {
+			return nil, err
+		}
+		out.set(key, done)
+	}
+	return out, nil
+}
+
+func (e *executor) complete(t *Object, v any, sel []selection, path []any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	case reflect.Slice, reflect.Array:
+		out := make([]any, rv.Len())
+		for i := range out {
+			item, err := e.complete(t, rv.Index(i).Interface(), sel, append(append([]any(nil), path...), i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	}
+	return e.object(t, v, sel, path)
+}
+
+// collect flattens fragments into sel's fields, merging repeated response
+// keys.
+func (e *executor) collect(sel []selection, out []selection, visiting map[string]bool) ([]selection, error) {
+	for _, s := range sel {
+		switch {
+		case s.inline != nil:
+			var err error
+			if out, err = e.collect(s.inline, out, visiting); err != nil {
+				return nil, err
+			}
+		case s.spread != "":
+			f, ok := e.doc.fragments[s.spread]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", s.spread)
+			}
+			if visiting[s.spread] {
+				return nil, fmt.Errorf("fragment %q spreads itself", s.spread)
+			}
+			visiting[s.spread] = true
+			var err error
+			if out, err = e.collect(f.sel, out, visiting); err != nil {
+				return nil, err
+			}
+			delete(visiting, s.spread)
+		default:
+			merged := false
+			for i := range out {
+				if out[i].key() == s.key() {
+					if out[i].name != s.name {
+						return nil, fmt.Errorf("response key %q selects both %s and %s", s.key(), out[i].name, s.name)
+					}
+					out[i].sel = append(append([]selection(nil), out[i].sel...), s.sel...)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				out = append(out, s)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (e *executor) resolveValue(v value) (any, error) {
+	switch v.kind {
+	case 'v':
+		val, ok := e.vars[v.variable]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined by the operation", v.variable)
+		}
+		return val, nil
+	case '[':
+		out := make([]any, len(v.list))
+		for i, item := range v.list {
+			var err error
+			if out[i], err = e.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case '{':
+		out := make(map[string]any, len(v.obj))
+		for k, item := range v.obj {
+			var err error
+			if out[k], err = e.resolveValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v.lit, nil
+}
+
+func defaultResolve(source any, name string) (any, error) {
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return v.Interface(), nil
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if tag == name || (tag == "" && f.Name == name) {
+				return rv.Field(i).Interface(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no resolver for field %q", name)
+}
+
+// orderedMap is a JSON object that keeps fields in selection order, as
+// GraphQL responses must.
+type orderedMap struct {
+	keys []string
+	vals map[string]any
+}
+
+func (m *orderedMap) set(k string, v any) {
+	if m.vals == nil {
+		m.vals = make(map[string]any)
+	}
+	if _, ok := m.vals[k]; !ok {
+		m.keys = append(m.keys, k)
+	}
+	m.vals[k] = v
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(m.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
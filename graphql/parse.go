@@ -0,0 +1,452 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	name string
+	vars map[string]value // defaults, by name without "$"
+	sel  []selection
+}
+
+type fragment struct {
+	sel []selection
+}
+
+// selection is a field, or a fragment spread/inline fragment when spread or
+// inline is set.
+type selection struct {
+	alias, name string
+	args        map[string]value
+	sel         []selection
+	spread      string
+	inline      []selection
+}
+
+func (s selection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// value is an argument as written: a literal, a $variable, a list or an
+// input object.
+type value struct {
+	kind     byte // 'l'iteral, 'v'ariable, '[' list, '{' object
+	lit      any
+	variable string
+	list     []value
+	obj      map[string]value
+}
+
+type token struct {
+	kind byte // 'n'ame, 'i'nt, 'f'loat, 's'tring, 'p'unctuator, 0 at EOF
+	text string
+	pos  int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != 0 {
+		switch {
+		case p.is('p', "{"):
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{sel: sel})
+		case p.is('n', "query"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.is('n', "fragment"):
+			name, f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = f
+		case p.is('n', "mutation"), p.is('n', "subscription"):
+			return nil, p.errorf("only queries are supported")
+		default:
+			return nil, p.errorf("unexpected %q", p.tok.text)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.src[:p.tok.pos], "\n")
+	return fmt.Errorf("syntax error at line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) is(kind byte, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+func (p *parser) expect(kind byte, text string) error {
+	if !p.is(kind, text) {
+		return p.errorf("expected %q, got %q", text, p.tok.text)
+	}
+	return p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != 'n' {
+		return "", p.errorf("expected a name, got %q", p.tok.text)
+	}
+	s := p.tok.text
+	return s, p.next()
+}
+
+func (p *parser) operation() (*operation, error) {
+	if err := p.next(); err != nil { // "query"
+		return nil, err
+	}
+	op := &operation{vars: make(map[string]value)}
+	if p.tok.kind == 'n' {
+		op.name = p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is('p', "(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.is('p', ")") {
+			if err := p.expect('p', "$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect('p', ":"); err != nil {
+				return nil, err
+			}
+			if err := p.typeRef(); err != nil {
+				return nil, err
+			}
+			op.vars[name] = value{kind: 'l'}
+			if p.is('p', "=") {
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				if op.vars[name], err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	op.sel, err = p.selectionSet()
+	return op, err
+}
+
+// typeRef skips a variable type such as [String!]!; arguments are checked by
+// the resolvers.
+func (p *parser) typeRef() error {
+	if p.is('p', "[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect('p', "]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.is('p', "!") {
+		return p.next()
+	}
+	return nil
+}
+
+func (p *parser) fragment() (string, *fragment, error) {
+	if err := p.next(); err != nil { // "fragment"
+		return "", nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := p.expect('n', "on"); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.name(); err != nil {
+		return "", nil, err
+	}
+	sel, err := p.selectionSet()
+	return name, &fragment{sel: sel}, err
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect('p', "{"); err != nil {
+		return nil, err
+	}
+	out := make([]selection, 0)
+	for !p.is('p', "}") {
+		if p.tok.kind == 0 {
+			return nil, p.errorf("unterminated selection set")
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if len(out) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return out, p.next()
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.is('p', "...") {
+		if err := p.next(); err != nil {
+			return selection{}, err
+		}
+		if p.is('n', "on") || p.is('p', "{") {
+			if p.is('n', "on") {
+				if err := p.next(); err != nil {
+					return selection{}, err
+				}
+				if _, err := p.name(); err != nil {
+					return selection{}, err
+				}
+			}
+			sel, err := p.selectionSet()
+			return selection{inline: sel}, err
+		}
+		name, err := p.name()
+		return selection{spread: name}, err
+	}
+	var s selection
+	name, err := p.name()
+	if err != nil {
+		return s, err
+	}
+	s.name = name
+	if p.is('p', ":") {
+		if err := p.next(); err != nil {
+			return s, err
+		}
+		s.alias = name
+		if s.name, err = p.name(); err != nil {
+			return s, err
+		}
+	}
+	if p.is('p', "(") {
+		if err := p.next(); err != nil {
+			return s, err
+		}
+		s.args = make(map[string]value)
+		for !p.is('p', ")") {
+			arg, err := p.name()
+			if err != nil {
+				return s, err
+			}
+			if err := p.expect('p', ":"); err != nil {
+				return s, err
+			}
+			if s.args[arg], err = p.value(false); err != nil {
+				return s, err
+			}
+		}
+		if err := p.next(); err != nil {
+			return s, err
+		}
+	}
+	if p.is('p', "@") {
+		return s, p.errorf("directives are not supported")
+	}
+	if p.is('p', "{") {
+		s.sel, err = p.selectionSet()
+	}
+	return s, err
+}
+
+func (p *parser) value(constant bool) (value, error) {
+	t := p.tok
+	switch {
+	case t.kind == 'p' && t.text == "$" && !constant:
+		if err := p.next(); err != nil {
+			return value{}, err
+		}
+		name, err := p.name()
+		return value{kind: 'v', variable: name}, err
+	case t.kind == 'p' && t.text == "[":
+		if err := p.next(); err != nil {
+			return value{}, err
+		}
+		v := value{kind: '[', list: make([]value, 0)}
+		for !p.is('p', "]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return value{}, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, p.next()
+	case t.kind == 'p' && t.text == "{":
+		if err := p.next(); err != nil {
+			return value{}, err
+		}
+		v := value{kind: '{', obj: make(map[string]value)}
+		for !p.is('p', "}") {
+			k, err := p.name()
+			if err != nil {
+				return value{}, err
+			}
+			if err := p.expect('p', ":"); err != nil {
+				return value{}, err
+			}
+			if v.obj[k], err = p.value(constant); err != nil {
+				return value{}, err
+			}
+		}
+		return v, p.next()
+	case t.kind == 'i':
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return value{}, p.errorf("invalid int %s", t.text)
+		}
+		return value{kind: 'l', lit: n}, p.next()
+	case t.kind == 'f':
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return value{}, p.errorf("invalid float %s", t.text)
+		}
+		return value{kind: 'l', lit: f}, p.next()
+	case t.kind == 's':
+		return value{kind: 'l', lit: t.text}, p.next()
+	case t.kind == 'n':
+		v := value{kind: 'l', lit: t.text} // enum values resolve as strings
+		switch t.text {
+		case "true":
+			v.lit = true
+		case "false":
+			v.lit = false
+		case "null":
+			v.lit = nil
+		}
+		return v, p.next()
+	}
+	return value{}, p.errorf("expected a value, got %q", t.text)
+}
+
+func (p *parser) next() error {
+	src := p.src
+	for p.pos < len(src) {
+		c := src[p.pos]
+		if c == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	start := p.pos
+	if p.pos >= len(src) {
+		p.tok = token{pos: start}
+		return nil
+	}
+	c := src[p.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{|}", c) >= 0:
+		p.pos++
+		p.tok = token{kind: 'p', text: string(c), pos: start}
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: 'p', text: "...", pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(src) && (src[p.pos] == '_' || isLetter(src[p.pos]) || isDigit(src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: 'n', text: src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		kind := byte('i')
+		p.pos++
+		for p.pos < len(src) {
+			d := src[p.pos]
+			if d == '.' || d == 'e' || d == 'E' || ((d == '+' || d == '-') && (src[p.pos-1] == 'e' || src[p.pos-1] == 'E')) {
+				kind = 'f'
+			} else if !isDigit(d) {
+				break
+			}
+			p.pos++
+		}
+		p.tok = token{kind: kind, text: src[start:p.pos], pos: start}
+	case c == '"':
+		if strings.HasPrefix(src[p.pos:], `"""`) {
+			end := strings.Index(src[p.pos+3:], `"""`)
+			if end < 0 {
+				p.tok = token{pos: start}
+				return p.errorf("unterminated block string")
+			}
+			p.tok = token{kind: 's', text: src[p.pos+3 : p.pos+3+end], pos: start}
+			p.pos += end + 6
+			return nil
+		}
+		p.pos++
+		for p.pos < len(src) && src[p.pos] != '"' && src[p.pos] != '\n' {
+			if src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(src) || src[p.pos] != '"' {
+			p.tok = token{pos: start}
+			return p.errorf("unterminated string")
+		}
+		p.pos++
+		s, err := strconv.Unquote(src[start:p.pos])
+		if err != nil {
+			p.tok = token{pos: start}
+			return p.errorf("invalid string %s", src[start:p.pos])
+		}
+		p.tok = token{kind: 's', text: s, pos: start}
+	default:
+		p.tok = token{pos: start}
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
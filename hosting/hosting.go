@@ -0,0 +1,121 @@
+// Package hosting maps merkle files to the URLs reward-service fetches them
+// from. Most are served from raw GitHub, but a chain or reward type can be
+// hosted elsewhere, e.g. on a regional CDN:
+//
+//	{
+//	  "default": "https://raw.githubusercontent.com/KyberNetwork/fairflow-reward/refs/heads/main/{dir}/{file}",
+//	  "templates": {
+//	    "8453": "https://eu.cdn.example.com/fairflow/{dir}/{file}",
+//	    "8453_EG": "https://ap.cdn.example.com/fairflow/{dir}/{file}"
+//	  }
+//	}
+//
+// A template is looked up by <chain>_<type>, then <chain>, then the default.
+// {chain}, {type}, {cycle}, {dir} (cycle-N) and {file} are filled in; every
+// template ends in {dir}/{file} so a URL's cycle and file can be read back
+// from values.yaml.
+package hosting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+const DefaultConfigPath = "config/hosting.json"
+
+// DefaultTemplate is raw GitHub on main, where every file was served
+// before templates.
+const DefaultTemplate = "https://raw.githubusercontent.com/KyberNetwork/fairflow-reward/refs/heads/main/{dir}/{file}"
+
+type Config struct {
+	Default   string            `json:"default,omitempty"`
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// LoadConfig reads the hosting config; a missing file serves everything
+// from DefaultTemplate.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if c.Default != "" {
+		if err := check(c.Default); err != nil {
+			return nil, fmt.Errorf("%s: default: %w", path, err)
+		}
+	}
+	for k, t := range c.Templates {
+		if err := check(t); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, k, err)
+		}
+	}
+	return c, nil
+}
+
+func check(t string) error {
+	if !strings.HasPrefix(t, "https://") && !strings.HasPrefix(t, "http://") {
+		return fmt.Errorf("template %q is not an http(s) URL", t)
+	}
+	if !strings.HasSuffix(t, "/{dir}/{file}") {
+		return fmt.Errorf("template %q does not end in /{dir}/{file}", t)
+	}
+	return nil
+}
+
+// Template is the URL template n's files are served from.
+func (c *Config) Template(n cycle.Name) string {
+	if t, ok := c.Templates[n.ChainID+"_"+n.RewardType]; ok {
+		return t
+	}
+	if t, ok := c.Templates[n.ChainID]; ok {
+		return t
+	}
+	if c.Default != "" {
+		return c.Default
+	}
+	return DefaultTemplate
+}
+
+// URL is where file, n itself or one of its shards or patches, is served.
+func (c *Config) URL(n cycle.Name, file string) string {
+	return strings.NewReplacer(
+		"{chain}", n.ChainID,
+		"{type}", n.RewardType,
+		"{cycle}", strconv.Itoa(n.Cycle),
+		"{dir}", cycle.DirName(n.Cycle),
+		"{file}", file,
+	).Replace(c.Template(n))
+}
+
+// URLs are the values.yaml URLs of n: one per shard when the file under
+// root's cycle directory was split by the shard command.
+func (c *Config) URLs(root string, n cycle.Name) ([]string, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	idx, err := cycle.LoadShardIndex(filepath.Join(root, cycle.DirName(n.Cycle)), n)
+	if err != nil {
+		return nil, err
+	}
+	if idx == nil {
+		return []string{c.URL(n, n.String())}, nil
+	}
+	out := make([]string, len(idx.Shards))
+	for i, s := range idx.Shards {
+		out[i] = c.URL(n, s.File)
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+// Package jobs maps the kinds of queued side effects to the code that
+// retries them, for flush-queue and the serve daemon.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/KyberNetwork/fairflow-reward/alert"
+	"github.com/KyberNetwork/fairflow-reward/notion"
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/webhook"
+)
+
+// Handlers returns a handler for every kind whose credentials are present:
+// alerts need PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY, Notion writebacks
+// NOTION_TOKEN or an OAuth client. Jobs of other kinds stay queued.
+func Handlers(db *state.DB) map[string]queue.Handler {
+	h := map[string]queue.Handler{
+		webhook.JobDeliver: webhook.NewRegistry(db).Redeliver,
+	}
+	if sender := alert.FromEnv(); sender != nil {
+		h[alert.JobSend] = func(ctx context.Context, payload json.RawMessage) error {
+			var a alert.Alert
+			if err := json.Unmarshal(payload, &a); err != nil {
+				return err
+			}
+			return sender.Send(ctx, a)
+		}
+	}
+	if notionConfigured() {
+		h[notion.JobCreatePage] = func(ctx context.Context, payload json.RawMessage) error {
+			var j notion.CreatePageJob
+			if err := json.Unmarshal(payload, &j); err != nil {
+				return err
+			}
+			token, err := notion.ResolveToken(ctx, os.Getenv("NOTION_TOKEN"), notion.DefaultOAuthConfigPath)
+			if err != nil {
+				return err
+			}
+			_, err = notion.NewClient(token, j.Version).CreatePage(ctx, notion.Source{ID: j.DataSourceID, Legacy: j.Legacy}, j.Properties)
+			return err
+		}
+	}
+	return h
+}
+
+func notionConfigured() bool {
+	if os.Getenv("NOTION_TOKEN") != "" {
+		return true
+	}
+	c, err := notion.LoadOAuthConfig(notion.DefaultOAuthConfigPath)
+	return c != nil || err != nil
+}
@@ -0,0 +1,134 @@
+// Package keccak implements the legacy Keccak-256 hash used by Ethereum.
+package keccak
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Size is the Keccak-256 digest length in bytes.
+const Size = 32
+
+const rate = 136
+
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotc and piln drive the combined rho and pi steps.
+var rotc = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+
+var piln = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+
+func permute(a *[25]uint64) {
+	for round := 0; round < 24; round++ {
+		// theta
+		c0 := a[0] ^ a[5] ^ a[10] ^ a[15] ^ a[20]
+		c1 := a[1] ^ a[6] ^ a[11] ^ a[16] ^ a[21]
+		c2 := a[2] ^ a[7] ^ a[12] ^ a[17] ^ a[22]
+		c3 := a[3] ^ a[8] ^ a[13] ^ a[18] ^ a[23]
+		c4 := a[4] ^ a[9] ^ a[14] ^ a[19] ^ a[24]
+		d0 := c4 ^ bits.RotateLeft64(c1, 1)
+		d1 := c0 ^ bits.RotateLeft64(c2, 1)
+		d2 := c1 ^ bits.RotateLeft64(c3, 1)
+		d3 := c2 ^ bits.RotateLeft64(c4, 1)
+		d4 := c3 ^ bits.RotateLeft64(c0, 1)
+		for y := 0; y < 25; y += 5 {
+			a[y] ^= d0
+			a[y+1] ^= d1
+			a[y+2] ^= d2
+			a[y+3] ^= d3
+			a[y+4] ^= d4
+		}
+
+		// rho and pi
+		t := a[1]
+		for i := 0; i < 24; i++ {
+			j := piln[i]
+			t, a[j] = a[j], bits.RotateLeft64(t, rotc[i])
+		}
+
+		// chi
+		for y := 0; y < 25; y += 5 {
+			b0, b1, b2, b3, b4 := a[y], a[y+1], a[y+2], a[y+3], a[y+4]
+			a[y] = b0 ^ (^b1 & b2)
+			a[y+1] = b1 ^ (^b2 & b3)
+			a[y+2] = b2 ^ (^b3 & b4)
+			a[y+3] = b3 ^ (^b4 & b0)
+			a[y+4] = b4 ^ (^b0 & b1)
+		}
+
+		// iota
+		a[0] ^= roundConstants[round]
+	}
+}
+
+type digest struct {
+	state [25]uint64
+	buf   [rate]byte
+	n     int
+}
+
+// New returns a hash.Hash computing Keccak-256.
+func New() hash.Hash { return &digest{} }
+
+func (d *digest) Size() int      { return Size }
+func (d *digest) BlockSize() int { return rate }
+
+func (d *digest) Reset() {
+	d.state = [25]uint64{}
+	d.n = 0
+}
+
+func (d *digest) absorb() {
+	for i := 0; i < rate/8; i++ {
+		d.state[i] ^= binary.LittleEndian.Uint64(d.buf[i*8:])
+	}
+	permute(&d.state)
+	d.n = 0
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		c := copy(d.buf[d.n:], p)
+		d.n += c
+		p = p[c:]
+		if d.n == rate {
+			d.absorb()
+		}
+	}
+	return written, nil
+}
+
+func (d *digest) Sum(in []byte) []byte {
+	dup := *d
+	for i := dup.n; i < rate; i++ {
+		dup.buf[i] = 0
+	}
+	dup.buf[dup.n] ^= 0x01
+	dup.buf[rate-1] ^= 0x80
+	dup.absorb()
+	var out [Size]byte
+	for i := 0; i < Size/8; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], dup.state[i])
+	}
+	return append(in, out[:]...)
+}
+
+// Sum256 returns the Keccak-256 digest of data.
+func Sum256(data ...[]byte) [Size]byte {
+	d := digest{}
+	for _, b := range data {
+		d.Write(b)
+	}
+	var out [Size]byte
+	copy(out[:], d.Sum(nil))
+	return out
+}
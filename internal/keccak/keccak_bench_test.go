@@ -0,0 +1,22 @@
+package keccak_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// BenchmarkSum256 covers the 64-byte pair hash of tree building and a leaf
+// of a few tokens.
+func BenchmarkSum256(b *testing.B) {
+	for _, n := range []int{64, 320} {
+		in := pattern(n)
+		b.Run(fmt.Sprintf("bytes=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				keccak.Sum256(in)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package keccak_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// pattern is n bytes counting 0..250, for inputs around the 136-byte rate.
+func pattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// Known answers: the published Ethereum Keccak-256 values for the short
+// strings, and a reference implementation's for the rate boundaries.
+var vectors = []struct {
+	name string
+	in   []byte
+	want string
+}{
+	{"empty", nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+	{"abc", []byte("abc"), "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	{"hello world", []byte("hello world"), "47173285a8d7341e5e972fc677286384f802f8ef42a5ec5f03bbfa254cb01fad"},
+	{"transfer event", []byte("Transfer(address,address,uint256)"), "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+	{"rate-1", pattern(135), "cbdfd9dee5faad3818d6b06f95a219fd290b0e1706f6a82e5a595b9ce9faca62"},
+	{"rate", pattern(136), "7ce759f1ab7f9ce437719970c26b0a66ff11fe3e38e17df89cf5d29c7d7f807e"},
+	{"rate+1", pattern(137), "ac73d4fae68b8453f764007c1a20ce95994187861f0c3227a3a8e99a73a3b1db"},
+	{"two blocks", pattern(272), "8e2476e65823b24d96ebe239f2c1534cdf763e689e2410c3b1cb0c74e6177bfc"},
+	{"1000 bytes", pattern(1000), "af692982e84a5a9688359025660a7857cd28ee7c8d867cfa1677baf2e6d1f63b"},
+}
+
+func TestSum256(t *testing.T) {
+	for _, v := range vectors {
+		if got := keccak.Sum256(v.in); hex.EncodeToString(got[:]) != v.want {
+			t.Errorf("%s: got %x, want %s", v.name, got, v.want)
+		}
+	}
+}
+
+// Writes split anywhere must hash like one write, and Sum must leave the
+// state alone for more writes.
+func TestStreaming(t *testing.T) {
+	for _, v := range vectors {
+		for _, chunk := range []int{1, 7, 135, 136, 137} {
+			d := keccak.New()
+			for p := v.in; len(p) > 0; {
+				n := min(chunk, len(p))
+				d.Write(p[:n])
+				d.Sum(nil)
+				p = p[n:]
+			}
+			if got := hex.EncodeToString(d.Sum(nil)); got != v.want {
+				t.Errorf("%s in %d-byte writes: got %s, want %s", v.name, chunk, got, v.want)
+			}
+		}
+	}
+}
+
+func TestSumAppendsAndReset(t *testing.T) {
+	d := keccak.New()
+	d.Write([]byte("junk"))
+	d.Reset()
+	d.Write([]byte("abc"))
+	prefix := []byte{0xff}
+	got := d.Sum(prefix)
+	if !bytes.Equal(got[:1], prefix) || hex.EncodeToString(got[1:]) != vectors[1].want {
+		t.Errorf("Sum after Reset: got %x", got)
+	}
+	if d.Size() != keccak.Size || d.BlockSize() != 136 {
+		t.Errorf("Size %d, BlockSize %d", d.Size(), d.BlockSize())
+	}
+}
+
+func TestSum256Parts(t *testing.T) {
+	in := pattern(300)
+	want := keccak.Sum256(in)
+	if got := keccak.Sum256(in[:100], nil, in[100:250], in[250:]); got != want {
+		t.Errorf("Sum256 of parts: got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+// Package offline is --offline (or env FAIRFLOW_OFFLINE=1) for the analysis
+// commands, which then run from the cycle directories alone, as on an
+// airgapped audit machine. An offline run calls no Notion, RPC, tracing
+// collector or metrics gateway. A check that needs one is skipped through
+// Skip, which warns about it as it happens; when the run ends, the skipped
+// checks are listed again together.
+package offline
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/runsummary"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// telemetryEnv are the variables tracing and metrics export through.
+var telemetryEnv = []string{
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+	"PUSHGATEWAY_URL",
+}
+
+var (
+	mu      sync.Mutex
+	on      bool
+	skipped []string
+)
+
+type Flag struct {
+	on *bool
+}
+
+// AddFlags registers --offline on fs.
+func AddFlags(fs *flag.FlagSet) *Flag {
+	return &Flag{on: fs.Bool("offline", os.Getenv("FAIRFLOW_OFFLINE") == "1", "never touch the network: skip, and list, the checks that need Notion or an RPC (or env FAIRFLOW_OFFLINE=1)")}
+}
+
+// Apply turns offline mode on when --offline is set. Call it after fs is
+// parsed and the --program applied, before tracing and metrics are set up:
+// it drops their endpoints from the environment.
+func (f *Flag) Apply() {
+	if !*f.on {
+		return
+	}
+	for _, k := range telemetryEnv {
+		os.Unsetenv(k)
+	}
+	os.Setenv("OTEL_SDK_DISABLED", "true")
+	mu.Lock()
+	on = true
+	mu.Unlock()
+	tmpdir.AtExit(report)
+}
+
+// Enabled reports whether the run is offline.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return on
+}
+
+// Skip reports whether the run is offline and, when it is, records check as
+// skipped, so a check that needs the network reads
+//
+//	if !offline.Skip("holders of the positions (--owners)") {
+//		...
+//	}
+func Skip(check string) bool {
+	if !Enabled() {
+		return false
+	}
+	mu.Lock()
+	skipped = append(skipped, check)
+	mu.Unlock()
+	runsummary.Warnf("offline: skipped %s", check)
+	return true
+}
+
+func report(int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(skipped) == 0 {
+		fmt.Fprintln(os.Stderr, "Offline run: nothing needed the network.")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Offline run: skipped %d check(s) that need the network:\n", len(skipped))
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "  - %s\n", s)
+	}
+}
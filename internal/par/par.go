@@ -0,0 +1,76 @@
+// Package par splits index ranges across goroutines. Results are written by
+// index and errors are reported for the lowest failing range, so callers get
+// the same output at any worker count.
+package par
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// minChunk keeps small inputs on the calling goroutine; below this the
+// scheduling cost outweighs the hashing.
+const minChunk = 2048
+
+var workers atomic.Int64
+
+// SetWorkers sets the number of goroutines Range may use. n <= 0 means one
+// per CPU.
+func SetWorkers(n int) {
+	workers.Store(int64(n))
+}
+
+func Workers() int {
+	if n := int(workers.Load()); n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Range calls fn over disjoint [lo, hi) ranges covering [0, n).
+func Range(n int, fn func(lo, hi int)) {
+	_ = RangeErr(n, func(lo, hi int) error {
+		fn(lo, hi)
+		return nil
+	})
+}
+
+// RangeErr is Range for fallible work. Every range runs to completion and the
+// error from the lowest range is returned.
+func RangeErr(n int, fn func(lo, hi int) error) error {
+	w := Workers()
+	if max := (n + minChunk - 1) / minChunk; w > max {
+		w = max
+	}
+	if w <= 1 {
+		if n == 0 {
+			return nil
+		}
+		return fn(0, n)
+	}
+	size := (n + w - 1) / w
+	errs := make([]error, w)
+	var wg sync.WaitGroup
+	for c := 0; c < w; c++ {
+		lo, hi := c*size, (c+1)*size
+		if hi > n {
+			hi = n
+		}
+		if lo >= hi {
+			break
+		}
+		wg.Add(1)
+		go func(c, lo, hi int) {
+			defer wg.Done()
+			errs[c] = fn(lo, hi)
+		}(c, lo, hi)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
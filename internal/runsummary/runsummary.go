@@ -0,0 +1,168 @@
+// Package runsummary writes the machine-readable summary of a run that
+// --summary-file (or env FAIRFLOW_SUMMARY_FILE) asks for: the command, its
+// inputs, the files it wrote, the positions it spot-checked, how long it
+// took, its warnings and errors and its exit code. The GitHub Action
+// wrapper turns it into the job summary, so report formatting stays out of
+// the Go code.
+//
+// Importing the package gives a command the flag; commands whose
+// subcommands parse their own flag set Register it too. The summary is
+// written when the run ends through tmpdir's Exit or main's deferred
+// Cleanup. Files put in place through tmpdir are outputs already; files
+// written otherwise are added with Output.
+package runsummary
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+type Summary struct {
+	Command string `json:"command"`
+	// Flags are the flags given on the command line; secrets are redacted.
+	Flags   map[string]string `json:"flags"`
+	Args    []string          `json:"args"`
+	Outputs []string          `json:"outputs"`
+	// Samples are the spot checks' picks, so a reviewer can redo them.
+	Samples  []Sample  `json:"samples"`
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+	Warnings []string  `json:"warnings"`
+	Errors   []string  `json:"errors"`
+	ExitCode int       `json:"exit_code"`
+}
+
+// Sample is what a spot check picked from one file.
+type Sample struct {
+	File      string   `json:"file"`
+	Scheme    string   `json:"scheme"`
+	Seed      uint64   `json:"seed"`
+	Positions []string `json:"positions"`
+}
+
+var (
+	mu       sync.Mutex
+	path     string
+	started  = time.Now()
+	sets     []*flag.FlagSet
+	outputs  []string
+	samples  []Sample
+	warnings []string
+	errs     []string
+)
+
+func init() {
+	Register(flag.CommandLine)
+	tmpdir.AtExit(write)
+}
+
+// Register adds --summary-file to fs, whose flags are then the run's inputs.
+func Register(fs *flag.FlagSet) {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		path = os.Getenv("FAIRFLOW_SUMMARY_FILE")
+	}
+	fs.StringVar(&path, "summary-file", path, "write a JSON summary of the run to this file (or env FAIRFLOW_SUMMARY_FILE)")
+	sets = append(sets, fs)
+}
+
+// Output records a file the run wrote other than through tmpdir.
+func Output(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	outputs = append(outputs, p)
+}
+
+// Sampled records that the run spot-checked positions of file, picked with
+// seed under scheme (see internal/sample).
+func Sampled(file, scheme string, seed uint64, positions []string) {
+	mu.Lock()
+	defer mu.Unlock()
+	samples = append(samples, Sample{File: file, Scheme: scheme, Seed: seed, Positions: append(make([]string, 0), positions...)})
+}
+
+// Warnf prints a warning to stderr and records it.
+func Warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintln(os.Stderr, "WARNING:", msg)
+	mu.Lock()
+	defer mu.Unlock()
+	warnings = append(warnings, msg)
+}
+
+// Fail records the error the run is about to exit with.
+func Fail(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	errs = append(errs, err.Error())
+}
+
+func write(code int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return
+	}
+	s := Summary{
+		Command:  filepath.Base(os.Args[0]),
+		Flags:    make(map[string]string),
+		Args:     make([]string, 0),
+		Outputs:  make([]string, 0),
+		Samples:  append(make([]Sample, 0), samples...),
+		Started:  started.UTC(),
+		Duration: time.Since(started).Seconds(),
+		Warnings: append(make([]string, 0), warnings...),
+		Errors:   append(make([]string, 0), errs...),
+		ExitCode: code,
+	}
+	for _, fs := range sets {
+		if !fs.Parsed() {
+			continue
+		}
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "summary-file" {
+				return
+			}
+			v := f.Value.String()
+			if secret(f.Name) {
+				v = "[redacted]"
+			}
+			s.Flags[f.Name] = v
+		})
+		s.Args = append(s.Args, fs.Args()...)
+	}
+	for _, p := range append(tmpdir.Written(), outputs...) {
+		if !slices.Contains(s.Outputs, p) {
+			s.Outputs = append(s.Outputs, p)
+		}
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = tmpdir.WriteFile(path, append(b, '\n'), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "runsummary:", err)
+	}
+}
+
+// secret reports whether flag name holds a credential, e.g. --notion-token
+// or --linear-api-key.
+func secret(name string) bool {
+	for _, w := range strings.Split(name, "-") {
+		switch w {
+		case "token", "key", "secret", "password":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Package safepath turns names that come from outside the repo — storage
+// keys, shard indexes, flags and config — into paths that stay inside the
+// directory they are joined to. Both "/" and "\" separate elements on every
+// OS, so a name that is harmless on Linux cannot climb out of its directory
+// on a Windows laptop, and the checks behave the same on both.
+//
+// Long paths need nothing here: on Windows the os package adds the \\?\
+// prefix itself, as long as paths are built with filepath rather than by
+// hand.
+package safepath
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// reserved are the Windows device names, which open the device whatever
+// the directory and extension.
+var reserved = map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true}
+
+func init() {
+	for _, d := range "123456789" {
+		reserved["COM"+string(d)] = true
+		reserved["LPT"+string(d)] = true
+	}
+}
+
+// Local converts name, relative and separated by "/" or "\", to a path for
+// this OS. It rejects an empty or absolute name, a drive, volume or stream
+// (anything with ":"), a ".." element, and elements Windows cannot hold: a
+// device name, or one ending in a dot or space.
+func Local(name string) (string, error) {
+	s := strings.ReplaceAll(name, `\`, "/")
+	if s == "" || strings.HasPrefix(s, "/") || strings.ContainsAny(s, ":\x00") {
+		return "", fmt.Errorf("unsafe path %q", name)
+	}
+	elems := make([]string, 0)
+	for _, e := range strings.Split(s, "/") {
+		base, _, _ := strings.Cut(e, ".")
+		switch {
+		case e == "" || e == ".":
+			continue
+		case e == ".." || strings.HasSuffix(e, ".") || strings.HasSuffix(e, " ") || reserved[strings.ToUpper(strings.TrimRight(base, " "))]:
+			return "", fmt.Errorf("unsafe path %q", name)
+		}
+		elems = append(elems, e)
+	}
+	if len(elems) == 0 {
+		return "", fmt.Errorf("unsafe path %q", name)
+	}
+	return filepath.Join(elems...), nil
+}
+
+// Join is root joined with the Local form of each of elems.
+func Join(root string, elems ...string) (string, error) {
+	p := root
+	for _, e := range elems {
+		l, err := Local(e)
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(p, l)
+	}
+	return p, nil
+}
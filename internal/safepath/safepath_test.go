@@ -0,0 +1,42 @@
+package safepath
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ok := []struct{ name, want string }{
+		{"cycle-21/56_LM_21.json", filepath.Join("cycle-21", "56_LM_21.json")},
+		{`cycle-21\56_LM_21.json`, filepath.Join("cycle-21", "56_LM_21.json")},
+		{"./a//b/", filepath.Join("a", "b")},
+		{`a\.\b`, filepath.Join("a", "b")},
+		{"console", "console"},
+	}
+	for _, c := range ok {
+		got, err := Local(c.name)
+		if err != nil || got != c.want {
+			t.Errorf("Local(%q) = %q, %v; want %q", c.name, got, err, c.want)
+		}
+	}
+	for _, name := range []string{
+		"", ".", "/etc/passwd", `\Windows\System32`, `C:\x`, "C:x", `\\server\share\x`,
+		"../x", `..\x`, "a/../../x", `a\..\..\x`, "a/..",
+		"file.json:stream", "NUL", "aux.txt", "con.json.bak", `a\COM1.json`, "lpt9 ", "a./b", "a /b", "x\x00y",
+	} {
+		if got, err := Local(name); err == nil {
+			t.Errorf("Local(%q) = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	root := filepath.Join("out", "cycle-21")
+	got, err := Join(root, "56_LM_21.json")
+	if want := filepath.Join(root, "56_LM_21.json"); err != nil || got != want {
+		t.Fatalf("Join = %q, %v; want %q", got, err, want)
+	}
+	if _, err := Join(root, "ok", `..\..\..\escape`); err == nil {
+		t.Fatal("Join accepted a name climbing out of root")
+	}
+}
@@ -0,0 +1,51 @@
+// Package sample picks the positions spot checks look at, in a way a
+// reviewer can redo by hand or in any language: each position key
+// ("<erc721Addr>:<id>", lowercase as in the merkle file) is ranked by
+//
+//	keccak256(uint64be(seed) || key)
+//
+// compared as a big-endian number, ties broken by key, and the k lowest are
+// the sample. A position's rank depends only on the seed and its own key, so
+// adding or reordering positions in a file never changes whether another
+// one is picked over a third.
+//
+// Commands record the seed and what they picked with runsummary.Sampled.
+package sample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// Scheme names the ranking above in run summaries; it changes if the
+// ranking ever does.
+const Scheme = "keccak256(uint64be(seed)||key)"
+
+// Keys returns up to k of keys, lowest rank first. keys is not modified.
+func Keys(keys []string, k int, seed uint64) []string {
+	type ranked struct {
+		key  string
+		rank [32]byte
+	}
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], seed)
+	rs := make([]ranked, len(keys))
+	for i, key := range keys {
+		rs[i] = ranked{key, keccak.Sum256(s[:], []byte(key))}
+	}
+	slices.SortFunc(rs, func(a, b ranked) int {
+		if c := bytes.Compare(a.rank[:], b.rank[:]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	out := make([]string, 0, max(0, min(k, len(rs))))
+	for _, r := range rs[:cap(out)] {
+		out = append(out, r.key)
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+// Package tmpdir gives each run one private temp directory (under
+// FAIRFLOW_TMPDIR, or the system's) to stage files in before they are
+// renamed into place, so an interrupted run never leaves a half-written
+// <file>.tmp beside its target for a later directory scan to trip over.
+// Commands defer Cleanup in main and leave through Exit; an interrupt or
+// SIGTERM cleans up before the process dies.
+package tmpdir
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+var (
+	mu  sync.Mutex
+	dir string
+	// staged are files outside dir that Rename created, for Cleanup.
+	staged = make(map[string]bool)
+	// written are the files Rename moved into place, for Written.
+	written []string
+	hooks   []func(code int)
+)
+
+// Dir returns the run's temp directory, creating it on first use.
+func Dir() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if dir != "" {
+		return dir, nil
+	}
+	d, err := os.MkdirTemp(os.Getenv("FAIRFLOW_TMPDIR"), "fairflow-*")
+	if err != nil {
+		return "", err
+	}
+	dir = d
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		code := 1
+		if n, ok := s.(syscall.Signal); ok {
+			code = 128 + int(n)
+		}
+		finish(code)
+		Cleanup()
+		// Die of the signal as if it had not been caught. Where it cannot
+		// be re-raised, as on Windows, exit with the shell's code for it
+		// rather than run on without a temp directory.
+		signal.Stop(sig)
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			err = p.Signal(s)
+		}
+		if err != nil {
+			Exit(code)
+		}
+	}()
+	return dir, nil
+}
+
+// AtExit registers f to run once when the run ends, before its temp
+// directory is removed: with Exit's code, or 0 from main's deferred Cleanup.
+func AtExit(f func(code int)) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, f)
+}
+
+func finish(code int) {
+	mu.Lock()
+	hs := hooks
+	hooks = nil
+	mu.Unlock()
+	for _, f := range hs {
+		f(code)
+	}
+}
+
+// Written lists the files WriteFile, Copy and Rename have put in place so
+// far.
+func Written() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string(nil), written...)
+}
+
+// Cleanup removes the run's temp directory and whatever is still staged.
+func Cleanup() {
+	finish(0)
+	mu.Lock()
+	defer mu.Unlock()
+	if dir != "" {
+		os.RemoveAll(dir)
+		dir = ""
+	}
+	for p := range staged {
+		os.Remove(p)
+		delete(staged, p)
+	}
+}
+
+// Exit cleans up and exits with code, for the exits that skip main's
+// deferred Cleanup.
+func Exit(code int) {
+	finish(code)
+	Cleanup()
+	os.Exit(code)
+}
+
+// WriteFile is os.WriteFile through the run's temp directory: path is only
+// ever absent, its old content or b.
+func WriteFile(path string, b []byte, perm os.FileMode) error {
+	return Copy(path, bytes.NewReader(b), perm)
+}
+
+// Copy is WriteFile for the content of r.
+func Copy(path string, r io.Reader, perm os.FileMode) (err error) {
+	d, err := Dir()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(d, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return Rename(f.Name(), path)
+}
+
+// Rename moves src to dst. Across filesystems, where the system temp
+// directory often is, src is copied to a hidden file beside dst first,
+// which Cleanup removes if the run dies before it is renamed.
+func Rename(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		record(dst)
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	stage(out.Name(), true)
+	defer func() {
+		os.Remove(out.Name())
+		stage(out.Name(), false)
+	}()
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), dst); err != nil {
+		return err
+	}
+	record(dst)
+	return os.Remove(src)
+}
+
+func record(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Contains(written, p) {
+		written = append(written, p)
+	}
+}
+
+func stage(p string, on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if on {
+		staged[p] = true
+	} else {
+		delete(staged, p)
+	}
+}
@@ -0,0 +1,50 @@
+// Package maintenance flips the maintenance key reward-service reads from
+// its values.yaml (or a ConfigMap), which stops it serving rewards while a
+// rollout or an incident is in progress.
+package maintenance
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const DefaultKey = "maintenance"
+
+var keyLine = regexp.MustCompile(`^(\s*)([A-Za-z0-9_.-]+)(\s*:\s*)(["']?)(true|false)(["']?)(\s*(#.*)?)$`)
+
+// Set sets key to on in the YAML file at path, keeping its indentation and
+// quoting (ConfigMap data values are quoted strings), and reports whether
+// the file changed. The first line setting key to a boolean is the one
+// changed; without one, the key is appended at the top level.
+func Set(path, key string, on bool) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	val := strconv.FormatBool(on)
+	lines := strings.SplitAfter(string(b), "\n")
+	found := false
+	for i, l := range lines {
+		body := strings.TrimRight(l, "\r\n")
+		m := keyLine.FindStringSubmatch(body)
+		if m == nil || m[2] != key || m[4] != m[6] {
+			continue
+		}
+		if m[5] == val {
+			return false, nil
+		}
+		lines[i] = m[1] + m[2] + m[3] + m[4] + val + m[6] + m[7] + l[len(body):]
+		found = true
+		break
+	}
+	out := strings.Join(lines, "")
+	if !found {
+		if out != "" && !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		out += key + ": " + val + "\n"
+	}
+	return true, os.WriteFile(path, []byte(out), 0o644)
+}
@@ -0,0 +1,263 @@
+// Package mapping reads and checks config/notion_mappings.json, which
+// translates the Chain select and Type multi-select option names of the
+// Notion cycle database into chain IDs and reward type codes. The file has
+// one canonical form — sorted keys, two-space indent, trailing newline — so
+// edits to it diff cleanly and can be linted in CI.
+package mapping
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+const DefaultPath = "config/notion_mappings.json"
+
+type Mapping struct {
+	// Chains is Notion option name -> chain ID.
+	Chains map[string]string `json:"chains"`
+	// Types is Notion option name -> reward type code, as in merkle file
+	// names.
+	Types map[string]string `json:"types"`
+}
+
+func New() *Mapping {
+	return &Mapping{Chains: make(map[string]string), Types: make(map[string]string)}
+}
+
+// Load reads and validates the mapping at path.
+func Load(path string) (*Mapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mapping: %w", err)
+	}
+	m, problems := Parse(b)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: %w", path, errors.Join(problems...))
+	}
+	return m, nil
+}
+
+// Parse decodes b and returns every problem found: malformed JSON, unknown
+// or repeated keys, and whatever Validate reports. m is nil only if b could
+// not be decoded at all.
+func Parse(b []byte) (*Mapping, []error) {
+	problems := make([]error, 0)
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(b, &top); err != nil {
+		return nil, append(problems, fmt.Errorf("parse mapping json: %w", err))
+	}
+	m := New()
+	for _, k := range sortedKeys(top) {
+		var err error
+		switch k {
+		case "chains":
+			err = json.Unmarshal(top[k], &m.Chains)
+		case "types":
+			err = json.Unmarshal(top[k], &m.Types)
+		default:
+			err = errors.New("unknown key")
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", k, err))
+		}
+	}
+	problems = append(problems, duplicateKeys(b)...)
+	if m.Chains == nil {
+		m.Chains = make(map[string]string)
+	}
+	if m.Types == nil {
+		m.Types = make(map[string]string)
+	}
+	return m, append(problems, m.Validate()...)
+}
+
+// Mapped values end up in merkle file names, storage keys, URLs and
+// values.yaml, so they are held to what those can carry verbatim.
+const (
+	// MaxChainIDLen is the digits of the largest uint64.
+	MaxChainIDLen = 20
+	MaxTypeLen    = 16
+	// MaxNameLen is Notion's limit on select option names.
+	MaxNameLen = 100
+)
+
+var typeRe = regexp.MustCompile(`^[A-Z]+$`)
+
+// CheckChainID reports why id cannot be a chain ID: it must be a positive
+// decimal uint64 without leading zeros.
+func CheckChainID(id string) error {
+	if len(id) > MaxChainIDLen || !isNumeric(id) || id[0] == '0' {
+		return fmt.Errorf("chain ID %q is not a positive decimal number", id)
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("chain ID %q is out of range", id)
+	}
+	return nil
+}
+
+// CheckType reports why code cannot be a reward type code: it must be 1 to
+// MaxTypeLen upper-case ASCII letters, as in merkle file names.
+func CheckType(code string) error {
+	if len(code) > MaxTypeLen || !typeRe.MatchString(code) {
+		return fmt.Errorf("type code %q is not 1-%d upper-case letters, as in merkle file names", code, MaxTypeLen)
+	}
+	return nil
+}
+
+// checkName reports why an option name cannot be mapped.
+func checkName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("blank option name")
+	case utf8.RuneCountInString(name) > MaxNameLen:
+		return fmt.Errorf("option name %.20q... is longer than %d characters", name, MaxNameLen)
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return fmt.Errorf("option name %q has control characters", name)
+	}
+	return nil
+}
+
+// Validate checks that chain IDs and type codes pass CheckChainID and
+// CheckType and that chain IDs are used once, and that no name is blank,
+// too long, or the same as another once normalized.
+func (m *Mapping) Validate() []error {
+	problems := make([]error, 0)
+	byID := make(map[string][]string)
+	for _, name := range sortedKeys(m.Chains) {
+		id := m.Chains[name]
+		if err := checkName(name); err != nil {
+			problems = append(problems, fmt.Errorf("chains: %w", err))
+		}
+		if err := CheckChainID(id); err != nil {
+			problems = append(problems, fmt.Errorf("chains: %q: %w", name, err))
+		}
+		byID[id] = append(byID[id], name)
+	}
+	for _, id := range sortedKeys(byID) {
+		if names := byID[id]; len(names) > 1 {
+			problems = append(problems, fmt.Errorf("chains: chain ID %s is mapped by %q", id, names))
+		}
+	}
+	for _, name := range sortedKeys(m.Types) {
+		if err := checkName(name); err != nil {
+			problems = append(problems, fmt.Errorf("types: %w", err))
+		}
+		if err := CheckType(m.Types[name]); err != nil {
+			problems = append(problems, fmt.Errorf("types: %q: %w", name, err))
+		}
+	}
+	for _, kind := range []string{"chains", "types"} {
+		names := m.Chains
+		if kind == "types" {
+			names = m.Types
+		}
+		byNorm := make(map[string]string)
+		for _, name := range sortedKeys(names) {
+			n := NormalizeName(name)
+			if prev, dup := byNorm[n]; dup {
+				problems = append(problems, fmt.Errorf("%s: %q and %q are the same name", kind, prev, name))
+			}
+			byNorm[n] = name
+		}
+	}
+	return problems
+}
+
+// Format returns the canonical encoding of m.
+func (m *Mapping) Format() []byte {
+	b, _ := json.MarshalIndent(m, "", "  ")
+	return append(b, '\n')
+}
+
+func (m *Mapping) Write(path string) error {
+	return tmpdir.WriteFile(path, m.Format(), 0o644)
+}
+
+// Lint returns every problem with the file content b, including not being
+// in canonical form.
+func Lint(b []byte) []error {
+	m, problems := Parse(b)
+	if m != nil && !bytes.Equal(b, m.Format()) {
+		problems = append(problems, errors.New("not in canonical form (run mapping fmt)"))
+	}
+	return problems
+}
+
+// duplicateKeys finds keys repeated within one object, which encoding/json
+// silently resolves to the last value.
+func duplicateKeys(b []byte) []error {
+	problems := make([]error, 0)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	type frame struct {
+		path    string
+		keys    map[string]bool
+		last    string
+		isObj   bool
+		wantKey bool
+	}
+	stack := make([]*frame, 0)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return problems
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if key, ok := tok.(string); ok && top != nil && top.isObj && top.wantKey {
+			if top.keys[key] {
+				problems = append(problems, fmt.Errorf("%s: key %q appears more than once", top.path, key))
+			}
+			top.keys[key], top.last = true, key
+			top.wantKey = false
+			continue
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			path := "mapping"
+			if top != nil {
+				path = top.path + "." + top.last
+			}
+			stack = append(stack, &frame{path: path, keys: make(map[string]bool), isObj: tok == json.Delim('{'), wantKey: tok == json.Delim('{')})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && stack[len(stack)-1].isObj {
+			stack[len(stack)-1].wantKey = true
+		}
+	}
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,102 @@
+package mapping
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NormalizeName is how option names are compared: zero-width and other
+// format characters are dropped, every Unicode space (a non-breaking one
+// pasted from a doc, say) becomes a single ASCII space, the ends are
+// trimmed, and a letter followed by a combining accent is composed, as NFC
+// would for the Latin letters option names use.
+func NormalizeName(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Cf, r):
+			continue
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return compose(b.String())
+}
+
+// compositions maps a combining mark to base letter -> precomposed letter.
+var compositions = func() map[rune]map[rune]rune {
+	pairs := map[rune]string{
+		'\u0300': "AÀEÈIÌOÒUÙaàeèiìoòuù",
+		'\u0301': "AÁEÉIÍOÓUÚYÝaáeéiíoóuúyý",
+		'\u0302': "AÂEÊIÎOÔUÛaâeêiîoôuû",
+		'\u0303': "AÃNÑOÕaãnñoõ",
+		'\u0308': "AÄEËIÏOÖUÜaäeëiïoöuüyÿ",
+		'\u030a': "AÅaå",
+		'\u0327': "CÇcç",
+	}
+	out := make(map[rune]map[rune]rune, len(pairs))
+	for mark, letters := range pairs {
+		rs := []rune(letters)
+		out[mark] = make(map[rune]rune, len(rs)/2)
+		for i := 0; i+1 < len(rs); i += 2 {
+			out[mark][rs[i]] = rs[i+1]
+		}
+	}
+	return out
+}()
+
+func compose(s string) string {
+	rs := []rune(s)
+	out := make([]rune, 0, len(rs))
+	for _, r := range rs {
+		if n := len(out); n > 0 {
+			if c, ok := compositions[r][out[n-1]]; ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// Chain returns the chain ID of the Notion option name.
+func (m *Mapping) Chain(name string) (string, error) {
+	return lookup("chain", m.Chains, name, CheckChainID)
+}
+
+// Type returns the reward type code of the Notion option name.
+func (m *Mapping) Type(name string) (string, error) {
+	return lookup("type", m.Types, name, CheckType)
+}
+
+// lookup finds name in names, both sides normalized. A miss shows the raw
+// bytes, since the character that broke it is usually invisible. The value
+// found is checked again, for mappings built without Validate.
+func lookup(kind string, names map[string]string, name string, check func(string) error) (string, error) {
+	v, ok := names[name]
+	if !ok {
+		want := NormalizeName(name)
+		for _, k := range sortedKeys(names) {
+			if NormalizeName(k) == want {
+				v, ok = names[k], true
+				break
+			}
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("%s %q not found in mapping (raw bytes % x)", kind, name, name)
+	}
+	if err := check(v); err != nil {
+		return "", fmt.Errorf("%s %q: %w", kind, name, err)
+	}
+	return v, nil
+}
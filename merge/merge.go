@@ -0,0 +1,165 @@
+// Package merge combines merkle files that several teams deliver for the
+// same chain, type and cycle into the one file the distributor can take:
+// their recipients' amounts are summed per position and token and the tree
+// is rebuilt.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+// Input is one delivered file.
+type Input struct {
+	Name string
+	File *cycle.File
+}
+
+// Options overrides what the inputs must otherwise agree on.
+type Options struct {
+	Metadata string
+	Salt     string
+}
+
+type Source struct {
+	File       string            `json:"file"`
+	Root       string            `json:"root"`
+	Recipients int               `json:"recipients"`
+	Totals     map[string]string `json:"totals"`
+}
+
+// Report records what went into a merged file.
+type Report struct {
+	Sources    []Source `json:"sources"`
+	Recipients int      `json:"recipients"`
+	// Shared counts the recipients paid by more than one source, whose
+	// amounts were summed.
+	Shared int               `json:"shared"`
+	Totals map[string]string `json:"totals"`
+	Root   string            `json:"root"`
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, s := range r.Sources {
+		fmt.Fprintf(&b, "%s: %d recipients, root %s\n", s.File, s.Recipients, s.Root)
+		for _, t := range sortedKeys(s.Totals) {
+			fmt.Fprintf(&b, "  %s %s\n", t, s.Totals[t])
+		}
+	}
+	fmt.Fprintf(&b, "merged: %d recipients (%d in more than one source), root %s\n", r.Recipients, r.Shared, r.Root)
+	for _, t := range sortedKeys(r.Totals) {
+		fmt.Fprintf(&b, "  %s %s\n", t, r.Totals[t])
+	}
+	return b.String()
+}
+
+// Merge sums the inputs into one file. They must share timestamps and hash
+// scheme; metadata and salt too unless opt sets them.
+func Merge(inputs []Input, opt Options) (*cycle.File, *Report, error) {
+	if len(inputs) < 2 {
+		return nil, nil, errors.New("need at least two files to merge")
+	}
+	for _, in := range inputs {
+		if err := cycle.CheckLeafEncoding(in.File); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", in.Name, err)
+		}
+	}
+	first := inputs[0].File
+	f := &cycle.File{
+		StartTimestamp: first.StartTimestamp,
+		EndTimestamp:   first.EndTimestamp,
+		Metadata:       opt.Metadata,
+		Salt:           opt.Salt,
+		HashScheme:     first.HashScheme,
+	}
+	if f.Metadata == "" {
+		f.Metadata = first.Metadata
+	}
+	if f.Salt == "" {
+		f.Salt = first.Salt
+	}
+	r := &Report{Sources: make([]Source, 0, len(inputs))}
+	sum := make(map[string]map[string]*big.Int)
+	sources := make(map[string]int)
+	for _, in := range inputs {
+		g := in.File
+		switch {
+		case g.StartTimestamp != f.StartTimestamp || g.EndTimestamp != f.EndTimestamp:
+			return nil, nil, fmt.Errorf("%s covers %s-%s, %s covers %s-%s", in.Name, g.StartTimestamp, g.EndTimestamp, inputs[0].Name, f.StartTimestamp, f.EndTimestamp)
+		case g.HashScheme != f.HashScheme:
+			return nil, nil, fmt.Errorf("%s uses hash scheme %q, %s uses %q", in.Name, g.HashScheme, inputs[0].Name, f.HashScheme)
+		case opt.Metadata == "" && g.Metadata != f.Metadata:
+			return nil, nil, fmt.Errorf("%s and %s have different metadata; set it explicitly", in.Name, inputs[0].Name)
+		case opt.Salt == "" && g.Salt != f.Salt:
+			return nil, nil, fmt.Errorf("%s and %s have different salts; set it explicitly", in.Name, inputs[0].Name)
+		}
+		seen := make(map[string]bool, len(g.UserDatas))
+		for _, ud := range g.UserDatas {
+			am, err := ud.Leaf.AmountsByToken()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", in.Name, err)
+			}
+			k := ud.Leaf.Key()
+			if sum[k] == nil {
+				sum[k] = make(map[string]*big.Int)
+			}
+			for t, a := range am {
+				if sum[k][t] == nil {
+					sum[k][t] = new(big.Int)
+				}
+				sum[k][t].Add(sum[k][t], a)
+			}
+			if !seen[k] {
+				seen[k] = true
+				sources[k]++
+			}
+		}
+		totals, err := g.SumAmounts()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", in.Name, err)
+		}
+		r.Sources = append(r.Sources, Source{File: in.Name, Root: g.Root, Recipients: len(seen), Totals: decimals(totals)})
+	}
+
+	keys := make([]string, 0, len(sum))
+	for k := range sum {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f.UserDatas = make([]cycle.UserData, 0, len(keys))
+	for _, k := range keys {
+		if sources[k] > 1 {
+			r.Shared++
+		}
+		addr, id, _ := strings.Cut(k, ":")
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(addr, id, sum[k])})
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		return nil, nil, err
+	}
+	r.Recipients, r.Totals, r.Root = len(f.UserDatas), f.TotalAmounts, f.Root
+	return f, r, nil
+}
+
+func decimals(m map[string]*big.Int) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v.String()
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,165 @@
+package merge_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/merge"
+)
+
+const (
+	nft  = "0x00000000000000000000000000000000000000a1"
+	tokA = "0x00000000000000000000000000000000000000b1"
+	tokB = "0x00000000000000000000000000000000000000b2"
+)
+
+type pay struct {
+	id  string
+	tok string
+	amt int64
+}
+
+func file(t *testing.T, pays ...pay) *cycle.File {
+	t.Helper()
+	f := &cycle.File{StartTimestamp: "100", EndTimestamp: "200", Metadata: "m", Salt: "0x" + strings.Repeat("0", 64)}
+	for _, p := range pays {
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(nft, p.id, map[string]*big.Int{p.tok: big.NewInt(p.amt)})})
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name   string
+		files  [][]pay
+		want   map[string]string
+		shared int
+		totals map[string]string
+	}{
+		{
+			name:   "disjoint",
+			files:  [][]pay{{{"1", tokA, 10}}, {{"2", tokA, 5}}},
+			want:   map[string]string{nft + ":1 " + tokA: "10", nft + ":2 " + tokA: "5"},
+			totals: map[string]string{tokA: "15"},
+		},
+		{
+			name:   "same position in both files",
+			files:  [][]pay{{{"1", tokA, 10}, {"2", tokA, 1}}, {{"1", tokA, 5}}},
+			want:   map[string]string{nft + ":1 " + tokA: "15", nft + ":2 " + tokA: "1"},
+			shared: 1,
+			totals: map[string]string{tokA: "16"},
+		},
+		{
+			name:   "same position, different tokens",
+			files:  [][]pay{{{"1", tokA, 10}}, {{"1", tokB, 3}}},
+			want:   map[string]string{nft + ":1 " + tokA: "10", nft + ":1 " + tokB: "3"},
+			shared: 1,
+			totals: map[string]string{tokA: "10", tokB: "3"},
+		},
+		{
+			name:   "duplicate position within one file",
+			files:  [][]pay{{{"1", tokA, 10}, {"1", tokA, 2}}, {{"3", tokB, 4}}},
+			want:   map[string]string{nft + ":1 " + tokA: "12", nft + ":3 " + tokB: "4"},
+			totals: map[string]string{tokA: "12", tokB: "4"},
+		},
+		{
+			name:   "three files",
+			files:  [][]pay{{{"1", tokA, 1}}, {{"1", tokA, 2}}, {{"1", tokA, 3}}},
+			want:   map[string]string{nft + ":1 " + tokA: "6"},
+			shared: 1,
+			totals: map[string]string{tokA: "6"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var in []merge.Input
+			for i, ps := range c.files {
+				in = append(in, merge.Input{Name: string(rune('a' + i)), File: file(t, ps...)})
+			}
+			f, r, err := merge.Merge(in, merge.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, ud := range f.UserDatas {
+				am, err := ud.Leaf.AmountsByToken()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for tok, a := range am {
+					got[ud.Leaf.Key()+" "+tok] = a.String()
+				}
+			}
+			if len(got) != len(c.want) {
+				t.Errorf("merged %v, want %v", got, c.want)
+			}
+			for k, v := range c.want {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+			if r.Shared != c.shared {
+				t.Errorf("shared = %d, want %d", r.Shared, c.shared)
+			}
+			if len(r.Totals) != len(c.totals) {
+				t.Errorf("totals %v, want %v", r.Totals, c.totals)
+			}
+			for tok, v := range c.totals {
+				if r.Totals[tok] != v || f.TotalAmounts[tok] != v {
+					t.Errorf("total %s = %s (file %s), want %s", tok, r.Totals[tok], f.TotalAmounts[tok], v)
+				}
+			}
+			if r.Recipients != len(f.UserDatas) || r.Root != f.Root || len(r.Sources) != len(c.files) {
+				t.Errorf("report %+v does not describe the file", r)
+			}
+		})
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(*cycle.File)
+		want   string
+	}{
+		{"timestamps", func(f *cycle.File) { f.EndTimestamp = "300" }, "covers"},
+		{"metadata", func(f *cycle.File) { f.Metadata = "other" }, "different metadata"},
+		{"salt", func(f *cycle.File) { f.Salt = "0x" + strings.Repeat("1", 64) }, "different salts"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := file(t, pay{"1", tokA, 1}), file(t, pay{"2", tokA, 1})
+			c.change(b)
+			if err := cycle.Rebuild(b); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := merge.Merge([]merge.Input{{Name: "a", File: a}, {Name: "b", File: b}}, merge.Options{})
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("got %v, want %q", err, c.want)
+			}
+		})
+	}
+
+	a, b := file(t, pay{"1", tokA, 1}), file(t, pay{"2", tokA, 1})
+	b.Metadata, b.Salt = "other", "0x"+strings.Repeat("1", 64)
+	if err := cycle.Rebuild(b); err != nil {
+		t.Fatal(err)
+	}
+	opt := merge.Options{Metadata: "set", Salt: "0x" + strings.Repeat("2", 64)}
+	f, _, err := merge.Merge([]merge.Input{{Name: "a", File: a}, {Name: "b", File: b}}, opt)
+	if err != nil {
+		t.Fatalf("explicit metadata and salt: %v", err)
+	}
+	if f.Metadata != opt.Metadata || f.Salt != opt.Salt {
+		t.Errorf("got metadata %q salt %q, want the options", f.Metadata, f.Salt)
+	}
+
+	if _, _, err := merge.Merge([]merge.Input{{Name: "a", File: a}}, merge.Options{}); err == nil {
+		t.Error("merged a single file")
+	}
+}
@@ -0,0 +1,178 @@
+// Package merkle builds and checks the OpenZeppelin-style merkle trees used by
+// the fairflow distributors: leaves are sorted by hash, stored at the tail of a
+// flat 2n-1 node array, and pairs are hashed in sorted order. The hash
+// functions are a Scheme; the package-level functions use Standard.
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+)
+
+type Hash [32]byte
+
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return h, fmt.Errorf("invalid hash %q: %w", s, err)
+	}
+	if len(b) != len(h) {
+		return h, fmt.Errorf("invalid hash %q: expected 32 bytes, got %d", s, len(b))
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+func HashPair(a, b Hash) Hash { return Standard.HashPair(a, b) }
+
+type Tree struct {
+	scheme Scheme
+	nodes  []Hash
+	// pos maps every node hash to its index in nodes.
+	pos map[Hash]int
+}
+
+// Build sorts the leaves and lays the tree out the way StandardMerkleTree does.
+func Build(leaves []Hash) (*Tree, error) { return Standard.Build(leaves) }
+
+// Build lays the tree out like the package-level Build, hashing with s.
+func (s Scheme) Build(leaves []Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("merkle: no leaves")
+	}
+	sorted := append([]Hash(nil), leaves...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	nodes := make([]Hash, 2*len(sorted)-1)
+	for i, l := range sorted {
+		nodes[len(nodes)-1-i] = l
+	}
+	// Nodes on one level only depend on the level below, so each level is
+	// hashed in parallel, deepest first.
+	internal := len(nodes) - len(sorted)
+	levels := make([][2]int, 0)
+	for lo := 0; lo < internal; lo = 2*lo + 1 {
+		hi := 2*lo + 1
+		if hi > internal {
+			hi = internal
+		}
+		levels = append(levels, [2]int{lo, hi})
+	}
+	for l := len(levels) - 1; l >= 0; l-- {
+		base := levels[l][0]
+		par.Range(levels[l][1]-base, func(lo, hi int) {
+			for i := base + lo; i < base+hi; i++ {
+				nodes[i] = s.HashPair(nodes[2*i+1], nodes[2*i+2])
+			}
+		})
+	}
+	return s.newTree(nodes)
+}
+
+// FromNodes loads a flat node array and checks every internal node.
+func FromNodes(nodes []Hash) (*Tree, error) { return Standard.FromNodes(nodes) }
+
+func (s Scheme) FromNodes(nodes []Hash) (*Tree, error) {
+	if len(nodes) == 0 || len(nodes)%2 == 0 {
+		return nil, fmt.Errorf("merkle: invalid tree length %d", len(nodes))
+	}
+	err := par.RangeErr(len(nodes)/2, func(lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			if s.HashPair(nodes[2*i+1], nodes[2*i+2]) != nodes[i] {
+				return fmt.Errorf("merkle: node %d does not match its children", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.newTree(nodes)
+}
+
+func (s Scheme) newTree(nodes []Hash) (*Tree, error) {
+	t := &Tree{scheme: s, nodes: nodes, pos: make(map[Hash]int, len(nodes))}
+	for i, n := range nodes {
+		if _, dup := t.pos[n]; dup {
+			return nil, fmt.Errorf("merkle: duplicate node %s", n.Hex())
+		}
+		t.pos[n] = i
+	}
+	return t, nil
+}
+
+func (t *Tree) Root() Hash { return t.nodes[0] }
+
+func (t *Tree) Scheme() Scheme { return t.scheme }
+
+func (t *Tree) Nodes() []Hash { return t.nodes }
+
+func (t *Tree) LeafCount() int { return (len(t.nodes) + 1) / 2 }
+
+// Leaves returns the leaf hashes in sorted order.
+func (t *Tree) Leaves() []Hash {
+	out := make([]Hash, 0, t.LeafCount())
+	for i := len(t.nodes) - 1; i >= t.firstLeaf(); i-- {
+		out = append(out, t.nodes[i])
+	}
+	return out
+}
+
+func (t *Tree) firstLeaf() int { return len(t.nodes) / 2 }
+
+func (t *Tree) isLeaf(i int) bool { return i >= t.firstLeaf() }
+
+// Proof returns the sibling path for leaf, bottom-up.
+func (t *Tree) Proof(leaf Hash) ([]Hash, error) {
+	i, ok := t.pos[leaf]
+	if !ok || !t.isLeaf(i) {
+		return nil, fmt.Errorf("merkle: leaf %s not in tree", leaf.Hex())
+	}
+	proof := make([]Hash, 0)
+	for i > 0 {
+		proof = append(proof, t.nodes[sibling(i)])
+		i = (i - 1) / 2
+	}
+	return proof, nil
+}
+
+// LeafForProof finds the leaf whose path starts with proof[0], without
+// needing to know how leaves are encoded. A single-leaf tree has an empty
+// proof and its only leaf is the root.
+func (t *Tree) LeafForProof(proof []Hash) (Hash, bool) {
+	if len(proof) == 0 {
+		return t.nodes[0], len(t.nodes) == 1
+	}
+	i, ok := t.pos[proof[0]]
+	if !ok || i == 0 {
+		return Hash{}, false
+	}
+	s := sibling(i)
+	if !t.isLeaf(s) {
+		return Hash{}, false
+	}
+	return t.nodes[s], true
+}
+
+func sibling(i int) int {
+	if i%2 == 1 {
+		return i + 1
+	}
+	return i - 1
+}
+
+func ProcessProof(leaf Hash, proof []Hash) Hash { return Standard.ProcessProof(leaf, proof) }
+
+func Verify(root, leaf Hash, proof []Hash) bool { return Standard.Verify(root, leaf, proof) }
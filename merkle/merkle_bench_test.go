@@ -0,0 +1,68 @@
+package merkle
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+)
+
+func benchSizes() []int {
+	if os.Getenv("FAIRFLOW_BENCH_LARGE") != "" {
+		return []int{100_000, 1_000_000, 5_000_000}
+	}
+	return []int{100_000}
+}
+
+// benchWorkers compares serial hashing with one goroutine per CPU.
+func benchWorkers() []int {
+	if n := runtime.NumCPU(); n > 1 {
+		return []int{1, n}
+	}
+	return []int{1}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	defer par.SetWorkers(0)
+	for _, n := range benchSizes() {
+		leaves := randomLeaves(rand.New(rand.NewSource(1)), n)
+		for _, w := range benchWorkers() {
+			par.SetWorkers(w)
+			b.Run(fmt.Sprintf("leaves=%d/workers=%d", n, w), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := Build(leaves); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkProofIndex measures loading a stored tree and serving a proof for
+// every leaf, the path verify and the proof indexer take.
+func BenchmarkProofIndex(b *testing.B) {
+	for _, n := range benchSizes() {
+		tr, err := Build(randomLeaves(rand.New(rand.NewSource(1)), n))
+		if err != nil {
+			b.Fatal(err)
+		}
+		nodes, leaves := tr.Nodes(), tr.Leaves()
+		b.Run(fmt.Sprintf("leaves=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx, err := FromNodes(nodes)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, l := range leaves {
+					if _, err := idx.Proof(l); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+)
+
+func randomLeaves(r *rand.Rand, n int) []Hash {
+	out := make([]Hash, n)
+	for i := range out {
+		r.Read(out[i][:])
+	}
+	return out
+}
+
+// leavesFromSeed derives n distinct leaves deterministically from data.
+func leavesFromSeed(data []byte, n int) []Hash {
+	out := make([]Hash, n)
+	var idx [8]byte
+	for i := range out {
+		binary.BigEndian.PutUint64(idx[:], uint64(i))
+		out[i] = keccak.Sum256(data, idx[:])
+	}
+	return out
+}
+
+func checkTree(t *testing.T, leaves []Hash) *Tree {
+	t.Helper()
+	tr, err := Build(leaves)
+	if err != nil {
+		t.Fatalf("build %d leaves: %v", len(leaves), err)
+	}
+	if tr.LeafCount() != len(leaves) {
+		t.Fatalf("leaf count %d, want %d", tr.LeafCount(), len(leaves))
+	}
+	for _, l := range leaves {
+		p, err := tr.Proof(l)
+		if err != nil {
+			t.Fatalf("proof: %v", err)
+		}
+		if !Verify(tr.Root(), l, p) {
+			t.Fatalf("proof for %s does not verify", l.Hex())
+		}
+		got, ok := tr.LeafForProof(p)
+		if !ok || got != l {
+			t.Fatalf("LeafForProof did not recover %s", l.Hex())
+		}
+	}
+	reloaded, err := FromNodes(tr.Nodes())
+	if err != nil {
+		t.Fatalf("round-trip FromNodes: %v", err)
+	}
+	if reloaded.Root() != tr.Root() {
+		t.Fatalf("round-trip root changed")
+	}
+	return tr
+}
+
+func TestEveryLeafVerifies(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 1; n <= 70; n++ {
+		checkTree(t, randomLeaves(r, n))
+	}
+	checkTree(t, randomLeaves(r, 1025))
+}
+
+func TestDeterministicUnderPermutation(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, n := range []int{2, 3, 17, 64, 333} {
+		leaves := randomLeaves(r, n)
+		want := checkTree(t, leaves).Root()
+		for round := 0; round < 5; round++ {
+			r.Shuffle(len(leaves), func(i, j int) { leaves[i], leaves[j] = leaves[j], leaves[i] })
+			tr, err := Build(leaves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.Root() != want {
+				t.Fatalf("n=%d: root changed after permutation", n)
+			}
+		}
+	}
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	defer par.SetWorkers(0)
+	leaves := randomLeaves(rand.New(rand.NewSource(5)), 20_000)
+	par.SetWorkers(1)
+	want := checkTree(t, leaves).Nodes()
+	for _, w := range []int{2, 3, 16} {
+		par.SetWorkers(w)
+		got := checkTree(t, leaves).Nodes()
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("workers=%d: node %d differs from serial build", w, i)
+			}
+		}
+	}
+	corrupt := append([]Hash(nil), want...)
+	corrupt[len(corrupt)-1][0] ^= 0x01
+	corrupt[len(corrupt)-2][0] ^= 0x01
+	par.SetWorkers(1)
+	_, serialErr := FromNodes(corrupt)
+	par.SetWorkers(16)
+	_, parErr := FromNodes(corrupt)
+	if serialErr == nil || parErr == nil || serialErr.Error() != parErr.Error() {
+		t.Fatalf("FromNodes errors differ: serial %v, parallel %v", serialErr, parErr)
+	}
+}
+
+func TestTamperedProofFails(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	leaves := randomLeaves(r, 40)
+	tr := checkTree(t, leaves)
+	for _, l := range leaves {
+		p, _ := tr.Proof(l)
+		p[len(p)-1][0] ^= 0x01
+		if Verify(tr.Root(), l, p) {
+			t.Fatalf("tampered proof for %s verified", l.Hex())
+		}
+	}
+}
+
+func TestFromNodesRejectsCorruption(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	tr := checkTree(t, randomLeaves(r, 9))
+	nodes := append([]Hash(nil), tr.Nodes()...)
+	nodes[len(nodes)-1][5] ^= 0xff
+	if _, err := FromNodes(nodes); err == nil {
+		t.Fatal("corrupted leaf accepted")
+	}
+	if _, err := FromNodes(nodes[:len(nodes)-1]); err == nil {
+		t.Fatal("even-length tree accepted")
+	}
+}
+
+func FuzzBuildVerify(f *testing.F) {
+	f.Add([]byte("seed"), uint16(1))
+	f.Add([]byte{}, uint16(2))
+	f.Add([]byte{0xff, 0x00}, uint16(300))
+	f.Fuzz(func(t *testing.T, data []byte, n uint16) {
+		count := int(n%512) + 1
+		checkTree(t, leavesFromSeed(data, count))
+	})
+}
+
+func FuzzFromNodes(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(make([]byte, 96))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		nodes := make([]Hash, len(data)/32)
+		for i := range nodes {
+			copy(nodes[i][:], data[i*32:])
+		}
+		tr, err := FromNodes(nodes)
+		if err != nil {
+			return
+		}
+		// Anything accepted must be internally consistent.
+		for _, l := range tr.Leaves() {
+			p, err := tr.Proof(l)
+			if err != nil || !Verify(tr.Root(), l, p) {
+				t.Fatalf("accepted tree has unverifiable leaf %s", l.Hex())
+			}
+		}
+	})
+}
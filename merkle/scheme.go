@@ -0,0 +1,91 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// Scheme is how a distributor hashes leaves and combines nodes. Every scheme
+// here sorts each pair before hashing it, so proofs carry no left/right
+// flags.
+type Scheme struct {
+	Name string
+	leaf func(enc []byte) Hash
+	node func(a, b []byte) Hash
+}
+
+var (
+	// Standard is OpenZeppelin's StandardMerkleTree: leaves are
+	// keccak256(keccak256(abi.encode(...))), pairs are keccak256.
+	Standard = Scheme{Name: "standard", leaf: doubleKeccakLeaf, node: keccakPair}
+	// Keccak is the older plain form (merkletreejs with sortPairs): leaves
+	// are a single keccak256 of the encoding.
+	Keccak = Scheme{Name: "keccak", leaf: keccakLeaf, node: keccakPair}
+	// SHA256 hashes leaves and pairs with sha256.
+	SHA256 = Scheme{Name: "sha256", leaf: sha256Leaf, node: sha256Pair}
+)
+
+var schemes = []Scheme{Standard, Keccak, SHA256}
+
+// SchemeByName returns the scheme called name; "" is Standard.
+func SchemeByName(name string) (Scheme, error) {
+	if name == "" {
+		return Standard, nil
+	}
+	for _, s := range schemes {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	names := make([]string, len(schemes))
+	for i, s := range schemes {
+		names[i] = s.Name
+	}
+	return Scheme{}, fmt.Errorf("merkle: unknown hash scheme %q (known: %s)", name, strings.Join(names, ", "))
+}
+
+// LeafHash hashes a leaf's ABI encoding.
+func (s Scheme) LeafHash(enc []byte) Hash { return s.leaf(enc) }
+
+func (s Scheme) HashPair(a, b Hash) Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return s.node(a[:], b[:])
+}
+
+func (s Scheme) ProcessProof(leaf Hash, proof []Hash) Hash {
+	h := leaf
+	for _, p := range proof {
+		h = s.HashPair(h, p)
+	}
+	return h
+}
+
+func (s Scheme) Verify(root, leaf Hash, proof []Hash) bool {
+	return s.ProcessProof(leaf, proof) == root
+}
+
+func keccakLeaf(enc []byte) Hash { return keccak.Sum256(enc) }
+
+func doubleKeccakLeaf(enc []byte) Hash {
+	inner := keccak.Sum256(enc)
+	return keccak.Sum256(inner[:])
+}
+
+func keccakPair(a, b []byte) Hash { return keccak.Sum256(a, b) }
+
+func sha256Leaf(enc []byte) Hash { return sha256.Sum256(enc) }
+
+func sha256Pair(a, b []byte) Hash {
+	h := sha256.New()
+	h.Write(a)
+	h.Write(b)
+	var out Hash
+	h.Sum(out[:0])
+	return out
+}
@@ -0,0 +1,265 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/keccak"
+)
+
+// The two leaves of the OpenZeppelin StandardMerkleTree README example:
+// abi.encode(address, uint256) of (0x1111..., 5e18) and (0x2222..., 2.5e18).
+var readmeLeaves = []string{
+	"0000000000000000000000001111111111111111111111111111111111111111" + "0000000000000000000000000000000000000000000000004563918244f40000",
+	"0000000000000000000000002222222222222222222222222222222222222222" + "00000000000000000000000000000000000000000000000022b1c8c1227a0000",
+}
+
+func TestSchemeVectors(t *testing.T) {
+	cases := []struct {
+		scheme Scheme
+		root   string
+	}{
+		// The root printed in the StandardMerkleTree README.
+		{Standard, "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"},
+		// keccak256 leaves with sorted keccak256 pairs, as merkletreejs
+		// builds with {sortPairs: true}.
+		{Keccak, "0xdbecec1f5c9f90da290eeb9e2c207f1e3c059af19dccbd040d31e80ff997f716"},
+		// sha256 leaves and sorted pairs, cross-checked with Python hashlib.
+		{SHA256, "0xb8d669b333b89e8db29d2ef78dd7ddd087907a814288bd75bdf7b5b3d0c2fdb9"},
+	}
+	for _, c := range cases {
+		leaves := make([]Hash, len(readmeLeaves))
+		for i, l := range readmeLeaves {
+			enc, _ := hex.DecodeString(l)
+			leaves[i] = c.scheme.LeafHash(enc)
+		}
+		tr, err := c.scheme.Build(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tr.Root().Hex(); got != c.root {
+			t.Errorf("%s: root %s, want %s", c.scheme.Name, got, c.root)
+		}
+		for _, l := range leaves {
+			p, _ := tr.Proof(l)
+			if !c.scheme.Verify(tr.Root(), l, p) {
+				t.Errorf("%s: proof does not verify", c.scheme.Name)
+			}
+		}
+		if _, err := c.scheme.FromNodes(tr.Nodes()); err != nil {
+			t.Errorf("%s: FromNodes: %v", c.scheme.Name, err)
+		}
+	}
+}
+
+type leafProof struct {
+	leaf  string
+	proof []string
+}
+
+// Vectors from the published cycle files, trees a deployed distributor
+// verifies claims against. Each leaf is the tree node its position's proof
+// starts from: the files do not show how the distributor encodes a leaf (see
+// cycle.CheckLeafEncoding), so these pin down pair hashing and proofs only.
+var deployedVectors = []struct {
+	file   string
+	root   string
+	proofs []leafProof
+}{
+	{
+		file: "56_LM_12.json",
+		root: "0xc35d8f854ee68786f47c0cef46749661ea148fcd505dd21eee5c62c1c4bf2084",
+		proofs: []leafProof{
+			{ // userDatas[0], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:56142
+				leaf: "0xcf7733479df6acd802e7f57625f0c81b10dfc7ca56cd83681caa7578efec44f5",
+				proof: []string{
+					"0xcef365c1da5fb41e735923cc2968e2222dd5d97300ebd951bfb18cfd96b187d1",
+					"0xe7107838be27113d4dc53833adab04a6d1faa0e75542e9b32b33893b0a169776",
+					"0x7c5f210f8a9abbb210c9830740eb7aa7a35f2b08007b38ac6571ff7cadc1ff2b",
+					"0x281482557603fdabe6dd5092a8291474551f2e5a646f433c048c3b16d7abae89",
+					"0x13cea67129199a8d216053cc0ab663ae5b618b95e3e1849ee61b43aff6325b02",
+					"0x953d564bfc093b79bb8ed58806cef8e32e4c31006a30c3a7abe77f44197873e2",
+					"0x881f7ea5b418095c13414dfedd2affb1f995c807896fe882b3011baadaed0f29",
+					"0xee2712a8cdfcaf96cbd76759616a4321352dd00f082332a173ccb330c59dc719",
+				},
+			},
+			{ // userDatas[165], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:80312
+				leaf: "0x705b3b403073e2918383da3e8372659e3bdd9fbec64a7b71661b1c54417aedaa",
+				proof: []string{
+					"0x6fd3d82c6e31e1738bb8082c6295399c0877559d6069ec1e86775e834d62f7f3",
+					"0x6fce90fb68d7d068c77529e260787d1bc10dfd6cd7cb8b5cb1794055e63c816c",
+					"0xe31ea2fc868e40182b944b4d01d0cb08cb9687b3bda57563e31978b116a9c1ef",
+					"0xf94af77700dccd57c17f997dc2fc4bc2e4af31bb9f2b23f5abb2becd06215004",
+					"0xc3150d819d8bd0961c4a01f65e65edd4b63e263d5e260fc977efd9465bbbb601",
+					"0xae85d0f4956f3c0b45619538bfa61c931b9b0897be15749660aed9079e8fe46e",
+					"0x520589940d29ff552fc5d36f1da713eee877205369daf3afab4056a117301fa9",
+					"0x0b217e9bdb7c505b0ab5a18d83693ae8b7ed0b941c4c2d943df7354dbc2587e3",
+				},
+			},
+			{ // userDatas[329], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:55160
+				leaf: "0x95d86eab883c4507698f6a3910124c2ec1e7488fc09e1d809c5a36ca5d883504",
+				proof: []string{
+					"0x92fb998b7c947734779aeacc76906bdbf4758a38d1b2454a17b6a45fb0044954",
+					"0x4c0ccd3f5d353bef04a5d45eb0411ce7d81d3dea1b70298e5d44e023243c573e",
+					"0x184b56e348afa9aa31e079f698ea949e2b29551253e167142dad77538a4bc07e",
+					"0x897a5ac736d44fa0fda5591e86366cc911ca47656d30ff7d110b20bc3b23d64e",
+					"0x22009beadbe83c9092188875fd187bd9a95f91986508e24860da640934457264",
+					"0x500f6b4cbf785772925d88d3d503bc9c6d817abe4db02d2c6eb8fd02ff6bc70e",
+					"0x520589940d29ff552fc5d36f1da713eee877205369daf3afab4056a117301fa9",
+					"0x0b217e9bdb7c505b0ab5a18d83693ae8b7ed0b941c4c2d943df7354dbc2587e3",
+				},
+			},
+		},
+	},
+	{
+		file: "56_EG_12.json",
+		root: "0xcd7c6865b2f429966815a0cb5ee97c4eaa3fb87afcb7a0e9b1980e78d390ab5e",
+		proofs: []leafProof{
+			{ // userDatas[0], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:77692
+				leaf: "0xcd20a3a3b8c39229eaa2347ab4722b3d9e0f336e0bc97800220d709492b06801",
+				proof: []string{
+					"0xcca669c53f8458b839d358f2fc0e03d72339bfccf23c20214138520754e1a19c",
+					"0x9ea99b61f029b81e4ca807d4b6d554c776f04e53c8fc9b9c4ccd7a4bef518f7a",
+					"0x9b3b2e7f6c8ef8b57a0a297298c76287bce6683a2f5e49b16b96fb905fc6e31a",
+					"0x128210ffdd7a6695cd683c81c09799383c7d53e43563374554b240faecd70bdb",
+					"0x666f2dad13404c1cbde65a1260a6a04adbcd5b53a18b37adfd91a9bfacf6d710",
+					"0xcafec1b590f1103ccbb919d24439f7aba38ce2d4c6b5833798d85ffd69131358",
+					"0xb1b77789be2cfdc945be9f55c98c30434171a6fccb83ec10edec25215e7c9efe",
+					"0x7a06078a6b7bb6ea59046f1340d24460a7d20a5d39a0d6130e60479928c3105c",
+				},
+			},
+			{ // userDatas[162], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:49440
+				leaf: "0x236999daef1f5de32d56472a37f65a9d37469d65e37e6ece83ea74dba6bc7262",
+				proof: []string{
+					"0x247e256aec5ff55f73a72632ad971db7e0bfa900bd18ecd5c7e3d0bce06c1096",
+					"0xab8f588e5fa780a7c7984b85308898cc9f2300b058a81e278665392ce4f72157",
+					"0x01ee8387e19545c916577ec8761b4b0dd6715f1df825937d20c08e4f227caed4",
+					"0xf05082a5b8775b6b09567735e6ffd7bab2931c4443ce82b662c302ad2ba13341",
+					"0x666f397e895c9c503ea2120cbdd6b6616213ba4e63fd754d990022cce6d4d4c1",
+					"0xf20221123f138b42866cd6cfd347c488d714bf158719012ec345594796de38b5",
+					"0x37ecd77c58a43af377e63acfab49b370287a4570f5f97b9b224c99c05e0a4fcf",
+					"0x230559be826dcbb9de134a99ef7b7aaa023d63945f2e15b210512312f5bf1e8b",
+					"0xb6a213370688bcb27744a79939b945337949d83759eb7ba22d86c1c3d708d914",
+				},
+			},
+			{ // userDatas[323], 0x55f4c8aba71a1e923edc303eb4feff14608cc226:75175
+				leaf: "0x43f6149c04e2e625ee97732d308809ced68da152e4bba61adb932efaff020562",
+				proof: []string{
+					"0x43541cf606c0933c10484d2916a244cd0fc1e9d8e22815c87c6267084ee3b939",
+					"0x9cc1af1586e5e5dd94539de5beada7fabbb4d23083b850cde48e8a79f3cd53d3",
+					"0x1c92045a9526924d5215c7fe2a71b876e1ce902dfc89c8a37708d141409c8840",
+					"0x92e75e8014b1e9f13e6337e6a7f7f4da6b77f1ce4e1461930774ce3c01557d47",
+					"0x8427a9e2ca115ac5214450aa254ad2f7df9eda4256405ad705a1815dab4f742b",
+					"0xca764e2c8bc0826dfde34b3746fb4b416bf2bf5703004a37be267da6a78f0250",
+					"0xb568552a76f3a4c1777c5e5d2045274a34be66454d155e2f525df3fa17af97d3",
+					"0x230559be826dcbb9de134a99ef7b7aaa023d63945f2e15b210512312f5bf1e8b",
+					"0xb6a213370688bcb27744a79939b945337949d83759eb7ba22d86c1c3d708d914",
+				},
+			},
+		},
+	},
+}
+
+func TestDeployedVectors(t *testing.T) {
+	for _, v := range deployedVectors {
+		root := mustHash(t, v.root)
+		for _, lp := range v.proofs {
+			leaf := mustHash(t, lp.leaf)
+			proof := make([]Hash, len(lp.proof))
+			for i, p := range lp.proof {
+				proof[i] = mustHash(t, p)
+			}
+			if !Standard.Verify(root, leaf, proof) {
+				t.Errorf("%s: proof of %s does not verify", v.file, lp.leaf)
+			}
+			if SHA256.Verify(root, leaf, proof) {
+				t.Errorf("%s: proof of %s verifies as sha256", v.file, lp.leaf)
+			}
+		}
+	}
+}
+
+// Building a standard tree from a published file's leaves must give back its
+// tree node for node.
+func TestDeployedTreesRebuild(t *testing.T) {
+	for _, v := range deployedVectors {
+		b, err := os.ReadFile(filepath.Join("..", v.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f struct {
+			Tree []string `json:"tree"`
+		}
+		if err := json.Unmarshal(b, &f); err != nil {
+			t.Fatal(err)
+		}
+		nodes := make([]Hash, len(f.Tree))
+		for i, n := range f.Tree {
+			nodes[i] = mustHash(t, n)
+		}
+		tr, err := Standard.Build(nodes[len(nodes)/2:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.Root().Hex() != v.root {
+			t.Errorf("%s: root %s, want %s", v.file, tr.Root().Hex(), v.root)
+		}
+		for i, n := range tr.Nodes() {
+			if n != nodes[i] {
+				t.Errorf("%s: tree[%d] is %s, want %s", v.file, i, n.Hex(), nodes[i].Hex())
+				break
+			}
+		}
+		if _, err := Standard.FromNodes(nodes); err != nil {
+			t.Errorf("%s: FromNodes: %v", v.file, err)
+		}
+	}
+}
+
+func mustHash(t *testing.T, s string) Hash {
+	t.Helper()
+	h, err := ParseHash(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestStandardLeafIsDoubleKeccak(t *testing.T) {
+	enc, _ := hex.DecodeString(readmeLeaves[0])
+	inner := Keccak.LeafHash(enc)
+	if Standard.LeafHash(enc) != keccak.Sum256(inner[:]) {
+		t.Fatal("standard leaf is not keccak256 of the keccak leaf")
+	}
+}
+
+func TestSchemesDoNotCrossVerify(t *testing.T) {
+	leaves := randomLeaves(rand.New(rand.NewSource(6)), 9)
+	tr, err := SHA256.Build(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Standard.FromNodes(tr.Nodes()); err == nil {
+		t.Fatal("a sha256 tree loaded as standard")
+	}
+	p, _ := tr.Proof(leaves[0])
+	if Verify(tr.Root(), leaves[0], p) {
+		t.Fatal("a sha256 proof verified as standard")
+	}
+}
+
+func TestSchemeByName(t *testing.T) {
+	for _, n := range []string{"", "standard", "keccak", "sha256"} {
+		if _, err := SchemeByName(n); err != nil {
+			t.Errorf("%q: %v", n, err)
+		}
+	}
+	if _, err := SchemeByName("blake2"); err == nil || !strings.Contains(err.Error(), "known") {
+		t.Errorf("unknown scheme: %v", err)
+	}
+}
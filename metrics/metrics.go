@@ -0,0 +1,133 @@
+// Package metrics pushes a summary of a one-shot command's run (duration,
+// files, bytes, failures and whether it succeeded) to a Prometheus
+// Pushgateway, so the release dashboard covers the CLI runs that nothing
+// scrapes.
+//
+// Pushing is enabled when PUSHGATEWAY_URL is set; otherwise every call is a
+// cheap no-op. Each run replaces its job's group, keyed by job and, once a
+// command sets it, cycle; PUSHGATEWAY_INSTANCE adds an instance label for
+// runners that push concurrently.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counters a command adds to; each is pushed as fairflow_run_<name>.
+const (
+	Files    = "files"
+	Bytes    = "bytes"
+	Failures = "failures"
+)
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	gateway string
+	job     string
+	start   time.Time
+	labels  map[string]string
+	counts  map[string]float64
+	pushed  bool
+)
+
+// Init starts timing the run of job, e.g. "fairflow-verify". Call Push
+// before the command exits, including on failure.
+func Init(jobName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	gateway = strings.TrimRight(os.Getenv("PUSHGATEWAY_URL"), "/")
+	enabled = gateway != ""
+	job, start, pushed = jobName, time.Now(), false
+	labels = make(map[string]string)
+	if inst := os.Getenv("PUSHGATEWAY_INSTANCE"); inst != "" {
+		labels["instance"] = inst
+	}
+	counts = map[string]float64{Files: 0, Bytes: 0, Failures: 0}
+}
+
+// SetLabel adds a grouping label, such as the cycle.
+func SetLabel(name, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled {
+		labels[name] = value
+	}
+}
+
+// Add adds n to the counter name.
+func Add(name string, n int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled {
+		counts[name] += float64(n)
+	}
+}
+
+// Push sends the run's summary, once; err is what the run failed with, if
+// anything.
+func Push(err error) {
+	mu.Lock()
+	if !enabled || pushed {
+		mu.Unlock()
+		return
+	}
+	pushed = true
+	body := render(time.Since(start), err)
+	u := gateway + "/metrics/job/" + url.PathEscape(job)
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		u += "/" + url.PathEscape(k) + "/" + url.PathEscape(labels[k])
+	}
+	mu.Unlock()
+
+	req, rerr := http.NewRequest("PUT", u, bytes.NewReader(body))
+	if rerr != nil {
+		fmt.Fprintln(os.Stderr, "metrics: push:", rerr)
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, rerr := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if rerr != nil {
+		fmt.Fprintln(os.Stderr, "metrics: push:", rerr)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintln(os.Stderr, "metrics: push:", resp.Status)
+	}
+}
+
+// render writes the run in the Prometheus text format; mu is held.
+func render(d time.Duration, err error) []byte {
+	var b bytes.Buffer
+	gauge := func(name, help string, v float64) {
+		fmt.Fprintf(&b, "# HELP fairflow_run_%s %s\n# TYPE fairflow_run_%s gauge\nfairflow_run_%s %g\n", name, help, name, name, v)
+	}
+	success := 1.0
+	if err != nil {
+		success = 0
+		if counts[Failures] == 0 {
+			counts[Failures] = 1
+		}
+	}
+	gauge("duration_seconds", "Wall time of the run.", d.Seconds())
+	gauge("files", "Files the run processed.", counts[Files])
+	gauge("bytes", "Bytes the run processed.", counts[Bytes])
+	gauge("failures", "Failures the run hit.", counts[Failures])
+	gauge("success", "1 if the run succeeded.", success)
+	gauge("timestamp_seconds", "When the run finished.", float64(time.Now().Unix()))
+	return b.Bytes()
+}
@@ -0,0 +1,132 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// DefaultCacheDir is where metadata responses are cached, next to the
+// token cache.
+const DefaultCacheDir = ".fairflow/notion-cache"
+
+// Cache keeps metadata lookups — a database's data sources and a data
+// source's schema — on disk, so commands run back to back do not fetch
+// what rarely changes every time. Entries are the API's responses, one file
+// per Notion-Version and path under Dir, and are trusted for TTL after they
+// were fetched. Rows, pages and files are never cached.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+	// Offline answers every lookup from the cache, however old, and fails
+	// one that is not cached rather than calling the API: dry-runs work
+	// without network or token once a run has filled the cache.
+	Offline bool
+}
+
+// ErrNotCached is returned offline for a lookup the cache does not hold.
+var ErrNotCached = errors.New("not in the Notion metadata cache")
+
+// SetCache makes c answer metadata lookups through cache; nil turns caching
+// off.
+func (c *Client) SetCache(cache *Cache) { c.cache = cache }
+
+func (k *Cache) path(version, apiPath string) string {
+	return filepath.Join(k.Dir, version, filepath.FromSlash(strings.TrimPrefix(apiPath, "/"))+".json")
+}
+
+// getMeta GETs the metadata at apiPath into out, through the cache when
+// there is one; src, when set, pins the Notion-Version.
+func (c *Client) getMeta(ctx context.Context, src *Source, apiPath, what string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", BaseURL+apiPath, nil)
+	if err != nil {
+		return err
+	}
+	if src != nil {
+		src.pin(req)
+	}
+	if c.cache == nil {
+		return c.send(req, what, out)
+	}
+	version := req.Header.Get("Notion-Version")
+	if version == "" {
+		version = c.notionVersion
+	}
+	p := c.cache.path(version, apiPath)
+	if fi, err := os.Stat(p); err == nil && (c.cache.Offline || time.Since(fi.ModTime()) < c.cache.TTL) {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, out); err != nil {
+			return fmt.Errorf("cached %s: %w", p, err)
+		}
+		return nil
+	}
+	if c.cache.Offline {
+		return fmt.Errorf("%s %s: %w (run once online to fill %s)", what, apiPath, ErrNotCached, c.cache.Dir)
+	}
+	var raw json.RawMessage
+	if err := c.send(req, what, &raw); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	if err := tmpdir.WriteFile(p, raw, 0o600); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// forget drops the cached metadata at apiPath after a write changed it.
+func (c *Client) forget(src Source, apiPath string) {
+	if c.cache == nil {
+		return
+	}
+	version := c.notionVersion
+	if src.Legacy {
+		version = LegacyAPIVersion
+	}
+	os.Remove(c.cache.path(version, apiPath))
+}
+
+// CacheFlags are the cache flags of a command that reads Notion metadata.
+type CacheFlags struct {
+	dir     *string
+	ttl     *time.Duration
+	offline *bool
+}
+
+// AddCacheFlags registers --notion-cache and --notion-cache-ttl on fs, and
+// for a command whose --dry-run only needs metadata, --notion-offline.
+func AddCacheFlags(fs *flag.FlagSet, dryRun bool) *CacheFlags {
+	f := &CacheFlags{
+		dir:     fs.String("notion-cache", DefaultCacheDir, "directory caching Notion database and schema lookups"),
+		ttl:     fs.Duration("notion-cache-ttl", time.Hour, "how long a cached Notion lookup is trusted (0 = no cache)"),
+		offline: new(bool),
+	}
+	if dryRun {
+		fs.BoolVar(f.offline, "notion-offline", false, "with --dry-run, answer Notion lookups from the cache only, however old, without a token")
+	}
+	return f
+}
+
+// Cache is the cache the flags describe, or nil when it is off.
+func (f *CacheFlags) Cache() *Cache {
+	if *f.ttl <= 0 && !*f.offline {
+		return nil
+	}
+	return &Cache{Dir: *f.dir, TTL: *f.ttl, Offline: *f.offline}
+}
+
+func (f *CacheFlags) Offline() bool { return *f.offline }
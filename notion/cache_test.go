@@ -0,0 +1,51 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheOffline(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, APIVersion, "databases", "db1.json")
+	ds := filepath.Join(dir, APIVersion, "data_sources", "ds1.json")
+	for p, body := range map[string]string{
+		db: `{"data_sources":[{"id":"ds1","name":"Cycles"}]}`,
+		ds: `{"id":"ds1","properties":{"Chain":{"id":"a","type":"select","select":{"options":[{"name":"Base"}]}},"Name":{"id":"title","type":"title"}}}`,
+	} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	c := NewClient("", APIVersion)
+	c.SetCache(&Cache{Dir: dir, Offline: true})
+	src, err := c.ResolveSource(ctx, "db1")
+	if err != nil || src != (Source{ID: "ds1"}) {
+		t.Fatalf("ResolveSource = %+v, %v", src, err)
+	}
+	missing, err := c.MissingSelectOptions(ctx, src, "Chain", "Base", "Unichain")
+	if err != nil || len(missing) != 1 || missing[0] != "Unichain" {
+		t.Fatalf("MissingSelectOptions = %v, %v", missing, err)
+	}
+	if _, err := c.ResolveSource(ctx, "db2"); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("uncached lookup: %v, want ErrNotCached", err)
+	}
+
+	got, err := c.RetrieveDataSource(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := map[string]any{"Name": TitleValue("Cycle 21"), "Chain": NumberValue(1), "Roots": RichTextValue("0x")}
+	err = CheckProperties(got, props)
+	if err == nil || err.Error() != "property \"Chain\" is select, not number\nno property \"Roots\"" {
+		t.Fatalf("CheckProperties = %v", err)
+	}
+}
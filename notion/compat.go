@@ -0,0 +1,64 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+)
+
+// LegacyAPIVersion is the last Notion-Version before data sources, when a
+// database was queried, parented and updated directly. Workspaces still on
+// that model return a database without data_sources.
+const LegacyAPIVersion = "2022-06-28"
+
+// Source is the table rows live in: the database's first data source, or on
+// the legacy model the database itself.
+type Source struct {
+	ID     string `json:"id"`
+	Legacy bool   `json:"legacy,omitempty"`
+}
+
+// ResolveSource finds the Source of databaseID, falling back to the legacy
+// model when the database has no data_sources, so one binary works against
+// workspaces of either API generation.
+func (c *Client) ResolveSource(ctx context.Context, databaseID string) (Source, error) {
+	db, err := c.RetrieveDatabase(ctx, databaseID)
+	if err != nil {
+		return Source{}, err
+	}
+	if len(db.DataSources) == 0 {
+		return Source{ID: databaseID, Legacy: true}, nil
+	}
+	return Source{ID: db.DataSources[0].ID}, nil
+}
+
+// path is the API path of s, under which it is retrieved, updated and
+// queried.
+func (s Source) path() string {
+	if s.Legacy {
+		return "/databases/" + s.ID
+	}
+	return "/data_sources/" + s.ID
+}
+
+func (s Source) kind() string {
+	if s.Legacy {
+		return "database"
+	}
+	return "data source"
+}
+
+// parent is the page parent of a row created in s.
+func (s Source) parent() map[string]any {
+	if s.Legacy {
+		return map[string]any{"type": "database_id", "database_id": s.ID}
+	}
+	return map[string]any{"type": "data_source_id", "data_source_id": s.ID}
+}
+
+// pin sends req with the Notion-Version s needs; the client's own version
+// is used otherwise.
+func (s Source) pin(req *http.Request) {
+	if s.Legacy {
+		req.Header.Set("Notion-Version", LegacyAPIVersion)
+	}
+}
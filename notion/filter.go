@@ -0,0 +1,175 @@
+package notion
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Filter is a query filter; it marshals to the filter object of a query
+// body. Build one from And, Or and the property and timestamp conditions
+// below.
+type Filter interface {
+	json.Marshaler
+	// depth is how many compound filters deep the filter nests.
+	depth() int
+}
+
+// maxDepth is as deep as Notion nests compound filters.
+const maxDepth = 2
+
+var errTooDeep = errors.New("notion: compound filters nest more than two deep")
+
+// And matches pages matching every filter.
+type And []Filter
+
+// Or matches pages matching any filter.
+type Or []Filter
+
+func (a And) MarshalJSON() ([]byte, error) { return compound("and", a.flat()) }
+func (o Or) MarshalJSON() ([]byte, error)  { return compound("or", o) }
+
+func (a And) depth() int { return 1 + maxChildDepth(a.flat()) }
+func (o Or) depth() int  { return 1 + maxChildDepth(o) }
+
+// flat inlines the conditions of nested Ands, which would otherwise use up
+// a level of nesting for nothing.
+func (a And) flat() And {
+	out := make(And, 0, len(a))
+	for _, f := range a {
+		if inner, ok := f.(And); ok {
+			out = append(out, inner.flat()...)
+		} else {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func compound(op string, fs []Filter) ([]byte, error) {
+	if 1+maxChildDepth(fs) > maxDepth {
+		return nil, errTooDeep
+	}
+	return json.Marshal(map[string]any{op: []Filter(fs)})
+}
+
+func maxChildDepth(fs []Filter) int {
+	d := 0
+	for _, f := range fs {
+		d = max(d, f.depth())
+	}
+	return d
+}
+
+// PropertyFilter is one condition on a property: Type is the property's
+// type ("title", "rich_text", "select", "status", "files", "checkbox",
+// "date", ...) and Condition one of its operators, e.g. "contains" or
+// "on_or_after", with Value as its operand.
+type PropertyFilter struct {
+	Property  string
+	Type      string
+	Condition string
+	Value     any
+}
+
+func (p PropertyFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"property": p.Property,
+		p.Type:     map[string]any{p.Condition: p.Value},
+	})
+}
+
+func (PropertyFilter) depth() int { return 0 }
+
+// TimestampFilter is a condition on a page's "created_time" or
+// "last_edited_time".
+type TimestampFilter struct {
+	Timestamp string
+	Condition string
+	Value     any
+}
+
+func (t TimestampFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"timestamp": t.Timestamp,
+		t.Timestamp: map[string]any{t.Condition: t.Value},
+	})
+}
+
+func (TimestampFilter) depth() int { return 0 }
+
+func TitleContains(prop, s string) Filter {
+	return PropertyFilter{prop, "title", "contains", s}
+}
+
+func TitleStartsWith(prop, s string) Filter {
+	return PropertyFilter{prop, "title", "starts_with", s}
+}
+
+func TextContains(prop, s string) Filter {
+	return PropertyFilter{prop, "rich_text", "contains", s}
+}
+
+func SelectEquals(prop, s string) Filter {
+	return PropertyFilter{prop, "select", "equals", s}
+}
+
+func StatusEquals(prop, s string) Filter {
+	return PropertyFilter{prop, "status", "equals", s}
+}
+
+func StatusNot(prop, s string) Filter {
+	return PropertyFilter{prop, "status", "does_not_equal", s}
+}
+
+func FilesNotEmpty(prop string) Filter {
+	return PropertyFilter{prop, "files", "is_not_empty", true}
+}
+
+func NumberEquals(prop string, n float64) Filter {
+	return PropertyFilter{prop, "number", "equals", n}
+}
+
+func Checkbox(prop string, checked bool) Filter {
+	return PropertyFilter{prop, "checkbox", "equals", checked}
+}
+
+// DateBetween matches a date property in [from, to); a zero bound is open.
+func DateBetween(prop string, from, to time.Time) Filter {
+	return dateRange(func(cond string, t time.Time) Filter {
+		return PropertyFilter{prop, "date", cond, t.UTC().Format(time.RFC3339)}
+	}, from, to)
+}
+
+// EditedBetween matches pages last edited in [from, to); a zero bound is
+// open.
+func EditedBetween(from, to time.Time) Filter {
+	return dateRange(func(cond string, t time.Time) Filter {
+		return TimestampFilter{"last_edited_time", cond, t.UTC().Format(time.RFC3339)}
+	}, from, to)
+}
+
+func dateRange(cond func(string, time.Time) Filter, from, to time.Time) Filter {
+	out := make(And, 0, 2)
+	if !from.IsZero() {
+		out = append(out, cond("on_or_after", from))
+	}
+	if !to.IsZero() {
+		out = append(out, cond("before", to))
+	}
+	if len(out) == 1 {
+		return out[0]
+	}
+	return out
+}
+
+// CycleFilter selects the rows of cycle n: by the number property cycleProp
+// when one is configured, else by the title property containing what every
+// title of the pattern (nil for DefaultTitlePattern) has for cycle n. Rows
+// it returns still go through InCycle: "Cycle 21" is in "Cycle 210" too.
+func CycleFilter(titleProp, cycleProp string, title *TitlePattern, n int) Filter {
+	if cycleProp != "" {
+		return NumberEquals(cycleProp, float64(n))
+	}
+	return TitleContains(titleProp, title.orDefault().Contains(n))
+}
@@ -0,0 +1,48 @@
+package notion
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFilterJSON(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		f    Filter
+		want string
+	}{
+		{"title", TitleStartsWith("Task name", "Cycle 21"), `{"property":"Task name","title":{"starts_with":"Cycle 21"}}`},
+		{"cycle number", CycleFilter("Task name", "Cycle", nil, 21), `{"number":{"equals":21},"property":"Cycle"}`},
+		{"cycle title", CycleFilter("Task name", "", nil, 21), `{"property":"Task name","title":{"contains":"Cycle 21"}}`},
+		{"cycle title pattern", CycleFilter("Task name", "", mustTitle(t, "C{{.Cycle}} — {{.Chain}}"), 21), `{"property":"Task name","title":{"contains":"C21 —"}}`},
+		{"checkbox", Checkbox("Reviewed", true), `{"checkbox":{"equals":true},"property":"Reviewed"}`},
+		{"open range", DateBetween("Due", from, time.Time{}), `{"date":{"on_or_after":"2026-01-01T00:00:00Z"},"property":"Due"}`},
+		{"edited", EditedBetween(from, to), `{"and":[{"last_edited_time":{"on_or_after":"2026-01-01T00:00:00Z"},"timestamp":"last_edited_time"},{"last_edited_time":{"before":"2026-02-01T00:00:00Z"},"timestamp":"last_edited_time"}]}`},
+		// The status conditions and the date range join the outer And
+		// rather than nesting under it.
+		{"sync", append(And{FilesNotEmpty("Merkle file"), DateBetween("Due", from, to)}, StatusConditions("Status", []string{"Done", "Approved"}, []string{"Superseded"})...),
+			`{"and":[{"files":{"is_not_empty":true},"property":"Merkle file"},{"date":{"on_or_after":"2026-01-01T00:00:00Z"},"property":"Due"},{"date":{"before":"2026-02-01T00:00:00Z"},"property":"Due"},{"or":[{"property":"Status","status":{"equals":"Done"}},{"property":"Status","status":{"equals":"Approved"}}]},{"property":"Status","status":{"does_not_equal":"Superseded"}}]}`},
+	}
+	for _, c := range cases {
+		b, err := json.Marshal(c.f)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(b) != c.want {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, b, c.want)
+		}
+	}
+}
+
+func TestFilterTooDeep(t *testing.T) {
+	f := Or{And{Or{Checkbox("A", true), Checkbox("B", true)}}}
+	if _, err := json.Marshal(f); err == nil {
+		t.Fatal("three levels of compound filters marshaled")
+	}
+	if _, err := json.Marshal(And{Or{Checkbox("A", true)}, And{Or{Checkbox("B", true)}}}); err != nil {
+		t.Fatalf("nested And counted as a level: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package notion is a minimal client for the Notion API (2025-09-03 data
+// source model, with a fallback to the database model of older workspaces;
+// see Source) covering what the cycle tooling reads and writes.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+const (
+	BaseURL    = "https://api.notion.com/v1"
+	APIVersion = "2025-09-03"
+)
+
+type RetrieveDatabaseResp struct {
+	DataSources []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"data_sources"`
+}
+
+type QueryResp struct {
+	Results    []Page `json:"results"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
+}
+
+type Page struct {
+	ID             string                 `json:"id"`
+	URL            string                 `json:"url,omitempty"`
+	CreatedTime    time.Time              `json:"created_time"`
+	LastEditedTime time.Time              `json:"last_edited_time"`
+	Properties     map[string]PropertyVal `json:"properties"`
+	// Archived and InTrash are set on a row removed from its database;
+	// queries leave such rows out, but one retrieved by ID still has them.
+	Archived bool `json:"archived,omitempty"`
+	InTrash  bool `json:"in_trash,omitempty"`
+}
+
+// PageURL is the notion.so link to the page with id, for operators to open
+// from errors and reports.
+func PageURL(id string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(id, "-", "")
+}
+
+// Link is the page's own URL as the API returned it, or one built from its ID.
+func (p Page) Link() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return PageURL(p.ID)
+}
+
+type PropertyVal struct {
+	Type string `json:"type"`
+
+	Title json.RawMessage `json:"title"`
+
+	Select *struct {
+		Name string `json:"name"`
+	} `json:"select"`
+
+	MultiSelect []struct {
+		Name string `json:"name"`
+	} `json:"multi_select"`
+
+	Status *struct {
+		Name string `json:"name"`
+	} `json:"status"`
+
+	Files []File `json:"files"`
+
+	People []Person `json:"people"`
+
+	Number *float64 `json:"number"`
+
+	Date *struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	} `json:"date"`
+
+	URL *string `json:"url"`
+}
+
+// Person is a user in a people property; Name or Person.Email may be
+// missing for guests and integrations.
+type Person struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Person *struct {
+		Email string `json:"email"`
+	} `json:"person"`
+}
+
+// Handle is how to address p: the name, else the email, else the user ID.
+func (p Person) Handle() string {
+	switch {
+	case p.Name != "":
+		return p.Name
+	case p.Person != nil && p.Person.Email != "":
+		return p.Person.Email
+	}
+	return p.ID
+}
+
+// PeopleText joins the handles of the people of a people property.
+func PeopleText(people []Person) string {
+	names := make([]string, 0, len(people))
+	for _, u := range people {
+		names = append(names, u.Handle())
+	}
+	return strings.Join(names, ", ")
+}
+
+type RichText struct {
+	PlainText string `json:"plain_text"`
+	Text      struct {
+		Content string `json:"content"`
+	} `json:"text"`
+}
+
+type File struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	File *struct {
+		URL        string `json:"url"`
+		ExpiryTime string `json:"expiry_time"`
+	} `json:"file"`
+	External *struct {
+		URL string `json:"url"`
+	} `json:"external"`
+}
+
+type Client struct {
+	http          *http.Client
+	token         string
+	notionVersion string
+	cache         *Cache
+}
+
+func NewClient(token, version string) *Client {
+	return &Client{
+		http:          &http.Client{Timeout: 60 * time.Second, Transport: tracing.Transport(nil)},
+		token:         token,
+		notionVersion: version,
+	}
+}
+
+// HTTPClient is the client used for API calls, reused for file downloads.
+func (c *Client) HTTPClient() *http.Client { return c.http }
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if req.Header.Get("Notion-Version") == "" {
+		req.Header.Set("Notion-Version", c.notionVersion)
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.http.Do(req)
+}
+
+func (c *Client) RetrieveDatabase(ctx context.Context, databaseID string) (RetrieveDatabaseResp, error) {
+	var out RetrieveDatabaseResp
+	err := c.getMeta(ctx, nil, "/databases/"+databaseID, "retrieve database", &out)
+	return out, err
+}
+
+// Query runs a query against src; body takes the same filter, sorts and
+// paging under either model.
+func (c *Client) Query(ctx context.Context, src Source, body any) (QueryResp, error) {
+	var out QueryResp
+	b, err := json.Marshal(body)
+	if err != nil {
+		return out, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", BaseURL+src.path()+"/query", bytes.NewReader(b))
+	if err != nil {
+		return out, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	src.pin(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return out, fmt.Errorf("query %s failed: %s: %s", src.kind(), resp.Status, string(rb))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// StatusConditions are the query conditions, to be and-ed with the others,
+// that the status property prop is one of anyOf (no condition when empty) and
+// none of exclude. Notion nests compound filters at most two deep, so the
+// caller's And holds them directly.
+func StatusConditions(prop string, anyOf, exclude []string) []Filter {
+	out := make([]Filter, 0, 1+len(exclude))
+	if len(anyOf) > 0 {
+		or := make(Or, 0, len(anyOf))
+		for _, s := range anyOf {
+			or = append(or, StatusEquals(prop, s))
+		}
+		out = append(out, or)
+	}
+	for _, s := range exclude {
+		out = append(out, StatusNot(prop, s))
+	}
+	return out
+}
+
+func TitleText(p PropertyVal) string {
+	if len(p.Title) == 0 {
+		return ""
+	}
+	var obj struct {
+		Title   []RichText `json:"title"`
+		Results []RichText `json:"results"`
+	}
+	if err := json.Unmarshal(p.Title, &obj); err == nil && (len(obj.Title) > 0 || len(obj.Results) > 0) {
+		return joinPlainText(append(obj.Title, obj.Results...))
+	}
+
+	var arr []RichText
+	if err := json.Unmarshal(p.Title, &arr); err == nil && len(arr) > 0 {
+		return joinPlainText(arr)
+	}
+	return ""
+}
+
+func joinPlainText(items []RichText) string {
+	parts := make([]string, 0, len(items))
+	for _, t := range items {
+		if t.PlainText != "" {
+			parts = append(parts, t.PlainText)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func FileURL(f File) (string, error) {
+	if f.Type == "file" && f.File != nil && f.File.URL != "" {
+		return f.File.URL, nil
+	}
+	if f.Type == "external" && f.External != nil && f.External.URL != "" {
+		return f.External.URL, nil
+	}
+	if f.File != nil && f.File.URL != "" {
+		return f.File.URL, nil
+	}
+	if f.External != nil && f.External.URL != "" {
+		return f.External.URL, nil
+	}
+	return "", fmt.Errorf("file entry %q has no downloadable URL", f.Name)
+}
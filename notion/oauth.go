@@ -0,0 +1,203 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// DefaultOAuthConfigPath holds the OAuth client of a public integration, for
+// workspaces (partner DAOs, say) that connect the tool rather than hand over
+// an internal integration token.
+const DefaultOAuthConfigPath = "config/notion_oauth.json"
+
+// DefaultTokenCachePath is where the token from notion-auth login is kept.
+const DefaultTokenCachePath = ".fairflow/notion_token.json"
+
+// refreshMargin is how long before expiry a cached token is refreshed.
+const refreshMargin = 5 * time.Minute
+
+type OAuthConfig struct {
+	ClientID string `json:"clientId"`
+	// ClientSecret may be left out and set in env NOTION_CLIENT_SECRET.
+	ClientSecret string `json:"clientSecret,omitempty"`
+	RedirectURI  string `json:"redirectUri"`
+	// TokenCache defaults to DefaultTokenCachePath.
+	TokenCache string `json:"tokenCache,omitempty"`
+}
+
+// LoadOAuthConfig reads the client at path; a missing file means OAuth is
+// not set up, and returns nil.
+func LoadOAuthConfig(path string) (*OAuthConfig, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c OAuthConfig
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if c.ClientSecret == "" {
+		c.ClientSecret = os.Getenv("NOTION_CLIENT_SECRET")
+	}
+	if c.ClientID == "" || c.ClientSecret == "" || c.RedirectURI == "" {
+		return nil, fmt.Errorf("%s: needs clientId, clientSecret (or env NOTION_CLIENT_SECRET) and redirectUri", path)
+	}
+	if c.TokenCache == "" {
+		c.TokenCache = DefaultTokenCachePath
+	}
+	return &c, nil
+}
+
+// Token is an OAuth grant for one workspace. ExpiresAt is zero for tokens
+// Notion issues without an expiry.
+type Token struct {
+	AccessToken   string    `json:"accessToken"`
+	RefreshToken  string    `json:"refreshToken,omitempty"`
+	WorkspaceID   string    `json:"workspaceId"`
+	WorkspaceName string    `json:"workspaceName"`
+	BotID         string    `json:"botId"`
+	ExpiresAt     time.Time `json:"expiresAt,omitzero"`
+}
+
+// AuthorizeURL is where the workspace owner approves the integration; Notion
+// then redirects to RedirectURI with the code for Exchange.
+func (c *OAuthConfig) AuthorizeURL(state string) string {
+	q := url.Values{
+		"client_id":     {c.ClientID},
+		"response_type": {"code"},
+		"owner":         {"user"},
+		"redirect_uri":  {c.RedirectURI},
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	return BaseURL + "/oauth/authorize?" + q.Encode()
+}
+
+// Exchange trades an authorization code for a token.
+func (c *OAuthConfig) Exchange(ctx context.Context, code string) (*Token, error) {
+	return c.grant(ctx, map[string]string{"grant_type": "authorization_code", "code": code, "redirect_uri": c.RedirectURI})
+}
+
+// Refresh trades t's refresh token for a new token.
+func (c *OAuthConfig) Refresh(ctx context.Context, t *Token) (*Token, error) {
+	if t.RefreshToken == "" {
+		return nil, errors.New("notion token has no refresh token; run notion-auth login again")
+	}
+	return c.grant(ctx, map[string]string{"grant_type": "refresh_token", "refresh_token": t.RefreshToken})
+}
+
+func (c *OAuthConfig) grant(ctx context.Context, body map[string]string) (*Token, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", BaseURL+"/oauth/token", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("notion oauth %s failed: %s: %s", body["grant_type"], resp.Status, string(rb))
+	}
+	var out struct {
+		AccessToken   string `json:"access_token"`
+		RefreshToken  string `json:"refresh_token"`
+		WorkspaceID   string `json:"workspace_id"`
+		WorkspaceName string `json:"workspace_name"`
+		BotID         string `json:"bot_id"`
+		ExpiresIn     int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	t := &Token{AccessToken: out.AccessToken, RefreshToken: out.RefreshToken, WorkspaceID: out.WorkspaceID, WorkspaceName: out.WorkspaceName, BotID: out.BotID}
+	if out.ExpiresIn > 0 {
+		t.ExpiresAt = time.Now().UTC().Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// LoadToken reads the token cached at path.
+func LoadToken(path string) (*Token, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// SaveToken caches t at path, readable only by the owner.
+func SaveToken(path string, t *Token) error {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return tmpdir.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// AccessToken returns the cached token, refreshing and re-caching it first
+// when it is about to expire.
+func (c *OAuthConfig) AccessToken(ctx context.Context) (string, error) {
+	t, err := LoadToken(c.TokenCache)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no Notion token cached at %s; run notion-auth login", c.TokenCache)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !t.ExpiresAt.IsZero() && time.Until(t.ExpiresAt) < refreshMargin {
+		if t, err = c.Refresh(ctx, t); err != nil {
+			return "", err
+		}
+		if err := SaveToken(c.TokenCache, t); err != nil {
+			return "", err
+		}
+	}
+	return t.AccessToken, nil
+}
+
+// ResolveToken is the token to call the API with: token when given (an
+// internal integration's), else the OAuth token of the client at
+// oauthConfig.
+func ResolveToken(ctx context.Context, token, oauthConfig string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	c, err := LoadOAuthConfig(oauthConfig)
+	if err != nil {
+		return "", err
+	}
+	if c == nil {
+		return "", errors.New("missing Notion token (set NOTION_TOKEN or --notion-token, or configure OAuth in " + oauthConfig + " and run notion-auth login)")
+	}
+	return c.AccessToken(ctx)
+}
@@ -0,0 +1,64 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ArchivedError is a page that was archived, trashed or deleted after a
+// command found it, e.g. between notion-sync's query and its download. It
+// is reported apart from other API failures so a run can skip the row and
+// carry on.
+type ArchivedError struct {
+	Page string
+	// Deleted is set when the API no longer returns the page at all, which
+	// is also how a page the integration lost access to looks.
+	Deleted bool
+}
+
+func (e *ArchivedError) Error() string {
+	if e.Deleted {
+		return fmt.Sprintf("page %s was deleted or is no longer shared with the integration", e.Page)
+	}
+	return fmt.Sprintf("page %s was archived", e.Page)
+}
+
+// IsArchived reports whether err is, or wraps, an *ArchivedError.
+func IsArchived(err error) bool {
+	var ae *ArchivedError
+	return errors.As(err, &ae)
+}
+
+// RetrievePage reads page id. A page that is archived or in the trash comes
+// back with an *ArchivedError alongside it; one that is gone, with only the
+// error.
+func (c *Client) RetrievePage(ctx context.Context, id string) (Page, error) {
+	var out Page
+	req, err := http.NewRequestWithContext(ctx, "GET", BaseURL+"/pages/"+id, nil)
+	if err != nil {
+		return out, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return out, &ArchivedError{Page: PageURL(id), Deleted: true}
+	}
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return out, fmt.Errorf("retrieve page failed: %s: %s", resp.Status, string(rb))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return out, err
+	}
+	if out.Archived || out.InTrash {
+		return out, &ArchivedError{Page: out.Link()}
+	}
+	return out, nil
+}
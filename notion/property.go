@@ -0,0 +1,171 @@
+package notion
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PropertyError is a page property that is missing or not of the type a
+// command reads it as, with what an operator needs to fix the row or the
+// command's --prop-* flags.
+type PropertyError struct {
+	Page     string
+	Property string
+	Want     string
+	// Got is the property's type; empty when the page has no such property.
+	Got string
+	// Available are the page's properties, when Property is missing.
+	Available []string
+}
+
+func (e *PropertyError) Error() string {
+	if e.Got == "" {
+		return fmt.Sprintf("page %s: no %s property %q (the page has %s)", e.Page, e.Want, e.Property, quoteList(e.Available))
+	}
+	return fmt.Sprintf("page %s: property %q is a %s property, not %s", e.Page, e.Property, e.Got, e.Want)
+}
+
+func quoteList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	q := make([]string, len(names))
+	for i, n := range names {
+		q[i] = fmt.Sprintf("%q", n)
+	}
+	return strings.Join(q, ", ")
+}
+
+// property looks up prop on p, which must be of one of types.
+func property(p Page, prop string, types ...string) (PropertyVal, error) {
+	v, ok := p.Properties[prop]
+	if !ok {
+		return v, &PropertyError{Page: p.Link(), Property: prop, Want: types[0], Available: slices.Sorted(maps.Keys(p.Properties))}
+	}
+	if !slices.Contains(types, v.Type) {
+		return v, &PropertyError{Page: p.Link(), Property: prop, Want: types[0], Got: v.Type}
+	}
+	return v, nil
+}
+
+func GetTitle(p Page, prop string) (string, error) {
+	v, err := property(p, prop, "title")
+	if err != nil {
+		return "", err
+	}
+	return TitleText(v), nil
+}
+
+// GetSelect is the selected option's name; empty when none is.
+func GetSelect(p Page, prop string) (string, error) {
+	v, err := property(p, prop, "select")
+	if err != nil || v.Select == nil {
+		return "", err
+	}
+	return v.Select.Name, nil
+}
+
+func GetMultiSelect(p Page, prop string) ([]string, error) {
+	v, err := property(p, prop, "multi_select")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(v.MultiSelect))
+	for i, o := range v.MultiSelect {
+		out[i] = o.Name
+	}
+	return out, nil
+}
+
+// GetStatus is the status's name; a select property stands in for a
+// status on boards older than the status type.
+func GetStatus(p Page, prop string) (string, error) {
+	v, err := property(p, prop, "status", "select")
+	switch {
+	case err != nil:
+		return "", err
+	case v.Status != nil:
+		return v.Status.Name, nil
+	case v.Select != nil:
+		return v.Select.Name, nil
+	}
+	return "", nil
+}
+
+func GetFiles(p Page, prop string) ([]File, error) {
+	v, err := property(p, prop, "files")
+	if err != nil {
+		return nil, err
+	}
+	return v.Files, nil
+}
+
+// GetNumber is nil when the property is empty.
+func GetNumber(p Page, prop string) (*float64, error) {
+	v, err := property(p, prop, "number")
+	if err != nil {
+		return nil, err
+	}
+	return v.Number, nil
+}
+
+// GetDate is the date's start and, for a range, end; both are zero when the
+// property is empty.
+func GetDate(p Page, prop string) (start, end time.Time, err error) {
+	v, err := property(p, prop, "date")
+	if err != nil || v.Date == nil {
+		return start, end, err
+	}
+	if start, err = parseDate(v.Date.Start); err != nil {
+		return start, end, fmt.Errorf("page %s: property %q: %w", p.Link(), prop, err)
+	}
+	if v.Date.End != "" {
+		if end, err = parseDate(v.Date.End); err != nil {
+			return start, end, fmt.Errorf("page %s: property %q: %w", p.Link(), prop, err)
+		}
+	}
+	return start, end, nil
+}
+
+// parseDate reads a date property's bound, a date or a date and time.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func GetURL(p Page, prop string) (string, error) {
+	v, err := property(p, prop, "url")
+	if err != nil || v.URL == nil {
+		return "", err
+	}
+	return *v.URL, nil
+}
+
+func GetPeople(p Page, prop string) ([]Person, error) {
+	v, err := property(p, prop, "people")
+	if err != nil {
+		return nil, err
+	}
+	return v.People, nil
+}
+
+// InCycle reports whether p is a row of cycle n as CycleFilter selects
+// them, for rows a query returned. By title, the pattern's cycle number must
+// be n itself.
+func InCycle(p Page, titleProp, cycleProp string, title *TitlePattern, n int) (bool, error) {
+	if cycleProp != "" {
+		v, err := GetNumber(p, cycleProp)
+		return v != nil && *v == float64(n), err
+	}
+	t, err := GetTitle(p, titleProp)
+	if err != nil {
+		return false, err
+	}
+	f, ok := title.orDefault().Match(t)
+	return ok && f.Cycle == n, nil
+}
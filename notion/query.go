@@ -0,0 +1,93 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Sort is one of a query's sorts, by a property or by one of the page
+// timestamps "created_time" and "last_edited_time".
+type Sort struct {
+	Property  string `json:"property,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	// Direction is "ascending" or "descending".
+	Direction string `json:"direction"`
+}
+
+// ParseSorts parses comma-separated sorts, each a property or timestamp
+// name with an optional :asc or :desc (ascending by default), e.g.
+// "Chain,last_edited_time:desc".
+func ParseSorts(s string) ([]Sort, error) {
+	out := make([]Sort, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, dir := part, "asc"
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			name, dir = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		var srt Sort
+		switch dir {
+		case "asc", "ascending":
+			srt.Direction = "ascending"
+		case "desc", "descending":
+			srt.Direction = "descending"
+		default:
+			return nil, fmt.Errorf("sort %q: direction is asc or desc", part)
+		}
+		switch name {
+		case "":
+			return nil, fmt.Errorf("sort %q: missing property", part)
+		case "created_time", "last_edited_time":
+			srt.Timestamp = name
+		default:
+			srt.Property = name
+		}
+		out = append(out, srt)
+	}
+	return out, nil
+}
+
+// QueryPages runs body (with any filter and sorts) against src and calls fn
+// with each page of results in order, following the cursor until the last
+// page. The next page is fetched while fn handles the current one. An error
+// from fn stops the query and is returned.
+func (c *Client) QueryPages(ctx context.Context, src Source, body map[string]any, fn func([]Page) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		qr  QueryResp
+		err error
+	}
+	// One page in flight while fn handles another.
+	pages := make(chan result, 1)
+	go func() {
+		defer close(pages)
+		body := maps.Clone(body)
+		for {
+			qr, err := c.Query(ctx, src, body)
+			select {
+			case pages <- result{qr, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil || !qr.HasMore || qr.NextCursor == "" {
+				return
+			}
+			body["start_cursor"] = qr.NextCursor
+		}
+	}()
+	for r := range pages {
+		if r.err != nil {
+			return r.err
+		}
+		if err := fn(r.qr.Results); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
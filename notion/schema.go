@@ -0,0 +1,169 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+type DataSource struct {
+	ID         string                    `json:"id"`
+	Properties map[string]PropertySchema `json:"properties"`
+}
+
+// PropertySchema is a data source column; Select or Multi holds the options
+// of a select or multi_select one.
+type PropertySchema struct {
+	ID     string         `json:"id"`
+	Type   string         `json:"type"`
+	Select *OptionsSchema `json:"select,omitempty"`
+	Multi  *OptionsSchema `json:"multi_select,omitempty"`
+}
+
+type OptionsSchema struct {
+	Options []SelectOption `json:"options"`
+}
+
+type SelectOption struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+func (p PropertySchema) options() *OptionsSchema {
+	if p.Type == "multi_select" {
+		return p.Multi
+	}
+	return p.Select
+}
+
+// RetrieveDataSource reads the schema of src, through the client's cache;
+// a legacy database carries the same properties.
+func (c *Client) RetrieveDataSource(ctx context.Context, src Source) (DataSource, error) {
+	var out DataSource
+	err := c.getMeta(ctx, &src, src.path(), "retrieve "+src.kind(), &out)
+	return out, err
+}
+
+// MissingSelectOptions returns the names the select or multi_select
+// property of src has no option for yet, as AddSelectOptions would add
+// them, from the schema as cached.
+func (c *Client) MissingSelectOptions(ctx context.Context, src Source, property string, names ...string) ([]string, error) {
+	ds, err := c.RetrieveDataSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	_, opts, err := selectOptions(ds, src, property)
+	if err != nil {
+		return nil, err
+	}
+	_, missing := withOptions(opts, names)
+	return missing, nil
+}
+
+// AddSelectOptions adds names missing from the options of the select or
+// multi_select property of src, keeping the existing ones, and returns the
+// names it added. The schema is read fresh, not from the cache: the update
+// replaces the whole option list.
+func (c *Client) AddSelectOptions(ctx context.Context, src Source, property string, names ...string) ([]string, error) {
+	var ds DataSource
+	req, err := http.NewRequestWithContext(ctx, "GET", BaseURL+src.path(), nil)
+	if err != nil {
+		return nil, err
+	}
+	src.pin(req)
+	if err := c.send(req, "retrieve "+src.kind(), &ds); err != nil {
+		return nil, err
+	}
+	p, opts, err := selectOptions(ds, src, property)
+	if err != nil {
+		return nil, err
+	}
+	opts, added := withOptions(opts, names)
+	if len(added) == 0 {
+		return added, nil
+	}
+	b, err := json.Marshal(map[string]any{
+		"properties": map[string]any{property: map[string]any{p.Type: map[string]any{"options": opts}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req, err = http.NewRequestWithContext(ctx, "PATCH", BaseURL+src.path(), bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	src.pin(req)
+	defer c.forget(src, src.path())
+	return added, c.send(req, "update "+src.kind(), nil)
+}
+
+// selectOptions is the select or multi_select property of ds and its
+// options.
+func selectOptions(ds DataSource, src Source, property string) (PropertySchema, []SelectOption, error) {
+	p, ok := ds.Properties[property]
+	if !ok || (p.Type != "select" && p.Type != "multi_select") {
+		return p, nil, fmt.Errorf("%s has no select or multi_select property %q", src.kind(), property)
+	}
+	opts := make([]SelectOption, 0)
+	if o := p.options(); o != nil {
+		opts = o.Options
+	}
+	return p, opts, nil
+}
+
+// withOptions appends to opts the names it lacks, returning both.
+func withOptions(opts []SelectOption, names []string) ([]SelectOption, []string) {
+	added := make([]string, 0, len(names))
+	for _, n := range names {
+		if !slices.ContainsFunc(opts, func(o SelectOption) bool { return o.Name == n }) {
+			opts = append(opts, SelectOption{Name: n})
+			added = append(added, n)
+		}
+	}
+	return opts, added
+}
+
+// CheckProperties reports the properties of a row to be created with props
+// that ds does not have, or has with another type: what CreatePage would
+// fail on.
+func CheckProperties(ds DataSource, props map[string]any) error {
+	errs := make([]error, 0)
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		v, _ := props[name].(map[string]any)
+		var typ string
+		for k := range v {
+			typ = k
+		}
+		p, ok := ds.Properties[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("no property %q", name))
+		case typ != "" && p.Type != typ:
+			errs = append(errs, fmt.Errorf("property %q is %s, not %s", name, p.Type, typ))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) send(req *http.Request, what string, out any) error {
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed: %s: %s", what, resp.Status, string(rb))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,161 @@
+package notion
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultTitlePattern is the title convention of the cycle database:
+// "Cycle 21" somewhere in the row's title.
+const DefaultTitlePattern = "Cycle {{.Cycle}}"
+
+// TitlePattern is the title a cycle row is expected to have. It is either a
+// template over {{.Cycle}}, {{.Chain}} and {{.Type}}, as
+// "C{{.Cycle}} — {{.Chain}}", or, prefixed "regexp:", a regular expression
+// with a Cycle named group and optionally Chain and Type ones. Either way it
+// matches anywhere in the title.
+type TitlePattern struct {
+	src string
+	re  *regexp.Regexp
+	// literal is the text around {{.Cycle}} up to the nearest other field,
+	// with %d for the cycle, which every matching title contains; a regexp
+	// only guarantees the number.
+	literal string
+}
+
+// TitleFields are what a title that matched its pattern says; Chain and
+// Type are "" when the pattern has no such field.
+type TitleFields struct {
+	Cycle int
+	Chain string
+	Type  string
+}
+
+var titleField = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+
+// ParseTitlePattern reads a --title-pattern; "" is DefaultTitlePattern.
+func ParseTitlePattern(s string) (*TitlePattern, error) {
+	if s == "" {
+		s = DefaultTitlePattern
+	}
+	if expr, ok := strings.CutPrefix(s, "regexp:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("title pattern %q: %w", s, err)
+		}
+		if re.SubexpIndex("Cycle") < 0 {
+			return nil, fmt.Errorf("title pattern %q: no (?P<Cycle>...) group", s)
+		}
+		return &TitlePattern{src: s, re: re, literal: "%d"}, nil
+	}
+
+	var expr, literal strings.Builder
+	seen := make(map[string]bool)
+	afterCycle := false
+	rest := s
+	for rest != "" {
+		loc := titleField.FindStringSubmatchIndex(rest)
+		text := rest
+		if loc != nil {
+			text = rest[:loc[0]]
+		}
+		expr.WriteString(regexp.QuoteMeta(text))
+		if !seen["Cycle"] || afterCycle {
+			literal.WriteString(strings.ReplaceAll(text, "%", "%%"))
+		}
+		if loc == nil {
+			break
+		}
+		name := rest[loc[2]:loc[3]]
+		rest = rest[loc[1]:]
+		if seen[name] {
+			return nil, fmt.Errorf("title pattern %q: {{.%s}} appears twice", s, name)
+		}
+		seen[name] = true
+		switch name {
+		case "Cycle":
+			expr.WriteString(`(?P<Cycle>[0-9]+)`)
+			literal.WriteString("%d")
+			afterCycle = true
+		case "Chain", "Type":
+			// The last field takes the rest of the title; one followed by
+			// more text stops at the first place it fits.
+			if rest == "" {
+				fmt.Fprintf(&expr, `(?P<%s>.+)`, name)
+			} else {
+				fmt.Fprintf(&expr, `(?P<%s>.+?)`, name)
+			}
+			if afterCycle {
+				afterCycle = false
+			} else if !seen["Cycle"] {
+				literal.Reset()
+			}
+		default:
+			return nil, fmt.Errorf("title pattern %q: unknown field {{.%s}} (want Cycle, Chain or Type)", s, name)
+		}
+	}
+	if !seen["Cycle"] {
+		return nil, fmt.Errorf("title pattern %q: no {{.Cycle}}", s)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("title pattern %q: %w", s, err)
+	}
+	return &TitlePattern{src: s, re: re, literal: literal.String()}, nil
+}
+
+var defaultTitle, _ = ParseTitlePattern(DefaultTitlePattern)
+
+func (t *TitlePattern) orDefault() *TitlePattern {
+	if t == nil {
+		return defaultTitle
+	}
+	return t
+}
+
+func (t *TitlePattern) String() string { return t.src }
+
+// Contains is text every title of cycle n contains, for the query filter.
+func (t *TitlePattern) Contains(n int) string {
+	return strings.TrimSpace(fmt.Sprintf(t.literal, n))
+}
+
+// Match extracts the fields of title, reporting whether it matches at all.
+func (t *TitlePattern) Match(title string) (TitleFields, bool) {
+	m := t.re.FindStringSubmatch(title)
+	if m == nil {
+		return TitleFields{}, false
+	}
+	var f TitleFields
+	var err error
+	if f.Cycle, err = strconv.Atoi(m[t.re.SubexpIndex("Cycle")]); err != nil {
+		return TitleFields{}, false
+	}
+	if i := t.re.SubexpIndex("Chain"); i >= 0 {
+		f.Chain = strings.TrimSpace(m[i])
+	}
+	if i := t.re.SubexpIndex("Type"); i >= 0 {
+		f.Type = strings.TrimSpace(m[i])
+	}
+	return f, true
+}
+
+// CheckSelects cross-checks the chain and type a title names against the
+// row's Chain select and Type multi-select options, ignoring case; a field
+// the pattern does not have is not checked.
+func (f TitleFields) CheckSelects(chain string, types []string) error {
+	if f.Chain != "" && !strings.EqualFold(f.Chain, strings.TrimSpace(chain)) {
+		return fmt.Errorf("title names chain %q but the Chain property is %q", f.Chain, chain)
+	}
+	if f.Type == "" {
+		return nil
+	}
+	for _, t := range types {
+		if strings.EqualFold(f.Type, strings.TrimSpace(t)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("title names type %q but the Type property is %s", f.Type, quoteList(types))
+}
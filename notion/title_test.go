@@ -0,0 +1,67 @@
+package notion
+
+import "testing"
+
+func mustTitle(t *testing.T, s string) *TitlePattern {
+	t.Helper()
+	p, err := ParseTitlePattern(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestTitlePattern(t *testing.T) {
+	cases := []struct {
+		pattern, title string
+		want           TitleFields
+		ok             bool
+	}{
+		{"", "Cycle 21 rewards", TitleFields{Cycle: 21}, true},
+		{"", "Cycle 210", TitleFields{Cycle: 210}, true},
+		{"", "cycle 21", TitleFields{}, false},
+		{"C{{.Cycle}} — {{.Chain}}", "C21 — Base", TitleFields{Cycle: 21, Chain: "Base"}, true},
+		{"{{.Chain}} / {{.Type}} / Cycle {{.Cycle}}", "Arbitrum One / LM / Cycle 7", TitleFields{Cycle: 7, Chain: "Arbitrum One", Type: "LM"}, true},
+		{"regexp:^(?P<Chain>\\w+)-(?P<Cycle>\\d+)$", "base-21", TitleFields{Cycle: 21, Chain: "base"}, true},
+	}
+	for _, c := range cases {
+		got, ok := mustTitle(t, c.pattern).Match(c.title)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%q on %q = %+v, %v; want %+v, %v", c.pattern, c.title, got, ok, c.want, c.ok)
+		}
+	}
+
+	for _, bad := range []string{"Cycle", "{{.Cycle}} {{.Cycle}}", "{{.Cycle}} {{.Token}}", "regexp:Cycle (\\d+)"} {
+		if _, err := ParseTitlePattern(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestTitleContains(t *testing.T) {
+	cases := map[string]string{
+		"":                                "Cycle 21",
+		"C{{.Cycle}} — {{.Chain}}":        "C21 —",
+		"{{.Chain}} Cycle {{.Cycle}} 50%": "Cycle 21 50%",
+		"{{.Chain}} {{.Cycle}}/{{.Type}}": "21/",
+		"regexp:(?P<Cycle>\\d+)":          "21",
+	}
+	for pattern, want := range cases {
+		if got := mustTitle(t, pattern).Contains(21); got != want {
+			t.Errorf("%q: Contains(21) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestTitleCheckSelects(t *testing.T) {
+	f := TitleFields{Cycle: 21, Chain: "base", Type: "lm"}
+	if err := f.CheckSelects("Base", []string{"Trading", "LM"}); err != nil {
+		t.Error(err)
+	}
+	if err := f.CheckSelects("Arbitrum", []string{"LM"}); err == nil {
+		t.Error("chain mismatch: no error")
+	}
+	if err := f.CheckSelects("Base", []string{"Trading"}); err == nil {
+		t.Error("type mismatch: no error")
+	}
+}
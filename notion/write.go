@@ -0,0 +1,92 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxRichText is Notion's limit on one rich text object's content.
+const maxRichText = 2000
+
+// CreatePage adds a row to src. props maps property names to values built
+// with the *Value helpers.
+func (c *Client) CreatePage(ctx context.Context, src Source, props map[string]any) (Page, error) {
+	var out Page
+	b, err := json.Marshal(map[string]any{
+		"parent":     src.parent(),
+		"properties": props,
+	})
+	if err != nil {
+		return out, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", BaseURL+"/pages", bytes.NewReader(b))
+	if err != nil {
+		return out, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	src.pin(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(resp.Body)
+		return out, fmt.Errorf("create page failed: %s: %s", resp.Status, string(rb))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// JobCreatePage is the queue kind of a CreatePage that failed; its payload is
+// a CreatePageJob.
+const JobCreatePage = "notion.create-page"
+
+type CreatePageJob struct {
+	// DataSourceID is the database ID when Legacy is set.
+	DataSourceID string         `json:"dataSourceId"`
+	Legacy       bool           `json:"legacy,omitempty"`
+	Properties   map[string]any `json:"properties"`
+	// Version is the Notion-Version the row was built for.
+	Version string `json:"version"`
+}
+
+func richText(s string) []any {
+	parts := make([]any, 0, len(s)/maxRichText+1)
+	r := []rune(s)
+	for len(r) > 0 {
+		n := len(r)
+		if n > maxRichText {
+			n = maxRichText
+		}
+		parts = append(parts, map[string]any{"type": "text", "text": map[string]any{"content": string(r[:n])}})
+		r = r[n:]
+	}
+	return parts
+}
+
+func TitleValue(s string) any { return map[string]any{"title": richText(s)} }
+
+// RichTextValue splits long text across rich text objects to stay under the
+// per-object limit.
+func RichTextValue(s string) any { return map[string]any{"rich_text": richText(s)} }
+
+func NumberValue(n float64) any { return map[string]any{"number": n} }
+
+func URLValue(s string) any {
+	if s == "" {
+		return map[string]any{"url": nil}
+	}
+	return map[string]any{"url": s}
+}
+
+func DateValue(t time.Time) any {
+	return map[string]any{"date": map[string]any{"start": t.UTC().Format(time.RFC3339)}}
+}
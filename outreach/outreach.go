@@ -0,0 +1,171 @@
+// Package outreach enriches the holders of positions with large unclaimed
+// rewards — when they last sent a transaction, their ENS name and any known
+// label such as an exchange deposit address — and exports them as a CSV the
+// community team works reminders from.
+package outreach
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+const DefaultLabelsPath = "config/labels.csv"
+
+type Label struct {
+	Name string
+	// Category groups labels, e.g. "cex" for exchange hot wallets and
+	// deposit addresses, whose holders a reminder cannot reach directly.
+	Category string
+}
+
+// Labels are known addresses, keyed by lowercased address.
+type Labels map[string]Label
+
+// LoadLabels reads a CSV with "address", "label" and "category" columns; a
+// missing file is no labels.
+func LoadLabels(path string) (Labels, error) {
+	fh, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Labels{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	cr := csv.NewReader(fh)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: read header: %w", path, err)
+	}
+	idx := map[string]int{"address": -1, "label": -1, "category": -1}
+	for j, h := range header {
+		if _, ok := idx[strings.ToLower(strings.TrimSpace(h))]; ok {
+			idx[strings.ToLower(strings.TrimSpace(h))] = j
+		}
+	}
+	if idx["address"] < 0 || idx["label"] < 0 {
+		return nil, fmt.Errorf("%s: needs address and label columns", path)
+	}
+	field := func(rec []string, col string) string {
+		if j := idx[col]; j >= 0 && j < len(rec) {
+			return strings.TrimSpace(rec[j])
+		}
+		return ""
+	}
+	out := make(Labels)
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		addr := strings.ToLower(field(rec, "address"))
+		if !evm.IsAddress(addr) {
+			return nil, fmt.Errorf("%s:%d: invalid address %q", path, line, addr)
+		}
+		out[addr] = Label{Name: field(rec, "label"), Category: strings.ToLower(field(rec, "category"))}
+	}
+}
+
+// Row is one position to reach out about.
+type Row struct {
+	ChainID  string
+	Position string
+	Holder   string
+	// Contract is set when the holder has code, e.g. a Safe; LastSent does
+	// not apply to it.
+	Contract bool
+	ENS      string
+	Label    Label
+	// Unclaimed is "<amount> <symbol>" per token, in token address order.
+	Unclaimed []string
+	// ShareBps is the largest share of a token's total left unclaimed, in
+	// basis points.
+	ShareBps  int64
+	LastSent  time.Time
+	SentBlock uint64
+}
+
+// Enricher fills in what is known about each holder.
+type Enricher struct {
+	// Client reads the position's chain at Block.
+	Client *evm.Client
+	Block  uint64
+	// ENS reads Ethereum mainnet; nil skips names.
+	ENS    *evm.Client
+	Labels Labels
+}
+
+// Enrich looks up r.Holder. A lookup fails when the RPC cannot
+// answer, e.g. for an old block on a pruned node.
+func (e *Enricher) Enrich(ctx context.Context, r *Row) error {
+	r.Label = e.Labels[strings.ToLower(r.Holder)]
+	block := evm.BlockParam(e.Block)
+	code, err := e.Client.CodeAt(ctx, r.Holder, block)
+	if err != nil {
+		return fmt.Errorf("code of %s: %w", r.Holder, err)
+	}
+	r.Contract = len(code) > 0
+	if !r.Contract {
+		n, ok, err := e.Client.LastSent(ctx, r.Holder, e.Block)
+		if err != nil {
+			return fmt.Errorf("last transaction of %s: %w", r.Holder, err)
+		}
+		if ok {
+			if r.LastSent, err = e.Client.BlockTime(ctx, n); err != nil {
+				return err
+			}
+			r.SentBlock = n
+		}
+	}
+	if e.ENS != nil {
+		if r.ENS, err = e.ENS.ENSName(ctx, r.Holder, "latest"); err != nil {
+			return fmt.Errorf("ENS name of %s: %w", r.Holder, err)
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes rows for the outreach sheet; days_inactive counts from
+// now.
+func WriteCSV(path string, rows []Row, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"chain", "position", "holder", "holder_type", "ens", "label", "category", "unclaimed", "share_bps", "last_sent_block", "last_sent_at", "days_inactive"})
+	for _, r := range rows {
+		kind := "eoa"
+		if r.Contract {
+			kind = "contract"
+		}
+		block, at, days := "", "", ""
+		if !r.LastSent.IsZero() {
+			block = strconv.FormatUint(r.SentBlock, 10)
+			at = r.LastSent.Format(time.RFC3339)
+			days = strconv.Itoa(int(now.Sub(r.LastSent).Hours() / 24))
+		}
+		w.Write([]string{r.ChainID, r.Position, r.Holder, kind, r.ENS, r.Label.Name, r.Label.Category,
+			strings.Join(r.Unclaimed, "; "), strconv.FormatInt(r.ShareBps, 10), block, at, days})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
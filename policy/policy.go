@@ -0,0 +1,174 @@
+// Package policy gates operations by who is running them and under which
+// profile. Destructive operations additionally need --confirm <cycle>, so a
+// command pointed at the wrong cycle or environment stops before it acts.
+//
+// config/policy.json:
+//
+//	{
+//	  "rules": [
+//	    {"identity": "*@kyber.network", "profile": "production", "allow": ["sync", "publish", "writeback"]},
+//	    {"identity": "*", "profile": "staging", "allow": ["*"]},
+//	    {"identity": "*", "allow": ["sync"]}
+//	  ],
+//	  "confirm": ["publish", "writeback", "rollback"]
+//	}
+//
+// The first rule matching identity and profile decides; no match denies.
+// Without a policy file every operation is allowed, as before. An operation
+// the commands do not check is refused in the file rather than ignored.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const DefaultPath = "config/policy.json"
+
+type Op string
+
+const (
+	OpSync      Op = "sync"
+	OpPublish   Op = "publish"
+	OpWriteback Op = "writeback"
+	// OpRollback is update-kyber-applications --rollback, which points
+	// values.yaml back at the cycle before the one it was bumped to.
+	OpRollback Op = "rollback"
+)
+
+var ops = []Op{OpSync, OpPublish, OpWriteback, OpRollback}
+
+var defaultConfirm = []Op{OpPublish, OpWriteback, OpRollback}
+
+type Rule struct {
+	// Identity is a glob ("*@kyber.network"); empty matches anyone.
+	Identity string `json:"identity"`
+	// Profile restricts the rule to one profile; empty matches any.
+	Profile string `json:"profile,omitempty"`
+	Allow   []Op   `json:"allow"`
+}
+
+type Config struct {
+	Rules   []Rule `json:"rules"`
+	Confirm []Op   `json:"confirm,omitempty"`
+}
+
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	for _, r := range c.Rules {
+		for _, op := range r.Allow {
+			if op != "*" && !slices.Contains(ops, op) {
+				return nil, fmt.Errorf("policy: unknown operation %q in allow", op)
+			}
+		}
+	}
+	for _, op := range c.Confirm {
+		if !slices.Contains(ops, op) {
+			return nil, fmt.Errorf("policy: unknown operation %q in confirm", op)
+		}
+	}
+	if c.Confirm == nil {
+		c.Confirm = defaultConfirm
+	}
+	return &c, nil
+}
+
+// Allowed reports whether identity may perform op under profile.
+func (c *Config) Allowed(op Op, identity, profile string) bool {
+	for _, r := range c.Rules {
+		if r.Profile != "" && r.Profile != profile {
+			continue
+		}
+		if r.Identity != "" {
+			if ok, _ := path.Match(r.Identity, identity); !ok {
+				continue
+			}
+		}
+		for _, a := range r.Allow {
+			if a == op || a == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (c *Config) NeedsConfirm(op Op) bool {
+	for _, o := range c.Confirm {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity is FAIRFLOW_IDENTITY, else the git user.email, else $USER.
+func Identity() string {
+	if id := os.Getenv("FAIRFLOW_IDENTITY"); id != "" {
+		return id
+	}
+	if out, err := exec.Command("git", "config", "user.email").Output(); err == nil {
+		if id := strings.TrimSpace(string(out)); id != "" {
+			return id
+		}
+	}
+	return os.Getenv("USER")
+}
+
+type Gate struct {
+	path    *string
+	confirm *string
+}
+
+// AddFlags registers --policy and --confirm on fs.
+func AddFlags(fs *flag.FlagSet) *Gate {
+	return &Gate{
+		path:    fs.String("policy", DefaultPath, "operation policy JSON"),
+		confirm: fs.String("confirm", "", "cycle number, required to confirm destructive operations"),
+	}
+}
+
+// Check authorises op on cycle for the current identity under profile.
+func (g *Gate) Check(op Op, profile string, cycle int) error {
+	c, err := Load(*g.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	id := Identity()
+	if !c.Allowed(op, id, profile) {
+		where := ""
+		if profile != "" {
+			where = " under profile " + profile
+		}
+		return fmt.Errorf("policy: identity %q is not allowed to %s%s (set FAIRFLOW_IDENTITY or git user.email)", id, op, where)
+	}
+	if !c.NeedsConfirm(op) {
+		return nil
+	}
+	if *g.confirm == "" {
+		return fmt.Errorf("policy: %s is destructive; rerun with --confirm %d", op, cycle)
+	}
+	if n, err := strconv.Atoi(*g.confirm); err != nil || n != cycle {
+		return errors.New("policy: --confirm " + *g.confirm + " does not match cycle " + strconv.Itoa(cycle))
+	}
+	return nil
+}
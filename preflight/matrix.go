@@ -0,0 +1,192 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChainCheck is one column of the chain matrix. Run checks a single chain;
+// an error is a red cell with the error as its detail.
+type ChainCheck struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string, error)
+}
+
+// ChainChecks returns the checks of one chain, in the order they run. The
+// checks of a chain run one after the other and may share what earlier ones
+// read; different chains run at the same time.
+type ChainChecks func(chainID string) []ChainCheck
+
+// Cell is one chain × check result.
+type Cell struct {
+	Check      string `json:"check"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type MatrixRow struct {
+	Chain      string `json:"chain"`
+	DurationMs int64  `json:"durationMs"`
+	Cells      []Cell `json:"cells"`
+}
+
+// Matrix is the per-chain report: one row per chain, one cell per check.
+type Matrix struct {
+	Checks []string    `json:"checks"`
+	Rows   []MatrixRow `json:"rows"`
+	// DurationMs is the wall time of the whole run, about that of the
+	// slowest chain rather than the sum of them.
+	DurationMs int64 `json:"durationMs"`
+}
+
+// RunMatrix runs checks for every chain, up to parallel chains at a time (0
+// for all at once), each chain under its own timeout (0 for none). Chains
+// are isolated from one another: a chain whose RPC hangs only times out its
+// own remaining cells, and a check that panics only fails its own chain.
+func RunMatrix(ctx context.Context, chainIDs []string, checks ChainChecks, parallel int, timeout time.Duration) *Matrix {
+	start := time.Now()
+	ids := append([]string(nil), chainIDs...)
+	sort.Strings(ids)
+	if parallel <= 0 || parallel > len(ids) {
+		parallel = len(ids)
+	}
+	rows := make([]MatrixRow, len(ids))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			rows[i] = runChain(ctx, id, checks(id), timeout)
+		}()
+	}
+	wg.Wait()
+
+	m := &Matrix{Rows: rows, DurationMs: time.Since(start).Milliseconds()}
+	seen := make(map[string]bool)
+	for _, r := range rows {
+		for _, c := range r.Cells {
+			if !seen[c.Check] {
+				seen[c.Check] = true
+				m.Checks = append(m.Checks, c.Check)
+			}
+		}
+	}
+	return m
+}
+
+func runChain(ctx context.Context, chainID string, checks []ChainCheck, timeout time.Duration) MatrixRow {
+	start := time.Now()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	row := MatrixRow{Chain: chainID, Cells: make([]Cell, 0, len(checks))}
+	for i, ck := range checks {
+		if err := ctx.Err(); err != nil {
+			for _, rest := range checks[i:] {
+				row.Cells = append(row.Cells, Cell{Check: rest.Name, Status: Red, Detail: "not run: " + err.Error()})
+			}
+			break
+		}
+		c, panicked := runCell(ctx, ck)
+		row.Cells = append(row.Cells, c)
+		if panicked {
+			for _, rest := range checks[i+1:] {
+				row.Cells = append(row.Cells, Cell{Check: rest.Name, Status: Red, Detail: "not run: " + ck.Name + " panicked"})
+			}
+			break
+		}
+	}
+	row.DurationMs = time.Since(start).Milliseconds()
+	return row
+}
+
+func runCell(ctx context.Context, ck ChainCheck) (c Cell, panicked bool) {
+	start := time.Now()
+	c.Check = ck.Name
+	defer func() {
+		if r := recover(); r != nil {
+			c.Status, c.Detail, panicked = Red, fmt.Sprintf("panic: %v", r), true
+		}
+		c.DurationMs = time.Since(start).Milliseconds()
+	}()
+	status, detail, err := ck.Run(ctx)
+	if err != nil {
+		status, detail = Red, err.Error()
+	}
+	c.Status, c.Detail = status, detail
+	return c, false
+}
+
+// Column summarizes one check across chains as a checklist entry: its worst
+// cell, and an item for every cell that is not green.
+func (m *Matrix) Column(check, name string) Check {
+	ck := Check{Name: name, Status: Green}
+	chains := 0
+	for _, r := range m.Rows {
+		for _, c := range r.Cells {
+			if c.Check != check {
+				continue
+			}
+			chains++
+			if rank[c.Status] > rank[ck.Status] {
+				ck.Status = c.Status
+			}
+			if c.Status != Green {
+				ck.Items = append(ck.Items, fmt.Sprintf("chain %s: %s", r.Chain, c.Detail))
+			}
+		}
+	}
+	if len(ck.Items) == 0 {
+		ck.Detail = fmt.Sprintf("%d chains pass", chains)
+	} else {
+		ck.Detail = fmt.Sprintf("%d of %d chains fail or warn", len(ck.Items), chains)
+	}
+	return ck
+}
+
+// String renders the matrix as a Markdown table of pass/warn/fail and how
+// long each cell took.
+func (m *Matrix) String() string {
+	var b strings.Builder
+	b.WriteString("| chain |")
+	for _, c := range m.Checks {
+		b.WriteString(" " + c + " |")
+	}
+	b.WriteString(" total |\n|---|")
+	b.WriteString(strings.Repeat("---|", len(m.Checks)+1))
+	b.WriteString("\n")
+	for _, r := range m.Rows {
+		cells := make(map[string]Cell, len(r.Cells))
+		for _, c := range r.Cells {
+			cells[c.Check] = c
+		}
+		fmt.Fprintf(&b, "| %s |", r.Chain)
+		for _, name := range m.Checks {
+			c, ok := cells[name]
+			if !ok {
+				b.WriteString(" - |")
+				continue
+			}
+			fmt.Fprintf(&b, " %s %s |", verdict[c.Status], ms(c.DurationMs))
+		}
+		fmt.Fprintf(&b, " %s |\n", ms(r.DurationMs))
+	}
+	fmt.Fprintf(&b, "\n%d chains in %s\n", len(m.Rows), ms(m.DurationMs))
+	return b.String()
+}
+
+var verdict = map[Status]string{Green: "pass", Yellow: "warn", Red: "fail"}
+
+func ms(n int64) string {
+	return (time.Duration(n) * time.Millisecond).String()
+}
@@ -0,0 +1,148 @@
+// Package preflight is the checklist run the day before a cycle is
+// released: is every Notion row done, is every distributor funded, does
+// every chain have a healthy RPC, and is values.yaml still on the cycle the
+// release will replace. Each check is green, yellow (look at it before
+// releasing) or red (the release will fail as things stand).
+package preflight
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+)
+
+type Status string
+
+const (
+	Green  Status = "green"
+	Yellow Status = "yellow"
+	Red    Status = "red"
+)
+
+var rank = map[Status]int{Green: 0, Yellow: 1, Red: 2}
+
+func (s Status) label() string { return "[" + strings.ToUpper(string(s)) + "]" }
+
+type Check struct {
+	Name   string   `json:"name"`
+	Status Status   `json:"status"`
+	Detail string   `json:"detail"`
+	Items  []string `json:"items,omitempty"`
+}
+
+type Checklist struct {
+	Cycle  int     `json:"cycle"`
+	Checks []Check `json:"checks"`
+	// Matrix is the per-chain detail behind the on-chain checks, when they
+	// ran.
+	Matrix *Matrix `json:"matrix,omitempty"`
+}
+
+func (c *Checklist) Add(ck Check) { c.Checks = append(c.Checks, ck) }
+
+// Status is the worst status of the checks.
+func (c *Checklist) Status() Status {
+	worst := Green
+	for _, ck := range c.Checks {
+		if rank[ck.Status] > rank[worst] {
+			worst = ck.Status
+		}
+	}
+	return worst
+}
+
+// String renders the checklist as Markdown, fit for chat and PR comments.
+func (c *Checklist) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Cycle %d preflight\n\n", c.Status().label(), c.Cycle)
+	for _, ck := range c.Checks {
+		fmt.Fprintf(&b, "- %s **%s**: %s\n", ck.Status.label(), ck.Name, ck.Detail)
+		for _, it := range ck.Items {
+			fmt.Fprintf(&b, "  - %s\n", it)
+		}
+	}
+	if c.Matrix != nil {
+		b.WriteString("\n" + c.Matrix.String())
+	}
+	return b.String()
+}
+
+// Funding checks the reconcile report: an underfunded distributor is red.
+// A nil report, when none was given, is yellow.
+func Funding(rep *reconcile.Report) Check {
+	ck := Check{Name: "Distributor funding"}
+	if rep == nil {
+		ck.Status, ck.Detail = Yellow, "no reconcile report given; funding not checked"
+		return ck
+	}
+	under := rep.Underfunded()
+	if len(under) == 0 {
+		ck.Status, ck.Detail = Green, fmt.Sprintf("%d distributor balances cover what is unclaimed", len(rep.Lines))
+		return ck
+	}
+	ck.Status, ck.Detail = Red, fmt.Sprintf("%d of %d distributor balances are short", len(under), len(rep.Lines))
+	for _, l := range under {
+		ck.Items = append(ck.Items, fmt.Sprintf("chain %s distributor %s token %s short %s", l.ChainID, l.Distributor, l.Token, l.Shortfall))
+	}
+	return ck
+}
+
+var (
+	cycleURLRe      = regexp.MustCompile(`/cycle-([0-9]+)/`)
+	maintenanceTrue = regexp.MustCompile(`(?m)^\s*([A-Za-z0-9_.-]+)\s*:\s*["']?true["']?\s*(#.*)?$`)
+)
+
+// Values checks values.yaml before cycle n is released: update-kyber-
+// applications will swap each URL of cycle n-1 for cycle n's, so every one
+// of urls, cycle n-1's, must be there. A URL of cycle n means the swap
+// already happened; one older than n-2 is stale; a maintenance key left on
+// is yellow.
+func Values(values string, n int, urls []string, maintKey string) Check {
+	ck := Check{Name: "values.yaml"}
+	status := Green
+	raise := func(s Status, item string) {
+		if rank[s] > rank[status] {
+			status = s
+		}
+		ck.Items = append(ck.Items, item)
+	}
+	for _, u := range urls {
+		if !strings.Contains(values, u) {
+			raise(Red, "missing "+u)
+		}
+	}
+	seen := make(map[int]int)
+	for _, m := range cycleURLRe.FindAllStringSubmatch(values, -1) {
+		c, _ := strconv.Atoi(m[1])
+		seen[c]++
+	}
+	cycles := make([]int, 0, len(seen))
+	for c := range seen {
+		cycles = append(cycles, c)
+	}
+	sort.Ints(cycles)
+	for _, c := range cycles {
+		switch {
+		case c >= n:
+			raise(Red, fmt.Sprintf("%d URL(s) already on cycle %d", seen[c], c))
+		case c < n-2:
+			raise(Yellow, fmt.Sprintf("%d URL(s) still on cycle %d", seen[c], c))
+		}
+	}
+	for _, m := range maintenanceTrue.FindAllStringSubmatch(values, -1) {
+		if m[1] == maintKey {
+			raise(Yellow, maintKey+" is still true")
+		}
+	}
+	ck.Status = status
+	if status == Green {
+		ck.Detail = fmt.Sprintf("all %d URLs of cycle %d are referenced", len(urls), n-1)
+	} else {
+		ck.Detail = fmt.Sprintf("%d problem(s)", len(ck.Items))
+	}
+	return ck
+}
@@ -0,0 +1,182 @@
+// Package profile switches a command between named environments
+// (production, staging, ...) in one step. A profile supplies defaults for the
+// command's flags and environment (e.g. notification webhooks), so a staging
+// run cannot pick up the production database or values file by accident.
+//
+// config/profiles.json:
+//
+//	{
+//	  "default": "production",
+//	  "profiles": {
+//	    "staging": {
+//	      "databaseId": "…",
+//	      "mapping": "config/notion_mappings.staging.json",
+//	      "outDir": "../fairflow-reward-staging",
+//	      "values": "../infra-staging/values.yaml",
+//	      "env": {"SLACK_WEBHOOK_URL": "${STAGING_SLACK_WEBHOOK_URL}"},
+//	      "releasesDatabaseId": "…",
+//	      "commands": {"publish": {"to": "staging-bucket"}}
+//	    }
+//	  }
+//	}
+//
+// Flags given on the command line always win over the profile.
+package profile
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DefaultPath = "config/profiles.json"
+
+type Config struct {
+	Default  string             `json:"default"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+type Profile struct {
+	Name       string `json:"-"`
+	DatabaseID string `json:"databaseId,omitempty"`
+	Mapping    string `json:"mapping,omitempty"`
+	OutDir     string `json:"outDir,omitempty"`
+	Values     string `json:"values,omitempty"`
+	// ReleasesDatabaseID is the "Cycle Releases" database notion-release
+	// writes to.
+	ReleasesDatabaseID string `json:"releasesDatabaseId,omitempty"`
+	// Env is exported into the process before the command runs; values may
+	// reference other variables as ${VAR}. It is meant for settings read at
+	// run time, like notification webhooks and alert keys; flags that default
+	// from the environment are resolved earlier, so set those via Commands.
+	Env map[string]string `json:"env,omitempty"`
+	// Commands holds per-command flag overrides: command -> flag -> value.
+	Commands map[string]map[string]string `json:"commands,omitempty"`
+}
+
+// flags returns flag name -> value for command. The typed fields only feed
+// the commands they were meant for: --database-id means different databases
+// to notion-sync and notion-release.
+func (p *Profile) flags(command string) map[string]string {
+	out := make(map[string]string)
+	switch command {
+	case "notion-sync":
+		out["database-id"], out["mapping"], out["out-dir"] = p.DatabaseID, p.Mapping, p.OutDir
+	case "notion-release":
+		out["database-id"] = p.ReleasesDatabaseID
+	case "add-chain":
+		out["database-id"], out["mapping"] = p.DatabaseID, p.Mapping
+	case "update-kyber-applications":
+		out["values"] = p.Values
+	}
+	for k, v := range p.Commands[command] {
+		out[k] = v
+	}
+	return out
+}
+
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse profiles: %w", err)
+	}
+	if c.Default != "" {
+		if _, ok := c.Profiles[c.Default]; !ok {
+			return nil, fmt.Errorf("profiles: default profile %q is not defined", c.Default)
+		}
+	}
+	return &c, nil
+}
+
+func (c *Config) Names() []string {
+	out := make([]string, 0, len(c.Profiles))
+	for n := range c.Profiles {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type Selector struct {
+	name *string
+	path *string
+}
+
+// AddFlags registers --profile and --profiles on fs.
+func AddFlags(fs *flag.FlagSet) *Selector {
+	return &Selector{
+		name: fs.String("profile", os.Getenv("FAIRFLOW_PROFILE"), "named config profile (or env FAIRFLOW_PROFILE)"),
+		path: fs.String("profiles", DefaultPath, "profiles JSON"),
+	}
+}
+
+// Apply activates the selected profile after fs has been parsed and reports
+// it on stderr. With no profiles file and no --profile it does nothing and
+// returns nil.
+func (s *Selector) Apply(fs *flag.FlagSet, command string) (*Profile, error) {
+	return s.apply(fs, command, os.Stderr)
+}
+
+func (s *Selector) apply(fs *flag.FlagSet, command string, log io.Writer) (*Profile, error) {
+	cfg, err := Load(*s.path)
+	if os.IsNotExist(err) {
+		if *s.name != "" {
+			return nil, fmt.Errorf("profile %q requested but %s does not exist", *s.name, *s.path)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	name := *s.name
+	if name == "" {
+		name = cfg.Default
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q (known: %s)", name, strings.Join(cfg.Names(), ", "))
+	}
+	p.Name = name
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	applied := make([]string, 0)
+	for k, v := range p.flags(command) {
+		if v == "" || set[k] || fs.Lookup(k) == nil {
+			continue
+		}
+		if err := fs.Set(k, v); err != nil {
+			return nil, fmt.Errorf("profile %s: --%s: %w", name, k, err)
+		}
+		applied = append(applied, "--"+k+"="+v)
+	}
+	sort.Strings(applied)
+	for k, v := range p.Env {
+		os.Setenv(k, os.ExpandEnv(v))
+	}
+	fmt.Fprintf(log, "Active profile: %s", name)
+	if len(applied) > 0 {
+		fmt.Fprintf(log, " (%s)", strings.Join(applied, " "))
+	}
+	fmt.Fprintln(log)
+	return &p, nil
+}
+
+// ID is the profile name, or "" when no profile is active.
+func (p *Profile) ID() string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
@@ -0,0 +1,196 @@
+// Package programs builds programs.json, the claim UI's one discovery
+// document: every chain/type/cycle reward-service currently serves, read
+// from the URLs in its values.yaml, with the root, tokens and claim deadline
+// of each from the cycle directories.
+package programs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+)
+
+// FileName is where the programs command writes the document by default.
+const FileName = "programs.json"
+
+type Document struct {
+	Programs []Program `json:"programs"`
+}
+
+type Program struct {
+	ChainID    string `json:"chainId"`
+	RewardType string `json:"type"`
+	Cycle      int    `json:"cycle"`
+	File       string `json:"file"`
+	// Sources are the URLs values.yaml serves the program from: the file,
+	// or its shards, and any patches.
+	Sources    []Source `json:"sources"`
+	Recipients int      `json:"recipients"`
+	// Tokens is token -> total base units.
+	Tokens map[string]string `json:"tokens"`
+	// ClaimDeadline comes from the cycle's stats.json, if it has one.
+	ClaimDeadline time.Time `json:"claimDeadline,omitzero"`
+}
+
+type Source struct {
+	URL string `json:"url"`
+	// Root is the one the distributor checks claims from URL against: the
+	// master root for a two-level sharded file.
+	Root string `json:"root"`
+}
+
+var urlRe = regexp.MustCompile(`https?://[^\s"']+/cycle-([0-9]+)/([A-Za-z0-9_.-]+\.json)`)
+
+// Build reads the merkle URLs in values and describes each program they
+// belong to from the cycle-N directories under root.
+func Build(values []byte, root string) (*Document, error) {
+	byName := make(map[cycle.Name]*Program)
+	stats := make(map[int]*summary.Stats)
+	for _, m := range urlRe.FindAllSubmatch(values, -1) {
+		u, file := string(m[0]), string(m[2])
+		n, ok := cycle.Owner(file)
+		if !ok || fmt.Sprint(n.Cycle) != string(m[1]) {
+			continue
+		}
+		// Sidecars other than shards and patches are not claimed from.
+		if file == n.BloomName() || file == n.ShardIndexName() || file == n.UniswapName() {
+			continue
+		}
+		p := byName[n]
+		if p == nil {
+			var err error
+			if p, err = describe(root, n, stats); err != nil {
+				return nil, err
+			}
+			byName[n] = p
+		}
+		if hasURL(p.Sources, u) {
+			continue
+		}
+		r, err := sourceRoot(filepath.Join(root, cycle.DirName(n.Cycle)), n, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", u, err)
+		}
+		p.Sources = append(p.Sources, Source{URL: u, Root: r})
+	}
+	doc := &Document{Programs: make([]Program, 0, len(byName))}
+	for _, p := range byName {
+		doc.Programs = append(doc.Programs, *p)
+	}
+	sort.Slice(doc.Programs, func(i, j int) bool {
+		a, b := doc.Programs[i], doc.Programs[j]
+		if a.Cycle != b.Cycle {
+			return a.Cycle > b.Cycle
+		}
+		return a.File < b.File
+	})
+	return doc, nil
+}
+
+// describe fills in n's recipients, tokens and deadline, from the cycle's
+// stats.json when it has one and from the merkle file when not.
+func describe(root string, n cycle.Name, stats map[int]*summary.Stats) (*Program, error) {
+	dir := filepath.Join(root, cycle.DirName(n.Cycle))
+	p := &Program{ChainID: n.ChainID, RewardType: n.RewardType, Cycle: n.Cycle, File: n.String()}
+	s, ok := stats[n.Cycle]
+	if !ok {
+		var err error
+		if s, err = loadStats(dir); err != nil {
+			return nil, err
+		}
+		stats[n.Cycle] = s
+	}
+	if s != nil {
+		for _, fs := range s.Files {
+			if fs.Name != n.String() {
+				continue
+			}
+			p.Recipients, p.ClaimDeadline = fs.Recipients, s.ClaimDeadline
+			p.Tokens = make(map[string]string, len(fs.Tokens))
+			for t, ts := range fs.Tokens {
+				p.Tokens[t] = ts.Total
+			}
+			return p, nil
+		}
+	}
+	f, err := cycle.Load(filepath.Join(dir, n.String()))
+	if err != nil {
+		return nil, err
+	}
+	totals, err := f.SumAmounts()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n, err)
+	}
+	p.Recipients = len(f.UserDatas)
+	p.Tokens = make(map[string]string, len(totals))
+	for t, a := range totals {
+		p.Tokens[t] = a.String()
+	}
+	return p, nil
+}
+
+func loadStats(dir string) (*summary.Stats, error) {
+	b, err := os.ReadFile(filepath.Join(dir, cycle.StatsName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s summary.Stats
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, cycle.StatsName), err)
+	}
+	return &s, nil
+}
+
+// sourceRoot is the root claims from file, n itself or one of its shards or
+// patches, are proven against.
+func sourceRoot(dir string, n cycle.Name, file string) (string, error) {
+	idx, err := cycle.LoadShardIndex(dir, n)
+	if err != nil {
+		return "", err
+	}
+	if idx != nil {
+		for _, s := range idx.Shards {
+			if s.File != file {
+				continue
+			}
+			if idx.TwoLevel() {
+				return idx.MasterRoot, nil
+			}
+			return s.Root, nil
+		}
+	}
+	f, err := cycle.Load(filepath.Join(dir, file))
+	if err != nil {
+		return "", err
+	}
+	return f.Root, nil
+}
+
+func hasURL(ss []Source, u string) bool {
+	for _, s := range ss {
+		if s.URL == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes doc as indented JSON to path.
+func Write(path string, doc *Document) error {
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
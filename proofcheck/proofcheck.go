@@ -0,0 +1,112 @@
+// Package proofcheck verifies one claim proof, as served by /proof, with the
+// same hashing the merkle files are built with. cmd/proofcheck compiles it
+// to WebAssembly for the claim front-end and to a C shared library for
+// mobile wallets, so a client never trusts a proof it cannot check.
+//
+// A position proof is only judged when the leaf encoding is the deployed
+// distributor's, which a proof from a published cycle file shows; until then
+// every position request comes back unsupported rather than invalid, so
+// clients do not turn away real claims.
+package proofcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+)
+
+// Request is a /proof response, either form: root, leaf and proof for a
+// position, or merkleRoot, account, index, amount and proof for a
+// merkle-distributor claim. HashScheme is the file's, when not the standard
+// one.
+type Request struct {
+	Root       string     `json:"root"`
+	Leaf       cycle.Leaf `json:"leaf"`
+	HashScheme string     `json:"hashScheme,omitempty"`
+
+	MerkleRoot string `json:"merkleRoot"`
+	Account    string `json:"account"`
+	Index      uint64 `json:"index"`
+	Amount     string `json:"amount"`
+
+	Proof []string `json:"proof"`
+}
+
+type Result struct {
+	Valid bool `json:"valid"`
+	// Unsupported is set when the proof could not be judged either way.
+	Unsupported bool   `json:"unsupported,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ErrUnsupported is returned for a position proof while cycle's leaf
+// encoding does not verify a proof of a published cycle file.
+var ErrUnsupported = errors.New("cannot check position proofs")
+
+// published is 56_LM_12.json's first position, as its /proof response.
+var published = Request{
+	Root: "0xc35d8f854ee68786f47c0cef46749661ea148fcd505dd21eee5c62c1c4bf2084",
+	Leaf: cycle.Leaf{
+		ERC721Addr: "0x55f4c8aba71a1e923edc303eb4feff14608cc226",
+		ERC721ID:   "56142",
+		Tokens:     []string{"0xfe56d5892bdffc7bf58f2e84be1b2c32d21c308b"},
+		Amounts:    []string{"47028891354355262098"},
+	},
+	Proof: []string{
+		"0xcef365c1da5fb41e735923cc2968e2222dd5d97300ebd951bfb18cfd96b187d1",
+		"0xe7107838be27113d4dc53833adab04a6d1faa0e75542e9b32b33893b0a169776",
+		"0x7c5f210f8a9abbb210c9830740eb7aa7a35f2b08007b38ac6571ff7cadc1ff2b",
+		"0x281482557603fdabe6dd5092a8291474551f2e5a646f433c048c3b16d7abae89",
+		"0x13cea67129199a8d216053cc0ab663ae5b618b95e3e1849ee61b43aff6325b02",
+		"0x953d564bfc093b79bb8ed58806cef8e32e4c31006a30c3a7abe77f44197873e2",
+		"0x881f7ea5b418095c13414dfedd2affb1f995c807896fe882b3011baadaed0f29",
+		"0xee2712a8cdfcaf96cbd76759616a4321352dd00f082332a173ccb330c59dc719",
+	},
+}
+
+var encodingErr = sync.OnceValue(func() error {
+	if err := cycle.VerifyProof(merkle.Standard, published.Root, cycle.UserData{Leaf: published.Leaf, Proof: published.Proof}); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsupported, cycle.ErrLeafEncoding)
+	}
+	return nil
+})
+
+// Verify checks r's proof against its root.
+func Verify(r Request) error {
+	if r.Account != "" {
+		c := cycle.UniswapClaims{MerkleRoot: r.MerkleRoot}
+		return c.VerifyClaim(r.Account, cycle.UniswapClaim{Index: r.Index, Amount: r.Amount, Proof: r.Proof})
+	}
+	if r.Root == "" {
+		return errors.New("no root or merkleRoot")
+	}
+	scheme, err := merkle.SchemeByName(r.HashScheme)
+	if err != nil {
+		return err
+	}
+	if err := encodingErr(); err != nil {
+		return err
+	}
+	return cycle.VerifyProof(scheme, r.Root, cycle.UserData{Leaf: r.Leaf, Proof: r.Proof})
+}
+
+// VerifyJSON is Verify from a JSON Request to a JSON Result, the form the
+// WebAssembly and C exports take and return.
+func VerifyJSON(req []byte) []byte {
+	var res Result
+	var r Request
+	if err := json.Unmarshal(req, &r); err != nil {
+		res.Error = "parse request: " + err.Error()
+	} else if err := Verify(r); err != nil {
+		res.Error = err.Error()
+		res.Unsupported = errors.Is(err, ErrUnsupported)
+	} else {
+		res.Valid = true
+	}
+	b, _ := json.Marshal(res)
+	return b
+}
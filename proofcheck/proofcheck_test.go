@@ -0,0 +1,42 @@
+package proofcheck_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/proofcheck"
+)
+
+// Real claims from a published cycle file must never come back invalid. The
+// leaf encoding does not match the distributor yet, so they come back
+// unsupported; when it does, they must be valid.
+func TestPublishedProofs(t *testing.T) {
+	f, err := cycle.Load("../56_LM_12.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 1, 100, len(f.UserDatas) - 1} {
+		req, _ := json.Marshal(proofcheck.Request{Root: f.Root, Leaf: f.UserDatas[i].Leaf, Proof: f.UserDatas[i].Proof})
+		var res proofcheck.Result
+		if err := json.Unmarshal(proofcheck.VerifyJSON(req), &res); err != nil {
+			t.Fatal(err)
+		}
+		if !res.Valid && !res.Unsupported {
+			t.Errorf("userDatas[%d]: a published proof was rejected: %s", i, res.Error)
+		}
+		if res.Valid {
+			t.Errorf("userDatas[%d]: verified; drop the unsupported gate and require valid here", i)
+		}
+	}
+}
+
+func TestMalformedRequest(t *testing.T) {
+	var res proofcheck.Result
+	if err := json.Unmarshal(proofcheck.VerifyJSON([]byte(`{"proof":[]}`)), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Valid || res.Unsupported || res.Error == "" {
+		t.Errorf("request without a root: %+v", res)
+	}
+}
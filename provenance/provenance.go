@@ -0,0 +1,148 @@
+// Package provenance checks published cycle files against git history: the
+// blob at HEAD, and in the working tree, must be the blob of the commit that
+// first published the file. Once a cycle is out its files only ever gain
+// siblings, so any other blob is a rewrite.
+package provenance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+// bucket pins each path's first publication, so a rewrite of the history
+// itself (a force-push replacing the publishing commit) is caught too.
+const bucket = "provenance"
+
+// Repo runs git in a work tree.
+type Repo struct {
+	Root string
+}
+
+func (r Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CheckHistory fails on a shallow clone, whose history cannot say where a
+// file was first published.
+func (r Repo) CheckHistory() error {
+	out, err := r.git("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return err
+	}
+	if out == "true" {
+		return errors.New("shallow clone: fetch the full history (git fetch --unshallow, or fetch-depth: 0)")
+	}
+	return nil
+}
+
+// Published is where a file first appeared.
+type Published struct {
+	Commit string    `json:"commit"`
+	Blob   string    `json:"blob"`
+	Time   time.Time `json:"time"`
+}
+
+type Result struct {
+	Path      string
+	Published Published
+	// Head and Work are the file's blob at HEAD and in the working tree.
+	Head string
+	Work string
+	// Rewrites lists the commits since Published that changed the file.
+	Rewrites []string
+	// Pinned is the publication an earlier run recorded, when it differs
+	// from the history's.
+	Pinned *Published
+}
+
+// Tracked reports whether the file was ever committed.
+func (r *Result) Tracked() bool { return r.Published.Commit != "" }
+
+// Problems describes everything wrong with the file; none means intact.
+func (r *Result) Problems() []string {
+	out := make([]string, 0)
+	if !r.Tracked() {
+		return append(out, "not committed")
+	}
+	short := r.Published.Commit[:min(12, len(r.Published.Commit))]
+	if r.Pinned != nil {
+		out = append(out, fmt.Sprintf("history rewritten: first published in %s (blob %s), the history now says %s (blob %s)", r.Pinned.Commit, r.Pinned.Blob, r.Published.Commit, r.Published.Blob))
+	}
+	if r.Head != r.Published.Blob {
+		msg := fmt.Sprintf("changed since it was published in %s: blob %s at HEAD, %s then", short, r.Head, r.Published.Blob)
+		if r.Head == "" {
+			msg = fmt.Sprintf("deleted since it was published in %s", short)
+		}
+		if len(r.Rewrites) > 0 {
+			msg += " (by " + strings.Join(r.Rewrites, "; ") + ")"
+		}
+		out = append(out, msg)
+	}
+	if r.Work != r.Head {
+		out = append(out, "uncommitted change in the working tree")
+	}
+	return out
+}
+
+// Check compares the file at path, relative to the repo root, with its
+// first publication, and with the one pinned in db when db is not nil.
+func (r Repo) Check(path string, db *state.DB) (*Result, error) {
+	rel := filepath.ToSlash(path)
+	res := &Result{Path: rel}
+	log, err := r.git("log", "--diff-filter=A", "--format=%H %cI", "--", rel)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(log, "\n")
+	commit, at, _ := strings.Cut(lines[len(lines)-1], " ")
+	if commit == "" {
+		return res, nil
+	}
+	res.Published.Commit = commit
+	res.Published.Time, _ = time.Parse(time.RFC3339, at)
+	if res.Published.Blob, err = r.git("rev-parse", commit+":./"+rel); err != nil {
+		return nil, err
+	}
+	// A file deleted at HEAD has no blob there.
+	res.Head, _ = r.git("rev-parse", "HEAD:./"+rel)
+	res.Work, _ = r.git("hash-object", "--", rel)
+	if res.Head != res.Published.Blob {
+		changes, err := r.git("log", "--format=%h %an: %s", commit+"..HEAD", "--", rel)
+		if err != nil {
+			return nil, err
+		}
+		if changes != "" {
+			res.Rewrites = strings.Split(changes, "\n")
+		}
+	}
+
+	if db == nil {
+		return res, nil
+	}
+	var pin Published
+	ok, err := db.Get(bucket, rel, &pin)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return res, db.Put(bucket, rel, res.Published)
+	}
+	if pin.Commit != res.Published.Commit || pin.Blob != res.Published.Blob {
+		res.Pinned = &pin
+	}
+	return res, nil
+}
@@ -0,0 +1,138 @@
+// Package queue persists side effects that failed after the main work
+// succeeded — notifications, Notion writebacks, webhook deliveries — in the
+// state DB, so flush-queue or a daemon can retry them instead of the failure
+// being lost in a log.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+const bucket = "queue"
+
+const (
+	baseBackoff = time.Minute
+	maxBackoff  = 6 * time.Hour
+)
+
+type Job struct {
+	ID      string          `json:"id"`
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+	// Attempts counts failures, including the original one.
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError"`
+	CreatedAt   time.Time `json:"createdAt"`
+	NextAttempt time.Time `json:"nextAttempt"`
+}
+
+// Handler performs a job of one kind from its payload. Credentials come from
+// the environment at retry time and are never stored in the payload.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type Queue struct {
+	db *state.DB
+}
+
+func New(db *state.DB) *Queue { return &Queue{db: db} }
+
+// Enqueue records a side effect that just failed with cause and saves the DB.
+func (q *Queue) Enqueue(kind string, payload any, cause error) (Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, err
+	}
+	now := time.Now().UTC()
+	j := Job{ID: newID(), Kind: kind, Payload: raw, CreatedAt: now}
+	j.fail(cause, now)
+	if err := q.db.Put(bucket, j.ID, j); err != nil {
+		return Job{}, err
+	}
+	return j, q.db.Save()
+}
+
+func (j *Job) fail(err error, now time.Time) {
+	j.Attempts++
+	j.LastError = err.Error()
+	d := baseBackoff << min(j.Attempts-1, 16)
+	j.NextAttempt = now.Add(min(d, maxBackoff))
+}
+
+// List returns the queued jobs, oldest first.
+func (q *Queue) List() ([]Job, error) {
+	out := make([]Job, 0)
+	for _, id := range q.db.Keys(bucket) {
+		var j Job
+		if _, err := q.db.Get(bucket, id, &j); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].CreatedAt.Before(out[k].CreatedAt) })
+	return out, nil
+}
+
+// Drop removes job id and reports whether it existed.
+func (q *Queue) Drop(id string) (bool, error) {
+	var j Job
+	ok, err := q.db.Get(bucket, id, &j)
+	if err != nil || !ok {
+		return false, err
+	}
+	q.db.Delete(bucket, id)
+	return true, q.db.Save()
+}
+
+type Result struct {
+	Done, Failed, Waiting, Unhandled int
+}
+
+// Flush retries every job that is due (every job with force), removing those
+// that succeed and backing off the rest. Jobs of a kind without a handler
+// are left alone.
+func (q *Queue) Flush(ctx context.Context, handlers map[string]Handler, force bool) (Result, error) {
+	var res Result
+	jobs, err := q.List()
+	if err != nil {
+		return res, err
+	}
+	for _, j := range jobs {
+		h, ok := handlers[j.Kind]
+		switch {
+		case !ok:
+			res.Unhandled++
+			continue
+		case !force && time.Now().Before(j.NextAttempt):
+			res.Waiting++
+			continue
+		}
+		if err := h(ctx, j.Payload); err != nil {
+			j.fail(err, time.Now().UTC())
+			if err := q.db.Put(bucket, j.ID, j); err != nil {
+				return res, err
+			}
+			res.Failed++
+			continue
+		}
+		q.db.Delete(bucket, j.ID)
+		res.Done++
+	}
+	if err := q.db.Save(); err != nil {
+		return res, fmt.Errorf("save queue: %w", err)
+	}
+	return res, nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
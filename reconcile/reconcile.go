@@ -0,0 +1,201 @@
+// Package reconcile compares what distributors still owe with what they
+// actually hold on-chain.
+//
+// A distributor pays out against a single merkle root, and its claimed
+// amounts are cumulative per position across every cycle it has paid, so
+// what it owes is the file for its current root minus those claims. Older
+// cycle files are superseded, not owed on top.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+// Outstanding is the unclaimed amount of one token in the file for a
+// distributor's current root.
+type Outstanding struct {
+	Cycle     int    `json:"cycle"`
+	File      string `json:"file"`
+	Root      string `json:"root"`
+	Total     string `json:"total"`
+	Unclaimed string `json:"unclaimed"`
+}
+
+// Line is one (chain, distributor, token) row of the report.
+type Line struct {
+	ChainID     string        `json:"chainId"`
+	Distributor string        `json:"distributor"`
+	Token       string        `json:"token"`
+	Balance     string        `json:"balance"`
+	Unclaimed   string        `json:"unclaimed"`
+	Shortfall   string        `json:"shortfall"`
+	Treasury    string        `json:"treasury,omitempty"`
+	TreasuryBal string        `json:"treasuryBalance,omitempty"`
+	Cycles      []Outstanding `json:"cycles"`
+	// Links are explorer pages for the addresses above, when the chain
+	// registry has an explorer.
+	Links *Links `json:"links,omitempty"`
+}
+
+type Links struct {
+	Distributor string `json:"distributor,omitempty"`
+	Token       string `json:"token,omitempty"`
+	Treasury    string `json:"treasury,omitempty"`
+}
+
+func (l Line) Underfunded() bool {
+	s, ok := new(big.Int).SetString(l.Shortfall, 10)
+	return ok && s.Sign() > 0
+}
+
+type Report struct {
+	// Block is the requested block tag; Blocks is the block number actually
+	// read on each chain.
+	Block  string            `json:"block"`
+	Blocks map[string]uint64 `json:"blocks,omitempty"`
+	Lines  []Line            `json:"lines"`
+}
+
+func (r *Report) Underfunded() []Line {
+	out := make([]Line, 0)
+	for _, l := range r.Lines {
+		if l.Underfunded() {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Current returns the index of the file among one distributor's files whose
+// root is root, the only one its claimed amounts can be subtracted from.
+func Current(files []*cycle.File, root string) (int, error) {
+	for i, f := range files {
+		if strings.EqualFold(f.Root, root) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("root %s matches none of the %d cycle files", root, len(files))
+}
+
+// FileUnclaimed sums the unclaimed amounts of f per token. f must be the file
+// for the distributor's current root; see Current.
+func FileUnclaimed(ctx context.Context, f *cycle.File, src claims.Source) (map[string]*big.Int, error) {
+	out := make(map[string]*big.Int)
+	for _, ud := range f.UserDatas {
+		c, err := src.Claimed(ctx, ud.Leaf)
+		if err != nil {
+			return nil, err
+		}
+		rest, err := claims.Unclaimed(ud.Leaf, c)
+		if err != nil {
+			return nil, err
+		}
+		for t, a := range rest {
+			if prev, ok := out[t]; ok {
+				prev.Add(prev, a)
+			} else {
+				out[t] = new(big.Int).Set(a)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Accumulator groups unclaimed amounts by distributor and token.
+type Accumulator struct {
+	lines map[string]*acc
+}
+
+type acc struct {
+	line      Line
+	unclaimed *big.Int
+}
+
+func NewAccumulator() *Accumulator {
+	return &Accumulator{lines: make(map[string]*acc)}
+}
+
+// Add records the unclaimed amounts of the distributor's current file e.
+func (a *Accumulator) Add(chainID, distributor string, e cycle.Entry, root string, totals, unclaimed map[string]*big.Int) {
+	for t, u := range unclaimed {
+		key := chainID + ":" + strings.ToLower(distributor) + ":" + t
+		l, ok := a.lines[key]
+		if !ok {
+			l = &acc{line: Line{ChainID: chainID, Distributor: strings.ToLower(distributor), Token: t}, unclaimed: new(big.Int)}
+			a.lines[key] = l
+		}
+		l.unclaimed.Add(l.unclaimed, u)
+		total := "0"
+		if tt, ok := totals[t]; ok {
+			total = tt.String()
+		}
+		l.line.Cycles = append(l.line.Cycles, Outstanding{Cycle: e.Cycle, File: e.Name.String(), Root: root, Total: total, Unclaimed: u.String()})
+	}
+}
+
+// BalanceFunc reads a token balance for a holder on a chain.
+type BalanceFunc func(ctx context.Context, chainID, token, holder string) (*big.Int, error)
+
+// Finish reads balances and computes shortfalls.
+func (a *Accumulator) Finish(ctx context.Context, block string, balance BalanceFunc, treasury func(chainID string) string) (*Report, error) {
+	keys := make([]string, 0, len(a.lines))
+	for k := range a.lines {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	r := &Report{Block: block}
+	for _, k := range keys {
+		l := a.lines[k]
+		bal, err := balance(ctx, l.line.ChainID, l.line.Token, l.line.Distributor)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: balance of %s at %s: %w", l.line.ChainID, l.line.Token, l.line.Distributor, err)
+		}
+		short := new(big.Int).Sub(l.unclaimed, bal)
+		if short.Sign() < 0 {
+			short.SetInt64(0)
+		}
+		l.line.Balance = bal.String()
+		l.line.Unclaimed = l.unclaimed.String()
+		l.line.Shortfall = short.String()
+		if tr := treasury(l.line.ChainID); tr != "" {
+			tb, err := balance(ctx, l.line.ChainID, l.line.Token, tr)
+			if err != nil {
+				return nil, fmt.Errorf("chain %s: treasury balance of %s: %w", l.line.ChainID, l.line.Token, err)
+			}
+			l.line.Treasury = strings.ToLower(tr)
+			l.line.TreasuryBal = tb.String()
+		}
+		sort.Slice(l.line.Cycles, func(i, j int) bool { return l.line.Cycles[i].Cycle < l.line.Cycles[j].Cycle })
+		r.Lines = append(r.Lines, l.line)
+	}
+	return r, nil
+}
+
+func WriteJSON(path string, r *Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+func LoadJSON(path string) (*Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("parse reconcile report: %w", err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,181 @@
+package reconcile_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/claims"
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/reconcile"
+)
+
+const (
+	nft   = "0x00000000000000000000000000000000000000a1"
+	tokA  = "0x00000000000000000000000000000000000000b1"
+	tokB  = "0x00000000000000000000000000000000000000b2"
+	dist  = "0x00000000000000000000000000000000000000D1"
+	treas = "0x00000000000000000000000000000000000000c1"
+)
+
+// file pays nft:<id> the cumulative amounts given per id.
+func file(t *testing.T, end string, pays map[string]map[string]*big.Int) *cycle.File {
+	t.Helper()
+	f := &cycle.File{StartTimestamp: "100", EndTimestamp: end, Salt: "0x" + strings.Repeat("0", 64)}
+	for id, am := range pays {
+		f.UserDatas = append(f.UserDatas, cycle.UserData{Leaf: cycle.NewLeaf(nft, id, am)})
+	}
+	if err := cycle.Rebuild(f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func amt(n int64) *big.Int { return big.NewInt(n) }
+
+func entry(t *testing.T, name string) cycle.Entry {
+	t.Helper()
+	n, ok := cycle.ParseName(name)
+	if !ok {
+		t.Fatalf("bad name %s", name)
+	}
+	return cycle.Entry{Name: n, Path: name}
+}
+
+func TestCurrent(t *testing.T) {
+	files := []*cycle.File{
+		file(t, "200", map[string]map[string]*big.Int{"1": {tokA: amt(10)}}),
+		file(t, "300", map[string]map[string]*big.Int{"1": {tokA: amt(25)}}),
+	}
+	i, err := reconcile.Current(files, "0x"+strings.ToUpper(files[1].Root[2:]))
+	if err != nil || i != 1 {
+		t.Fatalf("got %d, %v, want 1", i, err)
+	}
+	if _, err := reconcile.Current(files, "0x"+strings.Repeat("0", 64)); err == nil {
+		t.Fatal("matched an unknown root")
+	}
+}
+
+func TestFileUnclaimed(t *testing.T) {
+	f := file(t, "300", map[string]map[string]*big.Int{
+		"1": {tokA: amt(100), tokB: amt(40)},
+		"2": {tokA: amt(50)},
+		"3": {tokB: amt(5)},
+	})
+	cases := []struct {
+		name    string
+		claimed claims.FileSource
+		want    map[string]string
+		err     bool
+	}{
+		{
+			name: "nothing claimed",
+			want: map[string]string{tokA: "150", tokB: "45"},
+		},
+		{
+			name: "partly claimed",
+			claimed: claims.FileSource{
+				nft + ":1": {tokA: amt(60)},
+				nft + ":2": {tokA: amt(50)},
+			},
+			want: map[string]string{tokA: "40", tokB: "45"},
+		},
+		{
+			name: "fully claimed token drops out",
+			claimed: claims.FileSource{
+				nft + ":1": {tokB: amt(40)},
+				nft + ":3": {tokB: amt(5)},
+			},
+			want: map[string]string{tokA: "150"},
+		},
+		{
+			name:    "claimed more than the file pays",
+			claimed: claims.FileSource{nft + ":2": {tokA: amt(51)}},
+			err:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := reconcile.FileUnclaimed(context.Background(), f, c.claimed)
+			if c.err {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+			for tok, v := range c.want {
+				if got[tok] == nil || got[tok].String() != v {
+					t.Errorf("%s = %v, want %s", tok, got[tok], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	a := reconcile.NewAccumulator()
+	root := "0x" + strings.Repeat("1", 64)
+	a.Add("56", dist, entry(t, "56_LM_4.json"), root,
+		map[string]*big.Int{tokA: amt(100), tokB: amt(30)},
+		map[string]*big.Int{tokA: amt(70), tokB: amt(30)})
+	a.Add("56", dist, entry(t, "56_EG_4.json"), root,
+		map[string]*big.Int{tokA: amt(20)},
+		map[string]*big.Int{tokA: amt(20)})
+	a.Add("1", dist, entry(t, "1_LM_4.json"), root, nil, map[string]*big.Int{tokA: amt(5)})
+
+	balances := map[string]int64{
+		"56:" + strings.ToLower(dist) + ":" + tokA: 60,
+		"56:" + strings.ToLower(dist) + ":" + tokB: 45,
+		"1:" + strings.ToLower(dist) + ":" + tokA:  5,
+		"56:" + treas + ":" + tokA:                 1000,
+		"56:" + treas + ":" + tokB:                 0,
+	}
+	balance := func(_ context.Context, chainID, token, holder string) (*big.Int, error) {
+		return amt(balances[chainID+":"+strings.ToLower(holder)+":"+token]), nil
+	}
+	treasury := func(chainID string) string {
+		if chainID == "56" {
+			return treas
+		}
+		return ""
+	}
+	r, err := a.Finish(context.Background(), "latest", balance, treasury)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		chain, token, unclaimed, balance, shortfall, treasury string
+		cycles                                                int
+	}{
+		{"1", tokA, "5", "5", "0", "", 1},
+		{"56", tokA, "90", "60", "30", "1000", 2},
+		{"56", tokB, "30", "45", "0", "0", 1},
+	}
+	if len(r.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(r.Lines), len(want))
+	}
+	for i, w := range want {
+		l := r.Lines[i]
+		if l.ChainID != w.chain || l.Token != w.token || l.Unclaimed != w.unclaimed || l.Balance != w.balance ||
+			l.Shortfall != w.shortfall || l.TreasuryBal != w.treasury || len(l.Cycles) != w.cycles {
+			t.Errorf("line %d = %+v, want %+v", i, l, w)
+		}
+		if l.Distributor != strings.ToLower(dist) {
+			t.Errorf("line %d distributor %s", i, l.Distributor)
+		}
+	}
+	if u := r.Underfunded(); len(u) != 1 || u[0].Token != tokA || u[0].ChainID != "56" {
+		t.Errorf("underfunded %+v", u)
+	}
+	if c := r.Lines[1].Cycles[0]; c.Total != "100" || c.Unclaimed != "70" || c.Root != root {
+		t.Errorf("cycle %+v", c)
+	}
+}
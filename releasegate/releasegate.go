@@ -0,0 +1,282 @@
+// Package releasegate holds a cycle to the organisation's release policies
+// before publish uploads it. Each gate applies one of a small set of fixed
+// rules to the cycle's summary and its recent history:
+//
+// config/release-gates.json:
+//
+//	{
+//	  "history": 4,
+//	  "gates": [
+//	    {
+//	      "name": "chain-totals-near-average",
+//	      "rule": "chain-total-near-average",
+//	      "maxDeviationBps": 1000,
+//	      "message": "a chain's total is more than 10% off its average over the last 4 cycles"
+//	    },
+//	    {
+//	      "name": "new-chain-approved",
+//	      "rule": "new-chain-approved",
+//	      "message": "a new chain needs --gate-approvals new-chain:<id>"
+//	    },
+//	    {
+//	      "name": "bsc-knc-cap",
+//	      "rule": "chain-total-cap",
+//	      "chains": ["56"],
+//	      "limits": {"0xfe56d5892bdffc7bf58f2e84be1b2c32d21c308b": "100000000000000000000000"}
+//	    }
+//	  ]
+//	}
+//
+// The rules are:
+//
+//	chain-total-near-average  each chain's total of each token is within
+//	                          maxDeviationBps of its mean over the history
+//	                          cycles that paid the token on the chain; a
+//	                          token no history cycle paid there passes
+//	new-chain-approved        a chain no history cycle paid on needs the
+//	                          approval new-chain:<id> (publish --gate-approvals)
+//	chain-total-cap           no chain pays more of a token than its limit
+//
+// Chains, when set, limits a gate to those chain IDs. Amounts are base
+// units. Without a gates file nothing is checked.
+package releasegate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/evm"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+)
+
+const DefaultPath = "config/release-gates.json"
+
+// DefaultHistory is how many previous cycles the history covers by
+// default.
+const DefaultHistory = 4
+
+const (
+	RuleNearAverage = "chain-total-near-average"
+	RuleNewChain    = "new-chain-approved"
+	RuleTotalCap    = "chain-total-cap"
+)
+
+// Rules are the rules a gate may name.
+var Rules = []string{RuleNearAverage, RuleNewChain, RuleTotalCap}
+
+type Gate struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+	// Chains limits the gate to these chain IDs; empty is every chain.
+	Chains []string `json:"chains,omitempty"`
+	// MaxDeviationBps is how far off its average a chain-total-near-average
+	// total may be.
+	MaxDeviationBps int64 `json:"maxDeviationBps,omitempty"`
+	// Limits maps token to the most a chain-total-cap chain may pay of it.
+	Limits map[string]string `json:"limits,omitempty"`
+	// Message tells whoever runs publish what failed and how to proceed.
+	Message string `json:"message,omitempty"`
+
+	limits map[string]*big.Int
+}
+
+type Config struct {
+	History int    `json:"history,omitempty"`
+	Gates   []Gate `json:"gates"`
+}
+
+// Load reads and checks the gates at path; a missing file has none.
+func Load(path string) (*Config, error) {
+	c := &Config{History: DefaultHistory}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("parse release gates: %w", err)
+	}
+	if c.History <= 0 {
+		c.History = DefaultHistory
+	}
+	seen := make(map[string]bool, len(c.Gates))
+	for i := range c.Gates {
+		g := &c.Gates[i]
+		if g.Name == "" || seen[g.Name] {
+			return nil, fmt.Errorf("release gates: gate %d: missing or duplicate name %q", i, g.Name)
+		}
+		seen[g.Name] = true
+		if err := g.check(); err != nil {
+			return nil, fmt.Errorf("release gates: %s: %w", g.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// check validates the gate's parameters for its rule.
+func (g *Gate) check() error {
+	if !slices.Contains(Rules, g.Rule) {
+		return fmt.Errorf("unknown rule %q (want one of %s)", g.Rule, strings.Join(Rules, ", "))
+	}
+	switch {
+	case g.Rule == RuleNearAverage && g.MaxDeviationBps <= 0:
+		return fmt.Errorf("%s needs a positive maxDeviationBps", g.Rule)
+	case g.Rule != RuleNearAverage && g.MaxDeviationBps != 0:
+		return fmt.Errorf("maxDeviationBps only applies to %s", RuleNearAverage)
+	case g.Rule == RuleTotalCap && len(g.Limits) == 0:
+		return fmt.Errorf("%s needs limits", g.Rule)
+	case g.Rule != RuleTotalCap && len(g.Limits) > 0:
+		return fmt.Errorf("limits only apply to %s", RuleTotalCap)
+	}
+	g.limits = make(map[string]*big.Int, len(g.Limits))
+	for t, v := range g.Limits {
+		if !evm.IsAddress(t) {
+			return fmt.Errorf("limits: invalid token %q", t)
+		}
+		a, err := cycle.ParseAmount(v)
+		if err != nil {
+			return fmt.Errorf("limits %s: %w", t, err)
+		}
+		g.limits[strings.ToLower(t)] = a
+	}
+	return nil
+}
+
+// LoadHistory summarises the up to c.History cycle directories before cur
+// that are beside dir, most recent first.
+func (c *Config) LoadHistory(dir string, cur int) ([]*summary.Cycle, error) {
+	out := make([]*summary.Cycle, 0, c.History)
+	parent := filepath.Dir(filepath.Clean(dir))
+	for n := cur - 1; n >= max(1, cur-c.History); n-- {
+		d := filepath.Join(parent, cycle.DirName(n))
+		if _, err := os.Stat(d); os.IsNotExist(err) {
+			continue
+		}
+		s, err := summary.Build(d)
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", n, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Result is one gate's verdict.
+type Result struct {
+	Gate string `json:"gate"`
+	OK   bool   `json:"ok"`
+	// Problem is the gate's message and what broke it, when it failed.
+	Problem string `json:"problem,omitempty"`
+}
+
+// Evaluate runs every gate against cur.
+func (c *Config) Evaluate(cur *summary.Cycle, history []*summary.Cycle, approvals []string) []Result {
+	out := make([]Result, 0, len(c.Gates))
+	for _, g := range c.Gates {
+		var bad []string
+		for _, id := range cur.Chains() {
+			if len(g.Chains) > 0 && !slices.Contains(g.Chains, id) {
+				continue
+			}
+			switch g.Rule {
+			case RuleNearAverage:
+				bad = append(bad, nearAverage(id, cur, history, g.MaxDeviationBps)...)
+			case RuleNewChain:
+				if !paid(id, history) && !slices.Contains(approvals, "new-chain:"+id) {
+					bad = append(bad, fmt.Sprintf("chain %s is new and not approved", id))
+				}
+			case RuleTotalCap:
+				bad = append(bad, overCap(id, cur, g.limits)...)
+			}
+		}
+		r := Result{Gate: g.Name, OK: len(bad) == 0}
+		if !r.OK {
+			r.Problem = strings.Join(bad, "; ")
+			if g.Message != "" {
+				r.Problem = g.Message + ": " + r.Problem
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func paid(chain string, history []*summary.Cycle) bool {
+	for _, h := range history {
+		if _, ok := h.ChainTotals[chain]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nearAverage lists chain's tokens whose total is more than maxBps off the
+// mean of the history cycles that paid them there.
+func nearAverage(chain string, cur *summary.Cycle, history []*summary.Cycle, maxBps int64) []string {
+	var bad []string
+	for _, t := range sortedKeys(cur.ChainTotals[chain]) {
+		sum, n := new(big.Rat), 0
+		for _, h := range history {
+			if a, ok := h.ChainTotals[chain][t]; ok {
+				sum.Add(sum, amount(a))
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		avg := sum.Quo(sum, big.NewRat(int64(n), 1))
+		total := amount(cur.ChainTotals[chain][t])
+		diff := new(big.Rat).Sub(total, avg)
+		diff.Abs(diff)
+		// |total - avg| * 10000 > avg * maxBps, which also fails any total
+		// against a zero average.
+		if diff.Mul(diff, big.NewRat(10_000, 1)).Cmp(new(big.Rat).Mul(avg, big.NewRat(maxBps, 1))) > 0 {
+			bad = append(bad, fmt.Sprintf("chain %s pays %s of %s against an average of %s", chain, total.RatString(), t, avg.FloatString(0)))
+		}
+	}
+	return bad
+}
+
+// overCap lists chain's tokens whose total is above their limit.
+func overCap(chain string, cur *summary.Cycle, limits map[string]*big.Int) []string {
+	var bad []string
+	for _, t := range sortedKeys(cur.ChainTotals[chain]) {
+		limit, ok := limits[strings.ToLower(t)]
+		if !ok {
+			continue
+		}
+		if total := amount(cur.ChainTotals[chain][t]); total.Cmp(new(big.Rat).SetInt(limit)) > 0 {
+			bad = append(bad, fmt.Sprintf("chain %s pays %s of %s, above the limit of %s", chain, total.RatString(), t, limit))
+		}
+	}
+	return bad
+}
+
+// amount reads a summary total, which summary.Build always writes as
+// base units.
+func amount(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
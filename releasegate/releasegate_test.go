@@ -0,0 +1,88 @@
+package releasegate_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/releasegate"
+	"github.com/KyberNetwork/fairflow-reward/summary"
+)
+
+const gates = `{
+  "gates": [
+    {"name": "near", "rule": "chain-total-near-average", "maxDeviationBps": 1000, "message": "off average"},
+    {"name": "new", "rule": "new-chain-approved"},
+    {"name": "cap", "rule": "chain-total-cap", "chains": ["56"], "limits": {"0x00000000000000000000000000000000000000A1": "2000"}}
+  ]
+}`
+
+func load(t *testing.T, src string) (*releasegate.Config, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "gates.json")
+	if err := os.WriteFile(p, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return releasegate.Load(p)
+}
+
+func cycleOf(n int, totals map[string]map[string]string) *summary.Cycle {
+	return &summary.Cycle{Cycle: n, ChainTotals: totals}
+}
+
+func TestEvaluate(t *testing.T) {
+	const a1, a2 = "0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"
+	cfg, err := load(t, gates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []*summary.Cycle{
+		cycleOf(11, map[string]map[string]string{"56": {a1: "1000"}}),
+		cycleOf(10, map[string]map[string]string{"56": {a1: "900", a2: "5"}}),
+	}
+	cases := []struct {
+		name      string
+		totals    map[string]map[string]string
+		approvals []string
+		failed    map[string]string
+	}{
+		{"within", map[string]map[string]string{"56": {a1: "1045", a2: "5"}}, nil, nil},
+		// 950 average, 10% is 95 either way.
+		{"edge", map[string]map[string]string{"56": {a1: "855"}}, nil, nil},
+		{"off", map[string]map[string]string{"56": {a1: "1046"}}, nil, map[string]string{"near": "off average: chain 56 pays 1046 of " + a1}},
+		// A token new to the chain has no average to be off.
+		{"new token", map[string]map[string]string{"56": {a1: "950", "0x00000000000000000000000000000000000000a3": "7"}}, nil, nil},
+		{"new chain", map[string]map[string]string{"56": {a1: "950"}, "137": {a1: "1"}}, nil, map[string]string{"new": "chain 137 is new"}},
+		{"approved", map[string]map[string]string{"56": {a1: "950"}, "137": {a1: "1"}}, []string{"new-chain:137"}, nil},
+		// The cap is on 56 only; 137 may pay more.
+		{"cap", map[string]map[string]string{"56": {a1: "2001"}, "137": {a1: "3000"}}, []string{"new-chain:137"}, map[string]string{"near": "chain 56", "cap": "above the limit of 2000"}},
+	}
+	for _, c := range cases {
+		for _, r := range cfg.Evaluate(cycleOf(12, c.totals), history, c.approvals) {
+			want, fail := c.failed[r.Gate]
+			if r.OK == fail || fail && !strings.Contains(r.Problem, want) {
+				t.Errorf("%s: gate %s = %v %q, want failing %v with %q", c.name, r.Gate, r.OK, r.Problem, fail, want)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"gates": [{"name": "x", "rule": "chains.all(c, true)"}]}`,
+		`{"gates": [{"name": "x", "rule": "chain-total-near-average"}]}`,
+		`{"gates": [{"name": "x", "rule": "new-chain-approved", "maxDeviationBps": 10}]}`,
+		`{"gates": [{"name": "x", "rule": "chain-total-cap"}]}`,
+		`{"gates": [{"name": "x", "rule": "chain-total-cap", "limits": {"0xa1": "1"}}]}`,
+		`{"gates": [{"name": "x", "rule": "chain-total-cap", "limits": {"0x00000000000000000000000000000000000000a1": "-1"}}]}`,
+		`{"gates": [{"name": "x", "rule": "new-chain-approved"}, {"name": "x", "rule": "new-chain-approved"}]}`,
+	} {
+		if _, err := load(t, src); err == nil {
+			t.Errorf("%s loaded", src)
+		}
+	}
+	if cfg, err := releasegate.Load(filepath.Join(t.TempDir(), "none.json")); err != nil || len(cfg.Gates) != 0 {
+		t.Errorf("missing file: %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,66 @@
+// Package safe writes Safe{Wallet} Transaction Builder batch files that
+// signers import and execute from the treasury Safe.
+package safe
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"time"
+)
+
+type Batch struct {
+	Version      string `json:"version"`
+	ChainID      string `json:"chainId"`
+	CreatedAt    int64  `json:"createdAt"`
+	Meta         Meta   `json:"meta"`
+	Transactions []Tx   `json:"transactions"`
+}
+
+type Meta struct {
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	CreatedFromSafeAddress string `json:"createdFromSafeAddress,omitempty"`
+}
+
+type Tx struct {
+	To    string `json:"to"`
+	Value string `json:"value"`
+	Data  string `json:"data"`
+}
+
+func NewBatch(chainID, name, description, safeAddr string) *Batch {
+	return &Batch{
+		Version:   "1.0",
+		ChainID:   chainID,
+		CreatedAt: time.Now().UnixMilli(),
+		Meta: Meta{
+			Name:                   name,
+			Description:            description,
+			CreatedFromSafeAddress: safeAddr,
+		},
+		Transactions: make([]Tx, 0),
+	}
+}
+
+// Add appends a call; nil value means zero and nil data a plain transfer.
+func (b *Batch) Add(to string, value *big.Int, data []byte) {
+	v := "0"
+	if value != nil {
+		v = value.String()
+	}
+	d := "0x"
+	if len(data) > 0 {
+		d = "0x" + hex.EncodeToString(data)
+	}
+	b.Transactions = append(b.Transactions, Tx{To: to, Value: v, Data: d})
+}
+
+func (b *Batch) Write(path string) error {
+	out, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
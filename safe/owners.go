@@ -0,0 +1,45 @@
+package safe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KyberNetwork/fairflow-reward/evm"
+)
+
+// Info is a Safe's signing policy as read on-chain.
+type Info struct {
+	Address   string   `json:"address"`
+	Owners    []string `json:"owners"`
+	Threshold int64    `json:"threshold"`
+}
+
+// Inspect reads addr's owners and threshold. It returns nil, nil when addr is
+// an EOA or a contract that does not answer the Safe getters.
+func Inspect(ctx context.Context, c *evm.Client, addr, block string) (*Info, error) {
+	code, err := c.CodeAt(ctx, addr, block)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, nil
+	}
+	res, err := c.Call(ctx, addr, evm.Selector("getThreshold()"), block)
+	if err != nil || len(res) == 0 {
+		// Contracts without the Safe getters revert.
+		return nil, nil
+	}
+	t, err := evm.DecodeUint(res, 0)
+	if err != nil || !t.IsInt64() || t.Sign() == 0 {
+		return nil, nil
+	}
+	res, err = c.Call(ctx, addr, evm.Selector("getOwners()"), block)
+	if err != nil {
+		return nil, fmt.Errorf("safe %s: getOwners: %w", addr, err)
+	}
+	owners, err := evm.DecodeAddressArray(res)
+	if err != nil {
+		return nil, fmt.Errorf("safe %s: getOwners: %w", addr, err)
+	}
+	return &Info{Address: addr, Owners: owners, Threshold: t.Int64()}, nil
+}
@@ -0,0 +1,434 @@
+// Package schedule describes when cycles are released with a cron-like
+// expression in a fixed time zone, and derives from it the cycle number due
+// at any time. Cycles go out every other Thursday at 14:00 UTC+7:
+//
+//	0 14 * * 4/2   in UTC+7, anchored at 21@2026-10-08
+//
+// The fields are minute, hour, day of month, month and day of week (0 =
+// Sunday), each "*", a number, a range "a-b", a list "a,b", or a step "*/n"
+// or "a-b/n". In the day-of-week field a step after a single day means weeks
+// instead: "4/2" is every second Thursday, counted from the anchor. "*/2" is
+// Sunday, Tuesday, Thursday and Saturday as in cron, and "1-5/2" is refused
+// as ambiguous.
+//
+// Times are wall-clock times in the zone. One that the clocks skip going
+// forward fires as much later as they moved, and one they repeat going back
+// fires once, at its first occurrence.
+//
+// A cadence that is not a whole number of weeks is "@every <n>d" (or
+// "<n>w"): every n days from the anchor, at the anchor's time of day.
+//
+//	@every 10d   in UTC, anchored at 1@2026-01-05T09:00
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets
+	domAll, dowAll                bool
+	// weeks is the day-of-week step: fire only every weeks-th week.
+	weeks int
+	// every, when set, replaces the fields: fire every that many days from
+	// the anchor.
+	every int
+	loc   *time.Location
+	// anchor is a firing time and the cycle released at it.
+	anchor      time.Time
+	anchorCycle int
+}
+
+type field struct {
+	min, max int
+}
+
+// Day of week runs to 7, which is Sunday again.
+var fields = []field{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Parse reads a five-field or @every expression evaluated in loc.
+func Parse(expr string, loc *time.Location) (*Schedule, error) {
+	if period, ok := strings.CutPrefix(expr, "@every "); ok {
+		days, err := parsePeriod(strings.TrimSpace(period))
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		return &Schedule{loc: loc, weeks: 1, every: days}, nil
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 fields (minute hour day month weekday), got %d", expr, len(parts))
+	}
+	s := &Schedule{loc: loc, weeks: 1, domAll: parts[2] == "*", dowAll: parts[4] == "*"}
+	sets := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, p := range parts {
+		if i == 4 {
+			if base, step, ok := strings.Cut(p, "/"); ok && !strings.HasPrefix(p, "*") {
+				if strings.ContainsAny(base, "-,") {
+					return nil, fmt.Errorf("schedule %q: a week step takes a single day, as \"4/2\"; %q is ambiguous", expr, p)
+				}
+				n, err := strconv.Atoi(step)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("schedule %q: invalid week step %q", expr, step)
+				}
+				s.weeks, p = n, base
+			}
+		}
+		set, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		*sets[i] = set
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(p string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(p, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid range %q", item)
+				}
+			}
+			if lo < f.min || hi > f.max || lo > hi {
+				return 0, fmt.Errorf("%q is out of range %d-%d", item, f.min, f.max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// parsePeriod reads "14d" or "2w" as a number of days.
+func parsePeriod(v string) (int, error) {
+	unit := 1
+	switch {
+	case strings.HasSuffix(v, "d"):
+	case strings.HasSuffix(v, "w"):
+		unit = 7
+	default:
+		return 0, fmt.Errorf("invalid period %q: want <n>d or <n>w", v)
+	}
+	n, err := strconv.Atoi(v[:len(v)-1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid period %q: want <n>d or <n>w", v)
+	}
+	return n * unit, nil
+}
+
+// Anchor ties the schedule to the cycle released at firing time at; it is
+// required for week steps, @every and Expected. For @every, at is the
+// start every later release is counted from.
+func (s *Schedule) Anchor(cycle int, at time.Time) error {
+	at = at.In(s.loc)
+	if s.every == 0 && !s.matches(at) {
+		return fmt.Errorf("anchor %s is not a time the schedule fires", at.Format(time.RFC3339))
+	}
+	s.anchor, s.anchorCycle = at, cycle
+	return nil
+}
+
+// ParseAnchor reads "<cycle>@<date>[T<hh:mm>]" in the schedule's zone, as
+// "21@2026-10-08T14:00"; without a time it takes the day's firing time, or
+// midnight for @every.
+func (s *Schedule) ParseAnchor(v string) error {
+	c, d, ok := strings.Cut(v, "@")
+	if !ok {
+		return fmt.Errorf("anchor %q: want <cycle>@<date>", v)
+	}
+	n, err := strconv.Atoi(c)
+	if err != nil {
+		return fmt.Errorf("anchor %q: invalid cycle", v)
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", d, s.loc); err == nil {
+		return s.Anchor(n, t)
+	}
+	day, err := time.ParseInLocation("2006-01-02", d, s.loc)
+	if err != nil {
+		return fmt.Errorf("anchor %q: invalid date", v)
+	}
+	if s.every > 0 {
+		return s.Anchor(n, day)
+	}
+	t := s.next(day.Add(-time.Minute), false)
+	if t.IsZero() || !sameDay(t, day) {
+		return fmt.Errorf("anchor %q: the schedule does not fire that day", v)
+	}
+	return s.Anchor(n, t)
+}
+
+func (s *Schedule) matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	return s.dayMatches(t, true)
+}
+
+func (s *Schedule) dayMatches(t time.Time, withWeeks bool) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	var ok bool
+	switch {
+	case s.domAll:
+		ok = dow
+	case s.dowAll:
+		ok = dom
+	default:
+		ok = dom || dow // cron: either restricted day field matches
+	}
+	if !ok || s.weeks == 1 || !withWeeks || s.anchor.IsZero() {
+		return ok
+	}
+	days := int(dayNumber(t) - dayNumber(s.anchor))
+	weeks := days / 7
+	if days < 0 && days%7 != 0 {
+		weeks--
+	}
+	return ((weeks%s.weeks)+s.weeks)%s.weeks == 0
+}
+
+// Next returns the first firing time strictly after t.
+func (s *Schedule) Next(t time.Time) time.Time { return s.next(t, true) }
+
+func (s *Schedule) next(t time.Time, withWeeks bool) time.Time {
+	if s.every > 0 {
+		f, k := s.period(t)
+		for !f.IsZero() && !f.After(t) {
+			k++
+			f = s.firing(k)
+		}
+		return f
+	}
+	// Walk wall-clock minutes, held in UTC where each occurs exactly once,
+	// and place a match in the zone.
+	w := wall(t).Truncate(time.Minute).Add(time.Minute)
+	limit := w.AddDate(5, 0, 0)
+	for w.Before(limit) {
+		switch {
+		case s.month&(1<<int(w.Month())) == 0:
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(w, withWeeks):
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<w.Hour()) == 0:
+			w = w.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<w.Minute()) == 0:
+			w = w.Add(time.Minute)
+		default:
+			// t itself may be the second pass of a repeated hour.
+			if f := s.at(w); f.After(t) {
+				return f
+			}
+			w = w.Add(time.Minute)
+		}
+	}
+	return time.Time{}
+}
+
+// wall is t's wall-clock time in its zone, as a UTC time.
+func wall(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// at places wall-clock time w in the zone: at its first occurrence when the
+// clocks repeat it, or, when they skip it, where it would be had they not
+// moved yet. time.Date leaves both choices open.
+func (s *Schedule) at(w time.Time) time.Time {
+	_, before := w.Add(-24 * time.Hour).In(s.loc).Zone()
+	_, after := w.Add(24 * time.Hour).In(s.loc).Zone()
+	for _, off := range []int{max(before, after), min(before, after)} {
+		if t := w.Add(-time.Duration(off) * time.Second).In(s.loc); wall(t).Equal(w) {
+			return t
+		}
+	}
+	return w.Add(-time.Duration(before) * time.Second).In(s.loc)
+}
+
+// Prev returns the last firing time at or before t.
+func (s *Schedule) Prev(t time.Time) time.Time {
+	if s.every > 0 {
+		f, k := s.period(t)
+		if f.After(t) {
+			f = s.firing(k - 1)
+		}
+		return f
+	}
+	// Step back a week at a time (more with a week step) and scan forward.
+	span := 7 * 24 * time.Hour * time.Duration(s.weeks)
+	for from := t.Add(-span); from.After(t.AddDate(-5, 0, 0)); from = from.Add(-span) {
+		var last time.Time
+		for f := s.Next(from); !f.IsZero() && !f.After(t); f = s.Next(f) {
+			last = f
+		}
+		if !last.IsZero() {
+			return last
+		}
+	}
+	return time.Time{}
+}
+
+// period returns the @every firing on or before t's day, and its number
+// counted from the anchor; zero without an anchor.
+func (s *Schedule) period(t time.Time) (time.Time, int) {
+	if s.anchor.IsZero() {
+		return time.Time{}, 0
+	}
+	d := int(dayNumber(t.In(s.loc)) - dayNumber(s.anchor))
+	k := d / s.every
+	if d < 0 && d%s.every != 0 {
+		k--
+	}
+	return s.firing(k), k
+}
+
+// firing is the k-th @every release after the anchor, at the anchor's
+// wall-clock time across DST changes.
+func (s *Schedule) firing(k int) time.Time {
+	return s.at(wall(s.anchor).AddDate(0, 0, k*s.every))
+}
+
+var ErrNoAnchor = errors.New("schedule has no anchor cycle")
+
+// Expected returns the number of the latest cycle due at or before t: the
+// anchor cycle plus one per firing since.
+func (s *Schedule) Expected(t time.Time) (int, error) {
+	if s.anchor.IsZero() {
+		return 0, ErrNoAnchor
+	}
+	n := s.anchorCycle
+	if t.Before(s.anchor) {
+		for f := s.Prev(t); !f.IsZero() && f.Before(s.anchor) && n > 0; f = s.Next(f) {
+			n--
+		}
+		return n, nil
+	}
+	for f := s.Next(s.anchor); !f.IsZero() && !f.After(t); f = s.Next(f) {
+		n++
+	}
+	return n, nil
+}
+
+// Release returns when cycle n is due: the anchor's firing time stepped
+// forward or back one firing per cycle.
+func (s *Schedule) Release(n int) (time.Time, error) {
+	if s.anchor.IsZero() {
+		return time.Time{}, ErrNoAnchor
+	}
+	t := s.anchor
+	for c := s.anchorCycle; c < n && !t.IsZero(); c++ {
+		t = s.Next(t)
+	}
+	for c := s.anchorCycle; c > n && !t.IsZero(); c-- {
+		t = s.Prev(t.Add(-time.Minute))
+	}
+	if t.IsZero() {
+		return time.Time{}, fmt.Errorf("cycle %d is out of the schedule's range", n)
+	}
+	return t, nil
+}
+
+// Validate reports a schedule that cannot be evaluated: a week step or
+// @every without an anchor.
+func (s *Schedule) Validate() error {
+	if s.every > 0 && s.anchor.IsZero() {
+		return fmt.Errorf("@every needs an anchor cycle to start from: %w", ErrNoAnchor)
+	}
+	if s.weeks > 1 && s.anchor.IsZero() {
+		return fmt.Errorf("a week step needs an anchor cycle: %w", ErrNoAnchor)
+	}
+	return nil
+}
+
+func (s *Schedule) Location() *time.Location { return s.loc }
+
+// FromSpec builds a schedule from the --schedule, --schedule-tz and
+// --schedule-anchor flags the commands share; an empty expr means no
+// schedule and a nil one.
+func FromSpec(expr, tz, anchor string) (*Schedule, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = ParseLocation(tz); err != nil {
+			return nil, err
+		}
+	}
+	s, err := Parse(expr, loc)
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		if err := s.ParseAnchor(anchor); err != nil {
+			return nil, err
+		}
+	}
+	return s, s.Validate()
+}
+
+// ParseLocation accepts an IANA zone ("Asia/Ho_Chi_Minh") or a fixed offset
+// ("UTC+7", "UTC-03:30", "+07:00").
+func ParseLocation(v string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(v); err == nil {
+		return loc, nil
+	}
+	off := strings.TrimPrefix(strings.TrimPrefix(v, "UTC"), "GMT")
+	if off == "" || (off[0] != '+' && off[0] != '-') {
+		return nil, fmt.Errorf("unknown time zone %q", v)
+	}
+	h, m, _ := strings.Cut(off[1:], ":")
+	hours, err := strconv.Atoi(h)
+	if err != nil || hours > 14 {
+		return nil, fmt.Errorf("unknown time zone %q", v)
+	}
+	mins := 0
+	if m != "" {
+		if mins, err = strconv.Atoi(m); err != nil || mins > 59 {
+			return nil, fmt.Errorf("unknown time zone %q", v)
+		}
+	}
+	secs := hours*3600 + mins*60
+	if off[0] == '-' {
+		secs = -secs
+	}
+	return time.FixedZone(v, secs), nil
+}
+
+func dayNumber(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
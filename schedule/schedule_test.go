@@ -0,0 +1,241 @@
+package schedule_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+)
+
+func ts(t *testing.T, v string) time.Time {
+	t.Helper()
+	tm, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func spec(t *testing.T, expr, tz, anchor string) *schedule.Schedule {
+	t.Helper()
+	s, err := schedule.FromSpec(expr, tz, anchor)
+	if err != nil {
+		t.Fatalf("%s in %s at %s: %v", expr, tz, anchor, err)
+	}
+	return s
+}
+
+// The release cadence: every other Thursday at 14:00 UTC+7, 07:00 UTC.
+const (
+	biweekly = "0 14 * * 4/2"
+	bangkok  = "UTC+7"
+	cycle21  = "21@2026-10-08"
+)
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct{ expr, want string }{
+		{"0 14 * * 1-5/2", "ambiguous"},
+		{"0 14 * * 1,4/2", "ambiguous"},
+		{"0 14 * * 4/0", "invalid week step"},
+		{"0 14 * * 4/x", "invalid week step"},
+		{"0 14 * *", "want 5 fields"},
+		{"60 14 * * *", "out of range 0-59"},
+		{"0 14 0 * *", "out of range 1-31"},
+		{"0 14 * * 8", "out of range 0-7"},
+		{"0 14 5-1 * *", "out of range"},
+		{"0 */0 * * *", "invalid step"},
+		{"0 a * * *", "invalid value"},
+		{"0 1-b * * *", "invalid range"},
+		{"@every 0d", "invalid period"},
+		{"@every 2x", "invalid period"},
+		{"@every d", "invalid period"},
+	}
+	for _, c := range cases {
+		if _, err := schedule.Parse(c.expr, time.UTC); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want %q", c.expr, err, c.want)
+		}
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	cases := []struct{ expr, tz, anchor, want string }{
+		{biweekly, bangkok, "", "needs an anchor"},
+		{"@every 10d", "", "", "needs an anchor"},
+		{biweekly, "Mars/Olympus", cycle21, "unknown time zone"},
+		{biweekly, "UTC+15", cycle21, "unknown time zone"},
+		{biweekly, bangkok, "21@2026-10-09", "does not fire that day"},
+		{biweekly, bangkok, "21@2026-10-08T15:00", "not a time the schedule fires"},
+		{biweekly, bangkok, "21", "want <cycle>@<date>"},
+		{biweekly, bangkok, "x@2026-10-08", "invalid cycle"},
+		{biweekly, bangkok, "21@08/10/2026", "invalid date"},
+	}
+	for _, c := range cases {
+		if _, err := schedule.FromSpec(c.expr, c.tz, c.anchor); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s in %q at %q: got %v, want %q", c.expr, c.tz, c.anchor, err, c.want)
+		}
+	}
+	if _, err := schedule.FromSpec(biweekly, bangkok, ""); !errors.Is(err, schedule.ErrNoAnchor) {
+		t.Errorf("got %v, want ErrNoAnchor", err)
+	}
+	if s, err := schedule.FromSpec("", "", ""); s != nil || err != nil {
+		t.Errorf("empty spec: got %v, %v", s, err)
+	}
+}
+
+func TestNextPrev(t *testing.T) {
+	cases := []struct {
+		name, expr, tz, anchor string
+		at, next, prev         string
+	}{
+		{"week step at a release", biweekly, bangkok, cycle21,
+			"2026-10-08T07:00:00Z", "2026-10-22T07:00:00Z", "2026-10-08T07:00:00Z"},
+		{"week step skips the off week", biweekly, bangkok, cycle21,
+			"2026-10-15T06:59:00Z", "2026-10-22T07:00:00Z", "2026-10-08T07:00:00Z"},
+		{"week step before the anchor", biweekly, bangkok, cycle21,
+			"2026-09-20T00:00:00Z", "2026-09-24T07:00:00Z", "2026-09-10T07:00:00Z"},
+		{"week step in the zone's day", biweekly, bangkok, cycle21,
+			"2026-10-21T23:30:00Z", "2026-10-22T07:00:00Z", "2026-10-08T07:00:00Z"},
+		{"seconds round up", "0 9 * * *", "", "",
+			"2026-10-15T09:00:30Z", "2026-10-16T09:00:00Z", "2026-10-15T09:00:00Z"},
+		{"day-of-week step is cron's", "0 9 * * */2", "", "",
+			"2026-10-15T10:00:00Z", "2026-10-17T09:00:00Z", "2026-10-15T09:00:00Z"},
+		{"Sunday as 7", "0 9 * * 7", "", "",
+			"2026-10-15T10:00:00Z", "2026-10-18T09:00:00Z", "2026-10-11T09:00:00Z"},
+		{"days of month", "30 9 1,15 * *", "", "",
+			"2026-10-15T09:30:00Z", "2026-11-01T09:30:00Z", "2026-10-15T09:30:00Z"},
+		{"either day field", "0 0 13 * 5", "", "",
+			"2026-10-01T00:00:00Z", "2026-10-02T00:00:00Z", "2026-09-25T00:00:00Z"},
+		{"months", "0 0 1 1,7 *", "", "",
+			"2026-07-01T00:00:00Z", "2027-01-01T00:00:00Z", "2026-07-01T00:00:00Z"},
+		{"spring forward fires after the gap", "30 2 * * *", "America/New_York", "",
+			"2026-03-07T07:30:00Z", "2026-03-08T07:30:00Z", "2026-03-07T07:30:00Z"},
+		{"the day after spring forward", "30 2 * * *", "America/New_York", "",
+			"2026-03-08T07:30:00Z", "2026-03-09T06:30:00Z", "2026-03-08T07:30:00Z"},
+		{"fall back fires the first pass", "30 1 * * *", "America/New_York", "",
+			"2026-10-31T05:30:00Z", "2026-11-01T05:30:00Z", "2026-10-31T05:30:00Z"},
+		{"fall back skips the second pass", "30 1 * * *", "America/New_York", "",
+			"2026-11-01T06:00:00Z", "2026-11-02T06:30:00Z", "2026-11-01T05:30:00Z"},
+		{"fall back in Europe", "30 2 * * *", "Europe/Berlin", "",
+			"2026-10-25T00:00:00Z", "2026-10-25T00:30:00Z", "2026-10-24T00:30:00Z"},
+		{"@every", "@every 10d", "", "1@2026-01-05T09:00",
+			"2026-01-05T09:00:00Z", "2026-01-15T09:00:00Z", "2026-01-05T09:00:00Z"},
+		{"@every before the anchor", "@every 2w", "", "1@2026-01-05T09:00",
+			"2026-01-01T00:00:00Z", "2026-01-05T09:00:00Z", "2025-12-22T09:00:00Z"},
+		{"@every keeps the wall clock over DST", "@every 10d", "America/New_York", "1@2026-03-01T09:00",
+			"2026-03-01T14:00:00Z", "2026-03-11T13:00:00Z", "2026-03-01T14:00:00Z"},
+		{"@every in a spring-forward gap", "@every 7d", "America/New_York", "1@2026-03-01T02:30",
+			"2026-03-01T07:30:00Z", "2026-03-08T07:30:00Z", "2026-03-01T07:30:00Z"},
+	}
+	for _, c := range cases {
+		s := spec(t, c.expr, c.tz, c.anchor)
+		at := ts(t, c.at)
+		if got := s.Next(at); !got.Equal(ts(t, c.next)) {
+			t.Errorf("%s: Next(%s) = %s, want %s", c.name, c.at, got.UTC().Format(time.RFC3339), c.next)
+		}
+		if got := s.Prev(at); !got.Equal(ts(t, c.prev)) {
+			t.Errorf("%s: Prev(%s) = %s, want %s", c.name, c.at, got.UTC().Format(time.RFC3339), c.prev)
+		}
+	}
+}
+
+func TestExpected(t *testing.T) {
+	cases := []struct {
+		name, expr, tz, anchor string
+		at                     string
+		want                   int
+	}{
+		{"at the anchor", biweekly, bangkok, cycle21, "2026-10-08T07:00:00Z", 21},
+		{"just before the anchor", biweekly, bangkok, cycle21, "2026-10-08T06:59:00Z", 20},
+		{"off week", biweekly, bangkok, cycle21, "2026-10-15T07:00:00Z", 21},
+		{"next release", biweekly, bangkok, cycle21, "2026-10-22T07:00:00Z", 22},
+		{"months later", biweekly, bangkok, cycle21, "2027-01-01T00:00:00Z", 27},
+		{"before the anchor", biweekly, bangkok, cycle21, "2026-09-24T07:00:00Z", 20},
+		{"weeks before the anchor", biweekly, bangkok, cycle21, "2026-09-23T00:00:00Z", 19},
+		{"anchor is the first cycle", biweekly, bangkok, "1@2026-10-08", "2026-10-08T07:00:00Z", 1},
+		{"before the first cycle", biweekly, bangkok, "1@2026-10-08", "2026-10-01T00:00:00Z", 0},
+		{"long before the first cycle", biweekly, bangkok, "1@2026-10-08", "2025-01-01T00:00:00Z", 0},
+		{"@every across DST", "@every 10d", "America/New_York", "1@2026-03-01T09:00", "2026-03-11T12:59:00Z", 1},
+		{"@every after DST", "@every 10d", "America/New_York", "1@2026-03-01T09:00", "2026-03-11T13:00:00Z", 2},
+		{"daily over fall back", "30 1 * * *", "America/New_York", "1@2026-10-31", "2026-11-02T06:30:00Z", 3},
+		{"daily over spring forward", "30 2 * * *", "America/New_York", "1@2026-03-07", "2026-03-09T06:30:00Z", 3},
+	}
+	for _, c := range cases {
+		s := spec(t, c.expr, c.tz, c.anchor)
+		got, err := s.Expected(ts(t, c.at))
+		if err != nil || got != c.want {
+			t.Errorf("%s: Expected(%s) = %d, %v, want %d", c.name, c.at, got, err, c.want)
+		}
+	}
+	s := spec(t, "0 9 * * *", "", "")
+	if _, err := s.Expected(time.Now()); !errors.Is(err, schedule.ErrNoAnchor) {
+		t.Errorf("without an anchor: got %v", err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	cases := []struct {
+		name, expr, tz, anchor string
+		cycle                  int
+		want                   string
+	}{
+		{"anchor", biweekly, bangkok, cycle21, 21, "2026-10-08T07:00:00Z"},
+		{"after", biweekly, bangkok, cycle21, 23, "2026-11-05T07:00:00Z"},
+		{"before", biweekly, bangkok, cycle21, 19, "2026-09-10T07:00:00Z"},
+		{"@every after DST", "@every 10d", "America/New_York", "1@2026-03-01T09:00", 2, "2026-03-11T13:00:00Z"},
+		{"@every before the anchor", "@every 2w", "", "3@2026-01-05T09:00", 1, "2025-12-08T09:00:00Z"},
+		{"daily over fall back", "30 1 * * *", "America/New_York", "1@2026-10-31", 3, "2026-11-02T06:30:00Z"},
+	}
+	for _, c := range cases {
+		s := spec(t, c.expr, c.tz, c.anchor)
+		got, err := s.Release(c.cycle)
+		if err != nil || !got.Equal(ts(t, c.want)) {
+			t.Errorf("%s: Release(%d) = %s, %v, want %s", c.name, c.cycle, got.UTC().Format(time.RFC3339), err, c.want)
+		}
+	}
+	// Release and Expected agree both ways.
+	s := spec(t, biweekly, bangkok, cycle21)
+	for n := 15; n <= 30; n++ {
+		at, err := s.Release(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := s.Expected(at); got != n {
+			t.Errorf("Expected(Release(%d)) = %d", n, got)
+		}
+		if got, _ := s.Expected(at.Add(-time.Minute)); got != n-1 {
+			t.Errorf("Expected(Release(%d) - 1m) = %d", n, got)
+		}
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	cases := []struct {
+		in   string
+		off  int
+		fail bool
+	}{
+		{"UTC+7", 7 * 3600, false},
+		{"UTC-03:30", -(3*3600 + 30*60), false},
+		{"+07:00", 7 * 3600, false},
+		{"GMT+5", 5 * 3600, false},
+		{"UTC", 0, false},
+		{"UTC+7:60", 0, true},
+		{"UTC+15", 0, true},
+		{"7", 0, true},
+	}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range cases {
+		loc, err := schedule.ParseLocation(c.in)
+		if (err != nil) != c.fail {
+			t.Errorf("%s: got %v", c.in, err)
+			continue
+		}
+		if err == nil {
+			if _, off := at.In(loc).Zone(); off != c.off {
+				t.Errorf("%s: offset %d, want %d", c.in, off, c.off)
+			}
+		}
+	}
+}
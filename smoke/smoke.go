@@ -0,0 +1,168 @@
+// Package smoke checks a deployed reward-service against the local cycle
+// files after a release: for a sample of each file's positions it asks the
+// service for the position's reward and compares the root, amounts and
+// proof with what the file says.
+package smoke
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/sample"
+)
+
+// Client asks reward-service for positions' rewards.
+type Client struct {
+	// Endpoint is a URL template; {chain}, {type}, {cycle}, {file},
+	// {position} (addr:id), {addr} and {id} are filled in, query-escaped.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// Answer is a position's reward as the service returns it, in the shape of
+// serve's /proof.
+type Answer struct {
+	Root  string     `json:"root"`
+	Leaf  cycle.Leaf `json:"leaf"`
+	Proof []string   `json:"proof"`
+}
+
+func (c *Client) url(n cycle.Name, key string) string {
+	addr, id, _ := strings.Cut(key, ":")
+	return strings.NewReplacer(
+		"{chain}", url.QueryEscape(n.ChainID),
+		"{type}", url.QueryEscape(n.RewardType),
+		"{cycle}", strconv.Itoa(n.Cycle),
+		"{file}", url.QueryEscape(n.String()),
+		"{position}", url.QueryEscape(key),
+		"{addr}", url.QueryEscape(addr),
+		"{id}", url.QueryEscape(id),
+	).Replace(c.Endpoint)
+}
+
+// Fetch asks for the reward of the position key in n.
+func (c *Client) Fetch(ctx context.Context, n cycle.Name, key string) (*Answer, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url(n, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	var a Answer
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("decode answer: %w", err)
+	}
+	return &a, nil
+}
+
+type Finding struct {
+	File     string `json:"file"`
+	Position string `json:"position"`
+	Problem  string `json:"problem"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.File, f.Position, f.Problem)
+}
+
+// Sample picks up to k positions of f, the same ones for the same seed;
+// see internal/sample for how.
+func Sample(f *cycle.File, k int, seed uint64) []string {
+	keys := make([]string, len(f.UserDatas))
+	for i, ud := range f.UserDatas {
+		keys[i] = ud.Leaf.Key()
+	}
+	return sample.Keys(keys, k, seed)
+}
+
+// Check compares the service's answers for keys, positions of the file n
+// in dir, with the file's.
+func Check(ctx context.Context, c *Client, dir string, n cycle.Name, keys []string) ([]Finding, error) {
+	out := make([]Finding, 0)
+	for _, key := range keys {
+		want, root, err := cycle.FindProof(dir, n, key)
+		if err != nil {
+			return nil, err
+		}
+		got, err := c.Fetch(ctx, n, key)
+		if err != nil {
+			out = append(out, Finding{File: n.String(), Position: key, Problem: err.Error()})
+			continue
+		}
+		for _, p := range compare(got, want, root) {
+			out = append(out, Finding{File: n.String(), Position: key, Problem: p})
+		}
+	}
+	return out, nil
+}
+
+func compare(got *Answer, want cycle.UserData, root string) []string {
+	out := make([]string, 0)
+	if !strings.EqualFold(got.Root, root) {
+		out = append(out, fmt.Sprintf("root %s, want %s (is the service still on an older cycle?)", got.Root, root))
+	}
+	ga, gerr := got.Leaf.AmountsByToken()
+	wa, werr := want.Leaf.AmountsByToken()
+	if gerr != nil || werr != nil || !sameAmounts(ga, wa) {
+		out = append(out, fmt.Sprintf("amounts %s, want %s", amounts(got.Leaf), amounts(want.Leaf)))
+	}
+	if !sameProof(got.Proof, want.Proof) {
+		out = append(out, fmt.Sprintf("proof of %d nodes differs from the file's %d", len(got.Proof), len(want.Proof)))
+	}
+	return out
+}
+
+func sameAmounts(a, b map[string]*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for t, x := range a {
+		if y, ok := b[t]; !ok || x.Cmp(y) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sameProof(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func amounts(l cycle.Leaf) string {
+	parts := make([]string, len(l.Tokens))
+	for i, t := range l.Tokens {
+		a := "?"
+		if i < len(l.Amounts) {
+			a = l.Amounts[i]
+		}
+		parts[i] = t + "=" + a
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
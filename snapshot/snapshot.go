@@ -0,0 +1,206 @@
+// Package snapshot reads eligibility/points data either from the snapshot
+// service (JSON) or from a CSV export, local or over HTTP.
+package snapshot
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Entry is one eligible position and its points.
+type Entry struct {
+	ERC721Addr string
+	ERC721ID   string
+	Points     *big.Rat
+}
+
+func (e Entry) Key() string {
+	return strings.ToLower(e.ERC721Addr) + ":" + e.ERC721ID
+}
+
+type Format string
+
+const (
+	FormatAuto Format = "auto"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+type Options struct {
+	Format Format
+	// Token is sent as a bearer token when fetching over HTTP.
+	Token   string
+	Timeout time.Duration
+}
+
+// Load reads entries from an http(s) URL or a local path.
+func Load(ctx context.Context, src string, opt Options) ([]Entry, error) {
+	var (
+		r           io.ReadCloser
+		contentType string
+	)
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		timeout := opt.Timeout
+		if timeout == 0 {
+			timeout = 60 * time.Second
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", src, nil)
+		if err != nil {
+			return nil, err
+		}
+		if opt.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+opt.Token)
+		}
+		resp, err := (&http.Client{Timeout: timeout}).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode/100 != 2 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("snapshot fetch failed: %s: %s", resp.Status, string(b))
+		}
+		r, contentType = resp.Body, resp.Header.Get("Content-Type")
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	format := opt.Format
+	if format == "" || format == FormatAuto {
+		format = detect(src, contentType)
+	}
+	switch format {
+	case FormatJSON:
+		return ParseJSON(r)
+	case FormatCSV:
+		return ParseCSV(r)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot format %q", format)
+	}
+}
+
+func detect(src, contentType string) Format {
+	if strings.Contains(contentType, "csv") || strings.HasSuffix(strings.ToLower(strings.SplitN(src, "?", 2)[0]), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSON
+}
+
+type jsonEntry struct {
+	ERC721Addr string          `json:"erc721Addr"`
+	ERC721ID   json.RawMessage `json:"erc721Id"`
+	Points     json.RawMessage `json:"points"`
+}
+
+// ParseJSON accepts either a bare array of entries or {"data": [...]}.
+func ParseJSON(r io.Reader) ([]Entry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var raw []jsonEntry
+	if err := json.Unmarshal(b, &raw); err != nil {
+		var wrapped struct {
+			Data []jsonEntry `json:"data"`
+		}
+		if err2 := json.Unmarshal(b, &wrapped); err2 != nil {
+			return nil, fmt.Errorf("parse snapshot json: %w", err)
+		}
+		raw = wrapped.Data
+	}
+	out := make([]Entry, 0, len(raw))
+	for i, je := range raw {
+		e, err := newEntry(je.ERC721Addr, unquote(je.ERC721ID), unquote(je.Points))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot entry %d: %w", i, err)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// ParseCSV expects a header with erc721Addr, erc721Id and points columns.
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot csv header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	ai, ok1 := col["erc721addr"]
+	ii, ok2 := col["erc721id"]
+	pi, ok3 := col["points"]
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("snapshot csv must have erc721Addr, erc721Id and points columns")
+	}
+	out := make([]Entry, 0)
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot csv line %d: %w", line, err)
+		}
+		e, err := newEntry(rec[ai], rec[ii], rec[pi])
+		if err != nil {
+			return nil, fmt.Errorf("snapshot csv line %d: %w", line, err)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func newEntry(addr, id, points string) (Entry, error) {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	id = strings.TrimSpace(id)
+	if addr == "" || id == "" {
+		return Entry{}, errors.New("missing erc721Addr or erc721Id")
+	}
+	if _, ok := new(big.Int).SetString(id, 10); !ok {
+		return Entry{}, fmt.Errorf("invalid erc721Id %q", id)
+	}
+	p, ok := new(big.Rat).SetString(strings.TrimSpace(points))
+	if !ok || p.Sign() < 0 {
+		return Entry{}, fmt.Errorf("invalid points %q", points)
+	}
+	return Entry{ERC721Addr: addr, ERC721ID: id, Points: p}, nil
+}
+
+func unquote(m json.RawMessage) string {
+	s := strings.TrimSpace(string(m))
+	return strings.Trim(s, `"`)
+}
+
+// Merge sums points of duplicate positions, keeping first-seen order.
+func Merge(entries []Entry) []Entry {
+	idx := make(map[string]int, len(entries))
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if i, ok := idx[e.Key()]; ok {
+			out[i].Points = new(big.Rat).Add(out[i].Points, e.Points)
+			continue
+		}
+		idx[e.Key()] = len(out)
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+//go:build !unix
+
+package state
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLock is how old a lock file must be before it is taken to be left
+// by a run that died holding it. Saves hold it for milliseconds.
+const staleLock = time.Minute
+
+// lock creates path exclusively, waiting while another run holds it, where
+// flock is unavailable.
+func lock(path string) (unlock func(), err error) {
+	deadline := time.Now().Add(2 * staleLock)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New(path + " is held by another run")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,166 @@
+// Package state is the small persistent key/value store shared by commands
+// that need to remember things between runs (verification results, queues,
+// registrations). It is a single JSON document grouped into buckets, written
+// atomically. Several commands write it, serve's loop included, so Save
+// re-reads the file under a lock and applies only this DB's own changes.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+	"github.com/KyberNetwork/fairflow-reward/tenant"
+)
+
+const DefaultPath = ".fairflow/state.json"
+
+type DB struct {
+	path string
+	// ns prefixes every bucket of a --program other than the default, so
+	// programs sharing a DB never see each other's data.
+	ns string
+
+	mu      sync.Mutex
+	buckets map[string]map[string]json.RawMessage
+	// pending are the Puts (a value) and Deletes (nil) since the last
+	// Save, which it replays onto the file as it is then.
+	pending map[string]map[string]json.RawMessage
+}
+
+// Open loads the DB at path; a missing file is an empty DB. Buckets are
+// those of the --program selected (see tenant).
+func Open(path string) (*DB, error) {
+	db := &DB{path: path, pending: make(map[string]map[string]json.RawMessage)}
+	if p := tenant.Current(); p != "" {
+		db.ns = p + "/"
+	}
+	var err error
+	if db.buckets, err = read(path); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// read loads the buckets at path; a missing file has none.
+func read(path string) (map[string]map[string]json.RawMessage, error) {
+	buckets := make(map[string]map[string]json.RawMessage)
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return buckets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &buckets); err != nil {
+		return nil, fmt.Errorf("state %s: %w", path, err)
+	}
+	return buckets, nil
+}
+
+// set puts raw, or deletes the key if raw is nil.
+func set(buckets map[string]map[string]json.RawMessage, bucket, key string, raw json.RawMessage) {
+	if raw == nil {
+		delete(buckets[bucket], key)
+		return
+	}
+	if buckets[bucket] == nil {
+		buckets[bucket] = make(map[string]json.RawMessage)
+	}
+	buckets[bucket][key] = raw
+}
+
+func (db *DB) Path() string { return db.path }
+
+// Get decodes bucket/key into v and reports whether it was present.
+func (db *DB) Get(bucket, key string, v any) (bool, error) {
+	db.mu.Lock()
+	raw, ok := db.buckets[db.ns+bucket][key]
+	db.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("state %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+func (db *DB) Put(bucket, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("state %s/%s: %w", bucket, key, err)
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	bucket = db.ns + bucket
+	set(db.buckets, bucket, key, raw)
+	set(db.pending, bucket, key, raw)
+	return nil
+}
+
+func (db *DB) Delete(bucket, key string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.buckets[db.ns+bucket][key]; ok {
+		delete(db.buckets[db.ns+bucket], key)
+		if db.pending[db.ns+bucket] == nil {
+			db.pending[db.ns+bucket] = make(map[string]json.RawMessage)
+		}
+		db.pending[db.ns+bucket][key] = nil
+	}
+}
+
+// Keys returns the keys in bucket in sorted order.
+func (db *DB) Keys(bucket string) []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	out := make([]string, 0, len(db.buckets[db.ns+bucket]))
+	for k := range db.buckets[db.ns+bucket] {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Save replays the changes made since the DB was opened or last saved onto
+// the file as it is now, under a lock, so what other commands wrote in the
+// meantime survives, and writes it if there were any. Either way the DB
+// then reflects the file, so a long-running caller sees the others' writes.
+func (db *DB) Save() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(db.path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lock(db.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("state %s: lock: %w", db.path, err)
+	}
+	defer unlock()
+	buckets, err := read(db.path)
+	if err != nil {
+		return err
+	}
+	if len(db.pending) > 0 {
+		for bucket, keys := range db.pending {
+			for key, raw := range keys {
+				set(buckets, bucket, key, raw)
+			}
+		}
+		b, err := json.MarshalIndent(buckets, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := tmpdir.WriteFile(db.path, append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+		db.pending = make(map[string]map[string]json.RawMessage)
+	}
+	db.buckets = buckets
+	return nil
+}
@@ -0,0 +1,58 @@
+package state_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+// Two commands with the DB open at once must not drop each other's writes,
+// as serve's loop did to flush-queue's and emergency-pause's.
+func TestSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	long, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := long.Put("seen", "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := long.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Put("queue", "job", "x"); err != nil {
+		t.Fatal(err)
+	}
+	other.Delete("seen", "a")
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := long.Put("seen", "b", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := long.Save(); err != nil {
+		t.Fatal(err)
+	}
+	var job string
+	if ok, err := long.Get("queue", "job", &job); !ok || err != nil || job != "x" {
+		t.Errorf("queue/job after save = %q, %v, %v; want the other writer's x", job, ok, err)
+	}
+
+	got, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := got.Keys("seen"); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("seen = %v, want [b]: a deleted by the other writer, b added", keys)
+	}
+	if keys := got.Keys("queue"); len(keys) != 1 {
+		t.Errorf("queue = %v, want the other writer's job", keys)
+	}
+}
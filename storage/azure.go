@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+// Azure Blob Storage.
+//
+//	azblob://<account>/<container>/<prefix>
+//
+// With AZURE_STORAGE_CONNECTION_STRING set, its AccountKey (Shared Key) or
+// SharedAccessSignature is used and its endpoint wins. Otherwise requests use
+// a managed identity token from the instance metadata service; set
+// ?clientId=<id> (or AZURE_CLIENT_ID) for a user-assigned identity.
+func init() {
+	Register("azblob", openAzure)
+}
+
+const azureVersion = "2021-08-06"
+
+type azureBackend struct {
+	account   string
+	container string
+	prefix    string
+	endpoint  string // https://<account>.blob.core.windows.net
+	key       []byte
+	sas       url.Values
+	token     *imdsToken
+	http      *http.Client
+}
+
+func openAzure(u *url.URL) (Backend, error) {
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Host == "" || container == "" {
+		return nil, fmt.Errorf("storage: azblob URL must be azblob://<account>/<container>[/prefix]")
+	}
+	b := &azureBackend{
+		account:   u.Host,
+		container: container,
+		prefix:    prefix,
+		endpoint:  "https://" + u.Host + ".blob.core.windows.net",
+		http:      &http.Client{Timeout: 10 * time.Minute, Transport: tracing.Transport(nil)},
+	}
+	if cs := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); cs != "" {
+		if err := b.useConnectionString(cs); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	b.token = &imdsToken{clientID: firstNonEmpty(u.Query().Get("clientId"), os.Getenv("AZURE_CLIENT_ID")), http: b.http}
+	return b, nil
+}
+
+func (b *azureBackend) useConnectionString(cs string) error {
+	kv := make(map[string]string)
+	for _, part := range strings.Split(cs, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if ok {
+			kv[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if name := kv["AccountName"]; name != "" && name != b.account {
+		return fmt.Errorf("storage: connection string is for account %s, URL names %s", name, b.account)
+	}
+	switch {
+	case kv["BlobEndpoint"] != "":
+		b.endpoint = strings.TrimRight(kv["BlobEndpoint"], "/")
+	case kv["EndpointSuffix"] != "":
+		proto := firstNonEmpty(kv["DefaultEndpointsProtocol"], "https")
+		b.endpoint = proto + "://" + b.account + ".blob." + kv["EndpointSuffix"]
+	}
+	switch {
+	case kv["AccountKey"] != "":
+		key, err := base64.StdEncoding.DecodeString(kv["AccountKey"])
+		if err != nil {
+			return fmt.Errorf("storage: invalid AccountKey: %w", err)
+		}
+		b.key = key
+	case kv["SharedAccessSignature"] != "":
+		sas, err := url.ParseQuery(strings.TrimPrefix(kv["SharedAccessSignature"], "?"))
+		if err != nil {
+			return fmt.Errorf("storage: invalid SharedAccessSignature: %w", err)
+		}
+		b.sas = sas
+	default:
+		return fmt.Errorf("storage: connection string has neither AccountKey nor SharedAccessSignature")
+	}
+	return nil
+}
+
+func (b *azureBackend) blobURL(key string) *url.URL {
+	u, _ := url.Parse(b.endpoint)
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + b.container + "/" + join(b.prefix, key)
+	if b.sas != nil {
+		u.RawQuery = b.sas.Encode()
+	}
+	return u
+}
+
+func (b *azureBackend) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	switch {
+	case b.key != nil:
+		b.signSharedKey(req)
+	case b.token != nil:
+		tok, err := b.token.get(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, b, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+// signSharedKey implements the Blob service Shared Key scheme.
+func (b *azureBackend) signSharedKey(req *http.Request) {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	h := req.Header
+	ms := make([]string, 0)
+	for k := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			ms = append(ms, lk)
+		}
+	}
+	sort.Strings(ms)
+	var canon strings.Builder
+	for _, k := range ms {
+		canon.WriteString(k + ":" + strings.TrimSpace(h.Get(k)) + "\n")
+	}
+	resource := "/" + b.account + req.URL.EscapedPath()
+	q := req.URL.Query()
+	qk := make([]string, 0, len(q))
+	for k := range q {
+		qk = append(qk, k)
+	}
+	sort.Strings(qk)
+	for _, k := range qk {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(vs, ",")
+	}
+	toSign := strings.Join([]string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date: x-ms-date is used instead
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+		canon.String() + resource,
+	}, "\n")
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(toSign))
+	req.Header.Set("Authorization", "SharedKey "+b.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (b *azureBackend) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size < 0 {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(buf), int64(len(buf))
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", b.blobURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	resp, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *azureBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.blobURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *azureBackend) Close() error { return nil }
+
+func (b *azureBackend) String() string {
+	return "azblob://" + b.account + "/" + join(b.container, b.prefix)
+}
+
+// imdsToken caches a managed identity access token for Azure Storage.
+type imdsToken struct {
+	clientID string
+	http     *http.Client
+
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+const imdsURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+func (t *imdsToken) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Until(t.expires) > 5*time.Minute {
+		return t.value, nil
+	}
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {"https://storage.azure.com/"}}
+	if t.clientID != "" {
+		q.Set("client_id", t.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", imdsURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("azure managed identity: %w", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("azure managed identity: %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return "", fmt.Errorf("azure managed identity: %w", err)
+	}
+	secs, _ := strconv.ParseInt(out.ExpiresOn, 10, 64)
+	t.value, t.expires = out.AccessToken, time.Unix(secs, 0)
+	return t.value, nil
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The signatures were computed independently from the string-to-sign
+// documented for Shared Key ("Authorize with Shared Key"), with the storage
+// emulator's well-known account key.
+func TestSignSharedKey(t *testing.T) {
+	key, _ := base64.StdEncoding.DecodeString("Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==")
+	b := &azureBackend{account: "devstoreaccount1", key: key}
+	const date = "Fri, 26 Jun 2015 23:39:12 GMT"
+
+	put, _ := http.NewRequest(http.MethodPut, "https://devstoreaccount1.blob.core.windows.net/rewards/cycles/a%20b.json", strings.NewReader(`{"ok":true}`))
+	put.Header.Set("Content-Type", "application/json")
+	put.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	get, _ := http.NewRequest(http.MethodGet, "https://devstoreaccount1.blob.core.windows.net/rewards/x.json?timeout=20&comp=metadata", nil)
+	get.Header.Set("Range", "bytes=0-99")
+
+	for _, c := range []struct {
+		req *http.Request
+		sig string
+	}{
+		{put, "MnZULzqqP8ixrq6yJ7MhLpnOQ/bCtlYLltmtuDzQE9c="},
+		{get, "s2L19nRJWKloxhOgloVX3RCGynzH0ttfIpzqPgre0Dw="},
+	} {
+		c.req.Header.Set("x-ms-version", azureVersion)
+		c.req.Header.Set("x-ms-date", date)
+		b.signSharedKey(c.req)
+		if got, want := c.req.Header.Get("Authorization"), "SharedKey devstoreaccount1:"+c.sig; got != want {
+			t.Errorf("%s %s: got %s, want %s", c.req.Method, c.req.URL, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DefaultConfigPath = "config/storage.json"
+
+// Config names destinations so commands can refer to "partner-drop" instead
+// of repeating URLs. ${VAR} in a URL is expanded from the environment, which
+// keeps secrets out of the file.
+type Config struct {
+	Destinations map[string]Destination `json:"destinations"`
+}
+
+type Destination struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// LoadConfig reads the destinations file; a missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{Destinations: make(map[string]Destination)}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("parse storage config: %w", err)
+	}
+	for name, d := range c.Destinations {
+		if d.URL == "" {
+			return nil, fmt.Errorf("storage config: destination %s has no url", name)
+		}
+	}
+	return c, nil
+}
+
+// Open resolves a destination name or a literal URL.
+func (c *Config) Open(nameOrURL string) (Backend, error) {
+	if strings.Contains(nameOrURL, "://") {
+		return Open(nameOrURL)
+	}
+	d, ok := c.Destinations[nameOrURL]
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown destination %q (configured: %s)", nameOrURL, strings.Join(c.Names(), ", "))
+	}
+	return Open(os.ExpandEnv(d.URL))
+}
+
+func (c *Config) Names() []string {
+	out := make([]string, 0, len(c.Destinations))
+	for n := range c.Destinations {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
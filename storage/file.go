@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/KyberNetwork/fairflow-reward/internal/safepath"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+func init() {
+	Register("file", func(u *url.URL) (Backend, error) {
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("storage: file URL with host %q (want file:///path or file:///C:/path)", u.Host)
+		}
+		if u.Path == "" {
+			return nil, errors.New("storage: file URL needs a path")
+		}
+		return &fileBackend{root: localRoot(u.Path)}, nil
+	})
+}
+
+type fileBackend struct{ root string }
+
+// localRoot is the directory of a file URL's path; on Windows
+// "/C:/backup" is C:\backup.
+func localRoot(p string) string {
+	if runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+func (b *fileBackend) path(key string) (string, error) {
+	p, err := safepath.Join(b.root, key)
+	if err != nil {
+		return "", fmt.Errorf("storage: key %q: %w", key, err)
+	}
+	return p, nil
+}
+
+func (b *fileBackend) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return tmpdir.Copy(p, r, 0o644)
+}
+
+func (b *fileBackend) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (b *fileBackend) Close() error { return nil }
+
+func (b *fileBackend) String() string { return "file://" + filepath.ToSlash(b.root) }
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+// S3 and GCS (through its S3-compatible XML API with HMAC keys).
+//
+//	s3://bucket/prefix?region=eu-west-1&endpoint=https://minio.internal:9000
+//	gs://bucket/prefix
+//
+// Credentials come from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY
+// (/ AWS_SESSION_TOKEN), or GCS_HMAC_ACCESS_KEY / GCS_HMAC_SECRET for gs://.
+
+func init() {
+	Register("s3", func(u *url.URL) (Backend, error) {
+		region := firstNonEmpty(u.Query().Get("region"), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+		creds := awsCreds{
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		return newS3(u, creds, region, u.Query().Get("endpoint"))
+	})
+	Register("gs", func(u *url.URL) (Backend, error) {
+		creds := awsCreds{AccessKey: os.Getenv("GCS_HMAC_ACCESS_KEY"), SecretKey: os.Getenv("GCS_HMAC_SECRET")}
+		return newS3(u, creds, "auto", firstNonEmpty(u.Query().Get("endpoint"), "https://storage.googleapis.com"))
+	})
+}
+
+type s3Backend struct {
+	scheme string
+	bucket string
+	prefix string
+	region string
+	creds  awsCreds
+	// endpoint is set for path-style access; empty means AWS virtual-hosted.
+	endpoint *url.URL
+	http     *http.Client
+}
+
+func newS3(u *url.URL, creds awsCreds, region, endpoint string) (*s3Backend, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("storage: %s URL needs a bucket", u.Scheme)
+	}
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return nil, fmt.Errorf("storage: %s credentials are not set", u.Scheme)
+	}
+	b := &s3Backend{
+		scheme: u.Scheme,
+		bucket: u.Host,
+		prefix: u.Path,
+		region: region,
+		creds:  creds,
+		http:   &http.Client{Timeout: 10 * time.Minute, Transport: tracing.Transport(nil)},
+	}
+	if endpoint != "" {
+		ep, err := url.Parse(endpoint)
+		if err != nil || ep.Host == "" {
+			return nil, fmt.Errorf("storage: invalid endpoint %q", endpoint)
+		}
+		b.endpoint = ep
+	}
+	return b, nil
+}
+
+func (b *s3Backend) objectURL(key string) *url.URL {
+	path := "/" + join(b.prefix, key)
+	u := &url.URL{Scheme: "https", Host: b.bucket + ".s3." + b.region + ".amazonaws.com"}
+	if b.endpoint != nil {
+		u.Scheme, u.Host = b.endpoint.Scheme, b.endpoint.Host
+		path = "/" + b.bucket + path
+	}
+	u.Path, u.RawPath = path, uriEncode(path, true)
+	return u
+}
+
+func (b *s3Backend) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signV4(req, b.creds, b.region, "s3", payloadHash, time.Now())
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, b, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+func (b *s3Backend) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size < 0 {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(buf), int64(len(buf))
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", b.objectURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := b.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req, sha256Hex(nil))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *s3Backend) Close() error { return nil }
+
+func (b *s3Backend) String() string { return b.scheme + "://" + join(b.bucket, b.prefix) }
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// SFTP drops, via the OpenSSH sftp client in batch mode so host keys, agents
+// and ~/.ssh/config work as they do for operators. Password auth is not
+// supported; use a key (?identity=/path/to/key or the agent).
+//
+//	sftp://drop@partner.example.com:2222/incoming?identity=/secrets/id_ed25519
+func init() {
+	Register("sftp", func(u *url.URL) (Backend, error) {
+		if u.Host == "" {
+			return nil, fmt.Errorf("storage: sftp URL needs a host")
+		}
+		return &sftpBackend{u: u}, nil
+	})
+}
+
+type sftpBackend struct{ u *url.URL }
+
+func (b *sftpBackend) run(ctx context.Context, batch string) error {
+	args := []string{"-q", "-b", "-", "-o", "BatchMode=yes"}
+	if p := b.u.Port(); p != "" {
+		args = append(args, "-P", p)
+	}
+	if id := b.u.Query().Get("identity"); id != "" {
+		args = append(args, "-i", id)
+	}
+	target := b.u.Hostname()
+	if b.u.User != nil {
+		target = b.u.User.Username() + "@" + target
+	}
+	cmd := exec.CommandContext(ctx, "sftp", append(args, target)...)
+	cmd.Stdin = strings.NewReader(batch)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sftp %s: %w: %s", b, err, bytes.TrimSpace(out.Bytes()))
+	}
+	return nil
+}
+
+// remote is the remote path of key.
+func (b *sftpBackend) remote(key string) (string, error) {
+	p := "/" + join(b.u.Path, key)
+	return p, batchPath(p)
+}
+
+// batchPath refuses what quote cannot carry through the sftp batch parser
+// and the glob put and get apply: control characters, which would end or
+// split a command, and glob metacharacters.
+func batchPath(p string) error {
+	if i := strings.IndexFunc(p, func(r rune) bool { return r < 0x20 || r == 0x7f || strings.ContainsRune("*?[]", r) }); i >= 0 {
+		return fmt.Errorf("storage: sftp path %q: %q not allowed", p, p[i])
+	}
+	return nil
+}
+
+// quote quotes a path for the sftp batch parser, which unescapes \" and \\
+// inside double quotes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// putBatch uploads src to dst. It creates the parent directories ("-"
+// prefixed commands may fail: the directory already exists) and uploads
+// under a temporary name so readers never see a partial file. OpenSSH's
+// rename uses posix-rename@openssh.com where the server offers it, which
+// replaces dst atomically; a server without it refuses to overwrite dst and
+// the upload fails rather than leave dst missing.
+func putBatch(src, dst string) string {
+	var batch strings.Builder
+	dirs := strings.Split(strings.Trim(path.Dir(dst), "/"), "/")
+	for i := range dirs {
+		batch.WriteString("-mkdir " + quote("/"+strings.Join(dirs[:i+1], "/")) + "\n")
+	}
+	batch.WriteString("put " + quote(src) + " " + quote(dst+".tmp") + "\n")
+	batch.WriteString("rename " + quote(dst+".tmp") + " " + quote(dst) + "\n")
+	return batch.String()
+}
+
+func (b *sftpBackend) Put(ctx context.Context, key string, r io.Reader, _ int64) error {
+	dst, err := b.remote(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "fairflow-sftp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := batchPath(tmp.Name()); err != nil {
+		return err
+	}
+	return b.run(ctx, putBatch(tmp.Name(), dst))
+}
+
+func (b *sftpBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	src, err := b.remote(key)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp("", "fairflow-sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	if err := batchPath(tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := b.run(ctx, "get "+quote(src)+" "+quote(tmp.Name())+"\n"); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	f, err := os.Open(tmp.Name())
+	os.Remove(tmp.Name())
+	return f, err
+}
+
+func (b *sftpBackend) Close() error { return nil }
+
+func (b *sftpBackend) String() string {
+	u := *b.u
+	u.RawQuery = ""
+	return u.Redacted()
+}
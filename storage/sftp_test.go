@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"/drop/a b.json":  `"/drop/a b.json"`,
+		`/drop/"x"`:       `"/drop/\"x\""`,
+		`/drop/a\" b`:     `"/drop/a\\\" b"`,
+		`/drop/trailing\`: `"/drop/trailing\\"`,
+	} {
+		if got := quote(in); got != want {
+			t.Errorf("quote(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// A key must not be able to end the quoted path and add a command.
+func TestSFTPRemoteRejects(t *testing.T) {
+	b := &sftpBackend{u: &url.URL{Scheme: "sftp", Host: "h", Path: "/drop"}}
+	for _, key := range []string{"a\nrm /etc", "a\rb", "a\x00b", "a\tb", "a\x7fb", "*.json", "a?", "[ab]"} {
+		if p, err := b.remote(key); err == nil {
+			t.Errorf("%q: got %q", key, p)
+		}
+	}
+	if p, err := b.remote(`cycles/5/"odd" name\.json`); err != nil || p != `/drop/cycles/5/"odd" name\.json` {
+		t.Errorf("got %q, %v", p, err)
+	}
+}
+
+func TestPutBatch(t *testing.T) {
+	got := putBatch("/tmp/fairflow-sftp-1", "/drop/cycles/5.json")
+	want := strings.Join([]string{
+		`-mkdir "/drop"`,
+		`-mkdir "/drop/cycles"`,
+		`put "/tmp/fairflow-sftp-1" "/drop/cycles/5.json.tmp"`,
+		`rename "/drop/cycles/5.json.tmp" "/drop/cycles/5.json"`,
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
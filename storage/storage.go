@@ -0,0 +1,109 @@
+// Package storage is the upload/download abstraction for artifact
+// destinations (mirrors, partner drops, archives). Backends are selected by
+// URL scheme:
+//
+//	file:///var/backup/fairflow
+//	s3://bucket/prefix?region=eu-west-1
+//	gs://bucket/prefix
+//	azblob://account/container/prefix
+//	sftp://user@host/path
+//	webdav://host/path
+//	git+ssh://git@github.com/org/backup.git#main
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+type Backend interface {
+	// Put stores r under key (slash-separated, relative to the backend root).
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Close flushes batched work (e.g. a git push). Backends without any
+	// return nil.
+	Close() error
+	String() string
+}
+
+type Opener func(u *url.URL) (Backend, error)
+
+var (
+	mu      sync.Mutex
+	openers = make(map[string]Opener)
+)
+
+// Register adds a backend for a URL scheme. It panics on a duplicate.
+func Register(scheme string, open Opener) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := openers[scheme]; dup {
+		panic("storage: duplicate scheme " + scheme)
+	}
+	openers[scheme] = open
+}
+
+func Schemes() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]string, 0, len(openers))
+	for s := range openers {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func Open(rawURL string) (Backend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("storage: %q has no scheme (known: %s)", rawURL, strings.Join(Schemes(), ", "))
+	}
+	mu.Lock()
+	open, ok := openers[u.Scheme]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown scheme %q (known: %s)", u.Scheme, strings.Join(Schemes(), ", "))
+	}
+	return open(u)
+}
+
+// join prefixes key with a backend root path.
+func join(prefix, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	key = strings.TrimLeft(key, "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
+
+// PutFile uploads the local file at path under key.
+func PutFile(ctx context.Context, b Backend, key, path string) (err error) {
+	ctx, span := tracing.Start(ctx, "storage.put", "storage.backend", b.String(), "storage.key", key)
+	defer func() { span.End(err) }()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := b.Put(ctx, key, f, st.Size()); err != nil {
+		return fmt.Errorf("%s: put %s: %w", b, key, err)
+	}
+	return nil
+}
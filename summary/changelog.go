@@ -0,0 +1,138 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultChangelog is the history publish appends to, beside the cycle
+// directories. A path ending in .json keeps the same entries as JSON.
+const DefaultChangelog = "CYCLES.md"
+
+// ChangelogEntry is one published cycle.
+type ChangelogEntry struct {
+	Cycle  int      `json:"cycle"`
+	Date   string   `json:"date"`
+	Chains []string `json:"chains"`
+	// Totals is chain ID -> token -> base units.
+	Totals map[string]map[string]string `json:"totals"`
+	PR     string                       `json:"pr,omitempty"`
+}
+
+func (s *Cycle) ChangelogEntry(at time.Time, prURL string) ChangelogEntry {
+	return ChangelogEntry{Cycle: s.Cycle, Date: at.UTC().Format("2006-01-02"), Chains: s.Chains(), Totals: s.ChainTotals, PR: prURL}
+}
+
+// AppendChangelog adds e to the changelog at path, creating it. A cycle
+// already in a Markdown changelog is left as written; in a JSON one it is
+// replaced. It reports whether the file changed.
+func AppendChangelog(path string, e ChangelogEntry) (bool, error) {
+	if strings.HasSuffix(path, ".json") {
+		return appendJSON(path, e)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	doc := string(b)
+	heading := fmt.Sprintf("## Cycle %d ", e.Cycle)
+	for _, l := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(l+" ", heading) {
+			return false, nil
+		}
+	}
+	if doc == "" {
+		doc = "# Cycles\n"
+	}
+	if !strings.HasSuffix(doc, "\n") {
+		doc += "\n"
+	}
+	return true, os.WriteFile(path, []byte(doc+"\n"+e.markdown()), 0o644)
+}
+
+func (e ChangelogEntry) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Cycle %d (%s)\n\n", e.Cycle, e.Date)
+	fmt.Fprintf(&b, "- Chains: %s\n", strings.Join(e.Chains, ", "))
+	if e.PR != "" {
+		fmt.Fprintf(&b, "- PR: %s\n", e.PR)
+	}
+	b.WriteString("- Totals:\n")
+	for _, c := range e.Chains {
+		toks := make([]string, 0, len(e.Totals[c]))
+		for t := range e.Totals[c] {
+			toks = append(toks, t)
+		}
+		sort.Strings(toks)
+		for _, t := range toks {
+			fmt.Fprintf(&b, "  - %s %s %s\n", c, t, e.Totals[c][t])
+		}
+	}
+	return b.String()
+}
+
+func appendJSON(path string, e ChangelogEntry) (bool, error) {
+	entries := make([]ChangelogEntry, 0)
+	b, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &entries); err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return false, err
+	}
+	replaced := false
+	for i := range entries {
+		if entries[i].Cycle == e.Cycle {
+			entries[i], replaced = e, true
+		}
+	}
+	if !replaced {
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Cycle < entries[j].Cycle })
+	out, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	out = append(out, '\n')
+	if string(out) == string(b) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, out, 0o644)
+}
+
+// ChangelogHas reports whether the changelog at path already has cycle n.
+func ChangelogHas(path string, n int) (bool, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if strings.HasSuffix(path, ".json") {
+		var entries []ChangelogEntry
+		if err := json.Unmarshal(b, &entries); err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, e := range entries {
+			if e.Cycle == n {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	heading := fmt.Sprintf("## Cycle %d ", n)
+	for _, l := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(l+" ", heading) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
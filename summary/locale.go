@@ -0,0 +1,115 @@
+package summary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Locale selects the message catalog reports and notifications are written
+// in. Data — file names, roots, amounts — is never translated.
+type Locale string
+
+const (
+	LocaleEN Locale = "en"
+	LocaleVI Locale = "vi"
+	LocaleZH Locale = "zh"
+)
+
+// catalog is locale -> message key -> fmt format. English is complete;
+// a key missing from another locale falls back to it.
+var catalog = map[Locale]map[string]string{
+	LocaleEN: {
+		"pr.title":       "Cycle %d rewards",
+		"cycle":          "Cycle %d",
+		"totals":         "Totals",
+		"totals.units":   "Totals (chain token base-units)",
+		"roots":          "Roots",
+		"changes.since":  "Changes since cycle %d",
+		"col.file":       "File",
+		"col.status":     "Status",
+		"col.recipients": "Recipients",
+		"col.root":       "Root changed",
+		"yes":            "yes",
+		"no":             "no",
+		"status.added":   "added",
+		"status.removed": "removed",
+		"status.changed": "changed",
+		"notion":         "Notion",
+		"owner":          "owner: %s",
+		"published":      "Cycle %d published.",
+	},
+	LocaleVI: {
+		"pr.title":       "Phần thưởng chu kỳ %d",
+		"cycle":          "Chu kỳ %d",
+		"totals":         "Tổng",
+		"totals.units":   "Tổng (chain token đơn vị cơ sở)",
+		"roots":          "Merkle root",
+		"changes.since":  "Thay đổi so với chu kỳ %d",
+		"col.file":       "Tệp",
+		"col.status":     "Trạng thái",
+		"col.recipients": "Người nhận",
+		"col.root":       "Root thay đổi",
+		"yes":            "có",
+		"no":             "không",
+		"status.added":   "thêm mới",
+		"status.removed": "đã xóa",
+		"status.changed": "thay đổi",
+		"notion":         "Notion",
+		"owner":          "phụ trách: %s",
+		"published":      "Đã phát hành chu kỳ %d.",
+	},
+	LocaleZH: {
+		"pr.title":       "第 %d 期奖励",
+		"cycle":          "第 %d 期",
+		"totals":         "总额",
+		"totals.units":   "总额（链 代币 最小单位）",
+		"roots":          "Merkle 根",
+		"changes.since":  "与第 %d 期相比的变化",
+		"col.file":       "文件",
+		"col.status":     "状态",
+		"col.recipients": "接收者",
+		"col.root":       "根已变更",
+		"yes":            "是",
+		"no":             "否",
+		"status.added":   "新增",
+		"status.removed": "已移除",
+		"status.changed": "已变更",
+		"notion":         "Notion",
+		"owner":          "负责人：%s",
+		"published":      "第 %d 期已发布。",
+	},
+}
+
+// ParseLocale accepts a catalog's locale, or a tag such as "vi-VN" or
+// "zh_CN" of one; "" is English.
+func ParseLocale(s string) (Locale, error) {
+	if s == "" {
+		return LocaleEN, nil
+	}
+	base, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(s, "_", "-")), "-")
+	if _, ok := catalog[Locale(base)]; ok {
+		return Locale(base), nil
+	}
+	known := make([]string, 0, len(catalog))
+	for l := range catalog {
+		known = append(known, string(l))
+	}
+	sort.Strings(known)
+	return "", fmt.Errorf("unknown locale %q (known: %s)", s, strings.Join(known, ", "))
+}
+
+// T formats the message key in l, falling back to English and then to the
+// key itself.
+func (l Locale) T(key string, args ...any) string {
+	f, ok := catalog[l][key]
+	if !ok {
+		if f, ok = catalog[LocaleEN][key]; !ok {
+			f = key
+		}
+	}
+	if len(args) == 0 {
+		return f
+	}
+	return fmt.Sprintf(f, args...)
+}
@@ -0,0 +1,197 @@
+package summary
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+// Release is what PR and commit message templates are executed with: the
+// cycle's summary, how it differs from the previous cycle, and the Notion
+// rows its files were synced from.
+type Release struct {
+	*Cycle
+	Dir string
+	// Previous is nil when the previous cycle directory is not beside Dir.
+	Previous *Cycle
+	Diff     []FileDiff
+	// NotionLinks is file name -> Notion page URL, from the sync manifest.
+	NotionLinks map[string]string
+	// Owners is file name -> owner, from the sync manifest.
+	Owners map[string]string
+	// Locale is what the tr template function translates into.
+	Locale Locale
+}
+
+// FileDiff compares a file with the same chain and reward type in the
+// previous cycle. Status is "added", "removed" or "changed".
+type FileDiff struct {
+	Name            string
+	ChainID         string
+	RewardType      string
+	Status          string
+	RootChanged     bool
+	Recipients      int
+	PrevRecipients  int
+	RecipientsDelta int
+	Tokens          []TokenDiff
+}
+
+// TokenDiff amounts are base units; Delta is signed.
+type TokenDiff struct {
+	Token    string
+	Previous string
+	Current  string
+	Delta    string
+}
+
+// NewRelease summarises dir and, when present, its previous cycle directory.
+func NewRelease(dir string) (*Release, error) {
+	cur, err := Build(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Release{Cycle: cur, Dir: dir, NotionLinks: make(map[string]string), Owners: make(map[string]string)}
+	prevDir := filepath.Join(filepath.Dir(filepath.Clean(dir)), cycle.DirName(cur.Cycle-1))
+	if _, err := os.Stat(prevDir); err == nil {
+		if r.Previous, err = Build(prevDir); err != nil {
+			return nil, fmt.Errorf("previous cycle: %w", err)
+		}
+	}
+	r.Diff = diff(r.Previous, cur)
+	m, err := cycle.LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for name, e := range m.Files {
+		if u := e.NotionURL(); u != "" {
+			r.NotionLinks[name] = u
+		}
+		if e.Owner != "" {
+			r.Owners[name] = e.Owner
+		}
+	}
+	return r, nil
+}
+
+func diff(prev, cur *Cycle) []FileDiff {
+	type pair struct{ chain, typ string }
+	before := make(map[pair]File)
+	if prev != nil {
+		for _, f := range prev.Files {
+			before[pair{f.ChainID, f.RewardType}] = f
+		}
+	}
+	out := make([]FileDiff, 0, len(cur.Files))
+	for _, f := range cur.Files {
+		p := pair{f.ChainID, f.RewardType}
+		old, ok := before[p]
+		delete(before, p)
+		d := FileDiff{Name: f.Name, ChainID: f.ChainID, RewardType: f.RewardType, Status: "changed", Recipients: f.Recipients}
+		if !ok {
+			d.Status = "added"
+		}
+		d.PrevRecipients = old.Recipients
+		d.RecipientsDelta = f.Recipients - old.Recipients
+		d.RootChanged = !strings.EqualFold(old.Root, f.Root)
+		d.Tokens = tokenDiffs(old.Totals, f.Totals)
+		out = append(out, d)
+	}
+	for _, old := range before {
+		out = append(out, FileDiff{
+			Name: old.Name, ChainID: old.ChainID, RewardType: old.RewardType, Status: "removed",
+			RootChanged: true, PrevRecipients: old.Recipients, RecipientsDelta: -old.Recipients,
+			Tokens: tokenDiffs(old.Totals, nil),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func tokenDiffs(prev, cur map[string]string) []TokenDiff {
+	toks := make([]string, 0, len(prev)+len(cur))
+	for t := range cur {
+		toks = append(toks, t)
+	}
+	for t := range prev {
+		if _, ok := cur[t]; !ok {
+			toks = append(toks, t)
+		}
+	}
+	sort.Strings(toks)
+	out := make([]TokenDiff, 0, len(toks))
+	for _, t := range toks {
+		p, c := amount(prev[t]), amount(cur[t])
+		out = append(out, TokenDiff{Token: t, Previous: p.String(), Current: c.String(), Delta: new(big.Int).Sub(c, p).String()})
+	}
+	return out
+}
+
+func amount(s string) *big.Int {
+	a, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return a
+}
+
+// Default templates, used when a team has not provided its own. Their text
+// comes from the message catalog through tr, so they follow Locale.
+const (
+	DefaultPRTitle = `{{tr "pr.title" .Cycle.Cycle}}`
+	DefaultPRBody  = `## {{tr "cycle" .Cycle.Cycle}}
+
+### {{tr "totals"}}
+` + "```" + `
+{{.TotalsText}}
+` + "```" + `
+
+### {{tr "roots"}}
+` + "```" + `
+{{.RootsText}}
+` + "```" + `
+{{- if .Previous}}
+
+### {{tr "changes.since" .Previous.Cycle}}
+| {{tr "col.file"}} | {{tr "col.status"}} | {{tr "col.recipients"}} | {{tr "col.root"}} |
+|---|---|---|---|
+{{- range .Diff}}
+| {{.Name}} | {{tr (print "status." .Status)}} | {{.Recipients}} ({{signed .RecipientsDelta}}) | {{if .RootChanged}}{{tr "yes"}}{{else}}{{tr "no"}}{{end}} |
+{{- end}}
+{{- end}}
+{{- if .NotionLinks}}
+
+### {{tr "notion"}}
+{{- range $file, $url := .NotionLinks}}
+- [{{$file}}]({{$url}}){{with index $.Owners $file}} ({{tr "owner" .}}){{end}}
+{{- end}}
+{{- end}}
+`
+)
+
+var funcs = template.FuncMap{
+	"join":   strings.Join,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"signed": func(n int) string { return fmt.Sprintf("%+d", n) },
+}
+
+// Render executes the Go text/template text with r; name labels errors.
+// Besides funcs, {{tr "key" args...}} formats a catalog message in r.Locale.
+func (r *Release) Render(name, text string) (string, error) {
+	t, err := template.New(name).Funcs(funcs).Funcs(template.FuncMap{"tr": r.Locale.T}).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, r); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
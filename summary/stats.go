@@ -0,0 +1,184 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/tmpdir"
+)
+
+// Stats is stats.json: the figures reward-service and the front-end display
+// for a cycle, small enough to fetch without the merkle files. It is written
+// from the files alone, so rebuilding it gives the same bytes.
+type Stats struct {
+	Cycle int `json:"cycle"`
+	// ClaimDeadline is when the cycle stops being served, if known.
+	ClaimDeadline time.Time   `json:"claimDeadline,omitzero"`
+	Recipients    int         `json:"recipients"`
+	Files         []FileStats `json:"files"`
+	// ChainTotals is chain ID -> token -> base units across reward types.
+	ChainTotals map[string]map[string]string `json:"chainTotals"`
+}
+
+type FileStats struct {
+	Name       string `json:"name"`
+	ChainID    string `json:"chainId"`
+	RewardType string `json:"type"`
+	Root       string `json:"root"`
+	Recipients int    `json:"recipients"`
+	// Wallets counts distinct erc721Addr, as one wallet may hold several
+	// positions.
+	Wallets int                   `json:"wallets"`
+	Tokens  map[string]TokenStats `json:"tokens"`
+}
+
+// TokenStats describes how one token of a file is spread over the
+// recipients paid in it; amounts are base units.
+type TokenStats struct {
+	Total      string `json:"total"`
+	Recipients int    `json:"recipients"`
+	Min        string `json:"min"`
+	Median     string `json:"median"`
+	P90        string `json:"p90"`
+	Max        string `json:"max"`
+	// Top10ShareBps is the share of Total paid to the ten largest
+	// recipients, in basis points.
+	Top10ShareBps int64 `json:"top10ShareBps"`
+	// Gini is 0 for an even split and approaches 1 as one recipient takes
+	// everything.
+	Gini float64 `json:"gini"`
+}
+
+// BuildStats computes the stats of every merkle file in dir.
+func BuildStats(dir string, deadline time.Time) (*Stats, error) {
+	s, err := Build(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := &Stats{Cycle: s.Cycle, ChainTotals: s.ChainTotals, Files: make([]FileStats, 0, len(s.Files))}
+	if !deadline.IsZero() {
+		out.ClaimDeadline = deadline.UTC()
+	}
+	for _, sf := range s.Files {
+		f, err := cycle.Load(filepath.Join(dir, sf.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sf.Name, err)
+		}
+		fs := FileStats{Name: sf.Name, ChainID: sf.ChainID, RewardType: sf.RewardType, Root: sf.Root, Recipients: sf.Recipients, Tokens: make(map[string]TokenStats)}
+		wallets := make(map[string]struct{})
+		amounts := make(map[string][]*big.Int)
+		for _, ud := range f.UserDatas {
+			wallets[strings.ToLower(ud.Leaf.ERC721Addr)] = struct{}{}
+			am, err := ud.Leaf.AmountsByToken()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", sf.Name, err)
+			}
+			for tok, a := range am {
+				if a.Sign() > 0 {
+					amounts[tok] = append(amounts[tok], a)
+				}
+			}
+		}
+		fs.Wallets = len(wallets)
+		for tok, as := range amounts {
+			fs.Tokens[tok] = tokenStats(as)
+		}
+		out.Recipients += fs.Recipients
+		out.Files = append(out.Files, fs)
+	}
+	return out, nil
+}
+
+func tokenStats(as []*big.Int) TokenStats {
+	sort.Slice(as, func(i, j int) bool { return as[i].Cmp(as[j]) < 0 })
+	n := len(as)
+	total := new(big.Int)
+	for _, a := range as {
+		total.Add(total, a)
+	}
+	top := new(big.Int)
+	for _, a := range as[max(0, n-10):] {
+		top.Add(top, a)
+	}
+	top.Mul(top, big.NewInt(10_000)).Quo(top, total)
+
+	// Gini = Σ (2i - n - 1) x_i / (n Σ x), i = 1..n over ascending x.
+	weighted := new(big.Int)
+	for i, a := range as {
+		weighted.Add(weighted, new(big.Int).Mul(a, big.NewInt(int64(2*(i+1)-n-1))))
+	}
+	num, _ := new(big.Float).SetInt(weighted).Float64()
+	den, _ := new(big.Float).SetInt(new(big.Int).Mul(total, big.NewInt(int64(n)))).Float64()
+
+	return TokenStats{
+		Total:         total.String(),
+		Recipients:    n,
+		Min:           as[0].String(),
+		Median:        rankOf(as, 0.5).String(),
+		P90:           rankOf(as, 0.9).String(),
+		Max:           as[n-1].String(),
+		Top10ShareBps: top.Int64(),
+		Gini:          math.Round(num/den*10_000) / 10_000,
+	}
+}
+
+// rankOf is the nearest-rank p-th percentile of ascending as.
+func rankOf(as []*big.Int, p float64) *big.Int {
+	i := int(math.Ceil(p*float64(len(as)))) - 1
+	return as[max(0, i)]
+}
+
+func (s *Stats) marshal() ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// WriteStats writes s as dir/stats.json.
+func WriteStats(dir string, s *Stats) error {
+	b, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, cycle.StatsName)
+	return tmpdir.WriteFile(path, b, 0o644)
+}
+
+// CheckStats reports a stats.json in dir that no longer matches its merkle
+// files; a directory without one passes.
+func CheckStats(dir string) error {
+	b, err := os.ReadFile(filepath.Join(dir, cycle.StatsName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var old Stats
+	if err := json.Unmarshal(b, &old); err != nil {
+		return fmt.Errorf("%s: %w", cycle.StatsName, err)
+	}
+	s, err := BuildStats(dir, old.ClaimDeadline)
+	if err != nil {
+		return err
+	}
+	want, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(b, want) {
+		return fmt.Errorf("%s is stale; rerun stats --cycle-dir %s", cycle.StatsName, dir)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package summary condenses a cycle directory into the figures reported
+// after a release: per-file roots and recipient counts, and totals per chain
+// and token.
+package summary
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+type File struct {
+	Name       string            `json:"name"`
+	ChainID    string            `json:"chainId"`
+	RewardType string            `json:"type"`
+	Root       string            `json:"root"`
+	Recipients int               `json:"recipients"`
+	Totals     map[string]string `json:"totals"`
+	// Notion links the row the file was synced from, when known.
+	Notion string `json:"notion,omitempty"`
+	// Owner is who to ask about the file, from the sync manifest.
+	Owner string `json:"owner,omitempty"`
+}
+
+type Cycle struct {
+	Cycle int    `json:"cycle"`
+	Files []File `json:"files"`
+	// ChainTotals is chain ID -> token -> base units across reward types.
+	ChainTotals map[string]map[string]string `json:"chainTotals"`
+}
+
+// Build summarises every merkle file in dir.
+func Build(dir string) (*Cycle, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no merkle files in %s", dir)
+	}
+	m, err := cycle.LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Cycle{Cycle: entries[0].Cycle, ChainTotals: make(map[string]map[string]string)}
+	sums := make(map[string]map[string]*big.Int)
+	for _, e := range entries {
+		if e.Cycle != s.Cycle {
+			return nil, fmt.Errorf("%s: belongs to cycle %d, not %d", filepath.Base(e.Path), e.Cycle, s.Cycle)
+		}
+		f, err := cycle.Load(e.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Label(e.String()), err)
+		}
+		totals, err := f.SumAmounts()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Label(e.String()), err)
+		}
+		fs := File{
+			Name:       e.String(),
+			ChainID:    e.ChainID,
+			RewardType: e.RewardType,
+			Root:       f.Root,
+			Recipients: len(f.UserDatas),
+			Totals:     make(map[string]string, len(totals)),
+			Notion:     m.Files[e.String()].NotionURL(),
+			Owner:      m.Files[e.String()].Owner,
+		}
+		if sums[e.ChainID] == nil {
+			sums[e.ChainID] = make(map[string]*big.Int)
+		}
+		for tok, a := range totals {
+			fs.Totals[tok] = a.String()
+			if sums[e.ChainID][tok] == nil {
+				sums[e.ChainID][tok] = new(big.Int)
+			}
+			sums[e.ChainID][tok].Add(sums[e.ChainID][tok], a)
+		}
+		s.Files = append(s.Files, fs)
+	}
+	for chain, toks := range sums {
+		s.ChainTotals[chain] = make(map[string]string, len(toks))
+		for tok, a := range toks {
+			s.ChainTotals[chain][tok] = a.String()
+		}
+	}
+	return s, nil
+}
+
+// Chains returns the chain IDs in numeric order.
+func (s *Cycle) Chains() []string {
+	out := make([]string, 0, len(s.ChainTotals))
+	for c := range s.ChainTotals {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i]) != len(out[j]) {
+			return len(out[i]) < len(out[j])
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// TotalsText renders chain totals one "chain token amount" per line.
+func (s *Cycle) TotalsText() string {
+	var b strings.Builder
+	for _, c := range s.Chains() {
+		toks := make([]string, 0, len(s.ChainTotals[c]))
+		for t := range s.ChainTotals[c] {
+			toks = append(toks, t)
+		}
+		sort.Strings(toks)
+		for _, t := range toks {
+			fmt.Fprintf(&b, "%s %s %s\n", c, t, s.ChainTotals[c][t])
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// RootsText renders "file root" per line.
+func (s *Cycle) RootsText() string {
+	var b strings.Builder
+	for _, f := range s.Files {
+		fmt.Fprintf(&b, "%s %s\n", f.Name, f.Root)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
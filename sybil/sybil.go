@@ -0,0 +1,229 @@
+// Package sybil groups a cycle's recipients that are likely controlled by one
+// entity, for the anti-sybil team to review before release. It flags two
+// patterns: positions whose holders were first funded by the same address,
+// and identical amounts repeated across positions new in this cycle.
+package sybil
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+)
+
+// Recipient is one position in the analysed cycle.
+type Recipient struct {
+	Position string
+	File     string
+	// Amounts is the canonical "token=amount,..." string of the leaf's
+	// non-zero amounts.
+	Amounts string
+	// Fresh is set when the position had no leaf in any earlier cycle.
+	Fresh bool
+}
+
+const (
+	ReasonFunder = "same-funder"
+	ReasonAmount = "identical-fresh-amounts"
+)
+
+type Cluster struct {
+	Reason string `json:"reason"`
+	// Key is the shared funder address or amount string.
+	Key       string   `json:"key"`
+	Positions []string `json:"positions"`
+}
+
+// Seen returns the position keys with a leaf in any cycle before n under
+// root.
+func Seen(root string, n int) (map[string]bool, error) {
+	cycles, err := cycle.Cycles(root)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, c := range cycles {
+		if c >= n {
+			continue
+		}
+		entries, err := cycle.ScanDir(filepath.Join(root, cycle.DirName(c)))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			f, err := cycle.Load(e.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Path, err)
+			}
+			for _, ud := range f.UserDatas {
+				seen[ud.Leaf.Key()] = true
+			}
+		}
+	}
+	return seen, nil
+}
+
+// Recipients lists every leaf in the cycle directory dir.
+func Recipients(dir string, seen map[string]bool) ([]Recipient, error) {
+	entries, err := cycle.ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no merkle files in %s", dir)
+	}
+	out := make([]Recipient, 0)
+	for _, e := range entries {
+		f, err := cycle.Load(e.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Path, err)
+		}
+		for _, ud := range f.UserDatas {
+			am, err := ud.Leaf.AmountsByToken()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Path, err)
+			}
+			parts := make([]string, 0, len(am))
+			for t, a := range am {
+				if a.Sign() > 0 {
+					parts = append(parts, t+"="+a.String())
+				}
+			}
+			sort.Strings(parts)
+			key := ud.Leaf.Key()
+			out = append(out, Recipient{Position: key, File: e.String(), Amounts: strings.Join(parts, ","), Fresh: !seen[key]})
+		}
+	}
+	return out, nil
+}
+
+// LoadTransfers reads a CSV with "from" and "to" columns (others are
+// ignored) and returns each recipient's first funder, in file order. "to"
+// may be a holder address or a position key.
+func LoadTransfers(path string) (map[string]string, error) {
+	rows, err := readCSV(path, "from", "to")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for _, r := range rows {
+		if _, ok := out[r[1]]; !ok {
+			out[r[1]] = r[0]
+		}
+	}
+	return out, nil
+}
+
+// LoadHolders reads a CSV with "position" and "holder" columns.
+func LoadHolders(path string) (map[string]string, error) {
+	rows, err := readCSV(path, "position", "holder")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(rows))
+	for _, r := range rows {
+		out[r[0]] = r[1]
+	}
+	return out, nil
+}
+
+func readCSV(path string, cols ...string) ([][]string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	cr := csv.NewReader(fh)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: read header: %w", path, err)
+	}
+	idx := make([]int, len(cols))
+	for i, c := range cols {
+		idx[i] = -1
+		for j, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), c) {
+				idx[i] = j
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("%s: missing %q column", path, c)
+		}
+	}
+	out := make([][]string, 0)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		row := make([]string, len(cols))
+		for i, j := range idx {
+			if j < len(rec) {
+				row[i] = strings.ToLower(strings.TrimSpace(rec[j]))
+			}
+		}
+		out = append(out, row)
+	}
+}
+
+// Analyze returns the clusters of at least minSize distinct positions,
+// largest first. funders and holders may be nil.
+func Analyze(rs []Recipient, funders, holders map[string]string, minSize int) []Cluster {
+	byFunder := make(map[string]map[string]bool)
+	byAmount := make(map[string]map[string]bool)
+	add := func(m map[string]map[string]bool, k, pos string) {
+		if m[k] == nil {
+			m[k] = make(map[string]bool)
+		}
+		m[k][pos] = true
+	}
+	for _, r := range rs {
+		funder := funders[r.Position]
+		if h, ok := holders[r.Position]; ok && funder == "" {
+			funder = funders[h]
+		}
+		if funder != "" {
+			add(byFunder, funder, r.Position)
+		}
+		if r.Fresh && r.Amounts != "" {
+			add(byAmount, r.Amounts, r.Position)
+		}
+	}
+	out := make([]Cluster, 0)
+	collect := func(reason string, m map[string]map[string]bool) {
+		for k, set := range m {
+			if len(set) < minSize {
+				continue
+			}
+			c := Cluster{Reason: reason, Key: k, Positions: make([]string, 0, len(set))}
+			for p := range set {
+				c.Positions = append(c.Positions, p)
+			}
+			sort.Strings(c.Positions)
+			out = append(out, c)
+		}
+	}
+	collect(ReasonFunder, byFunder)
+	collect(ReasonAmount, byAmount)
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].Positions) != len(out[j].Positions) {
+			return len(out[i].Positions) > len(out[j].Positions)
+		}
+		if out[i].Reason != out[j].Reason {
+			return out[i].Reason < out[j].Reason
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
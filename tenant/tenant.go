@@ -0,0 +1,225 @@
+// Package tenant runs one checkout for several reward programs — fairflow
+// and partner programs — each with its own Notion databases, mappings and
+// values files. Every command takes --program; the program's block supplies
+// defaults for the command's flags, and the state DB keeps each program's
+// data in its own buckets (see state.Open). Without --program a command runs
+// for the original program exactly as before.
+//
+// config/programs.json:
+//
+//	{
+//	  "programs": {
+//	    "partner": {
+//	      "databaseId": "…",
+//	      "releasesDatabaseId": "…",
+//	      "mapping": "config/partner/notion_mappings.json",
+//	      "values": "../partner-infra/values.yaml",
+//	      "profiles": "config/partner/profiles.json",
+//	      "cadence": {"start": "2026-01-05T09:00", "period": "14d", "firstCycle": 1, "tz": "UTC+7"},
+//	      "env": {"SLACK_WEBHOOK_URL": "${PARTNER_SLACK_WEBHOOK_URL}"},
+//	      "commands": {"publish": {"to": "partner-bucket"}}
+//	    }
+//	  }
+//	}
+//
+// A program is applied before the --profile, so it can point --profiles at
+// its own environments. Flags given on the command line always win.
+package tenant
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KyberNetwork/fairflow-reward/schedule"
+)
+
+const DefaultPath = "config/programs.json"
+
+type Config struct {
+	Programs map[string]Program `json:"programs"`
+}
+
+type Program struct {
+	Name       string `json:"-"`
+	DatabaseID string `json:"databaseId,omitempty"`
+	// ReleasesDatabaseID is the "Cycle Releases" database notion-release
+	// writes to.
+	ReleasesDatabaseID string `json:"releasesDatabaseId,omitempty"`
+	Mapping            string `json:"mapping,omitempty"`
+	Values             string `json:"values,omitempty"`
+	// Profiles is the program's own profiles JSON, for every command that
+	// takes --profiles.
+	Profiles string `json:"profiles,omitempty"`
+	// Cadence is when the program's cycles are due, for every command that
+	// takes --schedule.
+	Cadence *Cadence `json:"cadence,omitempty"`
+	// Env is exported into the process before the command runs, as with a
+	// profile's.
+	Env map[string]string `json:"env,omitempty"`
+	// Commands holds per-command flag overrides: command -> flag -> value.
+	Commands map[string]map[string]string `json:"commands,omitempty"`
+}
+
+// Cadence is a program's release calendar: cycle FirstCycle goes out at
+// Start and one more every Period after.
+type Cadence struct {
+	// Start is a <date>[T<hh:mm>] in TZ.
+	Start string `json:"start"`
+	// Period is a number of days or weeks, "14d" or "2w".
+	Period string `json:"period"`
+	// FirstCycle is the cycle released at Start; 0 means 1.
+	FirstCycle int `json:"firstCycle,omitempty"`
+	// TZ is an IANA zone or an offset like UTC+7; default UTC.
+	TZ string `json:"tz,omitempty"`
+}
+
+// Spec is the cadence as --schedule, --schedule-tz and --schedule-anchor.
+func (c *Cadence) Spec() (expr, tz, anchor string) {
+	first := c.FirstCycle
+	if first == 0 {
+		first = 1
+	}
+	return "@every " + c.Period, c.TZ, fmt.Sprintf("%d@%s", first, c.Start)
+}
+
+// nameRe keeps program names usable as state bucket prefixes and
+// directory names.
+var nameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// flags returns flag name -> value for command. As with profiles, the typed
+// fields only feed the commands they were meant for.
+func (p *Program) flags(command string) map[string]string {
+	out := map[string]string{"profiles": p.Profiles}
+	if p.Cadence != nil {
+		out["schedule"], out["schedule-tz"], out["schedule-anchor"] = p.Cadence.Spec()
+	}
+	switch command {
+	case "notion-sync", "add-chain":
+		out["database-id"], out["mapping"] = p.DatabaseID, p.Mapping
+	case "notion-release":
+		out["database-id"] = p.ReleasesDatabaseID
+	case "preflight":
+		out["database-id"], out["values"] = p.DatabaseID, p.Values
+	case "update-kyber-applications", "programs", "serve", "emergency-pause":
+		out["values"] = p.Values
+	}
+	for k, v := range p.Commands[command] {
+		out[k] = v
+	}
+	return out
+}
+
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse programs: %w", err)
+	}
+	for _, name := range c.Names() {
+		if !nameRe.MatchString(name) {
+			return nil, fmt.Errorf("programs: %q is not a valid program name (lower-case letters, digits and dashes)", name)
+		}
+		if cd := c.Programs[name].Cadence; cd != nil {
+			if _, err := schedule.FromSpec(cd.Spec()); err != nil {
+				return nil, fmt.Errorf("programs: %s cadence: %w", name, err)
+			}
+		}
+	}
+	return &c, nil
+}
+
+func (c *Config) Names() []string {
+	out := make([]string, 0, len(c.Programs))
+	for n := range c.Programs {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type Selector struct {
+	name *string
+	path *string
+}
+
+// AddFlags registers --program and --programs on fs.
+func AddFlags(fs *flag.FlagSet) *Selector {
+	return &Selector{
+		name: fs.String("program", os.Getenv("FAIRFLOW_PROGRAM"), "reward program to run for (or env FAIRFLOW_PROGRAM; default: the original one)"),
+		path: fs.String("programs", DefaultPath, "programs JSON"),
+	}
+}
+
+var (
+	mu      sync.Mutex
+	current string
+)
+
+// Apply activates the selected program after fs has been parsed and reports
+// it on stderr. Without --program it does nothing and returns nil.
+func (s *Selector) Apply(fs *flag.FlagSet, command string) (*Program, error) {
+	return s.apply(fs, command, os.Stderr)
+}
+
+func (s *Selector) apply(fs *flag.FlagSet, command string, log io.Writer) (*Program, error) {
+	name := *s.name
+	if name == "" {
+		return nil, nil
+	}
+	cfg, err := Load(*s.path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("program %q requested but %s does not exist", name, *s.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, ok := cfg.Programs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown program %q (known: %s)", name, strings.Join(cfg.Names(), ", "))
+	}
+	p.Name = name
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	applied := make([]string, 0)
+	for k, v := range p.flags(command) {
+		if v == "" || set[k] || fs.Lookup(k) == nil {
+			continue
+		}
+		if err := fs.Set(k, v); err != nil {
+			return nil, fmt.Errorf("program %s: --%s: %w", name, k, err)
+		}
+		applied = append(applied, "--"+k+"="+v)
+	}
+	sort.Strings(applied)
+	for k, v := range p.Env {
+		os.Setenv(k, os.ExpandEnv(v))
+	}
+	mu.Lock()
+	current = name
+	mu.Unlock()
+	fmt.Fprintf(log, "Active program: %s", name)
+	if len(applied) > 0 {
+		fmt.Fprintf(log, " (%s)", strings.Join(applied, " "))
+	}
+	fmt.Fprintln(log)
+	return &p, nil
+}
+
+// Current is the name of the program Apply activated, or "" for the
+// original one.
+func Current() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
@@ -0,0 +1,169 @@
+// Package tenderly simulates transactions on Tenderly before they are
+// proposed to signers, so a batch comes with a link showing that it
+// succeeds and emits the events it should.
+package tenderly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	DefaultConfigPath = "config/tenderly.json"
+	apiBase           = "https://api.tenderly.co/api/v1"
+	dashboardBase     = "https://dashboard.tenderly.co"
+)
+
+// Config is the Tenderly project simulations are saved to. ${VAR} in the
+// access key is expanded from the environment, which keeps the key out of
+// the file; TENDERLY_ACCESS_KEY is used when the file sets none.
+type Config struct {
+	Account   string `json:"account"`
+	Project   string `json:"project"`
+	AccessKey string `json:"accessKey"`
+
+	http *http.Client
+}
+
+// LoadConfig reads the Tenderly config; a missing file is a config without
+// a project, which Enabled reports.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{}
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, fmt.Errorf("parse tenderly config: %w", err)
+		}
+	}
+	c.AccessKey = os.ExpandEnv(c.AccessKey)
+	if c.AccessKey == "" {
+		c.AccessKey = os.Getenv("TENDERLY_ACCESS_KEY")
+	}
+	c.http = &http.Client{Timeout: 60 * time.Second}
+	return c, nil
+}
+
+// Enabled reports whether c names a project and has a key to reach it.
+func (c *Config) Enabled() bool {
+	return c.Account != "" && c.Project != "" && c.AccessKey != ""
+}
+
+// Tx is a call to simulate. Value and Data are as in a Safe batch: a
+// decimal wei amount and 0x-prefixed calldata.
+type Tx struct {
+	ChainID string
+	From    string
+	To      string
+	Value   string
+	Data    string
+}
+
+// Result is a saved simulation.
+type Result struct {
+	ID      string
+	URL     string
+	Success bool
+	// Error is the revert reason of a failed simulation.
+	Error string
+	// Events are the names of the logs emitted, in order; Tenderly names
+	// only those of contracts it has ABIs for.
+	Events []string
+}
+
+// Emitted reports whether the simulation emitted event name.
+func (r *Result) Emitted(name string) bool {
+	return slices.Contains(r.Events, name)
+}
+
+// Summary is a one-line account of r for batch descriptions and PR bodies.
+func (r *Result) Summary() string {
+	status := "succeeded"
+	if !r.Success {
+		status = "reverted"
+		if r.Error != "" {
+			status += ": " + r.Error
+		}
+	}
+	return fmt.Sprintf("Tenderly simulation %s %s", status, r.URL)
+}
+
+type simulateResp struct {
+	Simulation struct {
+		ID     string `json:"id"`
+		Status bool   `json:"status"`
+	} `json:"simulation"`
+	Transaction struct {
+		ErrorMessage    string `json:"error_message"`
+		TransactionInfo struct {
+			Logs []struct {
+				Name string `json:"name"`
+			} `json:"logs"`
+		} `json:"transaction_info"`
+	} `json:"transaction"`
+}
+
+// Simulate runs tx against the latest block of its chain and saves the
+// simulation, failed or not, so its link can be shared with signers.
+func (c *Config) Simulate(ctx context.Context, tx Tx) (*Result, error) {
+	if !c.Enabled() {
+		return nil, errors.New("tenderly: no account, project and access key configured")
+	}
+	body, err := json.Marshal(map[string]any{
+		"network_id":      tx.ChainID,
+		"from":            tx.From,
+		"to":              tx.To,
+		"input":           tx.Data,
+		"value":           tx.Value,
+		"save":            true,
+		"save_if_fails":   true,
+		"simulation_type": "full",
+	})
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/account/%s/project/%s/simulate", apiBase, c.Account, c.Project)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Access-Key", c.AccessKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tenderly simulate: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tenderly simulate: HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(b))
+	}
+	var sr simulateResp
+	if err := json.Unmarshal(b, &sr); err != nil {
+		return nil, fmt.Errorf("tenderly simulate: %w", err)
+	}
+	r := &Result{
+		ID:      sr.Simulation.ID,
+		URL:     fmt.Sprintf("%s/%s/%s/simulator/%s", dashboardBase, c.Account, c.Project, sr.Simulation.ID),
+		Success: sr.Simulation.Status,
+		Error:   sr.Transaction.ErrorMessage,
+	}
+	for _, l := range sr.Transaction.TransactionInfo.Logs {
+		r.Events = append(r.Events, l.Name)
+	}
+	return r, nil
+}
@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// Transport wraps base (nil = http.DefaultTransport) so every request gets a
+// client span named after its method and host. Trace headers are not sent to
+// third-party APIs.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base}
+}
+
+type roundTripper struct{ base http.RoundTripper }
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := start(req.Context(), req.Method+" "+req.URL.Host, kindClient, []any{
+		"http.request.method", req.Method,
+		"server.address", req.URL.Host,
+		"url.path", req.URL.Path,
+	})
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		span.SetAttrs("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			span.End(errStatus(resp.Status))
+			return resp, nil
+		}
+	}
+	span.End(err)
+	return resp, err
+}
+
+type errStatus string
+
+func (e errStatus) Error() string { return string(e) }
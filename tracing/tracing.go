@@ -0,0 +1,260 @@
+// Package tracing records OpenTelemetry spans for the release pipeline and
+// exports them over OTLP/HTTP (JSON encoding), without pulling in the SDK.
+//
+// Export is enabled when OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set; otherwise every call is a cheap
+// no-op. Each command is its own process, so a W3C TRACEPARENT in the
+// environment is adopted as the parent: a CI job that sets it once gets
+// sync, verify and publish in a single trace.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type spanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+type Span struct {
+	name   string
+	kind   int
+	sc     spanContext
+	parent [8]byte
+	start  time.Time
+	end    time.Time
+	attrs  map[string]any
+	err    error
+	ended  bool
+	mu     sync.Mutex
+}
+
+const (
+	kindInternal = 1
+	kindClient   = 3
+)
+
+type ctxKey struct{}
+
+var (
+	mu       sync.Mutex
+	enabled  bool
+	endpoint string
+	headers  http.Header
+	service  string
+	envRoot  *spanContext
+	pending  []*Span
+)
+
+const flushAt = 512
+
+// Init configures export for a command. Call Shutdown (or Flush before
+// os.Exit) so buffered spans are sent.
+func Init(serviceName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	service = firstNonEmpty(os.Getenv("OTEL_SERVICE_NAME"), serviceName)
+	endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	enabled = endpoint != "" && os.Getenv("OTEL_SDK_DISABLED") != "true"
+	headers = make(http.Header)
+	for _, kv := range strings.Split(firstNonEmpty(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"), os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			headers.Set(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	if sc, ok := parseTraceparent(os.Getenv("TRACEPARENT")); ok {
+		envRoot = &sc
+	}
+}
+
+// Start begins a span as a child of the span in ctx (or of TRACEPARENT).
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	return start(ctx, name, kindInternal, attrs)
+}
+
+func start(ctx context.Context, name string, kind int, attrs []any) (context.Context, *Span) {
+	s := &Span{name: name, kind: kind, start: time.Now(), attrs: make(map[string]any)}
+	if p, ok := ctx.Value(ctxKey{}).(*Span); ok && p != nil {
+		s.sc.TraceID, s.parent = p.sc.TraceID, p.sc.SpanID
+	} else if envRoot != nil {
+		s.sc.TraceID, s.parent = envRoot.TraceID, envRoot.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+	}
+	rand.Read(s.sc.SpanID[:])
+	s.SetAttrs(attrs...)
+	return context.WithValue(ctx, ctxKey{}, s), s
+}
+
+// SetAttrs takes alternating key, value pairs.
+func (s *Span) SetAttrs(kv ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok {
+			s.attrs[k] = kv[i+1]
+		}
+	}
+}
+
+// End finishes the span, marking it failed if err is non-nil. It is safe to
+// call more than once; only the first call counts.
+func (s *Span) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end, s.err = true, time.Now(), err
+	s.mu.Unlock()
+
+	mu.Lock()
+	if !enabled {
+		mu.Unlock()
+		return
+	}
+	pending = append(pending, s)
+	var batch []*Span
+	if len(pending) >= flushAt {
+		batch, pending = pending, nil
+	}
+	mu.Unlock()
+	if batch != nil {
+		export(batch)
+	}
+}
+
+// Traceparent renders the span in ctx as a W3C traceparent header, for
+// handing the trace to child processes.
+func Traceparent(ctx context.Context) string {
+	s, ok := ctx.Value(ctxKey{}).(*Span)
+	if !ok || s == nil {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(s.sc.TraceID[:]) + "-" + hex.EncodeToString(s.sc.SpanID[:]) + "-01"
+}
+
+// Flush exports buffered spans.
+func Flush() {
+	mu.Lock()
+	batch := pending
+	pending = nil
+	mu.Unlock()
+	if len(batch) > 0 {
+		export(batch)
+	}
+}
+
+func parseTraceparent(v string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return sc, false
+	}
+	t, err1 := hex.DecodeString(parts[1])
+	s, err2 := hex.DecodeString(parts[2])
+	if err1 != nil || err2 != nil {
+		return sc, false
+	}
+	copy(sc.TraceID[:], t)
+	copy(sc.SpanID[:], s)
+	return sc, true
+}
+
+func export(batch []*Span) {
+	spans := make([]map[string]any, 0, len(batch))
+	for _, s := range batch {
+		j := map[string]any{
+			"traceId":           hex.EncodeToString(s.sc.TraceID[:]),
+			"spanId":            hex.EncodeToString(s.sc.SpanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attributes(s.attrs),
+			"status":            map[string]any{"code": 1},
+		}
+		if s.parent != ([8]byte{}) {
+			j["parentSpanId"] = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != nil {
+			j["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+		}
+		spans = append(spans, j)
+	}
+	body := map[string]any{"resourceSpans": []any{map[string]any{
+		"resource":   map[string]any{"attributes": attributes(map[string]any{"service.name": service})},
+		"scopeSpans": []any{map[string]any{"scope": map[string]any{"name": "github.com/KyberNetwork/fairflow-reward"}, "spans": spans}},
+	}}}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return
+	}
+	for k, vs := range headers {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Use a bare client so exports are not traced themselves.
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tracing: export:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintln(os.Stderr, "tracing: export:", resp.Status)
+	}
+}
+
+func attributes(m map[string]any) []any {
+	out := make([]any, 0, len(m))
+	for k, v := range m {
+		var val map[string]any
+		switch x := v.(type) {
+		case string:
+			val = map[string]any{"stringValue": x}
+		case bool:
+			val = map[string]any{"boolValue": x}
+		case int:
+			val = map[string]any{"intValue": strconv.Itoa(x)}
+		case int64:
+			val = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			val = map[string]any{"doubleValue": x}
+		default:
+			val = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, map[string]any{"key": k, "value": val})
+	}
+	return out
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package tracker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Jira uses the REST v2 API with basic auth (account email + API token),
+// which takes plain-text descriptions and comments.
+type Jira struct {
+	BaseURL   string
+	Email     string
+	Token     string
+	Project   string
+	IssueType string
+}
+
+func (j *Jira) Name() string { return "jira" }
+
+func (j *Jira) header() http.Header {
+	auth := base64.StdEncoding.EncodeToString([]byte(j.Email + ":" + j.Token))
+	return http.Header{"Authorization": {"Basic " + auth}}
+}
+
+func (j *Jira) url(path string) string {
+	return strings.TrimRight(j.BaseURL, "/") + "/rest/api/2" + path
+}
+
+func (j *Jira) Create(ctx context.Context, title, description string) (Issue, error) {
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+	var out struct {
+		Key string `json:"key"`
+	}
+	in := map[string]any{"fields": map[string]any{
+		"project":     map[string]any{"key": j.Project},
+		"summary":     title,
+		"description": description,
+		"issuetype":   map[string]any{"name": issueType},
+	}}
+	if err := doJSON(ctx, "POST", j.url("/issue"), j.header(), in, &out); err != nil {
+		return Issue{}, err
+	}
+	return Issue{ID: out.Key, Key: out.Key, URL: strings.TrimRight(j.BaseURL, "/") + "/browse/" + out.Key}, nil
+}
+
+func (j *Jira) Comment(ctx context.Context, issue Issue, body string) error {
+	return doJSON(ctx, "POST", j.url("/issue/"+issue.ID+"/comment"), j.header(), map[string]any{"body": body}, nil)
+}
+
+func (j *Jira) Transition(ctx context.Context, issue Issue, state string) error {
+	var out struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := doJSON(ctx, "GET", j.url("/issue/"+issue.ID+"/transitions"), j.header(), nil, &out); err != nil {
+		return err
+	}
+	for _, t := range out.Transitions {
+		if strings.EqualFold(t.To.Name, state) || strings.EqualFold(t.Name, state) {
+			return doJSON(ctx, "POST", j.url("/issue/"+issue.ID+"/transitions"), j.header(),
+				map[string]any{"transition": map[string]any{"id": t.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("jira: %s has no transition to %q", issue.Key, state)
+}
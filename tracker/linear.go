@@ -0,0 +1,84 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const linearURL = "https://api.linear.app/graphql"
+
+type Linear struct {
+	APIKey string
+	TeamID string
+}
+
+func (l *Linear) Name() string { return "linear" }
+
+func (l *Linear) query(ctx context.Context, q string, vars map[string]any, out any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	h := http.Header{"Authorization": {l.APIKey}}
+	if err := doJSON(ctx, "POST", linearURL, h, map[string]any{"query": q, "variables": vars}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("linear: %s", resp.Errors[0].Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+func (l *Linear) Create(ctx context.Context, title, description string) (Issue, error) {
+	var out struct {
+		IssueCreate struct {
+			Issue struct {
+				ID         string `json:"id"`
+				Identifier string `json:"identifier"`
+				URL        string `json:"url"`
+			} `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	err := l.query(ctx, `mutation($input: IssueCreateInput!) {
+  issueCreate(input: $input) { issue { id identifier url } }
+}`, map[string]any{"input": map[string]any{"teamId": l.TeamID, "title": title, "description": description}}, &out)
+	if err != nil {
+		return Issue{}, err
+	}
+	i := out.IssueCreate.Issue
+	return Issue{ID: i.ID, Key: i.Identifier, URL: i.URL}, nil
+}
+
+func (l *Linear) Comment(ctx context.Context, issue Issue, body string) error {
+	return l.query(ctx, `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`,
+		map[string]any{"input": map[string]any{"issueId": issue.ID, "body": body}}, nil)
+}
+
+func (l *Linear) Transition(ctx context.Context, issue Issue, state string) error {
+	var states struct {
+		WorkflowStates struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"workflowStates"`
+	}
+	err := l.query(ctx, `query($team: ID!, $name: String!) {
+  workflowStates(filter: { team: { id: { eq: $team } }, name: { eq: $name } }) { nodes { id } }
+}`, map[string]any{"team": l.TeamID, "name": state}, &states)
+	if err != nil {
+		return err
+	}
+	if len(states.WorkflowStates.Nodes) == 0 {
+		return errors.New("linear: team has no workflow state " + state)
+	}
+	return l.query(ctx, `mutation($id: String!, $input: IssueUpdateInput!) { issueUpdate(id: $id, input: $input) { success } }`,
+		map[string]any{"id": issue.ID, "input": map[string]any{"stateId": states.WorkflowStates.Nodes[0].ID}}, nil)
+}
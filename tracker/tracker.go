@@ -0,0 +1,65 @@
+// Package tracker opens and closes the operational ticket that follows each
+// cycle release in Linear or Jira.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Issue struct {
+	// ID is the tracker's key for API calls (Linear UUID or Jira issue key).
+	ID    string `json:"id"`
+	Key   string `json:"key"`
+	URL   string `json:"url"`
+	Cycle int    `json:"cycle"`
+}
+
+type Tracker interface {
+	Create(ctx context.Context, title, description string) (Issue, error)
+	Comment(ctx context.Context, issue Issue, body string) error
+	// Transition moves the issue to the named workflow state (e.g. "Done").
+	Transition(ctx context.Context, issue Issue, state string) error
+	Name() string
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// doJSON sends in as JSON and decodes the response into out (if non-nil).
+func doJSON(ctx context.Context, method, url string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(b))
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
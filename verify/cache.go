@@ -0,0 +1,99 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+const cacheBucket = "verify"
+
+// cacheVersion is bumped whenever the cached form changes, so old results
+// stop matching. Rule changes need no bump: results are also keyed on
+// binaryDigest.
+const cacheVersion = "3"
+
+// binaryDigest is the sha256 of the running executable, which holds the rule
+// code, built-in and plugin alike: a build whose rules differ in any way
+// never reuses another build's results. When the executable cannot be read,
+// it is unique to the run so nothing is reused.
+var binaryDigest = sync.OnceValue(func() string {
+	if exe, err := os.Executable(); err == nil {
+		if d, err := Digest(exe); err == nil {
+			return d
+		}
+	}
+	return "unreadable-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+})
+
+// Cache remembers findings per file content (sha256) and active rule set, so
+// unchanged files are not re-verified.
+type Cache struct {
+	db *state.DB
+}
+
+func NewCache(db *state.DB) *Cache { return &Cache{db: db} }
+
+type cached struct {
+	Rules      string    `json:"rules"`
+	Findings   []Finding `json:"findings"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// Digest is the sha256 of the file at path.
+func Digest(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rulesKey identifies the rules a run evaluates and how findings count.
+func rulesKey(opt Options) string {
+	ids := make([]string, 0, len(rules))
+	for _, r := range rules {
+		if !opt.Disabled[r.ID()] {
+			ids = append(ids, r.ID())
+		}
+	}
+	sort.Strings(ids)
+	return cacheVersion + ":" + binaryDigest() + ":" + strings.Join(ids, ",") + ":" + opt.key()
+}
+
+// Lookup returns the findings stored for digest, relabelled with name.
+func (c *Cache) Lookup(digest, name string, opt Options) ([]Finding, bool) {
+	var e cached
+	if ok, err := c.db.Get(cacheBucket, digest, &e); !ok || err != nil || e.Rules != rulesKey(opt) {
+		return nil, false
+	}
+	for i := range e.Findings {
+		e.Findings[i].File = name
+	}
+	return e.Findings, true
+}
+
+func (c *Cache) Store(digest string, opt Options, fs []Finding) error {
+	return c.db.Put(cacheBucket, digest, cached{Rules: rulesKey(opt), Findings: fs, VerifiedAt: time.Now().UTC()})
+}
+
+// Verified reports whether a file with digest has been verified, under any
+// rules.
+func (c *Cache) Verified(digest string) bool {
+	var e cached
+	ok, err := c.db.Get(cacheBucket, digest, &e)
+	return ok && err == nil
+}
@@ -0,0 +1,32 @@
+package verify_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/state"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+func TestCacheKeyedOnRules(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := verify.NewCache(db)
+	fs := []verify.Finding{{Rule: "token-order", Severity: verify.SeverityWarning, File: "a.json", Message: "unsorted"}}
+	if err := c.Store("d1", verify.Options{}, fs); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.Lookup("d1", "b.json", verify.Options{}); !ok || len(got) != 1 || got[0].File != "b.json" {
+		t.Errorf("same rules: got %v, %v", got, ok)
+	}
+	for name, opt := range map[string]verify.Options{
+		"disabled rule": {Disabled: map[string]bool{"token-order": true}},
+		"other mode":    {Mode: verify.ModeStrict},
+	} {
+		if _, ok := c.Lookup("d1", "b.json", opt); ok {
+			t.Errorf("%s: reused findings stored under other rules", name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package verify
+
+// dirRules check a cycle directory as a whole rather than one file, so the
+// verify command runs them itself instead of through Run. They share the
+// rule ID space: --disable, the modes and the config apply to them as to
+// file rules.
+var dirRules = []Rule{
+	NewRule("manifest", "each file's sha256 matches the cycle manifest", SeverityError, checkDir),
+	NewRule("shards", "a file's shards and shard index match it", SeverityError, checkDir),
+	NewRule("bloom", "a file's bloom filter sidecar still matches it", SeverityError, checkDir),
+	NewRule("stats", "the cycle stats file matches the cycle's files", SeverityError, checkDir),
+}
+
+// checkDir is the Check of a directory rule, which Run never calls.
+func checkDir(*Target, Reporter) {}
+
+// DirRules returns the directory rules.
+func DirRules() []Rule {
+	return append([]Rule(nil), dirRules...)
+}
+
+// DirFinding is a finding of directory rule id about file under opt. The
+// caller skips rules opt disables.
+func (opt Options) DirFinding(id, file, msg string) Finding {
+	return Finding{Rule: id, Severity: opt.Severity(id, ruleSeverity(id)), File: file, Message: msg}
+}
@@ -0,0 +1,116 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Mode sets how strictly findings count. The release pipeline verifies in
+// ModeStrict; ad-hoc analysis of partner or historical files may use
+// ModePermissive.
+type Mode string
+
+const (
+	// ModeStrict makes every warning an error, known-fields included, so
+	// unknown JSON fields are rejected.
+	ModeStrict Mode = "strict"
+	// ModeStandard keeps each rule's own severity.
+	ModeStandard Mode = "standard"
+	// ModePermissive reports every finding as a warning.
+	ModePermissive Mode = "permissive"
+)
+
+var modes = []Mode{ModeStrict, ModeStandard, ModePermissive}
+
+func ParseMode(s string) (Mode, error) {
+	for _, m := range modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown verification mode %q (known: strict, standard, permissive)", s)
+}
+
+// DefaultConfigPath holds per-mode rule overrides.
+const DefaultConfigPath = "config/verify.json"
+
+// Config is mode -> rule ID -> "error", "warning" or "off", overriding what
+// the mode would otherwise make of the rule:
+//
+//	{"strict": {"token-order": "warning"}, "permissive": {"proof-valid": "error"}}
+type Config map[Mode]map[string]string
+
+// LoadConfig reads the overrides at path; a missing file has none.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for m := range c {
+		if _, err := ParseMode(string(m)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// Options returns the options for a run in mode, with disabled rules on top
+// of those the config turns off.
+func (c Config) Options(mode Mode, disabled map[string]bool) (Options, error) {
+	opt := Options{Mode: mode, Disabled: make(map[string]bool), Severities: make(map[string]Severity)}
+	for id := range disabled {
+		opt.Disabled[id] = true
+	}
+	ids := make([]string, 0, len(c[mode]))
+	for id := range c[mode] {
+		ids = append(ids, id)
+	}
+	if _, err := ParseRuleList(strings.Join(ids, ",")); err != nil {
+		return Options{}, fmt.Errorf("%s mode: %w", mode, err)
+	}
+	for id, v := range c[mode] {
+		switch v {
+		case "off":
+			opt.Disabled[id] = true
+		case string(SeverityError), string(SeverityWarning):
+			opt.Severities[id] = Severity(v)
+		default:
+			return Options{}, fmt.Errorf("%s mode: rule %s: want error, warning or off, got %q", mode, id, v)
+		}
+	}
+	return opt, nil
+}
+
+// Severity is what a finding of rule id counts as under opt.
+func (opt Options) Severity(id string, own Severity) Severity {
+	if s, ok := opt.Severities[id]; ok {
+		return s
+	}
+	switch opt.Mode {
+	case ModeStrict:
+		return SeverityError
+	case ModePermissive:
+		return SeverityWarning
+	}
+	return own
+}
+
+// key identifies the mode and overrides for the cache.
+func (opt Options) key() string {
+	ids := make([]string, 0, len(opt.Severities))
+	for id, s := range opt.Severities {
+		ids = append(ids, id+"="+string(s))
+	}
+	sort.Strings(ids)
+	return string(opt.Mode) + ":" + strings.Join(ids, ",")
+}
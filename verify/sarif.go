@@ -0,0 +1,154 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SARIF 2.1.0, the subset GitHub code scanning reads to annotate a PR.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine int `json:"startLine"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// WriteSARIF writes findings as a SARIF log to out. Findings name files by
+// base name, so files lists the paths verified, relative to the repository
+// root; a recipient finding points at the line its leaf or claim starts on.
+func WriteSARIF(out string, findings []Finding, files []string) error {
+	byName := make(map[string]string, len(files))
+	for _, p := range files {
+		byName[filepath.Base(p)] = p
+	}
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "fairflow-verify", Rules: make([]sarifRule, 0)}}, Results: make([]sarifResult, 0, len(findings))}
+	ruleIndex := make(map[string]int)
+	addRule := func(id, description string, s Severity) {
+		r := sarifRule{ID: id, ShortDescription: sarifMessage{Text: description}}
+		r.DefaultConfiguration.Level = string(s)
+		ruleIndex[id] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+	}
+	for _, r := range rules {
+		addRule(r.ID(), r.Description(), r.Severity())
+	}
+	lines := make(map[string]map[string]int)
+	for _, f := range findings {
+		if _, ok := ruleIndex[f.Rule]; !ok {
+			addRule(f.Rule, f.Rule, SeverityError)
+		}
+		path, ok := byName[f.File]
+		if !ok {
+			path = f.File
+		}
+		if _, ok := lines[path]; !ok {
+			lines[path] = recipientLines(path)
+		}
+		res := sarifResult{RuleID: f.Rule, RuleIndex: ruleIndex[f.Rule], Level: string(f.Severity), Message: sarifMessage{Text: f.Message}}
+		if f.Recipient != "" {
+			res.Message.Text = f.Recipient + ": " + f.Message
+		}
+		if f.Notion != "" {
+			res.Message.Text += " (" + f.Notion + ")"
+		}
+		if f.Owner != "" {
+			res.Message.Text += " [owner: " + f.Owner + "]"
+		}
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(filepath.Clean(path))
+		loc.PhysicalLocation.Region.StartLine = max(lines[path][strings.ToLower(f.Recipient)], 1)
+		res.Locations = []sarifLocation{loc}
+		run.Results = append(run.Results, res)
+	}
+	b, err := json.MarshalIndent(sarifLog{Schema: "https://json.schemastore.org/sarif-2.1.0.json", Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, append(b, '\n'), 0o644)
+}
+
+var (
+	addrLineRe  = regexp.MustCompile(`"erc721Addr":\s*"([^"]+)"`)
+	idLineRe    = regexp.MustCompile(`"erc721Id":\s*"([^"]+)"`)
+	claimLineRe = regexp.MustCompile(`^\s*"(0x[0-9a-fA-F]{40})":\s*\{`)
+)
+
+// recipientLines maps each lowercased leaf key (erc721Addr:erc721Id), or
+// claims account, to the 1-based line it starts on in an indented file.
+// Unreadable files map nothing and findings fall back to line 1.
+func recipientLines(path string) map[string]int {
+	out := make(map[string]int)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return out
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), len(b)+1)
+	addr, addrLine := "", 0
+	for n := 1; sc.Scan(); n++ {
+		l := sc.Text()
+		if m := addrLineRe.FindStringSubmatch(l); m != nil {
+			addr, addrLine = strings.ToLower(m[1]), n
+		}
+		if m := idLineRe.FindStringSubmatch(l); m != nil && addr != "" {
+			key := addr + ":" + m[1]
+			if _, dup := out[key]; !dup {
+				out[key] = addrLine
+			}
+			addr = ""
+		}
+		if m := claimLineRe.FindStringSubmatch(l); m != nil {
+			out[strings.ToLower(m[1])] = n
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package verify
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sort"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+)
+
+// RunUniswap checks a merkle-distributor claims file. It has no stored tree,
+// so only the rules that apply to it run: proof-valid, index-continuity,
+// totals-match and, given the file's raw JSON, known-fields, under the same
+// IDs as for cycle files.
+func RunUniswap(path string, c *cycle.UniswapClaims, raw []byte, opt Options) []Finding {
+	out := make([]Finding, 0)
+	report := func(rule string, recipient, msg string) {
+		if !opt.Disabled[rule] {
+			out = append(out, Finding{Rule: rule, Severity: opt.Severity(rule, ruleSeverity(rule)), File: filepath.Base(path), Recipient: recipient, Message: msg})
+		}
+	}
+	accounts := make([]string, 0, len(c.Claims))
+	for a := range c.Claims {
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return c.Claims[accounts[i]].Index < c.Claims[accounts[j]].Index })
+
+	errs := make([]error, len(accounts))
+	par.Range(len(accounts), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			errs[i] = c.VerifyClaim(accounts[i], c.Claims[accounts[i]])
+		}
+	})
+	seen := make(map[uint64]string, len(accounts))
+	total := new(big.Int)
+	for i, a := range accounts {
+		if errs[i] != nil {
+			report("proof-valid", a, errs[i].Error())
+		}
+		cl := c.Claims[a]
+		if prev, dup := seen[cl.Index]; dup {
+			report("index-continuity", a, fmt.Sprintf("index %d already used by %s", cl.Index, prev))
+		} else if cl.Index >= uint64(len(accounts)) {
+			report("index-continuity", a, fmt.Sprintf("index %d is past the %d claims", cl.Index, len(accounts)))
+		}
+		seen[cl.Index] = a
+		if amount, err := cycle.ParseUniswapAmount(cl.Amount); err == nil {
+			total.Add(total, amount)
+		}
+	}
+	if raw != nil {
+		if err := cycle.CheckFields(raw); err != nil {
+			report("known-fields", "", err.Error())
+		}
+	}
+	declared, err := cycle.ParseUniswapAmount(c.TokenTotal)
+	switch {
+	case err != nil:
+		report("totals-match", "", fmt.Sprintf("tokenTotal: %v", err))
+	case declared.Cmp(total) != 0:
+		report("totals-match", "", fmt.Sprintf("tokenTotal is %s, claims sum to %s", declared, total))
+	}
+	return out
+}
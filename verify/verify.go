@@ -0,0 +1,460 @@
+// Package verify lints cycle merkle files. Every check is a rule with a
+// stable ID so it can be toggled per run and referenced in reports.
+//
+// Teams can compile in their own rules without forking: implement Rule in a
+// package that calls Register from init, and blank-import it from
+// cmd/verify/plugins.go.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/internal/par"
+	"github.com/KyberNetwork/fairflow-reward/merkle"
+	"github.com/KyberNetwork/fairflow-reward/tracing"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	// Recipient is the leaf key (erc721Addr:erc721Id) when the finding is
+	// about a single recipient.
+	Recipient string `json:"recipient,omitempty"`
+	Message   string `json:"message"`
+	// Notion is the URL of the Notion row the file was synced from, when
+	// the cycle manifest records it.
+	Notion string `json:"notion,omitempty"`
+	// Owner is who the Notion row assigns the file to.
+	Owner string `json:"owner,omitempty"`
+}
+
+func (f Finding) String() string {
+	loc := f.File
+	if f.Recipient != "" {
+		loc += " " + f.Recipient
+	}
+	msg := fmt.Sprintf("%s [%s] %s: %s", strings.ToUpper(string(f.Severity)), f.Rule, loc, f.Message)
+	if f.Notion != "" {
+		msg += " (" + f.Notion + ")"
+	}
+	if f.Owner != "" {
+		msg += " [owner: " + f.Owner + "]"
+	}
+	return msg
+}
+
+// Target is a parsed file shared by all rules.
+type Target struct {
+	Path string
+	File *cycle.File
+	// Tree is nil when the stored tree is malformed; tree-structure reports why.
+	Tree    *merkle.Tree
+	TreeErr error
+	Proofs  [][]merkle.Hash
+	// Raw is the file's JSON when the caller kept it; known-fields needs it.
+	// It may be a mapping the caller releases once the rules have run.
+	Raw []byte
+
+	// hashes are the userDatas' leaves hashed with the tree's scheme, once
+	// a rule needs them; hashErrs says why a leaf could not be.
+	hashes   []merkle.Hash
+	hashErrs []error
+}
+
+func NewTarget(path string, f *cycle.File) *Target {
+	t := &Target{Path: path, File: f, Proofs: make([][]merkle.Hash, len(f.UserDatas))}
+	t.Tree, t.TreeErr = f.ParseTree()
+	par.Range(len(f.UserDatas), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			if p, err := cycle.ParseProof(f.UserDatas[i].Proof); err == nil {
+				t.Proofs[i] = p
+			}
+		}
+	})
+	return t
+}
+
+func (t *Target) Name() string { return filepath.Base(t.Path) }
+
+// leafHashes hashes every userData's leaf, so rules check the leaf the file
+// states rather than whichever one its proof happens to lead from.
+func (t *Target) leafHashes() ([]merkle.Hash, []error) {
+	if t.hashes != nil {
+		return t.hashes, t.hashErrs
+	}
+	t.hashes = make([]merkle.Hash, len(t.File.UserDatas))
+	t.hashErrs = make([]error, len(t.File.UserDatas))
+	par.Range(len(t.hashes), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			t.hashes[i], t.hashErrs[i] = cycle.SchemeLeafHash(t.Tree.Scheme(), t.File.UserDatas[i].Leaf)
+		}
+	})
+	return t.hashes, t.hashErrs
+}
+
+// Reporter records a finding; recipient is empty for file-level findings.
+type Reporter func(recipient, msg string)
+
+type Rule interface {
+	// ID is the stable, machine-readable identifier (kebab-case).
+	ID() string
+	Description() string
+	Severity() Severity
+	Check(t *Target, report Reporter)
+}
+
+type funcRule struct {
+	id          string
+	description string
+	severity    Severity
+	check       func(t *Target, report Reporter)
+}
+
+func (r funcRule) ID() string                       { return r.id }
+func (r funcRule) Description() string              { return r.description }
+func (r funcRule) Severity() Severity               { return r.severity }
+func (r funcRule) Check(t *Target, report Reporter) { r.check(t, report) }
+
+// NewRule adapts a check function to Rule.
+func NewRule(id, description string, severity Severity, check func(t *Target, report Reporter)) Rule {
+	return funcRule{id: id, description: description, severity: severity, check: check}
+}
+
+var rules []Rule
+
+// Register adds a rule after the built-in ones. It panics on a duplicate ID,
+// so conflicting plugins fail at startup rather than silently shadowing.
+func Register(r Rule) {
+	for _, existing := range append(AllRules(), dirRules...) {
+		if existing.ID() == r.ID() {
+			panic("verify: duplicate rule " + r.ID())
+		}
+	}
+	rules = append(rules, r)
+}
+
+// AllRules returns every registered rule in evaluation order.
+func AllRules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+func init() {
+	for _, r := range builtin {
+		Register(NewRule(r.id, r.description, r.severity, r.check))
+	}
+}
+
+var builtin = []funcRule{
+	{"tree-structure", "stored tree is a well-formed sorted-pair keccak tree", SeverityError, checkTreeStructure},
+	{"root-match", "root field equals tree[0]", SeverityError, checkRoot},
+	{"leaf-count", "tree has exactly one leaf per userData", SeverityError, checkLeafCount},
+	{"proof-valid", "every proof verifies against the root", SeverityError, checkProofs},
+	{"leaf-order", "tree leaves are sorted by hash as the contract's tree layout expects", SeverityError, checkLeafOrder},
+	{"index-continuity", "leaf indices are contiguous from 0 with no gaps or duplicates", SeverityError, checkIndexContinuity},
+	{"proof-depth", "proof lengths match leaf depth and differ by at most one", SeverityError, checkProofDepth},
+	{"token-order", "tokens within a leaf are unique and sorted", SeverityWarning, checkTokenOrder},
+	{"totals-match", "totalAmounts equals the sum of leaf amounts", SeverityError, checkTotals},
+	{"known-fields", "the JSON has no fields outside its format", SeverityWarning, checkKnownFields},
+}
+
+// Rules returns the known rule IDs in evaluation order, then the directory
+// rules'.
+func Rules() []string {
+	out := make([]string, 0, len(rules)+len(dirRules))
+	for _, r := range append(AllRules(), dirRules...) {
+		out = append(out, r.ID())
+	}
+	return out
+}
+
+func ruleSeverity(id string) Severity {
+	for _, r := range append(AllRules(), dirRules...) {
+		if r.ID() == id {
+			return r.Severity()
+		}
+	}
+	return SeverityError
+}
+
+type Options struct {
+	// Disabled rule IDs are skipped.
+	Disabled map[string]bool
+	// Mode and Severities (rule ID -> severity) set what findings count as;
+	// the zero Mode keeps each rule's own severity.
+	Mode       Mode
+	Severities map[string]Severity
+}
+
+// ParseRuleList validates a comma-separated rule ID list.
+func ParseRuleList(s string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	if s == "" {
+		return out, nil
+	}
+	known := make(map[string]bool)
+	for _, id := range Rules() {
+		known[id] = true
+	}
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !known[id] {
+			return nil, fmt.Errorf("unknown rule %q (known: %s)", id, strings.Join(Rules(), ", "))
+		}
+		out[id] = true
+	}
+	return out, nil
+}
+
+func Run(t *Target, opt Options) []Finding {
+	return RunContext(context.Background(), t, opt)
+}
+
+// RunContext is Run with a span per rule under the span in ctx.
+func RunContext(ctx context.Context, t *Target, opt Options) []Finding {
+	out := make([]Finding, 0)
+	for _, r := range rules {
+		if opt.Disabled[r.ID()] {
+			continue
+		}
+		_, span := tracing.Start(ctx, "rule "+r.ID(), "file", t.Name())
+		n := len(out)
+		r.Check(t, func(recipient, msg string) {
+			out = append(out, Finding{Rule: r.ID(), Severity: opt.Severity(r.ID(), r.Severity()), File: t.Name(), Recipient: recipient, Message: msg})
+		})
+		span.SetAttrs("findings", len(out)-n)
+		span.End(nil)
+	}
+	return out
+}
+
+func HasErrors(fs []Finding) bool {
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTreeStructure(t *Target, report Reporter) {
+	if t.TreeErr != nil {
+		report("", t.TreeErr.Error())
+	}
+}
+
+func checkRoot(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	if !strings.EqualFold(t.File.Root, t.Tree.Root().Hex()) {
+		report("", fmt.Sprintf("root %s does not match tree[0] %s", t.File.Root, t.Tree.Root().Hex()))
+	}
+}
+
+func checkLeafCount(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	if n := t.Tree.LeafCount(); n != len(t.File.UserDatas) {
+		report("", fmt.Sprintf("tree has %d leaves but file has %d userDatas", n, len(t.File.UserDatas)))
+	}
+}
+
+func checkProofs(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	hashes, errs := t.leafHashes()
+	// Hash in parallel, report in file order.
+	msgs := make([]string, len(t.File.UserDatas))
+	par.Range(len(msgs), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			p := t.Proofs[i]
+			switch {
+			case errs[i] != nil:
+				msgs[i] = errs[i].Error()
+			case p == nil && len(t.File.UserDatas[i].Proof) > 0:
+				msgs[i] = "proof contains malformed hashes"
+			case !t.Tree.Scheme().Verify(t.Tree.Root(), hashes[i], p):
+				msgs[i] = "proof does not verify against root"
+			}
+		}
+	})
+	// A file none of whose leaves verify was hashed some other way; one
+	// finding says so rather than one per recipient.
+	failed := 0
+	for _, msg := range msgs {
+		if msg != "" {
+			failed++
+		}
+	}
+	if failed > 1 && failed == len(msgs) {
+		report("", fmt.Sprintf("none of %d leaves verify: %v", failed, cycle.ErrLeafEncoding))
+		return
+	}
+	for i, msg := range msgs {
+		if msg != "" {
+			report(t.File.UserDatas[i].Leaf.Key(), msg)
+		}
+	}
+}
+
+func checkLeafOrder(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	leaves := t.Tree.Leaves()
+	if !sort.SliceIsSorted(leaves, func(i, j int) bool { return lessHash(leaves[i], leaves[j]) }) {
+		report("", "tree leaves are not sorted by hash")
+	}
+}
+
+// leafIndex maps each userData to the index of its hashed leaf (0 =
+// smallest hash), or -1 when the tree does not hold it.
+func (t *Target) leafIndex() []int {
+	out := make([]int, len(t.File.UserDatas))
+	idx := make(map[merkle.Hash]int, t.Tree.LeafCount())
+	for i, l := range t.Tree.Leaves() {
+		idx[l] = i
+	}
+	hashes, errs := t.leafHashes()
+	for i := range t.File.UserDatas {
+		out[i] = -1
+		if errs[i] != nil {
+			continue
+		}
+		if li, ok := idx[hashes[i]]; ok {
+			out[i] = li
+		}
+	}
+	return out
+}
+
+func checkIndexContinuity(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	index := t.leafIndex()
+	if slices.Max(append(index, -1)) < 0 {
+		return // no leaf is in the tree; proof-valid reports the file
+	}
+	seen := make(map[int]string, len(t.File.UserDatas))
+	for i, li := range index {
+		key := t.File.UserDatas[i].Leaf.Key()
+		if li < 0 {
+			continue // proof-valid reports these
+		}
+		if prev, dup := seen[li]; dup {
+			report(key, fmt.Sprintf("leaf index %d already used by %s", li, prev))
+			continue
+		}
+		seen[li] = key
+	}
+	missing := make([]int, 0)
+	for i := 0; i < t.Tree.LeafCount(); i++ {
+		if _, ok := seen[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		report("", fmt.Sprintf("%d leaf indices have no userData (first: %d)", len(missing), missing[0]))
+	}
+}
+
+func checkProofDepth(t *Target, report Reporter) {
+	if t.Tree == nil {
+		return
+	}
+	minD, maxD := -1, -1
+	hashes, errs := t.leafHashes()
+	for i, ud := range t.File.UserDatas {
+		d := len(ud.Proof)
+		if minD < 0 || d < minD {
+			minD = d
+		}
+		if d > maxD {
+			maxD = d
+		}
+		if errs[i] != nil {
+			continue
+		}
+		if want, err := t.Tree.Proof(hashes[i]); err == nil && len(want) != d {
+			report(ud.Leaf.Key(), fmt.Sprintf("proof length %d, leaf is at depth %d", d, len(want)))
+		}
+	}
+	if maxD-minD > 1 {
+		report("", fmt.Sprintf("proof lengths range from %d to %d", minD, maxD))
+	}
+}
+
+func checkTokenOrder(t *Target, report Reporter) {
+	for _, ud := range t.File.UserDatas {
+		toks := ud.Leaf.Tokens
+		for i := 1; i < len(toks); i++ {
+			a, b := strings.ToLower(toks[i-1]), strings.ToLower(toks[i])
+			if a == b {
+				report(ud.Leaf.Key(), fmt.Sprintf("duplicate token %s", b))
+			} else if a > b {
+				report(ud.Leaf.Key(), "tokens are not sorted")
+				break
+			}
+		}
+	}
+}
+
+func checkTotals(t *Target, report Reporter) {
+	sums, err := t.File.SumAmounts()
+	if err != nil {
+		report("", err.Error())
+		return
+	}
+	declared := make(map[string]string, len(t.File.TotalAmounts))
+	for tok, v := range t.File.TotalAmounts {
+		declared[strings.ToLower(tok)] = v
+	}
+	for tok, sum := range sums {
+		if declared[tok] != sum.String() {
+			report("", fmt.Sprintf("totalAmounts[%s] is %q, leaves sum to %s", tok, declared[tok], sum))
+		}
+	}
+	for tok, v := range declared {
+		if _, ok := sums[tok]; !ok && v != "0" {
+			report("", fmt.Sprintf("totalAmounts[%s] is %s but no leaf pays it", tok, v))
+		}
+	}
+}
+
+func checkKnownFields(t *Target, report Reporter) {
+	if t.Raw == nil {
+		return
+	}
+	if err := cycle.CheckFields(t.Raw); err != nil {
+		report("", err.Error())
+	}
+}
+
+func lessHash(a, b merkle.Hash) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
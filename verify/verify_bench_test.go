@@ -0,0 +1,27 @@
+package verify_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+func BenchmarkVerify(b *testing.B) {
+	for _, n := range fixtures.BenchSizes() {
+		b.Run(fmt.Sprintf("recipients=%d", n), func(b *testing.B) {
+			f, err := fixtures.Cached(n)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				fs := verify.Run(verify.NewTarget("bench.json", f), verify.Options{})
+				if verify.HasErrors(fs) {
+					b.Fatalf("fixture failed verification: %v", fs[0])
+				}
+			}
+		})
+	}
+}
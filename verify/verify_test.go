@@ -0,0 +1,83 @@
+package verify_test
+
+import (
+	"testing"
+
+	"github.com/KyberNetwork/fairflow-reward/cycle"
+	"github.com/KyberNetwork/fairflow-reward/fixtures"
+	"github.com/KyberNetwork/fairflow-reward/verify"
+)
+
+// A leaf edited after the tree was built keeps its old proof, which still
+// leads to the root from the old leaf; proof-valid must hash the leaf the
+// file states and reject it. Totals are fixed up to match the edit.
+func TestProofValidTamperedLeaf(t *testing.T) {
+	cases := map[string]func(l *cycle.Leaf){
+		"amount": func(l *cycle.Leaf) { l.Amounts[0] = "99999999999999999999999" },
+		"token":  func(l *cycle.Leaf) { l.Tokens[0] = "0x000000000000000000000000000000000000dead" },
+	}
+	for name, tamper := range cases {
+		f, err := fixtures.Generate(fixtures.Options{Recipients: 25, Seed: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(&f.UserDatas[3].Leaf)
+		sums, err := f.SumAmounts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.TotalAmounts = make(map[string]string, len(sums))
+		for tok, a := range sums {
+			f.TotalAmounts[tok] = a.String()
+		}
+
+		// The tree's old leaf is now no userData's: index-continuity sees it
+		// too.
+		want := map[string]string{"proof-valid": f.UserDatas[3].Leaf.Key(), "index-continuity": ""}
+		fs := verify.Run(verify.NewTarget("tampered.json", f), verify.Options{})
+		if len(fs) != len(want) {
+			t.Errorf("%s: got %d findings, want %d: %v", name, len(fs), len(want), fs)
+		}
+		for _, fd := range fs {
+			if rec, ok := want[fd.Rule]; !ok || fd.Recipient != rec || fd.Severity != verify.SeverityError {
+				t.Errorf("%s: unexpected finding %s", name, fd)
+			}
+		}
+	}
+}
+
+func TestProofValidUnknownEncoding(t *testing.T) {
+	f, err := cycle.Load("../56_LM_12.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := verify.Run(verify.NewTarget("56_LM_12.json", f), verify.Options{})
+	if len(fs) != 1 || fs[0].Rule != "proof-valid" || fs[0].Recipient != "" {
+		t.Fatalf("want one file-level proof-valid finding, got %v", fs)
+	}
+}
+
+func TestDirFindingSeverity(t *testing.T) {
+	cfg := verify.Config{verify.ModeStandard: {"manifest": "warning"}}
+	for _, c := range []struct {
+		mode verify.Mode
+		id   string
+		want verify.Severity
+	}{
+		{verify.ModeStrict, "manifest", verify.SeverityError},
+		{verify.ModeStandard, "manifest", verify.SeverityWarning},
+		{verify.ModeStandard, "shards", verify.SeverityError},
+		{verify.ModePermissive, "stats", verify.SeverityWarning},
+	} {
+		opt, err := cfg.Options(c.mode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := opt.DirFinding(c.id, "56_LM_12.json", "stale").Severity; got != c.want {
+			t.Errorf("%s in %s mode: got %s, want %s", c.id, c.mode, got, c.want)
+		}
+	}
+	if _, err := verify.ParseRuleList("bloom,stats"); err != nil {
+		t.Errorf("directory rules are not accepted: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package webhook lets external systems subscribe to events from the serve
+// daemon: a new cycle loaded, a scheduled cycle missing its release window, a
+// distributor root changing on-chain, or a cycle's claim rate crossing a
+// threshold; and from emergency-pause when it pauses distributors.
+// Registrations live in the state DB; deliveries are JSON POSTs signed with
+// the hook's secret.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/KyberNetwork/fairflow-reward/queue"
+	"github.com/KyberNetwork/fairflow-reward/state"
+)
+
+const bucket = "webhooks"
+
+const (
+	EventCycleLoaded  = "cycle.loaded"
+	EventCycleOverdue = "cycle.overdue"
+	EventRootChanged  = "root.changed"
+	EventClaimRate    = "claim-rate.crossed"
+	EventPreflight    = "preflight.checked"
+	EventCanaryFailed = "canary.failed"
+	EventPaused       = "distributor.paused"
+)
+
+var Events = []string{EventCycleLoaded, EventCycleOverdue, EventRootChanged, EventClaimRate, EventPreflight, EventCanaryFailed, EventPaused}
+
+type Hook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret keys the X-Fairflow-Signature HMAC; it is never listed back.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Event is the body of every delivery.
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+func NewEvent(typ string, data any) Event {
+	return Event{ID: randomID(), Type: typ, Time: time.Now().UTC(), Data: data}
+}
+
+type Registry struct {
+	db *state.DB
+}
+
+func NewRegistry(db *state.DB) *Registry { return &Registry{db: db} }
+
+// Add registers url for events and saves the DB.
+func (r *Registry) Add(rawURL string, events []string, secret string) (Hook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Hook{}, fmt.Errorf("webhook url %q is not an http(s) URL", rawURL)
+	}
+	if len(events) == 0 {
+		return Hook{}, errors.New("webhook needs at least one event")
+	}
+	for _, e := range events {
+		if !slices.Contains(Events, e) {
+			return Hook{}, fmt.Errorf("unknown event %q (want one of %v)", e, Events)
+		}
+	}
+	h := Hook{ID: randomID(), URL: rawURL, Events: events, Secret: secret, CreatedAt: time.Now().UTC()}
+	if err := r.db.Put(bucket, h.ID, h); err != nil {
+		return Hook{}, err
+	}
+	return h, r.db.Save()
+}
+
+// Remove deletes hook id and reports whether it existed.
+func (r *Registry) Remove(id string) (bool, error) {
+	var h Hook
+	ok, err := r.db.Get(bucket, id, &h)
+	if err != nil || !ok {
+		return false, err
+	}
+	r.db.Delete(bucket, id)
+	return true, r.db.Save()
+}
+
+func (r *Registry) List() ([]Hook, error) {
+	out := make([]Hook, 0)
+	for _, id := range r.db.Keys(bucket) {
+		var h Hook
+		if _, err := r.db.Get(bucket, id, &h); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// JobDeliver is the queue kind of a delivery that failed every attempt.
+const JobDeliver = "webhook.deliver"
+
+type deliveryJob struct {
+	HookID string          `json:"hookId"`
+	Type   string          `json:"type"`
+	ID     string          `json:"id"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// Notify delivers ev to every hook subscribed to its type. A failed delivery
+// is retried with backoff, then queued for flush-queue and reported; it does
+// not stop the others.
+func (r *Registry) Notify(ctx context.Context, ev Event) error {
+	hooks, err := r.List()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for _, h := range hooks {
+		if !slices.Contains(h.Events, ev.Type) {
+			continue
+		}
+		if err := deliver(ctx, h, ev.Type, ev.ID, body); err != nil {
+			job := deliveryJob{HookID: h.ID, Type: ev.Type, ID: ev.ID, Body: body}
+			if _, qerr := queue.New(r.db).Enqueue(JobDeliver, job, err); qerr != nil {
+				err = errors.Join(err, qerr)
+			}
+			errs = append(errs, fmt.Errorf("webhook %s: %w", h.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redeliver is the queue handler for JobDeliver. A delivery to a hook removed
+// since is dropped.
+func (r *Registry) Redeliver(ctx context.Context, payload json.RawMessage) error {
+	var job deliveryJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	var h Hook
+	ok, err := r.db.Get(bucket, job.HookID, &h)
+	if err != nil || !ok {
+		return err
+	}
+	return post(ctx, h, job.Type, job.ID, job.Body)
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+const attempts = 3
+
+func deliver(ctx context.Context, h Hook, typ, id string, body []byte) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<i) * time.Second):
+			}
+		}
+		if err = post(ctx, h, typ, id, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func post(ctx context.Context, h Hook, typ, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Fairflow-Event", typ)
+	req.Header.Set("X-Fairflow-Delivery", id)
+	if h.Secret != "" {
+		req.Header.Set("X-Fairflow-Signature", Sign(h.Secret, body))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(rb))
+	}
+	return nil
+}
+
+// Sign is the X-Fairflow-Signature of body: "sha256=" and the hex
+// HMAC-SHA256 under secret. Receivers recompute it over the raw body.
+func Sign(secret string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}